@@ -23,6 +23,159 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/capacity": {
+            "get": {
+                "description": "Returns the collector's memory use against its memory limit, tracked GPUs and hosts against the cardinality caps, the broker queue depth, quarantine size and the use of the filesystems holding its data, plus the gateway's own memory. Each limit is ok, warning (from 80%) or critical (from 95%), and status is the most severe of them; limits without a configured maximum are always ok.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Capacity"
+                ],
+                "summary": "Get capacity",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.CapacityReport"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/compare": {
+            "get": {
+                "description": "Returns one metric of two or more GPUs over the same window, as series of bucket means aligned to shared timestamps, with a summary of each GPU's samples and the mean and median difference of each GPU from the first. The window ends at the newest sample of any of the GPUs. Without a step the window is split into about 60 buckets.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GPUs"
+                ],
+                "summary": "Compare GPUs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma-separated GPU IDs, the first being the baseline",
+                        "name": "gpus",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metric to compare",
+                        "name": "metric",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Window to compare, as a Go duration (default: 1h)",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bucket width, as a Go duration",
+                        "name": "step",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated units to convert the metric to, at most one per dimension, e.g. GiB,F",
+                        "name": "units",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.CompareResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/fleet": {
+            "get": {
+                "description": "Returns the latest value of each metric for every GPU, with when each GPU was last seen. By default it reflects the collector's cache of recent samples; with as_of it is rebuilt from the collector's stored telemetry, cold storage included, using only samples at or before that time, answering what the fleet looked like then. GPUs with no samples by as_of are left out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GPUs"
+                ],
+                "summary": "Get a fleet snapshot",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Point in time to snapshot (RFC3339, default: now)",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated units to convert metrics to, at most one per dimension, e.g. GiB,F",
+                        "name": "units",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.FleetSnapshot"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/gpus": {
             "get": {
                 "description": "Returns a list of all GPU IDs for which telemetry data is available",
@@ -48,6 +201,17 @@ const docTemplate = `{
                         "description": "Number of items to skip (default: 0)",
                         "name": "offset",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "healthy",
+                            "degraded",
+                            "unknown"
+                        ],
+                        "type": "string",
+                        "description": "Only list GPUs in this health state",
+                        "name": "health",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -72,6 +236,62 @@ const docTemplate = `{
                 }
             }
         },
+        "/gpus/{id}/energy": {
+            "get": {
+                "description": "Returns energy (kWh) per UTC day integrated from DCGM power usage samples. Gaps between samples longer than the collector's max gap are not interpolated; covered_hours shows how much of each day the estimate spans.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GPUs"
+                ],
+                "summary": "Get energy usage for a GPU",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "GPU ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day to include (YYYY-MM-DD or RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day to include (YYYY-MM-DD or RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.EnergySummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/gpus/{id}/telemetry": {
             "get": {
                 "description": "Returns telemetry entries for a specific GPU, optionally filtered by time range",
@@ -116,6 +336,24 @@ const docTemplate = `{
                         "description": "Number of items to skip (default: 0)",
                         "name": "offset",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated units to convert metrics to, at most one per dimension, e.g. GiB,F",
+                        "name": "units",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from a previous response; returns 304 if unchanged",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified from a previous response; returns 304 if unchanged",
+                        "name": "If-Modified-Since",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -125,6 +363,9 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.TelemetryResponse"
                         }
                     },
+                    "304": {
+                        "description": "Telemetry unchanged since the conditional request"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -146,9 +387,29 @@ const docTemplate = `{
                 }
             }
         },
-        "/hosts": {
+        "/grafana/": {
             "get": {
-                "description": "Returns a list of all hostnames for which telemetry data is available",
+                "description": "Returns 200 so the Grafana SimpleJSON datasource can verify its URL",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Grafana"
+                ],
+                "summary": "Grafana datasource connection test",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.GrafanaStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/grafana/query": {
+            "post": {
+                "description": "Returns datapoints for each target within the requested range. A GPU target returns one series per metric; \"gpu:metric\" returns a single series. When the range and intervalMs imply more points per series than the server's limit (or maxDataPoints, if lower), series are served as averages over a coarser step, reported in each series' resolution. Table targets return one row per sample.",
                 "consumes": [
                     "application/json"
                 ],
@@ -156,28 +417,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Hosts"
+                    "Grafana"
                 ],
-                "summary": "Get all host names",
+                "summary": "Grafana panel query",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Number of items to return (default: 100, max: 1000)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Number of items to skip (default: 0)",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "Panel query",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.GrafanaQueryRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_api.HostsResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_api.GrafanaTimeSeries"
+                            }
                         }
                     },
                     "400": {
@@ -195,9 +456,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/hosts/{hostname}/gpus": {
-            "get": {
-                "description": "Returns a list of GPU UUIDs associated with the specified hostname",
+        "/grafana/search": {
+            "post": {
+                "description": "Returns GPU IDs containing the search text, or the metrics of a GPU as \"gpu:metric\" targets when the text is \"gpu:\"",
                 "consumes": [
                     "application/json"
                 ],
@@ -205,23 +466,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Hosts"
+                    "Grafana"
                 ],
-                "summary": "Get GPU IDs for a host",
+                "summary": "Grafana target search",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Hostname",
-                        "name": "hostname",
-                        "in": "path",
-                        "required": true
+                        "description": "Search text",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.GrafanaSearchRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_api.HostGPUsResponse"
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
@@ -230,12 +496,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/internal_api.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -244,27 +504,628 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_harishb93_telemetry-pipeline_internal_collector.Telemetry": {
-            "type": "object",
-            "properties": {
-                "gpu_id": {
-                    "type": "string"
-                },
-                "hostname": {
-                    "type": "string"
-                },
-                "metrics": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "number",
-                        "format": "float64"
-                    }
-                },
-                "timestamp": {
+        },
+        "/hosts": {
+            "get": {
+                "description": "Returns a list of all hostnames for which telemetry data is available",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Get all host names",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of items to return (default: 100, max: 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip (default: 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.HostsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/{hostname}/gpus": {
+            "get": {
+                "description": "Returns a list of GPU UUIDs associated with the specified hostname",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Get GPU IDs for a host",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Hostname",
+                        "name": "hostname",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.HostGPUsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Returns the distinct metric names (e.g. DCGM fields) the collector has received since it started, with sample counts, the number of GPUs reporting each and when each was last seen",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Get metric names",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only metrics reported by this GPU",
+                        "name": "gpu_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.MetricCatalogResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/silences": {
+            "get": {
+                "description": "Returns pending and active silences, newest first. Expired silences are kept as an audit trail and included with all=true.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Silences"
+                ],
+                "summary": "List silences",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include expired silences",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.Silence"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Suppresses GPU health alerts whose labels (gpu_id, hostname, state) match every matcher between starts_at and ends_at, e.g. for planned maintenance. Matcher values may be glob patterns. created_by is required and recorded for audit; starts_at defaults to now.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Silences"
+                ],
+                "summary": "Create a silence",
+                "parameters": [
+                    {
+                        "description": "Silence to create",
+                        "name": "silence",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.Silence"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.Silence"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/silences/{id}": {
+            "delete": {
+                "description": "Ends a pending or active silence now. The silence is kept, with expired_by recording who ended it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Silences"
+                ],
+                "summary": "Expire a silence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Silence ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Who is expiring the silence",
+                        "name": "expired_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.Silence"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_harishb93_telemetry-pipeline_internal_collector.CapacityReport": {
+            "type": "object",
+            "properties": {
+                "limits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.CapacityUsage"
+                    }
+                },
+                "status": {
+                    "description": "Status is the most severe status of the limits",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.CapacityUsage": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error is why the usage could not be measured",
+                    "type": "string"
+                },
+                "limit": {
+                    "description": "Limit is zero when there is no limit, and Percent then left out",
+                    "type": "number"
+                },
+                "name": {
+                    "description": "Name identifies the limit, e.g. memory, gpus or disk",
+                    "type": "string"
+                },
+                "path": {
+                    "description": "Path is the directory a disk limit was measured for",
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "used": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.DailyEnergy": {
+            "type": "object",
+            "properties": {
+                "covered_hours": {
+                    "description": "CoveredHours is the part of the day between power samples no further\napart than the max gap; energy outside it is unknown",
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "energy_kwh": {
+                    "type": "number"
+                },
+                "samples": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.EnergySummary": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.DailyEnergy"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "gpu_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total_kwh": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.FleetSnapshot": {
+            "type": "object",
+            "properties": {
+                "as_of": {
+                    "type": "string",
+                    "example": "2025-07-18T14:00:00Z"
+                },
+                "gpus": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.GPUSnapshot"
+                    }
+                },
+                "hosts": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "source": {
+                    "description": "Source is live for the collector's cache of recent samples or archive\nfor its stored telemetry",
+                    "type": "string",
+                    "example": "archive"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 16
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.GPUHealth": {
+            "type": "object",
+            "properties": {
+                "last_seen": {
+                    "type": "string"
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "state": {
+                    "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.HealthState"
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.GPUSnapshot": {
+            "type": "object",
+            "properties": {
+                "gpu_id": {
+                    "type": "string",
+                    "example": "gpu_0"
+                },
+                "hostname": {
+                    "type": "string",
+                    "example": "mtv5-dgx1-hgpu-031"
+                },
+                "last_seen": {
+                    "description": "LastSeen is the time of the GPU's newest sample up to the snapshot",
+                    "type": "string",
+                    "example": "2025-07-18T13:59:58Z"
+                },
+                "metrics": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    },
+                    "example": {
+                        "DCGM_FI_DEV_GPU_TEMP": 61.5,
+                        "DCGM_FI_DEV_GPU_UTIL": 87
+                    }
+                },
+                "units": {
+                    "description": "Units are the units of the metrics with a known unit",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "DCGM_FI_DEV_GPU_TEMP": "C",
+                        "DCGM_FI_DEV_GPU_UTIL": "%"
+                    }
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.HealthState": {
+            "type": "string",
+            "enum": [
+                "healthy",
+                "degraded",
+                "unknown"
+            ],
+            "x-enum-varnames": [
+                "HealthHealthy",
+                "HealthDegraded",
+                "HealthUnknown"
+            ]
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.MetricInfo": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count is the number of samples carrying the metric",
+                    "type": "integer"
+                },
+                "gpus": {
+                    "description": "GPUs is the number of GPUs reporting the metric",
+                    "type": "integer"
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "unit": {
+                    "description": "Unit is the unit the metric is reported in, if known",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.Silence": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
                     "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "expired_by": {
+                    "description": "ExpiredBy records who ended the silence early, if anyone",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "matchers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.SilenceState"
+                }
+            }
+        },
+        "github_com_harishb93_telemetry-pipeline_internal_collector.SilenceState": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "active",
+                "expired"
+            ],
+            "x-enum-varnames": [
+                "SilencePending",
+                "SilenceActive",
+                "SilenceExpired"
+            ]
+        },
+        "internal_api.CompareDelta": {
+            "type": "object",
+            "properties": {
+                "baseline": {
+                    "type": "string",
+                    "example": "gpu_0"
+                },
+                "gpu_id": {
+                    "type": "string",
+                    "example": "gpu_1"
+                },
+                "mean_diff": {
+                    "type": "number",
+                    "example": -4.2
+                },
+                "mean_diff_percent": {
+                    "description": "MeanDiffPercent is MeanDiff relative to the baseline mean, omitted\nwhen that is zero",
+                    "type": "number",
+                    "example": -4.8
+                },
+                "median_diff": {
+                    "type": "number",
+                    "example": -3
+                }
+            }
+        },
+        "internal_api.CompareResponse": {
+            "type": "object",
+            "properties": {
+                "deltas": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_api.CompareDelta"
+                    }
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2025-07-18T19:42:34Z"
+                },
+                "metric": {
+                    "type": "string",
+                    "example": "DCGM_FI_DEV_GPU_UTIL"
+                },
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_api.CompareSeries"
+                    }
+                },
+                "step": {
+                    "type": "string",
+                    "example": "1m0s"
+                },
+                "timestamps": {
+                    "description": "Timestamps are the starts of the buckets every series is aligned to",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2025-07-18T20:42:34Z"
+                },
+                "unit": {
+                    "description": "Unit is the unit of the values, after any conversion asked for, if\nthe metric's unit is known",
+                    "type": "string",
+                    "example": "%"
+                }
+            }
+        },
+        "internal_api.CompareSeries": {
+            "type": "object",
+            "properties": {
+                "gpu_id": {
+                    "type": "string",
+                    "example": "gpu_0"
+                },
+                "summary": {
+                    "$ref": "#/definitions/internal_api.SeriesSummary"
+                },
+                "values": {
+                    "description": "Values are the mean of each bucket, aligned with the response's\ntimestamps; null where the GPU has no samples",
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
                 }
             }
         },
@@ -272,13 +1133,29 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "code": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 400
                 },
                 "error": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "Bad Request"
+                },
+                "error_code": {
+                    "description": "ErrorCode and Reason identify the error; unlike Message, they never change",
+                    "type": "string",
+                    "example": "TP-4002"
                 },
                 "message": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "Invalid pagination parameters: strconv.Atoi: parsing \"x\": invalid syntax"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "INVALID_PAGINATION"
+                },
+                "trace_id": {
+                    "type": "string",
+                    "example": "4bf92f3577b34da6a3ce929d0e0e4736"
                 }
             }
         },
@@ -289,13 +1166,109 @@ const docTemplate = `{
                     "type": "array",
                     "items": {
                         "type": "string"
+                    },
+                    "example": [
+                        "gpu_0",
+                        "gpu_1"
+                    ]
+                },
+                "health": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.GPUHealth"
                     }
                 },
                 "pagination": {
                     "$ref": "#/definitions/internal_api.PaginationMetadata"
                 },
                 "total": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "internal_api.GrafanaQueryRequest": {
+            "type": "object",
+            "properties": {
+                "intervalMs": {
+                    "description": "IntervalMs is the panel's step between points",
+                    "type": "integer"
+                },
+                "maxDataPoints": {
                     "type": "integer"
+                },
+                "range": {
+                    "type": "object",
+                    "properties": {
+                        "from": {
+                            "type": "string"
+                        },
+                        "to": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "targets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_api.GrafanaQueryTarget"
+                    }
+                }
+            }
+        },
+        "internal_api.GrafanaQueryTarget": {
+            "type": "object",
+            "properties": {
+                "hide": {
+                    "type": "boolean"
+                },
+                "refId": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Type is \"timeserie\" (the default) or \"table\"",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_api.GrafanaSearchRequest": {
+            "type": "object",
+            "properties": {
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_api.GrafanaStatus": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "internal_api.GrafanaTimeSeries": {
+            "type": "object",
+            "properties": {
+                "datapoints": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "number",
+                            "format": "float64"
+                        }
+                    }
+                },
+                "resolution": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
                 }
             }
         },
@@ -306,13 +1279,19 @@ const docTemplate = `{
                     "type": "array",
                     "items": {
                         "type": "string"
-                    }
+                    },
+                    "example": [
+                        "gpu_0",
+                        "gpu_1"
+                    ]
                 },
                 "hostname": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "mtv5-dgx1-hgpu-031"
                 },
                 "total": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 2
                 }
             }
         },
@@ -323,11 +1302,29 @@ const docTemplate = `{
                     "type": "array",
                     "items": {
                         "type": "string"
-                    }
+                    },
+                    "example": [
+                        "mtv5-dgx1-hgpu-031"
+                    ]
                 },
                 "pagination": {
                     "$ref": "#/definitions/internal_api.PaginationMetadata"
                 },
+                "total": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "internal_api.MetricCatalogResponse": {
+            "type": "object",
+            "properties": {
+                "metrics": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.MetricInfo"
+                    }
+                },
                 "total": {
                     "type": "integer"
                 }
@@ -337,13 +1334,95 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "has_next": {
-                    "type": "boolean"
+                    "type": "boolean",
+                    "example": true
                 },
                 "limit": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 100
                 },
                 "offset": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "internal_api.SeriesSummary": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number",
+                    "example": 100
+                },
+                "mean": {
+                    "type": "number",
+                    "example": 87.5
+                },
+                "median": {
+                    "type": "number",
+                    "example": 91
+                },
+                "min": {
+                    "type": "number",
+                    "example": 12
+                },
+                "samples": {
+                    "type": "integer",
+                    "example": 3600
+                }
+            }
+        },
+        "internal_api.TelemetryEntry": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "description": "CorrelationID identifies the source record the sample was streamed\nfrom: \u003crun\u003e.\u003cworker\u003e.\u003cpass\u003e.\u003cfile\u003e.\u003cline\u003e",
+                    "type": "string",
+                    "example": "01J3Q8M4K7W9X2T5B6N8R0C1DE.0.0.0.17"
+                },
+                "gpu_id": {
+                    "type": "string",
+                    "example": "gpu_0"
+                },
+                "hostname": {
+                    "type": "string",
+                    "example": "mtv5-dgx1-hgpu-031"
+                },
+                "labels": {
+                    "description": "Labels annotate where the sample came from and how it was stored, e.g.\nits source file and row or its sampling rate",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "sampling": "1m"
+                    }
+                },
+                "metrics": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    },
+                    "example": {
+                        "DCGM_FI_DEV_GPU_TEMP": 61.5,
+                        "DCGM_FI_DEV_GPU_UTIL": 87
+                    }
+                },
+                "timestamp": {
+                    "type": "string",
+                    "example": "2025-07-18T20:42:34Z"
+                },
+                "units": {
+                    "description": "Units are the units of the metrics with a known unit, after any\nconversion asked for",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "DCGM_FI_DEV_GPU_TEMP": "C",
+                        "DCGM_FI_DEV_GPU_UTIL": "%"
+                    }
                 }
             }
         },
@@ -353,14 +1432,37 @@ const docTemplate = `{
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_harishb93_telemetry-pipeline_internal_collector.Telemetry"
+                        "$ref": "#/definitions/internal_api.TelemetryEntry"
                     }
                 },
+                "error": {
+                    "type": "string"
+                },
                 "pagination": {
                     "$ref": "#/definitions/internal_api.PaginationMetadata"
                 },
+                "tiers": {
+                    "description": "Tiers lists the collector storage tiers that held the data: warm for\nits telemetry files, cold for its object storage",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "cold",
+                        "warm"
+                    ]
+                },
                 "total": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 5000
+                },
+                "trace_id": {
+                    "type": "string",
+                    "example": "4bf92f3577b34da6a3ce929d0e0e4736"
+                },
+                "truncated": {
+                    "type": "boolean",
+                    "example": false
                 }
             }
         }
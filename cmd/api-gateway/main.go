@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
 	"syscall"
 
-	_ "github.com/harishb93/telemetry-pipeline/api" // Swagger docs
+	docs "github.com/harishb93/telemetry-pipeline/api" // Swagger docs
 	"github.com/harishb93/telemetry-pipeline/internal/api"
 	"github.com/harishb93/telemetry-pipeline/internal/collector"
 	"github.com/harishb93/telemetry-pipeline/internal/logger"
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
+	"github.com/harishb93/telemetry-pipeline/internal/selftest"
 )
 
 // @title Telemetry API Gateway
@@ -27,20 +30,55 @@ import (
 func main() {
 	// Initialize logger
 	log := logger.NewFromEnv().WithComponent("api-gateway")
+	defer log.Flush()
+	defer log.RecoverPanic()
 
 	// Command line flags
 	var (
-		port          = flag.String("port", "8081", "Port for API server")
+		port          = flag.String("port", "8081", "Port, host:port address, unix: socket path or systemd: passed socket for the API server")
 		collectorPort = flag.String("collector-port", "8080", "Port of the collector health endpoint")
 		dataDir       = flag.String("data-dir", "./data", "Directory where telemetry data is stored")
+		slowQuery     = flag.Duration("slow-query-threshold", api.DefaultSlowQueryThreshold, "Log and count API requests slower than this (0 disables)")
+		basePath      = flag.String("base-path", "", "Serve all routes under this path prefix (e.g. /telemetry) when behind a shared reverse proxy")
+		enableUI      = flag.Bool("ui", true, "Serve the built-in dashboard at the root of the base path")
+		maxResults    = flag.Int("max-result-items", api.DefaultMaxResultItems, "Hard cap on items returned in a single telemetry response")
+		maxPoints     = flag.Int("max-series-points", api.DefaultMaxSeriesPoints, "Points per series above which Grafana queries are served as rollups")
+		usageHeader   = flag.String("usage-key-header", api.DefaultUsageKeyHeader, "Request header identifying API consumers for usage accounting")
+		usageDir      = flag.String("usage-export-dir", "", "Directory for monthly usage reports (empty keeps usage in memory only)")
+		tlsCert       = flag.String("tls-cert", "", "TLS certificate file; serves HTTPS when given with --tls-key")
+		tlsKey        = flag.String("tls-key", "", "TLS private key file for --tls-cert")
+		hstsMaxAge    = flag.Duration("hsts-max-age", api.DefaultHSTSMaxAge, "Strict-Transport-Security max-age sent on HTTPS responses (0 disables)")
+		logSuccesses  = flag.Float64("access-log-success-rate", 1, "Fraction of successful requests written to the access log (e.g. 0.01)")
+		logErrors     = flag.Float64("access-log-error-rate", 1, "Fraction of requests with a 4xx or 5xx status written to the access log")
+		reusePort     = flag.Bool("reuse-port", false, "Listen with SO_REUSEPORT so a replacement process can take over the port before this one stops")
+		selfTest      = flag.Bool("selftest", false, "Run the startup self-test, print a report and exit non-zero if a check fails")
 	)
 	flag.Parse()
 
+	if *selfTest {
+		checks := []selftest.Check{selftest.Clock(), selftest.Readable("data dir readable", *dataDir)}
+		if *tlsCert != "" {
+			checks = append(checks,
+				selftest.Readable("tls certificate readable", *tlsCert),
+				selftest.Readable("tls key readable", *tlsKey))
+		}
+		selftest.Run("api-gateway", checks...).Exit()
+	}
+
 	log.Info("Starting Telemetry API Gateway")
 	log.Info("Configuration loaded",
 		"api_port", *port,
 		"collector_port", *collectorPort,
-		"data_dir", *dataDir)
+		"data_dir", *dataDir,
+		"slow_query_threshold", *slowQuery,
+		"max_result_items", *maxResults,
+		"max_series_points", *maxPoints,
+		"usage_key_header", *usageHeader,
+		"usage_export_dir", *usageDir,
+		"base_path", *basePath,
+		"tls", *tlsCert != "",
+		"reuse_port", *reusePort)
+	netutil.SetReusePort(*reusePort)
 
 	// Create a minimal collector instance for data access
 	// In a real deployment, this would connect to the actual collector service
@@ -57,12 +95,49 @@ func main() {
 
 	coll := collector.NewCollector(broker, collectorConfig)
 
+	// Point Swagger UI's "Try it out" at the prefixed API, on whatever host
+	// the proxy serves it from
+	base := api.NormalizeBasePath(*basePath)
+	if base != "" {
+		docs.SwaggerInfo.BasePath = base + docs.SwaggerInfo.BasePath
+		docs.SwaggerInfo.Host = ""
+	}
+
+	// Trace requests when OTEL_TRACES_EXPORTER is set
+	tracerProvider, err := api.NewTracerProviderFromEnv(context.Background(), "api-gateway")
+	if err != nil {
+		log.Fatal("Failed to configure tracing", "error", err)
+	}
+	if tracerProvider != nil {
+		shutdownTracing := func() { _ = tracerProvider.Shutdown(context.Background()) }
+		defer shutdownTracing()
+		logger.RegisterExitHook("tracing", shutdownTracing)
+		log.Info("Exporting request traces over OTLP")
+	}
+
 	// Create API server
 	serverConfig := api.ServerConfig{
-		Port: *port,
+		Port:                 *port,
+		BasePath:             base,
+		SlowQueryThreshold:   *slowQuery,
+		MaxResultItems:       *maxResults,
+		MaxSeriesPoints:      *maxPoints,
+		EnableUI:             *enableUI,
+		UsageKeyHeader:       *usageHeader,
+		UsageExportDir:       *usageDir,
+		TLSCertFile:          *tlsCert,
+		TLSKeyFile:           *tlsKey,
+		HSTSMaxAge:           *hstsMaxAge,
+		AccessLogSuccessRate: *logSuccesses,
+		AccessLogErrorRate:   *logErrors,
+	}
+	// Only a configured provider, as a nil one would not be a nil interface
+	if tracerProvider != nil {
+		serverConfig.TracerProvider = tracerProvider
 	}
 
 	server := api.NewServer(coll, serverConfig)
+	logger.RegisterExitHook("api-server", func() { _ = server.Stop() })
 
 	// Set up signal handling for graceful shutdown
 	sigCh := make(chan os.Signal, 1)
@@ -77,9 +152,10 @@ func main() {
 
 	log.Info("API Gateway started successfully",
 		"port", *port,
-		"swagger_ui", "http://localhost:"+*port+"/swagger/",
-		"health_endpoint", "http://localhost:"+*port+"/health",
-		"api_base", "http://localhost:"+*port+"/api/v1")
+		"dashboard", server.URL()+base+"/",
+		"swagger_ui", server.URL()+base+"/swagger/",
+		"health_endpoint", server.URL()+base+"/health",
+		"api_base", server.URL()+base+"/api/v1")
 	log.Info("Press Ctrl+C to stop...")
 
 	// Wait for shutdown signal
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+// streamAckKey identifies a message streamed to a subscriber awaiting its Ack
+type streamAckKey struct {
+	topic string
+	group string
+	id    string
+}
+
+// streamAck is the acknowledgment of a streamed message and the stream it
+// was sent on
+type streamAck struct {
+	stream pb.MQService_SubscribeServer
+	ack    func()
+}
+
+// streamAcks holds the acknowledgments of messages sent on Subscribe
+// streams until the subscriber calls Ack. A message whose stream ends first
+// is left pending and redelivered once its ack timeout passes.
+type streamAcks struct {
+	mu      sync.Mutex
+	pending map[streamAckKey]streamAck
+}
+
+func newStreamAcks() *streamAcks {
+	return &streamAcks{pending: make(map[streamAckKey]streamAck)}
+}
+
+// add holds the acknowledgment of a message sent on stream
+func (a *streamAcks) add(key streamAckKey, stream pb.MQService_SubscribeServer, ack func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending[key] = streamAck{stream: stream, ack: ack}
+}
+
+// take removes and returns the acknowledgment of a message
+func (a *streamAcks) take(key streamAckKey) (func(), bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pending, ok := a.pending[key]
+	if !ok {
+		return nil, false
+	}
+	delete(a.pending, key)
+	return pending.ack, true
+}
+
+// release drops the acknowledgments of messages sent on stream once it ends
+func (a *streamAcks) release(stream pb.MQService_SubscribeServer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, pending := range a.pending {
+		if pending.stream == stream {
+			delete(a.pending, key)
+		}
+	}
+}
+
+// Ack implements the Ack gRPC method, acknowledging a message sent on a
+// Subscribe stream
+func (s *gRPCMQService) Ack(ctx context.Context, req *pb.AckRequest) (*pb.AckResponse, error) {
+	ack, ok := s.acks.take(streamAckKey{topic: req.Topic, group: req.ConsumerGroup, id: req.MessageId})
+	if !ok {
+		return nil, mq.GRPCStatus(fmt.Errorf("%w: %s on topic %s", mq.ErrMessageNotFound, req.MessageId, req.Topic))
+	}
+	ack()
+
+	logger.FromContext(ctx).Debug("Message acknowledged via gRPC", "topic", req.Topic, "message_id", req.MessageId)
+	return &pb.AckResponse{}, nil
+}
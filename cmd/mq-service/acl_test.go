@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+// loadTestACL loads an ACL letting "streamer" publish to and "collector"
+// subscribe to telemetry
+func loadTestACL(t *testing.T) *mq.ACL {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "acl.yaml")
+	content := `clients:
+  - name: streamer
+    token: streamer-secret
+    publish: ["telemetry"]
+  - name: collector
+    token: collector-secret
+    subscribe: ["telemetry"]
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	acl, err := mq.LoadACL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acl
+}
+
+func TestHTTPPublish_ACL(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+	service.SetACL(loadTestACL(t))
+	service.SetProtocolGuard(mq.NewProtocolGuard(false, logger.NewFromEnv()))
+
+	for token, want := range map[string]int{
+		"":                 http.StatusUnauthorized,
+		"collector-secret": http.StatusForbidden,
+		"streamer-secret":  http.StatusOK,
+	} {
+		req := httptest.NewRequest("POST", "/publish/telemetry", strings.NewReader(`{"gpu_id": "0"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		service.httpServer.Handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("%q: expected %d, got %d: %s", token, want, rr.Code, rr.Body.String())
+		}
+		if rr.Header().Get(mq.ProtocolHeader) == "" {
+			t.Errorf("%q: expected the protocol version on the response", token)
+		}
+	}
+}
+
+func TestHTTPAdmin_ACL(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	routes, err := mq.NewRouter(filepath.Join(t.TempDir(), "routes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+	service.SetACL(loadTestACL(t))
+	service.SetRouter(routes)
+	service.SetAdminToken("admin-secret")
+
+	for _, tc := range []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/admin/topics/telemetry/pending", "", http.StatusUnauthorized},
+		{"GET", "/admin/topics/telemetry/pending", "streamer-secret", http.StatusForbidden},
+		{"GET", "/admin/topics/telemetry/pending", "collector-secret", http.StatusNotFound},
+		{"POST", "/admin/topics/telemetry/pending/m1/redeliver", "", http.StatusUnauthorized},
+		{"POST", "/admin/topics/telemetry/pending/m1/dead-letter", "streamer-secret", http.StatusForbidden},
+		{"GET", "/admin/topics/telemetry/dlq", "", http.StatusUnauthorized},
+		{"GET", "/admin/topics/telemetry/dlq", "streamer-secret", http.StatusForbidden},
+		{"GET", "/admin/topics/telemetry/dlq", "collector-secret", http.StatusOK},
+		{"DELETE", "/admin/topics/telemetry/dlq", "streamer-secret", http.StatusForbidden},
+		{"POST", "/admin/topics/telemetry/dlq/redrive", "", http.StatusUnauthorized},
+		{"POST", "/admin/topics/telemetry/dlq/redrive", "collector-secret", http.StatusForbidden},
+		{"POST", "/admin/topics/telemetry/dlq/redrive", "streamer-secret", http.StatusOK},
+		{"GET", "/routes", "", http.StatusUnauthorized},
+		{"GET", "/routes", "collector-secret", http.StatusUnauthorized},
+		{"POST", "/routes", "streamer-secret", http.StatusUnauthorized},
+		{"DELETE", "/routes/r1", "", http.StatusUnauthorized},
+		{"GET", "/routes", "admin-secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{}`))
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rr := httptest.NewRecorder()
+		service.httpServer.Handler.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s %s with %q: expected %d, got %d: %s", tc.method, tc.path, tc.token, tc.want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestGRPC_ACL(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	acl := loadTestACL(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(acl.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(acl.StreamServerInterceptor()))
+	pb.RegisterMQServiceServer(grpcServer, NewgRPCMQService(broker, logger.NewFromEnv()))
+	go func() { _ = grpcServer.Serve(lis) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	client := pb.NewMQServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+	publish := &pb.PublishRequest{Topic: "telemetry", Payload: []byte(`{}`)}
+
+	if _, err := client.Publish(ctx, publish); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a token, got %v", err)
+	}
+	if _, err := client.Publish(withToken("collector-secret"), publish); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a subscriber publishing, got %v", err)
+	}
+	if _, err := client.Publish(withToken("streamer-secret"), publish); err != nil {
+		t.Errorf("Expected the streamer to publish, got %v", err)
+	}
+	if _, err := client.Health(ctx, &pb.HealthRequest{}); err != nil {
+		t.Errorf("Expected Health outside the ACL, got %v", err)
+	}
+
+	// Dead letters need the permissions of the subscriber that reads and
+	// purges them, or of the publisher they are re-driven as
+	for _, tc := range []struct {
+		name  string
+		call  func(ctx context.Context) error
+		allow string
+		deny  string
+	}{
+		{"ListDeadLetters", func(ctx context.Context) error {
+			_, err := client.ListDeadLetters(ctx, &pb.DeadLettersRequest{Topic: "telemetry"})
+			return err
+		}, "collector-secret", "streamer-secret"},
+		{"PurgeDeadLetters", func(ctx context.Context) error {
+			_, err := client.PurgeDeadLetters(ctx, &pb.PurgeDeadLettersRequest{Topic: "telemetry"})
+			return err
+		}, "collector-secret", "streamer-secret"},
+		{"RedriveDeadLetters", func(ctx context.Context) error {
+			_, err := client.RedriveDeadLetters(ctx, &pb.RedriveDeadLettersRequest{Topic: "telemetry"})
+			return err
+		}, "streamer-secret", "collector-secret"},
+	} {
+		if err := tc.call(ctx); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: expected Unauthenticated without a token, got %v", tc.name, err)
+		}
+		if err := tc.call(withToken(tc.deny)); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: expected PermissionDenied for %s, got %v", tc.name, tc.deny, err)
+		}
+		if err := tc.call(withToken(tc.allow)); status.Code(err) == codes.Unauthenticated || status.Code(err) == codes.PermissionDenied {
+			t.Errorf("%s: expected %s allowed, got %v", tc.name, tc.allow, err)
+		}
+	}
+
+	for token, want := range map[string]codes.Code{"": codes.Unauthenticated, "streamer-secret": codes.PermissionDenied} {
+		subCtx := ctx
+		if token != "" {
+			subCtx = withToken(token)
+		}
+		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Topic: "telemetry"})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != want {
+			t.Errorf("Subscribe with %q: expected %v, got %v", token, want, err)
+		}
+	}
+
+	// The broker client sends its token with every call
+	subscriber, err := mq.NewGRPCBrokerClient(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscriber.Close()
+	if err := subscriber.Publish("telemetry", mq.Message{Payload: []byte(`{}`)}); !errors.Is(err, mq.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized without a token, got %v", err)
+	}
+	subscriber.SetToken("collector-secret")
+	if err := subscriber.Publish("telemetry", mq.Message{Payload: []byte(`{}`)}); !errors.Is(err, mq.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden for a subscriber publishing, got %v", err)
+	}
+	ch, unsubscribe, err := subscriber.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	publisher, err := mq.NewGRPCBrokerClient(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	publisher.SetToken("streamer-secret")
+	deadline := time.After(5 * time.Second)
+	for {
+		// Publish until the subscription is registered and a message arrives
+		if err := publisher.Publish("telemetry", mq.Message{Payload: []byte(`{"gpu_id": "0"}`)}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-ch:
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("Timed out waiting for an authorized subscription to receive")
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+// batchMessage is one message of an HTTP batch publish. Payloads are JSON,
+// as they are for /publish.
+type batchMessage struct {
+	Payload json.RawMessage   `json:"payload"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// batchResult is the outcome of publishing one message of a batch
+type batchResult struct {
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// handlePublishBatch publishes a JSON array of messages to a topic together,
+// reporting the result of each
+func (s *HTTPMQService) handlePublishBatch(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	if err := mq.ValidateTopic(topic); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var batch []batchMessage
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, "Invalid batch: expected a JSON array of messages: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	msgs := make([]mq.Message, len(batch))
+	for i, message := range batch {
+		msgs[i] = mq.Message{Payload: message.Payload, Headers: mq.MessageHeaders(message.Headers)}
+	}
+	errs, err := s.broker.PublishBatch(r.Context(), topic, msgs)
+	if err != nil {
+		s.logger.Error("Failed to publish batch", "topic", topic, "error", err)
+		http.Error(w, "Failed to publish batch: "+err.Error(), mq.HTTPStatus(err))
+		return
+	}
+
+	results := make([]batchResult, len(msgs))
+	published := 0
+	for i, msg := range msgs {
+		results[i] = batchResult{MessageID: msg.ID, Status: "published"}
+		if errs[i] != nil {
+			results[i].Status = "failed"
+			results[i].Error = errs[i].Error()
+			continue
+		}
+		published++
+		if s.routes != nil {
+			if err := s.routes.Route(s.broker, topic, msg.Headers, msg.Payload); err != nil {
+				s.logger.Warn("Failed to route message", "topic", topic, "error", err)
+			}
+		}
+	}
+	if published < len(msgs) {
+		s.logger.Warn("Batch partially published", "topic", topic, "published", published, "failed", len(msgs)-published)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":     topic,
+		"published": published,
+		"failed":    len(msgs) - published,
+		"results":   results,
+	})
+}
+
+// PublishBatch implements the PublishBatch gRPC method
+func (s *gRPCMQService) PublishBatch(ctx context.Context, req *pb.PublishBatchRequest) (*pb.PublishBatchResponse, error) {
+	msgs := make([]mq.Message, len(req.Messages))
+	for i, message := range req.Messages {
+		msgs[i] = mq.Message{Payload: message.Payload, Headers: mq.MessageHeaders(message.Headers)}
+	}
+	errs, err := s.broker.PublishBatch(ctx, req.Topic, msgs)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to publish batch", "topic", req.Topic, "error", err)
+		return nil, mq.GRPCStatus(err)
+	}
+
+	resp := &pb.PublishBatchResponse{Results: make([]*pb.PublishResponse, len(msgs))}
+	published := 0
+	for i, msg := range msgs {
+		resp.Results[i] = &pb.PublishResponse{MessageId: msg.ID, Success: errs[i] == nil}
+		if errs[i] != nil {
+			resp.Results[i].Error = errs[i].Error()
+			continue
+		}
+		published++
+		if s.routes != nil {
+			if err := s.routes.Route(s.broker, req.Topic, msg.Headers, msg.Payload); err != nil {
+				logger.FromContext(ctx).Warn("Failed to route message", "topic", req.Topic, "error", err)
+			}
+		}
+	}
+
+	logger.FromContext(ctx).Debug("Batch published via gRPC", "topic", req.Topic, "published", published, "failed", len(msgs)-published)
+	return resp, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+func TestPublishBatchHTTP(t *testing.T) {
+	config := mq.DefaultBrokerConfig()
+	config.MaxQueueSize = 2
+	broker := mq.NewBroker(config)
+	defer broker.Close()
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+
+	body := `[{"payload":{"gpu":0}},{"payload":{"gpu":1},"headers":{"source":"streamer"}},{"payload":{"gpu":2}}]`
+	rec := httptest.NewRecorder()
+	service.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/publish-batch/telemetry", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Published int           `json:"published"`
+		Failed    int           `json:"failed"`
+		Results   []batchResult `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	// The third message finds the queue full
+	if resp.Published != 2 || resp.Failed != 1 || len(resp.Results) != 3 {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+	if resp.Results[0].Status != "published" || resp.Results[0].MessageID == "" || resp.Results[2].Status != "failed" || resp.Results[2].Error == "" {
+		t.Errorf("Unexpected results %+v", resp.Results)
+	}
+
+	if stats, err := broker.GetTopicStats("telemetry"); err != nil || stats.QueueSize != 2 {
+		t.Fatalf("Expected 2 messages queued, got %+v (%v)", stats, err)
+	}
+
+	rec = httptest.NewRecorder()
+	service.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/publish-batch/telemetry", strings.NewReader(`{"payload":{}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a body that is not an array, got %d", rec.Code)
+	}
+}
+
+func TestPublishBatchGRPC(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	service := NewgRPCMQService(broker, logger.NewFromEnv())
+
+	resp, err := service.PublishBatch(context.Background(), &pb.PublishBatchRequest{
+		Topic: "telemetry",
+		Messages: []*pb.BatchMessage{
+			{Payload: []byte(`{"gpu":0}`)},
+			{Payload: []byte(`{"gpu":1}`), Headers: map[string]string{"source": "streamer"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+	if len(resp.Results) != 2 || !resp.Results[0].Success || !resp.Results[1].Success || resp.Results[0].MessageId == resp.Results[1].MessageId {
+		t.Fatalf("Unexpected results %+v", resp.Results)
+	}
+
+	if stats, err := broker.GetTopicStats("telemetry"); err != nil || stats.PublishedMessages != 2 {
+		t.Errorf("Expected 2 messages published, got %+v (%v)", stats, err)
+	}
+
+	if _, err := service.PublishBatch(context.Background(), &pb.PublishBatchRequest{Topic: "bad topic!"}); err == nil {
+		t.Error("Expected an invalid topic to be refused")
+	}
+}
+
+// Batches are routed on each message's headers, as single publishes are
+func TestPublishBatch_RoutesOnMessageHeaders(t *testing.T) {
+	t.Run("HTTP", func(t *testing.T) {
+		broker, service := newSourceRoutedService(t)
+		body := `[{"payload":{}},{"payload":{},"headers":{"x-source-host":"node-1","schema_version":"2"}}]`
+		rec := httptest.NewRecorder()
+		service.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/publish-batch/telemetry", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		expectRouted(t, broker)
+		if got := broker.GetQueueSize("telemetry.node-1"); got != 0 {
+			t.Errorf("Expected only the message from node-1 routed, got %d more", got)
+		}
+	})
+
+	t.Run("GRPC", func(t *testing.T) {
+		broker, httpService := newSourceRoutedService(t)
+		service := NewgRPCMQService(broker, logger.NewFromEnv())
+		service.routes = httpService.routes
+		if _, err := service.PublishBatch(context.Background(), &pb.PublishBatchRequest{
+			Topic: "telemetry",
+			Messages: []*pb.BatchMessage{
+				{Payload: []byte(`{}`)},
+				{Payload: []byte(`{}`), Headers: map[string]string{"x-source-host": "node-1", "schema_version": "2"}},
+			},
+		}); err != nil {
+			t.Fatalf("PublishBatch failed: %v", err)
+		}
+		expectRouted(t, broker)
+		if got := broker.GetQueueSize("telemetry.node-1"); got != 0 {
+			t.Errorf("Expected only the message from node-1 routed, got %d more", got)
+		}
+	})
+}
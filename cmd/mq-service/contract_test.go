@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/mq/mqtest"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+func TestBrokerContract_Broker(t *testing.T) {
+	mqtest.Run(t, func(t *testing.T) mqtest.Target {
+		broker := mq.NewBroker(mq.DefaultBrokerConfig())
+		t.Cleanup(broker.Close)
+		return mqtest.Target{Broker: broker, Expire: mqtest.ExpireBroker(broker)}
+	})
+}
+
+// The HTTP client only publishes; the suite subscribes to the broker behind
+// the service
+func TestBrokerContract_HTTPBroker(t *testing.T) {
+	mqtest.Run(t, func(t *testing.T) mqtest.Target {
+		broker := mq.NewBroker(mq.DefaultBrokerConfig())
+		t.Cleanup(broker.Close)
+		service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+		service.SetProtocolGuard(mq.NewProtocolGuard(true, logger.NewFromEnv()))
+		server := httptest.NewServer(service.httpServer.Handler)
+		t.Cleanup(server.Close)
+
+		client := mq.NewHTTPBroker(server.URL)
+		t.Cleanup(client.Close)
+		return mqtest.Target{Broker: client, Subscriber: broker, Expire: mqtest.ExpireBroker(broker)}
+	})
+}
+
+func TestBrokerContract_GRPCBrokerClient(t *testing.T) {
+	mqtest.Run(t, func(t *testing.T) mqtest.Target {
+		broker := mq.NewBroker(mq.DefaultBrokerConfig())
+		t.Cleanup(broker.Close)
+		client, err := mq.NewGRPCBrokerClient(startContractGRPCServer(t, broker))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(client.Close)
+		return mqtest.Target{Broker: client, Expire: mqtest.ExpireBroker(broker)}
+	})
+}
+
+// startContractGRPCServer serves broker over gRPC, behind a strict protocol
+// guard as in production, and returns its address
+func startContractGRPCServer(t *testing.T, broker *mq.Broker) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	protocol := mq.NewProtocolGuard(true, logger.NewFromEnv())
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(protocol.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(protocol.StreamServerInterceptor()))
+	pb.RegisterMQServiceServer(grpcServer, NewgRPCMQService(broker, logger.NewFromEnv()))
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+	return lis.Addr().String()
+}
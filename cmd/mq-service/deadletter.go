@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+// handleListDeadLetters lists the messages held on a topic's dead-letter
+// topic, with why they were dead-lettered
+func (s *HTTPMQService) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	letters, err := s.broker.DeadLetters(topic)
+	if err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":        topic,
+		"dead_letters": letters,
+		"total":        len(letters),
+	})
+}
+
+// handleRedriveDeadLetters publishes a topic's dead letters, or the one
+// named in the path, to the topic again
+func (s *HTTPMQService) handleRedriveDeadLetters(w http.ResponseWriter, r *http.Request) {
+	topic, ids := deadLetterVars(r)
+	count, err := s.broker.RedriveDeadLetters(topic, ids...)
+	if err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Info("Dead letters re-driven on request", "topic", topic, "count", count)
+	writeDeadLetterCount(w, topic, "redriven", count)
+}
+
+// handlePurgeDeadLetters discards a topic's dead letters, or the one named
+// in the path
+func (s *HTTPMQService) handlePurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	topic, ids := deadLetterVars(r)
+	count, err := s.broker.PurgeDeadLetters(topic, ids...)
+	if err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Warn("Dead letters purged on request", "topic", topic, "count", count)
+	writeDeadLetterCount(w, topic, "purged", count)
+}
+
+// deadLetterVars returns the topic and, if the path names one, the dead
+// letter a request is for
+func deadLetterVars(r *http.Request) (string, []string) {
+	vars := mux.Vars(r)
+	if id, ok := vars["id"]; ok {
+		return vars["topic"], []string{id}
+	}
+	return vars["topic"], nil
+}
+
+func writeDeadLetterCount(w http.ResponseWriter, topic, field string, count int) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic": topic,
+		field:   count,
+	})
+}
+
+// ListDeadLetters implements the ListDeadLetters gRPC method
+func (s *gRPCMQService) ListDeadLetters(ctx context.Context, req *pb.DeadLettersRequest) (*pb.DeadLettersResponse, error) {
+	letters, err := s.broker.DeadLetters(req.Topic)
+	if err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+
+	resp := &pb.DeadLettersResponse{
+		Topic:       req.Topic,
+		DeadLetters: make([]*pb.DeadLetter, 0, len(letters)),
+	}
+	for _, letter := range letters {
+		resp.DeadLetters = append(resp.DeadLetters, &pb.DeadLetter{
+			Id:                     letter.ID,
+			MessageId:              letter.MessageID,
+			Group:                  letter.Group,
+			PublishedAtUnixNano:    letter.PublishedAt.UnixNano(),
+			DeadLetteredAtUnixNano: letter.DeadLetteredAt.UnixNano(),
+			Retries:                int32(letter.Retries),
+			Error:                  letter.Error,
+			Payload:                letter.Payload,
+			Headers:                letter.Headers,
+		})
+	}
+	return resp, nil
+}
+
+// RedriveDeadLetters implements the RedriveDeadLetters gRPC method
+func (s *gRPCMQService) RedriveDeadLetters(ctx context.Context, req *pb.RedriveDeadLettersRequest) (*pb.DeadLettersCountResponse, error) {
+	count, err := s.broker.RedriveDeadLetters(req.Topic, req.Ids...)
+	if err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+	s.logger.Info("Dead letters re-driven via gRPC", "topic", req.Topic, "count", count)
+	return &pb.DeadLettersCountResponse{Count: int32(count)}, nil
+}
+
+// PurgeDeadLetters implements the PurgeDeadLetters gRPC method
+func (s *gRPCMQService) PurgeDeadLetters(ctx context.Context, req *pb.PurgeDeadLettersRequest) (*pb.DeadLettersCountResponse, error) {
+	count, err := s.broker.PurgeDeadLetters(req.Topic, req.Ids...)
+	if err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+	s.logger.Warn("Dead letters purged via gRPC", "topic", req.Topic, "count", count)
+	return &pb.DeadLettersCountResponse{Count: int32(count)}, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+func TestDeadLetterAdminAPI(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		service.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		if err := broker.Publish("telemetry", mq.Message{ID: id, Payload: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := broker.DeadLetter("telemetry", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var listed struct {
+		DeadLetters []mq.DeadLetterInfo `json:"dead_letters"`
+		Total       int                 `json:"total"`
+	}
+	rec := serve("GET", "/admin/topics/telemetry/dlq")
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || listed.Total != 3 || listed.DeadLetters[0].ID != "m1" {
+		t.Fatalf("Expected the dead letters listed, got %+v (%v)", listed, err)
+	}
+
+	if rec := serve("POST", "/admin/topics/telemetry/dlq/m1/redrive"); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a re-drive, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("DELETE", "/admin/topics/telemetry/dlq/m1"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 once the message is re-driven, got %d", rec.Code)
+	}
+	if rec := serve("DELETE", "/admin/topics/telemetry/dlq/m2"); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a purge, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var redriven struct {
+		Redriven int `json:"redriven"`
+	}
+	rec = serve("POST", "/admin/topics/telemetry/dlq/redrive")
+	if err := json.NewDecoder(rec.Body).Decode(&redriven); err != nil || redriven.Redriven != 1 {
+		t.Errorf("Expected the remaining message re-driven, got %+v (%v)", redriven, err)
+	}
+	if rec := serve("GET", "/admin/topics/.hidden/dlq"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid topic, got %d", rec.Code)
+	}
+}
+
+func TestGRPCDeadLetters(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMQServiceServer(grpcServer, NewgRPCMQService(broker, logger.NewFromEnv()))
+	go func() { _ = grpcServer.Serve(lis) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	client := pb.NewMQServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, id := range []string{"m1", "m2"} {
+		if err := broker.Publish("telemetry", mq.Message{ID: id, Payload: []byte(id), Headers: map[string]string{"source": "host-1"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := broker.DeadLetter("telemetry", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	listed, err := client.ListDeadLetters(ctx, &pb.DeadLettersRequest{Topic: "telemetry"})
+	if err != nil || len(listed.DeadLetters) != 2 {
+		t.Fatalf("Expected 2 dead letters, got %+v, %v", listed, err)
+	}
+	if letter := listed.DeadLetters[0]; letter.Id != "m1" || string(letter.Payload) != "m1" || letter.Headers["source"] != "host-1" || letter.DeadLetteredAtUnixNano == 0 {
+		t.Errorf("Unexpected dead letter %+v", letter)
+	}
+
+	if resp, err := client.RedriveDeadLetters(ctx, &pb.RedriveDeadLettersRequest{Topic: "telemetry", Ids: []string{"m1"}}); err != nil || resp.Count != 1 {
+		t.Errorf("Expected 1 message re-driven, got %+v, %v", resp, err)
+	}
+	if _, err := client.PurgeDeadLetters(ctx, &pb.PurgeDeadLettersRequest{Topic: "telemetry", Ids: []string{"m1"}}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for a re-driven message, got %v", err)
+	}
+	if resp, err := client.PurgeDeadLetters(ctx, &pb.PurgeDeadLettersRequest{Topic: "telemetry"}); err != nil || resp.Count != 1 {
+		t.Errorf("Expected 1 message purged, got %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+func TestGRPCSubscribe_MessageMetadata(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMQServiceServer(grpcServer, NewgRPCMQService(broker, logger.NewFromEnv()))
+	go func() { _ = grpcServer.Serve(lis) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	client := pb.NewMQServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.Subscribe(ctx, &pb.SubscribeRequest{Topic: "metadata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Publish until the subscription is registered and a message arrives
+	published := time.Now().Add(-time.Minute)
+	received := make(chan *pb.Message, 1)
+	go func() {
+		msg, err := stream.Recv()
+		if err == nil {
+			received <- msg
+		}
+	}()
+	var msg *pb.Message
+	for msg == nil {
+		if err := broker.Publish("metadata", mq.Message{ID: "msg-1", Timestamp: published, Payload: []byte("{}"), Headers: map[string]string{mq.HeaderSchemaVersion: "2"}}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case msg = <-received:
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("No message received")
+		}
+	}
+
+	if msg.Id != "msg-1" {
+		t.Errorf("Expected the broker's message ID, got %q", msg.Id)
+	}
+	if msg.PublishedAtUnixNano != published.UnixNano() || msg.Timestamp != published.Unix() {
+		t.Errorf("Expected the original publish time %v, got %d and %d", published, msg.PublishedAtUnixNano, msg.Timestamp)
+	}
+	if msg.RetryCount != 0 {
+		t.Errorf("Expected a first delivery, got retry count %d", msg.RetryCount)
+	}
+	if msg.Headers[mq.HeaderSchemaVersion] != "2" || msg.Headers[mq.ProtocolHeader] != mq.ProtocolVersion {
+		t.Errorf("Expected the message's headers and the protocol version, got %v", msg.Headers)
+	}
+}
+
+func TestGRPCAck(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMQServiceServer(grpcServer, NewgRPCMQService(broker, logger.NewFromEnv()))
+	go func() { _ = grpcServer.Serve(lis) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	client := pb.NewMQServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pending := func(topic string) int {
+		msgs, _ := broker.PendingMessages(topic)
+		return len(msgs)
+	}
+
+	// A subscriber speaking protocol 1.1 acknowledges messages itself
+	acking := metadata.AppendToOutgoingContext(ctx, mq.ProtocolHeader, mq.ProtocolVersion)
+	stream, err := client.Subscribe(acking, &pb.SubscribeRequest{Topic: "acked", ConsumerGroup: mq.DefaultConsumerGroup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for broker.GetSubscriberCount("acked") == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := broker.Publish("acked", mq.Message{ID: "msg-1", Payload: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := pending("acked"); n != 1 {
+		t.Fatalf("Expected the message pending until acknowledged, got %d pending", n)
+	}
+	if _, err := client.Ack(acking, &pb.AckRequest{Topic: "acked", MessageId: msg.Id, ConsumerGroup: mq.DefaultConsumerGroup}); err != nil {
+		t.Fatal(err)
+	}
+	if n := pending("acked"); n != 0 {
+		t.Errorf("Expected the message acknowledged, got %d pending", n)
+	}
+	_, err = client.Ack(acking, &pb.AckRequest{Topic: "acked", MessageId: msg.Id, ConsumerGroup: mq.DefaultConsumerGroup})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound acknowledging a message twice, got %v", err)
+	}
+
+	// Subscribers predating the Ack RPC are acknowledged as messages are sent
+	legacy, err := client.Subscribe(ctx, &pb.SubscribeRequest{Topic: "legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for broker.GetSubscriberCount("legacy") == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := broker.Publish("legacy", mq.Message{ID: "msg-1", Payload: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := legacy.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for pending("legacy") != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := pending("legacy"); n != 0 {
+		t.Errorf("Expected a legacy subscriber's message acknowledged once sent, got %d pending", n)
+	}
+}
+
+func TestStopGRPCServer_DrainTimeout(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMQServiceServer(grpcServer, NewgRPCMQService(broker, logger.NewFromEnv()))
+	go func() { _ = grpcServer.Serve(lis) }()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	stream, err := pb.NewMQServiceClient(conn).Subscribe(context.Background(), &pb.SubscribeRequest{Topic: "drain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the stream to be open on the server
+	for broker.GetSubscriberCount("drain") == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// An open Subscribe stream does not hold up shutdown past the timeout
+	start := time.Now()
+	stopGRPCServer(grpcServer, 100*time.Millisecond, logger.NewFromEnv())
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected shutdown soon after the drain timeout, took %v", elapsed)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Error("Expected the stream to be closed")
+	}
+}
+
+func TestRequestLoggerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Level: logger.INFO, Format: "text", Output: &buf})
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-7", "x-tenant-id", "acme"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/mq.MQService/Publish"}
+	_, err := unaryRequestLoggerInterceptor(log)(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		logger.FromContext(ctx).Info("deep call site")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"request_id=req-7", "route=/mq.MQService/Publish", "tenant=acme"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Log %q missing %q", buf.String(), want)
+		}
+	}
+}
+
+func TestGRPCSubscribe_Replay(t *testing.T) {
+	config := mq.DefaultBrokerConfig()
+	config.PersistenceEnabled = true
+	config.PersistenceDir = t.TempDir()
+	broker := mq.NewBroker(config)
+	defer broker.Close()
+	for _, id := range []string{"m0", "m1", "m2"} {
+		if err := broker.Publish("telemetry", mq.Message{ID: id, Payload: []byte(id)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterMQServiceServer(grpcServer, NewgRPCMQService(broker, logger.NewFromEnv()))
+	go func() { _ = grpcServer.Serve(lis) }()
+	defer grpcServer.Stop()
+
+	client, err := mq.NewGRPCBrokerClient(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ch, unsubscribe, err := client.SubscribeFrom("telemetry", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	for _, want := range []uint64{1, 2} {
+		select {
+		case msg := <-ch:
+			if msg.Offset != want || string(msg.Payload) != fmt.Sprintf("m%d", want) {
+				t.Errorf("Expected m%d at offset %d, got %s at %d", want, want, msg.Payload, msg.Offset)
+			}
+			msg.Ack()
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected offset %d replayed", want)
+		}
+	}
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := pb.NewMQServiceClient(conn).Subscribe(ctx, &pb.SubscribeRequest{Topic: "telemetry", Replay: true, FromOffset: 10})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.OutOfRange {
+		t.Errorf("Expected OutOfRange past the end of the log, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -10,16 +11,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
 	"github.com/harishb93/telemetry-pipeline/internal/logger"
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+	"github.com/harishb93/telemetry-pipeline/internal/selftest"
 	pb "github.com/harishb93/telemetry-pipeline/proto"
+	"github.com/soheilhy/cmux"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
 // gRPCMQService implements the gRPC MQ service
@@ -27,6 +36,11 @@ type gRPCMQService struct {
 	pb.UnimplementedMQServiceServer
 	broker *mq.Broker
 	logger *logger.Logger
+	routes *mq.Router
+	acks   *streamAcks
+	// adminToken authorizes topic management calls; none are allowed
+	// without one
+	adminToken string
 }
 
 // NewgRPCMQService creates a new gRPC MQ service
@@ -34,28 +48,32 @@ func NewgRPCMQService(broker *mq.Broker, logger *logger.Logger) *gRPCMQService {
 	return &gRPCMQService{
 		broker: broker,
 		logger: logger,
+		acks:   newStreamAcks(),
 	}
 }
 
 // Publish implements the Publish gRPC method
 func (s *gRPCMQService) Publish(ctx context.Context, req *pb.PublishRequest) (*pb.PublishResponse, error) {
-	messageID := fmt.Sprintf("%d", time.Now().UnixNano())
+	messageID := mq.NewMessageID()
 
 	msg := mq.Message{
+		ID:      messageID,
 		Payload: req.Payload,
+		Headers: mq.MessageHeaders(req.Headers),
 		Ack:     nil, // No acknowledgment function for published messages
 	}
 
 	if err := s.broker.Publish(req.Topic, msg); err != nil {
-		s.logger.Error("Failed to publish message", "topic", req.Topic, "error", err)
-		return &pb.PublishResponse{
-			MessageId: messageID,
-			Success:   false,
-			Error:     err.Error(),
-		}, nil
+		logger.FromContext(ctx).Error("Failed to publish message", "topic", req.Topic, "error", err)
+		return nil, mq.GRPCStatus(err)
 	}
 
-	s.logger.Debug("Message published via gRPC", "topic", req.Topic, "message_id", messageID)
+	logger.FromContext(ctx).Debug("Message published via gRPC", "topic", req.Topic, "message_id", messageID)
+	if s.routes != nil {
+		if err := s.routes.Route(s.broker, req.Topic, msg.Headers, req.Payload); err != nil {
+			logger.FromContext(ctx).Warn("Failed to route message", "topic", req.Topic, "error", err)
+		}
+	}
 
 	return &pb.PublishResponse{
 		MessageId: messageID,
@@ -65,32 +83,62 @@ func (s *gRPCMQService) Publish(ctx context.Context, req *pb.PublishRequest) (*p
 
 // Subscribe implements the Subscribe gRPC streaming method
 func (s *gRPCMQService) Subscribe(req *pb.SubscribeRequest, stream pb.MQService_SubscribeServer) error {
-	s.logger.Info("Starting gRPC subscription", "topic", req.Topic, "consumer_group", req.ConsumerGroup)
-
-	// Subscribe to the topic
-	msgCh, unsubscribe, err := s.broker.SubscribeWithAck(req.Topic)
+	s.logger.Info("Starting gRPC subscription", "topic", req.Topic, "consumer_group", req.ConsumerGroup, "replay", req.Replay, "from_offset", req.FromOffset)
+
+	// Subscribe to the topic, sharing its messages with the rest of the
+	// consumer group if there is one
+	var msgCh chan mq.Message
+	var unsubscribe func()
+	var err error
+	switch {
+	case req.Replay && req.ConsumerGroup != "":
+		return status.Errorf(codes.InvalidArgument, "consumer group %s cannot replay topic %s; replay is for subscribers outside a group", req.ConsumerGroup, req.Topic)
+	case req.Replay:
+		msgCh, unsubscribe, err = s.broker.SubscribeFrom(req.Topic, req.FromOffset)
+	case req.ConsumerGroup != "":
+		msgCh, unsubscribe, err = s.broker.SubscribeGroup(req.Topic, req.ConsumerGroup)
+	default:
+		msgCh, unsubscribe, err = s.broker.SubscribeWithAck(req.Topic)
+	}
 	if err != nil {
 		s.logger.Error("Failed to subscribe to topic", "topic", req.Topic, "error", err)
-		return fmt.Errorf("failed to subscribe to topic %s: %w", req.Topic, err)
+		return mq.GRPCStatus(fmt.Errorf("failed to subscribe to topic %s: %w", req.Topic, err))
 	}
 	defer unsubscribe()
 
-	// Handle context cancellation
+	// Subscribers speaking protocol 1.1 acknowledge each message with the
+	// Ack RPC once handled; older ones are acknowledged as messages are sent
 	ctx := stream.Context()
+	acknowledges := mq.PeerAcknowledges(ctx)
+	if acknowledges {
+		defer s.acks.release(stream)
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			s.logger.Info("gRPC subscription cancelled", "topic", req.Topic)
 			return ctx.Err()
-		case msg := <-msgCh:
+		case msg, ok := <-msgCh:
+			if !ok {
+				// The broker is closing
+				return nil
+			}
+
 			// Create protobuf message
 			pbMsg := &pb.Message{
-				Id:        fmt.Sprintf("%d", time.Now().UnixNano()),
-				Topic:     req.Topic,
-				Payload:   msg.Payload,
-				Timestamp: time.Now().Unix(),
-				Headers:   make(map[string]string),
+				Id:                  msg.ID,
+				Topic:               req.Topic,
+				Payload:             msg.Payload,
+				Timestamp:           msg.Timestamp.Unix(),
+				Headers:             mq.WireHeaders(msg.Headers),
+				RetryCount:          int32(msg.Retries),
+				PublishedAtUnixNano: msg.Timestamp.UnixNano(),
+				Offset:              msg.Offset,
+			}
+
+			if acknowledges && msg.Ack != nil {
+				s.acks.add(streamAckKey{topic: req.Topic, group: req.ConsumerGroup, id: msg.ID}, stream, msg.Ack)
 			}
 
 			// Send message to client
@@ -99,8 +147,7 @@ func (s *gRPCMQService) Subscribe(req *pb.SubscribeRequest, stream pb.MQService_
 				return err
 			}
 
-			// Acknowledge the message
-			if msg.Ack != nil {
+			if !acknowledges && msg.Ack != nil {
 				msg.Ack()
 			}
 
@@ -131,12 +178,21 @@ func (s *gRPCMQService) GetStats(ctx context.Context, req *pb.StatsRequest) (*pb
 
 	for topicName, topicStats := range stats.Topics {
 		pbTopicStats := &pb.TopicStats{
-			Topic:             topicName,
-			QueueSize:         int64(topicStats.QueueSize),
-			SubscriberCount:   int32(topicStats.SubscriberCount),
-			PendingMessages:   int64(topicStats.PendingMessages),
-			PublishedMessages: 0, // Would need to track this in broker
-			ConsumedMessages:  0, // Would need to track this in broker
+			Topic:                 topicName,
+			QueueSize:             int64(topicStats.QueueSize),
+			SubscriberCount:       int32(topicStats.SubscriberCount),
+			PendingMessages:       int64(topicStats.PendingMessages),
+			PublishedMessages:     topicStats.PublishedMessages,
+			ConsumedMessages:      topicStats.AckedMessages,
+			DeliveredMessages:     topicStats.DeliveredMessages,
+			RedeliveredMessages:   topicStats.RedeliveredMessages,
+			DeadLetteredMessages:  topicStats.DeadLetteredMessages,
+			DroppedOldestMessages: topicStats.DroppedOldestMessages,
+			DroppedNewestMessages: topicStats.DroppedNewestMessages,
+			RejectedMessages:      topicStats.RejectedMessages,
+			Paused:                topicStats.Paused,
+			FirstOffset:           topicStats.FirstOffset,
+			NextOffset:            topicStats.NextOffset,
 		}
 		pbStats.Topics[topicName] = pbTopicStats
 		pbStats.TotalMessages += pbTopicStats.QueueSize
@@ -150,6 +206,14 @@ type HTTPMQService struct {
 	broker     *mq.Broker
 	httpServer *http.Server
 	logger     *logger.Logger
+	protocol   *mq.ProtocolGuard
+	bridges    []*mq.Bridge
+	mux        *mux.Router
+	routes     *mq.Router
+	// acl is set once publishes need a client token; the routing rules API
+	// then needs adminToken, as rules move messages between any topics
+	acl        *mq.ACL
+	adminToken string
 }
 
 // NewHTTPMQService creates a new HTTP MQ service
@@ -161,11 +225,22 @@ func NewHTTPMQService(broker *mq.Broker, port string, logger *logger.Logger) *HT
 
 	router := mux.NewRouter()
 	router.HandleFunc("/publish/{topic}", service.handlePublish).Methods("POST", "OPTIONS")
+	router.HandleFunc("/publish-batch/{topic}", service.handlePublishBatch).Methods("POST")
 	router.HandleFunc("/health", service.handleHealth).Methods("GET", "OPTIONS")
 	router.HandleFunc("/stats", service.handleStats).Methods("GET", "OPTIONS")
-
+	router.Handle("/metrics", broker.MetricsHandler()).Methods("GET")
+	router.HandleFunc("/admin/topics/{topic}/pending", service.handleListPending).Methods("GET")
+	router.HandleFunc("/admin/topics/{topic}/pending/{id}/redeliver", service.handleRedeliverPending).Methods("POST")
+	router.HandleFunc("/admin/topics/{topic}/pending/{id}/dead-letter", service.handleDeadLetterPending).Methods("POST")
+	router.HandleFunc("/admin/topics/{topic}/dlq", service.handleListDeadLetters).Methods("GET")
+	router.HandleFunc("/admin/topics/{topic}/dlq", service.handlePurgeDeadLetters).Methods("DELETE")
+	router.HandleFunc("/admin/topics/{topic}/dlq/redrive", service.handleRedriveDeadLetters).Methods("POST")
+	router.HandleFunc("/admin/topics/{topic}/dlq/{id}", service.handlePurgeDeadLetters).Methods("DELETE")
+	router.HandleFunc("/admin/topics/{topic}/dlq/{id}/redrive", service.handleRedriveDeadLetters).Methods("POST")
+
+	service.mux = router
 	service.httpServer = &http.Server{
-		Addr:              ":" + port,
+		Addr:              netutil.ListenAddress(port),
 		Handler:           router,
 		ReadHeaderTimeout: 10 * time.Second,
 		ReadTimeout:       30 * time.Second,
@@ -176,6 +251,35 @@ func NewHTTPMQService(broker *mq.Broker, port string, logger *logger.Logger) *HT
 	return service
 }
 
+// SetProtocolGuard checks the protocol version of every HTTP client with
+// guard, and reports incompatible versions seen in the health response
+func (s *HTTPMQService) SetProtocolGuard(guard *mq.ProtocolGuard) {
+	s.protocol = guard
+	s.httpServer.Handler = guard.HTTPMiddleware(s.httpServer.Handler)
+}
+
+// SetACL refuses HTTP publishes, and requests for a topic's pending messages
+// and dead letters, without a client token permitting them. The routing
+// rules API then needs the admin token.
+func (s *HTTPMQService) SetACL(acl *mq.ACL) {
+	s.acl = acl
+	s.httpServer.Handler = acl.HTTPMiddleware(s.httpServer.Handler)
+}
+
+// SetRouter applies routes' rules to published messages and serves the
+// admin API managing them
+func (s *HTTPMQService) SetRouter(routes *mq.Router) {
+	s.routes = routes
+	s.mux.HandleFunc("/routes", s.authorizeRoutes(s.handleListRoutes)).Methods("GET")
+	s.mux.HandleFunc("/routes", s.authorizeRoutes(s.handleAddRoute)).Methods("POST")
+	s.mux.HandleFunc("/routes/{id}", s.authorizeRoutes(s.handleRemoveRoute)).Methods("DELETE")
+}
+
+// SetBridges reports the progress of bridges in the health response
+func (s *HTTPMQService) SetBridges(bridges []*mq.Bridge) {
+	s.bridges = bridges
+}
+
 func (s *HTTPMQService) handlePublish(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -191,8 +295,8 @@ func (s *HTTPMQService) handlePublish(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	topic := vars["topic"]
 
-	if topic == "" {
-		http.Error(w, "Topic is required", http.StatusBadRequest)
+	if err := mq.ValidateTopic(topic); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -204,19 +308,26 @@ func (s *HTTPMQService) handlePublish(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() { _ = r.Body.Close() }()
 
+	messageID := mq.NewMessageID()
 	msg := mq.Message{
+		ID:      messageID,
 		Payload: body,
+		Headers: mq.HTTPMessageHeaders(r.Header),
 		Ack:     nil,
 	}
 
-	messageID := fmt.Sprintf("%d", time.Now().UnixNano())
-
 	if err := s.broker.Publish(topic, msg); err != nil {
 		s.logger.Error("Failed to publish message", "topic", topic, "error", err)
-		http.Error(w, "Failed to publish message", http.StatusInternalServerError)
+		http.Error(w, "Failed to publish message: "+err.Error(), mq.HTTPStatus(err))
 		return
 	}
 
+	if s.routes != nil {
+		if err := s.routes.Route(s.broker, topic, msg.Headers, body); err != nil {
+			s.logger.Warn("Failed to route message", "topic", topic, "error", err)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{
 		"status":     "published",
@@ -237,12 +348,27 @@ func (s *HTTPMQService) handleHealth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	response := map[string]interface{}{
+		"status":           "healthy",
+		"service":          "mq-service",
+		"timestamp":        time.Now().UTC(),
+		"protocol_version": mq.ProtocolVersion,
+	}
+	if s.protocol != nil {
+		if versions := s.protocol.IncompatibleVersions(); len(versions) > 0 {
+			response["incompatible_peer_versions"] = versions
+		}
+	}
+	if len(s.bridges) > 0 {
+		bridges := make([]mq.BridgeStats, len(s.bridges))
+		for i, bridge := range s.bridges {
+			bridges[i] = bridge.Stats()
+		}
+		response["bridges"] = bridges
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "healthy",
-		"service":   "mq-service",
-		"timestamp": time.Now().UTC(),
-	})
+	_ = json.NewEncoder(w).Encode(response)
 }
 
 func (s *HTTPMQService) handleStats(w http.ResponseWriter, r *http.Request) {
@@ -263,13 +389,24 @@ func (s *HTTPMQService) handleStats(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *HTTPMQService) Start() error {
-	s.logger.Info("Starting HTTP MQ service", "address", s.httpServer.Addr)
+	listener, err := netutil.Listen(s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	s.Serve(listener)
+	return nil
+}
+
+// Serve serves HTTP requests from listener in the background
+func (s *HTTPMQService) Serve(listener net.Listener) {
+	s.logger.Info("Starting HTTP MQ service", "address", listener.Addr().String())
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		err := s.httpServer.Serve(listener)
+		// A shared single-port listener reports its own closure as cmux.ErrServerClosed
+		if err != nil && err != http.ErrServerClosed && !errors.Is(err, cmux.ErrServerClosed) {
 			s.logger.Error("HTTP server error", "error", err)
 		}
 	}()
-	return nil
 }
 
 func (s *HTTPMQService) Stop() error {
@@ -282,15 +419,34 @@ func (s *HTTPMQService) Stop() error {
 func main() {
 	// Initialize logger
 	log := logger.NewFromEnv().WithComponent("mq-service")
+	defer log.Flush()
+	defer log.RecoverPanic()
 
 	// Command line flags
 	var (
-		grpcPort           = flag.String("grpc-port", "9091", "gRPC server port")
-		httpPort           = flag.String("http-port", "9090", "HTTP server port")
+		grpcPort           = flag.String("grpc-port", "9091", "gRPC server port, host:port address, unix: socket path or systemd: passed socket")
+		httpPort           = flag.String("http-port", "9090", "HTTP server port, host:port address, unix: socket path or systemd: passed socket")
+		singlePort         = flag.String("single-port", "", "Serve gRPC and HTTP together on this port, host:port address, unix: socket path or systemd: passed socket (replaces --grpc-port and --http-port)")
 		persistenceEnabled = flag.Bool("persistence", true, "Enable message persistence")
 		persistenceDir     = flag.String("persistence-dir", "./mq-data", "Directory for message persistence")
+		minFreeDisk        = flag.Int64("min-free-disk-bytes", persistence.DefaultMinFreeDiskBytes, "Refuse publishes while the persistence directory's filesystem has less free space (0 never refuses them)")
 		ackTimeout         = flag.Duration("ack-timeout", 30*time.Second, "Message acknowledgment timeout")
 		maxRetries         = flag.Int("max-retries", 3, "Maximum message delivery retries")
+		maxQueueSize       = flag.Int("max-queue-size", 0, "Messages each topic queues until they are acknowledged (0 leaves queues unbounded)")
+		overflowPolicy     = flag.String("overflow-policy", string(mq.DefaultOverflowPolicy), "What publishing to a full topic does: reject, block, drop-oldest or drop-newest")
+		poisonThreshold    = flag.Int("poison-threshold", 3, "Quarantine messages failing (nacked or not acknowledged) more than this many times to <topic>"+mq.PoisonTopicSuffix+"; 0 disables quarantine")
+		encryptionKeys     = flag.String("encryption-key-file", "", "Key file for encrypting persisted messages at rest (defaults to "+encryption.KeysEnvVar+")")
+		strictProtocol     = flag.Bool("strict-protocol", false, "Refuse clients speaking an incompatible protocol version instead of only warning")
+		bridgeSources      = flag.String("bridge-sources", "", "Comma-separated gRPC addresses of remote mq-services whose topics are republished here")
+		bridgeTopics       = flag.String("bridge-topics", "telemetry", "Comma-separated topics to bridge from each remote mq-service")
+		routesFile         = flag.String("routes-file", "", "File keeping routing rules across restarts (default: routes.json in --persistence-dir when persistence is enabled)")
+		bridgeBuffer       = flag.Int("bridge-buffer", mq.DefaultBridgeConfig().BufferSize, "Messages buffered per bridge while publishing here is slow")
+		reusePort          = flag.Bool("reuse-port", false, "Listen with SO_REUSEPORT so a replacement process can take over the ports before this one stops")
+		drainTimeout       = flag.Duration("drain-timeout", 10*time.Second, "Longest shutdown waits for in-flight gRPC calls and Subscribe streams to finish")
+		aclFile            = flag.String("acl-file", "", "YAML file of client tokens and the topics each may publish and subscribe to, reloaded on SIGHUP (empty allows anonymous clients)")
+		bridgeToken        = flag.String("bridge-token", "", "Bearer token sent to remote mq-services with an ACL (defaults to MQ_BRIDGE_TOKEN)")
+		adminToken         = flag.String("admin-token", "", "Bearer token authorizing the topic management API; the API is disabled without one (defaults to MQ_ADMIN_TOKEN)")
+		selfTest           = flag.Bool("selftest", false, "Run the startup self-test, print a report and exit non-zero if a check fails")
 	)
 	flag.Parse()
 
@@ -298,17 +454,72 @@ func main() {
 	log.Info("Configuration loaded",
 		"grpc_port", *grpcPort,
 		"http_port", *httpPort,
+		"single_port", *singlePort,
 		"persistence_enabled", *persistenceEnabled,
 		"persistence_dir", *persistenceDir,
 		"ack_timeout", *ackTimeout,
-		"max_retries", *maxRetries)
+		"max_retries", *maxRetries,
+		"poison_threshold", *poisonThreshold,
+		"protocol_version", mq.ProtocolVersion,
+		"strict_protocol", *strictProtocol,
+		"bridge_sources", *bridgeSources,
+		"bridge_topics", *bridgeTopics,
+		"reuse_port", *reusePort)
+	netutil.SetReusePort(*reusePort)
+
+	// Validate listen addresses
+	if *singlePort != "" {
+		if err := netutil.Validate(*singlePort); err != nil {
+			log.Fatal("Invalid single-port listen address", "port", *singlePort, "error", err)
+		}
+	} else {
+		if err := netutil.Validate(*grpcPort); err != nil {
+			log.Fatal("Invalid gRPC listen address", "port", *grpcPort, "error", err)
+		}
+		if err := netutil.Validate(*httpPort); err != nil {
+			log.Fatal("Invalid HTTP listen address", "port", *httpPort, "error", err)
+		}
+	}
+
+	policy, err := mq.ParseOverflowPolicy(*overflowPolicy)
+	if err != nil {
+		log.Fatal("Invalid overflow policy", "error", err)
+	}
 
-	// Validate ports
-	if portNum, err := strconv.Atoi(*grpcPort); err != nil || portNum < 1 || portNum > 65535 {
-		log.Fatal("Invalid gRPC port number", "port", *grpcPort)
+	keyring, err := encryption.LoadKeyring(*encryptionKeys)
+	if err != nil {
+		log.Fatal("Failed to load encryption keys", "error", err)
+	}
+	if keyring != nil {
+		log.Info("Encryption at rest enabled", "active_key", keyring.ActiveKeyID())
+	}
+
+	if *selfTest {
+		checks := []selftest.Check{selftest.Clock(), selftest.BrokerLoopback()}
+		if *persistenceEnabled {
+			checks = append(checks, selftest.BrokerPersistence(*persistenceDir, keyring))
+		}
+		selftest.Run("mq-service", checks...).Exit()
 	}
-	if portNum, err := strconv.Atoi(*httpPort); err != nil || portNum < 1 || portNum > 65535 {
-		log.Fatal("Invalid HTTP port number", "port", *httpPort)
+
+	// Bring messages persisted by older versions up to the current layout
+	if *persistenceEnabled {
+		format := mq.PersistenceFormat(keyring)
+		from, err := format.Migrate(*persistenceDir, func(p persistence.MigrationProgress) {
+			log.Info("Migrating persistence directory",
+				"persistence_dir", *persistenceDir,
+				"to_version", p.Version,
+				"migration", p.Description,
+				"done", p.Done,
+				"total", p.Total)
+		})
+		if err != nil {
+			log.Fatal("Cannot use persistence directory", "persistence_dir", *persistenceDir, "error", err)
+		}
+		if from < format.Version() {
+			log.Info("Migrated persistence directory", "persistence_dir", *persistenceDir,
+				"from_version", from, "to_version", format.Version())
+		}
 	}
 
 	// Create broker configuration
@@ -317,58 +528,233 @@ func main() {
 		PersistenceDir:     *persistenceDir,
 		AckTimeout:         *ackTimeout,
 		MaxRetries:         *maxRetries,
+		PoisonThreshold:    *poisonThreshold,
+		Keyring:            keyring,
+		MinFreeDiskBytes:   *minFreeDisk,
+		MaxQueueSize:       *maxQueueSize,
+		OverflowPolicy:     policy,
 	}
 
 	// Create and start MQ broker
 	broker := mq.NewBroker(brokerConfig)
+	// A fatal error closes the broker first, syncing its topic logs
+	logger.RegisterExitHook("broker", broker.Close)
+	for topic, stats := range broker.GetStats().Topics {
+		if stats.ReplayedMessages > 0 {
+			log.Info("Replayed unacknowledged messages", "topic", topic, "messages", stats.ReplayedMessages)
+		}
+	}
+
+	// Clients' protocol versions are checked on every gRPC call and HTTP
+	// request
+	protocol := mq.NewProtocolGuard(*strictProtocol, log)
+	protocol.RegisterMetrics(broker.Metrics())
+
+	// Publishes and subscriptions need a client token permitting the topic
+	// once an ACL is configured
+	unaryInterceptors := []grpc.UnaryServerInterceptor{unaryRequestLoggerInterceptor(log), protocol.UnaryServerInterceptor()}
+	streamInterceptors := []grpc.StreamServerInterceptor{streamRequestLoggerInterceptor(log), protocol.StreamServerInterceptor()}
+	var acl *mq.ACL
+	if *aclFile != "" {
+		acl, err = mq.LoadACL(*aclFile)
+		if err != nil {
+			log.Fatal("Failed to load ACL", "error", err)
+		}
+		acl.RegisterMetrics(broker.Metrics())
+		unaryInterceptors = append(unaryInterceptors, acl.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, acl.StreamServerInterceptor())
+		log.Info("ACL loaded", "acl_file", *aclFile, "clients", acl.Clients())
+	} else {
+		log.Info("No ACL configured; clients may publish and subscribe without a token")
+	}
 
 	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...))
 	grpcService := NewgRPCMQService(broker, log)
 	pb.RegisterMQServiceServer(grpcServer, grpcService)
 	reflection.Register(grpcServer)
 
 	// Create HTTP service (for backward compatibility)
-	httpService := NewHTTPMQService(broker, *httpPort, log)
+	httpAddr := *httpPort
+	if *singlePort != "" {
+		httpAddr = *singlePort
+	}
+	httpService := NewHTTPMQService(broker, httpAddr, log)
+	if acl != nil {
+		// Set before the protocol guard, so refusals carry the version too
+		httpService.SetACL(acl)
+	}
+	httpService.SetProtocolGuard(protocol)
+
+	// Routing rules republish matching messages to other topics
+	if *routesFile == "" && *persistenceEnabled {
+		*routesFile = filepath.Join(*persistenceDir, "routes.json")
+	}
+	routes, err := mq.NewRouter(*routesFile)
+	if err != nil {
+		log.Fatal("Failed to load routing rules", "error", err)
+	}
+	routes.RegisterMetrics(broker.Metrics())
+	grpcService.routes = routes
+	httpService.SetRouter(routes)
+	log.Info("Routing rules loaded", "rules", len(routes.Rules()), "routes_file", *routesFile)
+
+	// Topic management is only served to callers with the admin token,
+	// read from the environment unless given, so it stays out of --help
+	if *adminToken == "" {
+		*adminToken = os.Getenv("MQ_ADMIN_TOKEN")
+	}
+	if *adminToken != "" {
+		grpcService.adminToken = *adminToken
+		httpService.SetAdminToken(*adminToken)
+		log.Info("Topic management API enabled")
+	} else {
+		log.Info("Topic management API disabled; set --admin-token to enable it")
+	}
+
+	// Bridges republish topics from remote mq-services into this broker
+	bridgeConfig := mq.DefaultBridgeConfig()
+	bridgeConfig.Topics = splitList(*bridgeTopics)
+	for _, topic := range bridgeConfig.Topics {
+		if err := mq.ValidateTopic(topic); err != nil {
+			log.Fatal("Invalid bridge topic", "error", err)
+		}
+	}
+	bridgeConfig.BufferSize = *bridgeBuffer
+	if *bridgeToken == "" {
+		*bridgeToken = os.Getenv("MQ_BRIDGE_TOKEN")
+	}
+	var bridges []*mq.Bridge
+	for _, source := range splitList(*bridgeSources) {
+		client, err := mq.NewGRPCBrokerClientWithGuard(source, protocol)
+		if err != nil {
+			log.Fatal("Failed to create bridge client", "source", source, "error", err)
+		}
+		defer client.Close()
+		client.SetToken(*bridgeToken)
+		bridges = append(bridges, mq.NewBridge(client, broker, bridgeConfig, log.WithComponent("mq-bridge")))
+	}
+	if len(bridges) > 0 {
+		mq.RegisterBridgeMetrics(broker.Metrics(), bridges...)
+		httpService.SetBridges(bridges)
+	}
 
 	// Set up signal handling for graceful shutdown
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start gRPC server
-	grpcLis, err := net.Listen("tcp", ":"+*grpcPort)
-	if err != nil {
-		log.Fatal("Failed to listen on gRPC port", "port", *grpcPort, "error", err)
+	// SIGHUP reloads the ACL; a file that fails to load keeps the current one
+	if acl != nil {
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
+		go func() {
+			for range hupCh {
+				if err := acl.Reload(); err != nil {
+					log.Error("Failed to reload ACL, keeping the current one", "acl_file", *aclFile, "error", err)
+					continue
+				}
+				log.Info("ACL reloaded", "acl_file", *aclFile, "clients", acl.Clients())
+			}
+		}()
+	}
+
+	// Open listeners: either one shared by gRPC and HTTP, or one each
+	var grpcLis net.Listener
+	var shared *singlePortListener
+	if *singlePort != "" {
+		lis, err := netutil.Listen(*singlePort)
+		if err != nil {
+			log.Fatal("Failed to listen on single port", "port", *singlePort, "error", err)
+		}
+		shared = newSinglePortListener(lis)
+		grpcLis = shared.grpc
+		httpService.Serve(shared.http)
+	} else {
+		grpcLis, err = netutil.Listen(*grpcPort)
+		if err != nil {
+			log.Fatal("Failed to listen on gRPC port", "port", *grpcPort, "error", err)
+		}
+		if err := httpService.Start(); err != nil {
+			log.Fatal("Failed to start HTTP service", "error", err)
+		}
 	}
 
+	// Start gRPC server
 	go func() {
-		log.Info("Starting gRPC server", "port", *grpcPort)
+		log.Info("Starting gRPC server", "address", grpcLis.Addr().String())
 		if err := grpcServer.Serve(grpcLis); err != nil {
 			log.Error("gRPC server error", "error", err)
 		}
 	}()
 
-	// Start HTTP server
-	if err := httpService.Start(); err != nil {
-		log.Fatal("Failed to start HTTP service", "error", err)
+	if shared != nil {
+		go func() {
+			if err := shared.Serve(); err != nil {
+				log.Error("Single-port listener error", "error", err)
+			}
+		}()
+	}
+
+	for _, bridge := range bridges {
+		bridge.Start()
 	}
 
 	log.Info("MQ Service started successfully",
-		"grpc_endpoint", "localhost:"+*grpcPort,
-		"http_endpoint", "http://localhost:"+*httpPort,
-		"health_endpoint", "http://localhost:"+*httpPort+"/health")
+		"grpc_endpoint", grpcLis.Addr().String(),
+		"http_endpoint", netutil.URL(httpAddr),
+		"health_endpoint", netutil.URL(httpAddr)+"/health")
 	log.Info("Press Ctrl+C to stop...")
 
 	// Wait for shutdown signal
 	<-sigCh
 	log.Info("Shutdown signal received, stopping MQ service...")
 
-	// Graceful shutdown
-	grpcServer.GracefulStop()
+	// Graceful shutdown; bridges flush what they buffered before the
+	// broker closes
+	for _, bridge := range bridges {
+		bridge.Stop()
+	}
+	stopGRPCServer(grpcServer, *drainTimeout, log)
 	if err := httpService.Stop(); err != nil {
 		log.Error("Error during HTTP service shutdown", "error", err)
 	}
+	if shared != nil {
+		shared.Close()
+	}
 	broker.Close()
 
 	log.Info("MQ Service stopped successfully")
 }
+
+// stopGRPCServer stops accepting calls and waits up to timeout for those in
+// progress, including Subscribe streams, before closing them
+func stopGRPCServer(server *grpc.Server, timeout time.Duration, log *logger.Logger) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+	case <-timer.C:
+		log.Warn("gRPC calls still in progress after drain timeout, closing them", "timeout", timeout)
+		server.Stop()
+		<-stopped
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
 )
 
 func TestMainFlagDefaults(t *testing.T) {
@@ -377,3 +383,26 @@ func TestServerConfiguration(t *testing.T) {
 		}
 	})
 }
+
+func TestMetricsEndpoint(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+
+	if err := broker.Publish("telemetry", mq.Message{Payload: []byte("{}")}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	service.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `mq_published_messages_total{topic="telemetry"} 1`) {
+		t.Errorf("Unexpected metrics body:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// handleListPending lists a topic's messages awaiting acknowledgment, with
+// their age and retries
+func (s *HTTPMQService) handleListPending(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	pending, err := s.broker.PendingMessages(topic)
+	if err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":   topic,
+		"pending": pending,
+		"total":   len(pending),
+	})
+}
+
+// handleRedeliverPending redelivers a pending message now
+func (s *HTTPMQService) handleRedeliverPending(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := s.broker.Redeliver(vars["topic"], vars["id"]); err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Info("Pending message redelivered on request", "topic", vars["topic"], "message_id", vars["id"])
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeadLetterPending gives up on a pending message
+func (s *HTTPMQService) handleDeadLetterPending(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := s.broker.DeadLetter(vars["topic"], vars["id"]); err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Warn("Pending message dead-lettered on request", "topic", vars["topic"], "message_id", vars["id"])
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestPendingAdminAPI(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		service.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if err := broker.Publish("telemetry", mq.Message{ID: "poison", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var listed struct {
+		Pending []mq.PendingInfo `json:"pending"`
+		Total   int              `json:"total"`
+	}
+	rec := serve("GET", "/admin/topics/telemetry/pending")
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || listed.Total != 1 || listed.Pending[0].ID != "poison" {
+		t.Fatalf("Expected the pending message listed, got %+v (%v)", listed, err)
+	}
+	if rec := serve("GET", "/admin/topics/missing/pending"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown topic, got %d", rec.Code)
+	}
+
+	if rec := serve("POST", "/admin/topics/telemetry/pending/poison/redeliver"); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a redelivery, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("POST", "/admin/topics/telemetry/pending/poison/dead-letter"); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a dead-letter, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("POST", "/admin/topics/telemetry/pending/poison/redeliver"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 once the message is dead-lettered, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// maxRequestIDLength bounds client-supplied IDs so they cannot bloat logs
+const maxRequestIDLength = 64
+
+// requestLogger returns log with the request ID, tenant and method of a gRPC
+// call. The request ID is the x-request-id or x-trace-id the client sent, or
+// a new one.
+func requestLogger(ctx context.Context, log *logger.Logger, method string) *logger.Logger {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := ""
+	for _, key := range []string{"x-request-id", "x-trace-id"} {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" && len(values[0]) <= maxRequestIDLength {
+			requestID = values[0]
+			break
+		}
+	}
+	if requestID == "" {
+		requestID = mq.NewMessageID()
+	}
+
+	fields := []interface{}{"request_id", requestID, "route", method}
+	if values := md.Get("x-tenant-id"); len(values) > 0 && values[0] != "" && len(values[0]) <= maxRequestIDLength {
+		fields = append(fields, "tenant", values[0])
+	}
+	return log.With(fields...)
+}
+
+// unaryRequestLoggerInterceptor stores a request-scoped logger in the
+// context of each unary call, for logger.FromContext
+func unaryRequestLoggerInterceptor(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(logger.NewContext(ctx, requestLogger(ctx, log, info.FullMethod)), req)
+	}
+}
+
+// streamRequestLoggerInterceptor stores a request-scoped logger in the
+// context of each stream, for logger.FromContext
+func streamRequestLoggerInterceptor(log *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := logger.NewContext(ss.Context(), requestLogger(ss.Context(), log, info.FullMethod))
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextServerStream is a server stream with a replaced context
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
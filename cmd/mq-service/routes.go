@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// handleListRoutes returns the routing rules
+func (s *HTTPMQService) handleListRoutes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules": s.routes.Rules(),
+	})
+}
+
+// handleAddRoute adds a routing rule, or replaces the rule with its ID
+func (s *HTTPMQService) handleAddRoute(w http.ResponseWriter, r *http.Request) {
+	var rule mq.RoutingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid routing rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := s.routes.AddRule(rule)
+	if errors.Is(err, mq.ErrInvalidRule) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to add routing rule", "error", err)
+		http.Error(w, "Failed to add routing rule", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("Routing rule added", "id", rule.ID, "topic", rule.Topic, "target", rule.Target)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// handleRemoveRoute removes a routing rule
+func (s *HTTPMQService) handleRemoveRoute(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := s.routes.RemoveRule(id); err != nil {
+		if errors.Is(err, mq.ErrRuleNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		s.logger.Error("Failed to remove routing rule", "id", id, "error", err)
+		http.Error(w, "Failed to remove routing rule", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("Routing rule removed", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
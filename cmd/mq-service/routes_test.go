@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestRoutesAdminAPI(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+	routes, err := mq.NewRouter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.SetRouter(routes)
+
+	serve := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		service.httpServer.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("POST", "/routes", `{"id":"site-a","topic":"telemetry","target":"telemetry.site-a","match":[{"header":"X-Site","equals":"a"}]}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("POST", "/routes", `{"topic":"telemetry"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an incomplete rule to be rejected, got %d", rec.Code)
+	}
+
+	var listed struct {
+		Rules []mq.RoutingRule `json:"rules"`
+	}
+	rec = serve("GET", "/routes", "", nil)
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || len(listed.Rules) != 1 {
+		t.Fatalf("Expected one rule, got %+v (%v)", listed, err)
+	}
+
+	// Only the publish carrying the message header is routed; request
+	// headers that are not message headers are not matched
+	serve("POST", "/publish/telemetry", `{"fields":{}}`, http.Header{"X-Message-X-Site": {"a"}})
+	serve("POST", "/publish/telemetry", `{"fields":{}}`, http.Header{"X-Message-X-Site": {"b"}})
+	serve("POST", "/publish/telemetry", `{"fields":{}}`, http.Header{"X-Site": {"a"}})
+	if got := broker.GetQueueSize("telemetry.site-a"); got != 1 {
+		t.Errorf("Expected one routed message, got %d", got)
+	}
+	if got := broker.GetQueueSize("telemetry"); got != 3 {
+		t.Errorf("Expected every message on the original topic, got %d", got)
+	}
+
+	if rec := serve("DELETE", "/routes/site-a", "", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rec.Code)
+	}
+	if rec := serve("DELETE", "/routes/site-a", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed rule, got %d", rec.Code)
+	}
+}
+
+// newSourceRoutedService serves a broker with a rule copying telemetry from
+// node-1, told by its x-source-host message header, to telemetry.node-1
+func newSourceRoutedService(t *testing.T) (*mq.Broker, *HTTPMQService) {
+	t.Helper()
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	t.Cleanup(broker.Close)
+	routes, err := mq.NewRouter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := routes.AddRule(mq.RoutingRule{ID: "node-1", Topic: "telemetry", Target: "telemetry.node-1",
+		Match: []mq.RouteMatch{{Header: "x-source-host", Equals: "node-1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+	service.SetRouter(routes)
+	return broker, service
+}
+
+// expectRouted waits for the copy routed to telemetry.node-1 and checks it
+// kept the message headers
+func expectRouted(t *testing.T, broker *mq.Broker) {
+	t.Helper()
+	ch, unsubscribe, err := broker.SubscribeWithAck("telemetry.node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	select {
+	case msg := <-ch:
+		if msg.Headers["x-source-host"] != "node-1" || msg.Headers["schema_version"] != "2" {
+			t.Errorf("Expected the routed copy to keep the message headers, got %v", msg.Headers)
+		}
+		msg.Ack()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a message routed on its x-source-host header")
+	}
+}
+
+func TestRoutes_PublishMatchesMessageHeaders(t *testing.T) {
+	broker, service := newSourceRoutedService(t)
+	server := httptest.NewServer(service.httpServer.Handler)
+	defer server.Close()
+
+	// The streamer publishes its provenance as X-Message-* request headers
+	client := mq.NewHTTPBroker(server.URL)
+	defer client.Close()
+	headers := map[string]string{"x-source-host": "node-1", "schema_version": "2"}
+	if err := client.Publish("telemetry", mq.Message{Payload: []byte(`{}`), Headers: headers}); err != nil {
+		t.Fatal(err)
+	}
+	expectRouted(t, broker)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"net"
+
+	"github.com/soheilhy/cmux"
+)
+
+// singlePortListener splits one listener into gRPC and HTTP listeners by
+// inspecting each connection: HTTP/2 requests with a gRPC content type go to
+// gRPC, everything else to HTTP. Closing either child listener closes the
+// shared one, so stopping the gRPC server also stops new HTTP connections;
+// in-flight HTTP requests are still drained by the HTTP server's Shutdown.
+type singlePortListener struct {
+	mux  cmux.CMux
+	grpc net.Listener
+	http net.Listener
+}
+
+func newSinglePortListener(listener net.Listener) *singlePortListener {
+	m := cmux.New(listener)
+	return &singlePortListener{
+		mux: m,
+		// Clients such as grpc-go wait for the server's SETTINGS frame before
+		// sending headers, so the matcher must send it
+		grpc: m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc")),
+		http: m.Match(cmux.Any()),
+	}
+}
+
+// Serve dispatches connections until the underlying listener is closed
+func (s *singlePortListener) Serve() error {
+	err := s.mux.Serve()
+	if err == nil || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// Close stops accepting connections on the shared listener
+func (s *singlePortListener) Close() {
+	s.mux.Close()
+}
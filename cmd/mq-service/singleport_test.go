@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+func TestSinglePortServing(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	log := logger.NewFromEnv()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	shared := newSinglePortListener(lis)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterMQServiceServer(grpcServer, NewgRPCMQService(broker, log))
+	go func() { _ = grpcServer.Serve(shared.grpc) }()
+
+	httpService := NewHTTPMQService(broker, addr, log)
+	httpService.Serve(shared.http)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- shared.Serve() }()
+
+	// gRPC on the shared port
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	health, err := pb.NewMQServiceClient(conn).Health(ctx, &pb.HealthRequest{})
+	if err != nil {
+		t.Fatalf("gRPC Health over shared port failed: %v", err)
+	}
+	if health.Status == "" {
+		t.Error("Expected gRPC health status")
+	}
+
+	// HTTP on the same port
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatalf("HTTP health over shared port failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected HTTP 200, got %d", resp.StatusCode)
+	}
+
+	// Shutdown in the same order as main
+	grpcServer.GracefulStop()
+	if err := httpService.Stop(); err != nil {
+		t.Errorf("HTTP shutdown failed: %v", err)
+	}
+	shared.Close()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Shared listener did not stop")
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+// topicConfigRequest is a topic configuration as sent over HTTP, with the
+// ack timeout as a duration such as "45s"
+type topicConfigRequest struct {
+	MaxQueueSize   int    `json:"max_queue_size,omitempty"`
+	OverflowPolicy string `json:"overflow_policy,omitempty"`
+	AckTimeout     string `json:"ack_timeout,omitempty"`
+}
+
+// config converts the request to a broker topic configuration
+func (req topicConfigRequest) config() (mq.TopicConfig, error) {
+	config := mq.TopicConfig{MaxQueueSize: req.MaxQueueSize, OverflowPolicy: mq.OverflowPolicy(req.OverflowPolicy)}
+	if req.AckTimeout != "" {
+		timeout, err := time.ParseDuration(req.AckTimeout)
+		if err != nil {
+			return config, fmt.Errorf("%w: ack_timeout: %w", mq.ErrInvalidTopicConfig, err)
+		}
+		config.AckTimeout = timeout
+	}
+	return config, nil
+}
+
+// topicConfigResponse reports a topic's configuration over HTTP
+func topicConfigResponse(topic string, config mq.TopicConfig) map[string]interface{} {
+	response := map[string]interface{}{
+		"topic":           topic,
+		"max_queue_size":  config.MaxQueueSize,
+		"overflow_policy": string(config.OverflowPolicy),
+	}
+	if config.AckTimeout > 0 {
+		response["ack_timeout"] = config.AckTimeout.String()
+	}
+	return response
+}
+
+// adminAuthorized reports whether authorization carries the admin token as
+// a bearer token. Without an admin token nothing is authorized.
+func adminAuthorized(token, authorization string) bool {
+	presented, ok := strings.CutPrefix(authorization, "Bearer ")
+	return token != "" && ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// SetAdminToken serves the topic management API, to requests carrying
+// token as a bearer token
+func (s *HTTPMQService) SetAdminToken(token string) {
+	s.adminToken = token
+	admin := s.requireAdmin
+	s.mux.HandleFunc("/admin/topics/{topic}", admin(s.handleCreateTopic)).Methods("PUT")
+	s.mux.HandleFunc("/admin/topics/{topic}", admin(s.handleDeleteTopic)).Methods("DELETE")
+	s.mux.HandleFunc("/admin/topics/{topic}/config", admin(s.handleGetTopicConfig)).Methods("GET")
+	s.mux.HandleFunc("/admin/topics/{topic}/config", admin(s.handleConfigureTopic)).Methods("PUT")
+	s.mux.HandleFunc("/admin/topics/{topic}/purge", admin(s.handlePurgeTopic)).Methods("POST")
+	s.mux.HandleFunc("/admin/topics/{topic}/pause", admin(s.handlePauseTopic)).Methods("POST")
+	s.mux.HandleFunc("/admin/topics/{topic}/resume", admin(s.handleResumeTopic)).Methods("POST")
+}
+
+// requireAdmin refuses requests to handler that do not carry the admin
+// token as a bearer token
+func (s *HTTPMQService) requireAdmin(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorized(s.adminToken, r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mq-admin"`)
+			http.Error(w, mq.ErrUnauthorized.Error(), mq.HTTPStatus(mq.ErrUnauthorized))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// authorizeRoutes requires the admin token for a routing rules request once
+// an ACL is set: a rule republishes between any topics, which no client's
+// per-topic permissions cover
+func (s *HTTPMQService) authorizeRoutes(handler http.HandlerFunc) http.HandlerFunc {
+	admin := s.requireAdmin(handler)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.acl == nil {
+			handler(w, r)
+			return
+		}
+		admin(w, r)
+	}
+}
+
+// decodeTopicConfig reads a topic configuration from a request body, which
+// may be empty for the broker's configuration
+func decodeTopicConfig(r *http.Request) (mq.TopicConfig, error) {
+	var req topicConfigRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return mq.TopicConfig{}, fmt.Errorf("%w: %w", mq.ErrInvalidTopicConfig, err)
+		}
+	}
+	return req.config()
+}
+
+// handleCreateTopic creates a topic with the configuration in the body
+func (s *HTTPMQService) handleCreateTopic(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	config, err := decodeTopicConfig(r)
+	if err == nil {
+		err = s.broker.CreateTopic(topic, config)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Info("Topic created on request", "topic", topic, "config", config)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(topicConfigResponse(topic, config))
+}
+
+// handleGetTopicConfig returns a topic's configuration
+func (s *HTTPMQService) handleGetTopicConfig(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	config, err := s.broker.GetTopicConfig(topic)
+	if err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(topicConfigResponse(topic, config))
+}
+
+// handleConfigureTopic replaces a topic's configuration with the one in the
+// body
+func (s *HTTPMQService) handleConfigureTopic(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	config, err := decodeTopicConfig(r)
+	if err == nil {
+		err = s.broker.ConfigureTopic(topic, config)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Info("Topic configured on request", "topic", topic, "config", config)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(topicConfigResponse(topic, config))
+}
+
+// handleDeleteTopic deletes a topic with its messages
+func (s *HTTPMQService) handleDeleteTopic(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	if err := s.broker.DeleteTopic(topic); err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Warn("Topic deleted on request", "topic", topic)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePurgeTopic discards a topic's queued messages
+func (s *HTTPMQService) handlePurgeTopic(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	count, err := s.broker.PurgeTopic(topic)
+	if err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Warn("Topic purged on request", "topic", topic, "count", count)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":  topic,
+		"purged": count,
+	})
+}
+
+// handlePauseTopic stops delivery on a topic
+func (s *HTTPMQService) handlePauseTopic(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	if err := s.broker.PauseTopic(topic); err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Info("Topic paused on request", "topic", topic)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResumeTopic restarts delivery on a paused topic
+func (s *HTTPMQService) handleResumeTopic(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+	if err := s.broker.ResumeTopic(topic); err != nil {
+		http.Error(w, err.Error(), mq.HTTPStatus(err))
+		return
+	}
+	s.logger.Info("Topic resumed on request", "topic", topic)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeAdmin checks that a topic management call carries the admin
+// token as a bearer token in its authorization metadata
+func (s *gRPCMQService) authorizeAdmin(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if adminAuthorized(s.adminToken, authorization) {
+			return nil
+		}
+	}
+	if s.adminToken == "" {
+		return mq.GRPCStatus(fmt.Errorf("%w: topic management is disabled without an admin token", mq.ErrUnauthorized))
+	}
+	return mq.GRPCStatus(mq.ErrUnauthorized)
+}
+
+// topicConfigFromProto converts a gRPC topic configuration
+func topicConfigFromProto(config *pb.TopicConfig) mq.TopicConfig {
+	return mq.TopicConfig{
+		MaxQueueSize:   int(config.GetMaxQueueSize()),
+		OverflowPolicy: mq.OverflowPolicy(config.GetOverflowPolicy()),
+		AckTimeout:     time.Duration(config.GetAckTimeoutMs()) * time.Millisecond,
+	}
+}
+
+// CreateTopic implements the CreateTopic gRPC method
+func (s *gRPCMQService) CreateTopic(ctx context.Context, req *pb.CreateTopicRequest) (*pb.TopicAdminResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	config := topicConfigFromProto(req.Config)
+	if err := s.broker.CreateTopic(req.Topic, config); err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+	s.logger.Info("Topic created via gRPC", "topic", req.Topic, "config", config)
+	return &pb.TopicAdminResponse{Topic: req.Topic}, nil
+}
+
+// ConfigureTopic implements the ConfigureTopic gRPC method
+func (s *gRPCMQService) ConfigureTopic(ctx context.Context, req *pb.CreateTopicRequest) (*pb.TopicAdminResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	config := topicConfigFromProto(req.Config)
+	if err := s.broker.ConfigureTopic(req.Topic, config); err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+	s.logger.Info("Topic configured via gRPC", "topic", req.Topic, "config", config)
+	return &pb.TopicAdminResponse{Topic: req.Topic}, nil
+}
+
+// DeleteTopic implements the DeleteTopic gRPC method
+func (s *gRPCMQService) DeleteTopic(ctx context.Context, req *pb.TopicRequest) (*pb.TopicAdminResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.broker.DeleteTopic(req.Topic); err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+	s.logger.Warn("Topic deleted via gRPC", "topic", req.Topic)
+	return &pb.TopicAdminResponse{Topic: req.Topic}, nil
+}
+
+// PurgeTopic implements the PurgeTopic gRPC method
+func (s *gRPCMQService) PurgeTopic(ctx context.Context, req *pb.TopicRequest) (*pb.TopicAdminResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	count, err := s.broker.PurgeTopic(req.Topic)
+	if err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+	s.logger.Warn("Topic purged via gRPC", "topic", req.Topic, "count", count)
+	return &pb.TopicAdminResponse{Topic: req.Topic, Count: int32(count)}, nil
+}
+
+// PauseTopic implements the PauseTopic gRPC method
+func (s *gRPCMQService) PauseTopic(ctx context.Context, req *pb.TopicRequest) (*pb.TopicAdminResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.broker.PauseTopic(req.Topic); err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+	s.logger.Info("Topic paused via gRPC", "topic", req.Topic)
+	return &pb.TopicAdminResponse{Topic: req.Topic}, nil
+}
+
+// ResumeTopic implements the ResumeTopic gRPC method
+func (s *gRPCMQService) ResumeTopic(ctx context.Context, req *pb.TopicRequest) (*pb.TopicAdminResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.broker.ResumeTopic(req.Topic); err != nil {
+		return nil, mq.GRPCStatus(err)
+	}
+	s.logger.Info("Topic resumed via gRPC", "topic", req.Topic)
+	return &pb.TopicAdminResponse{Topic: req.Topic}, nil
+}
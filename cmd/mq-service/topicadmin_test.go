@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+func TestTopicAdminAPI(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	service := NewHTTPMQService(broker, "0", logger.NewFromEnv())
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		service.httpServer.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Without an admin token the API is not served
+	if rec := serve("PUT", "/admin/topics/telemetry", "secret", `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the API disabled without an admin token, got %d", rec.Code)
+	}
+
+	service.SetAdminToken("secret")
+	if rec := serve("PUT", "/admin/topics/telemetry", "", `{}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", rec.Code)
+	}
+	if rec := serve("PUT", "/admin/topics/telemetry", "wrong", `{}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong token, got %d", rec.Code)
+	}
+
+	rec := serve("PUT", "/admin/topics/telemetry", "secret", `{"max_queue_size": 5, "overflow_policy": "drop-oldest", "ack_timeout": "45s"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 for a create, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("PUT", "/admin/topics/telemetry", "secret", ``); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an existing topic, got %d", rec.Code)
+	}
+	if rec := serve("PUT", "/admin/topics/other", "secret", `{"ack_timeout": "soon"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid configuration, got %d", rec.Code)
+	}
+
+	var config struct {
+		MaxQueueSize   int    `json:"max_queue_size"`
+		OverflowPolicy string `json:"overflow_policy"`
+		AckTimeout     string `json:"ack_timeout"`
+	}
+	rec = serve("GET", "/admin/topics/telemetry/config", "secret", "")
+	if err := json.NewDecoder(rec.Body).Decode(&config); err != nil || config.MaxQueueSize != 5 || config.OverflowPolicy != "drop-oldest" || config.AckTimeout != "45s" {
+		t.Errorf("Expected the created configuration, got %+v (%v)", config, err)
+	}
+	if rec := serve("PUT", "/admin/topics/telemetry/config", "secret", `{"max_queue_size": 10}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a configure, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := serve("POST", "/admin/topics/telemetry/pause", "secret", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a pause, got %d", rec.Code)
+	}
+	for _, id := range []string{"m1", "m2"} {
+		if err := broker.Publish("telemetry", mq.Message{ID: id, Payload: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats, _ := broker.GetTopicStats("telemetry"); !stats.Paused {
+		t.Errorf("Expected the topic paused, got %+v", stats)
+	}
+	if rec := serve("POST", "/admin/topics/telemetry/resume", "secret", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a resume, got %d", rec.Code)
+	}
+
+	var purged struct {
+		Purged int `json:"purged"`
+	}
+	rec = serve("POST", "/admin/topics/telemetry/purge", "secret", "")
+	if err := json.NewDecoder(rec.Body).Decode(&purged); err != nil || purged.Purged != 2 {
+		t.Errorf("Expected 2 messages purged, got %+v (%v)", purged, err)
+	}
+
+	if rec := serve("DELETE", "/admin/topics/telemetry", "secret", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a delete, got %d", rec.Code)
+	}
+	if rec := serve("DELETE", "/admin/topics/telemetry", "secret", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted topic, got %d", rec.Code)
+	}
+}
+
+func TestGRPCTopicAdmin(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	service := NewgRPCMQService(broker, logger.NewFromEnv())
+	pb.RegisterMQServiceServer(grpcServer, service)
+	go func() { _ = grpcServer.Serve(lis) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	client := pb.NewMQServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	authorized := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	create := &pb.CreateTopicRequest{Topic: "telemetry", Config: &pb.TopicConfig{MaxQueueSize: 5, AckTimeoutMs: 45000}}
+
+	if _, err := client.CreateTopic(authorized, create); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without an admin token, got %v", err)
+	}
+	service.adminToken = "secret"
+	if _, err := client.CreateTopic(ctx, create); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a token, got %v", err)
+	}
+
+	if _, err := client.CreateTopic(authorized, create); err != nil {
+		t.Fatal(err)
+	}
+	if config, err := broker.GetTopicConfig("telemetry"); err != nil || config.MaxQueueSize != 5 || config.AckTimeout != 45*time.Second {
+		t.Errorf("Expected the created configuration, got %+v, %v", config, err)
+	}
+	if _, err := client.CreateTopic(authorized, create); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists, got %v", err)
+	}
+	if _, err := client.ConfigureTopic(authorized, &pb.CreateTopicRequest{Topic: "telemetry", Config: &pb.TopicConfig{OverflowPolicy: "spill"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown policy, got %v", err)
+	}
+
+	if _, err := client.PauseTopic(authorized, &pb.TopicRequest{Topic: "telemetry"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish("telemetry", mq.Message{ID: "m1", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ResumeTopic(authorized, &pb.TopicRequest{Topic: "telemetry"}); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := client.PurgeTopic(authorized, &pb.TopicRequest{Topic: "telemetry"}); err != nil || resp.Count != 1 {
+		t.Errorf("Expected 1 message purged, got %+v, %v", resp, err)
+	}
+	if _, err := client.DeleteTopic(authorized, &pb.TopicRequest{Topic: "telemetry"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DeleteTopic(authorized, &pb.TopicRequest{Topic: "telemetry"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for a deleted topic, got %v", err)
+	}
+}
@@ -4,35 +4,80 @@ import (
 	"flag"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/harishb93/telemetry-pipeline/internal/collector"
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
 	"github.com/harishb93/telemetry-pipeline/internal/logger"
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
+	"github.com/harishb93/telemetry-pipeline/internal/notify"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+	"github.com/harishb93/telemetry-pipeline/internal/selftest"
 )
 
+// notifyShutdownTimeout bounds how long shutdown waits for queued alert
+// notifications to be delivered
+const notifyShutdownTimeout = 10 * time.Second
+
 func main() {
 	// Initialize logger
 	log := logger.NewFromEnv().WithComponent("collector")
+	defer log.Flush()
+	defer log.RecoverPanic()
 
 	// Command line flags
 	var (
-		workers           = flag.Int("workers", 1, "Number of worker goroutines")
+		workers           = flag.Int("workers", 1, "Number of worker goroutines decoding messages")
+		writers           = flag.Int("writers", collector.DefaultWriters, "Number of storage writers; each GPU's telemetry is stored in order by one writer")
 		dataDir           = flag.String("data-dir", "./data", "Directory for file storage")
 		maxEntriesPerGPU  = flag.Int("max-entries", 1000, "Maximum entries per GPU in memory storage")
 		checkpointEnabled = flag.Bool("checkpoint", true, "Enable checkpoint persistence")
 		checkpointDir     = flag.String("checkpoint-dir", "./checkpoints", "Directory for checkpoint files")
-		healthPort        = flag.String("health-port", "9090", "Port for health check server")
-		mqGrpcPort        = flag.String("mq-grpc-port", "9091", "Port for gRPC server")
-		mqServiceURL      = flag.String("mq-url", "http://localhost:9090", "URL of the MQ service")
+		checkpointEvery   = flag.Duration("checkpoint-interval", collector.DefaultCheckpointInterval, "Longest a storage writer's checkpoint goes without an update while messages arrive too slowly to fill a batch of 100")
+		healthPort        = flag.String("health-port", "9090", "Port, host:port address or unix: socket path for the health check server")
+		mqGrpcPort        = flag.String("mq-grpc-port", "9091", "Port of the MQ gRPC server, or its full host:port or unix: socket address")
+		mqServiceURL      = flag.String("mq-url", "http://localhost:9090", "URL of the MQ service, or a comma-separated list of URLs to fail over between in order")
 		mqTopic           = flag.String("mq-topic", "telemetry", "MQ topic to subscribe to")
+		ackBatchSize      = flag.Int("ack-batch-size", collector.DefaultAckBatchSize, "Acknowledge processed messages in batches of this many (1 acknowledges each message immediately)")
+		ackBatchInterval  = flag.Duration("ack-batch-interval", collector.DefaultAckBatchInterval, "Longest a processed message waits for its batch to be acknowledged")
+		redactFields      = flag.String("redact-fields", "", "Comma-separated fields to redact before storage, as field[:hash|drop] (e.g. pod,namespace,container:drop)")
+		energyMaxGap      = flag.Duration("energy-max-gap", collector.DefaultEnergyMaxGap, "Longest gap between power samples interpolated into the daily energy metric")
+		healthRules       = flag.String("health-rules", collector.DefaultHealthRules, "Comma-separated rules marking a GPU degraded, as [name=]metric>threshold[&metric<threshold...][:for]")
+		healthRulesFile   = flag.String("health-rules-file", "", "JSON file of additional health rules, with per-rule GPU matchers and description templates")
+		healthStaleAfter  = flag.Duration("health-stale-after", collector.DefaultHealthStaleAfter, "Report a GPU's health as unknown after this long without telemetry")
+		reportDir         = flag.String("report-dir", "", "Directory for daily per-host CSV/JSON rollup reports (empty disables)")
+		reportFormats     = flag.String("report-formats", "csv,json", "Comma-separated report formats: csv, json")
+		reportInterval    = flag.Duration("report-interval", collector.DefaultReportInterval, "How often to check for completed days that need a report")
+		hotRetention      = flag.Duration("hot-retention", 0, "Evict cached samples older than this (0 keeps the newest --max-entries per GPU whatever their age)")
+		warmRetention     = flag.Duration("warm-retention", 0, "Move stored samples older than this from the data directory to --cold-dir (0 keeps them)")
+		coldDir           = flag.String("cold-dir", "", "Directory for cold telemetry segments, e.g. a mounted object storage bucket")
+		tieringInterval   = flag.Duration("tiering-interval", collector.DefaultTieringInterval, "How often aged telemetry is moved down a storage tier")
+		maxGPUs           = flag.Int("max-gpus", collector.DefaultMaxGPUs, "Maximum GPUs tracked; samples from further GPUs are dropped (0 for no limit)")
+		maxHosts          = flag.Int("max-hosts", collector.DefaultMaxHosts, "Maximum hostnames tracked; further hosts are grouped as "+collector.OverflowHostname+" (0 for no limit)")
+		maxMetricsPerGPU  = flag.Int("max-metrics-per-gpu", collector.DefaultMaxMetricsPerGPU, "Maximum metric names tracked per GPU; further metrics are dropped (0 for no limit)")
+		sampling          = flag.String("sample", "", "Comma-separated sampling rules for chatty metrics, as metric=1/N (keep one in N) or metric=K/interval (keep K per interval); metric may be a glob")
+		notifyConfig      = flag.String("notify-config", "", "JSON file configuring SMTP, PagerDuty and webhook channels for GPU health alerts (empty disables)")
+		encryptionKeys    = flag.String("encryption-key-file", "", "Key file for encrypting telemetry files at rest (defaults to "+encryption.KeysEnvVar+")")
+		quarantine        = flag.Bool("quarantine", true, "Keep messages that cannot be decoded for inspection and re-ingestion instead of retrying them")
+		quarantineDir     = flag.String("quarantine-dir", "", "Directory for quarantined messages (default: quarantine in --data-dir)")
+		quarantineMax     = flag.Int64("quarantine-max-bytes", collector.DefaultQuarantineMaxBytes, "Maximum payload bytes kept in quarantine; the oldest messages are evicted beyond it")
+		wal               = flag.Bool("wal", true, "Log each batch of samples to a synced write-ahead log in --data-dir before acknowledging their messages, so none is lost to a crash")
+		eventsTopic       = flag.String("events-topic", collector.DefaultEventsTopic, "MQ topic the collector publishes its ingest errors, guardrail activations and evictions to (empty disables)")
+		minFreeDisk       = flag.Int64("min-free-disk-bytes", persistence.DefaultMinFreeDiskBytes, "Pause storing telemetry while the data directory's filesystem has less free space, leaving messages queued in the broker (0 never pauses)")
+		strictProtocol    = flag.Bool("strict-protocol", false, "Refuse an MQ service speaking an incompatible protocol version instead of only warning")
+		mqToken           = flag.String("mq-token", "", "Bearer token sent to an MQ service with an ACL (defaults to MQ_TOKEN)")
+		selfTest          = flag.Bool("selftest", false, "Run the startup self-test, print a report and exit non-zero if a check fails")
 	)
 	flag.Parse()
 
 	log.Info("Starting Telemetry Collector")
 	log.Info("Configuration loaded",
 		"workers", *workers,
+		"writers", *writers,
 		"data_dir", *dataDir,
 		"max_entries_per_gpu", *maxEntriesPerGPU,
 		"checkpoint_enabled", *checkpointEnabled,
@@ -40,38 +85,160 @@ func main() {
 		"health_port", *healthPort,
 		"grpc_port", *mqGrpcPort,
 		"mq_service_url", *mqServiceURL,
-		"mq_topic", *mqTopic)
-
-	// Connect to external MQ service via gRPC
-	// Parse the MQ URL to get the gRPC address
-	grpcAddr := *mqServiceURL
-	// Default to localhost if URL is not provided
-	if grpcAddr == "http://localhost:9090" {
-		grpcAddr = "localhost:" + *mqGrpcPort
-	} else {
-		// Remove http:// prefix
-		grpcAddr = strings.TrimPrefix(grpcAddr, "http://")
-		// Remove any existing port and replace with mqGrpcPort
-		if idx := strings.LastIndex(grpcAddr, ":"); idx != -1 {
-			grpcAddr = grpcAddr[:idx]
+		"mq_topic", *mqTopic,
+		"ack_batch_size", *ackBatchSize,
+		"ack_batch_interval", *ackBatchInterval)
+
+	redaction, err := collector.ParseRedactionRules(*redactFields)
+	if err != nil {
+		log.Fatal("Invalid redaction rules", "error", err)
+	}
+	redactionSalt := os.Getenv(collector.RedactionSaltEnvVar)
+	if len(redaction) > 0 {
+		log.Info("Field redaction enabled", "rules", *redactFields)
+		if redactionSalt == "" {
+			log.Warn("Redacting without a salt; hashed values of short fields can be guessed", "env", collector.RedactionSaltEnvVar)
 		}
-		grpcAddr = grpcAddr + ":" + *mqGrpcPort
 	}
 
-	broker, err := mq.NewGRPCBrokerClient(grpcAddr)
+	rules, err := collector.ParseHealthRules(*healthRules)
+	if err != nil {
+		log.Fatal("Invalid health rules", "error", err)
+	}
+	if *healthRulesFile != "" {
+		fileRules, err := collector.LoadHealthRules(*healthRulesFile)
+		if err != nil {
+			log.Fatal("Invalid health rules file", "error", err)
+		}
+		rules = append(rules, fileRules...)
+		log.Info("Health rules loaded", "file", *healthRulesFile, "rules", len(fileRules))
+	}
+
+	if err := mq.ValidateTopic(*mqTopic); err != nil {
+		log.Fatal("Invalid MQ topic", "error", err)
+	}
+
+	samplingRules, err := collector.ParseSamplingRules(*sampling)
 	if err != nil {
-		log.Fatal("Failed to connect to MQ service via gRPC", "address", grpcAddr, "error", err)
+		log.Fatal("Invalid sampling rules", "error", err)
+	}
+	if len(samplingRules) > 0 {
+		log.Info("Sampling enabled", "rules", *sampling)
+	}
+
+	formats, err := collector.ParseReportFormats(*reportFormats)
+	if err != nil {
+		log.Fatal("Invalid report formats", "error", err)
+	}
+	if *reportDir != "" {
+		log.Info("Daily reports enabled", "dir", *reportDir, "formats", *reportFormats, "interval", *reportInterval)
 	}
 
+	if *warmRetention > 0 && *coldDir == "" {
+		log.Fatal("Invalid retention tiering", "error", "--warm-retention requires --cold-dir")
+	}
+	if *hotRetention > 0 || *warmRetention > 0 {
+		log.Info("Retention tiering enabled",
+			"hot_retention", *hotRetention,
+			"warm_retention", *warmRetention,
+			"cold_dir", *coldDir,
+			"interval", *tieringInterval)
+	}
+
+	var notifier *notify.Dispatcher
+	if *notifyConfig != "" {
+		config, err := notify.LoadConfig(*notifyConfig)
+		if err != nil {
+			log.Fatal("Failed to load notifier config", "error", err)
+		}
+		notifier, err = notify.NewDispatcher(config)
+		if err != nil {
+			log.Fatal("Invalid notifier config", "error", err)
+		}
+		log.Info("Alert notifications enabled", "config", *notifyConfig, "channels", len(config.Channels))
+	}
+
+	keyring, err := encryption.LoadKeyring(*encryptionKeys)
+	if err != nil {
+		log.Fatal("Failed to load encryption keys", "error", err)
+	}
+	if keyring != nil {
+		log.Info("Encryption at rest enabled", "active_key", keyring.ActiveKeyID())
+	}
+
+	if *selfTest {
+		checks := []selftest.Check{selftest.Clock(), selftest.TelemetryStorage(*dataDir, keyring)}
+		if *checkpointEnabled {
+			checks = append(checks, selftest.Checkpoints(*checkpointDir))
+		}
+		checks = append(checks, selftest.BrokerLoopback())
+		selftest.Run("telemetry-collector", checks...).Exit()
+	}
+
+	// Connect to the external MQ services via gRPC, failing over between
+	// them in the order given
+	grpcAddrs := mqGRPCAddrs(*mqServiceURL, *mqGrpcPort)
+	broker, err := mq.NewFailoverBrokerClient(grpcAddrs, mq.NewProtocolGuard(*strictProtocol, log),
+		mq.DefaultFailoverConfig(), log.WithComponent("mq-client"))
+	if err != nil {
+		log.Fatal("Failed to connect to MQ service via gRPC", "addresses", grpcAddrs, "error", err)
+	}
+	logger.RegisterExitHook("mq-client", broker.Close)
+	// The MQ token is read from the environment unless given, so it stays
+	// out of --help
+	if *mqToken == "" {
+		*mqToken = os.Getenv("MQ_TOKEN")
+	}
+	broker.SetToken(*mqToken)
+
 	// Create collector configuration
 	collectorConfig := collector.CollectorConfig{
-		Workers:           *workers,
-		DataDir:           *dataDir,
-		MaxEntriesPerGPU:  *maxEntriesPerGPU,
-		CheckpointEnabled: *checkpointEnabled,
-		CheckpointDir:     *checkpointDir,
-		HealthPort:        *healthPort,
-		MQTopic:           *mqTopic,
+		Workers:            *workers,
+		Writers:            *writers,
+		DataDir:            *dataDir,
+		MaxEntriesPerGPU:   *maxEntriesPerGPU,
+		CheckpointEnabled:  *checkpointEnabled,
+		CheckpointDir:      *checkpointDir,
+		CheckpointInterval: *checkpointEvery,
+		HealthPort:         *healthPort,
+		MQTopic:            *mqTopic,
+		AckBatchSize:       *ackBatchSize,
+		AckBatchInterval:   *ackBatchInterval,
+		Keyring:            keyring,
+		Redaction:          redaction,
+		RedactionSalt:      redactionSalt,
+		EnergyMaxGap:       *energyMaxGap,
+		Health: collector.HealthConfig{
+			Rules:      rules,
+			StaleAfter: *healthStaleAfter,
+		},
+		Reports: collector.ReportConfig{
+			Dir:      *reportDir,
+			Formats:  formats,
+			Interval: *reportInterval,
+		},
+		Tiering: collector.TieringConfig{
+			HotAge:   *hotRetention,
+			WarmAge:  *warmRetention,
+			ColdDir:  *coldDir,
+			Interval: *tieringInterval,
+		},
+		Notifier: notifier,
+		Cardinality: collector.CardinalityConfig{
+			MaxGPUs:          *maxGPUs,
+			MaxHosts:         *maxHosts,
+			MaxMetricsPerGPU: *maxMetricsPerGPU,
+		},
+		Sampling:         samplingRules,
+		MinFreeDiskBytes: *minFreeDisk,
+		WALEnabled:       *wal,
+		EventsTopic:      *eventsTopic,
+	}
+	if *quarantine {
+		collectorConfig.Quarantine = collector.QuarantineConfig{Dir: *quarantineDir, MaxBytes: *quarantineMax}
+		if collectorConfig.Quarantine.Dir == "" {
+			collectorConfig.Quarantine.Dir = filepath.Join(*dataDir, "quarantine")
+		}
 	}
 
 	// Create collector
@@ -86,10 +253,12 @@ func main() {
 		if err := coll.Start(); err != nil {
 			log.Fatal("Failed to start collector", "error", err)
 		}
+		// From here on a fatal error stores what the collector holds first
+		logger.RegisterExitHook("collector", coll.Stop)
 	}()
 
 	log.Info("Collector started successfully",
-		"health_endpoint", "http://localhost:"+*healthPort+"/health",
+		"health_endpoint", netutil.URL(*healthPort)+"/health",
 		"mq_service_url", *mqServiceURL)
 	log.Info("Press Ctrl+C to stop...")
 
@@ -99,7 +268,32 @@ func main() {
 
 	// Graceful shutdown
 	coll.Stop()
+	notifier.Close(notifyShutdownTimeout)
 	broker.Close()
 
 	log.Info("Collector stopped successfully")
 }
+
+// mqGRPCAddrs returns the gRPC address of each MQ service in the
+// comma-separated mqURL list: its host with grpcPort. A full address given as
+// grpcPort, including a unix: socket, is used instead.
+func mqGRPCAddrs(mqURL, grpcPort string) []string {
+	if netutil.IsUnix(grpcPort) || strings.Contains(grpcPort, ":") {
+		return []string{grpcPort}
+	}
+
+	var addrs []string
+	for _, url := range strings.Split(mqURL, ",") {
+		host := strings.TrimSpace(url)
+		if host == "" {
+			continue
+		}
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "http://"), "https://")
+		// Replace any existing port with grpcPort
+		if idx := strings.LastIndex(host, ":"); idx != -1 {
+			host = host[:idx]
+		}
+		addrs = append(addrs, host+":"+grpcPort)
+	}
+	return addrs
+}
@@ -409,3 +409,20 @@ func TestGracefulShutdown(t *testing.T) {
 		}
 	})
 }
+
+func TestMQGRPCAddrs(t *testing.T) {
+	tests := []struct {
+		mqURL, grpcPort string
+		want            []string
+	}{
+		{"http://localhost:9090", "9091", []string{"localhost:9091"}},
+		{"http://mq-a:9090, http://mq-b:9090,", "9091", []string{"mq-a:9091", "mq-b:9091"}},
+		{"https://mq.example.com", "9091", []string{"mq.example.com:9091"}},
+		{"http://mq-a:9090,http://mq-b:9090", "mq-c:9191", []string{"mq-c:9191"}},
+	}
+	for _, tt := range tests {
+		if got := mqGRPCAddrs(tt.mqURL, tt.grpcPort); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("mqGRPCAddrs(%q, %q) = %v, want %v", tt.mqURL, tt.grpcPort, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// telemetry-rekey migrates data at rest to the active encryption key. Run it
+// after enabling encryption to encrypt existing plaintext data, or after
+// adding a new key at the top of the key file to rotate old data onto it.
+// The collector and MQ service must be stopped while it runs.
+func main() {
+	// Initialize logger
+	log := logger.NewFromEnv().WithComponent("rekey")
+	defer log.Flush()
+	defer log.RecoverPanic()
+
+	// Command line flags
+	var (
+		dataDir        = flag.String("data-dir", "", "Collector data directory to re-encrypt")
+		persistenceDir = flag.String("persistence-dir", "", "MQ persistence directory to re-encrypt")
+		encryptionKeys = flag.String("encryption-key-file", "", "Key file; the first key is the target key (defaults to "+encryption.KeysEnvVar+")")
+		generateKey    = flag.Bool("generate-key", false, "Print a new random AES-256 key and exit")
+	)
+	flag.Parse()
+
+	if *generateKey {
+		key, err := encryption.GenerateKey()
+		if err != nil {
+			log.Fatal("Failed to generate key", "error", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	if *dataDir == "" && *persistenceDir == "" {
+		log.Fatal("Nothing to do: set -data-dir and/or -persistence-dir")
+	}
+
+	keyring, err := encryption.LoadKeyring(*encryptionKeys)
+	if err != nil {
+		log.Fatal("Failed to load encryption keys", "error", err)
+	}
+	if keyring == nil {
+		log.Fatal("No encryption keys configured", "env", encryption.KeysEnvVar)
+	}
+
+	log.Info("Re-encrypting data", "active_key", keyring.ActiveKeyID())
+
+	if *dataDir != "" {
+		// Holding the directory lock guarantees no collector is writing
+		lock, err := persistence.AcquireDirLock(*dataDir)
+		if err != nil {
+			log.Fatal("Cannot lock data directory (is a collector running?)", "data_dir", *dataDir, "error", err)
+		}
+
+		// Never rewrite data in a layout this build does not know
+		if _, err := persistence.TelemetryFormat.Migrate(*dataDir, nil); err != nil {
+			_ = lock.Release()
+			log.Fatal("Cannot use data directory", "data_dir", *dataDir, "error", err)
+		}
+
+		storage := persistence.NewFileStorage(*dataDir)
+		storage.SetKeyring(keyring)
+		count, err := storage.Reencrypt()
+		if releaseErr := lock.Release(); releaseErr != nil {
+			log.Warn("Failed to release data directory lock", "error", releaseErr)
+		}
+		if err != nil {
+			log.Fatal("Failed to re-encrypt collector data", "data_dir", *dataDir, "records", count, "error", err)
+		}
+		log.Info("Collector data re-encrypted", "data_dir", *dataDir, "records", count)
+	}
+
+	if *persistenceDir != "" {
+		// Older layouts are converted, sealing messages with the active key
+		format := mq.PersistenceFormat(keyring)
+		from, err := format.Migrate(*persistenceDir, nil)
+		if err != nil {
+			log.Fatal("Cannot use persistence directory", "persistence_dir", *persistenceDir, "error", err)
+		}
+		if from < format.Version() {
+			log.Info("Migrated persistence directory", "persistence_dir", *persistenceDir,
+				"from_version", from, "to_version", format.Version())
+		}
+
+		count, err := mq.ReencryptPersistence(*persistenceDir, keyring)
+		if err != nil {
+			log.Fatal("Failed to re-encrypt MQ persistence", "persistence_dir", *persistenceDir, "messages", count, "error", err)
+		}
+		log.Info("MQ persistence re-encrypted", "persistence_dir", *persistenceDir, "messages", count)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "github.com/harishb93/telemetry-pipeline/internal/streamer"
+
+// handleControlSignals does nothing: SIGUSR1 and SIGUSR2 do not exist on
+// this platform, so pausing and resuming is left to the control endpoint
+func handleControlSignals(s *streamer.Streamer) {}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/harishb93/telemetry-pipeline/internal/streamer"
+)
+
+// handleControlSignals pauses publishing on SIGUSR1 and resumes it on SIGUSR2
+func handleControlSignals(s *streamer.Streamer) {
+	controlCh := make(chan os.Signal, 1)
+	signal.Notify(controlCh, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range controlCh {
+			if sig == syscall.SIGUSR1 {
+				s.Pause()
+			} else {
+				s.Resume()
+			}
+		}
+	}()
+}
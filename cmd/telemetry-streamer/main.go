@@ -2,32 +2,140 @@ package main
 
 import (
 	"flag"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/harishb93/telemetry-pipeline/internal/logger"
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
+	"github.com/harishb93/telemetry-pipeline/internal/selftest"
 	"github.com/harishb93/telemetry-pipeline/internal/streamer"
 )
 
 func main() {
 	// Initialize logger
 	log := logger.NewFromEnv().WithComponent("streamer")
+	defer log.Flush()
+	defer log.RecoverPanic()
 
 	log.Info("Telemetry Streamer starting...")
 
 	// Define CLI flags
-	csvPath := flag.String("csv-file", "", "Path to the CSV file containing telemetry data")
+	csvPath := flag.String("csv-file", "", "Path to the CSV file containing telemetry data, or a comma-separated list of paths and globs (data/*.csv) streamed in order")
 	workers := flag.Int("workers", 1, "Number of worker goroutines")
 	rate := flag.Float64("rate", 1.0, "Messages per second per worker (fractional values allowed)")
+	ramp := flag.String("ramp", "", "Ramp the rate per worker linearly, e.g. 0..500msg/s@10m, instead of a constant --rate")
+	rateProfile := flag.String("rate-profile", "", "JSON file describing the rate per worker over time (diurnal curves, spikes) instead of a constant --rate")
 	persistence := flag.Bool("persistence", false, "Enable message persistence")
 	persistenceDir := flag.String("persistence-dir", "/tmp/mq-data", "Directory for message persistence")
-	brokerURL := flag.String("broker-url", "http://localhost:9090", "URL of MQ service (default: http://localhost:9090)")
-	topic := flag.String("topic", "telemetry", "Topic to publish messages to")
+	brokerURL := flag.String("broker-url", "http://localhost:9090", "URL of MQ service, or a unix: socket address (default: http://localhost:9090)")
+	topic := flag.String("topic", "telemetry", "Topic to publish messages to; {column} placeholders such as telemetry.{hostname} are resolved per record")
+	instance := flag.String("instance", "", "Name published records are labelled with as their source_instance (default: the hostname)")
+	demo := flag.Bool("demo", false, "Stream the embedded DCGM demo dataset instead of --csv-file")
+	strict := flag.Bool("strict", false, "Fail on the first malformed CSV row instead of skipping it; the whole file is checked before streaming")
+	errorFile := flag.String("error-file", "", "Write malformed CSV rows and why they were rejected to this file, with a summary at exit")
+	statsPort := flag.String("stats-port", "", "Port, host:port address or unix: socket path serving /stats and /control/pause|resume (default: disabled)")
+	progressInterval := flag.Duration("progress-interval", streamer.DefaultProgressInterval, "How often progress is logged (0 disables progress logs)")
+	chaosData := flag.Float64("chaos-data", 0, "Percentage of records to corrupt with malformed JSON, missing fields, extreme values and duplicate UUIDs, for resiliency testing")
+	chaosSeed := flag.Int64("chaos-seed", 0, "Seed for --chaos-data, to repeat a run's corruption (default: random)")
+	dryRun := flag.Bool("dry-run", false, "Parse and validate the whole input, print a report of what would be streamed and exit without publishing")
+	strictProtocol := flag.Bool("strict-protocol", false, "Stop publishing to an MQ service speaking an incompatible protocol version instead of only warning")
+	mqToken := flag.String("mq-token", "", "Bearer token sent to an MQ service with an ACL (defaults to MQ_TOKEN)")
+	exporters := flag.String("exporters", "", "Comma-separated DCGM exporter metrics URLs; runs the streamer as an edge agent instead of streaming a CSV file")
+	scrapeInterval := flag.Duration("scrape-interval", 10*time.Second, "Edge agent: how often exporters are scraped")
+	spoolDir := flag.String("spool-dir", "/var/lib/telemetry-streamer/spool", "Edge agent: directory buffering scraped telemetry until it is forwarded")
+	spoolMaxBytes := flag.Int64("spool-max-bytes", 1<<30, "Edge agent: spool size beyond which the oldest telemetry is dropped (0 = unlimited)")
+	maxBandwidth := flag.Int("max-bandwidth", 0, "Edge agent: forwarded bytes per second (0 = unlimited)")
+	shipWindows := flag.String("ship-windows", "", "Edge agent: daily local-time windows to forward in, e.g. 22:00-06:00 (default: always)")
+	httpDefaults := mq.DefaultHTTPClientConfig()
+	httpTimeout := flag.Duration("http-timeout", httpDefaults.Timeout, "Timeout of each publish to the MQ service (0 = none)")
+	httpMaxIdleConns := flag.Int("http-max-idle-conns", httpDefaults.MaxIdleConns, "Idle connections to the MQ service kept open for reuse; raise above --workers for many workers")
+	httpIdleConnTimeout := flag.Duration("http-idle-conn-timeout", httpDefaults.IdleConnTimeout, "How long an idle connection to the MQ service is kept open")
+	httpKeepAlive := flag.Duration("http-keepalive", httpDefaults.KeepAlive, "Interval of TCP keep-alive probes on connections to the MQ service (negative disables them)")
+	selfTest := flag.Bool("selftest", false, "Run the startup self-test, print a report and exit non-zero if a check fails")
 	flag.Parse()
 
+	httpConfig := httpDefaults
+	httpConfig.Timeout = *httpTimeout
+	httpConfig.MaxIdleConns = *httpMaxIdleConns
+	httpConfig.IdleConnTimeout = *httpIdleConnTimeout
+	httpConfig.KeepAlive = *httpKeepAlive
+
+	// The MQ token is read from the environment unless given, so it stays
+	// out of --help
+	if *mqToken == "" {
+		*mqToken = os.Getenv("MQ_TOKEN")
+	}
+	newBroker := func() *mq.HTTPBroker {
+		broker := mq.NewHTTPBrokerWithConfig(*brokerURL, mq.NewProtocolGuard(*strictProtocol, log), httpConfig)
+		broker.SetToken(*mqToken)
+		return broker
+	}
+
+	if *selfTest {
+		checks := []selftest.Check{selftest.Clock(), selftest.BrokerLoopback()}
+		for _, path := range strings.Split(*csvPath, ",") {
+			// Globs may legitimately match nothing yet
+			if path = strings.TrimSpace(path); path != "" && !strings.ContainsAny(path, "*?[") {
+				checks = append(checks, selftest.Readable("csv input readable: "+path, path))
+			}
+		}
+		if *exporters != "" {
+			checks = append(checks, selftest.Writable("spool dir writable", *spoolDir))
+		}
+		selftest.Run("telemetry-streamer", checks...).Exit()
+	}
+
+	if *exporters != "" {
+		if *demo || *csvPath != "" {
+			log.Fatal("--exporters is mutually exclusive with --demo and --csv-file")
+		}
+		if strings.ContainsAny(*topic, "{}") {
+			log.Fatal("--topic templates are only supported when streaming a CSV file")
+		}
+		runAgent(log, streamer.AgentConfig{
+			Exporters:      strings.Split(*exporters, ","),
+			ScrapeInterval: *scrapeInterval,
+			Topic:          *topic,
+			SpoolDir:       *spoolDir,
+			MaxSpoolBytes:  *spoolMaxBytes,
+			MaxBandwidth:   *maxBandwidth,
+			ShipWindows:    *shipWindows,
+			Instance:       *instance,
+		}, newBroker())
+		return
+	}
+
+	if *demo {
+		if *csvPath != "" {
+			log.Fatal("--demo and --csv-file are mutually exclusive")
+		}
+
+		demoPath, err := streamer.WriteDemoDataset()
+		if err != nil {
+			log.Fatal("Failed to prepare demo dataset", "error", err)
+		}
+		defer func() { _ = os.Remove(demoPath) }()
+		*csvPath = demoPath
+
+		// Use a realistic exporter rate unless the user picked one explicitly
+		rateSet := false
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "rate" {
+				rateSet = true
+			}
+		})
+		if !rateSet {
+			*rate = streamer.DemoRate
+		}
+
+		log.Info("Demo mode enabled, streaming embedded DCGM dataset", "rate", *rate)
+	}
+
 	if *csvPath == "" {
 		log.Fatal("--csv-file flag is required")
 	}
@@ -39,58 +147,109 @@ func main() {
 	if *rate <= 0 {
 		log.Fatal("--rate must be greater than 0")
 	}
+	if *chaosData < 0 || *chaosData > 100 {
+		log.Fatal("--chaos-data must be a percentage between 0 and 100")
+	}
+	if *ramp != "" && *rateProfile != "" {
+		log.Fatal("--ramp and --rate-profile are mutually exclusive")
+	}
+	var profile *streamer.RateProfile
+	if *ramp != "" {
+		var err error
+		if profile, err = streamer.ParseRamp(*ramp); err != nil {
+			log.Fatal("Invalid --ramp", "error", err)
+		}
+	}
+	if *rateProfile != "" {
+		var err error
+		if profile, err = streamer.LoadRateProfile(*rateProfile); err != nil {
+			log.Fatal("Invalid --rate-profile", "error", err)
+		}
+	}
 
 	log.Info("Configuration loaded",
 		"csv_file", *csvPath,
 		"workers", *workers,
 		"rate", *rate,
+		"ramp", *ramp,
+		"rate_profile", *rateProfile,
 		"persistence", *persistence,
 		"persistence_dir", *persistenceDir,
 		"broker_url", *brokerURL,
-		"topic", *topic)
-
-	// Initialize the message broker with configuration
-	var broker mq.BrokerInterface
+		"topic", *topic,
+		"strict", *strict,
+		"error_file", *errorFile,
+		"stats_port", *statsPort,
+		"progress_interval", *progressInterval,
+		"chaos_data", *chaosData,
+		"dry_run", *dryRun,
+		"demo", *demo)
 
-	// Always use HTTP broker to connect to MQ service
-	log.Info("Connecting to MQ service", "url", *brokerURL)
-	broker = mq.NewHTTPBroker(*brokerURL)
+	files, err := streamer.ResolveCSVFiles(*csvPath)
+	if err != nil {
+		log.Fatal("Invalid --csv-file", "error", err)
+	}
 
-	// Check if list of HostNames are provided and pre-process csv file with HostNames
+	// Check if list of HostNames are provided and pre-process csv files with HostNames
 	hostList := os.Getenv("HOSTNAME_LIST")
 	finalCSVPath := *csvPath
 
 	if hostList != "" && strings.TrimSpace(hostList) != "" {
-		log.Info("HOSTNAME_LIST environment variable found, preprocessing CSV file",
+		log.Info("HOSTNAME_LIST environment variable found, preprocessing CSV files",
 			"hostname_list", hostList,
 			"original_csv", *csvPath)
 
-		processedCSVPath, err := streamer.PreProcessCSVByHostNames(*csvPath, hostList)
-		if err != nil {
-			log.Info("Failed to preprocess CSV file, continuing with original file",
-				"error", err,
-				"original_csv", *csvPath)
-		} else if processedCSVPath != *csvPath {
-			log.Info("CSV preprocessing successful, using filtered file",
-				"original_csv", *csvPath,
-				"processed_csv", processedCSVPath)
-			finalCSVPath = processedCSVPath
-		} else {
-			log.Info("CSV preprocessing completed, continuing with original file",
-				"csv", *csvPath)
+		processed := make([]string, len(files))
+		for i, path := range files {
+			processed[i] = preprocessCSV(log, path, hostList)
 		}
+		finalCSVPath = strings.Join(processed, ",")
 	} else {
 		log.Debug("No HOSTNAME_LIST environment variable found, using original CSV file",
 			"csv", *csvPath)
 	}
 
+	if *dryRun {
+		runDryRun(log, finalCSVPath, *topic, *workers, *rate, *strict, *errorFile)
+		return
+	}
+
+	// Initialize the message broker with configuration
+	var broker mq.BrokerInterface
+
+	// Always use HTTP broker to connect to MQ service
+	log.Info("Connecting to MQ service", "url", *brokerURL)
+	broker = newBroker()
+
 	// Create the streamer with the final CSV path (either original or filtered)
 	s := streamer.NewStreamer(finalCSVPath, *workers, *rate, *topic, broker)
+	s.SetStrict(*strict)
+	s.SetErrorFile(*errorFile)
+	s.SetProgressInterval(*progressInterval)
+	if *instance != "" {
+		s.SetInstance(*instance)
+	}
+	if profile != nil {
+		s.SetRateProfile(profile)
+	}
+	if *chaosData > 0 {
+		log.Warn("Chaos mode enabled, publishing corrupted records", "percent", *chaosData, "seed", *chaosSeed)
+		s.SetChaos(*chaosData, *chaosSeed)
+	}
 
 	// Start the streamer
 	if err := s.Start(); err != nil {
 		log.Fatal("Failed to start streamer", "error", err)
 	}
+	logger.RegisterExitHook("streamer", s.Stop)
+
+	if *statsPort != "" {
+		statsServer, err := serveStats(log, *statsPort, s.StatsHandler(), s.ControlHandler())
+		if err != nil {
+			log.Fatal("Failed to start stats server", "error", err)
+		}
+		defer func() { _ = statsServer.Close() }()
+	}
 
 	// Handle graceful shutdown
 	signalCh := make(chan os.Signal, 1)
@@ -102,9 +261,100 @@ func main() {
 		"total_rate", float64(*workers)*(*rate))
 	log.Info("Press Ctrl+C to stop...")
 
-	<-signalCh
-	log.Info("Received shutdown signal, stopping streamer...")
+	handleControlSignals(s)
+
+	select {
+	case <-signalCh:
+		log.Info("Received shutdown signal, stopping streamer...")
+	case <-s.Done():
+	}
 
 	s.Stop()
+	if err := s.Err(); err != nil {
+		log.Fatal("Streamer stopped", "error", err)
+	}
 	log.Info("Streamer stopped gracefully")
 }
+
+// preprocessCSV filters a CSV file to the hosts in hostList, returning the
+// file to stream: the filtered copy, or the original if filtering fails or
+// matches nothing
+func preprocessCSV(log *logger.Logger, csvPath, hostList string) string {
+	processedCSVPath, err := streamer.PreProcessCSVByHostNames(csvPath, hostList)
+	if err != nil {
+		log.Info("Failed to preprocess CSV file, continuing with original file",
+			"error", err,
+			"original_csv", csvPath)
+		return csvPath
+	}
+	if processedCSVPath != csvPath {
+		log.Info("CSV preprocessing successful, using filtered file",
+			"original_csv", csvPath,
+			"processed_csv", processedCSVPath)
+	} else {
+		log.Info("CSV preprocessing completed, continuing with original file",
+			"csv", csvPath)
+	}
+	return processedCSVPath
+}
+
+// serveStats serves the streamer's progress at /stats and its pause and
+// resume controls under /control/ on addr
+func serveStats(log *logger.Logger, addr string, stats, control http.Handler) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /stats", stats)
+	mux.Handle("/control/", control)
+
+	listener, err := netutil.Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{
+		Addr:              netutil.ListenAddress(addr),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Info("Stats server starting", "address", server.Addr)
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("Stats server error", "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// runDryRun reports on the CSV file without connecting to the MQ service. In
+// strict mode any malformed row fails the run.
+func runDryRun(log *logger.Logger, csvPath, topic string, workers int, rate float64, strict bool, errorFile string) {
+	report, err := streamer.DryRun(csvPath, topic, workers, rate, errorFile)
+	if err != nil {
+		log.Fatal("Dry run failed", "error", err)
+	}
+	if err := report.Print(os.Stdout); err != nil {
+		log.Fatal("Failed to print dry run report", "error", err)
+	}
+	if strict && report.Malformed > 0 {
+		log.Fatal("Dry run found malformed rows", "malformed", report.Malformed)
+	}
+}
+
+// runAgent runs the streamer as an edge agent until it is signalled to stop
+func runAgent(log *logger.Logger, config streamer.AgentConfig, broker mq.BrokerInterface) {
+	for i, exporter := range config.Exporters {
+		config.Exporters[i] = strings.TrimSpace(exporter)
+	}
+	agent, err := streamer.NewAgent(config, broker)
+	if err != nil {
+		log.Fatal("Failed to create edge agent", "error", err)
+	}
+	agent.Start()
+
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
+	log.Info("Edge agent running", "exporters", config.Exporters, "topic", config.Topic)
+	<-signalCh
+
+	log.Info("Received shutdown signal, stopping edge agent...")
+	agent.Stop()
+	log.Info("Edge agent stopped gracefully", "stats", agent.Stats())
+}
@@ -5,6 +5,18 @@ set -e
 PORT=${PORT:-"8081"}
 DATA_DIR=${DATA_DIR:-"/data"}
 COLLECTOR_PORT=${COLLECTOR_PORT:-"8080"}
+BASE_PATH=${BASE_PATH:-""}
+UI_ENABLED=${UI_ENABLED:-"true"}
+MAX_SERIES_POINTS=${MAX_SERIES_POINTS:-""}
+USAGE_KEY_HEADER=${USAGE_KEY_HEADER:-""}
+USAGE_EXPORT_DIR=${USAGE_EXPORT_DIR:-""}
+TLS_CERT=${TLS_CERT:-""}
+TLS_KEY=${TLS_KEY:-""}
+HSTS_MAX_AGE=${HSTS_MAX_AGE:-""}
+ACCESS_LOG_SUCCESS_RATE=${ACCESS_LOG_SUCCESS_RATE:-""}
+ACCESS_LOG_ERROR_RATE=${ACCESS_LOG_ERROR_RATE:-""}
+REUSE_PORT=${REUSE_PORT:-"false"}
+SELFTEST=${SELFTEST:-"false"}
 LOG_LEVEL=${LOG_LEVEL:-"INFO"}
 LOG_FORMAT=${LOG_FORMAT:-"text"}
 
@@ -23,6 +35,54 @@ if [ -n "$COLLECTOR_PORT" ]; then
     ARGS="$ARGS -collector-port=$COLLECTOR_PORT"
 fi
 
+if [ -n "$BASE_PATH" ]; then
+    ARGS="$ARGS -base-path=$BASE_PATH"
+fi
+
+if [ -n "$MAX_SERIES_POINTS" ]; then
+    ARGS="$ARGS -max-series-points=$MAX_SERIES_POINTS"
+fi
+
+if [ -n "$USAGE_KEY_HEADER" ]; then
+    ARGS="$ARGS -usage-key-header=$USAGE_KEY_HEADER"
+fi
+
+if [ -n "$USAGE_EXPORT_DIR" ]; then
+    ARGS="$ARGS -usage-export-dir=$USAGE_EXPORT_DIR"
+fi
+
+if [ -n "$TLS_CERT" ]; then
+    ARGS="$ARGS -tls-cert=$TLS_CERT"
+fi
+
+if [ -n "$TLS_KEY" ]; then
+    ARGS="$ARGS -tls-key=$TLS_KEY"
+fi
+
+if [ -n "$HSTS_MAX_AGE" ]; then
+    ARGS="$ARGS -hsts-max-age=$HSTS_MAX_AGE"
+fi
+
+if [ -n "$ACCESS_LOG_SUCCESS_RATE" ]; then
+    ARGS="$ARGS -access-log-success-rate=$ACCESS_LOG_SUCCESS_RATE"
+fi
+
+if [ -n "$ACCESS_LOG_ERROR_RATE" ]; then
+    ARGS="$ARGS -access-log-error-rate=$ACCESS_LOG_ERROR_RATE"
+fi
+
+if [ "$REUSE_PORT" = "true" ]; then
+    ARGS="$ARGS -reuse-port"
+fi
+
+if [ "$UI_ENABLED" != "true" ]; then
+    ARGS="$ARGS -ui=false"
+fi
+
+if [ "$SELFTEST" = "true" ]; then
+    ARGS="$ARGS -selftest"
+fi
+
 # Add any additional arguments passed to the container
 ARGS="$ARGS $@"
 
@@ -3,14 +3,41 @@ set -e
 
 # Default values
 WORKERS=${WORKERS:-"1"}
+WRITERS=${WRITERS:-""}
 DATA_DIR=${DATA_DIR:-"/data"}
 MAX_ENTRIES=${MAX_ENTRIES:-"10000"}
 HEALTH_PORT=${HEALTH_PORT:-"8080"}
 MQ_URL=${MQ_URL:-"http://localhost:9090"}
 TOPIC=${TOPIC:-"telemetry"}
 CHECKPOINT_ENABLED=${CHECKPOINT_ENABLED:-"true"}
+CHECKPOINT_INTERVAL=${CHECKPOINT_INTERVAL:-""}
 LOG_LEVEL=${LOG_LEVEL:-"INFO"}
 LOG_FORMAT=${LOG_FORMAT:-"text"}
+ENCRYPTION_KEY_FILE=${ENCRYPTION_KEY_FILE:-""}
+REDACT_FIELDS=${REDACT_FIELDS:-""}
+HEALTH_RULES=${HEALTH_RULES:-""}
+HEALTH_RULES_FILE=${HEALTH_RULES_FILE:-""}
+REPORT_DIR=${REPORT_DIR:-""}
+REPORT_FORMATS=${REPORT_FORMATS:-""}
+HOT_RETENTION=${HOT_RETENTION:-""}
+WARM_RETENTION=${WARM_RETENTION:-""}
+COLD_DIR=${COLD_DIR:-""}
+QUARANTINE_ENABLED=${QUARANTINE_ENABLED:-"true"}
+QUARANTINE_DIR=${QUARANTINE_DIR:-""}
+QUARANTINE_MAX_BYTES=${QUARANTINE_MAX_BYTES:-""}
+MIN_FREE_DISK_BYTES=${MIN_FREE_DISK_BYTES:-""}
+WAL_ENABLED=${WAL_ENABLED:-"true"}
+EVENTS_ENABLED=${EVENTS_ENABLED:-"true"}
+EVENTS_TOPIC=${EVENTS_TOPIC:-""}
+NOTIFY_CONFIG=${NOTIFY_CONFIG:-""}
+MAX_GPUS=${MAX_GPUS:-""}
+MAX_HOSTS=${MAX_HOSTS:-""}
+MAX_METRICS_PER_GPU=${MAX_METRICS_PER_GPU:-""}
+SAMPLE_RULES=${SAMPLE_RULES:-""}
+ACK_BATCH_SIZE=${ACK_BATCH_SIZE:-""}
+ACK_BATCH_INTERVAL=${ACK_BATCH_INTERVAL:-""}
+STRICT_PROTOCOL=${STRICT_PROTOCOL:-"false"}
+SELFTEST=${SELFTEST:-"false"}
 
 # Build command line arguments
 ARGS=""
@@ -19,6 +46,10 @@ if [ -n "$WORKERS" ]; then
     ARGS="$ARGS -workers=$WORKERS"
 fi
 
+if [ -n "$WRITERS" ]; then
+    ARGS="$ARGS -writers=$WRITERS"
+fi
+
 if [ -n "$DATA_DIR" ]; then
     ARGS="$ARGS -data-dir=$DATA_DIR"
 fi
@@ -41,6 +72,106 @@ fi
 
 if [ "$CHECKPOINT_ENABLED" = "true" ]; then
     ARGS="$ARGS -checkpoint"
+    if [ -n "$CHECKPOINT_INTERVAL" ]; then
+        ARGS="$ARGS -checkpoint-interval=$CHECKPOINT_INTERVAL"
+    fi
+fi
+
+if [ -n "$REDACT_FIELDS" ]; then
+    ARGS="$ARGS -redact-fields=$REDACT_FIELDS"
+fi
+
+if [ -n "$ENCRYPTION_KEY_FILE" ]; then
+    ARGS="$ARGS -encryption-key-file=$ENCRYPTION_KEY_FILE"
+fi
+
+if [ -n "$HEALTH_RULES" ]; then
+    ARGS="$ARGS -health-rules=$HEALTH_RULES"
+fi
+
+if [ -n "$HEALTH_RULES_FILE" ]; then
+    ARGS="$ARGS -health-rules-file=$HEALTH_RULES_FILE"
+fi
+
+if [ -n "$REPORT_DIR" ]; then
+    ARGS="$ARGS -report-dir=$REPORT_DIR"
+fi
+
+if [ -n "$REPORT_FORMATS" ]; then
+    ARGS="$ARGS -report-formats=$REPORT_FORMATS"
+fi
+
+if [ -n "$HOT_RETENTION" ]; then
+    ARGS="$ARGS -hot-retention=$HOT_RETENTION"
+fi
+
+if [ -n "$WARM_RETENTION" ]; then
+    ARGS="$ARGS -warm-retention=$WARM_RETENTION"
+fi
+
+if [ -n "$COLD_DIR" ]; then
+    ARGS="$ARGS -cold-dir=$COLD_DIR"
+fi
+
+if [ "$QUARANTINE_ENABLED" = "true" ]; then
+    if [ -n "$QUARANTINE_DIR" ]; then
+        ARGS="$ARGS -quarantine-dir=$QUARANTINE_DIR"
+    fi
+    if [ -n "$QUARANTINE_MAX_BYTES" ]; then
+        ARGS="$ARGS -quarantine-max-bytes=$QUARANTINE_MAX_BYTES"
+    fi
+else
+    ARGS="$ARGS -quarantine=false"
+fi
+
+if [ -n "$MIN_FREE_DISK_BYTES" ]; then
+    ARGS="$ARGS -min-free-disk-bytes=$MIN_FREE_DISK_BYTES"
+fi
+
+if [ "$WAL_ENABLED" = "false" ]; then
+    ARGS="$ARGS -wal=false"
+fi
+
+if [ "$EVENTS_ENABLED" = "false" ]; then
+    ARGS="$ARGS -events-topic="
+elif [ -n "$EVENTS_TOPIC" ]; then
+    ARGS="$ARGS -events-topic=$EVENTS_TOPIC"
+fi
+
+if [ -n "$NOTIFY_CONFIG" ]; then
+    ARGS="$ARGS -notify-config=$NOTIFY_CONFIG"
+fi
+
+if [ -n "$MAX_GPUS" ]; then
+    ARGS="$ARGS -max-gpus=$MAX_GPUS"
+fi
+
+if [ -n "$MAX_HOSTS" ]; then
+    ARGS="$ARGS -max-hosts=$MAX_HOSTS"
+fi
+
+if [ -n "$MAX_METRICS_PER_GPU" ]; then
+    ARGS="$ARGS -max-metrics-per-gpu=$MAX_METRICS_PER_GPU"
+fi
+
+if [ -n "$SAMPLE_RULES" ]; then
+    ARGS="$ARGS -sample=$SAMPLE_RULES"
+fi
+
+if [ -n "$ACK_BATCH_SIZE" ]; then
+    ARGS="$ARGS -ack-batch-size=$ACK_BATCH_SIZE"
+fi
+
+if [ -n "$ACK_BATCH_INTERVAL" ]; then
+    ARGS="$ARGS -ack-batch-interval=$ACK_BATCH_INTERVAL"
+fi
+
+if [ "$STRICT_PROTOCOL" = "true" ]; then
+    ARGS="$ARGS -strict-protocol"
+fi
+
+if [ "$SELFTEST" = "true" ]; then
+    ARGS="$ARGS -selftest"
 fi
 
 # Add any additional arguments passed to the container
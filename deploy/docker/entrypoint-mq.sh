@@ -6,10 +6,27 @@ GRPC_PORT=${GRPC_PORT:-"9091"}
 HTTP_PORT=${HTTP_PORT:-"9090"}
 PERSISTENCE_ENABLED=${PERSISTENCE_ENABLED:-"true"}
 PERSISTENCE_DIR=${PERSISTENCE_DIR:-"/var/lib/mq"}
+MIN_FREE_DISK_BYTES=${MIN_FREE_DISK_BYTES:-""}
 MAX_RETRIES=${MAX_RETRIES:-"3"}
+POISON_THRESHOLD=${POISON_THRESHOLD:-""}
+MAX_QUEUE_SIZE=${MAX_QUEUE_SIZE:-""}
+OVERFLOW_POLICY=${OVERFLOW_POLICY:-""}
 ACK_TIMEOUT=${ACK_TIMEOUT:-"30s"}
 LOG_LEVEL=${LOG_LEVEL:-"INFO"}
 LOG_FORMAT=${LOG_FORMAT:-"text"}
+ENCRYPTION_KEY_FILE=${ENCRYPTION_KEY_FILE:-""}
+SINGLE_PORT=${SINGLE_PORT:-""}
+STRICT_PROTOCOL=${STRICT_PROTOCOL:-"false"}
+ROUTES_FILE=${ROUTES_FILE:-""}
+BRIDGE_SOURCES=${BRIDGE_SOURCES:-""}
+BRIDGE_TOPICS=${BRIDGE_TOPICS:-""}
+BRIDGE_BUFFER=${BRIDGE_BUFFER:-""}
+REUSE_PORT=${REUSE_PORT:-"false"}
+DRAIN_TIMEOUT=${DRAIN_TIMEOUT:-""}
+ADMIN_TOKEN=${ADMIN_TOKEN:-""}
+ACL_FILE=${ACL_FILE:-""}
+BRIDGE_TOKEN=${BRIDGE_TOKEN:-""}
+SELFTEST=${SELFTEST:-"false"}
 
 # Build command line arguments
 ARGS=""
@@ -27,6 +44,9 @@ if [ "$PERSISTENCE_ENABLED" = "true" ]; then
     if [ -n "$PERSISTENCE_DIR" ]; then
         ARGS="$ARGS -persistence-dir=$PERSISTENCE_DIR"
     fi
+    if [ -n "$MIN_FREE_DISK_BYTES" ]; then
+        ARGS="$ARGS -min-free-disk-bytes=$MIN_FREE_DISK_BYTES"
+    fi
 fi
 
 
@@ -35,10 +55,66 @@ if [ -n "$MAX_RETRIES" ]; then
     ARGS="$ARGS -max-retries=$MAX_RETRIES"
 fi
 
+if [ -n "$POISON_THRESHOLD" ]; then
+    ARGS="$ARGS -poison-threshold=$POISON_THRESHOLD"
+fi
+
+if [ -n "$MAX_QUEUE_SIZE" ]; then
+    ARGS="$ARGS -max-queue-size=$MAX_QUEUE_SIZE"
+fi
+
+if [ -n "$OVERFLOW_POLICY" ]; then
+    ARGS="$ARGS -overflow-policy=$OVERFLOW_POLICY"
+fi
+
 if [ -n "$ACK_TIMEOUT" ]; then
     ARGS="$ARGS -ack-timeout=$ACK_TIMEOUT"
 fi
 
+if [ -n "$ENCRYPTION_KEY_FILE" ]; then
+    ARGS="$ARGS -encryption-key-file=$ENCRYPTION_KEY_FILE"
+fi
+
+if [ -n "$SINGLE_PORT" ]; then
+    ARGS="$ARGS -single-port=$SINGLE_PORT"
+fi
+
+if [ "$STRICT_PROTOCOL" = "true" ]; then
+    ARGS="$ARGS -strict-protocol"
+fi
+
+if [ -n "$ROUTES_FILE" ]; then
+    ARGS="$ARGS -routes-file=$ROUTES_FILE"
+fi
+
+if [ -n "$BRIDGE_SOURCES" ]; then
+    ARGS="$ARGS -bridge-sources=$BRIDGE_SOURCES"
+fi
+
+if [ -n "$BRIDGE_TOPICS" ]; then
+    ARGS="$ARGS -bridge-topics=$BRIDGE_TOPICS"
+fi
+
+if [ -n "$BRIDGE_BUFFER" ]; then
+    ARGS="$ARGS -bridge-buffer=$BRIDGE_BUFFER"
+fi
+
+if [ -n "$ACL_FILE" ]; then
+    ARGS="$ARGS -acl-file=$ACL_FILE"
+fi
+
+if [ "$REUSE_PORT" = "true" ]; then
+    ARGS="$ARGS -reuse-port"
+fi
+
+if [ -n "$DRAIN_TIMEOUT" ]; then
+    ARGS="$ARGS -drain-timeout=$DRAIN_TIMEOUT"
+fi
+
+if [ "$SELFTEST" = "true" ]; then
+    ARGS="$ARGS -selftest"
+fi
+
 # Add any additional arguments passed to the container (if not the default CMD)
 if [ "$#" -gt 0 ] && [ "$1" != "mq-service" ]; then
     ARGS="$ARGS $@"
@@ -48,6 +124,15 @@ fi
 export LOG_LEVEL="$LOG_LEVEL"
 export LOG_FORMAT="$LOG_FORMAT"
 
+# Pass the admin token in the environment, keeping it out of the arguments
+# logged below and the process list
+if [ -n "$ADMIN_TOKEN" ]; then
+    export MQ_ADMIN_TOKEN="$ADMIN_TOKEN"
+fi
+if [ -n "$BRIDGE_TOKEN" ]; then
+    export MQ_BRIDGE_TOKEN="$BRIDGE_TOKEN"
+fi
+
 # Log the command being executed
 echo "Starting mq-service with arguments: $ARGS"
 echo "Log level: $LOG_LEVEL, format: $LOG_FORMAT"
@@ -9,11 +9,54 @@ RATE=${RATE:-"10.0"}
 WORKERS=${WORKERS:-"2"}
 LOG_LEVEL=${LOG_LEVEL:-"INFO"}
 LOG_FORMAT=${LOG_FORMAT:-"text"}
+DEMO=${DEMO:-"false"}
+STRICT_PROTOCOL=${STRICT_PROTOCOL:-"false"}
+STRICT=${STRICT:-"false"}
+ERROR_FILE=${ERROR_FILE:-""}
+DRY_RUN=${DRY_RUN:-"false"}
+RAMP=${RAMP:-""}
+CHAOS_DATA=${CHAOS_DATA:-""}
+CHAOS_SEED=${CHAOS_SEED:-""}
+RATE_PROFILE=${RATE_PROFILE:-""}
+STATS_PORT=${STATS_PORT:-""}
+PROGRESS_INTERVAL=${PROGRESS_INTERVAL:-""}
+EXPORTERS=${EXPORTERS:-""}
+SCRAPE_INTERVAL=${SCRAPE_INTERVAL:-""}
+SPOOL_DIR=${SPOOL_DIR:-""}
+SPOOL_MAX_BYTES=${SPOOL_MAX_BYTES:-""}
+MAX_BANDWIDTH=${MAX_BANDWIDTH:-""}
+SHIP_WINDOWS=${SHIP_WINDOWS:-""}
+SELFTEST=${SELFTEST:-"false"}
+HTTP_TIMEOUT=${HTTP_TIMEOUT:-""}
+HTTP_MAX_IDLE_CONNS=${HTTP_MAX_IDLE_CONNS:-""}
+HTTP_IDLE_CONN_TIMEOUT=${HTTP_IDLE_CONN_TIMEOUT:-""}
+HTTP_KEEPALIVE=${HTTP_KEEPALIVE:-""}
+STREAMER_INSTANCE=${STREAMER_INSTANCE:-""}
 
 # Build command line arguments
 ARGS=""
 
-if [ -n "$CSV_FILE" ]; then
+if [ -n "$EXPORTERS" ]; then
+    # Edge agent mode: scrape local exporters instead of streaming a CSV file
+    ARGS="$ARGS -exporters=$EXPORTERS"
+    if [ -n "$SCRAPE_INTERVAL" ]; then
+        ARGS="$ARGS -scrape-interval=$SCRAPE_INTERVAL"
+    fi
+    if [ -n "$SPOOL_DIR" ]; then
+        ARGS="$ARGS -spool-dir=$SPOOL_DIR"
+    fi
+    if [ -n "$SPOOL_MAX_BYTES" ]; then
+        ARGS="$ARGS -spool-max-bytes=$SPOOL_MAX_BYTES"
+    fi
+    if [ -n "$MAX_BANDWIDTH" ]; then
+        ARGS="$ARGS -max-bandwidth=$MAX_BANDWIDTH"
+    fi
+    if [ -n "$SHIP_WINDOWS" ]; then
+        ARGS="$ARGS -ship-windows=$SHIP_WINDOWS"
+    fi
+elif [ "$DEMO" = "true" ]; then
+    ARGS="$ARGS -demo"
+elif [ -n "$CSV_FILE" ]; then
     ARGS="$ARGS -csv-file=$CSV_FILE"
 fi
 
@@ -29,10 +72,74 @@ if [ -n "$RATE" ]; then
     ARGS="$ARGS -rate=$RATE"
 fi
 
+if [ -n "$RAMP" ]; then
+    ARGS="$ARGS -ramp=$RAMP"
+fi
+
+if [ -n "$RATE_PROFILE" ]; then
+    ARGS="$ARGS -rate-profile=$RATE_PROFILE"
+fi
+
 if [ -n "$WORKERS" ]; then
     ARGS="$ARGS -workers=$WORKERS"
 fi
 
+if [ "$STRICT" = "true" ]; then
+    ARGS="$ARGS -strict"
+fi
+
+if [ -n "$ERROR_FILE" ]; then
+    ARGS="$ARGS -error-file=$ERROR_FILE"
+fi
+
+if [ -n "$STATS_PORT" ]; then
+    ARGS="$ARGS -stats-port=$STATS_PORT"
+fi
+
+if [ -n "$PROGRESS_INTERVAL" ]; then
+    ARGS="$ARGS -progress-interval=$PROGRESS_INTERVAL"
+fi
+
+if [ -n "$CHAOS_DATA" ]; then
+    ARGS="$ARGS -chaos-data=$CHAOS_DATA"
+fi
+
+if [ -n "$CHAOS_SEED" ]; then
+    ARGS="$ARGS -chaos-seed=$CHAOS_SEED"
+fi
+
+if [ "$DRY_RUN" = "true" ]; then
+    ARGS="$ARGS -dry-run"
+fi
+
+if [ "$STRICT_PROTOCOL" = "true" ]; then
+    ARGS="$ARGS -strict-protocol"
+fi
+
+if [ "$SELFTEST" = "true" ]; then
+    ARGS="$ARGS -selftest"
+fi
+
+if [ -n "$HTTP_TIMEOUT" ]; then
+    ARGS="$ARGS -http-timeout=$HTTP_TIMEOUT"
+fi
+
+if [ -n "$HTTP_MAX_IDLE_CONNS" ]; then
+    ARGS="$ARGS -http-max-idle-conns=$HTTP_MAX_IDLE_CONNS"
+fi
+
+if [ -n "$HTTP_IDLE_CONN_TIMEOUT" ]; then
+    ARGS="$ARGS -http-idle-conn-timeout=$HTTP_IDLE_CONN_TIMEOUT"
+fi
+
+if [ -n "$HTTP_KEEPALIVE" ]; then
+    ARGS="$ARGS -http-keepalive=$HTTP_KEEPALIVE"
+fi
+
+if [ -n "$STREAMER_INSTANCE" ]; then
+    ARGS="$ARGS -instance=$STREAMER_INSTANCE"
+fi
+
 # Add any additional arguments passed to the container
 ARGS="$ARGS $@"
 
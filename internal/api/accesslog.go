@@ -0,0 +1,66 @@
+package api
+
+import (
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+// accessLogSampler decides which requests the access log records, keeping a
+// fraction of successful requests and a separate fraction of failed ones
+type accessLogSampler struct {
+	successRate float64
+	errorRate   float64
+	suppressed  *metrics.CounterVec
+}
+
+// newAccessLogSampler creates a sampler with the given rates, counting the
+// lines it suppresses in registry
+func newAccessLogSampler(registry *metrics.Registry, successRate, errorRate float64) *accessLogSampler {
+	return &accessLogSampler{
+		successRate: sampleRate(successRate),
+		errorRate:   sampleRate(errorRate),
+		suppressed: registry.NewCounterVec("api_access_log_suppressed_total",
+			"Access log lines suppressed by sampling.", "http_response_status_class"),
+	}
+}
+
+// sampleRate maps a configured rate to the fraction of lines kept: zero keeps
+// every line, a negative rate none
+func sampleRate(rate float64) float64 {
+	switch {
+	case rate == 0 || rate > 1:
+		return 1
+	case rate < 0:
+		return 0
+	}
+	return rate
+}
+
+// sample reports whether a request that got status should be logged,
+// counting it as suppressed otherwise. Requests carrying a trace ID are
+// sampled by it, so every service logs the same requests of a trace.
+func (s *accessLogSampler) sample(status int, traceID string) bool {
+	rate := s.successRate
+	if status >= 400 {
+		rate = s.errorRate
+	}
+	if rate >= 1 {
+		return true
+	}
+
+	var draw float64
+	if traceID != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(traceID))
+		draw = float64(h.Sum32()) / (1 << 32)
+	} else {
+		draw = rand.Float64()
+	}
+	if draw < rate {
+		return true
+	}
+	s.suppressed.WithLabelValues(statusClass(status)).Inc()
+	return false
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+func TestLoggingMiddleware_SamplesByStatus(t *testing.T) {
+	server := NewServer(createTestCollector(), ServerConfig{Port: "8095", AccessLogSuccessRate: -1, AccessLogErrorRate: 1})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	router.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}).Methods("GET")
+	router.Use(server.loggingMiddleware)
+
+	for _, path := range []string{"/ok", "/ok", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if strings.Contains(logs.String(), "/ok") {
+		t.Errorf("Expected successful requests left out, got %q", logs.String())
+	}
+	if !strings.Contains(logs.String(), "GET /fail 503") {
+		t.Errorf("Expected the failed request logged, got %q", logs.String())
+	}
+
+	var out bytes.Buffer
+	_, _ = server.metrics.WriteTo(&out)
+	if want := `api_access_log_suppressed_total{http_response_status_class="2xx"} 2`; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %s in metrics, got:\n%s", want, out.String())
+	}
+}
+
+func TestAccessLogSampler_SamplesTracesTogether(t *testing.T) {
+	sampler := newAccessLogSampler(metrics.NewRegistry(), 0.5, 0)
+
+	logged := 0
+	for i := 0; i < 1000; i++ {
+		traceID := fmt.Sprintf("%032x", i)
+		first := sampler.sample(http.StatusOK, traceID)
+		if sampler.sample(http.StatusOK, traceID) != first {
+			t.Fatalf("Expected trace %s sampled consistently", traceID)
+		}
+		if first {
+			logged++
+		}
+	}
+	if logged == 0 || logged == 1000 {
+		t.Errorf("Expected about half the traces logged, got %d of 1000", logged)
+	}
+	if !sampler.sample(http.StatusInternalServerError, "") {
+		t.Error("Expected a zero error rate to log every error")
+	}
+}
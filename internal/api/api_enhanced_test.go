@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
 	"github.com/harishb93/telemetry-pipeline/internal/collector"
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
 )
@@ -209,6 +210,7 @@ func TestErrorResponseHandling(t *testing.T) {
 	tests := []struct {
 		name       string
 		statusCode int
+		code       apierror.Code
 		message    string
 		details    string
 		checkJSON  func(t *testing.T, response ErrorResponse)
@@ -216,6 +218,7 @@ func TestErrorResponseHandling(t *testing.T) {
 		{
 			name:       "Basic error response",
 			statusCode: http.StatusBadRequest,
+			code:       apierror.InvalidTimeRange,
 			message:    "Invalid request",
 			details:    "",
 			checkJSON: func(t *testing.T, response ErrorResponse) {
@@ -228,11 +231,15 @@ func TestErrorResponseHandling(t *testing.T) {
 				if response.Error != "Bad Request" {
 					t.Errorf("Expected error 'Bad Request', got %s", response.Error)
 				}
+				if response.ErrorCode != "TP-4001" || response.Reason != "INVALID_TIME_RANGE" {
+					t.Errorf("Expected TP-4001 INVALID_TIME_RANGE, got %s %s", response.ErrorCode, response.Reason)
+				}
 			},
 		},
 		{
 			name:       "Error with details",
 			statusCode: http.StatusInternalServerError,
+			code:       apierror.Internal,
 			message:    "Database error",
 			details:    "connection timeout",
 			checkJSON: func(t *testing.T, response ErrorResponse) {
@@ -252,7 +259,7 @@ func TestErrorResponseHandling(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			rr := httptest.NewRecorder()
-			handlers.writeErrorResponse(rr, tt.statusCode, tt.message, tt.details)
+			handlers.writeErrorResponse(rr, tt.statusCode, tt.code, tt.message, tt.details)
 
 			if status := rr.Code; status != tt.statusCode {
 				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.statusCode)
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"/":           "",
+		"telemetry":   "/telemetry",
+		"/telemetry":  "/telemetry",
+		"/telemetry/": "/telemetry",
+		" /a/b/ ":     "/a/b",
+	}
+	for in, want := range tests {
+		if got := NormalizeBasePath(in); got != want {
+			t.Errorf("NormalizeBasePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestServerBasePath(t *testing.T) {
+	server := NewServer(createTestCollector(), ServerConfig{Port: "8096", BasePath: "/telemetry/"})
+	handler := server.handler()
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/telemetry/health", http.StatusOK},
+		{"/telemetry/metrics", http.StatusOK},
+		{"/telemetry/swagger/index.html", http.StatusOK},
+		{"/health", http.StatusNotFound},
+		{"/api/v1/gpus", http.StatusNotFound},
+		{"/swagger/index.html", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			if rr.Code != tt.status {
+				t.Errorf("GET %s: got %d, want %d", tt.path, rr.Code, tt.status)
+			}
+		})
+	}
+
+	// Middleware still applies to prefixed routes, labelled with the full template
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/telemetry/health", nil))
+	if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("Expected CORS headers on prefixed route")
+	}
+	if got := server.red.requests.WithLabelValues("GET", "/telemetry/health", "2xx").Value(); got != 2 {
+		t.Errorf("Expected 2 requests recorded for /telemetry/health, got %v", got)
+	}
+}
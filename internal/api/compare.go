@@ -0,0 +1,260 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+)
+
+// DefaultCompareWindow is the window compared when none is given
+const DefaultCompareWindow = time.Hour
+
+// compareBuckets is roughly how many buckets a window is split into when no
+// step is given
+const compareBuckets = 60
+
+// maxCompareGPUs caps the GPUs in one comparison
+const maxCompareGPUs = 16
+
+// CompareResponse lines up one metric of several GPUs over the same window.
+// The window ends at the newest sample of any of the GPUs, so replayed
+// historical telemetry compares as well as live telemetry.
+type CompareResponse struct {
+	Metric string `json:"metric" example:"DCGM_FI_DEV_GPU_UTIL"`
+	// Unit is the unit of the values, after any conversion asked for, if
+	// the metric's unit is known
+	Unit string    `json:"unit,omitempty" example:"%"`
+	From time.Time `json:"from" example:"2025-07-18T19:42:34Z"`
+	To   time.Time `json:"to" example:"2025-07-18T20:42:34Z"`
+	Step string    `json:"step" example:"1m0s"`
+	// Timestamps are the starts of the buckets every series is aligned to
+	Timestamps []time.Time     `json:"timestamps"`
+	Series     []CompareSeries `json:"series"`
+	Deltas     []CompareDelta  `json:"deltas"`
+}
+
+// CompareSeries is one GPU's part of a comparison
+type CompareSeries struct {
+	GPUID string `json:"gpu_id" example:"gpu_0"`
+	// Values are the mean of each bucket, aligned with the response's
+	// timestamps; null where the GPU has no samples
+	Values  []*float64    `json:"values"`
+	Summary SeriesSummary `json:"summary"`
+}
+
+// SeriesSummary summarizes a GPU's samples in the window
+type SeriesSummary struct {
+	Samples int     `json:"samples" example:"3600"`
+	Mean    float64 `json:"mean" example:"87.5"`
+	Median  float64 `json:"median" example:"91"`
+	Min     float64 `json:"min" example:"12"`
+	Max     float64 `json:"max" example:"100"`
+}
+
+// CompareDelta is how a GPU's summary differs from the baseline's, the first
+// GPU requested
+type CompareDelta struct {
+	GPUID      string  `json:"gpu_id" example:"gpu_1"`
+	Baseline   string  `json:"baseline" example:"gpu_0"`
+	MeanDiff   float64 `json:"mean_diff" example:"-4.2"`
+	MedianDiff float64 `json:"median_diff" example:"-3"`
+	// MeanDiffPercent is MeanDiff relative to the baseline mean, omitted
+	// when that is zero
+	MeanDiffPercent *float64 `json:"mean_diff_percent,omitempty" example:"-4.8"`
+}
+
+// CompareGPUs compares one metric across GPUs
+// @Summary Compare GPUs
+// @Description Returns one metric of two or more GPUs over the same window, as series of bucket means aligned to shared timestamps, with a summary of each GPU's samples and the mean and median difference of each GPU from the first. The window ends at the newest sample of any of the GPUs. Without a step the window is split into about 60 buckets.
+// @Tags GPUs
+// @Accept json
+// @Produce json
+// @Param gpus query string true "Comma-separated GPU IDs, the first being the baseline"
+// @Param metric query string true "Metric to compare"
+// @Param window query string false "Window to compare, as a Go duration (default: 1h)"
+// @Param step query string false "Bucket width, as a Go duration"
+// @Param units query string false "Comma-separated units to convert the metric to, at most one per dimension, e.g. GiB,F"
+// @Success 200 {object} CompareResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /compare [get]
+func (h *Handlers) CompareGPUs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	gpuIDs, err := parseCompareGPUs(query.Get("gpus"))
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidParameter, "Invalid comparison parameters", err.Error())
+		return
+	}
+	metric := query.Get("metric")
+	if metric == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidParameter, "Invalid comparison parameters", "metric is required")
+		return
+	}
+	window, step, err := parseCompareWindow(query.Get("window"), query.Get("step"), h.maxSeriesPoints)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidParameter, "Invalid comparison parameters", err.Error())
+		return
+	}
+	units, err := parseUnits(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidUnits, "Invalid units parameter", err.Error())
+		return
+	}
+
+	// Keep each GPU's samples of the metric, oldest first
+	samples := make([][]*collector.Telemetry, len(gpuIDs))
+	var to time.Time
+	for i, gpuID := range gpuIDs {
+		entries, err := h.getTelemetryEntries(r.Context(), gpuID)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve telemetry data", err.Error())
+			return
+		}
+		for _, telemetry := range entries {
+			if value, ok := telemetry.Metrics[metric]; ok {
+				telemetry.Metrics[metric] = units.Convert(metric, value)
+				samples[i] = append(samples[i], telemetry)
+				if telemetry.Timestamp.After(to) {
+					to = telemetry.Timestamp
+				}
+			}
+		}
+		sort.Slice(samples[i], func(a, b int) bool {
+			return samples[i][a].Timestamp.Before(samples[i][b].Timestamp)
+		})
+	}
+
+	from := to.Add(-window)
+	start := from.Truncate(step)
+	response := CompareResponse{
+		Metric: metric,
+		Unit:   units.Unit(metric),
+		From:   from.UTC(),
+		To:     to.UTC(),
+		Step:   step.String(),
+		Series: []CompareSeries{},
+		Deltas: []CompareDelta{},
+	}
+	for t := start; !t.After(to); t = t.Add(step) {
+		response.Timestamps = append(response.Timestamps, t.UTC())
+	}
+
+	for i, gpuID := range gpuIDs {
+		var inWindow []*collector.Telemetry
+		for _, telemetry := range samples[i] {
+			if !telemetry.Timestamp.Before(from) {
+				inWindow = append(inWindow, telemetry)
+			}
+		}
+		if len(inWindow) == 0 {
+			h.writeErrorResponse(w, http.StatusNotFound, apierror.NoSamples, "No samples to compare",
+				fmt.Sprintf("GPU %s has no %s samples between %s and %s", gpuID, metric,
+					from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339)))
+			return
+		}
+
+		series := CompareSeries{GPUID: gpuID, Values: make([]*float64, len(response.Timestamps))}
+		for _, datapoint := range rollupSeries(inWindow, metric, step).Datapoints {
+			value := datapoint[0]
+			bucket := time.UnixMilli(int64(datapoint[1])).Sub(start) / step
+			series.Values[bucket] = &value
+		}
+		values := make([]float64, len(inWindow))
+		for j, telemetry := range inWindow {
+			values[j] = telemetry.Metrics[metric]
+		}
+		series.Summary = summarizeSeries(values)
+		response.Series = append(response.Series, series)
+	}
+
+	baseline := response.Series[0]
+	for _, series := range response.Series[1:] {
+		delta := CompareDelta{
+			GPUID:      series.GPUID,
+			Baseline:   baseline.GPUID,
+			MeanDiff:   series.Summary.Mean - baseline.Summary.Mean,
+			MedianDiff: series.Summary.Median - baseline.Summary.Median,
+		}
+		if baseline.Summary.Mean != 0 {
+			percent := delta.MeanDiff / baseline.Summary.Mean * 100
+			delta.MeanDiffPercent = &percent
+		}
+		response.Deltas = append(response.Deltas, delta)
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// parseCompareGPUs splits a comma-separated list of GPU IDs, dropping
+// repeats, and checks it names between 2 and maxCompareGPUs GPUs
+func parseCompareGPUs(list string) ([]string, error) {
+	var gpuIDs []string
+	seen := make(map[string]bool)
+	for _, gpuID := range strings.Split(list, ",") {
+		gpuID = strings.TrimSpace(gpuID)
+		if gpuID != "" && !seen[gpuID] {
+			seen[gpuID] = true
+			gpuIDs = append(gpuIDs, gpuID)
+		}
+	}
+	if len(gpuIDs) < 2 {
+		return nil, fmt.Errorf("gpus must list at least two GPU IDs")
+	}
+	if len(gpuIDs) > maxCompareGPUs {
+		return nil, fmt.Errorf("gpus lists %d GPUs, at most %d can be compared", len(gpuIDs), maxCompareGPUs)
+	}
+	return gpuIDs, nil
+}
+
+// parseCompareWindow parses the window and step of a comparison. Without a
+// step the window is split into about compareBuckets buckets; a step may not
+// split it into more than maxPoints.
+func parseCompareWindow(windowParam, stepParam string, maxPoints int) (time.Duration, time.Duration, error) {
+	window := DefaultCompareWindow
+	if windowParam != "" {
+		var err error
+		if window, err = time.ParseDuration(windowParam); err != nil || window <= 0 {
+			return 0, 0, fmt.Errorf("window must be a positive duration such as 1h, got %q", windowParam)
+		}
+	}
+	if stepParam == "" {
+		return window, rollupStep(window, compareBuckets), nil
+	}
+
+	step, err := time.ParseDuration(stepParam)
+	if err != nil || step <= 0 {
+		return 0, 0, fmt.Errorf("step must be a positive duration such as 1m, got %q", stepParam)
+	}
+	if maxPoints > 0 && window/step > time.Duration(maxPoints) {
+		return 0, 0, fmt.Errorf("step %s splits the %s window into more than %d buckets", step, window, maxPoints)
+	}
+	return window, step, nil
+}
+
+// summarizeSeries summarizes a non-empty set of values
+func summarizeSeries(values []float64) SeriesSummary {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, value := range sorted {
+		sum += value
+	}
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + median) / 2
+	}
+	return SeriesSummary{
+		Samples: len(sorted),
+		Mean:    sum / float64(len(sorted)),
+		Median:  median,
+		Min:     sorted[0],
+		Max:     sorted[len(sorted)-1],
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+)
+
+// newCompareTestRouter serves the compare endpoint against a fake collector
+// holding ten minutely utilization samples for gpu_0, the same ten plus 10
+// for gpu_1 and none for gpu_2
+func newCompareTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+
+	body := func(gpuID string, offset float64) []byte {
+		base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		data := make([]*collector.Telemetry, 10)
+		for i := range data {
+			data[i] = &collector.Telemetry{
+				GPUId:     gpuID,
+				Metrics:   map[string]float64{"utilization": float64(i) + offset},
+				Timestamp: base.Add(time.Duration(i) * time.Minute),
+			}
+		}
+		encoded, err := json.Marshal(map[string]interface{}{"gpu_id": gpuID, "total": len(data), "data": data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	gpu0, gpu1 := body("gpu_0", 0), body("gpu_1", 10)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/gpus/gpu_0/telemetry":
+			_, _ = w.Write(gpu0)
+		case "/api/v1/gpus/gpu_1/telemetry":
+			_, _ = w.Write(gpu1)
+		default:
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/compare", handlers.CompareGPUs).Methods("GET")
+	return router
+}
+
+func TestCompareGPUs(t *testing.T) {
+	router := newCompareTestRouter(t)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/compare?gpus=gpu_0,gpu_1,gpu_0&metric=utilization&window=10m&step=5m", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response CompareResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response %q: %v", rr.Body.String(), err)
+	}
+
+	// The window ends at the newest sample, 12:09, and is bucketed from 11:55
+	if want := time.Date(2024, 1, 1, 12, 9, 0, 0, time.UTC); !response.To.Equal(want) {
+		t.Errorf("Expected window to end at %s, got %s", want, response.To)
+	}
+	if len(response.Timestamps) != 3 || !response.Timestamps[0].Equal(time.Date(2024, 1, 1, 11, 55, 0, 0, time.UTC)) {
+		t.Fatalf("Expected 3 buckets from 11:55, got %v", response.Timestamps)
+	}
+	if len(response.Series) != 2 {
+		t.Fatalf("Expected the repeated GPU compared once, got %d series", len(response.Series))
+	}
+
+	series := response.Series[0]
+	if series.GPUID != "gpu_0" || series.Values[0] != nil || *series.Values[1] != 2 || *series.Values[2] != 7 {
+		t.Errorf("Unexpected gpu_0 series: %+v", series)
+	}
+	if s := series.Summary; s.Samples != 10 || s.Mean != 4.5 || s.Median != 4.5 || s.Min != 0 || s.Max != 9 {
+		t.Errorf("Unexpected gpu_0 summary: %+v", s)
+	}
+
+	if len(response.Deltas) != 1 {
+		t.Fatalf("Expected 1 delta, got %d", len(response.Deltas))
+	}
+	delta := response.Deltas[0]
+	if delta.GPUID != "gpu_1" || delta.Baseline != "gpu_0" || delta.MeanDiff != 10 || delta.MedianDiff != 10 {
+		t.Errorf("Unexpected delta: %+v", delta)
+	}
+	if delta.MeanDiffPercent == nil || math.Abs(*delta.MeanDiffPercent-10/4.5*100) > 1e-9 {
+		t.Errorf("Unexpected mean difference percent: %v", delta.MeanDiffPercent)
+	}
+}
+
+func TestCompareGPUs_Errors(t *testing.T) {
+	router := newCompareTestRouter(t)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"gpus=gpu_0&metric=utilization", http.StatusBadRequest},
+		{"gpus=gpu_0,gpu_0&metric=utilization", http.StatusBadRequest},
+		{"gpus=gpu_0,gpu_1", http.StatusBadRequest},
+		{"gpus=gpu_0,gpu_1&metric=utilization&window=soon", http.StatusBadRequest},
+		{"gpus=gpu_0,gpu_1&metric=utilization&window=24h&step=1s", http.StatusBadRequest},
+		{"gpus=gpu_0,gpu_2&metric=utilization", http.StatusNotFound},
+		{"gpus=gpu_0,gpu_1&metric=temperature", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/compare?"+tt.query, nil))
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.query, tt.want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+	"github.com/harishb93/telemetry-pipeline/internal/httpcache"
+)
+
+// telemetryQueryKey hashes the parameters that shape a telemetry response, so
+// the same collector data queried differently gets a different ETag
+func telemetryQueryKey(limit, offset, maxItems int, startTime, endTime *time.Time, units collector.UnitConversions) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d|%d|%d|%s", limit, offset, maxItems, units)
+	for _, t := range []*time.Time{startTime, endTime} {
+		if t != nil {
+			fmt.Fprintf(h, "|%d", t.UnixNano())
+		} else {
+			fmt.Fprint(h, "|-")
+		}
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// gatewayETag derives the ETag of a telemetry response from the collector's
+// ETag for the underlying data and the query key
+func gatewayETag(upstream, queryKey string) string {
+	return `"` + httpcache.Opaque(upstream) + "-" + queryKey + `"`
+}
+
+// upstreamConditionals translates the client's conditional headers into ones
+// the collector understands: ETags issued for this query are stripped back to
+// the collector's ETag, and tags for other queries are dropped
+func upstreamConditionals(r *http.Request, queryKey string) http.Header {
+	header := http.Header{}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		var tags []string
+		for _, tag := range httpcache.ParseETags(inm) {
+			if tag == "*" {
+				tags = append(tags, tag)
+				continue
+			}
+			if upstream, ok := strings.CutSuffix(httpcache.Opaque(tag), "-"+queryKey); ok {
+				tags = append(tags, `"`+upstream+`"`)
+			}
+		}
+		if len(tags) > 0 {
+			header.Set("If-None-Match", strings.Join(tags, ", "))
+		}
+		// If-Modified-Since is ignored when If-None-Match is present
+		return header
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		header.Set("If-Modified-Since", ims)
+	}
+	return header
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/harishb93/telemetry-pipeline/internal/httpcache"
+)
+
+// newConditionalTestRouter serves GetTelemetry against a fake collector that
+// honours conditional requests for a fixed ETag, counting full responses
+func newConditionalTestRouter(t *testing.T, upstreamETag *atomic.Value, fullResponses *atomic.Int32) *mux.Router {
+	t.Helper()
+
+	body := collectorTelemetryBody(t, 5)
+	lastModified := time.Date(2024, 1, 1, 12, 4, 0, 0, time.UTC)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := upstreamETag.Load().(string)
+		httpcache.SetValidators(w, etag, lastModified)
+		if httpcache.NotModified(r, etag, lastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullResponses.Add(1)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/gpus/{id}/telemetry", NewHandlers(createTestCollector()).GetTelemetry).Methods("GET")
+	return router
+}
+
+func TestGetTelemetry_ConditionalRequests(t *testing.T) {
+	var upstreamETag atomic.Value
+	upstreamETag.Store(`"5-abc"`)
+	var fullResponses atomic.Int32
+	router := newConditionalTestRouter(t, &upstreamETag, &fullResponses)
+
+	get := func(query string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/gpus/gpu_0/telemetry"+query, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := get("?limit=2", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with ETag, got %d %q", first.Code, etag)
+	}
+	if first.Header().Get("Last-Modified") == "" {
+		t.Error("Expected Last-Modified to be passed through")
+	}
+
+	// Unchanged data: 304 without the collector sending its payload
+	rr := get("?limit=2", http.Header{"If-None-Match": {etag}})
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Fatalf("Expected empty 304, got %d %q", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("ETag") != etag {
+		t.Errorf("Expected 304 to carry ETag %q, got %q", etag, rr.Header().Get("ETag"))
+	}
+	if n := fullResponses.Load(); n != 1 {
+		t.Errorf("Expected collector to send 1 full response, sent %d", n)
+	}
+
+	// The same ETag does not validate a different query
+	if rr := get("?limit=3", http.Header{"If-None-Match": {etag}}); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a different query, got %d", rr.Code)
+	}
+	if other := get("?limit=3", nil).Header().Get("ETag"); other == etag {
+		t.Errorf("Expected different queries to have different ETags, both %q", etag)
+	}
+
+	// If-Modified-Since
+	rr = get("?limit=2", http.Header{"If-Modified-Since": {first.Header().Get("Last-Modified")}})
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for If-Modified-Since, got %d", rr.Code)
+	}
+
+	// New data upstream invalidates the ETag
+	upstreamETag.Store(`"6-abd"`)
+	rr = get("?limit=2", http.Header{"If-None-Match": {etag}})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 after upstream change, got %d", rr.Code)
+	}
+	if rr.Header().Get("ETag") == etag {
+		t.Error("Expected ETag to change with upstream data")
+	}
+}
+
+func TestUpstreamConditionals(t *testing.T) {
+	queryKey := "0badcafe"
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", `"5-abc-0badcafe", "5-abc-12345678", W/"4-abb-0badcafe"`)
+	req.Header.Set("If-Modified-Since", "Mon, 01 Jan 2024 12:00:00 GMT")
+
+	header := upstreamConditionals(req, queryKey)
+	if got := header.Get("If-None-Match"); got != `"5-abc", "4-abb"` {
+		t.Errorf("If-None-Match = %q", got)
+	}
+	if got := header.Get("If-Modified-Since"); got != "" {
+		t.Errorf("Expected If-Modified-Since to be dropped alongside If-None-Match, got %q", got)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-Modified-Since", "Mon, 01 Jan 2024 12:00:00 GMT")
+	if got := upstreamConditionals(req, queryKey).Get("If-Modified-Since"); got == "" {
+		t.Error("Expected If-Modified-Since to be forwarded")
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/testdata"
+)
+
+// TestAPI_Golden serves the shared DCGM dataset through a running collector
+// and checks the API's responses against their goldens
+func TestAPI_Golden(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	coll := collector.NewCollector(broker, collector.CollectorConfig{
+		Workers:          1,
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 100,
+		HealthPort:       "8296",
+	})
+	go func() {
+		if err := coll.Start(); err != nil {
+			t.Logf("Failed to start test collector: %v", err)
+		}
+	}()
+	defer coll.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	records := testdata.Records(t, testdata.Basic)
+	for _, payload := range testdata.Messages(t, testdata.Basic) {
+		if err := broker.Publish("telemetry", mq.Message{Payload: payload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for stored := 0; stored < len(records); {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d samples stored, got %d", len(records), stored)
+		}
+		time.Sleep(20 * time.Millisecond)
+		stored = 0
+		for _, host := range coll.GetAllHosts() {
+			for _, gpuID := range coll.GetGPUsForHost(host) {
+				stored += len(coll.GetTelemetryForGPU(gpuID, 0))
+			}
+		}
+	}
+
+	t.Setenv("COLLECTOR_URL", "http://localhost:8296")
+	handler := NewServer(coll, ServerConfig{Port: "8097"}).handler()
+
+	for golden, path := range map[string]string{
+		testdata.Basic + ".hosts":         "/api/v1/hosts",
+		testdata.Basic + ".host_gpus":     "/api/v1/hosts/mtv5-dgx1-hgpu-032/gpus",
+		testdata.Basic + ".gpu_telemetry": "/api/v1/gpus/GPU-5fd4f087-86f3-7a43-b711-4771313afc50/telemetry",
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+		var body interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		sortListings(body)
+		testdata.AssertGolden(t, golden, body)
+	}
+}
+
+// sortListings sorts the host and GPU lists of a decoded response, which the
+// collector returns in no particular order
+func sortListings(body interface{}) {
+	fields, ok := body.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, key := range []string{"hosts", "gpus"} {
+		if list, ok := fields[key].([]interface{}); ok {
+			sort.Slice(list, func(i, j int) bool {
+				return list[i].(string) < list[j].(string)
+			})
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+)
+
+// Grafana SimpleJSON datasource support. Targets name a GPU ("gpu_0"), which
+// expands to one series per metric, or a single metric ("gpu_0:utilization").
+
+// grafanaTargetSep separates the GPU ID from the metric name in a target
+const grafanaTargetSep = ":"
+
+// GrafanaSearchRequest is the body Grafana sends to /search
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaQueryRequest is the body Grafana sends to /query
+type GrafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	// IntervalMs is the panel's step between points
+	IntervalMs    int64                `json:"intervalMs"`
+	MaxDataPoints int                  `json:"maxDataPoints"`
+	Targets       []GrafanaQueryTarget `json:"targets"`
+}
+
+// GrafanaQueryTarget is a single query in a panel
+type GrafanaQueryTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	// Type is "timeserie" (the default) or "table"
+	Type string `json:"type"`
+	Hide bool   `json:"hide"`
+}
+
+// GrafanaTimeSeries is a time series result; each datapoint is
+// [value, unix milliseconds]. Resolution is "raw" for samples as stored, or
+// the bucket width (e.g. "5m0s") of averages the query planner chose.
+type GrafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+	Resolution string       `json:"resolution"`
+}
+
+// GrafanaTable is a table result
+type GrafanaTable struct {
+	Type    string               `json:"type"`
+	Columns []GrafanaTableColumn `json:"columns"`
+	Rows    [][]interface{}      `json:"rows"`
+}
+
+// GrafanaTableColumn describes a table column
+type GrafanaTableColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// GrafanaStatus is the response to the datasource connection test
+type GrafanaStatus struct {
+	Status string `json:"status" example:"ok"`
+}
+
+// GrafanaTestConnection answers the datasource "Save & test" check
+// @Summary Grafana datasource connection test
+// @Description Returns 200 so the Grafana SimpleJSON datasource can verify its URL
+// @Tags Grafana
+// @Produce json
+// @Success 200 {object} GrafanaStatus
+// @Router /grafana/ [get]
+func (h *Handlers) GrafanaTestConnection(w http.ResponseWriter, r *http.Request) {
+	h.writeJSONResponse(w, http.StatusOK, GrafanaStatus{Status: "ok"})
+}
+
+// GrafanaSearch lists query targets for the Grafana query editor
+// @Summary Grafana target search
+// @Description Returns GPU IDs containing the search text, or the metrics of a GPU as "gpu:metric" targets when the text is "gpu:"
+// @Tags Grafana
+// @Accept json
+// @Produce json
+// @Param request body GrafanaSearchRequest true "Search text"
+// @Success 200 {array} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grafana/search [post]
+func (h *Handlers) GrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	var request GrafanaSearchRequest
+	if err := decodeGrafanaRequest(r, &request); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidRequest, "Invalid search request", err.Error())
+		return
+	}
+
+	// "gpu_0:util" lists the metrics of gpu_0 that contain "util"
+	if gpuID, filter, ok := strings.Cut(request.Target, grafanaTargetSep); ok {
+		entries, err := h.getTelemetryEntries(r.Context(), gpuID)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve telemetry data", err.Error())
+			return
+		}
+		targets := []string{}
+		for _, name := range metricNames(entries) {
+			if strings.Contains(name, filter) {
+				targets = append(targets, gpuID+grafanaTargetSep+name)
+			}
+		}
+		h.writeJSONResponse(w, http.StatusOK, targets)
+		return
+	}
+
+	gpuIDs, err := h.getAllGPUIDs(r.Context())
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve GPU IDs", err.Error())
+		return
+	}
+	targets := []string{}
+	for _, gpuID := range gpuIDs {
+		if strings.Contains(gpuID, request.Target) {
+			targets = append(targets, gpuID)
+		}
+	}
+	sort.Strings(targets)
+	h.writeJSONResponse(w, http.StatusOK, targets)
+}
+
+// GrafanaQuery returns time series or tables for the targets of a panel
+// @Summary Grafana panel query
+// @Description Returns datapoints for each target within the requested range. A GPU target returns one series per metric; "gpu:metric" returns a single series. When the range and intervalMs imply more points per series than the server's limit (or maxDataPoints, if lower), series are served as averages over a coarser step, reported in each series' resolution. Table targets return one row per sample.
+// @Tags Grafana
+// @Accept json
+// @Produce json
+// @Param request body GrafanaQueryRequest true "Panel query"
+// @Success 200 {array} GrafanaTimeSeries
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grafana/query [post]
+func (h *Handlers) GrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var request GrafanaQueryRequest
+	if err := decodeGrafanaRequest(r, &request); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidRequest, "Invalid query request", err.Error())
+		return
+	}
+
+	var from, to *time.Time
+	if !request.Range.From.IsZero() {
+		from = &request.Range.From
+	}
+	if !request.Range.To.IsZero() {
+		to = &request.Range.To
+	}
+
+	maxPoints := h.maxSeriesPoints
+	if request.MaxDataPoints > 0 && (maxPoints <= 0 || request.MaxDataPoints < maxPoints) {
+		maxPoints = request.MaxDataPoints
+	}
+	plan := planQuery(request.Range.From, request.Range.To,
+		time.Duration(request.IntervalMs)*time.Millisecond, maxPoints)
+
+	// Panels often query several metrics of one GPU; fetch each GPU once
+	fetched := make(map[string][]*collector.Telemetry)
+	results := []interface{}{}
+
+	for _, target := range request.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+
+		gpuID, metric, _ := strings.Cut(target.Target, grafanaTargetSep)
+		entries, ok := fetched[gpuID]
+		if !ok {
+			all, err := h.getTelemetryEntries(r.Context(), gpuID)
+			if err != nil {
+				h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve telemetry data", err.Error())
+				return
+			}
+			for _, telemetry := range all {
+				if inTimeRange(telemetry, from, to) {
+					entries = append(entries, telemetry)
+				}
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].Timestamp.Before(entries[j].Timestamp)
+			})
+			fetched[gpuID] = entries
+		}
+
+		metrics := metricNames(entries)
+		if metric != "" {
+			metrics = []string{metric}
+		}
+
+		if target.Type == "table" {
+			results = append(results, grafanaTable(entries, metrics))
+			continue
+		}
+		for _, name := range metrics {
+			var series GrafanaTimeSeries
+			if plan.Step > 0 {
+				series = rollupSeries(entries, name, plan.Step)
+			} else {
+				series = grafanaSeries(entries, name, request.MaxDataPoints)
+			}
+			if metric == "" {
+				series.Target = gpuID + grafanaTargetSep + name
+			} else {
+				series.Target = target.Target
+			}
+			results = append(results, series)
+		}
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, results)
+}
+
+// decodeGrafanaRequest decodes a JSON request body into v
+func decodeGrafanaRequest(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+	return nil
+}
+
+// grafanaSeries builds the series for one metric, evenly thinned to at most
+// maxPoints datapoints when maxPoints is positive
+func grafanaSeries(entries []*collector.Telemetry, metric string, maxPoints int) GrafanaTimeSeries {
+	datapoints := [][2]float64{}
+	for _, telemetry := range entries {
+		if value, ok := telemetry.Metrics[metric]; ok {
+			datapoints = append(datapoints, [2]float64{value, float64(telemetry.Timestamp.UnixMilli())})
+		}
+	}
+
+	if maxPoints > 0 && len(datapoints) > maxPoints {
+		thinned := make([][2]float64, maxPoints)
+		for i := range thinned {
+			thinned[i] = datapoints[i*len(datapoints)/maxPoints]
+		}
+		// Keep the most recent sample so the panel reaches the end of the range
+		thinned[maxPoints-1] = datapoints[len(datapoints)-1]
+		datapoints = thinned
+	}
+
+	return GrafanaTimeSeries{Datapoints: datapoints, Resolution: rawResolution}
+}
+
+// grafanaTable builds a table with one row per sample and one column per
+// metric; missing values are null
+func grafanaTable(entries []*collector.Telemetry, metrics []string) GrafanaTable {
+	table := GrafanaTable{
+		Type: "table",
+		Columns: []GrafanaTableColumn{
+			{Text: "Time", Type: "time"},
+			{Text: "gpu_id", Type: "string"},
+			{Text: "hostname", Type: "string"},
+		},
+		Rows: [][]interface{}{},
+	}
+	for _, name := range metrics {
+		table.Columns = append(table.Columns, GrafanaTableColumn{Text: name, Type: "number"})
+	}
+
+	for _, telemetry := range entries {
+		row := []interface{}{telemetry.Timestamp.UnixMilli(), telemetry.GPUId, telemetry.Hostname}
+		for _, name := range metrics {
+			if value, ok := telemetry.Metrics[name]; ok {
+				row = append(row, value)
+			} else {
+				row = append(row, nil)
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table
+}
+
+// metricNames returns the sorted set of metric names across entries
+func metricNames(entries []*collector.Telemetry) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, telemetry := range entries {
+		for name := range telemetry.Metrics {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getTelemetryEntries fetches all telemetry the collector holds for a GPU
+func (h *Handlers) getTelemetryEntries(ctx context.Context, gpuID string) ([]*collector.Telemetry, error) {
+	resp, err := h.collectorGet(ctx, fmt.Sprintf("/api/v1/gpus/%s/telemetry", url.PathEscape(gpuID)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call collector telemetry endpoint: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("collector telemetry endpoint returned status %d", resp.StatusCode)
+	}
+
+	var entries []*collector.Telemetry
+	err = decodeTelemetryStream(resp.Body, func(telemetry *collector.Telemetry) {
+		entries = append(entries, telemetry)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode collector telemetry response: %w", err)
+	}
+	return entries, nil
+}
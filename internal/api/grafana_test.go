@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newGrafanaTestRouter serves the Grafana endpoints against a fake collector
+// holding ten utilization samples for gpu_0 and no telemetry for gpu_1
+func newGrafanaTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+
+	telemetry := collectorTelemetryBody(t, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/stats":
+			_, _ = w.Write([]byte(`{"gpu_entry_counts":{"gpu_1":0,"gpu_0":10}}`))
+		case "/api/v1/gpus/gpu_0/telemetry":
+			_, _ = w.Write([]byte(telemetry))
+		default:
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	router := mux.NewRouter()
+	router.HandleFunc("/grafana/", handlers.GrafanaTestConnection).Methods("GET")
+	router.HandleFunc("/grafana/search", handlers.GrafanaSearch).Methods("POST")
+	router.HandleFunc("/grafana/query", handlers.GrafanaQuery).Methods("POST")
+	return router
+}
+
+func postGrafana(t *testing.T, router *mux.Router, path, body string, v interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", path, strings.NewReader(body)))
+	if rr.Code == http.StatusOK && v != nil {
+		if err := json.Unmarshal(rr.Body.Bytes(), v); err != nil {
+			t.Fatalf("Could not parse response %q: %v", rr.Body.String(), err)
+		}
+	}
+	return rr
+}
+
+func TestGrafanaTestConnection(t *testing.T) {
+	router := newGrafanaTestRouter(t)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/grafana/", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestGrafanaSearch(t *testing.T) {
+	router := newGrafanaTestRouter(t)
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"", []string{"gpu_0", "gpu_1"}},
+		{"_1", []string{"gpu_1"}},
+		{"gpu_0:", []string{"gpu_0:utilization"}},
+		{"gpu_0:temp", []string{}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		rr := postGrafana(t, router, "/grafana/search", `{"target":"`+tt.target+`"}`, &got)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("search %q: expected status 200, got %d", tt.target, rr.Code)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("search %q = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestGrafanaQuery_TimeSeries(t *testing.T) {
+	router := newGrafanaTestRouter(t)
+
+	// Samples are one minute apart from 12:00; the range selects 12:02-12:07
+	body := `{
+		"range": {"from": "2024-01-01T12:02:00Z", "to": "2024-01-01T12:07:00Z"},
+		"maxDataPoints": 3,
+		"targets": [
+			{"refId": "A", "target": "gpu_0:utilization"},
+			{"refId": "B", "target": "gpu_0"},
+			{"refId": "C", "target": "gpu_0:hidden", "hide": true}
+		]
+	}`
+
+	var series []GrafanaTimeSeries
+	rr := postGrafana(t, router, "/grafana/query", body, &series)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %d", len(series))
+	}
+
+	for _, s := range series {
+		if s.Target != "gpu_0:utilization" {
+			t.Errorf("Unexpected series target %q", s.Target)
+		}
+		// Six samples thinned to three, ending with the latest
+		want := []float64{2, 4, 7}
+		if len(s.Datapoints) != len(want) {
+			t.Fatalf("Expected %d datapoints, got %v", len(want), s.Datapoints)
+		}
+		for i, dp := range s.Datapoints {
+			if dp[0] != want[i] {
+				t.Errorf("Datapoint %d value = %v, want %v", i, dp[0], want[i])
+			}
+		}
+		if last := s.Datapoints[2][1]; last != 1704110820000 {
+			t.Errorf("Expected last datapoint at 12:07 in ms, got %v", last)
+		}
+	}
+}
+
+func TestGrafanaQuery_Table(t *testing.T) {
+	router := newGrafanaTestRouter(t)
+
+	body := `{"targets": [{"refId": "A", "target": "gpu_0", "type": "table"}]}`
+
+	var tables []GrafanaTable
+	rr := postGrafana(t, router, "/grafana/query", body, &tables)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if len(tables) != 1 || tables[0].Type != "table" {
+		t.Fatalf("Expected one table, got %+v", tables)
+	}
+
+	table := tables[0]
+	var columns []string
+	for _, c := range table.Columns {
+		columns = append(columns, c.Text)
+	}
+	if want := []string{"Time", "gpu_id", "hostname", "utilization"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("Columns = %v, want %v", columns, want)
+	}
+	if len(table.Rows) != 10 {
+		t.Errorf("Expected 10 rows, got %d", len(table.Rows))
+	}
+}
+
+func TestGrafanaQuery_InvalidBody(t *testing.T) {
+	router := newGrafanaTestRouter(t)
+
+	rr := postGrafana(t, router, "/grafana/query", `{"targets":`, nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
@@ -1,36 +1,57 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
 	"github.com/harishb93/telemetry-pipeline/internal/collector"
+	"github.com/harishb93/telemetry-pipeline/internal/httpcache"
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
 )
 
 // Handlers contains HTTP request handlers for the API
 type Handlers struct {
-	collector    *collector.Collector
-	collectorURL string // URL to the collector service
+	collector      *collector.Collector
+	collectorURL   string // URL to the collector service
+	client         *http.Client
+	maxResultItems int // hard cap on items in a streamed response
+
+	maxSeriesPoints int // points per series above which rollups are served
+
+	usage *usageTracker // per API key usage, served by GetUsage
 }
 
 // NewHandlers creates a new handlers instance
 func NewHandlers(collector *collector.Collector) *Handlers {
-	// Get collector URL from environment, default to localhost for local development
+	// Get collector URL from environment, default to localhost for local development.
+	// A unix: address reaches a collector listening on a Unix domain socket.
 	collectorURL := os.Getenv("COLLECTOR_URL")
 	if collectorURL == "" {
 		collectorURL = "http://telemetry-collector:8080"
 	}
+	client, collectorURL := netutil.HTTPClient(collectorURL, 0)
 
 	return &Handlers{
-		collector:    collector,
-		collectorURL: collectorURL,
+		collector:      collector,
+		collectorURL:   collectorURL,
+		client:         client,
+		maxResultItems: DefaultMaxResultItems,
+
+		maxSeriesPoints: DefaultMaxSeriesPoints,
 	}
 }
 
@@ -42,46 +63,116 @@ type CollectorStats struct {
 	TotalGPUs        int            `json:"total_gpus"`
 }
 
-// GPUResponse represents the response for GPU list endpoint
+// GPUResponse represents the response for GPU list endpoint. Health holds
+// the collector's health classification of each listed GPU, and is omitted if
+// the collector could not provide it.
 type GPUResponse struct {
-	GPUs       []string           `json:"gpus"`
-	Total      int                `json:"total"`
-	Pagination PaginationMetadata `json:"pagination"`
+	GPUs       []string                       `json:"gpus" example:"gpu_0,gpu_1"`
+	Health     map[string]collector.GPUHealth `json:"health,omitempty"`
+	Total      int                            `json:"total" example:"2"`
+	Pagination PaginationMetadata             `json:"pagination"`
+}
+
+// TelemetryEntry is a single telemetry sample: every metric reported for a
+// GPU at one timestamp
+type TelemetryEntry struct {
+	GPUID    string             `json:"gpu_id" example:"gpu_0"`
+	Hostname string             `json:"hostname" example:"mtv5-dgx1-hgpu-031"`
+	Metrics  map[string]float64 `json:"metrics" example:"DCGM_FI_DEV_GPU_UTIL:87,DCGM_FI_DEV_GPU_TEMP:61.5"`
+	// Units are the units of the metrics with a known unit, after any
+	// conversion asked for
+	Units     map[string]string `json:"units,omitempty" example:"DCGM_FI_DEV_GPU_UTIL:%,DCGM_FI_DEV_GPU_TEMP:C"`
+	Timestamp time.Time         `json:"timestamp" example:"2025-07-18T20:42:34Z"`
+	// Labels annotate where the sample came from and how it was stored, e.g.
+	// its source file and row or its sampling rate
+	Labels map[string]string `json:"labels,omitempty" example:"sampling:1m"`
+	// CorrelationID identifies the source record the sample was streamed
+	// from: <run>.<worker>.<pass>.<file>.<line>
+	CorrelationID string `json:"correlation_id,omitempty" example:"01J3Q8M4K7W9X2T5B6N8R0C1DE.0.0.0.17"`
+}
+
+// newTelemetryEntry converts a collector sample to its API representation,
+// converting its metrics to the wanted units in place
+func newTelemetryEntry(t *collector.Telemetry, units collector.UnitConversions) TelemetryEntry {
+	return TelemetryEntry{
+		GPUID:     t.GPUId,
+		Hostname:  t.Hostname,
+		Metrics:   t.Metrics,
+		Units:     units.Apply(t.Metrics),
+		Timestamp: t.Timestamp,
+		Labels:    t.Labels,
+
+		CorrelationID: t.CorrelationID,
+	}
 }
 
-// TelemetryResponse represents the response for telemetry endpoint
+// TelemetryResponse represents the response for telemetry endpoint. It is
+// streamed rather than encoded in one piece; Truncated is set when the page
+// was cut short by the server's result size limit.
 type TelemetryResponse struct {
-	Data       []*collector.Telemetry `json:"data"`
-	Total      int                    `json:"total"`
-	Pagination PaginationMetadata     `json:"pagination"`
+	Data       []TelemetryEntry   `json:"data"`
+	Total      int                `json:"total" example:"5000"`
+	Truncated  bool               `json:"truncated" example:"false"`
+	Pagination PaginationMetadata `json:"pagination"`
+	// Tiers lists the collector storage tiers that held the data: warm for
+	// its telemetry files, cold for its object storage
+	Tiers   []string `json:"tiers,omitempty" example:"cold,warm"`
+	Error   string   `json:"error,omitempty"`
+	TraceID string   `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+}
+
+// HealthResponse represents the response for the health endpoint
+type HealthResponse struct {
+	Status    string          `json:"status" example:"healthy"`
+	Service   string          `json:"service" example:"telemetry-api-gateway"`
+	Timestamp time.Time       `json:"timestamp" example:"2025-10-20T12:00:00Z"`
+	Version   string          `json:"version" example:"1.0.0"`
+	Collector CollectorHealth `json:"collector"`
+}
+
+// CollectorHealth reports whether the gateway can reach the collector
+type CollectorHealth struct {
+	Status string `json:"status" example:"healthy"`
+	Error  string `json:"error,omitempty"`
 }
 
 // HostsResponse represents the response for hosts list endpoint
 type HostsResponse struct {
-	Hosts      []string           `json:"hosts"`
-	Total      int                `json:"total"`
+	Hosts      []string           `json:"hosts" example:"mtv5-dgx1-hgpu-031"`
+	Total      int                `json:"total" example:"1"`
 	Pagination PaginationMetadata `json:"pagination"`
 }
 
 // HostGPUsResponse represents the response for host GPUs endpoint
 type HostGPUsResponse struct {
-	Hostname string   `json:"hostname"`
-	GPUs     []string `json:"gpus"`
-	Total    int      `json:"total"`
+	Hostname string   `json:"hostname" example:"mtv5-dgx1-hgpu-031"`
+	GPUs     []string `json:"gpus" example:"gpu_0,gpu_1"`
+	Total    int      `json:"total" example:"2"`
+}
+
+// MetricCatalogResponse represents the response for the metric names endpoint
+type MetricCatalogResponse struct {
+	Metrics []collector.MetricInfo `json:"metrics"`
+	Total   int                    `json:"total"`
 }
 
 // PaginationMetadata represents pagination information
 type PaginationMetadata struct {
-	Limit   int  `json:"limit"`
-	Offset  int  `json:"offset"`
-	HasNext bool `json:"has_next"`
+	Limit   int  `json:"limit" example:"100"`
+	Offset  int  `json:"offset" example:"0"`
+	HasNext bool `json:"has_next" example:"true"`
 }
 
-// ErrorResponse represents an error response
+// ErrorResponse represents an error response. TraceID matches the
+// X-Trace-ID header, for quoting in bug reports.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	Error   string `json:"error" example:"Bad Request"`
+	Message string `json:"message" example:"Invalid pagination parameters: strconv.Atoi: parsing \"x\": invalid syntax"`
+	Code    int    `json:"code" example:"400"`
+	// ErrorCode and Reason identify the error; unlike Message, they never change
+	ErrorCode string `json:"error_code" example:"TP-4002"`
+	Reason    string `json:"reason" example:"INVALID_PAGINATION"`
+	TraceID   string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
 }
 
 // @title Telemetry API
@@ -103,6 +194,7 @@ type ErrorResponse struct {
 // @Produce json
 // @Param limit query int false "Number of items to return (default: 50, max: 1000)"
 // @Param offset query int false "Number of items to skip (default: 0)"
+// @Param health query string false "Only list GPUs in this health state" Enums(healthy, degraded, unknown)
 // @Success 200 {object} GPUResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -111,16 +203,44 @@ func (h *Handlers) GetGPUs(w http.ResponseWriter, r *http.Request) {
 	// Parse pagination parameters
 	limit, offset, err := h.parsePagination(r)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid pagination parameters", err.Error())
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidPagination, "Invalid pagination parameters", err.Error())
 		return
 	}
 
+	var healthFilter collector.HealthState
+	if v := r.URL.Query().Get("health"); v != "" {
+		healthFilter, err = collector.ParseHealthState(v)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidParameter, "Invalid health filter", err.Error())
+			return
+		}
+	}
+
+	// Health is best effort unless the caller filters on it
+	health, err := h.getGPUHealth(r.Context())
+	if err != nil {
+		if healthFilter != "" {
+			h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve GPU health", err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Warn("Failed to retrieve GPU health", "error", err)
+	}
+
 	// Get GPU IDs from both memory and file storage
-	gpuIDs, err := h.getAllGPUIDs()
+	gpuIDs, err := h.listGPUIDs(r.Context(), health)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve GPU IDs", err.Error())
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve GPU IDs", err.Error())
 		return
 	}
+	if healthFilter != "" {
+		filtered := []string{}
+		for _, gpuID := range gpuIDs {
+			if lookupHealth(health, gpuID).State == healthFilter {
+				filtered = append(filtered, gpuID)
+			}
+		}
+		gpuIDs = filtered
+	}
 
 	// Apply pagination
 	total := len(gpuIDs)
@@ -136,9 +256,18 @@ func (h *Handlers) GetGPUs(w http.ResponseWriter, r *http.Request) {
 		paginatedGPUs = []string{}
 	}
 
+	var pageHealth map[string]collector.GPUHealth
+	if health != nil {
+		pageHealth = make(map[string]collector.GPUHealth, len(paginatedGPUs))
+		for _, gpuID := range paginatedGPUs {
+			pageHealth[gpuID] = lookupHealth(health, gpuID)
+		}
+	}
+
 	response := GPUResponse{
-		GPUs:  paginatedGPUs,
-		Total: total,
+		GPUs:   paginatedGPUs,
+		Health: pageHealth,
+		Total:  total,
 		Pagination: PaginationMetadata{
 			Limit:   limit,
 			Offset:  offset,
@@ -160,7 +289,11 @@ func (h *Handlers) GetGPUs(w http.ResponseWriter, r *http.Request) {
 // @Param end_time query string false "End time filter (RFC3339 format)"
 // @Param limit query int false "Number of items to return (default: 100, max: 1000)"
 // @Param offset query int false "Number of items to skip (default: 0)"
+// @Param units query string false "Comma-separated units to convert metrics to, at most one per dimension, e.g. GiB,F"
+// @Param If-None-Match header string false "ETag from a previous response; returns 304 if unchanged"
+// @Param If-Modified-Since header string false "Last-Modified from a previous response; returns 304 if unchanged"
 // @Success 200 {object} TelemetryResponse
+// @Success 304 "Telemetry unchanged since the conditional request"
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -171,54 +304,244 @@ func (h *Handlers) GetTelemetry(w http.ResponseWriter, r *http.Request) {
 	gpuID := vars["id"]
 
 	if gpuID == "" {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Missing GPU ID", "GPU ID is required")
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.MissingParameter, "Missing GPU ID", "GPU ID is required")
 		return
 	}
 
 	// Parse pagination parameters
 	limit, offset, err := h.parsePagination(r)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid pagination parameters", err.Error())
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidPagination, "Invalid pagination parameters", err.Error())
 		return
 	}
 
 	// Parse time range parameters
 	startTime, endTime, err := h.parseTimeRange(r)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid time range parameters", err.Error())
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidTimeRange, "Invalid time range parameters", err.Error())
+		return
+	}
+
+	units, err := parseUnits(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidUnits, "Invalid units parameter", err.Error())
 		return
 	}
 
-	// Get telemetry data
-	telemetryData, err := h.getTelemetryData(gpuID, startTime, endTime, limit, offset)
+	maxItems := limit
+	if h.maxResultItems > 0 && h.maxResultItems < maxItems {
+		maxItems = h.maxResultItems
+	}
+
+	// Open the collector response before committing to a status code,
+	// passing on any conditional headers so unchanged data costs a 304
+	queryKey := telemetryQueryKey(limit, offset, maxItems, startTime, endTime, units)
+	endpoint := fmt.Sprintf("/api/v1/gpus/%s/telemetry", gpuID)
+	if startTime != nil {
+		// Lets the collector skip cold storage older than the range
+		endpoint += "?" + url.Values{"start_time": {startTime.Format(time.RFC3339)}}.Encode()
+	}
+	resp, err := h.collectorGet(r.Context(), endpoint, upstreamConditionals(r, queryKey))
 	if err != nil {
-		if err.Error() == "GPU not found" {
-			h.writeErrorResponse(w, http.StatusNotFound, "GPU not found", "No telemetry data found for GPU ID: "+gpuID)
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorUnavailable, "Failed to retrieve telemetry data", err.Error())
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotModified {
+		var etag string
+		if upstream := resp.Header.Get("ETag"); upstream != "" {
+			etag = gatewayETag(upstream, queryKey)
+		}
+		lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+
+		w.Header().Set("Cache-Control", "no-cache")
+		httpcache.SetValidators(w, etag, lastModified)
+		if resp.StatusCode == http.StatusNotModified || httpcache.NotModified(r, etag, lastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve telemetry data",
+			fmt.Sprintf("collector telemetry endpoint returned status %d", resp.StatusCode))
+		return
+	}
+
+	// Stream the page while decoding, counting the total in the same pass
+	stream := newJSONStream(w, http.StatusOK)
+	stream.BeginArray("data")
+
+	total, written, truncated := 0, 0, false
+	decodeErr := decodeTelemetryStream(resp.Body, func(telemetry *collector.Telemetry) {
+		if !inTimeRange(telemetry, startTime, endTime) {
 			return
 		}
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve telemetry data", err.Error())
+		if total >= offset && total < offset+limit {
+			if written < maxItems {
+				stream.Item(newTelemetryEntry(telemetry, units))
+				written++
+			} else {
+				truncated = true
+			}
+		}
+		total++
+	})
+
+	stream.EndArray()
+	stream.Field("total", total)
+	stream.Field("truncated", truncated)
+	stream.Field("pagination", PaginationMetadata{
+		Limit:   limit,
+		Offset:  offset,
+		HasNext: offset+written < total,
+	})
+	if tiers := resp.Header.Get(collector.TiersHeader); tiers != "" {
+		stream.Field("tiers", strings.Split(tiers, ","))
+	}
+	if decodeErr != nil {
+		// Headers are already sent, so report the failure in the body
+		stream.Field("error", fmt.Sprintf("failed to decode collector telemetry response: %v", decodeErr))
+		if id := w.Header().Get(TraceIDHeader); id != "" {
+			stream.Field("trace_id", id)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to stream telemetry response", "error", err)
+	}
+}
+
+// GetEnergy returns estimated daily energy use for a specific GPU
+// @Summary Get energy usage for a GPU
+// @Description Returns energy (kWh) per UTC day integrated from DCGM power usage samples. Gaps between samples longer than the collector's max gap are not interpolated; covered_hours shows how much of each day the estimate spans.
+// @Tags GPUs
+// @Accept json
+// @Produce json
+// @Param id path string true "GPU ID"
+// @Param from query string false "First day to include (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Last day to include (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} collector.EnergySummary
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /gpus/{id}/energy [get]
+func (h *Handlers) GetEnergy(w http.ResponseWriter, r *http.Request) {
+	gpuID := mux.Vars(r)["id"]
+	if gpuID == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.MissingParameter, "Missing GPU ID", "GPU ID is required")
 		return
 	}
 
-	// Get total count (without pagination)
-	totalData, err := h.getTelemetryData(gpuID, startTime, endTime, 0, 0)
+	from, err := collector.ParseEnergyDate(r.URL.Query().Get("from"))
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to get total count", err.Error())
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidTimeRange, "Invalid date range parameters", err.Error())
+		return
+	}
+	to, err := collector.ParseEnergyDate(r.URL.Query().Get("to"))
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidTimeRange, "Invalid date range parameters", err.Error())
 		return
 	}
-	total := len(totalData)
 
-	response := TelemetryResponse{
-		Data:  telemetryData,
-		Total: total,
-		Pagination: PaginationMetadata{
-			Limit:   limit,
-			Offset:  offset,
-			HasNext: offset+limit < total,
-		},
+	query := url.Values{}
+	if from != "" {
+		query.Set("from", from)
+	}
+	if to != "" {
+		query.Set("to", to)
+	}
+	endpoint := fmt.Sprintf("/api/v1/gpus/%s/energy", url.PathEscape(gpuID))
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, response)
+	resp, err := h.collectorGet(r.Context(), endpoint, nil)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorUnavailable, "Failed to retrieve energy data", err.Error())
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve energy data",
+			fmt.Sprintf("collector energy endpoint returned status %d", resp.StatusCode))
+		return
+	}
+
+	var summary collector.EnergySummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve energy data",
+			fmt.Sprintf("failed to decode collector energy response: %v", err))
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, summary)
+}
+
+// GetFleet returns the latest metric values of every GPU
+// @Summary Get a fleet snapshot
+// @Description Returns the latest value of each metric for every GPU, with when each GPU was last seen. By default it reflects the collector's cache of recent samples; with as_of it is rebuilt from the collector's stored telemetry, cold storage included, using only samples at or before that time, answering what the fleet looked like then. GPUs with no samples by as_of are left out.
+// @Tags GPUs
+// @Accept json
+// @Produce json
+// @Param as_of query string false "Point in time to snapshot (RFC3339, default: now)"
+// @Param units query string false "Comma-separated units to convert metrics to, at most one per dimension, e.g. GiB,F"
+// @Success 200 {object} collector.FleetSnapshot
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fleet [get]
+func (h *Handlers) GetFleet(w http.ResponseWriter, r *http.Request) {
+	asOf, err := collector.ParseAsOf(r.URL.Query().Get("as_of"))
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidTimeRange, "Invalid as_of parameter", err.Error())
+		return
+	}
+	units, err := parseUnits(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidUnits, "Invalid units parameter", err.Error())
+		return
+	}
+
+	endpoint := "/api/v1/fleet"
+	if !asOf.IsZero() {
+		endpoint += "?" + url.Values{"as_of": {asOf.Format(time.RFC3339Nano)}}.Encode()
+	}
+	resp, err := h.collectorGet(r.Context(), endpoint, nil)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorUnavailable, "Failed to retrieve fleet snapshot", err.Error())
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve fleet snapshot",
+			fmt.Sprintf("collector fleet endpoint returned status %d", resp.StatusCode))
+		return
+	}
+
+	var snapshot collector.FleetSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve fleet snapshot",
+			fmt.Sprintf("failed to decode collector fleet response: %v", err))
+		return
+	}
+	for i := range snapshot.GPUs {
+		snapshot.GPUs[i].Units = units.Apply(snapshot.GPUs[i].Metrics)
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, snapshot)
 }
 
 // GetHosts returns a list of all hosts with available telemetry data
@@ -237,14 +560,14 @@ func (h *Handlers) GetHosts(w http.ResponseWriter, r *http.Request) {
 	// Parse pagination parameters
 	limit, offset, err := h.parsePagination(r)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid pagination parameters", err.Error())
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidPagination, "Invalid pagination parameters", err.Error())
 		return
 	}
 
 	// Get hosts from collector service
-	hosts, err := h.getAllHosts()
+	hosts, err := h.getAllHosts(r.Context())
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve hosts", err.Error())
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve hosts", err.Error())
 		return
 	}
 
@@ -293,18 +616,18 @@ func (h *Handlers) GetHostGPUs(w http.ResponseWriter, r *http.Request) {
 	hostname := vars["hostname"]
 
 	if hostname == "" {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Missing hostname", "Hostname is required")
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.MissingParameter, "Missing hostname", "Hostname is required")
 		return
 	}
 
 	// Get GPUs for the host from collector service
-	gpus, err := h.getGPUsForHost(hostname)
+	gpus, err := h.getGPUsForHost(r.Context(), hostname)
 	if err != nil {
 		if err.Error() == "no data found for host" {
-			h.writeErrorResponse(w, http.StatusNotFound, "Host not found", "No telemetry data found for hostname: "+hostname)
+			h.writeErrorResponse(w, http.StatusNotFound, apierror.HostNotFound, "Host not found", "No telemetry data found for hostname: "+hostname)
 			return
 		}
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve GPUs for host", err.Error())
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve GPUs for host", err.Error())
 		return
 	}
 
@@ -317,25 +640,100 @@ func (h *Handlers) GetHostGPUs(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(w, http.StatusOK, response)
 }
 
+// GetMetricCatalog returns the metric names seen in telemetry
+// @Summary Get metric names
+// @Description Returns the distinct metric names (e.g. DCGM fields) the collector has received since it started, with sample counts, the number of GPUs reporting each and when each was last seen
+// @Tags Metrics
+// @Accept json
+// @Produce json
+// @Param gpu_id query string false "Only metrics reported by this GPU"
+// @Success 200 {object} MetricCatalogResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /metrics [get]
+func (h *Handlers) GetMetricCatalog(w http.ResponseWriter, r *http.Request) {
+	endpoint := "/api/v1/metrics"
+	if gpuID := r.URL.Query().Get("gpu_id"); gpuID != "" {
+		endpoint += "?" + url.Values{"gpu_id": {gpuID}}.Encode()
+	}
+
+	resp, err := h.collectorGet(r.Context(), endpoint, nil)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorUnavailable, "Failed to retrieve metric names", err.Error())
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve metric names",
+			fmt.Sprintf("collector metrics endpoint returned status %d", resp.StatusCode))
+		return
+	}
+
+	var response MetricCatalogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve metric names",
+			fmt.Sprintf("failed to decode collector metrics response: %v", err))
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// GetCapacity reports how close the pipeline is to its limits
+// @Summary Get capacity
+// @Description Returns the collector's memory use against its memory limit, tracked GPUs and hosts against the cardinality caps, the broker queue depth, quarantine size and the use of the filesystems holding its data, plus the gateway's own memory. Each limit is ok, warning (from 80%) or critical (from 95%), and status is the most severe of them; limits without a configured maximum are always ok.
+// @Tags Capacity
+// @Accept json
+// @Produce json
+// @Success 200 {object} collector.CapacityReport
+// @Failure 500 {object} ErrorResponse
+// @Router /capacity [get]
+func (h *Handlers) GetCapacity(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.collectorGet(r.Context(), "/api/v1/capacity", nil)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorUnavailable, "Failed to retrieve capacity", err.Error())
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve capacity",
+			fmt.Sprintf("collector capacity endpoint returned status %d", resp.StatusCode))
+		return
+	}
+
+	var report collector.CapacityReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Failed to retrieve capacity",
+			fmt.Sprintf("failed to decode collector capacity response: %v", err))
+		return
+	}
+	report.Add(collector.MemoryCapacity("gateway_memory"))
+
+	h.writeJSONResponse(w, http.StatusOK, report)
+}
+
 // Health returns the health status of the API
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
-	health := map[string]interface{}{
-		"status":    "healthy",
-		"timestamp": time.Now().UTC(),
-		"version":   "1.0.0",
-		"service":   "telemetry-api-gateway",
+	health := HealthResponse{
+		Status:    "healthy",
+		Service:   "telemetry-api-gateway",
+		Timestamp: time.Now().UTC(),
+		Version:   "1.0.0",
+		Collector: CollectorHealth{Status: "healthy"},
 	}
 
 	// Add collector health status by fetching from collector service
-	if _, err := h.getCollectorStats(); err == nil {
-		health["collector"] = map[string]interface{}{
-			"status": "healthy",
-		}
-	} else {
-		health["collector"] = map[string]interface{}{
-			"status": "unhealthy",
-			"error":  err.Error(),
-		}
+	if _, err := h.getCollectorStats(r.Context()); err != nil {
+		health.Collector = CollectorHealth{Status: "unhealthy", Error: err.Error()}
 	}
 
 	h.writeJSONResponse(w, http.StatusOK, health)
@@ -399,9 +797,49 @@ func (h *Handlers) parseTimeRange(r *http.Request) (*time.Time, *time.Time, erro
 	return startTime, endTime, nil
 }
 
+// parseUnits parses the units query parameter, the units metrics are
+// converted to
+func parseUnits(r *http.Request) (collector.UnitConversions, error) {
+	return collector.ParseUnitConversions(r.URL.Query().Get("units"))
+}
+
+// collectorGet issues a GET with the given extra headers to the collector
+// service, recording its timing for slow query logging
+func (h *Handlers) collectorGet(ctx context.Context, endpoint string, header http.Header) (*http.Response, error) {
+	return h.collectorDo(ctx, http.MethodGet, endpoint, header, nil)
+}
+
+// collectorDo issues a request to the collector service, recording its timing
+// for slow query logging
+func (h *Handlers) collectorDo(ctx context.Context, method, endpoint string, header http.Header, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, h.collectorURL+endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if id := TraceIDFromContext(ctx); id != "" {
+		req.Header.Set(TraceIDHeader, id)
+	}
+	if tenant := tenantFromContext(ctx); tenant != "" {
+		req.Header.Set(TenantHeader, tenant)
+	}
+
+	start := time.Now()
+	resp, err := h.client.Do(req)
+	call := upstreamCall{Endpoint: method + " " + endpoint, Duration: time.Since(start), Err: err}
+	if err == nil {
+		call.Status = resp.StatusCode
+	}
+	recordUpstream(ctx, call)
+
+	return resp, err
+}
+
 // getCollectorStats fetches stats from the collector service via HTTP
-func (h *Handlers) getCollectorStats() (*CollectorStats, error) {
-	resp, err := http.Get(h.collectorURL + "/stats")
+func (h *Handlers) getCollectorStats(ctx context.Context) (*CollectorStats, error) {
+	resp, err := h.collectorGet(ctx, "/stats", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -423,9 +861,19 @@ func (h *Handlers) getCollectorStats() (*CollectorStats, error) {
 	return &stats, nil
 }
 
-func (h *Handlers) getAllGPUIDs() ([]string, error) {
+func (h *Handlers) getAllGPUIDs(ctx context.Context) ([]string, error) {
+	health, err := h.getGPUHealth(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to retrieve GPU health", "error", err)
+	}
+	return h.listGPUIDs(ctx, health)
+}
+
+// listGPUIDs returns the GPUs cached by the collector service, plus those in
+// its GPU listing, which also covers GPUs only found in file storage, sorted
+func (h *Handlers) listGPUIDs(ctx context.Context, listed map[string]collector.GPUHealth) ([]string, error) {
 	// Get GPU IDs from collector service via HTTP
-	stats, err := h.getCollectorStats()
+	stats, err := h.getCollectorStats(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get collector stats: %w", err)
 	}
@@ -434,16 +882,21 @@ func (h *Handlers) getAllGPUIDs() ([]string, error) {
 	for gpuID := range stats.GPUEntryCounts {
 		gpuIDs = append(gpuIDs, gpuID)
 	}
+	for gpuID := range listed {
+		if _, ok := stats.GPUEntryCounts[gpuID]; !ok {
+			gpuIDs = append(gpuIDs, gpuID)
+		}
+	}
+	sort.Strings(gpuIDs)
 
 	return gpuIDs, nil
 }
 
-func (h *Handlers) getTelemetryData(gpuID string, startTime, endTime *time.Time, limit, offset int) ([]*collector.Telemetry, error) {
-	// Get telemetry data from collector service via HTTP
-	url := fmt.Sprintf("%s/api/v1/gpus/%s/telemetry", h.collectorURL, gpuID)
-	resp, err := http.Get(url)
+// getGPUHealth fetches the health of every GPU from the collector service
+func (h *Handlers) getGPUHealth(ctx context.Context) (map[string]collector.GPUHealth, error) {
+	resp, err := h.collectorGet(ctx, "/api/v1/gpus", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call collector telemetry endpoint: %w", err)
+		return nil, fmt.Errorf("failed to call collector GPUs endpoint: %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -452,64 +905,46 @@ func (h *Handlers) getTelemetryData(gpuID string, startTime, endTime *time.Time,
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("collector telemetry endpoint returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("collector GPUs endpoint returned status %d", resp.StatusCode)
 	}
 
 	var response struct {
-		Data  []*collector.Telemetry `json:"data"`
-		Total int                    `json:"total"`
-		GpuID string                 `json:"gpu_id"`
+		GPUs []collector.GPUHealthEntry `json:"gpus"`
 	}
-
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode collector telemetry response: %w", err)
+		return nil, fmt.Errorf("failed to decode GPUs response: %w", err)
 	}
 
-	allData := response.Data
-	if len(allData) == 0 {
-		return nil, nil // No error, just no data
-	}
-
-	// Apply time range filtering
-	var filteredData []*collector.Telemetry
-	for _, telemetry := range allData {
-		include := true
-
-		if startTime != nil && telemetry.Timestamp.Before(*startTime) {
-			include = false
-		}
-
-		if endTime != nil && telemetry.Timestamp.After(*endTime) {
-			include = false
-		}
-
-		if include {
-			filteredData = append(filteredData, telemetry)
-		}
+	health := make(map[string]collector.GPUHealth, len(response.GPUs))
+	for _, entry := range response.GPUs {
+		health[entry.GPUId] = entry.Health
 	}
+	return health, nil
+}
 
-	// Apply pagination
-	total := len(filteredData)
-	if limit == 0 {
-		return filteredData, nil // Return all if no limit specified
+// lookupHealth returns a GPU's health, or unknown if the collector has not
+// classified it
+func lookupHealth(health map[string]collector.GPUHealth, gpuID string) collector.GPUHealth {
+	if gpuHealth, ok := health[gpuID]; ok {
+		return gpuHealth
 	}
+	return collector.GPUHealth{State: collector.HealthUnknown}
+}
 
-	end := offset + limit
-	if end > total {
-		end = total
+// inTimeRange reports whether telemetry falls within the optional time bounds
+func inTimeRange(telemetry *collector.Telemetry, startTime, endTime *time.Time) bool {
+	if startTime != nil && telemetry.Timestamp.Before(*startTime) {
+		return false
 	}
-
-	if offset >= total {
-		return []*collector.Telemetry{}, nil // Return empty slice if offset is beyond data
+	if endTime != nil && telemetry.Timestamp.After(*endTime) {
+		return false
 	}
-
-	return filteredData[offset:end], nil
+	return true
 }
 
 // Helper method to get all hosts from collector service
-func (h *Handlers) getAllHosts() ([]string, error) {
-	url := fmt.Sprintf("%s/api/v1/hosts", h.collectorURL)
-	resp, err := http.Get(url)
+func (h *Handlers) getAllHosts(ctx context.Context) ([]string, error) {
+	resp, err := h.collectorGet(ctx, "/api/v1/hosts", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call collector hosts endpoint: %w", err)
 	}
@@ -535,9 +970,8 @@ func (h *Handlers) getAllHosts() ([]string, error) {
 }
 
 // Helper method to get GPUs for a specific host from collector service
-func (h *Handlers) getGPUsForHost(hostname string) ([]string, error) {
-	url := fmt.Sprintf("%s/api/v1/hosts/%s/gpus", h.collectorURL, hostname)
-	resp, err := http.Get(url)
+func (h *Handlers) getGPUsForHost(ctx context.Context, hostname string) ([]string, error) {
+	resp, err := h.collectorGet(ctx, fmt.Sprintf("/api/v1/hosts/%s/gpus", hostname), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call collector host GPUs endpoint: %w", err)
 	}
@@ -576,11 +1010,14 @@ func (h *Handlers) writeJSONResponse(w http.ResponseWriter, statusCode int, data
 	}
 }
 
-func (h *Handlers) writeErrorResponse(w http.ResponseWriter, statusCode int, message, details string) {
+func (h *Handlers) writeErrorResponse(w http.ResponseWriter, statusCode int, code apierror.Code, message, details string) {
 	errorResp := ErrorResponse{
-		Error:   http.StatusText(statusCode),
-		Message: message,
-		Code:    statusCode,
+		Error:     http.StatusText(statusCode),
+		Message:   message,
+		Code:      statusCode,
+		ErrorCode: code.ID,
+		Reason:    code.Name,
+		TraceID:   w.Header().Get(TraceIDHeader),
 	}
 
 	if details != "" {
@@ -699,6 +699,236 @@ func TestGetHostGPUs(t *testing.T) {
 	}
 }
 
+func TestGetGPUs_Health(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/stats":
+			_, _ = w.Write([]byte(`{"gpu_entry_counts":{"gpu_0":1,"gpu_1":1,"gpu_2":1}}`))
+		case "/api/v1/gpus":
+			// gpu_2 has not been classified by the collector yet
+			_, _ = w.Write([]byte(`{"gpus":[
+				{"gpu_id":"gpu_0","health":{"state":"healthy"}},
+				{"gpu_id":"gpu_1","health":{"state":"degraded","reasons":["DCGM_FI_DEV_GPU_TEMP > 85 for 5m0s"]}}
+			]}`))
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+
+	get := func(query string) (*httptest.ResponseRecorder, GPUResponse) {
+		rr := httptest.NewRecorder()
+		handlers.GetGPUs(rr, httptest.NewRequest("GET", "/api/v1/gpus"+query, nil))
+		var response GPUResponse
+		_ = json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr, response
+	}
+
+	rr, response := get("")
+	if rr.Code != http.StatusOK || response.Total != 3 {
+		t.Fatalf("Expected 3 GPUs, got %d %+v", rr.Code, response)
+	}
+	if response.Health["gpu_1"].State != collector.HealthDegraded {
+		t.Errorf("Expected gpu_1 degraded, got %+v", response.Health["gpu_1"])
+	}
+	if response.Health["gpu_2"].State != collector.HealthUnknown {
+		t.Errorf("Expected unclassified gpu_2 unknown, got %+v", response.Health["gpu_2"])
+	}
+
+	_, response = get("?health=degraded")
+	if len(response.GPUs) != 1 || response.GPUs[0] != "gpu_1" || response.Total != 1 {
+		t.Errorf("Expected only gpu_1 for health=degraded, got %+v", response)
+	}
+
+	rr, _ = get("?health=sick")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid health filter, got %d", rr.Code)
+	}
+}
+
+func TestGetGPUs_ListsStoredGPUs(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/stats":
+			_, _ = w.Write([]byte(`{"gpu_entry_counts":{"gpu_1":1}}`))
+		case "/api/v1/gpus":
+			// gpu_0 is only in the collector's file storage
+			_, _ = w.Write([]byte(`{"gpus":[
+				{"gpu_id":"gpu_0","health":{"state":"unknown"}},
+				{"gpu_id":"gpu_1","health":{"state":"healthy"}}
+			]}`))
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	rr := httptest.NewRecorder()
+	handlers.GetGPUs(rr, httptest.NewRequest("GET", "/api/v1/gpus", nil))
+
+	var response GPUResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 2 || response.GPUs[0] != "gpu_0" || response.GPUs[1] != "gpu_1" {
+		t.Errorf("Expected gpu_0 and gpu_1, got %+v", response)
+	}
+}
+
+func TestGetEnergy(t *testing.T) {
+	var upstreamQuery string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"gpu_id":"gpu_0","from":"2024-01-01","days":[{"date":"2024-01-01","energy_kwh":1.5,"covered_hours":24,"samples":1440}],"total_kwh":1.5}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/gpus/{id}/energy", handlers.GetEnergy).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/gpus/gpu_0/energy?from=2024-01-01T10:00:00Z", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if upstreamQuery != "from=2024-01-01" {
+		t.Errorf("Expected normalized date forwarded to collector, got %q", upstreamQuery)
+	}
+
+	var summary collector.EnergySummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalKWh != 1.5 || len(summary.Days) != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/gpus/gpu_0/energy?to=later", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid date, got %d", rr.Code)
+	}
+}
+
+func TestGetFleet(t *testing.T) {
+	var upstreamQuery string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"as_of":"2024-01-01T12:00:00Z","source":"archive","hosts":1,"gpus":[{"gpu_id":"gpu_0","hostname":"host-1","metrics":{"utilization":42},"last_seen":"2024-01-01T11:59:58Z"}],"total":1}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/fleet", handlers.GetFleet).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/fleet?as_of=2024-01-01T13:00:00%2B01:00", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if upstreamQuery != "as_of=2024-01-01T12%3A00%3A00Z" {
+		t.Errorf("Expected as_of forwarded to collector in UTC, got %q", upstreamQuery)
+	}
+
+	var snapshot collector.FleetSnapshot
+	if err := json.Unmarshal(rr.Body.Bytes(), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Source != collector.SnapshotSourceArchive || len(snapshot.GPUs) != 1 || snapshot.GPUs[0].Metrics["utilization"] != 42 {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/fleet?as_of=yesterday", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid as_of, got %d", rr.Code)
+	}
+}
+
+func TestGetFleet_Units(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"gpus":[{"gpu_id":"gpu_0","metrics":{"DCGM_FI_DEV_FB_USED":2048,"DCGM_FI_DEV_GPU_TEMP":50,"custom":1}}],"total":1}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/fleet", handlers.GetFleet).Methods("GET")
+
+	get := func(query string) (*httptest.ResponseRecorder, collector.GPUSnapshot) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/fleet"+query, nil))
+		var snapshot collector.FleetSnapshot
+		_ = json.Unmarshal(rr.Body.Bytes(), &snapshot)
+		if len(snapshot.GPUs) != 1 {
+			return rr, collector.GPUSnapshot{}
+		}
+		return rr, snapshot.GPUs[0]
+	}
+
+	// Units are reported as they are without conversions
+	if _, gpu := get(""); gpu.Units["DCGM_FI_DEV_FB_USED"] != "MiB" || gpu.Units["DCGM_FI_DEV_GPU_TEMP"] != "C" || gpu.Units["custom"] != "" {
+		t.Errorf("Unexpected units %v", gpu.Units)
+	}
+
+	_, gpu := get("?units=GiB,F")
+	if gpu.Metrics["DCGM_FI_DEV_FB_USED"] != 2 || gpu.Units["DCGM_FI_DEV_FB_USED"] != "GiB" {
+		t.Errorf("Expected 2 GiB of memory, got %v %s", gpu.Metrics["DCGM_FI_DEV_FB_USED"], gpu.Units["DCGM_FI_DEV_FB_USED"])
+	}
+	if gpu.Metrics["DCGM_FI_DEV_GPU_TEMP"] != 122 || gpu.Units["DCGM_FI_DEV_GPU_TEMP"] != "F" {
+		t.Errorf("Expected 122 F, got %v %s", gpu.Metrics["DCGM_FI_DEV_GPU_TEMP"], gpu.Units["DCGM_FI_DEV_GPU_TEMP"])
+	}
+	if gpu.Metrics["custom"] != 1 {
+		t.Errorf("Expected metrics without a known unit unchanged, got %v", gpu.Metrics["custom"])
+	}
+
+	for _, query := range []string{"?units=parsecs", "?units=GiB,MiB"} {
+		if rr, _ := get(query); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestGetMetricCatalog(t *testing.T) {
+	var upstreamQuery string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"metrics":[{"name":"DCGM_FI_DEV_GPU_UTIL","count":42,"gpus":2,"last_seen":"2024-01-01T12:00:00Z"}],"total":1}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	rr := httptest.NewRecorder()
+	handlers.GetMetricCatalog(rr, httptest.NewRequest("GET", "/api/v1/metrics?gpu_id=gpu_0", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if upstreamQuery != "gpu_id=gpu_0" {
+		t.Errorf("Expected gpu_id forwarded to collector, got %q", upstreamQuery)
+	}
+
+	var response MetricCatalogResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 1 || response.Metrics[0].Name != "DCGM_FI_DEV_GPU_UTIL" || response.Metrics[0].Count != 42 {
+		t.Errorf("Unexpected response %+v", response)
+	}
+}
+
 func BenchmarkGetGPUs(b *testing.B) {
 	coll := createTestCollector()
 	handlers := NewHandlers(coll)
@@ -727,3 +957,27 @@ func BenchmarkGetTelemetry(b *testing.B) {
 		router.ServeHTTP(rr, req)
 	}
 }
+
+func TestGetCapacity(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"warning","limits":[{"name":"gpus","used":85,"limit":100,"unit":"gpus","percent":85,"status":"warning"}]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/capacity", handlers.GetCapacity).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/capacity", nil))
+	var report collector.CapacityReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d %s", rr.Code, rr.Body.String())
+	}
+	// The collector's warning is kept, with the gateway's memory added
+	if report.Status != collector.CapacityWarning || len(report.Limits) != 2 || report.Limits[1].Name != "gateway_memory" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+)
+
+// DefaultMaxSeriesPoints is the number of points per series above which
+// series queries are served from rollups
+const DefaultMaxSeriesPoints = 1000
+
+// rawResolution is the resolution reported for series of raw samples
+const rawResolution = "raw"
+
+// rollupSteps are the bucket widths rollups are computed at; a plan uses the
+// smallest that keeps a series within its point limit
+var rollupSteps = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute,
+	time.Hour, 6 * time.Hour, 24 * time.Hour,
+}
+
+// queryPlan is how a series query is served
+type queryPlan struct {
+	// Step is the rollup bucket width; zero serves raw samples
+	Step time.Duration
+}
+
+// resolution describes the plan for API clients
+func (p queryPlan) resolution() string {
+	if p.Step == 0 {
+		return rawResolution
+	}
+	return p.Step.String()
+}
+
+// planQuery chooses raw samples or a rollup for a series query over
+// [from, to] at the client's step. Raw samples are served unless the window
+// and step imply more than maxPoints points; then the rollup is at the
+// smallest of rollupSteps (or whole days) keeping within maxPoints.
+func planQuery(from, to time.Time, step time.Duration, maxPoints int) queryPlan {
+	window := to.Sub(from)
+	if from.IsZero() || to.IsZero() || window <= 0 || step <= 0 || maxPoints <= 0 {
+		return queryPlan{}
+	}
+	if window/step <= time.Duration(maxPoints) {
+		return queryPlan{}
+	}
+	return queryPlan{Step: rollupStep(window, maxPoints)}
+}
+
+// rollupStep returns the smallest of rollupSteps, or whole days, that splits
+// window into at most maxPoints buckets
+func rollupStep(window time.Duration, maxPoints int) time.Duration {
+	minStep := (window + time.Duration(maxPoints) - 1) / time.Duration(maxPoints)
+	for _, rollup := range rollupSteps {
+		if rollup >= minStep {
+			return rollup
+		}
+	}
+	day := 24 * time.Hour
+	return (minStep + day - 1) / day * day
+}
+
+// rollupSeries averages one metric of time-ordered entries into buckets of
+// step, each stamped with its start. Buckets of steps that divide a day start
+// at multiples of the step from midnight UTC.
+func rollupSeries(entries []*collector.Telemetry, metric string, step time.Duration) GrafanaTimeSeries {
+	datapoints := [][2]float64{}
+	var bucket time.Time
+	var sum float64
+	var count int
+
+	flush := func() {
+		if count > 0 {
+			datapoints = append(datapoints, [2]float64{sum / float64(count), float64(bucket.UnixMilli())})
+		}
+	}
+	for _, telemetry := range entries {
+		value, ok := telemetry.Metrics[metric]
+		if !ok {
+			continue
+		}
+		start := telemetry.Timestamp.Truncate(step)
+		if !start.Equal(bucket) {
+			flush()
+			bucket, sum, count = start, 0, 0
+		}
+		sum += value
+		count++
+	}
+	flush()
+
+	return GrafanaTimeSeries{Datapoints: datapoints, Resolution: step.String()}
+}
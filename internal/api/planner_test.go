@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPlanQuery(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		window    time.Duration
+		step      time.Duration
+		maxPoints int
+		want      time.Duration
+	}{
+		{"within limit", time.Hour, 15 * time.Second, 1000, 0},
+		{"exactly at limit", 1000 * time.Second, time.Second, 1000, 0},
+		{"no step", time.Hour, 0, 10, 0},
+		{"no limit", time.Hour, time.Second, 0, 0},
+		{"empty window", 0, time.Second, 10, 0},
+		{"one over", 1001 * time.Second, time.Second, 1000, 5 * time.Second},
+		{"day at 1s", 24 * time.Hour, time.Second, 1000, 5 * time.Minute},
+		{"week at 1m", 7 * 24 * time.Hour, time.Minute, 1000, 30 * time.Minute},
+		{"years", 3 * 365 * 24 * time.Hour, time.Minute, 100, 11 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		plan := planQuery(from, from.Add(tt.window), tt.step, tt.maxPoints)
+		if plan.Step != tt.want {
+			t.Errorf("%s: expected step %v, got %v", tt.name, tt.want, plan.Step)
+		}
+		if tt.window > 0 && plan.Step > 0 && int(tt.window/plan.Step) > tt.maxPoints {
+			t.Errorf("%s: step %v exceeds %d points", tt.name, plan.Step, tt.maxPoints)
+		}
+	}
+
+	if got := planQuery(time.Time{}, from, time.Second, 10); got.resolution() != "raw" {
+		t.Errorf("Expected an open range to be served raw, got %s", got.resolution())
+	}
+}
+
+func TestGrafanaQuery_Rollup(t *testing.T) {
+	router := newGrafanaTestRouter(t)
+
+	// Ten minutes at a 1s step is 600 points; four allowed means 5m rollups
+	body := `{
+		"range": {"from": "2024-01-01T12:00:00Z", "to": "2024-01-01T12:10:00Z"},
+		"intervalMs": 1000,
+		"maxDataPoints": 4,
+		"targets": [{"refId": "A", "target": "gpu_0:utilization"}]
+	}`
+
+	var series []GrafanaTimeSeries
+	rr := postGrafana(t, router, "/grafana/query", body, &series)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(series) != 1 || series[0].Resolution != "5m0s" {
+		t.Fatalf("Expected one series at 5m0s resolution, got %+v", series)
+	}
+
+	// Samples 0-4 and 5-9 average to 2 and 7
+	want := [][2]float64{{2, 1704110400000}, {7, 1704110700000}}
+	if len(series[0].Datapoints) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, series[0].Datapoints)
+	}
+	for i, dp := range series[0].Datapoints {
+		if dp != want[i] {
+			t.Errorf("Datapoint %d = %v, want %v", i, dp, want[i])
+		}
+	}
+
+	// A step that fits is served raw
+	body = `{
+		"range": {"from": "2024-01-01T12:00:00Z", "to": "2024-01-01T12:10:00Z"},
+		"intervalMs": 60000,
+		"targets": [{"refId": "A", "target": "gpu_0:utilization"}]
+	}`
+	series = nil
+	postGrafana(t, router, "/grafana/query", body, &series)
+	if len(series) != 1 || series[0].Resolution != "raw" || len(series[0].Datapoints) != 10 {
+		t.Errorf("Expected ten raw datapoints, got %+v", series)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+// redMetrics holds the rate, errors and duration (RED) metrics recorded for
+// every API request. Names follow the OpenTelemetry HTTP server semantic
+// conventions as exported to Prometheus.
+type redMetrics struct {
+	requests *metrics.CounterVec
+	errors   *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+func newREDMetrics(registry *metrics.Registry) *redMetrics {
+	return &redMetrics{
+		requests: registry.NewCounterVec("http_server_requests_total",
+			"API requests served, by route and status class.",
+			"http_request_method", "http_route", "http_response_status_class"),
+		errors: registry.NewCounterVec("http_server_request_errors_total",
+			"API requests that failed with a 5xx status, by route.",
+			"http_request_method", "http_route", "http_response_status_class"),
+		duration: registry.NewHistogramVec("http_server_request_duration_seconds",
+			"Duration of API requests, by route and status class.", metrics.DefBuckets,
+			"http_request_method", "http_route", "http_response_status_class"),
+	}
+}
+
+// redMiddleware records request count, server errors and duration for each
+// request, labelled by method, route template and status class
+func (s *Server) redMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapper, r)
+
+		labels := []string{r.Method, routeTemplate(r), statusClass(wrapper.statusCode)}
+		s.red.requests.WithLabelValues(labels...).Inc()
+		if wrapper.statusCode >= http.StatusInternalServerError {
+			s.red.errors.WithLabelValues(labels...).Inc()
+		}
+		s.red.duration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeTemplate returns the mux path template matched by r, such as
+// /api/v1/gpus/{id}/telemetry, falling back to the raw path. Using the
+// template keeps metric and log cardinality bounded.
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// statusClass buckets an HTTP status code into 1xx..5xx
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dxx", code/100)
+}
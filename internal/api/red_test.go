@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestREDMiddleware(t *testing.T) {
+	server := NewServer(createTestCollector(), ServerConfig{Port: "8095"})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch mux.Vars(r)["id"] {
+		case "missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}).Methods("GET")
+	router.Handle("/metrics", server.metrics.Handler()).Methods("GET")
+	router.Use(server.redMiddleware)
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/missing", "/items/broken"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	labels := func(class string) []string { return []string{"GET", "/items/{id}", class} }
+
+	if got := server.red.requests.WithLabelValues(labels("2xx")...).Value(); got != 2 {
+		t.Errorf("Expected 2 2xx requests, got %v", got)
+	}
+	if got := server.red.requests.WithLabelValues(labels("4xx")...).Value(); got != 1 {
+		t.Errorf("Expected 1 4xx request, got %v", got)
+	}
+	if got := server.red.errors.WithLabelValues(labels("4xx")...).Value(); got != 0 {
+		t.Errorf("Expected 4xx not to count as errors, got %v", got)
+	}
+	if got := server.red.errors.WithLabelValues(labels("5xx")...).Value(); got != 1 {
+		t.Errorf("Expected 1 5xx error, got %v", got)
+	}
+	if got := server.red.duration.WithLabelValues(labels("2xx")...).Count(); got != 2 {
+		t.Errorf("Expected 2 duration observations, got %d", got)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`http_server_requests_total{http_request_method="GET",http_route="/items/{id}",http_response_status_class="2xx"} 2`,
+		`http_server_request_errors_total{http_request_method="GET",http_route="/items/{id}",http_response_status_class="5xx"} 1`,
+		`http_server_request_duration_seconds_count{http_request_method="GET",http_route="/items/{id}",http_response_status_class="4xx"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected /metrics to contain %q", want)
+		}
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for code, want := range map[int]string{200: "2xx", 204: "2xx", 304: "3xx", 404: "4xx", 503: "5xx", 0: "unknown", 999: "unknown"} {
+		if got := statusClass(code); got != want {
+			t.Errorf("statusClass(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
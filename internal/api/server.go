@@ -3,14 +3,20 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/harishb93/telemetry-pipeline/internal/collector"
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
 )
 
 // Server represents the HTTP API server
@@ -18,59 +24,230 @@ type Server struct {
 	collector  *collector.Collector
 	httpServer *http.Server
 	port       string
+	basePath   string
+	logger     *logger.Logger
+
+	slowQueryThreshold time.Duration
+	maxResultItems     int
+	maxSeriesPoints    int
+	enableUI           bool
+	metrics            *metrics.Registry
+	red                *redMetrics
+	slowQueries        *metrics.CounterVec
+	usage              *usageTracker
+	tlsCertFile        string
+	tlsKeyFile         string
+	hstsMaxAge         time.Duration
+	accessLog          *accessLogSampler
+	tracerProvider     trace.TracerProvider
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
+	// Port is a port, host:port address or unix: socket path to listen on
 	Port string
+	// BasePath serves every route under this prefix (e.g. /telemetry), for
+	// deployment behind a reverse proxy that does not strip it
+	BasePath string
+	// SlowQueryThreshold is the duration above which requests are logged and
+	// counted as slow; zero disables slow query logging
+	SlowQueryThreshold time.Duration
+	// MaxResultItems caps the number of items in a single streamed response;
+	// zero uses DefaultMaxResultItems
+	MaxResultItems int
+	// MaxSeriesPoints is the number of points per series above which series
+	// queries are served from rollups; zero uses DefaultMaxSeriesPoints
+	MaxSeriesPoints int
+	// EnableUI serves the built-in dashboard at the root of the base path
+	EnableUI bool
+	// UsageKeyHeader is the request header whose value usage is accounted
+	// to; empty uses DefaultUsageKeyHeader
+	UsageKeyHeader string
+	// UsageExportDir keeps each month's usage report, written when the month
+	// ends and on shutdown; empty keeps usage in memory only
+	UsageExportDir string
+	// TLSCertFile and TLSKeyFile serve HTTPS with this certificate and key;
+	// both empty serves plain HTTP
+	TLSCertFile string
+	TLSKeyFile  string
+	// HSTSMaxAge is the Strict-Transport-Security max-age sent on HTTPS
+	// responses; zero omits the header
+	HSTSMaxAge time.Duration
+	// AccessLogSuccessRate and AccessLogErrorRate are the fractions of
+	// requests below and from status 400 on written to the access log, e.g.
+	// 0.01 and 1 to log every error but one success in a hundred; zero logs
+	// every request, a negative rate none
+	AccessLogSuccessRate float64
+	AccessLogErrorRate   float64
+	// TracerProvider starts a span for every request, to which slow query
+	// and upstream call events are added; nil disables tracing
+	TracerProvider trace.TracerProvider
 }
 
 // NewServer creates a new API server instance
 func NewServer(collector *collector.Collector, config ServerConfig) *Server {
+	registry := metrics.NewRegistry()
+	serverLogger := logger.NewFromEnv().WithComponent("api")
+
+	usage, err := newUsageTracker(config.UsageKeyHeader, config.UsageExportDir)
+	if err != nil {
+		serverLogger.Warn("Failed to resume monthly usage", "error", err)
+	}
+
 	return &Server{
-		collector: collector,
-		port:      config.Port,
+		collector:          collector,
+		port:               config.Port,
+		basePath:           NormalizeBasePath(config.BasePath),
+		logger:             serverLogger,
+		slowQueryThreshold: config.SlowQueryThreshold,
+		maxResultItems:     config.MaxResultItems,
+		maxSeriesPoints:    config.MaxSeriesPoints,
+		enableUI:           config.EnableUI,
+		metrics:            registry,
+		red:                newREDMetrics(registry),
+		slowQueries: registry.NewCounterVec("api_slow_requests_total",
+			"API requests that exceeded the slow query threshold.", "route"),
+		usage:          usage,
+		tlsCertFile:    config.TLSCertFile,
+		tlsKeyFile:     config.TLSKeyFile,
+		hstsMaxAge:     config.HSTSMaxAge,
+		accessLog:      newAccessLogSampler(registry, config.AccessLogSuccessRate, config.AccessLogErrorRate),
+		tracerProvider: config.TracerProvider,
 	}
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server, serving HTTPS if a certificate is configured
 func (s *Server) Start() error {
+	if err := checkTLSFiles(s.tlsCertFile, s.tlsKeyFile); err != nil {
+		return err
+	}
+
+	s.httpServer = &http.Server{
+		Addr:         netutil.ListenAddress(s.port),
+		Handler:      s.handler(),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+		TLSConfig:    tlsConfig,
+	}
+
+	listener, err := netutil.Listen(s.port)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.port, err)
+	}
+
+	log.Printf("API server starting on %s", s.httpServer.Addr)
+	log.Printf("Swagger UI available at %s%s/swagger/", s.URL(), s.basePath)
+
+	if s.tlsCertFile != "" {
+		return s.httpServer.ServeTLS(listener, s.tlsCertFile, s.tlsKeyFile)
+	}
+	return s.httpServer.Serve(listener)
+}
+
+// URL returns the base URL the server is reached at, without the base path
+func (s *Server) URL() string {
+	url := netutil.URL(s.port)
+	if s.tlsCertFile != "" {
+		url = strings.Replace(url, "http://", "https://", 1)
+	}
+	return url
+}
+
+// handler builds the router with all routes and middleware
+func (s *Server) handler() http.Handler {
 	router := mux.NewRouter()
 
+	// All routes hang off the base path, if one is configured
+	routes := router
+	if s.basePath != "" {
+		routes = router.PathPrefix(s.basePath).Subrouter()
+	}
+
 	// Create handlers
 	handlers := NewHandlers(s.collector)
+	handlers.usage = s.usage
+	if s.maxResultItems > 0 {
+		handlers.maxResultItems = s.maxResultItems
+	}
+	if s.maxSeriesPoints > 0 {
+		handlers.maxSeriesPoints = s.maxSeriesPoints
+	}
 
 	// API v1 routes
-	v1 := router.PathPrefix("/api/v1").Subrouter()
+	v1 := routes.PathPrefix("/api/v1").Subrouter()
 	v1.HandleFunc("/gpus", handlers.GetGPUs).Methods("GET")
 	v1.HandleFunc("/gpus/{id}/telemetry", handlers.GetTelemetry).Methods("GET")
+	v1.HandleFunc("/gpus/{id}/energy", handlers.GetEnergy).Methods("GET")
+	v1.HandleFunc("/compare", handlers.CompareGPUs).Methods("GET")
+	v1.HandleFunc("/fleet", handlers.GetFleet).Methods("GET")
+	v1.HandleFunc("/metrics", handlers.GetMetricCatalog).Methods("GET")
+	v1.HandleFunc("/capacity", handlers.GetCapacity).Methods("GET")
 	v1.HandleFunc("/hosts", handlers.GetHosts).Methods("GET")
 	v1.HandleFunc("/hosts/{hostname}/gpus", handlers.GetHostGPUs).Methods("GET")
+	v1.HandleFunc("/silences", handlers.GetSilences).Methods("GET")
+	v1.HandleFunc("/silences", handlers.CreateSilence).Methods("POST")
+	v1.HandleFunc("/silences/{id}", handlers.ExpireSilence).Methods("DELETE")
+
+	// Grafana SimpleJSON datasource; Grafana tests the datasource URL with a
+	// GET of its root
+	v1.HandleFunc("/grafana", handlers.GrafanaTestConnection).Methods("GET")
+	v1.HandleFunc("/grafana/", handlers.GrafanaTestConnection).Methods("GET")
+	v1.HandleFunc("/grafana/search", handlers.GrafanaSearch).Methods("POST")
+	v1.HandleFunc("/grafana/query", handlers.GrafanaQuery).Methods("POST")
 
 	// Health endpoint
-	router.HandleFunc("/health", handlers.Health).Methods("GET")
+	routes.HandleFunc("/health", handlers.Health).Methods("GET")
+
+	// Usage per API key
+	routes.HandleFunc("/admin/usage", handlers.GetUsage).Methods("GET")
+
+	// Prometheus metrics endpoint
+	routes.Handle("/metrics", s.metrics.Handler()).Methods("GET")
 
-	// Swagger documentation endpoint
-	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	// Swagger documentation endpoint; the UI loads doc.json relative to its
+	// own URL, so it works under any prefix
+	routes.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+
+	// Built-in dashboard
+	if s.enableUI {
+		s.registerUI(router, routes)
+	}
+
+	// Span naming middleware
+	router.Use(s.spanRouteMiddleware)
 
 	// CORS middleware
 	router.Use(s.corsMiddleware)
 
+	// Request-scoped logger middleware
+	router.Use(s.requestLoggerMiddleware)
+
 	// Request logging middleware
 	router.Use(s.loggingMiddleware)
 
-	s.httpServer = &http.Server{
-		Addr:         ":" + s.port,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
+	// Request rate, error and duration metrics middleware
+	router.Use(s.redMiddleware)
 
-	log.Printf("API server starting on port %s", s.port)
-	log.Printf("Swagger UI available at http://localhost:%s/swagger/", s.port)
+	// Slow query logging middleware
+	router.Use(s.slowQueryMiddleware)
 
-	return s.httpServer.ListenAndServe()
+	// Usage accounting middleware
+	router.Use(s.usageMiddleware)
+
+	// Spans, trace IDs and security headers wrap the router so that
+	// unmatched routes get them too
+	return s.tracingMiddleware(s.securityHeadersMiddleware(s.traceIDMiddleware(router)))
+}
+
+// NormalizeBasePath returns path with a leading slash and no trailing slash,
+// or "" for an empty or root path
+func NormalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
 }
 
 // Stop gracefully stops the HTTP server
@@ -83,7 +260,11 @@ func (s *Server) Stop() error {
 	defer cancel()
 
 	log.Printf("API server stopping...")
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	if flushErr := s.usage.flush(); flushErr != nil {
+		s.logger.Warn("Failed to save monthly usage", "error", flushErr)
+	}
+	return err
 }
 
 // corsMiddleware adds CORS headers
@@ -91,7 +272,8 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-None-Match, If-Modified-Since, X-Trace-ID, X-Request-ID, X-Tenant-ID, "+s.usage.header)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Trace-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -102,7 +284,28 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// loggingMiddleware logs HTTP requests
+// requestLoggerMiddleware stores a logger carrying the request's trace ID,
+// matched route and tenant in the request context, for handlers to log with
+// logger.FromContext
+func (s *Server) requestLoggerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields := []interface{}{"request_id", TraceIDFromContext(r.Context())}
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				fields = append(fields, "route", template)
+			}
+		}
+		ctx := r.Context()
+		if tenant := r.Header.Get(TenantHeader); validTraceID(tenant) {
+			fields = append(fields, "tenant", tenant)
+			ctx = context.WithValue(ctx, tenantKey{}, tenant)
+		}
+		ctx = logger.NewContext(ctx, s.logger.With(fields...))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// loggingMiddleware logs HTTP requests, sampled by their status
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -113,17 +316,36 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(wrapper, r)
 
 		duration := time.Since(start)
-		log.Printf("%s %s %d %v", r.Method, r.URL.Path, wrapper.statusCode, duration)
+		traceID := TraceIDFromContext(r.Context())
+		if !s.accessLog.sample(wrapper.statusCode, traceID) {
+			return
+		}
+		log.Printf("%s %s %d %v trace_id=%s", r.Method, r.URL.Path, wrapper.statusCode, duration, traceID)
 	})
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// responseWriter wraps http.ResponseWriter to capture status code and the
+// number of body bytes written
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Flush passes flushes through so streamed responses are not held back
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
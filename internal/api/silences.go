@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+)
+
+// Silences live in the collector, which evaluates GPU health alerts; the
+// gateway proxies the collector's /api/v1/silences endpoints.
+
+// GetSilences lists alert silences
+// @Summary List silences
+// @Description Returns pending and active silences, newest first. Expired silences are kept as an audit trail and included with all=true.
+// @Tags Silences
+// @Accept json
+// @Produce json
+// @Param all query bool false "Include expired silences"
+// @Success 200 {array} collector.Silence
+// @Failure 500 {object} ErrorResponse
+// @Router /silences [get]
+func (h *Handlers) GetSilences(w http.ResponseWriter, r *http.Request) {
+	endpoint := "/api/v1/silences"
+	if r.URL.Query().Get("all") == "true" {
+		endpoint += "?all=true"
+	}
+
+	var silences []collector.Silence
+	h.proxySilences(w, r, http.MethodGet, endpoint, nil, &silences)
+}
+
+// CreateSilence creates an alert silence
+// @Summary Create a silence
+// @Description Suppresses GPU health alerts whose labels (gpu_id, hostname, state) match every matcher between starts_at and ends_at, e.g. for planned maintenance. Matcher values may be glob patterns. created_by is required and recorded for audit; starts_at defaults to now.
+// @Tags Silences
+// @Accept json
+// @Produce json
+// @Param silence body collector.Silence true "Silence to create"
+// @Success 201 {object} collector.Silence
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /silences [post]
+func (h *Handlers) CreateSilence(w http.ResponseWriter, r *http.Request) {
+	var silence collector.Silence
+	if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidSilence, "Invalid silence", fmt.Sprintf("failed to decode request body: %v", err))
+		return
+	}
+	body, err := json.Marshal(silence)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.Internal, "Failed to create silence", err.Error())
+		return
+	}
+
+	var created collector.Silence
+	h.proxySilences(w, r, http.MethodPost, "/api/v1/silences", body, &created)
+}
+
+// ExpireSilence ends a silence early
+// @Summary Expire a silence
+// @Description Ends a pending or active silence now. The silence is kept, with expired_by recording who ended it.
+// @Tags Silences
+// @Accept json
+// @Produce json
+// @Param id path string true "Silence ID"
+// @Param expired_by query string false "Who is expiring the silence"
+// @Success 200 {object} collector.Silence
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /silences/{id} [delete]
+func (h *Handlers) ExpireSilence(w http.ResponseWriter, r *http.Request) {
+	endpoint := "/api/v1/silences/" + url.PathEscape(mux.Vars(r)["id"])
+	if by := r.URL.Query().Get("expired_by"); by != "" {
+		endpoint += "?" + url.Values{"expired_by": {by}}.Encode()
+	}
+
+	var expired collector.Silence
+	h.proxySilences(w, r, http.MethodDelete, endpoint, nil, &expired)
+}
+
+// proxySilences forwards a silence request to the collector, decoding a
+// successful response into v and relaying it with the collector's status.
+// Client errors from the collector are reported with its code and message.
+func (h *Handlers) proxySilences(w http.ResponseWriter, r *http.Request, method, endpoint string, body []byte, v interface{}) {
+	var header http.Header
+	var reader io.Reader
+	if body != nil {
+		header = http.Header{"Content-Type": {"application/json"}}
+		reader = bytes.NewReader(body)
+	}
+
+	resp, err := h.collectorDo(r.Context(), method, endpoint, header, reader)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorUnavailable, "Failed to reach collector", err.Error())
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		code, message := apierror.Read(resp.Body, resp.StatusCode)
+		h.writeErrorResponse(w, resp.StatusCode, code, "Invalid silence", message)
+		return
+	case resp.StatusCode == http.StatusNotFound:
+		code, message := apierror.Read(resp.Body, resp.StatusCode)
+		h.writeErrorResponse(w, resp.StatusCode, code, "Silence not found", message)
+		return
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Collector request failed",
+			fmt.Sprintf("collector silences endpoint returned status %d", resp.StatusCode))
+		return
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		h.writeErrorResponse(w, http.StatusInternalServerError, apierror.CollectorError, "Collector request failed",
+			fmt.Sprintf("failed to decode collector silences response: %v", err))
+		return
+	}
+	h.writeJSONResponse(w, resp.StatusCode, v)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+)
+
+func TestSilencesProxy(t *testing.T) {
+	var upstreamRequests []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		upstreamRequests = append(upstreamRequests, r.Method+" "+r.URL.RequestURI())
+
+		switch {
+		case r.Method == http.MethodPost && !strings.Contains(string(body), `"created_by":"alice"`):
+			http.Error(w, "invalid silence: created_by is required", http.StatusBadRequest)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"abc123","matchers":{"hostname":"host-a"},"created_by":"alice","state":"active"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/silences/missing":
+			apierror.Write(w, http.StatusNotFound, apierror.SilenceNotFound, "silence not found")
+		case r.Method == http.MethodDelete:
+			_, _ = w.Write([]byte(`{"id":"abc123","created_by":"alice","expired_by":"bob","state":"expired"}`))
+		default:
+			_, _ = w.Write([]byte(`[{"id":"abc123","created_by":"alice","state":"active"}]`))
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/silences", handlers.GetSilences).Methods("GET")
+	router.HandleFunc("/api/v1/silences", handlers.CreateSilence).Methods("POST")
+	router.HandleFunc("/api/v1/silences/{id}", handlers.ExpireSilence).Methods("DELETE")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/silences",
+		strings.NewReader(`{"matchers":{"hostname":"host-a"},"ends_at":"2030-01-01T00:00:00Z","created_by":"alice"}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created collector.Silence
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != "abc123" || created.CreatedBy != "alice" {
+		t.Errorf("Unexpected silence %+v", created)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/silences", strings.NewReader(`{"matchers":{"hostname":"host-a"}}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+	var errResp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil || !strings.Contains(errResp.Message, "created_by is required") {
+		t.Errorf("Expected collector validation error in message, got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/silences/abc123?expired_by=bob", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/silences/missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rr.Code)
+	}
+	// The collector's error code is passed through
+	if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil || errResp.Reason != apierror.SilenceNotFound.Name {
+		t.Errorf("Expected the collector's error code, got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/silences?all=true", nil))
+	var silences []collector.Silence
+	if err := json.Unmarshal(rr.Body.Bytes(), &silences); err != nil || len(silences) != 1 {
+		t.Errorf("Unexpected silences %s", rr.Body.String())
+	}
+
+	if upstreamRequests[2] != "DELETE /api/v1/silences/abc123?expired_by=bob" || upstreamRequests[4] != "GET /api/v1/silences?all=true" {
+		t.Errorf("Unexpected upstream requests %v", upstreamRequests)
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultSlowQueryThreshold is the request duration above which API requests
+// are logged as slow
+const DefaultSlowQueryThreshold = time.Second
+
+// upstreamCall records a single request made to the collector while serving
+// an API request
+type upstreamCall struct {
+	Endpoint string
+	Status   int
+	Duration time.Duration
+	Err      error
+}
+
+func (c upstreamCall) String() string {
+	if c.Err != nil {
+		return fmt.Sprintf("%s error=%v %s", c.Endpoint, c.Err, c.Duration)
+	}
+	return fmt.Sprintf("%s %d %s", c.Endpoint, c.Status, c.Duration)
+}
+
+// queryTrace collects upstream timings for one API request
+type queryTrace struct {
+	mu       sync.Mutex
+	upstream []upstreamCall
+}
+
+type queryTraceKey struct{}
+
+// withQueryTrace attaches a new trace to ctx
+func withQueryTrace(ctx context.Context) (context.Context, *queryTrace) {
+	qt := &queryTrace{}
+	return context.WithValue(ctx, queryTraceKey{}, qt), qt
+}
+
+// recordUpstream adds call to the trace in ctx, if any, and to the current span
+func recordUpstream(ctx context.Context, call upstreamCall) {
+	if qt, ok := ctx.Value(queryTraceKey{}).(*queryTrace); ok {
+		qt.mu.Lock()
+		qt.upstream = append(qt.upstream, call)
+		qt.mu.Unlock()
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("collector.endpoint", call.Endpoint),
+		attribute.Int("http.response.status_code", call.Status),
+		attribute.Float64("duration_ms", durationMillis(call.Duration)),
+	}
+	if call.Err != nil {
+		attrs = append(attrs, attribute.String("error", call.Err.Error()))
+	}
+	trace.SpanFromContext(ctx).AddEvent("collector.request", trace.WithAttributes(attrs...))
+}
+
+// calls returns a copy of the recorded upstream calls and their total duration
+func (qt *queryTrace) calls() ([]upstreamCall, time.Duration) {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+
+	var total time.Duration
+	for _, call := range qt.upstream {
+		total += call.Duration
+	}
+	return append([]upstreamCall(nil), qt.upstream...), total
+}
+
+// slowQueryMiddleware logs and counts requests that take longer than the
+// configured threshold, including their parameters and collector timings, and
+// adds a slow_query event to the active trace span
+func (s *Server) slowQueryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.slowQueryThreshold <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		ctx, qt := withQueryTrace(r.Context())
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapper, r.WithContext(ctx))
+
+		duration := time.Since(start)
+		if duration < s.slowQueryThreshold {
+			return
+		}
+
+		route := routeTemplate(r)
+		s.slowQueries.WithLabelValues(route).Inc()
+
+		calls, upstreamTotal := qt.calls()
+		upstream := make([]string, len(calls))
+		for i, call := range calls {
+			upstream[i] = call.String()
+		}
+
+		s.logger.WarnContext(ctx, "Slow API request",
+			"trace_id", TraceIDFromContext(ctx),
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"path_params", mux.Vars(r),
+			"query", r.URL.RawQuery,
+			"status", wrapper.statusCode,
+			"duration_ms", durationMillis(duration),
+			"threshold_ms", durationMillis(s.slowQueryThreshold),
+			"upstream_calls", len(calls),
+			"upstream_ms", durationMillis(upstreamTotal),
+			"upstream", strings.Join(upstream, "; "))
+
+		trace.SpanFromContext(ctx).AddEvent("slow_query", trace.WithAttributes(
+			attribute.String("http.route", route),
+			attribute.String("url.query", r.URL.RawQuery),
+			attribute.Int("http.response.status_code", wrapper.statusCode),
+			attribute.Float64("duration_ms", durationMillis(duration)),
+			attribute.Float64("upstream_ms", durationMillis(upstreamTotal)),
+			attribute.StringSlice("upstream", upstream),
+		))
+	})
+}
+
+// durationMillis converts d to fractional milliseconds for logs and spans
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
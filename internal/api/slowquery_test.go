@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+)
+
+// recordingSpan captures span events added by the middleware
+type recordingSpan struct {
+	noop.Span
+	events []string
+}
+
+func (s *recordingSpan) AddEvent(name string, _ ...trace.EventOption) {
+	s.events = append(s.events, name)
+}
+
+// newSlowQueryTestRouter serves GetHosts against a fake collector that takes
+// upstreamDelay to respond
+func newSlowQueryTestRouter(t *testing.T, threshold, upstreamDelay time.Duration) (*Server, *bytes.Buffer, *mux.Router) {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(upstreamDelay)
+		_ = json.NewEncoder(w).Encode(map[string][]string{"hosts": {"host-a"}})
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	server := NewServer(createTestCollector(), ServerConfig{Port: "8094", SlowQueryThreshold: threshold})
+	var logs bytes.Buffer
+	server.logger = logger.New(logger.Config{Level: logger.INFO, Format: "json", Output: &logs})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/hosts", NewHandlers(server.collector).GetHosts).Methods("GET")
+	router.Use(server.slowQueryMiddleware)
+
+	return server, &logs, router
+}
+
+func TestSlowQueryMiddleware_LogsSlowRequests(t *testing.T) {
+	server, logs, router := newSlowQueryTestRouter(t, 10*time.Millisecond, 30*time.Millisecond)
+
+	span := &recordingSpan{}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/hosts?limit=5&offset=10", nil)
+	req = req.WithContext(trace.ContextWithSpan(req.Context(), span))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON log entry, got %q: %v", logs.String(), err)
+	}
+	if entry["msg"] != "Slow API request" {
+		t.Errorf("Unexpected log message: %v", entry["msg"])
+	}
+	if entry["route"] != "/api/v1/hosts" || entry["query"] != "limit=5&offset=10" {
+		t.Errorf("Expected route and query parameters in log, got %v", entry)
+	}
+	if calls, _ := entry["upstream_calls"].(float64); calls != 1 {
+		t.Errorf("Expected 1 upstream call, got %v", entry["upstream_calls"])
+	}
+	if upstreamMs, _ := entry["upstream_ms"].(float64); upstreamMs < 30 {
+		t.Errorf("Expected upstream time >= 30ms, got %v", entry["upstream_ms"])
+	}
+	if upstream, _ := entry["upstream"].(string); !strings.HasPrefix(upstream, "GET /api/v1/hosts 200 ") {
+		t.Errorf("Unexpected upstream timings: %q", upstream)
+	}
+
+	if got := server.slowQueries.WithLabelValues("/api/v1/hosts").Value(); got != 1 {
+		t.Errorf("Expected slow query counter 1, got %v", got)
+	}
+
+	if strings.Join(span.events, ",") != "collector.request,slow_query" {
+		t.Errorf("Unexpected span events: %v", span.events)
+	}
+}
+
+func TestSlowQueryMiddleware_IgnoresFastRequests(t *testing.T) {
+	server, logs, router := newSlowQueryTestRouter(t, time.Minute, 0)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/hosts", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no log output, got %q", logs.String())
+	}
+	if got := server.slowQueries.WithLabelValues("/api/v1/hosts").Value(); got != 0 {
+		t.Errorf("Expected slow query counter 0, got %v", got)
+	}
+}
+
+func TestSlowQueryMiddleware_Disabled(t *testing.T) {
+	_, logs, router := newSlowQueryTestRouter(t, 0, 5*time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/hosts", nil))
+
+	if logs.Len() != 0 {
+		t.Errorf("Expected slow query logging to be disabled, got %q", logs.String())
+	}
+}
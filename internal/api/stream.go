@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+)
+
+// DefaultMaxResultItems is the hard cap on items in a single streamed
+// response, regardless of the requested limit
+const DefaultMaxResultItems = 1000
+
+// streamFlushInterval is how many array items are written between flushes
+const streamFlushInterval = 100
+
+// jsonStream writes a JSON object to an HTTP response incrementally, so large
+// arrays are encoded item by item instead of being buffered as a whole.
+// Content-Length is never set; the response is sent chunked.
+type jsonStream struct {
+	buf     *bufio.Writer
+	flusher http.Flusher
+	fields  int
+	items   int
+	err     error
+}
+
+// newJSONStream writes the response header and opens the top-level object
+func newJSONStream(w http.ResponseWriter, statusCode int) *jsonStream {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(statusCode)
+
+	s := &jsonStream{buf: bufio.NewWriterSize(w, 32*1024)}
+	s.flusher, _ = w.(http.Flusher)
+	s.write([]byte{'{'})
+	return s
+}
+
+// Field writes a complete "name": value member
+func (s *jsonStream) Field(name string, value interface{}) {
+	s.key(name)
+	s.value(value)
+}
+
+// BeginArray opens a "name": [ member; follow with Item calls and EndArray
+func (s *jsonStream) BeginArray(name string) {
+	s.key(name)
+	s.write([]byte{'['})
+	s.items = 0
+}
+
+// Item appends one element to the open array
+func (s *jsonStream) Item(value interface{}) {
+	if s.items > 0 {
+		s.write([]byte{','})
+	}
+	s.value(value)
+	s.items++
+
+	if s.items%streamFlushInterval == 0 {
+		s.flush()
+	}
+}
+
+// EndArray closes the open array
+func (s *jsonStream) EndArray() {
+	s.write([]byte{']'})
+}
+
+// Close terminates the object and flushes it, returning the first write error
+func (s *jsonStream) Close() error {
+	s.write([]byte("}\n"))
+	s.flush()
+	return s.err
+}
+
+func (s *jsonStream) key(name string) {
+	if s.fields > 0 {
+		s.write([]byte{','})
+	}
+	s.fields++
+	s.value(name)
+	s.write([]byte{':'})
+}
+
+func (s *jsonStream) value(value interface{}) {
+	if s.err != nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.write(data)
+}
+
+func (s *jsonStream) write(p []byte) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.buf.Write(p)
+}
+
+func (s *jsonStream) flush() {
+	if s.err != nil {
+		return
+	}
+	if s.err = s.buf.Flush(); s.err == nil && s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
+// decodeTelemetryStream decodes the "data" array of a collector telemetry
+// response one entry at a time, calling fn for each, without holding the
+// whole array in memory. Other members are skipped.
+func decodeTelemetryStream(r io.Reader, fn func(*collector.Telemetry)) error {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+
+		if key != "data" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		token, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			continue // "data": null
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return fmt.Errorf("expected data array, got %v", token)
+		}
+
+		for dec.More() {
+			var telemetry collector.Telemetry
+			if err := dec.Decode(&telemetry); err != nil {
+				return err
+			}
+			fn(&telemetry)
+		}
+
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, token)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/harishb93/telemetry-pipeline/internal/collector"
+)
+
+// newStreamTestRouter serves GetTelemetry against a fake collector returning
+// the given raw body
+func newStreamTestRouter(t *testing.T, body string, maxResultItems int) *mux.Router {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	handlers.maxResultItems = maxResultItems
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/gpus/{id}/telemetry", handlers.GetTelemetry).Methods("GET")
+	return router
+}
+
+// collectorTelemetryBody builds a collector telemetry response with n entries
+// one minute apart
+func collectorTelemetryBody(t *testing.T, n int) string {
+	t.Helper()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := make([]*collector.Telemetry, n)
+	for i := range data {
+		data[i] = &collector.Telemetry{
+			GPUId:     "gpu_0",
+			Metrics:   map[string]float64{"utilization": float64(i)},
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"gpu_id": "gpu_0", "total": n, "data": data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func getStreamedTelemetry(t *testing.T, router *mux.Router, query string) (*httptest.ResponseRecorder, TelemetryResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/api/v1/gpus/gpu_0/telemetry"+query, nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var response TelemetryResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response %q: %v", rr.Body.String(), err)
+	}
+	return rr, response
+}
+
+func TestGetTelemetry_StreamsPage(t *testing.T) {
+	router := newStreamTestRouter(t, collectorTelemetryBody(t, 10), DefaultMaxResultItems)
+
+	rr, response := getStreamedTelemetry(t, router, "?limit=3&offset=2")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if rr.Header().Get("Content-Length") != "" {
+		t.Errorf("Expected no Content-Length, got %q", rr.Header().Get("Content-Length"))
+	}
+	if !rr.Flushed {
+		t.Error("Expected streamed response to be flushed")
+	}
+	if len(response.Data) != 3 || response.Data[0].Metrics["utilization"] != 2 {
+		t.Fatalf("Expected entries 2-4, got %+v", response.Data)
+	}
+	if entry := response.Data[0]; entry.GPUID != "gpu_0" || !entry.Timestamp.Equal(time.Date(2024, 1, 1, 12, 2, 0, 0, time.UTC)) {
+		t.Errorf("Expected typed entries with gpu_id and timestamp, got %+v", entry)
+	}
+	if response.Total != 10 {
+		t.Errorf("Expected total 10, got %d", response.Total)
+	}
+	if response.Truncated {
+		t.Error("Expected truncated to be false")
+	}
+	if !response.Pagination.HasNext {
+		t.Error("Expected has_next to be true")
+	}
+}
+
+func TestGetTelemetry_TimeRangeFilter(t *testing.T) {
+	router := newStreamTestRouter(t, collectorTelemetryBody(t, 10), DefaultMaxResultItems)
+
+	_, response := getStreamedTelemetry(t, router,
+		"?start_time=2024-01-01T12:03:00Z&end_time=2024-01-01T12:05:00Z")
+
+	if response.Total != 3 || len(response.Data) != 3 {
+		t.Fatalf("Expected 3 entries in range, got total=%d data=%d", response.Total, len(response.Data))
+	}
+	if response.Pagination.HasNext {
+		t.Error("Expected has_next to be false")
+	}
+}
+
+func TestGetTelemetry_ReportsTiers(t *testing.T) {
+	body := collectorTelemetryBody(t, 3)
+	var upstreamQuery string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(collector.TiersHeader, "cold,warm")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handlers := NewHandlers(createTestCollector())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/gpus/{id}/telemetry", handlers.GetTelemetry).Methods("GET")
+
+	_, response := getStreamedTelemetry(t, router, "?start_time=2024-01-01T12:01:00Z")
+
+	if upstreamQuery != "start_time=2024-01-01T12%3A01%3A00Z" {
+		t.Errorf("Expected start_time forwarded to collector, got %q", upstreamQuery)
+	}
+	if len(response.Tiers) != 2 || response.Tiers[0] != "cold" || response.Tiers[1] != "warm" {
+		t.Errorf("Expected tiers cold and warm, got %v", response.Tiers)
+	}
+}
+
+func TestGetTelemetry_TruncatesAtMaxResultItems(t *testing.T) {
+	router := newStreamTestRouter(t, collectorTelemetryBody(t, 20), 5)
+
+	_, response := getStreamedTelemetry(t, router, "?limit=15")
+
+	if len(response.Data) != 5 {
+		t.Fatalf("Expected 5 entries, got %d", len(response.Data))
+	}
+	if !response.Truncated {
+		t.Error("Expected truncated to be true")
+	}
+	if response.Total != 20 {
+		t.Errorf("Expected total 20, got %d", response.Total)
+	}
+	if response.Pagination.Limit != 15 || !response.Pagination.HasNext {
+		t.Errorf("Unexpected pagination: %+v", response.Pagination)
+	}
+}
+
+func TestGetTelemetry_EmptyData(t *testing.T) {
+	router := newStreamTestRouter(t, `{"gpu_id":"gpu_0","total":0,"data":null}`, DefaultMaxResultItems)
+
+	rr, response := getStreamedTelemetry(t, router, "")
+
+	if !strings.Contains(rr.Body.String(), `"data":[]`) {
+		t.Errorf("Expected empty data array, got %s", rr.Body.String())
+	}
+	if response.Total != 0 || response.Truncated {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestGetTelemetry_UpstreamDecodeError(t *testing.T) {
+	body := collectorTelemetryBody(t, 3)
+	router := newStreamTestRouter(t, body[:len(body)-20], DefaultMaxResultItems)
+
+	rr, response := getStreamedTelemetry(t, router, "")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 once streaming has started, got %d", rr.Code)
+	}
+	if response.Error == "" {
+		t.Error("Expected error field for truncated upstream response")
+	}
+}
+
+func TestJSONStream(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	stream := newJSONStream(rr, http.StatusAccepted)
+	stream.BeginArray("items")
+	for i := 0; i < streamFlushInterval+1; i++ {
+		stream.Item(i)
+	}
+	stream.EndArray()
+	stream.Field("name", "a\"b")
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json, got %q", ct)
+	}
+
+	var decoded struct {
+		Items []int  `json:"items"`
+		Name  string `json:"name"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", rr.Body.String(), err)
+	}
+	if len(decoded.Items) != streamFlushInterval+1 || decoded.Name != "a\"b" {
+		t.Errorf("Unexpected decoded stream: %+v", decoded)
+	}
+}
+
+func TestDecodeTelemetryStream(t *testing.T) {
+	var count int
+	err := decodeTelemetryStream(strings.NewReader(collectorTelemetryBody(t, 4)), func(*collector.Telemetry) {
+		count++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 entries, got %d", count)
+	}
+
+	for _, body := range []string{`[]`, `{"data":{}}`, `{"data":[1]}`} {
+		if err := decodeTelemetryStream(strings.NewReader(body), func(*collector.Telemetry) {}); err == nil {
+			t.Errorf("Expected error decoding %s", body)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDHeader carries the trace ID of every API response, and is forwarded
+// to the collector on upstream calls
+const TraceIDHeader = "X-Trace-ID"
+
+// requestIDHeader is accepted as the trace ID when a proxy in front of the
+// gateway already assigns one
+const requestIDHeader = "X-Request-ID"
+
+// TenantHeader names the tenant a request is made for, added to the
+// request's logs. It is forwarded to the collector on upstream calls.
+const TenantHeader = "X-Tenant-ID"
+
+// maxTraceIDLength bounds client-supplied IDs so they cannot bloat logs
+const maxTraceIDLength = 64
+
+type traceIDKey struct{}
+
+type tenantKey struct{}
+
+// TraceIDFromContext returns the trace ID of the request ctx belongs to, or ""
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// tenantFromContext returns the tenant of the request ctx belongs to, or ""
+func tenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// traceIDMiddleware assigns every request a trace ID, returns it in the
+// X-Trace-ID response header and adds it to the request context for logs and
+// upstream calls. The ID of the active OpenTelemetry span is used if there is
+// one, then a valid X-Trace-ID or X-Request-ID from the client, and otherwise
+// a new random ID in the same format as an OpenTelemetry trace ID.
+func (s *Server) traceIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestTraceID(r)
+		w.Header().Set(TraceIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), traceIDKey{}, id)))
+	})
+}
+
+// requestTraceID picks the trace ID for r
+func requestTraceID(r *http.Request) string {
+	if span := trace.SpanContextFromContext(r.Context()); span.HasTraceID() {
+		return span.TraceID().String()
+	}
+	for _, header := range []string{TraceIDHeader, requestIDHeader} {
+		if id := r.Header.Get(header); validTraceID(id) {
+			return id
+		}
+	}
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// validTraceID reports whether a client-supplied ID is safe to echo and log:
+// short, and only letters, digits, '-', '_' and '.'
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+)
+
+func TestTraceIDMiddleware(t *testing.T) {
+	var upstreamIDs []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamIDs = append(upstreamIDs, r.Header.Get(TraceIDHeader))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	handler := NewServer(createTestCollector(), ServerConfig{Port: "8097"}).handler()
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A generated ID is returned, quoted in the error body and sent upstream
+	rr := serve("/api/v1/hosts", nil)
+	id := rr.Header().Get(TraceIDHeader)
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(id) {
+		t.Fatalf("Expected a generated 32 hex digit trace ID, got %q", id)
+	}
+	var errResp ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+		t.Fatal(err)
+	}
+	if errResp.Code != http.StatusInternalServerError || errResp.TraceID != id {
+		t.Errorf("Expected trace ID %s in the error body, got %+v", id, errResp)
+	}
+	if len(upstreamIDs) != 1 || upstreamIDs[0] != id {
+		t.Errorf("Expected the trace ID forwarded to the collector, got %v", upstreamIDs)
+	}
+
+	// Client IDs are kept when they are safe to log
+	if got := serve("/health", http.Header{"X-Request-Id": {"req-42.a_b"}}).Header().Get(TraceIDHeader); got != "req-42.a_b" {
+		t.Errorf("Expected the client's request ID, got %q", got)
+	}
+	if got := serve("/health", http.Header{"X-Trace-Id": {"bad id\n"}}).Header().Get(TraceIDHeader); got == "bad id\n" || got == "" {
+		t.Errorf("Expected an unsafe trace ID to be replaced, got %q", got)
+	}
+
+	// Unmatched routes get an ID too
+	if rr := serve("/nope", nil); rr.Code != http.StatusNotFound || rr.Header().Get(TraceIDHeader) == "" {
+		t.Errorf("Expected a trace ID on a 404, got %d %v", rr.Code, rr.Header())
+	}
+}
+
+func TestRequestLoggerMiddleware(t *testing.T) {
+	var tenants []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenants = append(tenants, r.Header.Get(TenantHeader))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	var buf bytes.Buffer
+	server := NewServer(createTestCollector(), ServerConfig{Port: "8097"})
+	server.logger = logger.New(logger.Config{Level: logger.INFO, Format: "text", Output: &buf})
+
+	var ctxLogger *logger.Logger
+	router := mux.NewRouter()
+	router.Use(server.requestLoggerMiddleware)
+	router.HandleFunc("/api/v1/gpus/{id}/telemetry", func(w http.ResponseWriter, r *http.Request) {
+		ctxLogger = logger.FromContext(r.Context())
+		ctxLogger.Info("deep call site")
+	})
+	req := httptest.NewRequest("GET", "/api/v1/gpus/gpu-0/telemetry", nil)
+	req.Header.Set(TenantHeader, "acme")
+	router.ServeHTTP(httptest.NewRecorder(), req.WithContext(context.WithValue(req.Context(), traceIDKey{}, "trace-1")))
+
+	if ctxLogger == nil {
+		t.Fatal("Handler not called")
+	}
+	for _, want := range []string{"request_id=trace-1", "route=/api/v1/gpus/{id}/telemetry", "tenant=acme"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Log %q missing %q", buf.String(), want)
+		}
+	}
+
+	// The tenant is forwarded to the collector with the trace ID
+	req = httptest.NewRequest("GET", "/api/v1/hosts", nil)
+	req.Header.Set(TenantHeader, "acme")
+	server.handler().ServeHTTP(httptest.NewRecorder(), req)
+	if len(tenants) != 1 || tenants[0] != "acme" {
+		t.Errorf("Expected the tenant forwarded to the collector, got %v", tenants)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the spans the gateway starts for requests
+const tracerName = "api-gateway"
+
+// NewTracerProviderFromEnv returns a tracer provider exporting spans over
+// OTLP/HTTP when OTEL_TRACES_EXPORTER is "otlp", or nil when tracing is not
+// enabled. The exporter follows the standard OTEL_EXPORTER_OTLP_* variables,
+// and OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES describe the service.
+// The caller shuts the provider down on exit to flush the last spans.
+func NewTracerProviderFromEnv(ctx context.Context, serviceName string) (*sdktrace.TracerProvider, error) {
+	switch exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q (want otlp or none)", exporter)
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	// The environment overrides the default service name
+	res, err := resource.Merge(
+		resource.NewSchemaless(semconv.ServiceName(serviceName)),
+		resource.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res)), nil
+}
+
+// tracingMiddleware starts a server span for every request, continuing a
+// trace the client propagated in a W3C traceparent header. It wraps every
+// other middleware, so the request's trace ID, logs and slow query events
+// belong to the span.
+func (s *Server) tracingMiddleware(next http.Handler) http.Handler {
+	if s.tracerProvider == nil {
+		return next
+	}
+	return otelhttp.NewHandler(next, tracerName,
+		otelhttp.WithTracerProvider(s.tracerProvider),
+		otelhttp.WithPropagators(propagation.TraceContext{}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}))
+}
+
+// spanRouteMiddleware names the request's span after its route template, as
+// the OpenTelemetry HTTP conventions ask, once the router has matched it
+func (s *Server) spanRouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_ = json.NewEncoder(w).Encode(map[string][]string{"hosts": {"host-a"}})
+	}))
+	defer upstream.Close()
+	t.Setenv("COLLECTOR_URL", upstream.URL)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer func() { _ = provider.Shutdown(t.Context()) }()
+	handler := NewServer(createTestCollector(), ServerConfig{
+		Port:               "8098",
+		SlowQueryThreshold: 10 * time.Millisecond,
+		TracerProvider:     provider,
+	}).handler()
+
+	// A propagated trace is continued, and its ID returned
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("GET", "/api/v1/hosts", nil)
+	req.Header.Set("traceparent", parent)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v1/hosts" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("Expected a server span named after the route, got %s %q", span.SpanKind(), span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the propagated trace continued, got %s", got)
+	}
+	if got := rr.Header().Get(TraceIDHeader); got != span.SpanContext().TraceID().String() {
+		t.Errorf("Expected the span's trace ID in %s, got %q", TraceIDHeader, got)
+	}
+	var events []string
+	for _, event := range span.Events() {
+		events = append(events, event.Name)
+	}
+	if len(events) != 2 || events[0] != "collector.request" || events[1] != "slow_query" {
+		t.Errorf("Expected collector.request and slow_query events, got %v", events)
+	}
+
+	// Without a provider no spans are started
+	handler = NewServer(createTestCollector(), ServerConfig{Port: "8098"}).handler()
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if len(recorder.Ended()) != 1 {
+		t.Errorf("Expected no span without a tracer provider, got %d", len(recorder.Ended())-1)
+	}
+}
+
+func TestNewTracerProviderFromEnv(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	if provider, err := NewTracerProviderFromEnv(t.Context(), "api-gateway"); provider != nil || err != nil {
+		t.Errorf("Expected tracing disabled by default, got %v, %v", provider, err)
+	}
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	if _, err := NewTracerProviderFromEnv(t.Context(), "api-gateway"); err == nil {
+		t.Error("Expected an unsupported exporter to be refused")
+	}
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:1")
+	provider, err := NewTracerProviderFromEnv(t.Context(), "api-gateway")
+	if err != nil || provider == nil {
+		t.Fatalf("Expected an OTLP tracer provider, got %v, %v", provider, err)
+	}
+	_ = provider.Shutdown(t.Context())
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// uiFiles holds the built-in dashboard, a static page that talks to the API
+// with relative URLs so it works under any base path
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// registerUI serves the dashboard page at / and its assets under /ui/
+func (s *Server) registerUI(router, routes *mux.Router) {
+	assets, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		// The embedded directory is fixed at build time
+		panic(err)
+	}
+
+	routes.PathPrefix("/ui/").
+		Handler(http.StripPrefix(s.basePath+"/ui/", http.FileServer(http.FS(assets)))).
+		Methods("GET", "HEAD")
+
+	routes.Path("/").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFileFS(w, r, assets, "index.html")
+		}).
+		Methods("GET", "HEAD")
+
+	// Relative asset URLs need the trailing slash to resolve under the prefix
+	if s.basePath != "" {
+		router.Path(s.basePath).
+			Handler(http.RedirectHandler(s.basePath+"/", http.StatusMovedPermanently)).
+			Methods("GET", "HEAD")
+	}
+}
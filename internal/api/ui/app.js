@@ -0,0 +1,287 @@
+// Minimal dashboard for the telemetry API gateway. All URLs are relative so
+// the page works when the gateway is served under a --base-path prefix.
+(function () {
+  "use strict";
+
+  const POLL_INTERVAL_MS = 5000;
+  const HISTORY_LIMIT = 100;
+
+  const el = {
+    status: document.getElementById("status"),
+    hosts: document.getElementById("hosts"),
+    title: document.getElementById("gpu-title"),
+    metric: document.getElementById("metric"),
+    mode: document.getElementById("mode"),
+    chart: document.getElementById("chart"),
+    latest: document.querySelector("#latest tbody"),
+  };
+
+  const state = {
+    gpu: null,
+    entries: [],
+    pollTimer: null,
+    source: null,
+  };
+
+  function setStatus(text, isError) {
+    el.status.textContent = text;
+    el.status.classList.toggle("error", Boolean(isError));
+  }
+
+  async function getJSON(path) {
+    // no-cache revalidates with the ETag, so unchanged data costs a 304
+    const resp = await fetch(path, { cache: "no-cache" });
+    if (!resp.ok) {
+      throw new Error(path + " returned " + resp.status);
+    }
+    return resp.json();
+  }
+
+  function enc(s) {
+    return encodeURIComponent(s);
+  }
+
+  // --- Host and GPU list ---
+
+  async function loadHosts() {
+    try {
+      const hosts = (await getJSON("api/v1/hosts?limit=1000")).hosts || [];
+      const groups = await Promise.all(hosts.map(async (host) => {
+        const gpus = (await getJSON("api/v1/hosts/" + enc(host) + "/gpus")).gpus || [];
+        return { host, gpus: gpus.slice().sort() };
+      }));
+
+      // GPUs reported without a hostname still deserve a place in the list
+      const listed = new Set(groups.flatMap((g) => g.gpus));
+      const all = (await getJSON("api/v1/gpus?limit=1000")).gpus || [];
+      const other = all.filter((id) => !listed.has(id)).sort();
+      if (other.length > 0) {
+        groups.push({ host: "Other", gpus: other });
+      }
+
+      renderHosts(groups);
+      setStatus("Updated " + new Date().toLocaleTimeString());
+    } catch (err) {
+      setStatus(err.message, true);
+    }
+  }
+
+  function renderHosts(groups) {
+    el.hosts.replaceChildren();
+    if (groups.length === 0) {
+      const p = document.createElement("p");
+      p.className = "muted";
+      p.textContent = "No telemetry yet";
+      el.hosts.append(p);
+      return;
+    }
+
+    for (const group of groups) {
+      const h = document.createElement("h3");
+      h.textContent = group.host;
+      const ul = document.createElement("ul");
+      for (const gpu of group.gpus) {
+        const li = document.createElement("li");
+        const btn = document.createElement("button");
+        btn.type = "button";
+        btn.textContent = gpu;
+        btn.title = gpu;
+        btn.classList.toggle("active", gpu === state.gpu);
+        btn.addEventListener("click", () => selectGPU(gpu));
+        li.append(btn);
+        ul.append(li);
+      }
+      el.hosts.append(h, ul);
+    }
+  }
+
+  // --- Telemetry for the selected GPU ---
+
+  function selectGPU(gpu) {
+    stopUpdates();
+    state.gpu = gpu;
+    state.entries = [];
+    el.title.textContent = gpu;
+    for (const btn of el.hosts.querySelectorAll("button")) {
+      btn.classList.toggle("active", btn.textContent === gpu);
+    }
+    loadTelemetry().then(startUpdates);
+  }
+
+  async function loadTelemetry() {
+    const gpu = state.gpu;
+    try {
+      const resp = await getJSON("api/v1/gpus/" + enc(gpu) + "/telemetry?limit=" + HISTORY_LIMIT);
+      if (gpu !== state.gpu) {
+        return; // selection changed while loading
+      }
+      state.entries = (resp.data || []).slice().sort(byTime);
+      render();
+    } catch (err) {
+      setStatus(err.message, true);
+    }
+  }
+
+  function byTime(a, b) {
+    return new Date(a.timestamp) - new Date(b.timestamp);
+  }
+
+  // Prefer a server-sent event stream if the gateway offers one, falling back
+  // to polling the telemetry endpoint
+  function startUpdates() {
+    if (!state.gpu) {
+      return;
+    }
+    if (window.EventSource) {
+      const source = new EventSource("api/v1/gpus/" + enc(state.gpu) + "/stream");
+      let opened = false;
+      source.onopen = () => {
+        opened = true;
+        el.mode.textContent = "live";
+      };
+      source.onmessage = (event) => {
+        try {
+          state.entries.push(JSON.parse(event.data));
+          state.entries = state.entries.slice(-HISTORY_LIMIT);
+          render();
+        } catch (err) {
+          // ignore malformed events
+        }
+      };
+      source.onerror = () => {
+        if (!opened) {
+          source.close();
+          state.source = null;
+          startPolling();
+        }
+      };
+      state.source = source;
+      return;
+    }
+    startPolling();
+  }
+
+  function startPolling() {
+    el.mode.textContent = "refreshing every " + POLL_INTERVAL_MS / 1000 + "s";
+    state.pollTimer = setInterval(loadTelemetry, POLL_INTERVAL_MS);
+  }
+
+  function stopUpdates() {
+    if (state.source) {
+      state.source.close();
+      state.source = null;
+    }
+    clearInterval(state.pollTimer);
+    state.pollTimer = null;
+    el.mode.textContent = "";
+  }
+
+  // --- Rendering ---
+
+  function metricNames() {
+    const names = new Set();
+    for (const entry of state.entries) {
+      Object.keys(entry.metrics || {}).forEach((name) => names.add(name));
+    }
+    return Array.from(names).sort();
+  }
+
+  function render() {
+    const names = metricNames();
+    const current = el.metric.value;
+    el.metric.replaceChildren(...names.map((name) => new Option(name, name)));
+    el.metric.disabled = names.length === 0;
+    if (names.includes(current)) {
+      el.metric.value = current;
+    } else if (names.includes("DCGM_FI_DEV_GPU_UTIL")) {
+      el.metric.value = "DCGM_FI_DEV_GPU_UTIL";
+    }
+
+    renderChart(el.metric.value);
+    renderTable(names);
+  }
+
+  function renderChart(metric) {
+    const width = 800;
+    const height = 300;
+    const pad = 24;
+    const points = state.entries
+      .filter((e) => e.metrics && typeof e.metrics[metric] === "number")
+      .map((e) => ({ t: new Date(e.timestamp).getTime(), v: e.metrics[metric] }));
+
+    const ns = "http://www.w3.org/2000/svg";
+    el.chart.replaceChildren();
+    if (points.length === 0) {
+      return;
+    }
+
+    const tMin = points[0].t;
+    const tMax = points[points.length - 1].t;
+    let vMin = Math.min(...points.map((p) => p.v));
+    let vMax = Math.max(...points.map((p) => p.v));
+    if (vMin === vMax) {
+      vMin -= 1;
+      vMax += 1;
+    }
+
+    const x = (t) => pad + (tMax === tMin ? 0.5 : (t - tMin) / (tMax - tMin)) * (width - 2 * pad);
+    const y = (v) => height - pad - ((v - vMin) / (vMax - vMin)) * (height - 2 * pad);
+
+    for (const v of [vMin, (vMin + vMax) / 2, vMax]) {
+      const line = document.createElementNS(ns, "line");
+      line.setAttribute("class", "grid");
+      line.setAttribute("x1", pad);
+      line.setAttribute("x2", width - pad);
+      line.setAttribute("y1", y(v));
+      line.setAttribute("y2", y(v));
+      const label = document.createElementNS(ns, "text");
+      label.setAttribute("x", 2);
+      label.setAttribute("y", y(v) - 2);
+      label.textContent = formatValue(v);
+      el.chart.append(line, label);
+    }
+
+    const polyline = document.createElementNS(ns, "polyline");
+    polyline.setAttribute("class", "line");
+    polyline.setAttribute("points", points.map((p) => x(p.t) + "," + y(p.v)).join(" "));
+    el.chart.append(polyline);
+  }
+
+  function renderTable(names) {
+    const rows = names.map((name) => {
+      const values = state.entries
+        .map((e) => e.metrics && e.metrics[name])
+        .filter((v) => typeof v === "number");
+      const tr = document.createElement("tr");
+      const unit = unitOf(name);
+      const label = unit ? name + " (" + unit + ")" : name;
+      for (const text of [label, values[values.length - 1], Math.min(...values), Math.max(...values)]) {
+        const td = document.createElement("td");
+        if (typeof text === "number") {
+          td.className = "num";
+          td.textContent = formatValue(text);
+        } else {
+          td.textContent = text;
+        }
+        tr.append(td);
+      }
+      return tr;
+    });
+    el.latest.replaceChildren(...rows);
+  }
+
+  // unitOf returns a metric's unit as reported by the API, if known
+  function unitOf(name) {
+    const entry = state.entries.find((e) => e.units && e.units[name]);
+    return entry ? entry.units[name] : "";
+  }
+
+  function formatValue(v) {
+    return Math.abs(v) >= 1000 ? v.toFixed(0) : Number(v.toPrecision(4)).toString();
+  }
+
+  el.metric.addEventListener("change", () => renderChart(el.metric.value));
+
+  loadHosts();
+  setInterval(loadHosts, 30000);
+})();
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerUI(t *testing.T) {
+	tests := []struct {
+		name        string
+		basePath    string
+		path        string
+		status      int
+		contentType string
+		location    string
+	}{
+		{"index", "", "/", http.StatusOK, "text/html", ""},
+		{"script", "", "/ui/app.js", http.StatusOK, "javascript", ""},
+		{"stylesheet", "", "/ui/style.css", http.StatusOK, "text/css", ""},
+		{"missing asset", "", "/ui/missing.js", http.StatusNotFound, "", ""},
+		{"prefixed index", "/telemetry", "/telemetry/", http.StatusOK, "text/html", ""},
+		{"prefixed script", "/telemetry", "/telemetry/ui/app.js", http.StatusOK, "javascript", ""},
+		{"prefix redirect", "/telemetry", "/telemetry", http.StatusMovedPermanently, "", "/telemetry/"},
+		{"unprefixed index", "/telemetry", "/", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(createTestCollector(), ServerConfig{BasePath: tt.basePath, EnableUI: true})
+			rr := httptest.NewRecorder()
+			server.handler().ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.status {
+				t.Fatalf("GET %s: got %d, want %d", tt.path, rr.Code, tt.status)
+			}
+			if ct := rr.Header().Get("Content-Type"); !strings.Contains(ct, tt.contentType) {
+				t.Errorf("GET %s: Content-Type %q, want %q", tt.path, ct, tt.contentType)
+			}
+			if loc := rr.Header().Get("Location"); loc != tt.location {
+				t.Errorf("GET %s: Location %q, want %q", tt.path, loc, tt.location)
+			}
+		})
+	}
+}
+
+func TestServerUIDisabled(t *testing.T) {
+	server := NewServer(createTestCollector(), ServerConfig{})
+	handler := server.handler()
+
+	for _, path := range []string{"/", "/ui/app.js"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("GET %s with UI disabled: got %d, want 404", path, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+)
+
+// DefaultUsageKeyHeader is the request header identifying API consumers for
+// usage accounting
+const DefaultUsageKeyHeader = "X-API-Key"
+
+// anonymousKey accounts requests without a key
+const anonymousKey = "anonymous"
+
+// otherKey accounts requests from keys beyond maxUsageKeys, so that random
+// keys cannot grow the tracker without bound
+const otherKey = "other"
+
+// maxUsageKeys bounds the number of keys tracked per month
+const maxUsageKeys = 10000
+
+// usageMonthFormat names a calendar month, in UTC
+const usageMonthFormat = "2006-01"
+
+// usageMonthPattern matches months accepted by /admin/usage?month=
+var usageMonthPattern = regexp.MustCompile(`^\d{4}-\d{2}$`)
+
+// KeyUsage is the usage accounted to one API key over a month. Key is a
+// fingerprint of the API key rather than the key itself, so the report does
+// not leak credentials.
+type KeyUsage struct {
+	Key           string    `json:"key"`
+	Requests      uint64    `json:"requests"`
+	Errors        uint64    `json:"errors"`
+	BytesReturned int64     `json:"bytes_returned"`
+	QuerySeconds  float64   `json:"query_seconds"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+// UsageReport is the usage of every API key over a calendar month (UTC),
+// heaviest consumers first
+type UsageReport struct {
+	Month string     `json:"month"`
+	Since time.Time  `json:"since"`
+	Keys  []KeyUsage `json:"keys"`
+}
+
+// usageTracker accounts requests, bytes returned and query time per API key
+// for the current month. When the month changes, the finished month is
+// exported to exportDir, if set, and accounting starts afresh.
+type usageTracker struct {
+	header    string
+	exportDir string
+	now       func() time.Time
+
+	mu    sync.Mutex
+	month string
+	since time.Time
+	keys  map[string]*KeyUsage
+}
+
+// newUsageTracker creates a tracker, resuming the current month from
+// exportDir if it was saved by a previous run. The tracker is usable even if
+// resuming fails.
+func newUsageTracker(header, exportDir string) (*usageTracker, error) {
+	if header == "" {
+		header = DefaultUsageKeyHeader
+	}
+	u := &usageTracker{header: header, exportDir: exportDir, now: time.Now}
+	u.reset(u.now())
+
+	if exportDir == "" {
+		return u, nil
+	}
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return u, fmt.Errorf("failed to create usage export directory: %w", err)
+	}
+	report, err := u.load(u.month)
+	if errors.Is(err, os.ErrNotExist) {
+		return u, nil
+	}
+	if err != nil {
+		return u, err
+	}
+	u.since = report.Since
+	for i := range report.Keys {
+		u.keys[report.Keys[i].Key] = &report.Keys[i]
+	}
+	return u, nil
+}
+
+// keyFingerprint identifies an API key in reports without revealing it
+func keyFingerprint(key string) string {
+	if key == "" {
+		return anonymousKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// reset starts accounting a new month at now. Caller must hold u.mu or own u.
+func (u *usageTracker) reset(now time.Time) {
+	u.month = now.UTC().Format(usageMonthFormat)
+	u.since = now.UTC()
+	u.keys = make(map[string]*KeyUsage)
+}
+
+// rollover exports and resets the accounting if the month has changed.
+// Caller must hold u.mu.
+func (u *usageTracker) rollover(now time.Time) error {
+	if now.UTC().Format(usageMonthFormat) == u.month {
+		return nil
+	}
+	err := u.save(u.reportLocked())
+	u.reset(now)
+	return err
+}
+
+// record accounts one request to key
+func (u *usageTracker) record(key string, status int, bytes int64, elapsed time.Duration) error {
+	now := u.now()
+	fingerprint := keyFingerprint(key)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	err := u.rollover(now)
+
+	usage, ok := u.keys[fingerprint]
+	if !ok {
+		if len(u.keys) >= maxUsageKeys {
+			fingerprint = otherKey
+			usage = u.keys[otherKey]
+		}
+		if usage == nil {
+			usage = &KeyUsage{Key: fingerprint}
+			u.keys[fingerprint] = usage
+		}
+	}
+	usage.Requests++
+	if status >= http.StatusBadRequest {
+		usage.Errors++
+	}
+	usage.BytesReturned += bytes
+	usage.QuerySeconds += elapsed.Seconds()
+	usage.LastSeen = now.UTC()
+	return err
+}
+
+// report returns the current month's usage
+func (u *usageTracker) report() (UsageReport, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	err := u.rollover(u.now())
+	return u.reportLocked(), err
+}
+
+// reportLocked builds the current month's report. Caller must hold u.mu.
+func (u *usageTracker) reportLocked() UsageReport {
+	report := UsageReport{Month: u.month, Since: u.since, Keys: make([]KeyUsage, 0, len(u.keys))}
+	for _, usage := range u.keys {
+		report.Keys = append(report.Keys, *usage)
+	}
+	sort.Slice(report.Keys, func(i, j int) bool {
+		a, b := report.Keys[i], report.Keys[j]
+		if a.QuerySeconds != b.QuerySeconds {
+			return a.QuerySeconds > b.QuerySeconds
+		}
+		return a.Key < b.Key
+	})
+	return report
+}
+
+// flush saves the current month so a restart resumes it
+func (u *usageTracker) flush() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.save(u.reportLocked())
+}
+
+// exportPath is where the report for month is kept
+func (u *usageTracker) exportPath(month string) string {
+	return filepath.Join(u.exportDir, "usage-"+month+".json")
+}
+
+// save writes report to the export directory, if one is configured
+func (u *usageTracker) save(report UsageReport) error {
+	if u.exportDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode usage report: %w", err)
+	}
+	path := u.exportPath(report.Month)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to export usage report: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to export usage report: %w", err)
+	}
+	return nil
+}
+
+// load reads the exported report for month
+func (u *usageTracker) load(month string) (UsageReport, error) {
+	var report UsageReport
+	data, err := os.ReadFile(u.exportPath(month))
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("failed to parse usage report %s: %w", month, err)
+	}
+	return report, nil
+}
+
+// usageMiddleware accounts each request to the API key in the configured
+// header
+func (s *Server) usageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapper, r)
+
+		if err := s.usage.record(r.Header.Get(s.usage.header), wrapper.statusCode, wrapper.bytes, time.Since(start)); err != nil {
+			s.logger.Warn("Failed to export monthly usage", "error", err)
+		}
+	})
+}
+
+// GetUsage serves the current month's usage per API key, or an exported
+// month with ?month=YYYY-MM
+func (h *Handlers) GetUsage(w http.ResponseWriter, r *http.Request) {
+	month := r.URL.Query().Get("month")
+
+	report, err := h.usage.report()
+	if err != nil {
+		log.Printf("Failed to export monthly usage: %v", err)
+	}
+	if month != "" && month != report.Month {
+		if !usageMonthPattern.MatchString(month) {
+			h.writeErrorResponse(w, http.StatusBadRequest, apierror.InvalidParameter, "Invalid month", "expected YYYY-MM")
+			return
+		}
+		if h.usage.exportDir == "" {
+			h.writeErrorResponse(w, http.StatusNotFound, apierror.UsageNotFound, "Usage not found", "past months are only kept with --usage-export-dir")
+			return
+		}
+		report, err = h.usage.load(month)
+		if errors.Is(err, os.ErrNotExist) {
+			h.writeErrorResponse(w, http.StatusNotFound, apierror.UsageNotFound, "Usage not found", "no usage exported for "+month)
+			return
+		}
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusInternalServerError, apierror.Internal, "Failed to read usage", err.Error())
+			return
+		}
+	}
+	h.writeJSONResponse(w, http.StatusOK, report)
+}
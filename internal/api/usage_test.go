@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageTracker_RolloverAndResume(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 9, 30, 23, 59, 0, 0, time.UTC)
+	tracker, err := newUsageTracker("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.now = func() time.Time { return now }
+	tracker.reset(now)
+
+	_ = tracker.record("key-a", http.StatusOK, 100, time.Second)
+	_ = tracker.record("key-a", http.StatusInternalServerError, 50, 2*time.Second)
+	_ = tracker.record("key-b", http.StatusOK, 10, time.Second)
+	_ = tracker.record("", http.StatusOK, 5, 0)
+
+	report, _ := tracker.report()
+	if report.Month != "2025-09" || len(report.Keys) != 3 {
+		t.Fatalf("Expected three keys in 2025-09, got %+v", report)
+	}
+	heaviest := report.Keys[0]
+	if heaviest.Key != keyFingerprint("key-a") || heaviest.Requests != 2 || heaviest.Errors != 1 ||
+		heaviest.BytesReturned != 150 || heaviest.QuerySeconds != 3 {
+		t.Errorf("Expected key-a first with its totals, got %+v", heaviest)
+	}
+	if keyFingerprint("key-a") == "key-a" || report.Keys[2].Key != anonymousKey {
+		t.Errorf("Expected fingerprinted keys and an anonymous entry, got %+v", report.Keys)
+	}
+
+	// The first request of a new month exports the old one and starts afresh
+	now = now.Add(2 * time.Minute)
+	if err := tracker.record("key-b", http.StatusOK, 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := tracker.load("2025-09")
+	if err != nil || len(exported.Keys) != 3 {
+		t.Fatalf("Expected September exported, got %+v, %v", exported, err)
+	}
+	if report, _ := tracker.report(); report.Month != "2025-10" || len(report.Keys) != 1 {
+		t.Errorf("Expected a fresh October, got %+v", report)
+	}
+
+	// Shutdown saves the month so far, which a restart within it resumes
+	if err := tracker.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "usage-2025-10.json")); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := tracker.load("2025-10")
+	if err != nil || len(saved.Keys) != 1 || saved.Keys[0].Requests != 1 {
+		t.Errorf("Expected October saved with one request, got %+v, %v", saved, err)
+	}
+}
+
+func TestUsageEndpoint(t *testing.T) {
+	server := NewServer(createTestCollector(), ServerConfig{Port: "8098", UsageKeyHeader: "X-Team", UsageExportDir: t.TempDir()})
+	handler := server.handler()
+	serve := func(path, team string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if team != "" {
+			req.Header.Set("X-Team", team)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Health bodies vary in length with their timestamps
+	returned := int64(serve("/health", "ml").Body.Len() + serve("/health", "ml").Body.Len())
+
+	var report UsageReport
+	if err := json.NewDecoder(serve("/admin/usage", "").Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	var ml *KeyUsage
+	for i := range report.Keys {
+		if report.Keys[i].Key == keyFingerprint("ml") {
+			ml = &report.Keys[i]
+		}
+	}
+	if ml == nil || ml.Requests != 2 || ml.BytesReturned != returned {
+		t.Errorf("Expected two requests and their bytes for the ml key, got %+v", report.Keys)
+	}
+
+	if rr := serve("/admin/usage?month=2020-01", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a month never exported, got %d", rr.Code)
+	}
+	if rr := serve("/admin/usage?month=last", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid month, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,162 @@
+// Package apierror defines the stable error codes returned with the error
+// responses of the API gateway and the collector, so clients can branch on a
+// code, or show their own translated message for it, rather than parse
+// messages written for people
+package apierror
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Code identifies an error condition. Codes are never renumbered or reused
+// for another condition; 4xxx codes are client errors and 5xxx server errors.
+type Code struct {
+	// ID is the code's number, e.g. TP-4001
+	ID string
+	// Name is the code's symbolic name, e.g. INVALID_TIME_RANGE
+	Name string
+}
+
+// String returns the ID and the name, e.g. "TP-4001 INVALID_TIME_RANGE"
+func (c Code) String() string {
+	return c.ID + " " + c.Name
+}
+
+// Invalid requests
+var (
+	InvalidRequest    = Code{"TP-4000", "INVALID_REQUEST"}
+	InvalidTimeRange  = Code{"TP-4001", "INVALID_TIME_RANGE"}
+	InvalidPagination = Code{"TP-4002", "INVALID_PAGINATION"}
+	InvalidParameter  = Code{"TP-4003", "INVALID_PARAMETER"}
+	MissingParameter  = Code{"TP-4004", "MISSING_PARAMETER"}
+	InvalidUnits      = Code{"TP-4005", "INVALID_UNITS"}
+	InvalidSilence    = Code{"TP-4006", "INVALID_SILENCE"}
+	InvalidMerge      = Code{"TP-4007", "INVALID_MERGE"}
+)
+
+// Missing resources
+var (
+	NotFound            = Code{"TP-4010", "NOT_FOUND"}
+	HostNotFound        = Code{"TP-4011", "HOST_NOT_FOUND"}
+	SilenceNotFound     = Code{"TP-4012", "SILENCE_NOT_FOUND"}
+	AliasNotFound       = Code{"TP-4013", "ALIAS_NOT_FOUND"}
+	NoSamples           = Code{"TP-4014", "NO_SAMPLES"}
+	UsageNotFound       = Code{"TP-4015", "USAGE_NOT_FOUND"}
+	MessageNotFound     = Code{"TP-4016", "MESSAGE_NOT_FOUND"}
+	NoConsistencyReport = Code{"TP-4017", "NO_CONSISTENCY_REPORT"}
+	FeatureDisabled     = Code{"TP-4018", "FEATURE_DISABLED"}
+)
+
+// Other client errors
+var (
+	MethodNotAllowed = Code{"TP-4020", "METHOD_NOT_ALLOWED"}
+	CheckRunning     = Code{"TP-4030", "CHECK_RUNNING"}
+)
+
+// Server errors
+var (
+	Internal = Code{"TP-5000", "INTERNAL"}
+	// CollectorUnavailable is returned by the gateway when it cannot reach
+	// the collector
+	CollectorUnavailable = Code{"TP-5001", "COLLECTOR_UNAVAILABLE"}
+	// CollectorError is returned by the gateway when the collector fails a
+	// request or returns a response it cannot read
+	CollectorError = Code{"TP-5002", "COLLECTOR_ERROR"}
+)
+
+// codes are all the codes, by ID
+var codes = map[string]Code{}
+
+func init() {
+	for _, code := range []Code{
+		InvalidRequest, InvalidTimeRange, InvalidPagination, InvalidParameter,
+		MissingParameter, InvalidUnits, InvalidSilence, InvalidMerge,
+		NotFound, HostNotFound, SilenceNotFound, AliasNotFound, NoSamples,
+		UsageNotFound, MessageNotFound, NoConsistencyReport, FeatureDisabled,
+		MethodNotAllowed, CheckRunning,
+		Internal, CollectorUnavailable, CollectorError,
+	} {
+		codes[code.ID] = code
+	}
+}
+
+// Codes returns every code in ID order
+func Codes() []Code {
+	all := make([]Code, 0, len(codes))
+	for _, code := range codes {
+		all = append(all, code)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all
+}
+
+// Lookup returns the code with an ID
+func Lookup(id string) (Code, bool) {
+	code, ok := codes[id]
+	return code, ok
+}
+
+// ForStatus is the code for an error with an HTTP status that carried none
+func ForStatus(status int) Code {
+	switch {
+	case status == http.StatusNotFound:
+		return NotFound
+	case status == http.StatusMethodNotAllowed:
+		return MethodNotAllowed
+	case status >= 400 && status < 500:
+		return InvalidRequest
+	default:
+		return Internal
+	}
+}
+
+// Response is the JSON body of an error response
+type Response struct {
+	// Error is the text of the HTTP status
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	// Code is the HTTP status
+	Code int `json:"code"`
+	// ErrorCode and Reason are the ID and name of the error's Code
+	ErrorCode string `json:"error_code"`
+	Reason    string `json:"reason"`
+}
+
+// New returns the body of an error response
+func New(status int, code Code, message string) Response {
+	return Response{
+		Error:     http.StatusText(status),
+		Message:   message,
+		Code:      status,
+		ErrorCode: code.ID,
+		Reason:    code.Name,
+	}
+}
+
+// Write writes an error response
+func Write(w http.ResponseWriter, status int, code Code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(New(status, code, message))
+}
+
+// Read reads the code and message of an error response with status. A body
+// that is not an error response, such as the plain text of a server that
+// predates codes, is the message, with the code for status.
+func Read(body io.Reader, status int) (Code, string) {
+	data, _ := io.ReadAll(io.LimitReader(body, 4096))
+	var response Response
+	if err := json.Unmarshal(data, &response); err == nil && response.ErrorCode != "" {
+		code, ok := Lookup(response.ErrorCode)
+		if !ok {
+			code = Code{ID: response.ErrorCode, Name: response.Reason}
+		}
+		return code, response.Message
+	}
+	return ForStatus(status), strings.TrimSpace(string(data))
+}
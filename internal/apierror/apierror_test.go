@@ -0,0 +1,56 @@
+package apierror
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Write(rr, http.StatusBadRequest, InvalidTimeRange, "start_time is after end_time")
+	if rr.Code != http.StatusBadRequest || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected response %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rr.Body.String(), `"error_code":"TP-4001","reason":"INVALID_TIME_RANGE"`) {
+		t.Errorf("Expected the code in the body, got %s", rr.Body.String())
+	}
+
+	code, message := Read(rr.Body, rr.Code)
+	if code != InvalidTimeRange || message != "start_time is after end_time" {
+		t.Errorf("Read = %v, %q", code, message)
+	}
+
+	// Codes this build does not know are kept as sent
+	code, _ = Read(strings.NewReader(`{"message":"m","error_code":"TP-4999","reason":"NEW"}`), http.StatusBadRequest)
+	if code.ID != "TP-4999" || code.Name != "NEW" {
+		t.Errorf("Expected an unknown code kept, got %v", code)
+	}
+}
+
+func TestReadPlainText(t *testing.T) {
+	code, message := Read(strings.NewReader("silence not found\n"), http.StatusNotFound)
+	if code != NotFound || message != "silence not found" {
+		t.Errorf("Read = %v, %q", code, message)
+	}
+	if code, _ := Read(strings.NewReader("boom"), http.StatusBadGateway); code != Internal {
+		t.Errorf("Expected %v for a server error, got %v", Internal, code)
+	}
+}
+
+func TestCodesUnique(t *testing.T) {
+	names := make(map[string]bool)
+	for _, code := range Codes() {
+		if !strings.HasPrefix(code.ID, "TP-") || names[code.Name] {
+			t.Errorf("Invalid or duplicate code %v", code)
+		}
+		names[code.Name] = true
+		if found, ok := Lookup(code.ID); !ok || found != code {
+			t.Errorf("Lookup(%s) = %v, %v", code.ID, found, ok)
+		}
+	}
+	if len(names) != 22 {
+		t.Errorf("Expected 22 codes, got %d; a code was given an ID already in use", len(names))
+	}
+}
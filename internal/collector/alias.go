@@ -0,0 +1,270 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// aliasStateFile holds GPU aliases in the data directory
+const aliasStateFile = "gpu_aliases.json"
+
+// ErrAliasNotFound is returned for a GPU ID that is not an alias
+var ErrAliasNotFound = errors.New("gpu alias not found")
+
+// GPUAlias records that Alias is another ID of the GPU with ID Canonical,
+// e.g. a legacy gpu_0 for a DCGM UUID
+type GPUAlias struct {
+	Alias     string    `json:"alias"`
+	Canonical string    `json:"canonical"`
+	MergedBy  string    `json:"merged_by"`
+	MergedAt  time.Time `json:"merged_at"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+// GPUMerge is the outcome of merging a GPU into another
+type GPUMerge struct {
+	GPUAlias
+	// SamplesMoved is the number of cached samples moved to the canonical
+	// GPU
+	SamplesMoved int `json:"samples_moved"`
+}
+
+// AliasStore keeps GPU aliases, persisted so merges survive restarts. An
+// alias always maps straight to a canonical ID: merging into an alias merges
+// into its canonical GPU, and merging a canonical GPU moves its aliases along.
+type AliasStore struct {
+	mu      sync.RWMutex
+	store   *persistence.FileStore
+	aliases map[string]*GPUAlias // by alias
+	now     func() time.Time
+}
+
+// NewAliasStore creates a store persisting to path
+func NewAliasStore(path string) *AliasStore {
+	return &AliasStore{
+		store:   persistence.NewFileStore(path),
+		aliases: make(map[string]*GPUAlias),
+		now:     time.Now,
+	}
+}
+
+// Load restores saved aliases
+func (s *AliasStore) Load() error {
+	var aliases []*GPUAlias
+	if err := s.store.Load(&aliases); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to load gpu aliases: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aliases = make(map[string]*GPUAlias, len(aliases))
+	for _, alias := range aliases {
+		s.aliases[alias.Alias] = alias
+	}
+	return nil
+}
+
+// Add validates and stores an alias, returning it with its canonical ID
+// resolved. Adding an alias again for the same GPU returns the stored one.
+func (s *AliasStore) Add(alias GPUAlias) (GPUAlias, error) {
+	alias.Alias = strings.TrimSpace(alias.Alias)
+	alias.Canonical = strings.TrimSpace(alias.Canonical)
+	alias.MergedBy = strings.TrimSpace(alias.MergedBy)
+	if alias.Alias == "" || alias.Canonical == "" {
+		return GPUAlias{}, fmt.Errorf("alias and canonical are required")
+	}
+	if alias.MergedBy == "" {
+		return GPUAlias{}, fmt.Errorf("merged_by is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if resolved, ok := s.aliases[alias.Canonical]; ok {
+		alias.Canonical = resolved.Canonical
+	}
+	if alias.Canonical == alias.Alias {
+		return GPUAlias{}, fmt.Errorf("cannot merge %s into itself", alias.Alias)
+	}
+	if existing, ok := s.aliases[alias.Alias]; ok {
+		if existing.Canonical == alias.Canonical {
+			return *existing, nil
+		}
+		return GPUAlias{}, fmt.Errorf("%s is already merged into %s", alias.Alias, existing.Canonical)
+	}
+	alias.MergedAt = s.now()
+
+	previous := make(map[string]string)
+	for _, other := range s.aliases {
+		if other.Canonical == alias.Alias {
+			previous[other.Alias] = other.Canonical
+			other.Canonical = alias.Canonical
+		}
+	}
+	stored := alias
+	s.aliases[alias.Alias] = &stored
+	if err := s.save(); err != nil {
+		delete(s.aliases, alias.Alias)
+		for name, canonical := range previous {
+			s.aliases[name].Canonical = canonical
+		}
+		return GPUAlias{}, fmt.Errorf("failed to save gpu aliases: %w", err)
+	}
+	return alias, nil
+}
+
+// Remove deletes an alias. Samples already merged stay with the canonical
+// GPU; new samples for the alias are stored under it again.
+func (s *AliasStore) Remove(name string) (GPUAlias, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias, ok := s.aliases[name]
+	if !ok {
+		return GPUAlias{}, ErrAliasNotFound
+	}
+	delete(s.aliases, name)
+	if err := s.save(); err != nil {
+		s.aliases[name] = alias
+		return GPUAlias{}, fmt.Errorf("failed to save gpu aliases: %w", err)
+	}
+	return *alias, nil
+}
+
+// Resolve returns the canonical ID of gpuID, which is gpuID itself unless it
+// is an alias
+func (s *AliasStore) Resolve(gpuID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if alias, ok := s.aliases[gpuID]; ok {
+		return alias.Canonical
+	}
+	return gpuID
+}
+
+// AliasesOf returns the aliases of canonical, sorted
+func (s *AliasStore) AliasesOf(canonical string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for name, alias := range s.aliases {
+		if alias.Canonical == canonical {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns the aliases sorted by alias
+func (s *AliasStore) List() []GPUAlias {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	aliases := make([]GPUAlias, 0, len(s.aliases))
+	for _, alias := range s.aliases {
+		aliases = append(aliases, *alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Alias < aliases[j].Alias })
+	return aliases
+}
+
+// save writes the aliases to disk; the caller holds mu
+func (s *AliasStore) save() error {
+	aliases := make([]*GPUAlias, 0, len(s.aliases))
+	for _, alias := range s.aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Alias < aliases[j].Alias })
+	return s.store.Save(aliases)
+}
+
+// MergeGPU records alias.Alias as another ID of alias.Canonical. Samples
+// received for the alias from now on are stored under the canonical ID, its
+// cached samples, health and metric catalog entries are moved there, and
+// telemetry reads of the canonical GPU include the alias's file.
+func (c *Collector) MergeGPU(alias GPUAlias) (GPUMerge, error) {
+	c.mergeMu.Lock()
+	defer c.mergeMu.Unlock()
+
+	merged, err := c.aliases.Add(alias)
+	if err != nil {
+		return GPUMerge{}, err
+	}
+	moved := c.memoryStorage.MergeGPU(merged.Alias, merged.Canonical)
+	c.health.Forget(merged.Alias)
+	c.catalog.Merge(merged.Alias, merged.Canonical)
+
+	c.logger.Info("GPU merged",
+		"alias", merged.Alias,
+		"canonical", merged.Canonical,
+		"merged_by", merged.MergedBy,
+		"samples_moved", moved,
+		"comment", merged.Comment)
+	return GPUMerge{GPUAlias: merged, SamplesMoved: moved}, nil
+}
+
+// gpuFiles returns the IDs whose files hold a GPU's telemetry: its canonical
+// ID followed by its aliases
+func (c *Collector) gpuFiles(gpuID string) []string {
+	canonical := c.aliases.Resolve(gpuID)
+	return append([]string{canonical}, c.aliases.AliasesOf(canonical)...)
+}
+
+// serveGPUAliases handles GET and POST /admin/gpu-aliases
+func (c *Collector) serveGPUAliases(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, c.aliases.List())
+	case http.MethodPost:
+		var alias GPUAlias
+		if err := json.NewDecoder(r.Body).Decode(&alias); err != nil {
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidMerge, "invalid merge: "+err.Error())
+			return
+		}
+		merged, err := c.MergeGPU(alias)
+		if err != nil {
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidMerge, "invalid merge: "+err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, merged)
+	default:
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+	}
+}
+
+// serveGPUAlias handles DELETE /admin/gpu-aliases/{alias}
+func (c *Collector) serveGPUAlias(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/admin/gpu-aliases/")
+	removed, err := c.aliases.Remove(name)
+	if errors.Is(err, ErrAliasNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.AliasNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, err.Error())
+		return
+	}
+
+	c.logger.Info("GPU alias removed", "alias", removed.Alias, "canonical", removed.Canonical)
+	writeJSON(w, http.StatusOK, removed)
+}
@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence/persistencetest"
+)
+
+func TestAliasStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), aliasStateFile)
+	store := NewAliasStore(path)
+
+	invalid := []GPUAlias{
+		{Canonical: "GPU-abc", MergedBy: "alice"},
+		{Alias: "gpu_0", Canonical: "GPU-abc"},
+		{Alias: "gpu_0", Canonical: "gpu_0", MergedBy: "alice"},
+	}
+	for _, alias := range invalid {
+		if _, err := store.Add(alias); err == nil {
+			t.Errorf("Expected error adding %+v", alias)
+		}
+	}
+
+	if _, err := store.Add(GPUAlias{Alias: "gpu_0", Canonical: "GPU-abc", MergedBy: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	// Merging into an alias merges into its canonical GPU
+	if merged, err := store.Add(GPUAlias{Alias: "0", Canonical: "gpu_0", MergedBy: "alice"}); err != nil || merged.Canonical != "GPU-abc" {
+		t.Errorf("Expected 0 merged into GPU-abc, got %+v, %v", merged, err)
+	}
+	if _, err := store.Add(GPUAlias{Alias: "gpu_0", Canonical: "GPU-def", MergedBy: "bob"}); err == nil {
+		t.Error("Expected error merging an alias into a second GPU")
+	}
+	if _, err := store.Add(GPUAlias{Alias: "GPU-abc", Canonical: "gpu_0", MergedBy: "bob"}); err == nil {
+		t.Error("Expected error merging a GPU into its own alias")
+	}
+
+	// Merging a canonical GPU moves its aliases along
+	if _, err := store.Add(GPUAlias{Alias: "GPU-abc", Canonical: "GPU-def", MergedBy: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"0", "gpu_0", "GPU-abc", "GPU-def"} {
+		if got := store.Resolve(id); got != "GPU-def" {
+			t.Errorf("Resolve(%s) = %s, want GPU-def", id, got)
+		}
+	}
+
+	if _, err := store.Remove("gpu_0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Remove("gpu_0"); !errors.Is(err, ErrAliasNotFound) {
+		t.Errorf("Expected ErrAliasNotFound, got %v", err)
+	}
+
+	// Aliases survive a restart
+	restored := NewAliasStore(path)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.AliasesOf("GPU-def"); len(got) != 2 || got[0] != "0" || got[1] != "GPU-abc" {
+		t.Errorf("Expected aliases 0 and GPU-abc, got %v", got)
+	}
+	if got := restored.Resolve("gpu_0"); got != "gpu_0" {
+		t.Errorf("Expected the removed alias to resolve to itself, got %s", got)
+	}
+}
+
+func TestCollectorMergeGPU(t *testing.T) {
+	storage := persistencetest.NewTelemetryStore()
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		Writers:          1,
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		Storage:          storage,
+	})
+
+	ingest := func(payload string) {
+		t.Helper()
+		telemetry, err := c.decode(mq.Message{Payload: []byte(payload)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.store(telemetry)
+	}
+	ingest(`{"timestamp":"2025-07-18T20:42:34Z","fields":{"gpu_id":"gpu_0","Hostname":"host-1","metric_name":"util","value":10}}`)
+	ingest(`{"timestamp":"2025-07-18T20:42:35Z","fields":{"uuid":"GPU-abc","Hostname":"host-1","metric_name":"util","value":20}}`)
+
+	body := []byte(`{"alias":"gpu_0","canonical":"GPU-abc","merged_by":"alice","comment":"legacy ID"}`)
+	rr := httptest.NewRecorder()
+	c.serveGPUAliases(rr, httptest.NewRequest("POST", "/admin/gpu-aliases", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Samples received for the alias from now on go to the canonical GPU
+	ingest(`{"timestamp":"2025-07-18T20:42:36Z","fields":{"gpu_id":"gpu_0","Hostname":"host-1","metric_name":"util","value":30}}`)
+	if n := len(storage.Samples("gpu_0")); n != 1 {
+		t.Errorf("Expected no new samples stored under the alias, got %d", n)
+	}
+
+	if ids := c.memoryStorage.GetAllGPUIDs(); len(ids) != 1 || ids[0] != "GPU-abc" {
+		t.Errorf("Expected only GPU-abc cached, got %v", ids)
+	}
+	if cached := c.memoryStorage.GetTelemetryForGPU("GPU-abc"); len(cached) != 3 {
+		t.Errorf("Expected 3 cached samples for GPU-abc, got %d", len(cached))
+	}
+	if _, ok := c.health.All()["gpu_0"]; ok {
+		t.Error("Expected the alias dropped from GPU health")
+	}
+
+	// Reads of either ID return both files under the canonical ID
+	for _, id := range []string{"GPU-abc", "gpu_0"} {
+		data := c.GetTelemetryForGPU(id, 0)
+		if len(data) != 3 || data[0].Metrics["util"] != 10 || data[2].Metrics["util"] != 30 || data[0].GPUId != "GPU-abc" {
+			t.Errorf("Unexpected telemetry for %s: %+v", id, data)
+		}
+	}
+	if gpus := c.GetGPUsForHost("host-1"); len(gpus) != 1 || gpus[0] != "GPU-abc" {
+		t.Errorf("Expected host-1 to list GPU-abc once, got %v", gpus)
+	}
+
+	rr = httptest.NewRecorder()
+	c.serveGPUAlias(rr, httptest.NewRequest("DELETE", "/admin/gpu-aliases/missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rr.Code)
+	}
+}
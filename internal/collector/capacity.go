@@ -0,0 +1,179 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// Percentages of a limit at which a capacity is reported as a warning or as
+// critical
+const (
+	CapacityWarningPercent  = 80
+	CapacityCriticalPercent = 95
+)
+
+// Capacity statuses, from least to most severe
+const (
+	CapacityOK       = "ok"
+	CapacityWarning  = "warning"
+	CapacityCritical = "critical"
+)
+
+// capacityQueueTimeout bounds asking a remote broker for its queue depth
+const capacityQueueTimeout = 2 * time.Second
+
+// CapacityUsage is how much of one limit is in use
+type CapacityUsage struct {
+	// Name identifies the limit, e.g. memory, gpus or disk
+	Name string `json:"name"`
+	// Path is the directory a disk limit was measured for
+	Path string  `json:"path,omitempty"`
+	Used float64 `json:"used"`
+	// Limit is zero when there is no limit, and Percent then left out
+	Limit   float64  `json:"limit"`
+	Unit    string   `json:"unit"`
+	Percent *float64 `json:"percent,omitempty"`
+	Status  string   `json:"status"`
+	// Error is why the usage could not be measured
+	Error string `json:"error,omitempty"`
+}
+
+// CapacityReport summarizes how close the collector is to its limits
+type CapacityReport struct {
+	// Status is the most severe status of the limits
+	Status    string          `json:"status"`
+	Timestamp time.Time       `json:"timestamp"`
+	Limits    []CapacityUsage `json:"limits"`
+}
+
+// newCapacityUsage returns the usage of a limit, with its percentage and
+// status if it has a limit
+func newCapacityUsage(name string, used, limit float64, unit string) CapacityUsage {
+	usage := CapacityUsage{Name: name, Used: used, Limit: limit, Unit: unit, Status: CapacityOK}
+	if limit > 0 {
+		percent := math.Round(used/limit*1000) / 10
+		usage.Percent = &percent
+		switch {
+		case percent >= CapacityCriticalPercent:
+			usage.Status = CapacityCritical
+		case percent >= CapacityWarningPercent:
+			usage.Status = CapacityWarning
+		}
+	}
+	return usage
+}
+
+// Add adds a limit's usage, raising the report's status to the limit's
+func (r *CapacityReport) Add(usage CapacityUsage) {
+	r.Limits = append(r.Limits, usage)
+	if r.Status == "" || capacitySeverity(usage.Status) > capacitySeverity(r.Status) {
+		r.Status = usage.Status
+	}
+}
+
+func capacitySeverity(status string) int {
+	switch status {
+	case CapacityCritical:
+		return 2
+	case CapacityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// MemoryCapacity returns the process's memory use against its Go memory
+// limit (GOMEMLIMIT), which is the memory budget the runtime collects
+// garbage to stay within
+func MemoryCapacity(name string) CapacityUsage {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		limit = 0
+	}
+	return newCapacityUsage(name, float64(stats.Sys-stats.HeapReleased), float64(limit), "bytes")
+}
+
+// diskCapacity returns the use of the filesystem holding dir
+func diskCapacity(dir string) CapacityUsage {
+	used, size, err := persistence.DiskUsage(dir)
+	if err != nil {
+		return CapacityUsage{Name: "disk", Path: dir, Unit: "bytes", Status: CapacityOK, Error: err.Error()}
+	}
+	usage := newCapacityUsage("disk", float64(used), float64(size), "bytes")
+	usage.Path = dir
+	return usage
+}
+
+// Capacity reports the collector's memory, cardinality, broker queue and
+// disk use against their limits
+func (c *Collector) Capacity(ctx context.Context) CapacityReport {
+	report := CapacityReport{Timestamp: time.Now().UTC()}
+	report.Add(MemoryCapacity("memory"))
+
+	c.limiter.mu.Lock()
+	gpus, hosts := len(c.limiter.gpus), len(c.limiter.hosts)
+	c.limiter.mu.Unlock()
+	report.Add(newCapacityUsage("gpus", float64(gpus), float64(c.limiter.config.MaxGPUs), "gpus"))
+	report.Add(newCapacityUsage("hosts", float64(hosts), float64(c.limiter.config.MaxHosts), "hosts"))
+
+	if sizer, ok := c.broker.(mq.QueueSizer); ok {
+		ctx, cancel := context.WithTimeout(ctx, capacityQueueTimeout)
+		defer cancel()
+		depth, err := sizer.QueueSize(ctx, c.topic())
+		usage := newCapacityUsage("broker_queue", float64(depth), 0, "messages")
+		if err != nil {
+			usage.Error = err.Error()
+		}
+		report.Add(usage)
+	}
+
+	if c.quarantined != nil {
+		_, size := c.quarantined.Size()
+		usage := newCapacityUsage("quarantine", float64(size), float64(c.quarantined.maxBytes), "bytes")
+		usage.Path = c.config.Quarantine.Dir
+		report.Add(usage)
+	}
+
+	seen := make(map[string]bool)
+	for _, dir := range []string{c.config.DataDir, c.checkpointDir(), c.config.Tiering.ColdDir, c.config.Quarantine.Dir} {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		report.Add(diskCapacity(dir))
+	}
+	return report
+}
+
+// checkpointDir is the checkpoint directory, if checkpoints are written
+func (c *Collector) checkpointDir() string {
+	if !c.config.CheckpointEnabled {
+		return ""
+	}
+	return c.config.CheckpointDir
+}
+
+// serveCapacity handles GET /api/v1/capacity
+func (c *Collector) serveCapacity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.Capacity(r.Context())); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to encode capacity response", "error", err)
+	}
+}
@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestCapacityUsageStatus(t *testing.T) {
+	tests := []struct {
+		used, limit float64
+		status      string
+	}{
+		{50, 100, CapacityOK},
+		{80, 100, CapacityWarning},
+		{96, 100, CapacityCritical},
+		{1e6, 0, CapacityOK},
+	}
+	for _, tt := range tests {
+		usage := newCapacityUsage("gpus", tt.used, tt.limit, "gpus")
+		if usage.Status != tt.status {
+			t.Errorf("%v of %v: expected %s, got %s", tt.used, tt.limit, tt.status, usage.Status)
+		}
+		if (usage.Percent == nil) != (tt.limit == 0) {
+			t.Errorf("%v of %v: expected a percentage only with a limit, got %v", tt.used, tt.limit, usage.Percent)
+		}
+	}
+
+	var report CapacityReport
+	report.Add(newCapacityUsage("a", 99, 100, "gpus"))
+	report.Add(newCapacityUsage("b", 85, 100, "gpus"))
+	if report.Status != CapacityCritical {
+		t.Errorf("Expected the most severe status, got %s", report.Status)
+	}
+}
+
+func TestCollectorCapacity(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	c := NewCollector(broker, CollectorConfig{
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		MQTopic:          "telemetry",
+		Cardinality:      CardinalityConfig{MaxGPUs: 2},
+	})
+	for _, gpuID := range []string{"gpu_0", "gpu_1"} {
+		c.limiter.admit(&Telemetry{GPUId: gpuID, Metrics: map[string]float64{}, Timestamp: time.Now()})
+	}
+	if err := broker.Publish("telemetry", mq.Message{Payload: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	c.serveCapacity(rr, httptest.NewRequest("GET", "/api/v1/capacity", nil))
+	var report CapacityReport
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	limits := make(map[string]CapacityUsage)
+	for _, usage := range report.Limits {
+		limits[usage.Name] = usage
+	}
+	if gpus := limits["gpus"]; gpus.Used != 2 || gpus.Status != CapacityCritical || report.Status != CapacityCritical {
+		t.Errorf("Expected the GPU cap reached, got %+v in %s", gpus, report.Status)
+	}
+	if queue := limits["broker_queue"]; queue.Used != 1 || queue.Percent != nil {
+		t.Errorf("Expected one queued message without a limit, got %+v", queue)
+	}
+	if disk := limits["disk"]; disk.Path != c.config.DataDir || (disk.Error == "" && disk.Limit == 0) {
+		t.Errorf("Expected the data directory's filesystem measured, got %+v", disk)
+	}
+	if _, ok := limits["memory"]; !ok {
+		t.Error("Expected memory reported")
+	}
+}
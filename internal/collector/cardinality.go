@@ -0,0 +1,163 @@
+package collector
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+const (
+	// DefaultMaxGPUs caps the GPUs tracked by a collector
+	DefaultMaxGPUs = 10000
+	// DefaultMaxHosts caps the distinct hostnames tracked
+	DefaultMaxHosts = 2000
+	// DefaultMaxMetricsPerGPU caps the metric names tracked for one GPU
+	DefaultMaxMetricsPerGPU = 256
+	// OverflowHostname replaces hostnames beyond the host limit, so their
+	// GPUs are still stored but grouped under one host
+	OverflowHostname = "__overflow__"
+	// cardinalityWarnInterval rate limits the warning logged while a limit
+	// is being hit
+	cardinalityWarnInterval = time.Minute
+)
+
+// Cardinality limit names, used in metrics and logs
+const (
+	limitGPUs    = "gpus"
+	limitHosts   = "hosts"
+	limitMetrics = "metrics_per_gpu"
+)
+
+// CardinalityConfig caps what the collector tracks, so a misbehaving
+// producer emitting random GPU IDs, hostnames or metric names cannot exhaust
+// its memory. Zero means no limit.
+type CardinalityConfig struct {
+	// MaxGPUs rejects samples from new GPUs once reached
+	MaxGPUs int
+	// MaxHosts maps new hostnames to OverflowHostname once reached
+	MaxHosts int
+	// MaxMetricsPerGPU drops new metric names from a GPU once reached
+	MaxMetricsPerGPU int
+}
+
+// cardinalityLimiter admits telemetry within the configured limits
+type cardinalityLimiter struct {
+	config CardinalityConfig
+	logger *logger.Logger
+
+	mu         sync.Mutex
+	gpus       map[string]map[string]struct{} // GPU ID -> metric names
+	hosts      map[string]struct{}
+	lastWarned map[string]time.Time
+
+	rejected *metrics.CounterVec
+	events   *eventPublisher // nil when events are disabled
+}
+
+func newCardinalityLimiter(config CardinalityConfig, log *logger.Logger, registry *metrics.Registry) *cardinalityLimiter {
+	l := &cardinalityLimiter{
+		config:     config,
+		logger:     log,
+		gpus:       make(map[string]map[string]struct{}),
+		hosts:      make(map[string]struct{}),
+		lastWarned: make(map[string]time.Time),
+	}
+
+	l.rejected = registry.NewCounterVec("collector_cardinality_overflow_total",
+		"Samples, hostnames or metric values rejected or aggregated by a cardinality limit.", "limit")
+	registry.NewGaugeFunc("collector_cardinality_tracked",
+		"GPUs and hostnames currently tracked, per cardinality limit.", []string{"limit"},
+		func(emit func(float64, ...string)) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			emit(float64(len(l.gpus)), limitGPUs)
+			emit(float64(len(l.hosts)), limitHosts)
+		})
+	registry.NewGaugeFunc("collector_cardinality_limit",
+		"Configured cardinality limits; 0 means unlimited.", []string{"limit"},
+		func(emit func(float64, ...string)) {
+			emit(float64(config.MaxGPUs), limitGPUs)
+			emit(float64(config.MaxHosts), limitHosts)
+			emit(float64(config.MaxMetricsPerGPU), limitMetrics)
+		})
+	return l
+}
+
+// admit applies the limits to a sample, rewriting its hostname or dropping
+// metrics that overflow. It returns false if the whole sample must be
+// rejected because it comes from a GPU beyond the limit.
+func (l *cardinalityLimiter) admit(telemetry *Telemetry) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	known, ok := l.gpus[telemetry.GPUId]
+	if !ok {
+		if l.config.MaxGPUs > 0 && len(l.gpus) >= l.config.MaxGPUs {
+			l.overflow(limitGPUs, 1, "gpu_id", telemetry.GPUId)
+			return false
+		}
+		known = make(map[string]struct{})
+		l.gpus[telemetry.GPUId] = known
+	}
+
+	if telemetry.Hostname != "" {
+		if _, ok := l.hosts[telemetry.Hostname]; !ok {
+			if l.config.MaxHosts > 0 && len(l.hosts) >= l.config.MaxHosts {
+				l.overflow(limitHosts, 1, "hostname", telemetry.Hostname)
+				telemetry.Hostname = OverflowHostname
+			} else {
+				l.hosts[telemetry.Hostname] = struct{}{}
+			}
+		}
+	}
+
+	dropped := 0
+	for name := range telemetry.Metrics {
+		if _, ok := known[name]; ok {
+			continue
+		}
+		if l.config.MaxMetricsPerGPU > 0 && len(known) >= l.config.MaxMetricsPerGPU {
+			delete(telemetry.Metrics, name)
+			dropped++
+			continue
+		}
+		known[name] = struct{}{}
+	}
+	if dropped > 0 {
+		l.overflow(limitMetrics, dropped, "gpu_id", telemetry.GPUId)
+	}
+	return true
+}
+
+// overflow counts values over a limit and logs a warning at most once per
+// cardinalityWarnInterval per limit. Called with l.mu held.
+func (l *cardinalityLimiter) overflow(limit string, n int, key, value string) {
+	l.rejected.WithLabelValues(limit).Add(float64(n))
+
+	now := time.Now()
+	if now.Sub(l.lastWarned[limit]) < cardinalityWarnInterval {
+		return
+	}
+	l.lastWarned[limit] = now
+	l.logger.Warn("Cardinality limit reached",
+		"limit", limit,
+		"max", l.max(limit),
+		key, value,
+		"total_overflow", l.rejected.WithLabelValues(limit).Value())
+	l.events.emit(EventGuardrail, "Cardinality limit reached",
+		"guardrail", "cardinality", "limit", limit, "max", strconv.Itoa(l.max(limit)), key, value)
+}
+
+func (l *cardinalityLimiter) max(limit string) int {
+	switch limit {
+	case limitGPUs:
+		return l.config.MaxGPUs
+	case limitHosts:
+		return l.config.MaxHosts
+	default:
+		return l.config.MaxMetricsPerGPU
+	}
+}
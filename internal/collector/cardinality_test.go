@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestCardinalityLimits(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		Cardinality:      CardinalityConfig{MaxGPUs: 2, MaxHosts: 1, MaxMetricsPerGPU: 2},
+	})
+
+	send := func(gpuID, hostname, metric string) {
+		t.Helper()
+		payload, _ := json.Marshal(StreamerMessage{
+			Timestamp: time.Now(),
+			Fields:    map[string]interface{}{"gpu_id": gpuID, "Hostname": hostname, "metric_name": metric, "value": 1.0},
+		})
+		if err := c.handleMessage(0, mq.Message{Payload: payload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send("gpu_0", "host-a", "m1")
+	send("gpu_0", "host-a", "m2")
+	// A third metric name for gpu_0 is dropped
+	send("gpu_0", "host-a", "m3")
+	// A second hostname is grouped under the overflow host
+	send("gpu_1", "host-b", "m1")
+	// A third GPU is rejected outright
+	send("gpu_2", "host-a", "m1")
+
+	if gpus := c.memoryStorage.GetAllGPUIDs(); len(gpus) != 2 {
+		t.Errorf("Expected 2 GPUs stored, got %v", gpus)
+	}
+	if gpus := c.GetGPUsForHost(OverflowHostname); len(gpus) != 1 || gpus[0] != "gpu_1" {
+		t.Errorf("Expected gpu_1 under %s, got %v", OverflowHostname, gpus)
+	}
+	for _, metric := range c.catalog.List("gpu_0") {
+		if metric.Name == "m3" {
+			t.Error("Expected metric m3 to be dropped for gpu_0")
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	c.metrics.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	for limit, want := range map[string]int{limitGPUs: 1, limitHosts: 1, limitMetrics: 1} {
+		line := fmt.Sprintf(`collector_cardinality_overflow_total{limit="%s"} %d`, limit, want)
+		if !strings.Contains(rr.Body.String(), line) {
+			t.Errorf("Expected %q in metrics:\n%s", line, rr.Body.String())
+		}
+	}
+	if !strings.Contains(rr.Body.String(), `collector_cardinality_tracked{limit="gpus"} 2`) {
+		t.Errorf("Expected 2 tracked GPUs in metrics:\n%s", rr.Body.String())
+	}
+}
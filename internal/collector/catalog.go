@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+)
+
+// MetricInfo describes a metric name seen in telemetry
+type MetricInfo struct {
+	Name string `json:"name"`
+	// Unit is the unit the metric is reported in, if known
+	Unit string `json:"unit,omitempty"`
+	// Count is the number of samples carrying the metric
+	Count int64 `json:"count"`
+	// GPUs is the number of GPUs reporting the metric
+	GPUs     int       `json:"gpus"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// metricStat counts one metric for one GPU
+type metricStat struct {
+	count    int64
+	lastSeen time.Time
+}
+
+// MetricCatalog records the distinct metric names flowing through the
+// collector, per GPU, since it started
+type MetricCatalog struct {
+	mu      sync.RWMutex
+	metrics map[string]map[string]*metricStat // metric -> GPU ID -> stat
+}
+
+// NewMetricCatalog creates an empty catalog
+func NewMetricCatalog() *MetricCatalog {
+	return &MetricCatalog{metrics: make(map[string]map[string]*metricStat)}
+}
+
+// Observe records the metrics in a telemetry sample
+func (m *MetricCatalog) Observe(telemetry *Telemetry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name := range telemetry.Metrics {
+		gpus, ok := m.metrics[name]
+		if !ok {
+			gpus = make(map[string]*metricStat)
+			m.metrics[name] = gpus
+		}
+		stat, ok := gpus[telemetry.GPUId]
+		if !ok {
+			stat = &metricStat{}
+			gpus[telemetry.GPUId] = stat
+		}
+		stat.count++
+		if telemetry.Timestamp.After(stat.lastSeen) {
+			stat.lastSeen = telemetry.Timestamp
+		}
+	}
+}
+
+// Merge folds the counts of GPU alias into GPU canonical
+func (m *MetricCatalog) Merge(alias, canonical string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, gpus := range m.metrics {
+		from, ok := gpus[alias]
+		if !ok {
+			continue
+		}
+		delete(gpus, alias)
+		into, ok := gpus[canonical]
+		if !ok {
+			gpus[canonical] = from
+			continue
+		}
+		into.count += from.count
+		if from.lastSeen.After(into.lastSeen) {
+			into.lastSeen = from.lastSeen
+		}
+	}
+}
+
+// List returns the metrics seen, sorted by name. A non-empty gpuID limits the
+// catalog to that GPU's metrics.
+func (m *MetricCatalog) List(gpuID string) []MetricInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metrics := []MetricInfo{}
+	for name, gpus := range m.metrics {
+		info := MetricInfo{Name: name, Unit: MetricUnit(name)}
+		for id, stat := range gpus {
+			if gpuID != "" && id != gpuID {
+				continue
+			}
+			info.Count += stat.count
+			info.GPUs++
+			if stat.lastSeen.After(info.LastSeen) {
+				info.LastSeen = stat.lastSeen
+			}
+		}
+		if info.GPUs > 0 {
+			metrics = append(metrics, info)
+		}
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+	return metrics
+}
+
+// serveMetricCatalog handles GET /api/v1/metrics[?gpu_id=...]
+func (c *Collector) serveMetricCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	metrics := c.catalog.List(r.URL.Query().Get("gpu_id"))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"metrics": metrics,
+		"total":   len(metrics),
+	}); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to encode metric catalog response", "error", err)
+	}
+}
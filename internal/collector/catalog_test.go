@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestMetricCatalog(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	catalog := NewMetricCatalog()
+	catalog.Observe(&Telemetry{GPUId: "gpu_0", Timestamp: base, Metrics: map[string]float64{MetricGPUUtil: 50, MetricGPUTemp: 60}})
+	catalog.Observe(&Telemetry{GPUId: "gpu_0", Timestamp: base.Add(time.Minute), Metrics: map[string]float64{MetricGPUUtil: 55}})
+	catalog.Observe(&Telemetry{GPUId: "gpu_1", Timestamp: base.Add(2 * time.Minute), Metrics: map[string]float64{MetricGPUUtil: 10}})
+	// Late samples do not move last_seen back
+	catalog.Observe(&Telemetry{GPUId: "gpu_1", Timestamp: base, Metrics: map[string]float64{MetricGPUUtil: 10}})
+
+	metrics := catalog.List("")
+	if len(metrics) != 2 {
+		t.Fatalf("Expected 2 metrics, got %+v", metrics)
+	}
+	temp, util := metrics[0], metrics[1]
+	if temp.Name != MetricGPUTemp || temp.Count != 1 || temp.GPUs != 1 || !temp.LastSeen.Equal(base) {
+		t.Errorf("Unexpected %s entry %+v", MetricGPUTemp, temp)
+	}
+	if util.Name != MetricGPUUtil || util.Count != 4 || util.GPUs != 2 || !util.LastSeen.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("Unexpected %s entry %+v", MetricGPUUtil, util)
+	}
+
+	metrics = catalog.List("gpu_1")
+	if len(metrics) != 1 || metrics[0].Name != MetricGPUUtil || metrics[0].Count != 2 {
+		t.Errorf("Unexpected gpu_1 catalog %+v", metrics)
+	}
+	if metrics := catalog.List("gpu_9"); len(metrics) != 0 {
+		t.Errorf("Expected empty catalog for unknown GPU, got %+v", metrics)
+	}
+}
+
+func TestCollectorMetricCatalogEndpoint(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: t.TempDir(), MaxEntriesPerGPU: 10})
+
+	payload, _ := json.Marshal(StreamerMessage{
+		Timestamp: time.Now(),
+		Fields:    map[string]interface{}{"gpu_id": "gpu_0", "metric_name": MetricPowerUsage, "value": 250.0},
+	})
+	if err := c.handleMessage(0, mq.Message{Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	c.serveMetricCatalog(rr, httptest.NewRequest("GET", "/api/v1/metrics", nil))
+
+	var response struct {
+		Metrics []MetricInfo `json:"metrics"`
+		Total   int          `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 1 || response.Metrics[0].Name != MetricPowerUsage || response.Metrics[0].Count != 1 {
+		t.Errorf("Unexpected catalog %+v", response)
+	}
+}
@@ -2,16 +2,28 @@ package collector
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/httpcache"
 	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
+	"github.com/harishb93/telemetry-pipeline/internal/notify"
 	"github.com/harishb93/telemetry-pipeline/internal/persistence"
 )
 
@@ -21,14 +33,35 @@ type Telemetry struct {
 	Hostname  string             `json:"hostname"`
 	Metrics   map[string]float64 `json:"metrics"`
 	Timestamp time.Time          `json:"timestamp"`
+	// Labels annotate where the sample came from and how it was stored,
+	// e.g. its source file or sampling rate
+	Labels map[string]string `json:"labels,omitempty"`
+	// CorrelationID identifies the source record the streamer read the
+	// sample from
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
 
 // StreamerMessage represents the message format from the streamer
 type StreamerMessage struct {
-	Timestamp time.Time              `json:"timestamp"`
-	Fields    map[string]interface{} `json:"fields"`
+	Timestamp     time.Time              `json:"timestamp"`
+	Fields        map[string]interface{} `json:"fields"`
+	CorrelationID string                 `json:"correlation_id,omitempty"`
+	// Labels record the message's provenance, e.g. source_file
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
+const (
+	// DefaultAckBatchSize is how many processed messages are acknowledged
+	// together
+	DefaultAckBatchSize = 100
+	// DefaultAckBatchInterval bounds how long a processed message waits to be
+	// acknowledged when traffic is too light to fill a batch
+	DefaultAckBatchInterval = 100 * time.Millisecond
+	// DefaultCheckpointInterval is how often a writer updates its checkpoint
+	// when it stores too few messages to fill a checkpoint batch
+	DefaultCheckpointInterval = 30 * time.Second
+)
+
 // CollectorConfig holds configuration for the collector
 type CollectorConfig struct {
 	Workers           int
@@ -36,63 +69,322 @@ type CollectorConfig struct {
 	MaxEntriesPerGPU  int
 	CheckpointEnabled bool
 	CheckpointDir     string
-	HealthPort        string
-	MQTopic           string
+	// CheckpointInterval updates each writer's checkpoint at least this
+	// often while it stores messages, besides every 100 messages; zero uses
+	// DefaultCheckpointInterval
+	CheckpointInterval time.Duration
+	HealthPort         string
+	MQTopic            string
+	// Keyring encrypts telemetry files at rest when set
+	Keyring *encryption.Keyring
+	// Storage, Cache and Checkpoints replace the telemetry files in DataDir,
+	// the in-memory cache of MaxEntriesPerGPU samples per GPU and the
+	// checkpoint file at CheckpointDir when set, for example with the fakes
+	// in persistence/persistencetest. Checkpoints is only used when
+	// CheckpointEnabled is set.
+	Storage     persistence.TelemetryStore
+	Cache       persistence.TelemetryCache
+	Checkpoints persistence.CheckpointStore
+	// Redaction hashes or drops fields before storage and API exposure
+	Redaction     []RedactionRule
+	RedactionSalt string
+	// EnergyMaxGap is the longest power sample interval integrated into the
+	// daily energy metric; zero uses DefaultEnergyMaxGap
+	EnergyMaxGap time.Duration
+	// Health classifies GPUs from incoming telemetry
+	Health HealthConfig
+	// Reports writes daily per-host rollups when Reports.Dir is set
+	Reports ReportConfig
+	// Tiering moves aged telemetry out of the cache and to cold storage
+	Tiering TieringConfig
+	// Notifier delivers GPU health alerts to on-call systems when set
+	Notifier *notify.Dispatcher
+	// Cardinality caps the GPUs, hostnames and metric names tracked
+	Cardinality CardinalityConfig
+	// Sampling thins chatty metrics before storage
+	Sampling []SamplingRule
+	// AckBatchSize acknowledges processed messages in batches of this many,
+	// or after AckBatchInterval; 1 or less acknowledges each message as soon
+	// as it is processed
+	AckBatchSize     int
+	AckBatchInterval time.Duration
+	// Writers is the number of storage writers; each GPU's telemetry is
+	// always stored by the same one
+	Writers int
+	// Quarantine keeps messages that cannot be decoded when Quarantine.Dir
+	// is set
+	Quarantine QuarantineConfig
+	// MinFreeDiskBytes pauses the writers while the filesystem holding
+	// DataDir has less free space; zero never pauses them
+	MinFreeDiskBytes int64
+	// WALEnabled logs each batch of samples to a write-ahead log in DataDir,
+	// synced before their messages are acknowledged, so a sample is never
+	// acknowledged before it is durable
+	WALEnabled bool
+	// EventsTopic is the topic operational events such as ingest errors,
+	// guardrail activations and evictions are published to; empty disables
+	// them
+	EventsTopic string
 }
 
 // Collector handles telemetry data collection and persistence
 type Collector struct {
 	config        CollectorConfig
 	broker        mq.BrokerInterface
-	fileStorage   *persistence.FileStorage
-	memoryStorage *persistence.MemoryStorage
-	checkpointMgr *persistence.CheckpointManager
+	fileStorage   persistence.TelemetryStore
+	memoryStorage persistence.TelemetryCache
+	cold          persistence.ColdTelemetryStore // nil without cold storage
+	checkpointMgr persistence.CheckpointStore    // nil when checkpoints are disabled
+	redactor      *Redactor                      // nil when no redaction is configured
+	energy        *EnergyTracker
+	health        *HealthEvaluator
+	silences      *SilenceStore
+	aliases       *AliasStore
+	catalog       *MetricCatalog
+	limiter       *cardinalityLimiter
+	sampler       *Sampler // nil when no sampling is configured
+	consistency   *consistencyChecker
+	schemas       *SchemaRegistry
+	quarantined   *QuarantineStore       // nil when quarantine is disabled
+	disk          *persistence.DiskGuard // nil without a free space minimum
+	metrics       *metrics.Registry
+	writeQueues   []chan writeJob             // one per storage writer
+	wals          []*persistence.TelemetryWAL // one per storage writer, nil when disabled
+	events        *eventPublisher             // nil when events are disabled
 	ctx           context.Context
 	cancel        context.CancelFunc
 	logger        *logger.Logger
 	wg            sync.WaitGroup
+	workerWg      sync.WaitGroup // goroutines feeding the writers, which stop first
 	healthServer  *http.Server
+
+	// Held by MergeGPU, and shared by store, so no sample is stored under an
+	// alias while it is being merged
+	mergeMu sync.RWMutex
+
+	// Counts quarantined messages evicted to make room
+	quarantineEvicted *metrics.Counter
+
+	// Counts writer pauses for lack of free disk space
+	diskPauses *metrics.Counter
+
+	// Closes the writer queues once the workers have stopped
+	stopWritersOnce sync.Once
+
+	// Exclusive locks on the data directory and checkpoint file so a second
+	// collector pointed at the same paths fails fast instead of corrupting them
+	lockMu         sync.Mutex
+	dataLock       *persistence.LockFile
+	checkpointLock *persistence.LockFile
 }
 
 // NewCollector creates a new collector instance
 func NewCollector(broker mq.BrokerInterface, config CollectorConfig) *Collector {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	fileStorage := persistence.NewFileStorage(config.DataDir)
-	memoryStorage := persistence.NewMemoryStorage(config.MaxEntriesPerGPU)
+	fileStorage := config.Storage
+	if fileStorage == nil {
+		files := persistence.NewFileStorage(config.DataDir)
+		if config.Keyring != nil {
+			files.SetKeyring(config.Keyring)
+		}
+		if config.Tiering.ColdDir != "" {
+			files.SetColdStore(persistence.NewDirObjectStore(config.Tiering.ColdDir))
+		}
+		fileStorage = files
+	}
+	var cold persistence.ColdTelemetryStore
+	if config.Tiering.ColdDir != "" {
+		cold, _ = fileStorage.(persistence.ColdTelemetryStore)
+	}
+	memoryStorage := config.Cache
+	if memoryStorage == nil {
+		memoryStorage = persistence.NewMemoryStorage(config.MaxEntriesPerGPU)
+	}
 
-	var checkpointMgr *persistence.CheckpointManager
+	var checkpointMgr persistence.CheckpointStore
 	if config.CheckpointEnabled {
-		checkpointMgr = persistence.NewCheckpointManager(config.CheckpointDir)
+		checkpointMgr = config.Checkpoints
+		if checkpointMgr == nil {
+			checkpointMgr = persistence.NewCheckpointManager(config.CheckpointDir)
+		}
 	}
 
-	return &Collector{
+	var redactor *Redactor
+	if len(config.Redaction) > 0 {
+		redactor = NewRedactor(config.Redaction, config.RedactionSalt)
+	}
+
+	log := logger.NewFromEnv().WithComponent("collector")
+	registry := metrics.NewRegistry()
+
+	var sampler *Sampler
+	if len(config.Sampling) > 0 {
+		sampler = NewSampler(config.Sampling, registry)
+	}
+
+	c := &Collector{
 		config:        config,
 		broker:        broker,
 		fileStorage:   fileStorage,
 		memoryStorage: memoryStorage,
+		cold:          cold,
 		checkpointMgr: checkpointMgr,
+		redactor:      redactor,
+		energy:        NewEnergyTracker(filepath.Join(config.DataDir, energyStateFile), config.EnergyMaxGap),
+		health:        NewHealthEvaluator(config.Health),
+		silences:      NewSilenceStore(filepath.Join(config.DataDir, silenceStateFile)),
+		aliases:       NewAliasStore(filepath.Join(config.DataDir, aliasStateFile)),
+		catalog:       NewMetricCatalog(),
+		limiter:       newCardinalityLimiter(config.Cardinality, log, registry),
+		sampler:       sampler,
+		consistency:   newConsistencyChecker(registry),
+		schemas:       NewSchemaRegistry(registry),
+		metrics:       registry,
+		writeQueues:   newWriteQueues(config.Writers),
 		ctx:           ctx,
 		cancel:        cancel,
-		logger:        logger.NewFromEnv().WithComponent("collector"),
+		logger:        log,
+	}
+	c.registerWriterMetrics()
+	if config.EventsTopic != "" {
+		c.events = newEventPublisher(broker, config.EventsTopic, log, registry)
+		c.limiter.events = c.events
 	}
+	if config.MinFreeDiskBytes > 0 {
+		c.disk = persistence.NewDiskGuard(config.DataDir, uint64(config.MinFreeDiskBytes))
+		c.registerDiskMetrics()
+	}
+	if config.Quarantine.Dir != "" {
+		c.quarantined = NewQuarantineStore(config.Quarantine.Dir, config.Quarantine.MaxBytes, config.Keyring)
+		c.registerQuarantineMetrics()
+	}
+
+	// Count exchanges with an incompatible MQ service on /metrics
+	if guarded, ok := broker.(interface{ ProtocolGuard() *mq.ProtocolGuard }); ok {
+		guarded.ProtocolGuard().RegisterMetrics(registry)
+	}
+	return c
 }
 
 // Start begins collecting telemetry data with specified number of workers
 func (c *Collector) Start() error {
 	c.logger.Info("Collector starting", "workers", c.config.Workers)
 
+	if err := c.acquireLocks(); err != nil {
+		return err
+	}
+
+	// Bring data written by older versions up to the current layout
+	if err := c.migrateDataDir(); err != nil {
+		c.releaseLocks()
+		return err
+	}
+
+	if c.checkpointMgr != nil {
+		if err := c.compactCheckpoints(); err != nil {
+			if errors.Is(err, persistence.ErrNewerFormat) {
+				c.releaseLocks()
+				return err
+			}
+			c.logger.Warn("Failed to compact checkpoints", "path", c.config.CheckpointDir, "error", err)
+		}
+	}
+
+	// Repair telemetry files left with torn or corrupt records by a crash
+	recovered, err := c.fileStorage.Recover()
+	if err != nil {
+		c.releaseLocks()
+		return fmt.Errorf("failed to recover telemetry files: %w", err)
+	}
+	for _, r := range recovered {
+		c.logger.Warn("Truncated corrupt telemetry file",
+			"gpu_id", r.GPUId,
+			"bytes_discarded", r.BytesDiscarded,
+			"reason", r.Reason)
+	}
+
+	// Store samples acknowledged before a crash but not yet synced to the
+	// telemetry files
+	if err := c.replayWALs(); err != nil {
+		c.releaseLocks()
+		return err
+	}
+
+	if c.config.Reports.Dir != "" {
+		if err := os.MkdirAll(c.config.Reports.Dir, 0755); err != nil {
+			c.releaseLocks()
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+
+	// Restore the daily energy metric
+	if err := c.initEnergy(); err != nil {
+		c.releaseLocks()
+		return fmt.Errorf("failed to initialize energy metrics: %w", err)
+	}
+
+	if err := c.silences.Load(); err != nil {
+		c.releaseLocks()
+		return err
+	}
+	if err := c.aliases.Load(); err != nil {
+		c.releaseLocks()
+		return err
+	}
+	if c.quarantined != nil {
+		if err := c.quarantined.Load(); err != nil {
+			c.releaseLocks()
+			return err
+		}
+	}
+
+	if err := c.openWALs(); err != nil {
+		c.releaseLocks()
+		return err
+	}
+
 	// Start health server
 	if err := c.startHealthServer(); err != nil {
+		c.closeWALs()
+		c.releaseLocks()
 		return fmt.Errorf("failed to start health server: %w", err)
 	}
 
-	// Start worker goroutines
+	// Start the storage writers, then the workers decoding for them
+	c.events.start()
+	c.startWriters()
 	for i := 0; i < c.config.Workers; i++ {
-		c.wg.Add(1)
+		c.workerWg.Add(1)
 		go c.worker(i)
 	}
 
+	// Log GPU health changes
+	c.wg.Add(1)
+	go c.healthLoop()
+
+	// Store reservoir-sampled telemetry as sampling intervals end
+	if c.sampler != nil && c.sampler.hasReservoir() {
+		c.workerWg.Add(1)
+		go c.samplingLoop()
+	}
+
+	// Persist energy metrics periodically
+	c.wg.Add(1)
+	go c.energySaveLoop()
+
+	// Start daily report generation
+	if c.config.Reports.Dir != "" {
+		c.wg.Add(1)
+		go c.reportLoop()
+	}
+
+	// Move aged telemetry down the storage tiers
+	if c.config.Tiering.enabled() {
+		c.wg.Add(1)
+		go c.tieringLoop()
+	}
+
 	return nil
 }
 
@@ -109,112 +401,283 @@ func (c *Collector) Stop() {
 		}
 	}
 
-	// Stop workers
+	// Stop workers, then let the writers store what they have queued
 	c.cancel()
+	c.workerWg.Wait()
+	c.stopWriters()
 	c.wg.Wait()
 
+	// Keep samples still held in sampling reservoirs
+	if c.sampler != nil {
+		for _, telemetry := range c.sampler.Flush(time.Time{}) {
+			_ = c.store(telemetry)
+		}
+	}
+
+	if err := c.energy.Save(); err != nil {
+		c.logger.Error("Failed to save energy metrics", "error", err)
+	}
+
+	c.releaseLocks()
+	c.events.close()
+
 	c.logger.Info("Collector stopped")
 }
 
-// worker runs a single worker goroutine
+// acquireLocks takes the data directory and checkpoint locks
+func (c *Collector) acquireLocks() error {
+	c.lockMu.Lock()
+	defer c.lockMu.Unlock()
+
+	dataLock, err := persistence.AcquireDirLock(c.config.DataDir)
+	if err != nil {
+		return fmt.Errorf("cannot use data directory %s (is another collector running?): %w", c.config.DataDir, err)
+	}
+	c.dataLock = dataLock
+
+	if c.checkpointMgr != nil && c.config.Checkpoints == nil {
+		checkpointLock, err := persistence.AcquireLockFile(c.config.CheckpointDir + ".lock")
+		if err != nil {
+			_ = c.dataLock.Release()
+			c.dataLock = nil
+			return fmt.Errorf("cannot use checkpoint path %s (is another collector running?): %w", c.config.CheckpointDir, err)
+		}
+		c.checkpointLock = checkpointLock
+	}
+
+	return nil
+}
+
+// migrateDataDir migrates the data directory to the current layout, logging
+// progress, and fails if a newer version wrote it
+func (c *Collector) migrateDataDir() error {
+	format := persistence.TelemetryFormat
+	from, err := format.Migrate(c.config.DataDir, func(p persistence.MigrationProgress) {
+		c.logger.Info("Migrating data directory",
+			"data_dir", c.config.DataDir,
+			"to_version", p.Version,
+			"migration", p.Description,
+			"done", p.Done,
+			"total", p.Total)
+	})
+	if err != nil {
+		return err
+	}
+	if from < format.Version() {
+		c.logger.Info("Migrated data directory", "data_dir", c.config.DataDir,
+			"from_version", from, "to_version", format.Version())
+	}
+	return nil
+}
+
+// releaseLocks releases any locks taken by acquireLocks
+func (c *Collector) releaseLocks() {
+	c.lockMu.Lock()
+	defer c.lockMu.Unlock()
+
+	for _, lock := range []*persistence.LockFile{c.dataLock, c.checkpointLock} {
+		if err := lock.Release(); err != nil {
+			c.logger.Warn("Failed to release lock", "path", lock.Path(), "error", err)
+		}
+	}
+	c.dataLock = nil
+	c.checkpointLock = nil
+}
+
+// topic is the topic telemetry is consumed from
+func (c *Collector) topic() string {
+	if c.config.MQTopic == "" {
+		return "telemetry" // default topic
+	}
+	return c.config.MQTopic
+}
+
+// worker decodes messages and hands them to the storage writer for their GPU
 func (c *Collector) worker(workerID int) {
-	defer c.wg.Done()
+	defer c.workerWg.Done()
 	c.logger.Info("Worker started", "worker_id", workerID)
 
 	// Subscribe to telemetry topic with acknowledgment support
-	topic := c.config.MQTopic
-	if topic == "" {
-		topic = "telemetry" // default topic
-	}
-	ch, unsubscribe, err := c.broker.SubscribeWithAck(topic)
+	ch, unsubscribe, err := c.broker.SubscribeWithAck(c.topic())
 	if err != nil {
 		c.logger.Error("Worker failed to subscribe", "worker_id", workerID, "error", err)
 		return
 	}
 	defer unsubscribe()
 
-	// Load checkpoint if enabled
-	var lastOffset int64
-	if c.checkpointMgr != nil {
-		checkpointName := fmt.Sprintf("worker-%d", workerID)
-		if checkpoint, err := c.checkpointMgr.LoadCheckpoint(checkpointName); err == nil {
-			lastOffset = checkpoint.ProcessedCount
-			c.logger.Debug("Worker loaded checkpoint", "worker_id", workerID, "offset", lastOffset)
-		}
-	}
+	// Messages whose samples are dropped are acknowledged here; the rest
+	// are acknowledged by their writer once stored
+	acks := mq.NewAckBatcher(c.config.AckBatchSize, c.config.AckBatchInterval)
+	defer acks.Flush()
 
-	processedCount := 0
+	decodedCount := 0
 
 	for {
 		select {
 		case <-c.ctx.Done():
-			c.logger.Info("Worker stopping", "worker_id", workerID, "messages_processed", processedCount)
+			c.logger.Info("Worker stopping", "worker_id", workerID, "messages_decoded", decodedCount)
 			return
 		case msg := <-ch:
-			if err := c.handleMessage(workerID, msg); err != nil {
+			telemetry, err := c.decode(msg)
+			if err != nil {
 				c.logger.Error("Worker error handling message", "worker_id", workerID, "error", err)
-				// Don't acknowledge failed messages for potential retry
+				// Quarantined messages are kept for re-ingestion, so they
+				// need no retry
+				quarantined := c.quarantine(msg, err)
+				c.events.emit(EventIngestError, err.Error(), "message_id", msg.ID, "quarantined", strconv.FormatBool(quarantined))
+				if quarantined {
+					acks.Ack(msg)
+					continue
+				}
+				// Don't acknowledge failed messages for potential retry; a
+				// broker that takes nacks quarantines messages failing
+				// repeatedly
+				if msg.Nack != nil {
+					msg.Nack(err)
+				}
 				continue
 			}
+			decodedCount++
 
-			// Acknowledge successful processing
-			msg.Ack()
-			processedCount++
-
-			// Update checkpoint periodically
-			if c.checkpointMgr != nil && processedCount%100 == 0 {
-				checkpointName := fmt.Sprintf("worker-%d", workerID)
-				if err := c.checkpointMgr.UpdateProcessedCount(checkpointName, 100); err != nil {
-					c.logger.Error("Worker failed to update checkpoint", "worker_id", workerID, "error", err)
-				}
+			if telemetry == nil {
+				acks.Ack(msg)
+				continue
+			}
+			if !c.enqueue(writeJob{telemetry: telemetry, msg: &msg}) {
+				// Stopping; the unacknowledged message will be redelivered
+				continue
 			}
 
-			if processedCount%1000 == 0 {
-				c.logger.Debug("Worker batch processed", "worker_id", workerID, "messages_processed", processedCount)
+			if decodedCount%1000 == 0 {
+				c.logger.Debug("Worker batch processed", "worker_id", workerID, "messages_decoded", decodedCount)
 			}
 		}
 	}
 }
 
-// handleMessage processes a single telemetry message
+// handleMessage decodes and stores a single telemetry message on the calling
+// goroutine
 func (c *Collector) handleMessage(workerID int, msg mq.Message) error {
-	// Parse the JSON message from streamer
-	var streamerMsg StreamerMessage
-	if err := json.Unmarshal(msg.Payload, &streamerMsg); err != nil {
-		return fmt.Errorf("failed to unmarshal message: %w", err)
+	telemetry, err := c.decode(msg)
+	if err != nil {
+		return err
+	}
+	if telemetry != nil {
+		return c.store(telemetry)
+	}
+	return nil
+}
+
+// decode parses, redacts and normalizes a message into a sample ready to
+// store. It returns nil if the sample is dropped by the cardinality limits or
+// sampling.
+func (c *Collector) decode(msg mq.Message) (*Telemetry, error) {
+	// Parse the message with the decoder for the streamer's schema version
+	streamerMsg, err := c.schemas.Decode(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Redact before anything is stored or exposed
+	if c.redactor != nil {
+		c.redactor.Apply(streamerMsg.Fields)
 	}
 
 	// Convert to typed Telemetry struct
 	telemetry, err := c.convertToTelemetry(streamerMsg)
 	if err != nil {
-		return fmt.Errorf("failed to convert message: %w", err)
+		return nil, fmt.Errorf("failed to convert message: %w", err)
 	}
+	telemetry.GPUId = c.aliases.Resolve(telemetry.GPUId)
 
-	// Convert to persistence.Telemetry for file storage
-	persistenceTelemetry := persistence.Telemetry{
-		GPUId:     telemetry.GPUId,
-		Hostname:  telemetry.Hostname,
-		Metrics:   telemetry.Metrics,
-		Timestamp: telemetry.Timestamp,
+	// Drop samples from GPUs beyond the cardinality limit; they are
+	// acknowledged so a misbehaving producer cannot cause redelivery storms
+	if !c.limiter.admit(telemetry) {
+		return nil, nil
 	}
 
+	// Thin chatty metrics; samples held in a reservoir are stored when its
+	// interval ends
+	if c.sampler != nil {
+		return c.sampler.Sample(telemetry, time.Now()), nil
+	}
+	return telemetry, nil
+}
+
+// store persists a telemetry sample and feeds it to the derived views. A
+// sample file storage fails to write is left out of them too, so its message
+// can be redelivered without counting it twice.
+func (c *Collector) store(telemetry *Telemetry) error {
+	c.mergeMu.RLock()
+	defer c.mergeMu.RUnlock()
+
+	// The GPU may have been merged since the sample was decoded
+	telemetry.GPUId = c.aliases.Resolve(telemetry.GPUId)
+
+	// Convert to persistence.Telemetry for file storage
+	persistenceTelemetry := telemetry.record()
+
 	// Persist to file storage
 	if err := c.fileStorage.WriteTelemetry(persistenceTelemetry); err != nil {
-		c.logger.Error("Worker failed to write to file storage", "worker_id", workerID, "error", err)
-		// Continue processing even if file write fails
+		c.logger.Error("Failed to write to file storage", "gpu_id", telemetry.GPUId, "error", err)
+		c.events.emit(EventStoreError, err.Error(), "gpu_id", telemetry.GPUId)
+		return err
 	}
 
 	// Store in memory
 	c.memoryStorage.StoreTelemetry(persistenceTelemetry)
 
+	// Update GPU health, the metric catalog and the derived daily energy
+	// metric
+	c.health.Observe(telemetry)
+	c.catalog.Observe(telemetry)
+	if watts, ok := telemetry.Metrics[MetricPowerUsage]; ok {
+		c.energy.Add(telemetry.GPUId, watts, telemetry.Timestamp)
+	}
 	return nil
 }
 
+// record converts a sample to its stored form
+func (t *Telemetry) record() persistence.Telemetry {
+	return persistence.Telemetry{
+		GPUId:         t.GPUId,
+		Hostname:      t.Hostname,
+		Metrics:       t.Metrics,
+		Timestamp:     t.Timestamp,
+		Labels:        t.Labels,
+		CorrelationID: t.CorrelationID,
+	}
+}
+
+// samplingLoop hands the samples kept from reservoir intervals to the
+// writers as the intervals end
+func (c *Collector) samplingLoop() {
+	defer c.workerWg.Done()
+
+	ticker := time.NewTicker(samplingFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, telemetry := range c.sampler.Flush(now) {
+				if !c.enqueue(writeJob{telemetry: telemetry}) {
+					return
+				}
+			}
+		}
+	}
+}
+
 // convertToTelemetry converts a StreamerMessage to typed Telemetry
 func (c *Collector) convertToTelemetry(msg StreamerMessage) (*Telemetry, error) {
 	telemetry := &Telemetry{
-		Metrics:   make(map[string]float64),
-		Timestamp: msg.Timestamp,
+		Metrics:       make(map[string]float64),
+		Timestamp:     msg.Timestamp,
+		Labels:        msg.Labels,
+		CorrelationID: msg.CorrelationID,
 	}
 
 	// If timestamp is zero, use current time
@@ -324,7 +787,7 @@ func (c *Collector) startHealthServer() error {
 	// Health check endpoint
 	mux.HandleFunc("/health", corsHandler(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
 			return
 		}
 
@@ -332,54 +795,112 @@ func (c *Collector) startHealthServer() error {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		if _, err := w.Write([]byte(`{"status":"healthy","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`)); err != nil {
-			c.logger.Error("Failed to write health response", "error", err)
+			logger.FromContext(r.Context()).Error("Failed to write health response", "error", err)
 		}
 	}))
 
 	// Stats endpoint
 	mux.HandleFunc("/stats", corsHandler(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		stats := c.memoryStorage.GetStats()
+		if failover, ok := c.broker.(*mq.FailoverBrokerClient); ok {
+			stats["mq"] = failover.Stats()
+		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(stats); err != nil {
-			c.logger.Error("Failed to encode stats response", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Error("Failed to encode stats response", "error", err)
+			apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Internal server error")
 		}
 	}))
 
+	// Prometheus metrics
+	mux.Handle("/metrics", c.metrics.Handler())
+
+	// GPU listing with health
+	mux.HandleFunc("/api/v1/gpus", c.serveGPUHealth)
+
+	// Catalog of metric names seen
+	mux.HandleFunc("/api/v1/metrics", c.serveMetricCatalog)
+
+	// Capacity against limits
+	mux.HandleFunc("/api/v1/capacity", c.serveCapacity)
+
+	// Latest metric values of every GPU, now or as of a past time
+	mux.HandleFunc("/api/v1/fleet", c.serveFleet)
+
+	// Alert silences
+	mux.HandleFunc("/api/v1/silences", c.serveSilences)
+	mux.HandleFunc("/api/v1/silences/", c.serveSilence)
+
+	// Cache and file storage consistency checks
+	mux.HandleFunc("/admin/consistency", c.serveConsistency)
+
+	// Merging GPUs reported under several IDs
+	mux.HandleFunc("/admin/gpu-aliases", c.serveGPUAliases)
+	mux.HandleFunc("/admin/gpu-aliases/", c.serveGPUAlias)
+
+	// Messages that could not be decoded
+	mux.HandleFunc("/admin/quarantine", c.serveQuarantine)
+	mux.HandleFunc("/admin/quarantine/", c.serveQuarantinedMessage)
+
 	// Telemetry endpoint for specific GPU
 	mux.HandleFunc("/api/v1/gpus/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		// Parse GPU ID from URL path: /api/v1/gpus/{gpu_id}/telemetry
 		path := r.URL.Path
 		if len(path) < 15 { // Minimum: "/api/v1/gpus/x/"
-			http.Error(w, "Invalid GPU ID", http.StatusBadRequest)
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidRequest, "Invalid GPU ID")
 			return
 		}
 
 		// Extract GPU ID and check for telemetry suffix
 		parts := strings.Split(strings.Trim(path, "/"), "/")
 		if len(parts) < 4 || parts[0] != "api" || parts[1] != "v1" || parts[2] != "gpus" {
-			http.Error(w, "Invalid path format", http.StatusBadRequest)
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidRequest, "Invalid path format")
 			return
 		}
 
 		gpuID := parts[3]
+		if len(parts) > 4 && parts[4] == "energy" {
+			c.serveEnergy(w, r, gpuID)
+			return
+		}
 		if len(parts) > 4 && parts[4] != "telemetry" {
-			http.Error(w, "Invalid endpoint", http.StatusBadRequest)
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidRequest, "Invalid endpoint")
 			return
 		}
 
+		// Cold segments ending before start_time need not be read
+		var since time.Time
+		if v := r.URL.Query().Get("start_time"); v != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, v); err != nil {
+				apierror.Write(w, http.StatusBadRequest, apierror.InvalidTimeRange, "Invalid start_time")
+				return
+			}
+		}
+
 		// Get telemetry data for the GPU
-		telemetryData := c.GetTelemetryForGPU(gpuID, 100) // Get last 100 entries
+		telemetryData, tiers := c.readTelemetry(gpuID, since, 100) // Get last 100 entries
+		if len(tiers) > 0 {
+			w.Header().Set(TiersHeader, strings.Join(tiers, ","))
+		}
+
+		// Let pollers that already have this data skip the payload
+		etag, lastModified := telemetryValidators(telemetryData)
+		httpcache.SetValidators(w, etag, lastModified)
+		if httpcache.NotModified(r, etag, lastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(map[string]interface{}{
@@ -387,15 +908,15 @@ func (c *Collector) startHealthServer() error {
 			"total":  len(telemetryData),
 			"gpu_id": gpuID,
 		}); err != nil {
-			c.logger.Error("Failed to encode telemetry response", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Error("Failed to encode telemetry response", "error", err)
+			apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Internal server error")
 		}
 	})
 
 	// Hosts endpoint
 	mux.HandleFunc("/api/v1/hosts", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
 			return
 		}
 
@@ -405,35 +926,35 @@ func (c *Collector) startHealthServer() error {
 			"hosts": hosts,
 			"total": len(hosts),
 		}); err != nil {
-			c.logger.Error("Failed to encode hosts response", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Error("Failed to encode hosts response", "error", err)
+			apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Internal server error")
 		}
 	})
 
 	// Host GPUs endpoint
 	mux.HandleFunc("/api/v1/hosts/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		// Parse hostname from URL path: /api/v1/hosts/{hostname}/gpus
 		path := r.URL.Path
 		if len(path) < 16 { // Minimum: "/api/v1/hosts/x/"
-			http.Error(w, "Invalid hostname", http.StatusBadRequest)
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidRequest, "Invalid hostname")
 			return
 		}
 
 		// Extract hostname and check for gpus suffix
 		parts := strings.Split(strings.Trim(path, "/"), "/")
 		if len(parts) < 4 || parts[0] != "api" || parts[1] != "v1" || parts[2] != "hosts" {
-			http.Error(w, "Invalid path format", http.StatusBadRequest)
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidRequest, "Invalid path format")
 			return
 		}
 
 		hostname := parts[3]
 		if len(parts) > 4 && parts[4] != "gpus" {
-			http.Error(w, "Invalid endpoint", http.StatusBadRequest)
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidRequest, "Invalid endpoint")
 			return
 		}
 
@@ -446,19 +967,24 @@ func (c *Collector) startHealthServer() error {
 			"gpus":     gpus,
 			"total":    len(gpus),
 		}); err != nil {
-			c.logger.Error("Failed to encode host GPUs response", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Error("Failed to encode host GPUs response", "error", err)
+			apierror.Write(w, http.StatusInternalServerError, apierror.Internal, "Internal server error")
 		}
 	})
 
 	c.healthServer = &http.Server{
-		Addr:    ":" + c.config.HealthPort,
-		Handler: mux,
+		Addr:    netutil.ListenAddress(c.config.HealthPort),
+		Handler: c.requestLoggerMiddleware(mux),
+	}
+
+	listener, err := netutil.Listen(c.config.HealthPort)
+	if err != nil {
+		return err
 	}
 
 	go func() {
-		c.logger.Info("Health server starting", "port", c.config.HealthPort)
-		if err := c.healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		c.logger.Info("Health server starting", "address", c.healthServer.Addr)
+		if err := c.healthServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 			c.logger.Error("Health server error", "error", err)
 		}
 	}()
@@ -466,27 +992,96 @@ func (c *Collector) startHealthServer() error {
 	return nil
 }
 
+// requestLoggerMiddleware stores a logger carrying the request's ID, route
+// and tenant in the request context, for handlers to log with
+// logger.FromContext. The API gateway forwards its trace ID and tenant, so
+// the collector's logs correlate with the gateway's.
+func (c *Collector) requestLoggerMiddleware(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields := []interface{}{"request_id", requestID(r)}
+		if _, route := mux.Handler(r); route != "" {
+			fields = append(fields, "route", route)
+		}
+		if tenant := r.Header.Get("X-Tenant-ID"); tenant != "" && len(tenant) <= maxRequestIDLength {
+			fields = append(fields, "tenant", tenant)
+		}
+		mux.ServeHTTP(w, r.WithContext(logger.NewContext(r.Context(), c.logger.With(fields...))))
+	})
+}
+
+// maxRequestIDLength bounds client-supplied IDs so they cannot bloat logs
+const maxRequestIDLength = 64
+
+// requestID returns the trace ID the API gateway sent with r, or a new ID
+func requestID(r *http.Request) string {
+	for _, header := range []string{"X-Trace-ID", "X-Request-ID"} {
+		if id := r.Header.Get(header); id != "" && len(id) <= maxRequestIDLength {
+			return id
+		}
+	}
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
 // GetMemoryStats returns current memory storage statistics
 func (c *Collector) GetMemoryStats() map[string]interface{} {
 	return c.memoryStorage.GetStats()
 }
 
-// GetTelemetryForGPU returns telemetry data for a specific GPU
+// GetTelemetryForGPU returns telemetry data for a specific GPU, from cold
+// storage as well as the telemetry files when tiering is enabled. A merged
+// GPU's telemetry includes the files of its aliases, in timestamp order and
+// under its canonical ID.
 func (c *Collector) GetTelemetryForGPU(gpuID string, limit int) []*Telemetry {
-	persistenceDataRaw, err := c.fileStorage.ReadTelemetryFile(gpuID)
-	if err != nil {
-		c.logger.Error("Failed to read telemetry file", "error", err)
-		return []*Telemetry{}
-	}
-	// Convert []json.RawMessage to []Telemetry
+	data, _ := c.readTelemetry(gpuID, time.Time{}, limit)
+	return data
+}
+
+// readTelemetry returns a GPU's telemetry like GetTelemetryForGPU, skipping
+// cold segments that end before since, along with the tiers that held any of
+// it
+func (c *Collector) readTelemetry(gpuID string, since time.Time, limit int) ([]*Telemetry, []string) {
+	ids := c.gpuFiles(gpuID)
 	var persistenceData []Telemetry
-	for _, rawMsg := range persistenceDataRaw {
-		var tel Telemetry
-		if err := json.Unmarshal(rawMsg, &tel); err != nil {
-			c.logger.Error("Failed to unmarshal telemetry data", "error", err)
-			continue
+	var tiers []string
+	read := func(tier string, records []json.RawMessage) {
+		if len(records) > 0 && (len(tiers) == 0 || tiers[len(tiers)-1] != tier) {
+			tiers = append(tiers, tier)
+		}
+		// Convert []json.RawMessage to []Telemetry
+		for _, rawMsg := range records {
+			var tel Telemetry
+			if err := json.Unmarshal(rawMsg, &tel); err != nil {
+				c.logger.Error("Failed to unmarshal telemetry data", "error", err)
+				continue
+			}
+			tel.GPUId = ids[0]
+			persistenceData = append(persistenceData, tel)
+		}
+	}
+	if c.cold != nil {
+		for _, id := range ids {
+			records, err := c.cold.ReadColdTelemetry(id, since)
+			if err != nil {
+				c.logger.Error("Failed to read cold telemetry", "gpu_id", id, "error", err)
+				continue
+			}
+			read(TierCold, records)
+		}
+	}
+	for _, id := range ids {
+		records, err := c.fileStorage.ReadTelemetryFile(id)
+		if err != nil {
+			c.logger.Error("Failed to read telemetry file", "error", err)
+			return []*Telemetry{}, nil
 		}
-		persistenceData = append(persistenceData, tel)
+		read(TierWarm, records)
+	}
+	if len(ids) > 1 || len(tiers) > 1 {
+		sort.SliceStable(persistenceData, func(i, j int) bool {
+			return persistenceData[i].Timestamp.Before(persistenceData[j].Timestamp)
+		})
 	}
 	// Convert and apply limit
 	var result []*Telemetry
@@ -495,14 +1090,55 @@ func (c *Collector) GetTelemetryForGPU(gpuID string, limit int) []*Telemetry {
 			break
 		}
 		tel := &Telemetry{
-			GPUId:     pTel.GPUId,
-			Hostname:  pTel.Hostname,
-			Metrics:   pTel.Metrics,
-			Timestamp: pTel.Timestamp,
+			GPUId:         pTel.GPUId,
+			Hostname:      pTel.Hostname,
+			Metrics:       pTel.Metrics,
+			Timestamp:     pTel.Timestamp,
+			Labels:        pTel.Labels,
+			CorrelationID: pTel.CorrelationID,
 		}
 		result = append(result, tel)
 	}
-	return result
+	return result, tiers
+}
+
+// telemetryValidators derives a cheap ETag from the entry count and latest
+// timestamp of data, which is enough to detect appended entries, and returns
+// the latest timestamp as the Last-Modified time
+func telemetryValidators(data []*Telemetry) (string, time.Time) {
+	var last time.Time
+	for _, tel := range data {
+		if tel.Timestamp.After(last) {
+			last = tel.Timestamp
+		}
+	}
+	var lastNanos int64
+	if !last.IsZero() {
+		lastNanos = last.UnixNano()
+	}
+	return fmt.Sprintf(`"%x-%x"`, len(data), lastNanos), last
+}
+
+// knownGPUs returns the GPUs with cached or stored telemetry, sorted, so
+// GPUs whose samples have left the cache are still found
+func (c *Collector) knownGPUs() []string {
+	gpuIDs := c.memoryStorage.GetAllGPUIDs()
+	stored, err := c.fileStorage.ListGPUFiles()
+	if err != nil {
+		c.logger.Error("Failed to list telemetry files", "error", err)
+	}
+	for _, gpuID := range stored {
+		gpuIDs = append(gpuIDs, c.aliases.Resolve(gpuID))
+	}
+	sort.Strings(gpuIDs)
+
+	known := gpuIDs[:0]
+	for i, gpuID := range gpuIDs {
+		if i == 0 || gpuID != gpuIDs[i-1] {
+			known = append(known, gpuID)
+		}
+	}
+	return known
 }
 
 // GetAllHosts returns all unique hostnames that have telemetry data
@@ -513,10 +1149,21 @@ func (c *Collector) GetAllHosts() []string {
 	return []string{}
 }
 
-// GetGPUsForHost returns all GPU IDs associated with a specific hostname
+// GetGPUsForHost returns all GPU IDs associated with a specific hostname,
+// listing merged GPUs once under their canonical ID
 func (c *Collector) GetGPUsForHost(hostname string) []string {
-	if gpus, err := c.fileStorage.GetGPUsForHost(hostname); err == nil {
-		return gpus
+	gpus, err := c.fileStorage.GetGPUsForHost(hostname)
+	if err != nil {
+		return []string{}
 	}
-	return []string{}
+	seen := make(map[string]bool, len(gpus))
+	canonical := make([]string, 0, len(gpus))
+	for _, gpuID := range gpus {
+		gpuID = c.aliases.Resolve(gpuID)
+		if !seen[gpuID] {
+			seen[gpuID] = true
+			canonical = append(canonical, gpuID)
+		}
+	}
+	return canonical
 }
@@ -1,12 +1,21 @@
 package collector
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
 )
 
 func TestCollectorConfig_DefaultValues(t *testing.T) {
@@ -192,3 +201,79 @@ func TestCollector_ConfigValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestCollector_DataDirLock(t *testing.T) {
+	dataDir := t.TempDir()
+	config := CollectorConfig{
+		Workers:           1,
+		DataDir:           dataDir,
+		MaxEntriesPerGPU:  100,
+		CheckpointEnabled: true,
+		CheckpointDir:     dataDir + "/checkpoints",
+		HealthPort:        "8288",
+		MQTopic:           "test-lock",
+	}
+
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	first := NewCollector(broker, config)
+	if err := first.Start(); err != nil {
+		t.Fatalf("First collector failed to start: %v", err)
+	}
+
+	config.HealthPort = "8289"
+	second := NewCollector(broker, config)
+	if err := second.Start(); err == nil {
+		second.Stop()
+		t.Fatal("Expected second collector on the same data directory to fail")
+	}
+
+	first.Stop()
+
+	// The directory is usable again once the first collector stops
+	third := NewCollector(broker, config)
+	if err := third.Start(); err != nil {
+		t.Fatalf("Collector failed to start after lock release: %v", err)
+	}
+	third.Stop()
+}
+
+func TestCollector_RefusesNewerDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	newer := `{"format":"telemetry","version":99}`
+	if err := os.WriteFile(filepath.Join(dataDir, persistence.FormatVersionFile), []byte(newer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	c := NewCollector(broker, CollectorConfig{Workers: 1, DataDir: dataDir, MaxEntriesPerGPU: 100, HealthPort: "8290"})
+	if err := c.Start(); !errors.Is(err, persistence.ErrNewerFormat) {
+		if err == nil {
+			c.Stop()
+		}
+		t.Fatalf("Expected a data directory from a newer version to be refused, got %v", err)
+	}
+}
+
+func TestCollector_RequestLoggerMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	c := &Collector{logger: logger.New(logger.Config{Level: logger.INFO, Format: "text", Output: &buf})}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/hosts/", func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("deep call site")
+	})
+	req := httptest.NewRequest("GET", "/api/v1/hosts/node-1/gpus", nil)
+	req.Header.Set("X-Trace-ID", "trace-1")
+	req.Header.Set("X-Tenant-ID", "acme")
+	c.requestLoggerMiddleware(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, want := range []string{"request_id=trace-1", "route=/api/v1/hosts/", "tenant=acme"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Log %q missing %q", buf.String(), want)
+		}
+	}
+}
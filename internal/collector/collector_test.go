@@ -2,11 +2,14 @@ package collector
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
 	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence/persistencetest"
 )
 
 // TestTelemetryConversion tests the conversion from StreamerMessage to Telemetry
@@ -517,3 +520,146 @@ func TestGetGPUsForHost(t *testing.T) {
 		t.Errorf("Expected 0 GPUs for non-existent host, got %d", len(gpus))
 	}
 }
+
+// TestTelemetryEndpointConditional tests ETag and Last-Modified handling on the
+// per-GPU telemetry endpoint
+func TestTelemetryEndpointConditional(t *testing.T) {
+	config := CollectorConfig{
+		Workers:           1,
+		DataDir:           t.TempDir(),
+		MaxEntriesPerGPU:  100,
+		CheckpointEnabled: false,
+		HealthPort:        "8294",
+	}
+
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	collector := NewCollector(broker, config)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := func(ts time.Time) {
+		msgBytes, err := json.Marshal(StreamerMessage{
+			Timestamp: ts,
+			Fields:    map[string]interface{}{"gpu_id": "gpu_etag", "temperature": 70.0},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := collector.handleMessage(1, mq.Message{Payload: msgBytes, Ack: func() {}}); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+	}
+	store(base)
+	store(base.Add(time.Minute))
+
+	if err := collector.startHealthServer(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = collector.healthServer.Close() })
+	time.Sleep(100 * time.Millisecond)
+
+	get := func(header, value string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", "http://localhost:8294/api/v1/gpus/gpu_etag/telemetry", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	resp := get("", "")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with ETag, got %d %q", resp.StatusCode, etag)
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != base.Add(time.Minute).Format(http.TimeFormat) {
+		t.Errorf("Expected Last-Modified of latest entry, got %q", lm)
+	}
+
+	if resp := get("If-None-Match", etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for matching ETag, got %d", resp.StatusCode)
+	}
+	if resp := get("If-Modified-Since", base.Add(time.Hour).Format(http.TimeFormat)); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for If-Modified-Since, got %d", resp.StatusCode)
+	}
+
+	// New data changes the ETag
+	store(base.Add(2 * time.Minute))
+	if resp := get("If-None-Match", etag); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 after new data, got %d", resp.StatusCode)
+	}
+}
+
+func TestCollectorInjectedStores(t *testing.T) {
+	storage := persistencetest.NewTelemetryStore()
+	checkpoints := persistencetest.NewCheckpointStore()
+	if err := checkpoints.SaveCheckpoint("writer-7", &persistence.Checkpoint{ProcessedCount: 500}); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		Writers:           1,
+		DataDir:           t.TempDir(),
+		MaxEntriesPerGPU:  10,
+		CheckpointEnabled: true,
+		Storage:           storage,
+		Checkpoints:       checkpoints,
+	})
+
+	now := time.Now().UTC()
+	c.store(&Telemetry{GPUId: "gpu_0", Hostname: "host-1", Timestamp: now, Metrics: map[string]float64{MetricGPUUtil: 50}})
+	if samples := storage.Samples("gpu_0"); len(samples) != 1 || samples[0].Hostname != "host-1" {
+		t.Errorf("Expected the sample in the injected storage, got %+v", samples)
+	}
+	if data := c.GetTelemetryForGPU("gpu_0", 10); len(data) != 1 || !data[0].Timestamp.Equal(now) {
+		t.Errorf("Expected reads from the injected storage, got %+v", data)
+	}
+	if hosts := c.GetAllHosts(); len(hosts) != 1 || hosts[0] != "host-1" {
+		t.Errorf("Expected host-1, got %v", hosts)
+	}
+
+	// A failed write is reported and the sample left out of the cache, for
+	// its message to be redelivered
+	storage.FailWrites(errors.New("disk full"))
+	if err := c.store(&Telemetry{GPUId: "gpu_0", Timestamp: now.Add(time.Second), Metrics: map[string]float64{MetricGPUUtil: 60}}); err == nil {
+		t.Error("Expected the failed write reported")
+	}
+	if len(storage.Samples("gpu_0")) != 1 || len(c.memoryStorage.GetTelemetryForGPU("gpu_0")) != 1 {
+		t.Error("Expected the failed write skipped and the sample not cached")
+	}
+
+	if err := c.compactCheckpoints(); err != nil {
+		t.Fatal(err)
+	}
+	if retired, err := checkpoints.LoadCheckpoint(retiredCheckpoint); err != nil || retired.ProcessedCount != 500 {
+		t.Errorf("Expected writer-7 retired into the injected checkpoint store, got %+v, %v", retired, err)
+	}
+}
+
+func TestCorrelationIDStored(t *testing.T) {
+	storage := persistencetest.NewTelemetryStore()
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		Writers:          1,
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		Storage:          storage,
+	})
+
+	payload := []byte(`{"timestamp":"2025-07-18T20:42:34Z","fields":{"uuid":"GPU-1","Hostname":"host-1","metric_name":"DCGM_FI_DEV_GPU_UTIL","value":87},"correlation_id":"RUN.0.0.0.2","labels":{"source_instance":"streamer-0","source_file":"a.csv","source_row":"2"}}`)
+	telemetry, err := c.decode(mq.Message{Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.store(telemetry)
+
+	data := c.GetTelemetryForGPU("GPU-1", 10)
+	if len(data) != 1 || data[0].CorrelationID != "RUN.0.0.0.2" {
+		t.Errorf("Expected the stored sample to keep its correlation ID, got %+v", data)
+	}
+	if len(data) == 1 && (data[0].Labels["source_file"] != "a.csv" || data[0].Labels["source_row"] != "2") {
+		t.Errorf("Expected the stored sample to keep its provenance labels, got %v", data[0].Labels)
+	}
+}
@@ -0,0 +1,284 @@
+package collector
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// DefaultConsistencySample is how many GPUs a consistency check compares
+// when no sample size is given
+const DefaultConsistencySample = 10
+
+// maxConsistencyExamples caps the divergent timestamps listed per GPU
+const maxConsistencyExamples = 10
+
+// ConsistencyReport is the outcome of comparing cached telemetry with file
+// storage for a sample of GPUs
+type ConsistencyReport struct {
+	StartedAt     time.Time        `json:"started_at"`
+	Duration      string           `json:"duration"`
+	GPUsTotal     int              `json:"gpus_total"`
+	GPUsChecked   int              `json:"gpus_checked"`
+	GPUsDivergent int              `json:"gpus_divergent"`
+	GPUs          []GPUConsistency `json:"gpus"`
+}
+
+// GPUConsistency compares one GPU's cached samples with the samples in its
+// file over the window the cache covers. Samples are matched by timestamp.
+type GPUConsistency struct {
+	GPUId        string    `json:"gpu_id"`
+	WindowStart  time.Time `json:"window_start"`
+	WindowEnd    time.Time `json:"window_end"`
+	MemoryPoints int       `json:"memory_points"`
+	FilePoints   int       `json:"file_points"`
+	// MissingInFile are cached samples with no sample in the file
+	MissingInFile int `json:"missing_in_file"`
+	// MissingInMemory are samples in the file the cache does not hold
+	MissingInMemory int `json:"missing_in_memory"`
+	// Duplicates are extra samples with the same timestamp, in either store
+	Duplicates int `json:"duplicates"`
+	// Mismatched are samples in both stores whose values differ
+	Mismatched int `json:"mismatched"`
+	// Unreadable are file records that are not valid telemetry
+	Unreadable int         `json:"unreadable"`
+	Examples   []time.Time `json:"examples,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Divergent reports whether the stores disagree for the GPU
+func (g *GPUConsistency) Divergent() bool {
+	return g.MissingInFile+g.MissingInMemory+g.Duplicates+g.Mismatched+g.Unreadable > 0 || g.Error != ""
+}
+
+// consistencyChecker runs consistency checks and keeps the latest report
+type consistencyChecker struct {
+	running     sync.Mutex
+	mu          sync.Mutex
+	last        *ConsistencyReport
+	divergences *metrics.CounterVec
+	rand        *rand.Rand
+}
+
+func newConsistencyChecker(registry *metrics.Registry) *consistencyChecker {
+	return &consistencyChecker{
+		divergences: registry.NewCounterVec("collector_consistency_divergences_total",
+			"Samples on which cached telemetry and file storage disagreed in consistency checks, by kind.", "kind"),
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// VerifyConsistency compares the cached telemetry of up to sample randomly
+// chosen GPUs, or every GPU if sample is 0 or less, with file storage over
+// the window each GPU's cache covers. It returns false without checking if a
+// check is already running.
+func (c *Collector) VerifyConsistency(sample int) (*ConsistencyReport, bool) {
+	checker := c.consistency
+	if !checker.running.TryLock() {
+		return nil, false
+	}
+	defer checker.running.Unlock()
+
+	report := &ConsistencyReport{StartedAt: time.Now()}
+	gpuIDs := c.memoryStorage.GetAllGPUIDs()
+	sort.Strings(gpuIDs)
+	report.GPUsTotal = len(gpuIDs)
+	if sample > 0 && sample < len(gpuIDs) {
+		checker.rand.Shuffle(len(gpuIDs), func(i, j int) { gpuIDs[i], gpuIDs[j] = gpuIDs[j], gpuIDs[i] })
+		gpuIDs = gpuIDs[:sample]
+		sort.Strings(gpuIDs)
+	}
+
+	for _, gpuID := range gpuIDs {
+		result := c.compareGPU(gpuID)
+		report.GPUsChecked++
+		if result.Divergent() {
+			report.GPUsDivergent++
+			checker.divergences.WithLabelValues("missing_in_file").Add(float64(result.MissingInFile))
+			checker.divergences.WithLabelValues("missing_in_memory").Add(float64(result.MissingInMemory))
+			checker.divergences.WithLabelValues("duplicate").Add(float64(result.Duplicates))
+			checker.divergences.WithLabelValues("mismatched").Add(float64(result.Mismatched))
+			checker.divergences.WithLabelValues("unreadable").Add(float64(result.Unreadable))
+			c.logger.Warn("Telemetry cache and file storage diverge",
+				"gpu_id", gpuID,
+				"missing_in_file", result.MissingInFile,
+				"missing_in_memory", result.MissingInMemory,
+				"duplicates", result.Duplicates,
+				"mismatched", result.Mismatched,
+				"unreadable", result.Unreadable,
+				"error", result.Error)
+		}
+		report.GPUs = append(report.GPUs, result)
+	}
+	report.Duration = time.Since(report.StartedAt).String()
+
+	c.logger.Info("Consistency check finished",
+		"gpus_checked", report.GPUsChecked,
+		"gpus_divergent", report.GPUsDivergent,
+		"duration", report.Duration)
+
+	checker.mu.Lock()
+	checker.last = report
+	checker.mu.Unlock()
+	return report, true
+}
+
+// LastConsistencyReport returns the report of the latest consistency check,
+// or nil if none has run
+func (c *Collector) LastConsistencyReport() *ConsistencyReport {
+	c.consistency.mu.Lock()
+	defer c.consistency.mu.Unlock()
+	return c.consistency.last
+}
+
+// compareGPU compares one GPU's cached samples with its file. Samples still
+// being stored can show up in the file before the cache; the window ends at
+// the newest cached sample, so they are not reported.
+func (c *Collector) compareGPU(gpuID string) GPUConsistency {
+	result := GPUConsistency{GPUId: gpuID}
+
+	cached := c.memoryStorage.GetTelemetryForGPU(gpuID)
+	result.MemoryPoints = len(cached)
+	if len(cached) == 0 {
+		return result
+	}
+	result.WindowStart, result.WindowEnd = cached[0].Timestamp, cached[0].Timestamp
+	for _, t := range cached {
+		if t.Timestamp.Before(result.WindowStart) {
+			result.WindowStart = t.Timestamp
+		}
+		if t.Timestamp.After(result.WindowEnd) {
+			result.WindowEnd = t.Timestamp
+		}
+	}
+
+	// A merged GPU's cache also holds the samples in its aliases' files, and
+	// the oldest cached samples may have moved to cold storage
+	var records []json.RawMessage
+	for _, id := range c.gpuFiles(gpuID) {
+		if c.cold != nil {
+			segments, err := c.cold.ReadColdTelemetry(id, result.WindowStart)
+			if err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			records = append(records, segments...)
+		}
+		file, err := c.fileStorage.ReadTelemetryFile(id)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		records = append(records, file...)
+	}
+	var stored []persistence.Telemetry
+	for _, record := range records {
+		var t persistence.Telemetry
+		if err := json.Unmarshal(record, &t); err != nil {
+			result.Unreadable++
+			continue
+		}
+		if t.Timestamp.Before(result.WindowStart) || t.Timestamp.After(result.WindowEnd) {
+			continue
+		}
+		stored = append(stored, t)
+	}
+	result.FilePoints = len(stored)
+
+	memoryByTime := indexByTimestamp(cached, &result.Duplicates)
+	fileByTime := indexByTimestamp(stored, &result.Duplicates)
+
+	var examples []int64
+	for ts, m := range memoryByTime {
+		f, ok := fileByTime[ts]
+		switch {
+		case !ok:
+			result.MissingInFile++
+		case !sameSample(m, f):
+			result.Mismatched++
+		default:
+			continue
+		}
+		examples = append(examples, ts)
+	}
+	for ts := range fileByTime {
+		if _, ok := memoryByTime[ts]; !ok {
+			result.MissingInMemory++
+			examples = append(examples, ts)
+		}
+	}
+
+	sort.Slice(examples, func(i, j int) bool { return examples[i] < examples[j] })
+	for _, ts := range examples[:min(len(examples), maxConsistencyExamples)] {
+		result.Examples = append(result.Examples, time.Unix(0, ts).UTC())
+	}
+	return result
+}
+
+// indexByTimestamp maps samples by timestamp, counting repeated timestamps
+// in duplicates
+func indexByTimestamp(samples []persistence.Telemetry, duplicates *int) map[int64]persistence.Telemetry {
+	byTime := make(map[int64]persistence.Telemetry, len(samples))
+	for _, t := range samples {
+		ts := t.Timestamp.UnixNano()
+		if _, ok := byTime[ts]; ok {
+			*duplicates++
+			continue
+		}
+		byTime[ts] = t
+	}
+	return byTime
+}
+
+// sameSample reports whether two samples of a GPU carry the same values. An
+// empty label set equals a missing one.
+func sameSample(a, b persistence.Telemetry) bool {
+	if a.Hostname != b.Hostname || !reflect.DeepEqual(a.Metrics, b.Metrics) {
+		return false
+	}
+	if len(a.Labels) == 0 && len(b.Labels) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Labels, b.Labels)
+}
+
+// serveConsistency handles GET /admin/consistency, returning the latest
+// report, and POST /admin/consistency?sample=N, running a check
+func (c *Collector) serveConsistency(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		report := c.LastConsistencyReport()
+		if report == nil {
+			apierror.Write(w, http.StatusNotFound, apierror.NoConsistencyReport, "no consistency check has run")
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	case http.MethodPost:
+		sample := DefaultConsistencySample
+		if value := r.URL.Query().Get("sample"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				apierror.Write(w, http.StatusBadRequest, apierror.InvalidParameter, "sample must be a non-negative number of GPUs (0 checks all)")
+				return
+			}
+			sample = n
+		}
+		report, ok := c.VerifyConsistency(sample)
+		if !ok {
+			apierror.Write(w, http.StatusConflict, apierror.CheckRunning, "a consistency check is already running")
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	default:
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+	}
+}
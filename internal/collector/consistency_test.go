@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+func TestVerifyConsistency(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: t.TempDir(), MaxEntriesPerGPU: 100})
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sample := func(gpuID string, i int, util float64) persistence.Telemetry {
+		return persistence.Telemetry{GPUId: gpuID, Hostname: "host-1", Timestamp: base.Add(time.Duration(i) * time.Second), Metrics: map[string]float64{MetricGPUUtil: util}}
+	}
+
+	// gpu_0 is stored normally
+	for i := 0; i < 5; i++ {
+		c.store(&Telemetry{GPUId: "gpu_0", Hostname: "host-1", Timestamp: base.Add(time.Duration(i) * time.Second), Metrics: map[string]float64{MetricGPUUtil: 50}})
+	}
+
+	// gpu_1 lost a write, has a point with different values and a point
+	// cached twice
+	for i := 0; i < 5; i++ {
+		c.memoryStorage.StoreTelemetry(sample("gpu_1", i, 50))
+	}
+	c.memoryStorage.StoreTelemetry(sample("gpu_1", 4, 50))
+	for _, s := range []persistence.Telemetry{sample("gpu_1", 0, 50), sample("gpu_1", 2, 50), sample("gpu_1", 3, 99), sample("gpu_1", 4, 50), sample("gpu_1", 1, 50)} {
+		if err := c.fileStorage.WriteTelemetry(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Points outside the cached window are ignored
+	if err := c.fileStorage.WriteTelemetry(sample("gpu_1", 60, 50)); err != nil {
+		t.Fatal(err)
+	}
+	// A point on disk newer than the cache may still be being stored
+	c.memoryStorage.StoreTelemetry(sample("gpu_1", 5, 50))
+	if err := c.fileStorage.WriteTelemetry(sample("gpu_1", 6, 50)); err != nil {
+		t.Fatal(err)
+	}
+
+	report, ok := c.VerifyConsistency(0)
+	if !ok {
+		t.Fatal("Expected the check to run")
+	}
+	if report.GPUsTotal != 2 || report.GPUsChecked != 2 || report.GPUsDivergent != 1 {
+		t.Fatalf("Expected 1 of 2 GPUs divergent, got %+v", report)
+	}
+	if report.GPUs[0].GPUId != "gpu_0" || report.GPUs[0].Divergent() || report.GPUs[0].FilePoints != 5 {
+		t.Errorf("Expected gpu_0 consistent, got %+v", report.GPUs[0])
+	}
+	got := report.GPUs[1]
+	if got.MissingInFile != 1 || got.MissingInMemory != 0 || got.Mismatched != 1 || got.Duplicates != 1 {
+		t.Errorf("Expected 1 missing in file, 1 mismatched and 1 duplicate, got %+v", got)
+	}
+	if len(got.Examples) != 2 || !got.Examples[0].Equal(base.Add(3*time.Second)) || !got.Examples[1].Equal(base.Add(5*time.Second)) {
+		t.Errorf("Expected the divergent timestamps listed, got %v", got.Examples)
+	}
+	if c.LastConsistencyReport() != report {
+		t.Error("Expected the report kept as the latest")
+	}
+
+	// Sampling limits the GPUs checked
+	if report, _ := c.VerifyConsistency(1); report.GPUsChecked != 1 || report.GPUsTotal != 2 {
+		t.Errorf("Expected 1 of 2 GPUs checked, got %+v", report)
+	}
+}
+
+func TestVerifyConsistency_MissingInMemory(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: t.TempDir(), MaxEntriesPerGPU: 100})
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		s := persistence.Telemetry{GPUId: "gpu_0", Timestamp: base.Add(time.Duration(i) * time.Second), Metrics: map[string]float64{MetricGPUUtil: 1}}
+		if err := c.fileStorage.WriteTelemetry(s); err != nil {
+			t.Fatal(err)
+		}
+		if i != 1 {
+			c.memoryStorage.StoreTelemetry(s)
+		}
+	}
+
+	report, _ := c.VerifyConsistency(0)
+	if got := report.GPUs[0]; got.MissingInMemory != 1 || got.MissingInFile != 0 || got.MemoryPoints != 2 || got.FilePoints != 3 {
+		t.Errorf("Expected 1 point missing in memory, got %+v", got)
+	}
+}
+
+func TestConsistencyEndpoint(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: t.TempDir(), MaxEntriesPerGPU: 10})
+
+	rr := httptest.NewRecorder()
+	c.serveConsistency(rr, httptest.NewRequest("GET", "/admin/consistency", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 before any check, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	c.serveConsistency(rr, httptest.NewRequest("POST", "/admin/consistency?sample=abc", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad sample size, got %d", rr.Code)
+	}
+
+	c.store(&Telemetry{GPUId: "gpu_0", Timestamp: time.Now(), Metrics: map[string]float64{MetricGPUUtil: 1}})
+	rr = httptest.NewRecorder()
+	c.serveConsistency(rr, httptest.NewRequest("POST", "/admin/consistency?sample=5", nil))
+	var report ConsistencyReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected a report, got %d %s", rr.Code, rr.Body.String())
+	}
+	if report.GPUsChecked != 1 || report.GPUsDivergent != 0 {
+		t.Errorf("Expected 1 consistent GPU, got %+v", report)
+	}
+
+	rr = httptest.NewRecorder()
+	c.serveConsistency(rr, httptest.NewRequest("GET", "/admin/consistency", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"gpus_checked":1`) {
+		t.Errorf("Expected the latest report, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,283 @@
+//go:build fastjson
+
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// fastDecoding reports whether the collector was built with the hand-rolled
+// message decoder
+const fastDecoding = true
+
+// decodeStreamerMessage parses a streamer message with a hand-rolled decoder
+// for the flat messages the streamer produces: a timestamp, a correlation ID,
+// ASCII string labels and ASCII string, number, boolean and null fields. Anything else, including
+// invalid JSON, is handed to encoding/json, so results and errors match
+// json.Unmarshal into a zero StreamerMessage.
+func decodeStreamerMessage(data []byte, msg *StreamerMessage) error {
+	var fast StreamerMessage
+	if decodeStreamerMessageFast(data, &fast) {
+		*msg = fast
+		return nil
+	}
+	return json.Unmarshal(data, msg)
+}
+
+// decodeStreamerMessageFast reports false for input it does not handle
+func decodeStreamerMessageFast(data []byte, msg *StreamerMessage) bool {
+	s := fastScanner{data: data}
+	if !s.consume('{') {
+		return false
+	}
+	if s.consume('}') {
+		return s.end()
+	}
+
+	seenFields, seenLabels := false, false
+	for {
+		key, ok := s.string()
+		if !ok || !s.consume(':') {
+			return false
+		}
+
+		// encoding/json matches keys case-insensitively
+		switch {
+		case strings.EqualFold(key, "timestamp"):
+			if s.literal("null") {
+				break
+			}
+			value, ok := s.string()
+			if !ok || msg.Timestamp.UnmarshalText([]byte(value)) != nil {
+				return false
+			}
+		case strings.EqualFold(key, "correlation_id"):
+			if s.literal("null") {
+				break
+			}
+			value, ok := s.string()
+			if !ok {
+				return false
+			}
+			msg.CorrelationID = value
+		case strings.EqualFold(key, "labels"):
+			// encoding/json merges repeated objects into one map
+			if seenLabels {
+				return false
+			}
+			seenLabels = true
+			if s.literal("null") {
+				break
+			}
+			if !s.labels(msg) {
+				return false
+			}
+		case strings.EqualFold(key, "fields"):
+			// encoding/json merges repeated objects into one map
+			if seenFields {
+				return false
+			}
+			seenFields = true
+			if s.literal("null") {
+				break
+			}
+			if !s.fields(msg) {
+				return false
+			}
+		default:
+			return false
+		}
+
+		if s.consume(',') {
+			continue
+		}
+		return s.consume('}') && s.end()
+	}
+}
+
+// fastScanner reads JSON tokens from data
+type fastScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *fastScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips whitespace and the byte c, if it comes next
+func (s *fastScanner) consume(c byte) bool {
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// literal skips whitespace and word, if it comes next
+func (s *fastScanner) literal(word string) bool {
+	s.skipSpace()
+	if bytes.HasPrefix(s.data[s.pos:], []byte(word)) {
+		s.pos += len(word)
+		return true
+	}
+	return false
+}
+
+// end reports whether only whitespace remains
+func (s *fastScanner) end() bool {
+	s.skipSpace()
+	return s.pos == len(s.data)
+}
+
+// string reads a string without escapes or non-ASCII characters
+func (s *fastScanner) string() (string, bool) {
+	if !s.consume('"') {
+		return "", false
+	}
+	start := s.pos
+	for i := start; i < len(s.data); i++ {
+		switch c := s.data[i]; {
+		case c == '"':
+			s.pos = i + 1
+			return string(s.data[start:i]), true
+		case c == '\\' || c < 0x20 || c >= utf8.RuneSelf:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// fields reads the fields object
+func (s *fastScanner) fields(msg *StreamerMessage) bool {
+	if !s.consume('{') {
+		return false
+	}
+	fields := make(map[string]interface{}, 16)
+	msg.Fields = fields
+	if s.consume('}') {
+		return true
+	}
+
+	for {
+		key, ok := s.string()
+		if !ok || !s.consume(':') {
+			return false
+		}
+		value, ok := s.value()
+		if !ok {
+			return false
+		}
+		fields[key] = value
+
+		if s.consume(',') {
+			continue
+		}
+		return s.consume('}')
+	}
+}
+
+// labels reads the labels object
+func (s *fastScanner) labels(msg *StreamerMessage) bool {
+	if !s.consume('{') {
+		return false
+	}
+	labels := make(map[string]string, 4)
+	msg.Labels = labels
+	if s.consume('}') {
+		return true
+	}
+
+	for {
+		key, ok := s.string()
+		if !ok || !s.consume(':') {
+			return false
+		}
+		value, ok := s.string()
+		if !ok {
+			return false
+		}
+		labels[key] = value
+
+		if s.consume(',') {
+			continue
+		}
+		return s.consume('}')
+	}
+}
+
+// value reads a string, number, boolean or null
+func (s *fastScanner) value() (interface{}, bool) {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return nil, false
+	}
+	switch c := s.data[s.pos]; {
+	case c == '"':
+		return s.string()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return s.number()
+	case s.literal("true"):
+		return true, true
+	case s.literal("false"):
+		return false, true
+	case s.literal("null"):
+		return nil, true
+	}
+	return nil, false
+}
+
+// number reads a number following the JSON grammar
+func (s *fastScanner) number() (interface{}, bool) {
+	start := s.pos
+	if s.data[s.pos] == '-' {
+		s.pos++
+	}
+	if s.pos < len(s.data) && s.data[s.pos] == '0' {
+		s.pos++
+	} else if !s.digits() {
+		return nil, false
+	}
+	if s.pos < len(s.data) && s.data[s.pos] == '.' {
+		s.pos++
+		if !s.digits() {
+			return nil, false
+		}
+	}
+	if s.pos < len(s.data) && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		s.pos++
+		if s.pos < len(s.data) && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+		if !s.digits() {
+			return nil, false
+		}
+	}
+
+	f, err := strconv.ParseFloat(string(s.data[start:s.pos]), 64)
+	if err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+// digits skips one or more decimal digits
+func (s *fastScanner) digits() bool {
+	start := s.pos
+	for s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+		s.pos++
+	}
+	return s.pos > start
+}
@@ -0,0 +1,15 @@
+//go:build fastjson
+
+package collector
+
+import "testing"
+
+func TestDecodeStreamerMessageFastPath(t *testing.T) {
+	var msg StreamerMessage
+	if !decodeStreamerMessageFast(dcgmMessage, &msg) {
+		t.Fatal("Expected a DCGM message to be decoded without falling back to encoding/json")
+	}
+	if msg.Fields["value"] != 100.0 || msg.Fields["Hostname"] != "mtv5-dgx1-hgpu-031" {
+		t.Errorf("Unexpected fields %v", msg.Fields)
+	}
+}
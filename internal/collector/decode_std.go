@@ -0,0 +1,15 @@
+//go:build !fastjson
+
+package collector
+
+import "encoding/json"
+
+// fastDecoding reports whether the collector was built with the hand-rolled
+// message decoder
+const fastDecoding = false
+
+// decodeStreamerMessage parses a streamer message with encoding/json. Build
+// with -tags fastjson for the faster hand-rolled decoder.
+func decodeStreamerMessage(data []byte, msg *StreamerMessage) error {
+	return json.Unmarshal(data, msg)
+}
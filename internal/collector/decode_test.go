@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// dcgmMessage is a typical streamer message for a DCGM CSV row
+var dcgmMessage = []byte(`{"timestamp":"2025-07-18T20:42:34.123456789Z","fields":{"metric_name":"DCGM_FI_DEV_GPU_UTIL","gpu_id":"0","device":"nvidia0","uuid":"GPU-5fd4f087-86f3-7a43-b711-4771313afc50","modelName":"NVIDIA H100 80GB HBM3","Hostname":"mtv5-dgx1-hgpu-031","container":"","namespace":"","pod":"","value":100,"labels_raw":"DCGM_FI_DRIVER_VERSION=535.129.03,Hostname=mtv5-dgx1-hgpu-031"}}`)
+
+// TestDecodeStreamerMessage checks the build's decoder agrees with
+// encoding/json; run it with and without -tags fastjson
+func TestDecodeStreamerMessage(t *testing.T) {
+	t.Logf("fast decoding: %v", fastDecoding)
+
+	inputs := []string{
+		string(dcgmMessage),
+		`{}`,
+		` { "timestamp" : "2025-07-18T20:42:34+02:00" , "fields" : { "a" : -1.5e3 , "b" : true , "c" : false , "d" : null , "e" : "" } } `,
+		`{"Timestamp":"2025-07-18T20:42:34Z","FIELDS":{"value":0}}`,
+		`{"timestamp":null,"fields":null}`,
+		`{"fields":{}}`,
+		`{"fields":{"a":1,"a":2}}`,
+		`{"fields":{"a":1},"fields":{"b":2}}`,
+		`{"fields":{"nested":{"a":[1,2]}}}`,
+		`{"fields":{"s":"tab\tand \"quotes\" é"}}`,
+		`{"fields":{"s":"héllo"}}`,
+		`{"fields":{"big":1e400}}`,
+		`{"fields":{"n":01}}`,
+		`{"fields":{"n":-}}`,
+		`{"fields":{"n":1.}}`,
+		`{"fields":{"n":.5}}`,
+		`{"fields":{"n":1e}}`,
+		`{"fields":{"t":tru}}`,
+		`{"timestamp":"yesterday"}`,
+		`{"timestamp":1721335354}`,
+		`{"other":1,"fields":{"a":1}}`,
+		`{"timestamp":"2025-07-18T20:42:34Z","fields":{"a":1},"correlation_id":"01J3Q8M4K7W9X2T5B6N8R0C1D.0.2.1.17"}`,
+		`{"correlation_id":null,"fields":{"a":1}}`,
+		`{"correlation_id":7}`,
+		`{"fields":{"a":1},"labels":{"source_instance":"streamer-0","source_file":"/data/a.csv","source_row":"17"}}`,
+		`{"labels":{},"fields":{"a":1}}`,
+		`{"labels":null}`,
+		`{"labels":{"a":"1","a":"2"}}`,
+		`{"labels":{"a":"1"},"labels":{"b":"2"}}`,
+		`{"labels":{"row":17}}`,
+		`{"labels":{"a":null}}`,
+		`{"fields":{"a":1},}`,
+		`{"fields":{"a":1}} x`,
+		`{"fields":{"a":1}`,
+		`[]`,
+		``,
+	}
+
+	for _, input := range inputs {
+		var want, got StreamerMessage
+		wantErr := json.Unmarshal([]byte(input), &want)
+		gotErr := decodeStreamerMessage([]byte(input), &got)
+
+		if (wantErr != nil) != (gotErr != nil) {
+			t.Errorf("%s: expected error %v, got %v", input, wantErr, gotErr)
+			continue
+		}
+		if wantErr != nil {
+			continue
+		}
+		if !want.Timestamp.Equal(got.Timestamp) || !reflect.DeepEqual(want.Fields, got.Fields) || want.CorrelationID != got.CorrelationID || !reflect.DeepEqual(want.Labels, got.Labels) {
+			t.Errorf("%s: expected %+v, got %+v", input, want, got)
+		}
+	}
+}
+
+func BenchmarkDecodeStreamerMessage(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(dcgmMessage)))
+	for i := 0; i < b.N; i++ {
+		var msg StreamerMessage
+		if err := decodeStreamerMessage(dcgmMessage, &msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeStreamerMessageStdlib(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(dcgmMessage)))
+	for i := 0; i < b.N; i++ {
+		var msg StreamerMessage
+		if err := json.Unmarshal(dcgmMessage, &msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
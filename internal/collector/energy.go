@@ -0,0 +1,288 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// DefaultEnergyMaxGap is the longest interval between two power samples that
+// is interpolated. Longer gaps are left out of the estimate rather than
+// guessed, and show up as reduced coverage.
+const DefaultEnergyMaxGap = 5 * time.Minute
+
+// energyStateFile holds the derived daily energy metric in the data directory
+const energyStateFile = "energy.json"
+
+// energySaveInterval is how often changed energy state is written to disk
+const energySaveInterval = 30 * time.Second
+
+// DailyEnergy is the energy one GPU used during one UTC day
+type DailyEnergy struct {
+	Date      string  `json:"date"`
+	EnergyKWh float64 `json:"energy_kwh"`
+	// CoveredHours is the part of the day between power samples no further
+	// apart than the max gap; energy outside it is unknown
+	CoveredHours float64 `json:"covered_hours"`
+	Samples      int     `json:"samples"`
+}
+
+// EnergySummary is the daily energy of a GPU over an inclusive date range
+type EnergySummary struct {
+	GPUId    string        `json:"gpu_id"`
+	From     string        `json:"from,omitempty"`
+	To       string        `json:"to,omitempty"`
+	Days     []DailyEnergy `json:"days"`
+	TotalKWh float64       `json:"total_kwh"`
+}
+
+// ParseEnergyDate accepts a YYYY-MM-DD date or an RFC3339 timestamp and
+// returns the UTC date it falls on; an empty string is returned unchanged
+func ParseEnergyDate(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if t, err := time.Parse(reportDateLayout, s); err == nil {
+		return t.Format(reportDateLayout), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q (expected YYYY-MM-DD or RFC3339)", s)
+	}
+	return t.UTC().Format(reportDateLayout), nil
+}
+
+// energyAccumulator integrates one GPU's power samples into daily energy
+type energyAccumulator struct {
+	LastWatts float64                 `json:"last_watts"`
+	LastAt    time.Time               `json:"last_at"`
+	Days      map[string]*DailyEnergy `json:"days"`
+}
+
+func (a *energyAccumulator) day(t time.Time) *DailyEnergy {
+	date := t.UTC().Format(reportDateLayout)
+	if a.Days == nil {
+		a.Days = make(map[string]*DailyEnergy)
+	}
+	d, ok := a.Days[date]
+	if !ok {
+		d = &DailyEnergy{Date: date}
+		a.Days[date] = d
+	}
+	return d
+}
+
+// add integrates the interval since the previous sample with the trapezoidal
+// rule, splitting it at UTC midnight with linearly interpolated power.
+// Samples not newer than the previous one are ignored and add returns false.
+func (a *energyAccumulator) add(watts float64, at time.Time, maxGap time.Duration) bool {
+	if !a.LastAt.IsZero() && !at.After(a.LastAt) {
+		return false
+	}
+	a.day(at).Samples++
+
+	if !a.LastAt.IsZero() && at.Sub(a.LastAt) <= maxGap {
+		total := at.Sub(a.LastAt)
+		start, startWatts := a.LastAt, a.LastWatts
+		for start.Before(at) {
+			end := start.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+			if end.After(at) {
+				end = at
+			}
+			endWatts := a.LastWatts + (watts-a.LastWatts)*float64(end.Sub(a.LastAt))/float64(total)
+
+			d := a.day(start)
+			hours := end.Sub(start).Hours()
+			d.EnergyKWh += (startWatts + endWatts) / 2 * hours / 1000
+			d.CoveredHours += hours
+
+			start, startWatts = end, endWatts
+		}
+	}
+
+	a.LastWatts = watts
+	a.LastAt = at
+	return true
+}
+
+// EnergyTracker maintains per-GPU daily energy as a derived metric, updated
+// as power samples arrive and persisted alongside the telemetry files
+type EnergyTracker struct {
+	mu     sync.Mutex
+	maxGap time.Duration
+	store  *persistence.FileStore
+	gpus   map[string]*energyAccumulator
+	dirty  bool
+}
+
+// NewEnergyTracker creates a tracker persisting to path; maxGap of zero uses
+// DefaultEnergyMaxGap
+func NewEnergyTracker(path string, maxGap time.Duration) *EnergyTracker {
+	if maxGap <= 0 {
+		maxGap = DefaultEnergyMaxGap
+	}
+	return &EnergyTracker{
+		maxGap: maxGap,
+		store:  persistence.NewFileStore(path),
+		gpus:   make(map[string]*energyAccumulator),
+	}
+}
+
+// Load restores saved state, reporting false if there was none
+func (t *EnergyTracker) Load() (bool, error) {
+	gpus := make(map[string]*energyAccumulator)
+	if err := t.store.Load(&gpus); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to load energy state: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.gpus = gpus
+	t.dirty = false
+	return true, nil
+}
+
+// Save writes the state to disk if it changed since the last save
+func (t *EnergyTracker) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.dirty {
+		return nil
+	}
+	if err := t.store.Save(t.gpus); err != nil {
+		return fmt.Errorf("failed to save energy state: %w", err)
+	}
+	t.dirty = false
+	return nil
+}
+
+// Add records a power sample in watts
+func (t *EnergyTracker) Add(gpuID string, watts float64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	acc, ok := t.gpus[gpuID]
+	if !ok {
+		acc = &energyAccumulator{}
+		t.gpus[gpuID] = acc
+	}
+	if acc.add(watts, at, t.maxGap) {
+		t.dirty = true
+	}
+}
+
+// Summary returns a GPU's daily energy between the inclusive dates from and
+// to (YYYY-MM-DD, either may be empty for no bound)
+func (t *EnergyTracker) Summary(gpuID, from, to string) EnergySummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summary := EnergySummary{GPUId: gpuID, From: from, To: to, Days: []DailyEnergy{}}
+	if acc, ok := t.gpus[gpuID]; ok {
+		for date, d := range acc.Days {
+			if (from != "" && date < from) || (to != "" && date > to) {
+				continue
+			}
+			summary.Days = append(summary.Days, *d)
+			summary.TotalKWh += d.EnergyKWh
+		}
+	}
+	sort.Slice(summary.Days, func(i, j int) bool {
+		return summary.Days[i].Date < summary.Days[j].Date
+	})
+	return summary
+}
+
+// initEnergy loads the energy state, rebuilding it from the telemetry files
+// when there is none, e.g. on first start after upgrading
+func (c *Collector) initEnergy() error {
+	loaded, err := c.energy.Load()
+	if err != nil || loaded {
+		return err
+	}
+
+	gpuIDs, err := c.fileStorage.ListGPUFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list telemetry files: %w", err)
+	}
+	for _, gpuID := range gpuIDs {
+		records, err := c.fileStorage.ReadTelemetryFile(gpuID)
+		if err != nil {
+			return fmt.Errorf("failed to read telemetry for %s: %w", gpuID, err)
+		}
+
+		var samples []*Telemetry
+		for _, record := range records {
+			var telemetry Telemetry
+			if err := json.Unmarshal(record, &telemetry); err != nil {
+				continue
+			}
+			if _, ok := telemetry.Metrics[MetricPowerUsage]; ok {
+				samples = append(samples, &telemetry)
+			}
+		}
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].Timestamp.Before(samples[j].Timestamp)
+		})
+		for _, telemetry := range samples {
+			c.energy.Add(gpuID, telemetry.Metrics[MetricPowerUsage], telemetry.Timestamp)
+		}
+	}
+
+	if len(gpuIDs) > 0 {
+		c.logger.Info("Rebuilt energy metrics from telemetry files", "gpus", len(gpuIDs))
+	}
+	return c.energy.Save()
+}
+
+// energySaveLoop periodically persists the energy state; Stop saves it once
+// more after the workers have finished
+func (c *Collector) energySaveLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(energySaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.energy.Save(); err != nil {
+				c.logger.Error("Failed to save energy metrics", "error", err)
+			}
+		}
+	}
+}
+
+// serveEnergy handles GET /api/v1/gpus/{id}/energy?from=...&to=...
+func (c *Collector) serveEnergy(w http.ResponseWriter, r *http.Request, gpuID string) {
+	from, err := ParseEnergyDate(r.URL.Query().Get("from"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidTimeRange, err.Error())
+		return
+	}
+	to, err := ParseEnergyDate(r.URL.Query().Get("to"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidTimeRange, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.energy.Summary(gpuID, from, to)); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to encode energy response", "error", err)
+	}
+}
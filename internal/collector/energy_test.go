@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestEnergyAccumulator(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var acc energyAccumulator
+
+	// 200W then 400W one hour apart: 300W average for an hour
+	acc.add(200, base, 2*time.Hour)
+	acc.add(400, base.Add(time.Hour), 2*time.Hour)
+	// Out-of-order samples are ignored
+	if acc.add(1000, base.Add(30*time.Minute), 2*time.Hour) {
+		t.Error("Expected out-of-order sample to be ignored")
+	}
+	// A gap longer than the max gap is not integrated
+	acc.add(400, base.Add(4*time.Hour), 2*time.Hour)
+
+	day := acc.Days["2024-01-01"]
+	if !almostEqual(day.EnergyKWh, 0.3) {
+		t.Errorf("Expected 0.3kWh, got %v", day.EnergyKWh)
+	}
+	if !almostEqual(day.CoveredHours, 1) {
+		t.Errorf("Expected 1 covered hour, got %v", day.CoveredHours)
+	}
+	if day.Samples != 3 {
+		t.Errorf("Expected 3 samples, got %d", day.Samples)
+	}
+}
+
+func TestEnergyAccumulatorSplitsAtMidnight(t *testing.T) {
+	midnight := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	var acc energyAccumulator
+
+	// 0W at 23:00 rising linearly to 200W at 01:00: 100W at midnight, so
+	// 50Wh before midnight and 150Wh after
+	acc.add(0, midnight.Add(-time.Hour), 3*time.Hour)
+	acc.add(200, midnight.Add(time.Hour), 3*time.Hour)
+
+	if got := acc.Days["2024-01-01"].EnergyKWh; !almostEqual(got, 0.05) {
+		t.Errorf("Expected 0.05kWh on the first day, got %v", got)
+	}
+	if got := acc.Days["2024-01-02"].EnergyKWh; !almostEqual(got, 0.15) {
+		t.Errorf("Expected 0.15kWh on the second day, got %v", got)
+	}
+}
+
+func TestEnergyTrackerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), energyStateFile)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker := NewEnergyTracker(path, 0)
+	if loaded, err := tracker.Load(); err != nil || loaded {
+		t.Fatalf("Expected no saved state, got loaded=%v err=%v", loaded, err)
+	}
+	tracker.Add("gpu_0", 100, base)
+	tracker.Add("gpu_0", 100, base.Add(time.Minute))
+	if err := tracker.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Integration continues from the saved last sample after a restart
+	restored := NewEnergyTracker(path, 0)
+	if loaded, err := restored.Load(); err != nil || !loaded {
+		t.Fatalf("Expected saved state, got loaded=%v err=%v", loaded, err)
+	}
+	restored.Add("gpu_0", 100, base.Add(2*time.Minute))
+
+	summary := restored.Summary("gpu_0", "", "")
+	if len(summary.Days) != 1 || !almostEqual(summary.TotalKWh, 100*2.0/60/1000) {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+
+	if summary := restored.Summary("gpu_0", "2024-01-02", ""); len(summary.Days) != 0 {
+		t.Errorf("Expected no days from 2024-01-02, got %+v", summary.Days)
+	}
+}
+
+func TestParseEnergyDate(t *testing.T) {
+	tests := map[string]string{
+		"":                          "",
+		"2024-01-02":                "2024-01-02",
+		"2024-01-02T23:30:00-02:00": "2024-01-03",
+	}
+	for in, want := range tests {
+		got, err := ParseEnergyDate(in)
+		if err != nil || got != want {
+			t.Errorf("ParseEnergyDate(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseEnergyDate("yesterday"); err == nil {
+		t.Error("Expected error for invalid date")
+	}
+}
+
+func TestCollectorEnergyEndpoint(t *testing.T) {
+	dataDir := t.TempDir()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Telemetry written before energy tracking existed is backfilled
+	storage := persistence.NewFileStorage(dataDir)
+	for i := 0; i < 3; i++ {
+		if err := storage.WriteTelemetry(persistence.Telemetry{
+			GPUId:     "gpu_0",
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Metrics:   map[string]float64{MetricPowerUsage: 600},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: dataDir})
+	if err := c.initEnergy(); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	c.serveEnergy(rr, httptest.NewRequest("GET", "/api/v1/gpus/gpu_0/energy?from=2024-01-01&to=2024-01-01", nil), "gpu_0")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var summary EnergySummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	// 600W for two minutes = 20Wh
+	if len(summary.Days) != 1 || !almostEqual(summary.TotalKWh, 0.02) {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+
+	rr = httptest.NewRecorder()
+	c.serveEnergy(rr, httptest.NewRequest("GET", "/api/v1/gpus/gpu_0/energy?from=soon", nil), "gpu_0")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid date, got %d", rr.Code)
+	}
+}
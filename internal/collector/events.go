@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// DefaultEventsTopic is the topic the collector publishes its operational
+// events to
+const DefaultEventsTopic = "pipeline.events"
+
+// EventTypeHeader carries an event's type in its message headers, so
+// routing rules and consumers can pick events without decoding them
+const EventTypeHeader = "x-event-type"
+
+// Event types published to the events topic
+const (
+	// EventIngestError is a message that could not be decoded
+	EventIngestError = "ingest_error"
+	// EventStoreError is a sample the telemetry files failed to store
+	EventStoreError = "store_error"
+	// EventGuardrail is a guardrail taking effect: a cardinality limit
+	// reached, or a writer pausing or resuming for disk space
+	EventGuardrail = "guardrail"
+	// EventEviction is data evicted to stay within a bound: quarantined
+	// messages over the quarantine size, or telemetry moved to cold storage
+	EventEviction = "eviction"
+)
+
+// eventQueueSize is how many events wait to be published before further
+// ones are dropped
+const eventQueueSize = 256
+
+// Event is an operational event of the collector, published as JSON
+type Event struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	// Collector is the hostname of the collector that emitted the event
+	Collector string            `json:"collector"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// eventPublisher publishes the collector's events to a topic from its own
+// goroutine, so a slow or unavailable MQ service never holds up ingestion.
+// Events emitted while the queue is full are dropped and counted. A nil
+// publisher discards events.
+type eventPublisher struct {
+	broker   mq.BrokerInterface
+	topic    string
+	hostname string
+	logger   *logger.Logger
+	queue    chan Event
+	stop     chan struct{}
+	done     sync.WaitGroup
+
+	published *metrics.CounterVec
+	dropped   *metrics.Counter
+}
+
+func newEventPublisher(broker mq.BrokerInterface, topic string, log *logger.Logger, registry *metrics.Registry) *eventPublisher {
+	hostname, _ := os.Hostname()
+	return &eventPublisher{
+		broker:   broker,
+		topic:    topic,
+		hostname: hostname,
+		logger:   log,
+		queue:    make(chan Event, eventQueueSize),
+		stop:     make(chan struct{}),
+		published: registry.NewCounterVec("collector_events_published_total",
+			"Operational events published to the events topic, by type.", "type"),
+		dropped: registry.NewCounterVec("collector_events_dropped_total",
+			"Operational events dropped because the events queue was full or publishing failed.").WithLabelValues(),
+	}
+}
+
+// emit queues an event with fields given as key, value pairs
+func (p *eventPublisher) emit(eventType, message string, fields ...string) {
+	if p == nil {
+		return
+	}
+	event := Event{
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Collector: p.hostname,
+		Message:   message,
+	}
+	if len(fields) > 0 {
+		event.Fields = make(map[string]string, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			event.Fields[fields[i]] = fields[i+1]
+		}
+	}
+
+	select {
+	case p.queue <- event:
+	default:
+		p.dropped.Inc()
+	}
+}
+
+// start publishes queued events until close
+func (p *eventPublisher) start() {
+	if p == nil {
+		return
+	}
+	p.done.Add(1)
+	go func() {
+		defer p.done.Done()
+		for {
+			select {
+			case event := <-p.queue:
+				p.publish(event)
+			case <-p.stop:
+				// Publish what was emitted while stopping
+				for {
+					select {
+					case event := <-p.queue:
+						p.publish(event)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// close publishes the events still queued and stops the publisher
+func (p *eventPublisher) close() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	p.done.Wait()
+}
+
+func (p *eventPublisher) publish(event Event) {
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = p.broker.Publish(p.topic, mq.Message{
+			Payload: payload,
+			Headers: map[string]string{EventTypeHeader: event.Type},
+		})
+	}
+	if err != nil {
+		p.dropped.Inc()
+		p.logger.Debug("Failed to publish collector event", "topic", p.topic, "type", event.Type, "error", err)
+		return
+	}
+	p.published.WithLabelValues(event.Type).Inc()
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence/persistencetest"
+)
+
+func TestCollectorPublishesEvents(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	events, unsubscribe, err := broker.SubscribeWithAck(DefaultEventsTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	storage := persistencetest.NewTelemetryStore()
+	c := NewCollector(broker, CollectorConfig{
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		Storage:          storage,
+		Cardinality:      CardinalityConfig{MaxGPUs: 1},
+		EventsTopic:      DefaultEventsTopic,
+	})
+	c.events.start()
+
+	send := func(gpuID string) error {
+		payload, _ := json.Marshal(StreamerMessage{
+			Timestamp: time.Now(),
+			Fields:    map[string]interface{}{"gpu_id": gpuID, "Hostname": "host-a", "metric_name": "m", "value": 1.0},
+		})
+		return c.handleMessage(0, mq.Message{Payload: payload})
+	}
+
+	// A GPU beyond the limit trips a guardrail, and a failed write is
+	// reported as a store error
+	if err := send("gpu_0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("gpu_1"); err != nil {
+		t.Fatal(err)
+	}
+	storage.FailWrites(fmt.Errorf("disk full"))
+	if err := send("gpu_0"); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	c.events.close()
+
+	var received []Event
+	for len(received) < 2 {
+		select {
+		case msg := <-events:
+			var event Event
+			if err := json.Unmarshal(msg.Payload, &event); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Headers[EventTypeHeader] != event.Type {
+				t.Errorf("Expected the %s header to carry the event type, got %v", EventTypeHeader, msg.Headers)
+			}
+			received = append(received, event)
+			msg.Ack()
+		case <-time.After(time.Second):
+			t.Fatalf("Expected two events, got %+v", received)
+		}
+	}
+
+	guardrail, stored := received[0], received[1]
+	if guardrail.Type != EventGuardrail || guardrail.Fields["guardrail"] != "cardinality" || guardrail.Fields["gpu_id"] != "gpu_1" {
+		t.Errorf("Expected a cardinality guardrail event for gpu_1, got %+v", guardrail)
+	}
+	if stored.Type != EventStoreError || stored.Fields["gpu_id"] != "gpu_0" || stored.Message != "disk full" {
+		t.Errorf("Expected a store error for gpu_0, got %+v", stored)
+	}
+}
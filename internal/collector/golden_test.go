@@ -0,0 +1,29 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/testdata"
+)
+
+// TestDecode_Golden checks the samples decoded from the shared DCGM
+// dataset's streamer records against their golden
+func TestDecode_Golden(t *testing.T) {
+	collector := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		Workers:          1,
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 100,
+	})
+
+	var samples []*Telemetry
+	for _, payload := range testdata.Messages(t, testdata.Basic) {
+		telemetry, err := collector.decode(mq.Message{Payload: payload, Ack: func() {}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, telemetry)
+	}
+
+	testdata.AssertGolden(t, testdata.Basic+".telemetry", samples)
+}
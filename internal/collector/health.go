@@ -0,0 +1,641 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/notify"
+)
+
+// HealthState classifies a GPU
+type HealthState string
+
+const (
+	HealthHealthy  HealthState = "healthy"
+	HealthDegraded HealthState = "degraded"
+	// HealthUnknown means no telemetry arrived recently enough to judge
+	HealthUnknown HealthState = "unknown"
+)
+
+// ParseHealthState parses a health state name
+func ParseHealthState(s string) (HealthState, error) {
+	switch state := HealthState(strings.ToLower(strings.TrimSpace(s))); state {
+	case HealthHealthy, HealthDegraded, HealthUnknown:
+		return state, nil
+	default:
+		return "", fmt.Errorf("invalid health state %q (expected healthy, degraded or unknown)", s)
+	}
+}
+
+const (
+	// DefaultHealthRules marks a GPU degraded when it runs hot for 5 minutes
+	DefaultHealthRules = MetricGPUTemp + ">85:5m"
+	// DefaultHealthStaleAfter is how long without telemetry before a GPU's
+	// health is unknown
+	DefaultHealthStaleAfter = 10 * time.Minute
+	// healthEvaluationInterval is how often health is re-evaluated to log
+	// state changes, including GPUs going quiet
+	healthEvaluationInterval = 15 * time.Second
+)
+
+// HealthCondition compares one metric with a threshold
+type HealthCondition struct {
+	Metric    string
+	Below     bool // breached below Threshold instead of above
+	Threshold float64
+}
+
+func (c HealthCondition) breached(v float64) bool {
+	if c.Below {
+		return v < c.Threshold
+	}
+	return v > c.Threshold
+}
+
+func (c HealthCondition) String() string {
+	op := ">"
+	if c.Below {
+		op = "<"
+	}
+	return fmt.Sprintf("%s %s %g", c.Metric, op, c.Threshold)
+}
+
+// HealthRule marks a GPU degraded while all of its conditions hold for at
+// least For, e.g. low utilization AND high power meaning a hung process.
+// Conditions on different metrics use each metric's latest value, since
+// metrics usually arrive in separate samples.
+type HealthRule struct {
+	// Name identifies the rule in alerts; empty uses the metric names
+	Name       string
+	Conditions []HealthCondition
+	For        time.Duration
+	// Match limits the rule to GPUs whose labels (gpu_id, hostname) match
+	// these glob patterns; empty applies it to every GPU
+	Match map[string]string
+	// Description, when set, is a text/template over HealthRuleData used as
+	// the reason a GPU is degraded
+	Description *template.Template
+}
+
+// HealthRuleData is available in a rule's description template
+type HealthRuleData struct {
+	Rule     string
+	GPUId    string
+	Hostname string
+	// Values holds the latest value of each metric in the rule's conditions
+	Values map[string]float64
+	Since  time.Time
+}
+
+// ID returns the rule's name, or its metric names joined with "&"
+func (r HealthRule) ID() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	metrics := make([]string, len(r.Conditions))
+	for i, condition := range r.Conditions {
+		metrics[i] = condition.Metric
+	}
+	return strings.Join(metrics, "&")
+}
+
+func (r HealthRule) String() string {
+	conditions := make([]string, len(r.Conditions))
+	for i, condition := range r.Conditions {
+		conditions[i] = condition.String()
+	}
+	s := fmt.Sprintf("%s for %s", strings.Join(conditions, " and "), r.For)
+	if r.Name != "" {
+		s = r.Name + ": " + s
+	}
+	return s
+}
+
+// applies reports whether the rule covers a GPU with the given labels
+func (r HealthRule) applies(labels map[string]string) bool {
+	for name, pattern := range r.Match {
+		if matched, err := path.Match(pattern, labels[name]); err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// uses reports whether any of the rule's conditions read a metric in metrics
+func (r HealthRule) uses(metrics map[string]float64) bool {
+	for _, condition := range r.Conditions {
+		if _, ok := metrics[condition.Metric]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// andSeparator splits the conditions of a composite rule
+var andSeparator = regexp.MustCompile(`(?i)\s+and\s+|&`)
+
+// parseHealthConditions parses conditions joined by "&" or "AND", e.g.
+// "DCGM_FI_DEV_GPU_UTIL<5 AND DCGM_FI_DEV_POWER_USAGE>300"
+func parseHealthConditions(spec string) ([]HealthCondition, error) {
+	var conditions []HealthCondition
+	for _, part := range andSeparator.Split(spec, -1) {
+		part = strings.TrimSpace(part)
+		i := strings.IndexAny(part, "<>")
+		if i <= 0 {
+			return nil, fmt.Errorf("expected metric>threshold or metric<threshold, got %q", part)
+		}
+
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(part[i+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad threshold in %q: %w", part, err)
+		}
+		conditions = append(conditions, HealthCondition{
+			Metric:    strings.TrimSpace(part[:i]),
+			Below:     part[i] == '<',
+			Threshold: threshold,
+		})
+	}
+	return conditions, nil
+}
+
+// ParseHealthRules parses a comma-separated list of "[name=]conditions[:for]"
+// rules, where conditions are one or more "metric>threshold" or
+// "metric<threshold" joined by "&" or "AND", e.g.
+// "DCGM_FI_DEV_GPU_TEMP>85:5m,hung=DCGM_FI_DEV_GPU_UTIL<5&DCGM_FI_DEV_POWER_USAGE>300:10m"
+func ParseHealthRules(spec string) ([]HealthRule, error) {
+	var rules []HealthRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var rule HealthRule
+		body := entry
+		if name, rest, ok := strings.Cut(entry, "="); ok && !strings.ContainsAny(name, "<>") {
+			rule.Name = strings.TrimSpace(name)
+			body = rest
+		}
+
+		condition, duration, hasDuration := strings.Cut(body, ":")
+		conditions, err := parseHealthConditions(condition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid health rule %q: %w", entry, err)
+		}
+		rule.Conditions = conditions
+
+		if hasDuration {
+			rule.For, err = time.ParseDuration(strings.TrimSpace(duration))
+			if err != nil || rule.For < 0 {
+				return nil, fmt.Errorf("invalid health rule %q: bad duration %q", entry, duration)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// healthRuleFile is the JSON format of a health rules file
+type healthRuleFile struct {
+	Rules []struct {
+		Name        string            `json:"name"`
+		Condition   string            `json:"condition"`
+		For         string            `json:"for"`
+		Match       map[string]string `json:"match"`
+		Description string            `json:"description"`
+	} `json:"rules"`
+}
+
+// LoadHealthRules reads rules from a JSON file. Besides the condition and
+// duration of a command-line rule, each can limit itself to matching GPUs and
+// describe a breach with a template, so one rule definition serves the whole
+// fleet:
+//
+//	{"rules": [{"name": "hung_process",
+//	  "condition": "DCGM_FI_DEV_GPU_UTIL < 5 AND DCGM_FI_DEV_POWER_USAGE > 300",
+//	  "for": "10m", "match": {"hostname": "dgx-*"},
+//	  "description": "{{.GPUId}} on {{.Hostname}} may be hung"}]}
+func LoadHealthRules(filename string) ([]HealthRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read health rules: %w", err)
+	}
+
+	var file healthRuleFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse health rules %s: %w", filename, err)
+	}
+
+	rules := make([]HealthRule, 0, len(file.Rules))
+	for i, entry := range file.Rules {
+		name := entry.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		conditions, err := parseHealthConditions(entry.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid health rule %s: %w", name, err)
+		}
+		rule := HealthRule{Name: entry.Name, Conditions: conditions, Match: entry.Match}
+
+		if entry.For != "" {
+			rule.For, err = time.ParseDuration(entry.For)
+			if err != nil || rule.For < 0 {
+				return nil, fmt.Errorf("invalid health rule %s: bad duration %q", name, entry.For)
+			}
+		}
+		for label, pattern := range entry.Match {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid health rule %s: bad %s pattern %q: %w", name, label, pattern, err)
+			}
+		}
+		if entry.Description != "" {
+			rule.Description, err = template.New(name).Option("missingkey=zero").Parse(entry.Description)
+			if err != nil {
+				return nil, fmt.Errorf("invalid health rule %s: bad description: %w", name, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// HealthConfig configures GPU health classification
+type HealthConfig struct {
+	Rules []HealthRule
+	// StaleAfter is how long without telemetry before health is unknown;
+	// zero uses DefaultHealthStaleAfter
+	StaleAfter time.Duration
+}
+
+// GPUHealth is the current health of a GPU
+type GPUHealth struct {
+	State    HealthState `json:"state"`
+	Reasons  []string    `json:"reasons,omitempty"`
+	LastSeen *time.Time  `json:"last_seen,omitempty"`
+}
+
+// gpuHealthState tracks rule breaches for one GPU. Breach durations use
+// sample timestamps; staleness uses the time samples were received, so
+// replayed historical data is not mistaken for a silent GPU.
+type gpuHealthState struct {
+	hostname    string
+	lastSeen    time.Time
+	values      map[string]metricSample // latest value of each rule metric
+	breachStart []time.Time             // per rule; zero when not breached
+	breachLast  []time.Time
+	reported    HealthState
+}
+
+// metricSample is a metric value and the timestamp of its sample
+type metricSample struct {
+	value float64
+	at    time.Time
+}
+
+func (s *gpuHealthState) labels(gpuID string) map[string]string {
+	return map[string]string{"gpu_id": gpuID, "hostname": s.hostname}
+}
+
+// HealthEvaluator classifies GPUs from their telemetry as it arrives
+type HealthEvaluator struct {
+	mu         sync.Mutex
+	rules      []HealthRule
+	staleAfter time.Duration
+	now        func() time.Time
+	gpus       map[string]*gpuHealthState
+}
+
+// NewHealthEvaluator creates an evaluator for the given configuration
+func NewHealthEvaluator(config HealthConfig) *HealthEvaluator {
+	staleAfter := config.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultHealthStaleAfter
+	}
+	return &HealthEvaluator{
+		rules:      config.Rules,
+		staleAfter: staleAfter,
+		now:        time.Now,
+		gpus:       make(map[string]*gpuHealthState),
+	}
+}
+
+// Observe updates rule state from a telemetry sample
+func (e *HealthEvaluator) Observe(telemetry *Telemetry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, ok := e.gpus[telemetry.GPUId]
+	if !ok {
+		state = &gpuHealthState{
+			values:      make(map[string]metricSample),
+			breachStart: make([]time.Time, len(e.rules)),
+			breachLast:  make([]time.Time, len(e.rules)),
+		}
+		e.gpus[telemetry.GPUId] = state
+	}
+	state.lastSeen = e.now()
+	if telemetry.Hostname != "" {
+		state.hostname = telemetry.Hostname
+	}
+
+	for _, rule := range e.rules {
+		for _, condition := range rule.Conditions {
+			if v, ok := telemetry.Metrics[condition.Metric]; ok {
+				state.values[condition.Metric] = metricSample{value: v, at: telemetry.Timestamp}
+			}
+		}
+	}
+
+	labels := state.labels(telemetry.GPUId)
+	for i, rule := range e.rules {
+		if !rule.uses(telemetry.Metrics) {
+			continue
+		}
+		if !rule.applies(labels) || !e.breached(state, rule, telemetry.Timestamp) {
+			state.breachStart[i] = time.Time{}
+			continue
+		}
+		if state.breachStart[i].IsZero() {
+			state.breachStart[i] = telemetry.Timestamp
+		}
+		state.breachLast[i] = telemetry.Timestamp
+	}
+}
+
+// breached reports whether every condition of rule holds on the GPU's latest
+// values. Values older than the stale period at the time of the sample are
+// ignored, so a metric that stopped reporting cannot keep a rule breached.
+func (e *HealthEvaluator) breached(state *gpuHealthState, rule HealthRule, at time.Time) bool {
+	for _, condition := range rule.Conditions {
+		sample, ok := state.values[condition.Metric]
+		if !ok || at.Sub(sample.at) > e.staleAfter || !condition.breached(sample.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Health returns the current health of a GPU
+func (e *HealthEvaluator) Health(gpuID string) GPUHealth {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.evaluate(gpuID, e.gpus[gpuID], e.now())
+}
+
+// Forget drops a GPU's state, so it is no longer listed
+func (e *HealthEvaluator) Forget(gpuID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.gpus, gpuID)
+}
+
+// All returns the current health of every GPU seen
+func (e *HealthEvaluator) All() map[string]GPUHealth {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	all := make(map[string]GPUHealth, len(e.gpus))
+	for gpuID, state := range e.gpus {
+		all[gpuID] = e.evaluate(gpuID, state, now)
+	}
+	return all
+}
+
+func (e *HealthEvaluator) evaluate(gpuID string, state *gpuHealthState, now time.Time) GPUHealth {
+	if state == nil {
+		return GPUHealth{State: HealthUnknown, Reasons: []string{"no telemetry received"}}
+	}
+
+	lastSeen := state.lastSeen
+	health := GPUHealth{State: HealthHealthy, LastSeen: &lastSeen}
+	if silent := now.Sub(state.lastSeen); silent > e.staleAfter {
+		health.State = HealthUnknown
+		health.Reasons = []string{fmt.Sprintf("no telemetry for %s", silent.Round(time.Second))}
+		return health
+	}
+
+	for i := range e.rules {
+		if !e.firing(state, i) {
+			continue
+		}
+		health.State = HealthDegraded
+		health.Reasons = append(health.Reasons, e.reason(gpuID, state, i))
+	}
+	return health
+}
+
+// reason describes why rule i is firing, using its description template if
+// it has one
+func (e *HealthEvaluator) reason(gpuID string, state *gpuHealthState, i int) string {
+	rule := e.rules[i]
+	since := state.breachStart[i].UTC().Format(time.RFC3339)
+	if rule.Description == nil {
+		return fmt.Sprintf("%s (since %s)", rule, since)
+	}
+
+	data := HealthRuleData{
+		Rule:     rule.ID(),
+		GPUId:    gpuID,
+		Hostname: state.hostname,
+		Values:   make(map[string]float64, len(rule.Conditions)),
+		Since:    state.breachStart[i],
+	}
+	for _, condition := range rule.Conditions {
+		data.Values[condition.Metric] = state.values[condition.Metric].value
+	}
+	var buf strings.Builder
+	if err := rule.Description.Execute(&buf, data); err != nil {
+		return fmt.Sprintf("%s (since %s)", rule, since)
+	}
+	return fmt.Sprintf("%s (since %s)", buf.String(), since)
+}
+
+// firing reports whether rule i has been breached for at least its duration
+func (e *HealthEvaluator) firing(state *gpuHealthState, i int) bool {
+	start := state.breachStart[i]
+	return !start.IsZero() && state.breachLast[i].Sub(start) >= e.rules[i].For
+}
+
+// staleRule names the rule behind unknown health in alerts
+const staleRule = "stale"
+
+// HealthChange is a change in a GPU's health; changes to degraded or unknown
+// are alerts
+type HealthChange struct {
+	GPUId    string
+	Hostname string
+	Previous HealthState // empty for a newly seen GPU
+	Health   GPUHealth
+	// Rules names the rules that fired (see HealthRule.ID), or "stale" when
+	// health is unknown
+	Rules []string
+}
+
+// Labels identifies the change for silence matching
+func (c HealthChange) Labels() map[string]string {
+	return map[string]string{
+		"gpu_id":   c.GPUId,
+		"hostname": c.Hostname,
+		"state":    string(c.Health.State),
+	}
+}
+
+// transitions evaluates every GPU and returns those whose state changed since
+// the previous call, ordered by GPU ID
+func (e *HealthEvaluator) transitions() []HealthChange {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	var changed []HealthChange
+	for gpuID, state := range e.gpus {
+		health := e.evaluate(gpuID, state, now)
+		if health.State == state.reported {
+			continue
+		}
+
+		change := HealthChange{GPUId: gpuID, Hostname: state.hostname, Previous: state.reported, Health: health}
+		switch health.State {
+		case HealthUnknown:
+			change.Rules = []string{staleRule}
+		case HealthDegraded:
+			for i, rule := range e.rules {
+				if e.firing(state, i) {
+					change.Rules = append(change.Rules, rule.ID())
+				}
+			}
+		}
+		state.reported = health.State
+		changed = append(changed, change)
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].GPUId < changed[j].GPUId
+	})
+	return changed
+}
+
+// healthLoop re-evaluates GPU health periodically and logs state changes.
+// Alerts matching an active silence are logged at info level instead.
+func (c *Collector) healthLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(healthEvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, change := range c.health.transitions() {
+			c.reportHealthChange(change)
+		}
+	}
+}
+
+// reportHealthChange logs a health change and sends notifications for
+// alerts, unless they are covered by a silence. A GPU returning to healthy
+// resolves its alert on the channels that were notified.
+func (c *Collector) reportHealthChange(change HealthChange) {
+	health := change.Health
+	if health.State == HealthHealthy {
+		c.logger.Info("GPU health changed", "gpu_id", change.GPUId, "hostname", change.Hostname, "state", health.State)
+		if change.Previous != "" {
+			c.config.Notifier.Notify(change.alert())
+		}
+		return
+	}
+
+	if silenceID := c.silences.Silencing(change.Labels()); silenceID != "" {
+		c.logger.Info("GPU health alert silenced", "gpu_id", change.GPUId, "hostname", change.Hostname,
+			"state", health.State, "silence_id", silenceID)
+		return
+	}
+
+	c.logger.Warn("GPU health changed", "gpu_id", change.GPUId, "hostname", change.Hostname,
+		"state", health.State, "reasons", strings.Join(health.Reasons, "; "))
+	c.config.Notifier.Notify(change.alert())
+}
+
+// alert builds the notification for a health change
+func (c HealthChange) alert() notify.Alert {
+	source := c.Hostname
+	if source == "" {
+		source = c.GPUId
+	}
+	summary := fmt.Sprintf("GPU %s is %s", c.GPUId, c.Health.State)
+	if c.Hostname != "" {
+		summary = fmt.Sprintf("GPU %s on %s is %s", c.GPUId, c.Hostname, c.Health.State)
+	}
+	return notify.Alert{
+		Key:      "gpu-health/" + c.GPUId,
+		Rules:    c.Rules,
+		Labels:   c.Labels(),
+		Source:   source,
+		Summary:  summary,
+		Reasons:  c.Health.Reasons,
+		Resolved: c.Health.State == HealthHealthy,
+		Time:     time.Now(),
+	}
+}
+
+// GPUHealthEntry pairs a GPU with its health in the collector's GPU listing
+type GPUHealthEntry struct {
+	GPUId  string    `json:"gpu_id"`
+	Health GPUHealth `json:"health"`
+}
+
+// serveGPUHealth handles GET /api/v1/gpus, listing every GPU known to the
+// collector with its health
+func (c *Collector) serveGPUHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// GPUs stored before this collector started, or whose samples have all
+	// left the cache, have not been observed yet
+	gpuIDs := c.knownGPUs()
+	all := c.health.All()
+	for gpuID := range all {
+		gpuIDs = append(gpuIDs, gpuID)
+	}
+	sort.Strings(gpuIDs)
+
+	entries := []GPUHealthEntry{}
+	for i, gpuID := range gpuIDs {
+		if i > 0 && gpuID == gpuIDs[i-1] {
+			continue
+		}
+		health, ok := all[gpuID]
+		if !ok {
+			health = c.health.Health(gpuID)
+		}
+		entries = append(entries, GPUHealthEntry{GPUId: gpuID, Health: health})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"gpus":  entries,
+		"total": len(entries),
+	}); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to encode GPU health response", "error", err)
+	}
+}
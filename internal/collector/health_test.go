@@ -0,0 +1,268 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/notify"
+)
+
+// thresholdRule returns a single-condition rule
+func thresholdRule(metric string, threshold float64, forDuration time.Duration) HealthRule {
+	return HealthRule{Conditions: []HealthCondition{{Metric: metric, Threshold: threshold}}, For: forDuration}
+}
+
+func TestParseHealthRules(t *testing.T) {
+	rules, err := ParseHealthRules(" DCGM_FI_DEV_GPU_TEMP>85:5m, DCGM_FI_DEV_SM_CLOCK<300, " +
+		"hung=DCGM_FI_DEV_GPU_UTIL<5 AND DCGM_FI_DEV_POWER_USAGE>300:10m, util&power=u<5&p>300")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []HealthRule{
+		{Conditions: []HealthCondition{{Metric: "DCGM_FI_DEV_GPU_TEMP", Threshold: 85}}, For: 5 * time.Minute},
+		{Conditions: []HealthCondition{{Metric: "DCGM_FI_DEV_SM_CLOCK", Below: true, Threshold: 300}}},
+		{Name: "hung", Conditions: []HealthCondition{
+			{Metric: "DCGM_FI_DEV_GPU_UTIL", Below: true, Threshold: 5},
+			{Metric: "DCGM_FI_DEV_POWER_USAGE", Threshold: 300},
+		}, For: 10 * time.Minute},
+		{Name: "util&power", Conditions: []HealthCondition{
+			{Metric: "u", Below: true, Threshold: 5},
+			{Metric: "p", Threshold: 300},
+		}},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("Got %+v, want %+v", rules, want)
+	}
+	if got := rules[0].ID(); got != "DCGM_FI_DEV_GPU_TEMP" {
+		t.Errorf("Expected unnamed rule ID to be its metric, got %q", got)
+	}
+	if got := rules[2].String(); got != "hung: DCGM_FI_DEV_GPU_UTIL < 5 and DCGM_FI_DEV_POWER_USAGE > 300 for 10m0s" {
+		t.Errorf("Unexpected rule string %q", got)
+	}
+
+	for _, spec := range []string{">85", "temp=85", "temp>hot", "temp>85:soon", "temp>85&", "hung=u<5 and"} {
+		if _, err := ParseHealthRules(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestHealthEvaluator(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	evaluator := NewHealthEvaluator(HealthConfig{
+		Rules:      []HealthRule{thresholdRule(MetricGPUTemp, 85, 5*time.Minute)},
+		StaleAfter: 10 * time.Minute,
+	})
+	evaluator.now = func() time.Time { return now }
+
+	observe := func(offset time.Duration, temp float64) {
+		evaluator.Observe(&Telemetry{
+			GPUId:     "gpu_0",
+			Timestamp: now.Add(offset),
+			Metrics:   map[string]float64{MetricGPUTemp: temp},
+		})
+	}
+
+	if got := evaluator.Health("gpu_0").State; got != HealthUnknown {
+		t.Errorf("Expected unknown before any telemetry, got %s", got)
+	}
+
+	// Hot for four minutes is not yet degraded
+	observe(0, 90)
+	observe(4*time.Minute, 91)
+	if got := evaluator.Health("gpu_0").State; got != HealthHealthy {
+		t.Errorf("Expected healthy after 4m hot, got %s", got)
+	}
+
+	observe(5*time.Minute, 92)
+	health := evaluator.Health("gpu_0")
+	if health.State != HealthDegraded || len(health.Reasons) != 1 {
+		t.Errorf("Expected degraded with one reason after 5m hot, got %+v", health)
+	}
+
+	// Cooling down resets the rule
+	observe(6*time.Minute, 70)
+	if got := evaluator.Health("gpu_0").State; got != HealthHealthy {
+		t.Errorf("Expected healthy after cooling, got %s", got)
+	}
+
+	// Silence makes health unknown
+	now = now.Add(11 * time.Minute)
+	if got := evaluator.Health("gpu_0").State; got != HealthUnknown {
+		t.Errorf("Expected unknown after 11m without data, got %s", got)
+	}
+}
+
+func TestHealthEvaluatorTransitions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	evaluator := NewHealthEvaluator(HealthConfig{
+		Rules: []HealthRule{thresholdRule(MetricGPUTemp, 85, 0)},
+	})
+	evaluator.now = func() time.Time { return now }
+
+	evaluator.Observe(&Telemetry{GPUId: "gpu_0", Timestamp: now, Metrics: map[string]float64{MetricGPUTemp: 60}})
+	if changed := evaluator.transitions(); len(changed) != 1 || changed[0].Health.State != HealthHealthy {
+		t.Errorf("Expected transition to healthy, got %+v", changed)
+	}
+	if changed := evaluator.transitions(); len(changed) != 0 {
+		t.Errorf("Expected no transitions without change, got %+v", changed)
+	}
+
+	evaluator.Observe(&Telemetry{GPUId: "gpu_0", Timestamp: now, Metrics: map[string]float64{MetricGPUTemp: 90}})
+	changed := evaluator.transitions()
+	if len(changed) != 1 || changed[0].Health.State != HealthDegraded || changed[0].Previous != HealthHealthy {
+		t.Fatalf("Expected transition from healthy to degraded, got %+v", changed)
+	}
+	if !reflect.DeepEqual(changed[0].Rules, []string{MetricGPUTemp}) {
+		t.Errorf("Expected %s rule to fire, got %v", MetricGPUTemp, changed[0].Rules)
+	}
+}
+
+func TestHealthEvaluatorCompositeRule(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"rules": [{
+		"name": "hung_process",
+		"condition": "DCGM_FI_DEV_GPU_UTIL < 5 AND DCGM_FI_DEV_POWER_USAGE > 300",
+		"for": "10m",
+		"match": {"hostname": "dgx-*"},
+		"description": "{{.GPUId}} on {{.Hostname}} may be hung ({{index .Values \"DCGM_FI_DEV_POWER_USAGE\"}}W)"
+	}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadHealthRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evaluator := NewHealthEvaluator(HealthConfig{Rules: rules})
+	evaluator.now = func() time.Time { return now }
+
+	// Metrics arrive in separate samples, as they do from the streamer
+	observe := func(gpuID, hostname string, offset time.Duration, metric string, value float64) {
+		evaluator.Observe(&Telemetry{
+			GPUId:     gpuID,
+			Hostname:  hostname,
+			Timestamp: now.Add(offset),
+			Metrics:   map[string]float64{metric: value},
+		})
+	}
+	for _, gpu := range []struct{ id, host string }{{"gpu_0", "dgx-01"}, {"gpu_1", "workstation"}} {
+		for _, offset := range []time.Duration{0, 10 * time.Minute} {
+			observe(gpu.id, gpu.host, offset, MetricGPUUtil, 1)
+			observe(gpu.id, gpu.host, offset, MetricPowerUsage, 350)
+		}
+	}
+
+	health := evaluator.Health("gpu_0")
+	if health.State != HealthDegraded || len(health.Reasons) != 1 ||
+		!strings.HasPrefix(health.Reasons[0], "gpu_0 on dgx-01 may be hung (350W) (since 2024-01-01T12:00:00Z)") {
+		t.Errorf("Expected gpu_0 degraded by hung_process, got %+v", health)
+	}
+	// The rule only applies to dgx hosts
+	if got := evaluator.Health("gpu_1").State; got != HealthHealthy {
+		t.Errorf("Expected gpu_1 healthy, got %s", got)
+	}
+
+	// Utilization picking up clears the breach even though power stays high
+	observe("gpu_0", "dgx-01", 11*time.Minute, MetricGPUUtil, 80)
+	if got := evaluator.Health("gpu_0").State; got != HealthHealthy {
+		t.Errorf("Expected gpu_0 healthy once busy, got %s", got)
+	}
+
+	if changed := evaluator.transitions(); len(changed) != 2 {
+		t.Errorf("Expected two transitions, got %+v", changed)
+	}
+}
+
+func TestCollectorGPUHealthEndpoint(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		Health:           HealthConfig{Rules: []HealthRule{thresholdRule(MetricGPUTemp, 85, 0)}},
+	})
+
+	for gpuID, temp := range map[string]float64{"gpu_0": 60, "gpu_1": 95} {
+		payload, _ := json.Marshal(StreamerMessage{
+			Timestamp: time.Now(),
+			Fields:    map[string]interface{}{"gpu_id": gpuID, "metric_name": MetricGPUTemp, "value": temp},
+		})
+		if err := c.handleMessage(0, mq.Message{Payload: payload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	c.serveGPUHealth(rr, httptest.NewRequest("GET", "/api/v1/gpus", nil))
+
+	var response struct {
+		GPUs  []GPUHealthEntry `json:"gpus"`
+		Total int              `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 2 {
+		t.Fatalf("Expected 2 GPUs, got %+v", response)
+	}
+	if response.GPUs[0].GPUId != "gpu_0" || response.GPUs[0].Health.State != HealthHealthy {
+		t.Errorf("Expected gpu_0 healthy, got %+v", response.GPUs[0])
+	}
+	if response.GPUs[1].GPUId != "gpu_1" || response.GPUs[1].Health.State != HealthDegraded {
+		t.Errorf("Expected gpu_1 degraded, got %+v", response.GPUs[1])
+	}
+}
+
+func TestReportHealthChangeNotifies(t *testing.T) {
+	var mu sync.Mutex
+	var alerts []notify.Alert
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert notify.Alert
+		_ = json.NewDecoder(r.Body).Decode(&alert)
+		mu.Lock()
+		alerts = append(alerts, alert)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	notifier, err := notify.NewDispatcher(notify.Config{Channels: []notify.ChannelConfig{
+		{Name: "hook", Type: "webhook", URL: hook.URL},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: t.TempDir(), Notifier: notifier})
+	if _, err := c.silences.Add(Silence{
+		Matchers:  map[string]string{"hostname": "host-b"},
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "alice",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	degraded := GPUHealth{State: HealthDegraded, Reasons: []string{"too hot"}}
+	c.reportHealthChange(HealthChange{GPUId: "gpu_0", Hostname: "host-a", Previous: HealthHealthy, Health: degraded, Rules: []string{MetricGPUTemp}})
+	c.reportHealthChange(HealthChange{GPUId: "gpu_1", Hostname: "host-b", Previous: HealthHealthy, Health: degraded, Rules: []string{MetricGPUTemp}})
+	c.reportHealthChange(HealthChange{GPUId: "gpu_0", Hostname: "host-a", Previous: HealthDegraded, Health: GPUHealth{State: HealthHealthy}})
+	notifier.Close(5 * time.Second)
+
+	// gpu_1 is silenced
+	if len(alerts) != 2 {
+		t.Fatalf("Expected firing and resolved alerts for gpu_0, got %+v", alerts)
+	}
+	if alerts[0].Key != "gpu-health/gpu_0" || alerts[0].Resolved || alerts[0].Summary != "GPU gpu_0 on host-a is degraded" {
+		t.Errorf("Unexpected firing alert %+v", alerts[0])
+	}
+	if !alerts[1].Resolved {
+		t.Errorf("Expected resolved alert, got %+v", alerts[1])
+	}
+}
@@ -0,0 +1,414 @@
+package collector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+const (
+	// DefaultWriters is the number of storage writers telemetry is sharded
+	// across
+	DefaultWriters = 4
+	// writerQueueSize bounds the decoded samples waiting for each writer
+	writerQueueSize = 256
+	// retiredCheckpoint accumulates the processed counts of pruned writer
+	// checkpoints, so the total across checkpoints survives a change in the
+	// number of writers
+	retiredCheckpoint = "retired"
+	// checkpointBatch is how many stored messages update a writer's
+	// checkpoint without waiting for the checkpoint interval
+	checkpointBatch = 100
+	// diskWaitInterval is how often a writer paused for lack of free disk
+	// space checks it again
+	diskWaitInterval = time.Second
+	// walDir is the directory in DataDir holding the writers' write-ahead
+	// logs
+	walDir = "wal"
+	// walBatch bounds the queued samples a writer logs with a single sync
+	walBatch = writerQueueSize
+)
+
+// writeJob is a decoded sample waiting to be stored. msg is acknowledged once
+// it is; samples flushed from sampling reservoirs have no message.
+type writeJob struct {
+	telemetry *Telemetry
+	msg       *mq.Message
+}
+
+// newWriteQueues creates one queue per storage writer
+func newWriteQueues(n int) []chan writeJob {
+	if n <= 0 {
+		n = 1
+	}
+	queues := make([]chan writeJob, n)
+	for i := range queues {
+		queues[i] = make(chan writeJob, writerQueueSize)
+	}
+	return queues
+}
+
+// registerWriterMetrics exposes the writer queue depths
+func (c *Collector) registerWriterMetrics() {
+	c.metrics.NewGaugeFunc("collector_writer_queue_depth",
+		"Decoded samples waiting to be stored, per storage writer.", []string{"writer"},
+		func(emit func(float64, ...string)) {
+			for i, queue := range c.writeQueues {
+				emit(float64(len(queue)), strconv.Itoa(i))
+			}
+		})
+}
+
+// startWriters starts one storage writer per queue. Decoding runs in
+// parallel on the workers, while each GPU's samples all go to the same writer
+// and are stored in the order they were decoded.
+func (c *Collector) startWriters() {
+	for i, queue := range c.writeQueues {
+		c.wg.Add(1)
+		go c.writer(i, queue)
+	}
+}
+
+// stopWriters lets the writers store what is queued and exit. The workers
+// must have stopped first.
+func (c *Collector) stopWriters() {
+	c.stopWritersOnce.Do(func() {
+		for _, queue := range c.writeQueues {
+			close(queue)
+		}
+	})
+}
+
+// shard returns the writer queue for a GPU
+func (c *Collector) shard(gpuID string) chan writeJob {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(gpuID))
+	return c.writeQueues[h.Sum32()%uint32(len(c.writeQueues))]
+}
+
+// enqueue hands a decoded sample to its GPU's writer. It returns false if the
+// collector stopped while waiting for room in the queue.
+func (c *Collector) enqueue(job writeJob) bool {
+	select {
+	case c.shard(job.telemetry.GPUId) <- job:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// writer stores the samples of one shard in order, acknowledging each message
+// once its sample is stored. With a write-ahead log, it takes whatever is
+// queued as a batch and logs it with one sync before storing it, and empties
+// the log as it checkpoints. Its checkpoint is updated every checkpointBatch
+// messages, at least every checkpoint interval while messages trickle in,
+// and when it stops. It drains its queue before exiting.
+func (c *Collector) writer(writerID int, queue <-chan writeJob) {
+	defer c.wg.Done()
+
+	var wal *persistence.TelemetryWAL
+	if c.wals != nil {
+		wal = c.wals[writerID]
+		defer func() {
+			if err := wal.Close(); err != nil {
+				c.logger.Warn("Failed to close write-ahead log", "path", wal.Path(), "error", err)
+			}
+		}()
+	}
+
+	acks := mq.NewAckBatcher(c.config.AckBatchSize, c.config.AckBatchInterval)
+	defer acks.Flush()
+
+	checkpointName := writerCheckpointName(writerID)
+	stored, checkpointed := 0, 0
+	// logged holds the GPUs with samples in the write-ahead log
+	logged := make(map[string]bool)
+	// checkpoint counts the messages stored since the last update, once
+	// they have all been acknowledged, and empties the write-ahead log once
+	// the samples in it are synced to their telemetry files
+	checkpoint := func() {
+		if stored == checkpointed && len(logged) == 0 {
+			return
+		}
+		acks.Flush()
+		if len(logged) > 0 && c.resetWAL(writerID, wal, logged) {
+			logged = make(map[string]bool)
+		}
+		if c.checkpointMgr != nil && stored > checkpointed {
+			if err := c.checkpointMgr.UpdateProcessedCount(checkpointName, int64(stored-checkpointed)); err != nil {
+				c.logger.Error("Writer failed to update checkpoint", "writer_id", writerID, "error", err)
+				return
+			}
+		}
+		checkpointed = stored
+	}
+
+	var tick <-chan time.Time
+	if c.checkpointMgr != nil || wal != nil {
+		ticker := time.NewTicker(c.checkpointInterval())
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	batch := make([]writeJob, 0, walBatch)
+	for {
+		select {
+		case job, ok := <-queue:
+			if !ok {
+				checkpoint()
+				c.logger.Info("Writer stopped", "writer_id", writerID, "messages_stored", stored)
+				return
+			}
+			batch = append(batch[:0], job)
+			if wal != nil {
+				batch = drainBatch(queue, batch)
+			}
+
+			// A sample that could not be logged or stored is left
+			// unacknowledged, so the broker redelivers it
+			if !c.waitForDisk(writerID) {
+				continue
+			}
+			if wal != nil && !c.logBatch(writerID, wal, batch, logged) {
+				continue
+			}
+			for _, job := range batch {
+				if err := c.store(job.telemetry); err != nil || job.msg == nil {
+					continue
+				}
+				acks.Ack(*job.msg)
+				stored++
+			}
+			if stored-checkpointed >= checkpointBatch {
+				checkpoint()
+			}
+		case <-tick:
+			checkpoint()
+		}
+	}
+}
+
+// drainBatch adds the jobs already queued to batch, up to walBatch
+func drainBatch(queue <-chan writeJob, batch []writeJob) []writeJob {
+	for len(batch) < walBatch {
+		select {
+		case job, ok := <-queue:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, job)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// logBatch appends a batch of samples to a writer's write-ahead log, adding
+// their GPUs to logged. It reports whether the batch was logged.
+func (c *Collector) logBatch(writerID int, wal *persistence.TelemetryWAL, batch []writeJob, logged map[string]bool) bool {
+	records := make([]persistence.Telemetry, len(batch))
+	for i, job := range batch {
+		// Log the sample under the GPU it will be stored as
+		job.telemetry.GPUId = c.aliases.Resolve(job.telemetry.GPUId)
+		records[i] = job.telemetry.record()
+	}
+	if err := wal.Append(records); err != nil {
+		c.logger.Error("Writer failed to log samples", "writer_id", writerID, "samples", len(records), "error", err)
+		return false
+	}
+	for _, record := range records {
+		logged[record.GPUId] = true
+	}
+	return true
+}
+
+// resetWAL syncs the telemetry files of the GPUs in a writer's write-ahead
+// log and then empties it. It reports whether it did; the log is kept if the
+// files could not be synced.
+func (c *Collector) resetWAL(writerID int, wal *persistence.TelemetryWAL, logged map[string]bool) bool {
+	if syncer, ok := c.fileStorage.(persistence.TelemetrySyncer); ok {
+		gpuIDs := make([]string, 0, len(logged))
+		for gpuID := range logged {
+			gpuIDs = append(gpuIDs, gpuID)
+		}
+		if err := syncer.SyncTelemetry(gpuIDs); err != nil {
+			c.logger.Error("Writer failed to sync telemetry files", "writer_id", writerID, "error", err)
+			return false
+		}
+	}
+	if err := wal.Reset(); err != nil {
+		c.logger.Error("Writer failed to reset write-ahead log", "writer_id", writerID, "error", err)
+		return false
+	}
+	return true
+}
+
+// walPath is the path of a storage writer's write-ahead log
+func (c *Collector) walPath(writerID int) string {
+	return filepath.Join(c.config.DataDir, walDir, writerCheckpointName(writerID)+".wal")
+}
+
+// replayWALs stores the samples left in the writers' write-ahead logs by a
+// collector that stopped before syncing them to the telemetry files, then
+// removes the logs. It replays logs whatever the current writer count, and
+// whether or not write-ahead logging is still enabled.
+func (c *Collector) replayWALs() error {
+	paths, err := filepath.Glob(filepath.Join(c.config.DataDir, walDir, "*.wal"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		gpuIDs, err := persistence.ReplayTelemetryWAL(path, c.config.Keyring, c.fileStorage)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove replayed write-ahead log: %w", err)
+		}
+		if len(gpuIDs) > 0 {
+			c.logger.Info("Replayed write-ahead log", "path", path, "gpus", len(gpuIDs))
+		}
+	}
+	return nil
+}
+
+// openWALs opens a write-ahead log for each storage writer when enabled
+func (c *Collector) openWALs() error {
+	if !c.config.WALEnabled {
+		return nil
+	}
+	c.wals = make([]*persistence.TelemetryWAL, len(c.writeQueues))
+	for i := range c.writeQueues {
+		wal, err := persistence.OpenTelemetryWAL(c.walPath(i), c.config.Keyring)
+		if err != nil {
+			c.closeWALs()
+			return err
+		}
+		c.wals[i] = wal
+	}
+	return nil
+}
+
+// closeWALs closes the write-ahead logs of writers that never started
+func (c *Collector) closeWALs() {
+	for _, wal := range c.wals {
+		if wal != nil {
+			_ = wal.Close()
+		}
+	}
+	c.wals = nil
+}
+
+// checkpointInterval is the longest a writer's checkpoint goes without an
+// update while it stores messages
+func (c *Collector) checkpointInterval() time.Duration {
+	if c.config.CheckpointInterval <= 0 {
+		return DefaultCheckpointInterval
+	}
+	return c.config.CheckpointInterval
+}
+
+// registerDiskMetrics exposes the data directory's disk usage and the
+// writer pauses for lack of free space
+func (c *Collector) registerDiskMetrics() {
+	c.metrics.NewGaugeFunc("collector_disk_free_bytes",
+		"Free bytes on the filesystem holding the data directory.", []string{"dir"},
+		func(emit func(float64, ...string)) {
+			if used, size, err := c.disk.Usage(); err == nil {
+				emit(float64(size-used), c.disk.Dir())
+			}
+		})
+	c.metrics.NewGaugeFunc("collector_disk_size_bytes",
+		"Size of the filesystem holding the data directory.", []string{"dir"},
+		func(emit func(float64, ...string)) {
+			if _, size, err := c.disk.Usage(); err == nil {
+				emit(float64(size), c.disk.Dir())
+			}
+		})
+	c.diskPauses = c.metrics.NewCounterVec("collector_disk_full_pauses_total",
+		"Times a storage writer paused because the data directory's disk was nearly full.").WithLabelValues()
+}
+
+// waitForDisk blocks while the data directory's filesystem has less free
+// space than the minimum. The paused writer's queue then fills, which stops
+// the workers taking messages from the broker until space is freed. It
+// returns false if the collector stopped while waiting.
+func (c *Collector) waitForDisk(writerID int) bool {
+	if c.disk == nil {
+		return true
+	}
+	err := c.disk.Check()
+	if err == nil {
+		return true
+	}
+	c.diskPauses.Inc()
+	c.logger.Warn("Writer paused, disk is nearly full", "writer_id", writerID, "error", err)
+	c.events.emit(EventGuardrail, "Writer paused, disk is nearly full",
+		"guardrail", "disk_full", "state", "paused", "writer_id", strconv.Itoa(writerID), "error", err.Error())
+
+	ticker := time.NewTicker(diskWaitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.disk.Check(); err == nil {
+				c.logger.Info("Writer resumed, disk space freed", "writer_id", writerID)
+				c.events.emit(EventGuardrail, "Writer resumed, disk space freed",
+					"guardrail", "disk_full", "state", "resumed", "writer_id", strconv.Itoa(writerID))
+				return true
+			}
+		case <-c.ctx.Done():
+			return false
+		}
+	}
+}
+
+// writerCheckpointName names the checkpoint of a storage writer
+func writerCheckpointName(writerID int) string {
+	return fmt.Sprintf("writer-%d", writerID)
+}
+
+// compactCheckpoints prunes checkpoints of writers that no longer exist,
+// since the writer count was lowered or from before telemetry was stored by
+// writers rather than workers, folding their counts into retiredCheckpoint.
+// The checkpoint file is migrated to the current layout on the way.
+func (c *Collector) compactCheckpoints() error {
+	current := make(map[string]bool, len(c.writeQueues)+1)
+	for i := range c.writeQueues {
+		current[writerCheckpointName(i)] = true
+	}
+	current[retiredCheckpoint] = true
+
+	result, err := c.checkpointMgr.CompactCheckpoints(func(name string, _ *persistence.Checkpoint) bool {
+		return current[name] || !(strings.HasPrefix(name, "writer-") || strings.HasPrefix(name, "worker-"))
+	})
+	if err != nil {
+		return err
+	}
+	if result.MigratedFrom != 0 {
+		c.logger.Info("Migrated checkpoint file", "path", c.config.CheckpointDir,
+			"from_version", result.MigratedFrom, "to_version", persistence.CheckpointFileVersion)
+	}
+	if len(result.Removed) == 0 {
+		return nil
+	}
+
+	var retired int64
+	names := make([]string, 0, len(result.Removed))
+	for name, checkpoint := range result.Removed {
+		retired += checkpoint.ProcessedCount
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.logger.Info("Pruned stale writer checkpoints", "checkpoints", names, "processed_count", retired)
+	return c.checkpointMgr.UpdateProcessedCount(retiredCheckpoint, retired)
+}
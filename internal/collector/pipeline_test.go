@@ -0,0 +1,330 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence/persistencetest"
+)
+
+func TestPipelinePreservesPerGPUOrder(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	c := NewCollector(broker, CollectorConfig{
+		Workers:          1,
+		Writers:          3,
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 1000,
+		HealthPort:       "127.0.0.1:0",
+		MQTopic:          "pipeline",
+		AckBatchSize:     10,
+		AckBatchInterval: time.Hour,
+	})
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	const gpus, samples = 5, 60
+	base := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < samples; i++ {
+		for g := 0; g < gpus; g++ {
+			payload, _ := json.Marshal(StreamerMessage{
+				Timestamp: base.Add(time.Duration(i) * time.Second),
+				Fields:    map[string]interface{}{"gpu_id": fmt.Sprintf("gpu_%d", g), "metric_name": "m", "value": float64(i)},
+			})
+			if err := broker.Publish("pipeline", mq.Message{Payload: payload}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Stay within the subscriber's channel buffer
+		time.Sleep(time.Millisecond)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(c.memoryStorage.GetTelemetryForGPU(fmt.Sprintf("gpu_%d", gpus-1))) == samples {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Stop()
+
+	for g := 0; g < gpus; g++ {
+		stored := c.memoryStorage.GetTelemetryForGPU(fmt.Sprintf("gpu_%d", g))
+		if len(stored) != samples {
+			t.Fatalf("gpu_%d: expected %d samples, got %d", g, samples, len(stored))
+		}
+		for i, tel := range stored {
+			if tel.Metrics["m"] != float64(i) {
+				t.Fatalf("gpu_%d: sample %d stored out of order (value %v)", g, i, tel.Metrics["m"])
+			}
+		}
+	}
+
+	// The writers flush their batched acks on shutdown
+	if pending := broker.GetStats().Topics["pipeline"].PendingMessages; pending != 0 {
+		t.Errorf("Expected every stored message acknowledged, %d pending", pending)
+	}
+}
+
+func TestPipelineShardIsStable(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: t.TempDir(), Writers: 4})
+	used := map[chan writeJob]bool{}
+	for g := 0; g < 64; g++ {
+		id := fmt.Sprintf("GPU-%d", g)
+		if c.shard(id) != c.shard(id) {
+			t.Fatalf("Expected %s to always map to the same writer", id)
+		}
+		used[c.shard(id)] = true
+	}
+	if len(used) != 4 {
+		t.Errorf("Expected GPUs spread over 4 writers, got %d", len(used))
+	}
+}
+
+func TestCompactCheckpointsPrunesStaleWriters(t *testing.T) {
+	dir := t.TempDir()
+	checkpointFile := filepath.Join(dir, "checkpoints.json")
+	// A version 1 file from a collector with 4 writers, and older workers
+	legacy := `{"worker-0":{"processed_count":1000},"writer-0":{"processed_count":100},"writer-1":{"processed_count":100},` +
+		`"writer-2":{"processed_count":100},"writer-3":{"processed_count":100},"import":{"processed_count":7}}`
+	if err := os.WriteFile(checkpointFile, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		Writers:           2,
+		DataDir:           filepath.Join(dir, "data"),
+		CheckpointEnabled: true,
+		CheckpointDir:     checkpointFile,
+	})
+	if err := c.compactCheckpoints(); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := persistence.NewCheckpointManager(checkpointFile).GetAllCheckpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || all["writer-0"] == nil || all["writer-1"] == nil || all["import"] == nil {
+		t.Errorf("Expected current writers and other checkpoints kept, got %v", all)
+	}
+	if retired := all[retiredCheckpoint]; retired == nil || retired.ProcessedCount != 1200 {
+		t.Errorf("Expected pruned counts folded into %s, got %+v", retiredCheckpoint, retired)
+	}
+
+	// Nothing changes on the next start
+	if err := c.compactCheckpoints(); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := persistence.NewCheckpointManager(checkpointFile).GetAllCheckpoints(); again[retiredCheckpoint].ProcessedCount != 1200 {
+		t.Errorf("Expected the retired count unchanged, got %+v", again[retiredCheckpoint])
+	}
+}
+
+func TestPipelineQuarantinesUndecodableMessages(t *testing.T) {
+	config := mq.DefaultBrokerConfig()
+	config.PoisonThreshold = 2
+	broker := mq.NewBroker(config)
+	defer broker.Close()
+	poisoned, unsubscribe, err := broker.SubscribeWithAck(mq.PoisonTopic("pipeline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	c := NewCollector(broker, CollectorConfig{
+		Workers:          1,
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		HealthPort:       "127.0.0.1:0",
+		MQTopic:          "pipeline",
+	})
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	if err := broker.Publish("pipeline", mq.Message{Payload: []byte(`{"fields":`)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-poisoned:
+		if msg.Headers[mq.HeaderPoisonFailures] != "3" || msg.Headers[mq.HeaderPoisonError] == "" {
+			t.Errorf("Unexpected poison headers %v", msg.Headers)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the undecodable message nacked until quarantined")
+	}
+}
+
+func TestPipelinePausesWritesOnFullDisk(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := persistence.DiskUsage(dir); err != nil {
+		t.Skipf("Disk usage not supported: %v", err)
+	}
+
+	// No filesystem has this much free
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: dir, MinFreeDiskBytes: 1 << 62})
+	done := make(chan bool)
+	go func() { done <- c.waitForDisk(0) }()
+	select {
+	case <-done:
+		t.Fatal("Expected the writer paused on a full disk")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.cancel()
+	if <-done {
+		t.Error("Expected a writer paused at shutdown to store nothing")
+	}
+	if c.diskPauses.Value() != 1 {
+		t.Errorf("Expected 1 pause counted, got %v", c.diskPauses.Value())
+	}
+
+	if c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: dir, MinFreeDiskBytes: 1}); !c.waitForDisk(0) {
+		t.Error("Expected writes allowed with space free")
+	}
+}
+
+func TestWriterCheckpointsOnIntervalAndStop(t *testing.T) {
+	checkpoints := persistencetest.NewCheckpointStore()
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		DataDir:            t.TempDir(),
+		MaxEntriesPerGPU:   10,
+		CheckpointEnabled:  true,
+		Checkpoints:        checkpoints,
+		CheckpointInterval: 20 * time.Millisecond,
+		AckBatchSize:       10,
+		AckBatchInterval:   time.Hour,
+	})
+	processed := func() int64 {
+		checkpoint, err := checkpoints.LoadCheckpoint(writerCheckpointName(0))
+		if err != nil {
+			return 0
+		}
+		return checkpoint.ProcessedCount
+	}
+
+	acked := 0
+	queue := make(chan writeJob)
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			queue <- writeJob{
+				telemetry: &Telemetry{GPUId: "gpu_0", Timestamp: time.Now(), Metrics: map[string]float64{"m": 1}},
+				msg:       &mq.Message{Ack: func() { acked++ }},
+			}
+		}
+	}
+	c.wg.Add(1)
+	go c.writer(0, queue)
+
+	// Far fewer messages than a batch are checkpointed, and acknowledged
+	// first, once the interval passes
+	send(3)
+	deadline := time.Now().Add(2 * time.Second)
+	for processed() != 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := processed(); got != 3 {
+		t.Fatalf("Expected 3 messages checkpointed after the interval, got %d", got)
+	}
+
+	// Stopping checkpoints what is left
+	send(2)
+	close(queue)
+	c.wg.Wait()
+	if got := processed(); got != 5 || acked != 5 {
+		t.Errorf("Expected 5 messages checkpointed and acknowledged on stop, got %d and %d", got, acked)
+	}
+}
+
+func TestWriterWriteAheadLog(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		DataDir:          dir,
+		MaxEntriesPerGPU: 10,
+		WALEnabled:       true,
+		AckBatchSize:     1,
+	})
+	if err := c.openWALs(); err != nil {
+		t.Fatal(err)
+	}
+
+	acked := 0
+	base := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+	sample := func(i int) *Telemetry {
+		return &Telemetry{GPUId: "gpu_0", Timestamp: base.Add(time.Duration(i) * time.Second), Metrics: map[string]float64{"m": float64(i)}}
+	}
+	queue := make(chan writeJob, 3)
+	for i := 0; i < 3; i++ {
+		queue <- writeJob{telemetry: sample(i), msg: &mq.Message{Ack: func() { acked++ }}}
+	}
+	close(queue)
+	c.wg.Add(1)
+	c.writer(0, queue)
+
+	// The samples are stored and acknowledged, and the log emptied once
+	// they are synced
+	stored, _ := c.fileStorage.ReadTelemetryFile("gpu_0")
+	if len(stored) != 3 || acked != 3 {
+		t.Fatalf("Expected 3 samples stored and acknowledged, got %d and %d", len(stored), acked)
+	}
+	if info, err := os.Stat(c.walPath(0)); err != nil || info.Size() != 0 {
+		t.Fatalf("Expected an empty write-ahead log, got %v, %v", info, err)
+	}
+
+	// Samples logged before a crash are stored on restart, once each
+	wal, err := persistence.OpenTelemetryWAL(c.walPath(1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wal.Append([]persistence.Telemetry{sample(2).record(), sample(3).record()}); err != nil {
+		t.Fatal(err)
+	}
+	_ = wal.Close()
+	restarted := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: dir, MaxEntriesPerGPU: 10})
+	if err := restarted.replayWALs(); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := restarted.fileStorage.ReadTelemetryFile("gpu_0"); len(stored) != 4 {
+		t.Errorf("Expected the logged sample replayed once, got %d samples", len(stored))
+	}
+	if logs, _ := filepath.Glob(filepath.Join(dir, walDir, "*.wal")); len(logs) != 0 {
+		t.Errorf("Expected replayed logs removed, got %v", logs)
+	}
+}
+
+func TestWriterLeavesFailedWritesUnacknowledged(t *testing.T) {
+	storage := persistencetest.NewTelemetryStore()
+	storage.FailWrites(fmt.Errorf("disk full"))
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		Storage:          storage,
+		WALEnabled:       true,
+	})
+	if err := c.openWALs(); err != nil {
+		t.Fatal(err)
+	}
+
+	acked := false
+	queue := make(chan writeJob, 1)
+	queue <- writeJob{
+		telemetry: &Telemetry{GPUId: "gpu_0", Timestamp: time.Now(), Metrics: map[string]float64{"m": 1}},
+		msg:       &mq.Message{Ack: func() { acked = true }},
+	}
+	close(queue)
+	c.wg.Add(1)
+	c.writer(0, queue)
+
+	if acked {
+		t.Error("Expected a sample that failed to store left for redelivery")
+	}
+}
@@ -0,0 +1,462 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// DefaultQuarantineMaxBytes caps the payload bytes kept in quarantine
+const DefaultQuarantineMaxBytes = 64 << 20
+
+// Files of a quarantined message: its details and its payload as received
+const (
+	quarantineInfoExt    = ".json"
+	quarantinePayloadExt = ".payload"
+)
+
+// ErrQuarantineNotFound is returned for an unknown quarantined message ID
+var ErrQuarantineNotFound = errors.New("quarantined message not found")
+
+// QuarantineConfig keeps messages the collector cannot decode for
+// inspection and re-ingestion instead of losing them
+type QuarantineConfig struct {
+	// Dir holds the quarantined messages; empty disables quarantine
+	Dir string
+	// MaxBytes caps the payload bytes kept, evicting the oldest messages;
+	// zero uses DefaultQuarantineMaxBytes
+	MaxBytes int64
+}
+
+// QuarantinedMessage describes a message that failed to decode
+type QuarantinedMessage struct {
+	ID string `json:"id"`
+	// MessageID is the broker's ID of the message
+	MessageID string            `json:"message_id,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	// Error is what the latest attempt to decode the message failed with
+	Error         string    `json:"error"`
+	Failures      int       `json:"failures"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+	SizeBytes     int64     `json:"size_bytes"`
+}
+
+// QuarantineStore keeps the payloads of undecodable messages in a directory,
+// each with a file describing why it failed. A message failing again, such
+// as on redelivery, updates its entry instead of adding another.
+type QuarantineStore struct {
+	mu        sync.Mutex
+	dir       string
+	maxBytes  int64
+	keyring   *encryption.Keyring
+	messages  map[string]*QuarantinedMessage
+	byMessage map[string]string // broker message ID -> quarantine ID
+	bytes     int64
+	now       func() time.Time
+}
+
+// NewQuarantineStore creates a store in dir keeping at most maxBytes of
+// payloads, zero meaning DefaultQuarantineMaxBytes. Payloads are encrypted
+// at rest with keyring when it is not nil.
+func NewQuarantineStore(dir string, maxBytes int64, keyring *encryption.Keyring) *QuarantineStore {
+	if maxBytes <= 0 {
+		maxBytes = DefaultQuarantineMaxBytes
+	}
+	return &QuarantineStore{
+		dir:       dir,
+		maxBytes:  maxBytes,
+		keyring:   keyring,
+		messages:  make(map[string]*QuarantinedMessage),
+		byMessage: make(map[string]string),
+		now:       time.Now,
+	}
+}
+
+// Load creates the directory and indexes the messages already in it
+func (q *QuarantineStore) Load() error {
+	if err := os.MkdirAll(q.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(q.dir, "*"+quarantineInfoExt))
+	if err != nil {
+		return fmt.Errorf("failed to list quarantined messages: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read quarantined message: %w", err)
+		}
+		var msg QuarantinedMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.ID != strings.TrimSuffix(filepath.Base(path), quarantineInfoExt) {
+			return fmt.Errorf("invalid quarantined message %s", path)
+		}
+		// A crash between writing the payload and its details leaves one
+		// without the other; the details alone are of no use
+		if _, err := os.Stat(q.path(msg.ID, quarantinePayloadExt)); err != nil {
+			_ = os.Remove(path)
+			continue
+		}
+		q.index(&msg)
+	}
+	return nil
+}
+
+// Add quarantines msg, which failed to decode with cause, evicting the
+// oldest messages if the store would exceed its size. It returns the
+// quarantined message and how many were evicted to make room.
+func (q *QuarantineStore) Add(msg mq.Message, cause error) (QuarantinedMessage, int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if id, ok := q.byMessage[msg.ID]; ok && msg.ID != "" {
+		updated, err := q.failedAgain(q.messages[id], cause)
+		return updated, 0, err
+	}
+
+	size := int64(len(msg.Payload))
+	if size > q.maxBytes {
+		return QuarantinedMessage{}, 0, fmt.Errorf("message of %d bytes exceeds the quarantine size of %d bytes", size, q.maxBytes)
+	}
+	evicted := 0
+	for q.bytes+size > q.maxBytes {
+		if err := q.remove(q.oldest()); err != nil {
+			return QuarantinedMessage{}, evicted, err
+		}
+		evicted++
+	}
+
+	now := q.now().UTC()
+	quarantined := &QuarantinedMessage{
+		ID:            mq.NewMessageID(),
+		MessageID:     msg.ID,
+		Headers:       msg.Headers,
+		Error:         cause.Error(),
+		Failures:      1,
+		FirstFailedAt: now,
+		LastFailedAt:  now,
+		SizeBytes:     size,
+	}
+	payload, err := q.keyring.Seal(msg.Payload)
+	if err != nil {
+		return QuarantinedMessage{}, evicted, fmt.Errorf("failed to encrypt quarantined payload: %w", err)
+	}
+	if err := os.WriteFile(q.path(quarantined.ID, quarantinePayloadExt), payload, 0600); err != nil {
+		return QuarantinedMessage{}, evicted, fmt.Errorf("failed to write quarantined payload: %w", err)
+	}
+	if err := q.writeInfo(quarantined); err != nil {
+		_ = os.Remove(q.path(quarantined.ID, quarantinePayloadExt))
+		return QuarantinedMessage{}, evicted, err
+	}
+	q.index(quarantined)
+	return *quarantined, evicted, nil
+}
+
+// List returns the quarantined messages, oldest first
+func (q *QuarantineStore) List() []QuarantinedMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := make([]QuarantinedMessage, 0, len(q.messages))
+	for _, msg := range q.messages {
+		list = append(list, *msg)
+	}
+	// IDs are ULIDs, so they sort by when the message was quarantined
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Get returns a quarantined message and its payload
+func (q *QuarantineStore) Get(id string) (QuarantinedMessage, []byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	msg, ok := q.messages[id]
+	if !ok {
+		return QuarantinedMessage{}, nil, fmt.Errorf("%w: %s", ErrQuarantineNotFound, id)
+	}
+	data, err := os.ReadFile(q.path(id, quarantinePayloadExt))
+	if err != nil {
+		return QuarantinedMessage{}, nil, fmt.Errorf("failed to read quarantined payload: %w", err)
+	}
+	payload, err := q.keyring.Open(data)
+	if err != nil {
+		return QuarantinedMessage{}, nil, fmt.Errorf("failed to decrypt quarantined payload: %w", err)
+	}
+	return *msg, payload, nil
+}
+
+// Update records that a quarantined message failed to decode again
+func (q *QuarantineStore) Update(id string, cause error) (QuarantinedMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	msg, ok := q.messages[id]
+	if !ok {
+		return QuarantinedMessage{}, fmt.Errorf("%w: %s", ErrQuarantineNotFound, id)
+	}
+	return q.failedAgain(msg, cause)
+}
+
+// failedAgain records another failure of a quarantined message. Caller must
+// hold q.mu.
+func (q *QuarantineStore) failedAgain(msg *QuarantinedMessage, cause error) (QuarantinedMessage, error) {
+	msg.Error = cause.Error()
+	msg.Failures++
+	msg.LastFailedAt = q.now().UTC()
+	if err := q.writeInfo(msg); err != nil {
+		return QuarantinedMessage{}, err
+	}
+	return *msg, nil
+}
+
+// Remove deletes a quarantined message
+func (q *QuarantineStore) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.messages[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrQuarantineNotFound, id)
+	}
+	return q.remove(id)
+}
+
+// Size returns the number of quarantined messages and their payload bytes
+func (q *QuarantineStore) Size() (int, int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages), q.bytes
+}
+
+// index adds a message to the in-memory index. Caller must hold q.mu.
+func (q *QuarantineStore) index(msg *QuarantinedMessage) {
+	q.messages[msg.ID] = msg
+	if msg.MessageID != "" {
+		q.byMessage[msg.MessageID] = msg.ID
+	}
+	q.bytes += msg.SizeBytes
+}
+
+// oldest returns the ID of the oldest message. Caller must hold q.mu.
+func (q *QuarantineStore) oldest() string {
+	oldest := ""
+	for id := range q.messages {
+		if oldest == "" || id < oldest {
+			oldest = id
+		}
+	}
+	return oldest
+}
+
+// remove deletes a message's files and index entries. Caller must hold q.mu.
+func (q *QuarantineStore) remove(id string) error {
+	msg := q.messages[id]
+	for _, ext := range []string{quarantineInfoExt, quarantinePayloadExt} {
+		if err := os.Remove(q.path(id, ext)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove quarantined message: %w", err)
+		}
+	}
+	delete(q.messages, id)
+	if q.byMessage[msg.MessageID] == id {
+		delete(q.byMessage, msg.MessageID)
+	}
+	q.bytes -= msg.SizeBytes
+	return nil
+}
+
+// writeInfo saves a message's details. Caller must hold q.mu.
+func (q *QuarantineStore) writeInfo(msg *QuarantinedMessage) error {
+	err := persistence.WriteFileAtomic(q.path(msg.ID, quarantineInfoExt), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write quarantined message: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a quarantined message with extension ext
+func (q *QuarantineStore) path(id, ext string) string {
+	return filepath.Join(q.dir, id+ext)
+}
+
+// quarantine keeps a message that failed to decode, reporting whether it
+// was kept and can be acknowledged
+func (c *Collector) quarantine(msg mq.Message, cause error) bool {
+	if c.quarantined == nil {
+		return false
+	}
+	quarantined, evicted, err := c.quarantined.Add(msg, cause)
+	if err != nil {
+		c.logger.Error("Failed to quarantine message", "message_id", msg.ID, "error", err)
+		return false
+	}
+	if evicted > 0 {
+		c.quarantineEvicted.Add(float64(evicted))
+		c.logger.Warn("Evicted the oldest quarantined messages to stay within the quarantine size",
+			"evicted", evicted, "max_bytes", c.quarantined.maxBytes)
+		c.events.emit(EventEviction, "Evicted the oldest quarantined messages to stay within the quarantine size",
+			"store", "quarantine", "evicted", strconv.Itoa(evicted), "max_bytes", strconv.FormatInt(c.quarantined.maxBytes, 10))
+	}
+	if quarantined.Failures == 1 {
+		c.logger.Warn("Quarantined undecodable message", "id", quarantined.ID, "message_id", msg.ID, "error", cause)
+	}
+	return true
+}
+
+// reingest decodes and stores a quarantined message again, removing it from
+// quarantine once stored
+func (c *Collector) reingest(id string) (QuarantinedMessage, error) {
+	quarantined, payload, err := c.quarantined.Get(id)
+	if err != nil {
+		return QuarantinedMessage{}, err
+	}
+	if err := c.handleMessage(-1, mq.Message{ID: quarantined.MessageID, Payload: payload, Headers: quarantined.Headers}); err != nil {
+		if updated, updateErr := c.quarantined.Update(id, err); updateErr == nil {
+			quarantined = updated
+		}
+		return quarantined, err
+	}
+	return quarantined, c.quarantined.Remove(id)
+}
+
+// registerQuarantineMetrics exposes the quarantine's size on /metrics
+func (c *Collector) registerQuarantineMetrics() {
+	c.metrics.NewGaugeFunc("collector_quarantined_messages",
+		"Undecodable messages held in quarantine.", nil,
+		func(emit func(float64, ...string)) {
+			count, _ := c.quarantined.Size()
+			emit(float64(count))
+		})
+	c.metrics.NewGaugeFunc("collector_quarantine_bytes",
+		"Payload bytes of the messages held in quarantine.", nil,
+		func(emit func(float64, ...string)) {
+			_, bytes := c.quarantined.Size()
+			emit(float64(bytes))
+		})
+	c.quarantineEvicted = c.metrics.NewCounterVec("collector_quarantine_evicted_total",
+		"Quarantined messages evicted to stay within the quarantine size.").WithLabelValues()
+}
+
+// serveQuarantine handles GET /admin/quarantine, listing quarantined
+// messages, and POST /admin/quarantine/reingest, re-ingesting them all
+func (c *Collector) serveQuarantine(w http.ResponseWriter, r *http.Request) {
+	if c.quarantined == nil {
+		apierror.Write(w, http.StatusNotFound, apierror.FeatureDisabled, "quarantine is disabled")
+		return
+	}
+
+	if strings.TrimSuffix(r.URL.Path, "/") == "/admin/quarantine/reingest" {
+		if r.Method != http.MethodPost {
+			apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+			return
+		}
+		reingested, failed := 0, []QuarantinedMessage{}
+		for _, quarantined := range c.quarantined.List() {
+			if updated, err := c.reingest(quarantined.ID); err != nil {
+				failed = append(failed, updated)
+				continue
+			}
+			reingested++
+		}
+		c.logger.Info("Re-ingested quarantined messages", "reingested", reingested, "failed", len(failed))
+		writeJSON(w, http.StatusOK, map[string]interface{}{"reingested": reingested, "failed": failed})
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+		return
+	}
+	messages := c.quarantined.List()
+	count, bytes := c.quarantined.Size()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"messages":   messages,
+		"total":      count,
+		"size_bytes": bytes,
+		"max_bytes":  c.quarantined.maxBytes,
+	})
+}
+
+// serveQuarantinedMessage handles /admin/quarantine/{id}: GET returns the
+// message's details, GET .../payload downloads its payload, POST
+// .../reingest decodes and stores it again and DELETE discards it
+func (c *Collector) serveQuarantinedMessage(w http.ResponseWriter, r *http.Request) {
+	if c.quarantined == nil {
+		apierror.Write(w, http.StatusNotFound, apierror.FeatureDisabled, "quarantine is disabled")
+		return
+	}
+	if strings.TrimSuffix(r.URL.Path, "/") == "/admin/quarantine/reingest" {
+		c.serveQuarantine(w, r)
+		return
+	}
+
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/quarantine/"), "/")
+	writeErr := func(err error) {
+		if errors.Is(err, ErrQuarantineNotFound) {
+			apierror.Write(w, http.StatusNotFound, apierror.MessageNotFound, err.Error())
+			return
+		}
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, err.Error())
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		quarantined, _, err := c.quarantined.Get(id)
+		if err != nil {
+			writeErr(err)
+			return
+		}
+		writeJSON(w, http.StatusOK, quarantined)
+	case action == "" && r.Method == http.MethodDelete:
+		if err := c.quarantined.Remove(id); err != nil {
+			writeErr(err)
+			return
+		}
+		c.logger.Info("Quarantined message discarded", "id", id)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "payload" && r.Method == http.MethodGet:
+		_, payload, err := c.quarantined.Get(id)
+		if err != nil {
+			writeErr(err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, id, quarantinePayloadExt))
+		_, _ = w.Write(payload)
+	case action == "reingest" && r.Method == http.MethodPost:
+		quarantined, err := c.reingest(id)
+		if errors.Is(err, ErrQuarantineNotFound) {
+			writeErr(err)
+			return
+		}
+		if err != nil {
+			// Still undecodable; the message stays quarantined
+			writeJSON(w, http.StatusUnprocessableEntity, quarantined)
+			return
+		}
+		c.logger.Info("Re-ingested quarantined message", "id", id, "message_id", quarantined.MessageID)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "" || action == "payload" || action == "reingest":
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+	default:
+		apierror.Write(w, http.StatusNotFound, apierror.NotFound, "Invalid endpoint")
+	}
+}
@@ -0,0 +1,180 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/encryption/encryptiontest"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestQuarantineStore(t *testing.T) {
+	dir := t.TempDir()
+	keyring := encryptiontest.Keyring(t, "k1="+encryptiontest.Key(t))
+	store := NewQuarantineStore(dir, 10, keyring)
+	if err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _, err := store.Add(mq.Message{ID: "a", Payload: []byte("{bad"), Headers: map[string]string{"source": "host-1"}}, errors.New("unexpected EOF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A redelivery of the same message updates its entry
+	again, _, err := store.Add(mq.Message{ID: "a", Payload: []byte("{bad")}, errors.New("still bad"))
+	if err != nil || again.ID != first.ID || again.Failures != 2 || again.Error != "still bad" {
+		t.Fatalf("Expected the entry updated, got %+v, %v", again, err)
+	}
+
+	// Payloads are encrypted at rest
+	onDisk, err := os.ReadFile(filepath.Join(dir, first.ID+quarantinePayloadExt))
+	if err != nil || !encryption.IsEncrypted(onDisk) {
+		t.Errorf("Expected an encrypted payload on disk, got %q, %v", onDisk, err)
+	}
+
+	// Going over the size evicts the oldest
+	second, evicted, err := store.Add(mq.Message{ID: "b", Payload: []byte("12345678")}, errors.New("bad"))
+	if err != nil || evicted != 1 {
+		t.Fatalf("Expected one eviction, got %d, %v", evicted, err)
+	}
+	if _, _, err := store.Add(mq.Message{ID: "c", Payload: make([]byte, 11)}, errors.New("bad")); err == nil {
+		t.Error("Expected a message larger than the store to be refused")
+	}
+
+	reopened := NewQuarantineStore(dir, 10, keyring)
+	if err := reopened.Load(); err != nil {
+		t.Fatal(err)
+	}
+	list := reopened.List()
+	if len(list) != 1 || list[0].ID != second.ID || list[0].MessageID != "b" {
+		t.Fatalf("Expected only the second message after reopening, got %+v", list)
+	}
+	if _, payload, err := reopened.Get(second.ID); err != nil || string(payload) != "12345678" {
+		t.Errorf("Get = %q, %v", payload, err)
+	}
+	if err := reopened.Remove(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := reopened.Get(second.ID); !errors.Is(err, ErrQuarantineNotFound) {
+		t.Errorf("Expected ErrQuarantineNotFound after removal, got %v", err)
+	}
+	if count, size := reopened.Size(); count != 0 || size != 0 {
+		t.Errorf("Expected an empty store, got %d messages of %d bytes", count, size)
+	}
+}
+
+func TestQuarantineAdminAPI(t *testing.T) {
+	dataDir := t.TempDir()
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		DataDir:          dataDir,
+		MaxEntriesPerGPU: 10,
+		Quarantine:       QuarantineConfig{Dir: filepath.Join(dataDir, "quarantine")},
+	})
+	if err := c.quarantined.Load(); err != nil {
+		t.Fatal(err)
+	}
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		if path == "/admin/quarantine" {
+			c.serveQuarantine(rr, httptest.NewRequest(method, path, nil))
+		} else {
+			c.serveQuarantinedMessage(rr, httptest.NewRequest(method, path, nil))
+		}
+		return rr
+	}
+
+	// One message that a fix made decodable, and one that is still broken
+	valid, _ := json.Marshal(StreamerMessage{
+		Timestamp: time.Now(),
+		Fields:    map[string]interface{}{"gpu_id": "gpu_0", "metric_name": "m", "value": 1.0},
+	})
+	fixed, _, _ := c.quarantined.Add(mq.Message{ID: "fixed", Payload: valid}, errors.New("failed to convert message"))
+	broken, _, _ := c.quarantined.Add(mq.Message{ID: "broken", Payload: []byte(`{"fields":`)}, errors.New("failed to unmarshal message"))
+
+	var listed struct {
+		Messages []QuarantinedMessage `json:"messages"`
+		Total    int                  `json:"total"`
+	}
+	if err := json.NewDecoder(serve("GET", "/admin/quarantine").Body).Decode(&listed); err != nil || listed.Total != 2 || listed.Messages[0].ID != fixed.ID {
+		t.Fatalf("Unexpected listing %+v, %v", listed, err)
+	}
+	if rr := serve("GET", "/admin/quarantine/"+broken.ID+"/payload"); rr.Code != http.StatusOK || rr.Body.String() != `{"fields":` {
+		t.Errorf("Expected the payload downloaded, got %d %q", rr.Code, rr.Body.String())
+	}
+	if rr := serve("GET", "/admin/quarantine/missing"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown message, got %d", rr.Code)
+	}
+
+	rr := serve("POST", "/admin/quarantine/"+broken.ID+"/reingest")
+	var failed QuarantinedMessage
+	if err := json.NewDecoder(rr.Body).Decode(&failed); rr.Code != http.StatusUnprocessableEntity || err != nil || failed.Failures != 2 {
+		t.Errorf("Expected the broken message to stay quarantined, got %d %+v", rr.Code, failed)
+	}
+
+	var result struct {
+		Reingested int                  `json:"reingested"`
+		Failed     []QuarantinedMessage `json:"failed"`
+	}
+	if err := json.NewDecoder(serve("POST", "/admin/quarantine/reingest").Body).Decode(&result); err != nil || result.Reingested != 1 || len(result.Failed) != 1 {
+		t.Fatalf("Unexpected re-ingest result %+v, %v", result, err)
+	}
+	if len(c.memoryStorage.GetTelemetryForGPU("gpu_0")) != 1 {
+		t.Error("Expected the fixed message stored")
+	}
+
+	if rr := serve("DELETE", "/admin/quarantine/"+broken.ID); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 when discarding, got %d", rr.Code)
+	}
+	if count, _ := c.quarantined.Size(); count != 0 {
+		t.Errorf("Expected an empty quarantine, got %d messages", count)
+	}
+}
+
+func TestPipelineQuarantinesToDisk(t *testing.T) {
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+
+	dataDir := t.TempDir()
+	c := NewCollector(broker, CollectorConfig{
+		Workers:          1,
+		DataDir:          dataDir,
+		MaxEntriesPerGPU: 10,
+		HealthPort:       "127.0.0.1:0",
+		MQTopic:          "pipeline",
+		AckBatchSize:     1,
+		Quarantine:       QuarantineConfig{Dir: filepath.Join(dataDir, "quarantine")},
+	})
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	payload := []byte(`{"fields":`)
+	if err := broker.Publish("pipeline", mq.Message{Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && broker.GetStats().Topics["pipeline"].PendingMessages > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The message is kept and acknowledged rather than retried
+	if pending := broker.GetStats().Topics["pipeline"].PendingMessages; pending != 0 {
+		t.Errorf("Expected the quarantined message acknowledged, %d pending", pending)
+	}
+	list := c.quarantined.List()
+	if len(list) != 1 {
+		t.Fatalf("Expected one quarantined message, got %+v", list)
+	}
+	if _, stored, _ := c.quarantined.Get(list[0].ID); !bytes.Equal(stored, payload) {
+		t.Errorf("Expected the payload kept as received, got %q", stored)
+	}
+}
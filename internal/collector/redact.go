@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// RedactionSaltEnvVar holds the secret mixed into hashed field values. Without
+// a salt, hashes of low-entropy values such as namespace names can be reversed
+// by brute force.
+const RedactionSaltEnvVar = "TELEMETRY_REDACTION_SALT"
+
+// RedactAction is what happens to a redacted field
+type RedactAction string
+
+const (
+	// RedactHash replaces the value with a keyed hash, so records can still be
+	// grouped by the field without revealing it
+	RedactHash RedactAction = "hash"
+	// RedactDrop removes the field entirely
+	RedactDrop RedactAction = "drop"
+)
+
+// RedactionRule redacts a single streamer field
+type RedactionRule struct {
+	Field  string
+	Action RedactAction
+}
+
+// ParseRedactionRules parses a comma-separated list of "field[:action]"
+// entries, e.g. "pod,namespace:hash,container:drop". The action defaults to
+// hash.
+func ParseRedactionRules(spec string) ([]RedactionRule, error) {
+	var rules []RedactionRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		field, action, hasAction := strings.Cut(entry, ":")
+		field = strings.TrimSpace(field)
+		if field == "" {
+			return nil, fmt.Errorf("invalid redaction rule %q: missing field name", entry)
+		}
+
+		rule := RedactionRule{Field: field, Action: RedactHash}
+		if hasAction {
+			switch RedactAction(strings.ToLower(strings.TrimSpace(action))) {
+			case RedactHash:
+			case RedactDrop:
+				rule.Action = RedactDrop
+			default:
+				return nil, fmt.Errorf("invalid redaction action %q for field %s (expected hash or drop)", action, field)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Redactor applies redaction rules to streamer fields before they are
+// converted, stored or served by the API
+type Redactor struct {
+	actions map[string]RedactAction // lower-cased field name -> action
+	salt    []byte
+}
+
+// NewRedactor creates a redactor for rules. Field names match
+// case-insensitively, since DCGM exports mix "Hostname" and "pod" styles.
+func NewRedactor(rules []RedactionRule, salt string) *Redactor {
+	actions := make(map[string]RedactAction, len(rules))
+	for _, rule := range rules {
+		actions[strings.ToLower(rule.Field)] = rule.Action
+	}
+	return &Redactor{actions: actions, salt: []byte(salt)}
+}
+
+// Apply redacts fields in place. Hashed values become strings, so a hashed
+// numeric field is no longer recorded as a metric.
+func (r *Redactor) Apply(fields map[string]interface{}) {
+	for key, value := range fields {
+		action, ok := r.actions[strings.ToLower(key)]
+		if !ok {
+			continue
+		}
+
+		switch action {
+		case RedactDrop:
+			delete(fields, key)
+		case RedactHash:
+			fields[key] = r.hash(fmt.Sprint(value))
+		}
+	}
+}
+
+// hash returns a short, stable, keyed digest of value. Empty values are left
+// empty so "not set" stays distinguishable from a real value.
+func (r *Redactor) hash(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(value))
+	return "redacted-" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,148 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestParseRedactionRules(t *testing.T) {
+	rules, err := ParseRedactionRules(" pod, namespace:HASH ,container:drop,")
+	if err != nil {
+		t.Fatalf("ParseRedactionRules failed: %v", err)
+	}
+
+	expected := []RedactionRule{
+		{Field: "pod", Action: RedactHash},
+		{Field: "namespace", Action: RedactHash},
+		{Field: "container", Action: RedactDrop},
+	}
+	if len(rules) != len(expected) {
+		t.Fatalf("Expected %d rules, got %d", len(expected), len(rules))
+	}
+	for i, rule := range rules {
+		if rule != expected[i] {
+			t.Errorf("Rule %d: expected %+v, got %+v", i, expected[i], rule)
+		}
+	}
+
+	if rules, err := ParseRedactionRules(""); err != nil || len(rules) != 0 {
+		t.Errorf("Empty spec = %v, %v; want no rules", rules, err)
+	}
+
+	for _, spec := range []string{":hash", "pod:mask"} {
+		if _, err := ParseRedactionRules(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestRedactor_Apply(t *testing.T) {
+	redactor := NewRedactor([]RedactionRule{
+		{Field: "pod", Action: RedactHash},
+		{Field: "hostname", Action: RedactHash},
+		{Field: "container", Action: RedactDrop},
+	}, "salt")
+
+	fields := map[string]interface{}{
+		"pod":       "billing-7f9c",
+		"Hostname":  "node-1",
+		"container": "trainer",
+		"namespace": "",
+		"value":     42.0,
+	}
+	redactor.Apply(fields)
+
+	if _, exists := fields["container"]; exists {
+		t.Error("Expected container to be dropped")
+	}
+
+	pod, _ := fields["pod"].(string)
+	if pod == "billing-7f9c" || !strings.HasPrefix(pod, "redacted-") {
+		t.Errorf("Expected pod to be hashed, got %q", pod)
+	}
+	if host, _ := fields["Hostname"].(string); host == "node-1" {
+		t.Error("Expected field matching to be case-insensitive")
+	}
+	if fields["value"] != 42.0 || fields["namespace"] != "" {
+		t.Errorf("Unconfigured fields must be untouched: %v", fields)
+	}
+
+	// Hashes are stable for a salt so records can still be grouped
+	again := map[string]interface{}{"pod": "billing-7f9c"}
+	redactor.Apply(again)
+	if again["pod"] != pod {
+		t.Errorf("Expected stable hash %q, got %q", pod, again["pod"])
+	}
+
+	other := map[string]interface{}{"pod": "billing-7f9c"}
+	NewRedactor([]RedactionRule{{Field: "pod", Action: RedactHash}}, "other-salt").Apply(other)
+	if other["pod"] == pod {
+		t.Error("Expected different salts to produce different hashes")
+	}
+
+	empty := map[string]interface{}{"pod": ""}
+	redactor.Apply(empty)
+	if empty["pod"] != "" {
+		t.Errorf("Expected empty value to stay empty, got %q", empty["pod"])
+	}
+}
+
+func TestMessageHandling_Redaction(t *testing.T) {
+	config := CollectorConfig{
+		Workers:          1,
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 100,
+		HealthPort:       "8087",
+		Redaction: []RedactionRule{
+			{Field: "Hostname", Action: RedactHash},
+			{Field: "fan_speed", Action: RedactDrop},
+		},
+		RedactionSalt: "test",
+	}
+
+	broker := mq.NewBroker(mq.DefaultBrokerConfig())
+	defer broker.Close()
+	collector := NewCollector(broker, config)
+
+	payload, err := json.Marshal(StreamerMessage{
+		Timestamp: time.Now(),
+		Fields: map[string]interface{}{
+			"gpu_id":      "gpu_redact",
+			"Hostname":    "secret-host",
+			"temperature": 70.0,
+			"fan_speed":   50.0,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+
+	if err := collector.handleMessage(1, mq.Message{Payload: payload}); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+
+	retrieved := collector.memoryStorage.GetTelemetryForGPU("gpu_redact")
+	if len(retrieved) != 1 {
+		t.Fatalf("Expected 1 telemetry entry, got %d", len(retrieved))
+	}
+	if retrieved[0].Hostname == "secret-host" || retrieved[0].Hostname == "" {
+		t.Errorf("Expected hashed hostname, got %q", retrieved[0].Hostname)
+	}
+	if _, exists := retrieved[0].Metrics["fan_speed"]; exists {
+		t.Error("Expected fan_speed to be dropped")
+	}
+
+	stored, err := collector.fileStorage.ReadTelemetryFile("gpu_redact")
+	if err != nil {
+		t.Fatalf("ReadTelemetryFile failed: %v", err)
+	}
+	for _, record := range stored {
+		if strings.Contains(string(record), "secret-host") {
+			t.Errorf("Stored record contains redacted value: %s", record)
+		}
+	}
+}
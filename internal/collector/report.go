@@ -0,0 +1,366 @@
+package collector
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// DCGM metrics summarized in daily reports and the energy metric
+const (
+	MetricGPUUtil    = "DCGM_FI_DEV_GPU_UTIL"
+	MetricGPUTemp    = "DCGM_FI_DEV_GPU_TEMP"
+	MetricPowerUsage = "DCGM_FI_DEV_POWER_USAGE"
+)
+
+// DefaultReportInterval is how often the collector checks for days that
+// still need a report
+const DefaultReportInterval = time.Hour
+
+// reportDateLayout names report files and dates, always in UTC
+const reportDateLayout = "2006-01-02"
+
+// ReportConfig configures daily rollup reports
+type ReportConfig struct {
+	// Dir is where reports are written; empty disables reports
+	Dir string
+	// Formats lists "csv" and/or "json"; empty writes both
+	Formats []string
+	// Interval between report runs; zero uses DefaultReportInterval
+	Interval time.Duration
+}
+
+// HostReport is one host's rollup for a day. Averages and maxima are omitted
+// when the host reported no samples of that metric.
+type HostReport struct {
+	Date           string   `json:"date"`
+	Hostname       string   `json:"hostname"`
+	GPUs           int      `json:"gpus"`
+	Samples        int      `json:"samples"`
+	AvgUtilization *float64 `json:"avg_utilization,omitempty"`
+	MaxUtilization *float64 `json:"max_utilization,omitempty"`
+	AvgTemperature *float64 `json:"avg_temperature_c,omitempty"`
+	MaxTemperature *float64 `json:"max_temperature_c,omitempty"`
+	// EnergyKWh integrates power samples, skipping gaps over the energy max gap
+	EnergyKWh float64 `json:"energy_kwh"`
+}
+
+// DailyReport holds the host rollups for one UTC day
+type DailyReport struct {
+	Date        string       `json:"date"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Hosts       []HostReport `json:"hosts"`
+}
+
+// ParseReportFormats parses a comma-separated list of report formats
+func ParseReportFormats(spec string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(spec, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		switch format {
+		case "":
+			continue
+		case "csv", "json":
+			formats = append(formats, format)
+		default:
+			return nil, fmt.Errorf("invalid report format %q (expected csv or json)", format)
+		}
+	}
+	return formats, nil
+}
+
+// stat accumulates the mean and maximum of a metric
+type stat struct {
+	sum float64
+	n   int
+	max float64
+}
+
+func (s *stat) add(v float64) {
+	if s.n == 0 || v > s.max {
+		s.max = v
+	}
+	s.sum += v
+	s.n++
+}
+
+func (s *stat) merge(o stat) {
+	if o.n == 0 {
+		return
+	}
+	if s.n == 0 || o.max > s.max {
+		s.max = o.max
+	}
+	s.sum += o.sum
+	s.n += o.n
+}
+
+func (s stat) mean() *float64 {
+	if s.n == 0 {
+		return nil
+	}
+	v := s.sum / float64(s.n)
+	return &v
+}
+
+func (s stat) maximum() *float64 {
+	if s.n == 0 {
+		return nil
+	}
+	v := s.max
+	return &v
+}
+
+// gpuDay accumulates one GPU's samples for one day
+type gpuDay struct {
+	hostname  string
+	samples   int
+	util      stat
+	temp      stat
+	energyKWh float64
+}
+
+// rollupGPU summarizes one GPU's telemetry per UTC day, skipping days for
+// which include returns false
+func rollupGPU(entries []*Telemetry, include func(day string) bool, maxGap time.Duration) map[string]*gpuDay {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	// Energy is integrated over every sample so intervals spanning midnight
+	// are split correctly even when the neighbouring day is not reported
+	var energy energyAccumulator
+	days := make(map[string]*gpuDay)
+	for _, telemetry := range entries {
+		if v, ok := telemetry.Metrics[MetricPowerUsage]; ok {
+			energy.add(v, telemetry.Timestamp, maxGap)
+		}
+
+		day := telemetry.Timestamp.UTC().Format(reportDateLayout)
+		if !include(day) {
+			continue
+		}
+		acc, ok := days[day]
+		if !ok {
+			acc = &gpuDay{}
+			days[day] = acc
+		}
+		if telemetry.Hostname != "" {
+			acc.hostname = telemetry.Hostname
+		}
+		acc.samples++
+		if v, ok := telemetry.Metrics[MetricGPUUtil]; ok {
+			acc.util.add(v)
+		}
+		if v, ok := telemetry.Metrics[MetricGPUTemp]; ok {
+			acc.temp.add(v)
+		}
+	}
+
+	for day, acc := range days {
+		if d, ok := energy.Days[day]; ok {
+			acc.energyKWh = d.EnergyKWh
+		}
+	}
+	return days
+}
+
+// hostDay accumulates a host's GPUs for one day
+type hostDay struct {
+	gpus      int
+	samples   int
+	util      stat
+	temp      stat
+	energyKWh float64
+}
+
+// generateReports writes a report for every complete UTC day before now that
+// has telemetry but no report yet, and returns the paths written
+func (c *Collector) generateReports(now time.Time) ([]string, error) {
+	cfg := c.config.Reports
+	formats := cfg.Formats
+	if len(formats) == 0 {
+		formats = []string{"csv", "json"}
+	}
+	today := now.UTC().Format(reportDateLayout)
+
+	// Days already reported in every format are skipped without rollup work
+	pending := make(map[string]bool)
+	include := func(day string) bool {
+		if day >= today {
+			return false
+		}
+		missing, ok := pending[day]
+		if !ok {
+			for _, format := range formats {
+				if _, err := os.Stat(reportPath(cfg.Dir, day, format)); err != nil {
+					missing = true
+				}
+			}
+			pending[day] = missing
+		}
+		return missing
+	}
+
+	gpuIDs, err := c.fileStorage.ListGPUFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list telemetry files: %w", err)
+	}
+
+	// Roll up one GPU file at a time to bound memory
+	hosts := make(map[string]map[string]*hostDay) // day -> hostname -> rollup
+	for _, gpuID := range gpuIDs {
+		records, err := c.fileStorage.ReadTelemetryFile(gpuID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read telemetry for %s: %w", gpuID, err)
+		}
+		entries := make([]*Telemetry, 0, len(records))
+		for _, record := range records {
+			var telemetry Telemetry
+			if err := json.Unmarshal(record, &telemetry); err != nil {
+				continue
+			}
+			entries = append(entries, &telemetry)
+		}
+
+		for day, g := range rollupGPU(entries, include, c.energy.maxGap) {
+			if hosts[day] == nil {
+				hosts[day] = make(map[string]*hostDay)
+			}
+			h, ok := hosts[day][g.hostname]
+			if !ok {
+				h = &hostDay{}
+				hosts[day][g.hostname] = h
+			}
+			h.gpus++
+			h.samples += g.samples
+			h.util.merge(g.util)
+			h.temp.merge(g.temp)
+			h.energyKWh += g.energyKWh
+		}
+	}
+
+	var written []string
+	for day, byHost := range hosts {
+		report := DailyReport{Date: day, GeneratedAt: now.UTC()}
+		for hostname, h := range byHost {
+			report.Hosts = append(report.Hosts, HostReport{
+				Date:           day,
+				Hostname:       hostname,
+				GPUs:           h.gpus,
+				Samples:        h.samples,
+				AvgUtilization: h.util.mean(),
+				MaxUtilization: h.util.maximum(),
+				AvgTemperature: h.temp.mean(),
+				MaxTemperature: h.temp.maximum(),
+				EnergyKWh:      h.energyKWh,
+			})
+		}
+		sort.Slice(report.Hosts, func(i, j int) bool {
+			return report.Hosts[i].Hostname < report.Hosts[j].Hostname
+		})
+
+		for _, format := range formats {
+			path := reportPath(cfg.Dir, day, format)
+			write := writeReportJSON
+			if format == "csv" {
+				write = writeReportCSV
+			}
+			if err := persistence.WriteFileAtomic(path, func(w io.Writer) error {
+				return write(w, &report)
+			}); err != nil {
+				return written, fmt.Errorf("failed to write report %s: %w", path, err)
+			}
+			written = append(written, path)
+		}
+	}
+
+	sort.Strings(written)
+	return written, nil
+}
+
+// reportLoop generates reports at startup and then on every interval
+func (c *Collector) reportLoop() {
+	defer c.wg.Done()
+
+	interval := c.config.Reports.Interval
+	if interval <= 0 {
+		interval = DefaultReportInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		written, err := c.generateReports(time.Now())
+		if err != nil {
+			c.logger.Error("Failed to generate reports", "error", err)
+		}
+		for _, path := range written {
+			c.logger.Info("Wrote daily report", "path", path)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reportPath(dir, day, format string) string {
+	return filepath.Join(dir, day+"."+format)
+}
+
+func writeReportJSON(w io.Writer, report *DailyReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// reportCSVHeader lists the CSV report columns; missing values are empty
+var reportCSVHeader = []string{
+	"date", "hostname", "gpus", "samples",
+	"avg_utilization", "max_utilization",
+	"avg_temperature_c", "max_temperature_c",
+	"energy_kwh",
+}
+
+func writeReportCSV(w io.Writer, report *DailyReport) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(reportCSVHeader); err != nil {
+		return err
+	}
+	for _, h := range report.Hosts {
+		if err := out.Write([]string{
+			h.Date,
+			h.Hostname,
+			strconv.Itoa(h.GPUs),
+			strconv.Itoa(h.Samples),
+			formatOptional(h.AvgUtilization),
+			formatOptional(h.MaxUtilization),
+			formatOptional(h.AvgTemperature),
+			formatOptional(h.MaxTemperature),
+			strconv.FormatFloat(h.EnergyKWh, 'f', 4, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func formatOptional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 2, 64)
+}
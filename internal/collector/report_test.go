@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+func TestParseReportFormats(t *testing.T) {
+	formats, err := ParseReportFormats(" CSV, json ,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []string{"csv", "json"}; !reflect.DeepEqual(formats, want) {
+		t.Errorf("Got %v, want %v", formats, want)
+	}
+
+	if _, err := ParseReportFormats("csv,xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestGenerateReports(t *testing.T) {
+	dataDir := t.TempDir()
+	reportDir := filepath.Join(t.TempDir(), "reports")
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two GPUs on host-a over 2024-01-01, one sample on host-b the next day
+	// and one sample today, which is not yet complete
+	storage := persistence.NewFileStorage(dataDir)
+	day := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 1, 3, 6, 0, 0, 0, time.UTC)
+	samples := []persistence.Telemetry{
+		{GPUId: "gpu_0", Hostname: "host-a", Timestamp: day, Metrics: map[string]float64{MetricGPUUtil: 50, MetricGPUTemp: 60, MetricPowerUsage: 300}},
+		{GPUId: "gpu_0", Hostname: "host-a", Timestamp: day.Add(time.Minute), Metrics: map[string]float64{MetricGPUUtil: 100, MetricGPUTemp: 70, MetricPowerUsage: 300}},
+		{GPUId: "gpu_1", Hostname: "host-a", Timestamp: day, Metrics: map[string]float64{MetricGPUUtil: 0, MetricPowerUsage: 60}},
+		{GPUId: "gpu_1", Hostname: "host-a", Timestamp: day.Add(time.Minute), Metrics: map[string]float64{MetricPowerUsage: 60}},
+		{GPUId: "gpu_2", Hostname: "host-b", Timestamp: day.Add(24 * time.Hour), Metrics: map[string]float64{MetricGPUUtil: 10}},
+		{GPUId: "gpu_2", Hostname: "host-b", Timestamp: now, Metrics: map[string]float64{MetricGPUUtil: 10}},
+	}
+	for _, s := range samples {
+		if err := storage.WriteTelemetry(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		DataDir: dataDir,
+		Reports: ReportConfig{Dir: reportDir},
+	})
+
+	written, err := c.generateReports(now)
+	if err != nil {
+		t.Fatalf("generateReports failed: %v", err)
+	}
+	want := []string{
+		filepath.Join(reportDir, "2024-01-01.csv"),
+		filepath.Join(reportDir, "2024-01-01.json"),
+		filepath.Join(reportDir, "2024-01-02.csv"),
+		filepath.Join(reportDir, "2024-01-02.json"),
+	}
+	if !reflect.DeepEqual(written, want) {
+		t.Fatalf("Written %v, want %v", written, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(reportDir, "2024-01-01.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report DailyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Hosts) != 1 {
+		t.Fatalf("Expected one host, got %+v", report.Hosts)
+	}
+	host := report.Hosts[0]
+	if host.Hostname != "host-a" || host.GPUs != 2 || host.Samples != 4 {
+		t.Errorf("Unexpected host summary %+v", host)
+	}
+	if *host.AvgUtilization != 50 || *host.MaxUtilization != 100 {
+		t.Errorf("Expected utilization avg 50 max 100, got %v %v", *host.AvgUtilization, *host.MaxUtilization)
+	}
+	if *host.AvgTemperature != 65 || *host.MaxTemperature != 70 {
+		t.Errorf("Expected temperature avg 65 max 70, got %v %v", *host.AvgTemperature, *host.MaxTemperature)
+	}
+	// 300W and 60W for one minute each = 6Wh
+	if math.Abs(host.EnergyKWh-0.006) > 1e-9 {
+		t.Errorf("Expected 0.006kWh, got %v", host.EnergyKWh)
+	}
+
+	file, err := os.Open(filepath.Join(reportDir, "2024-01-02.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := [][]string{
+		reportCSVHeader,
+		{"2024-01-02", "host-b", "1", "1", "10.00", "10.00", "", "", "0.0000"},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("CSV rows = %v, want %v", rows, wantRows)
+	}
+
+	// Existing reports are not rewritten
+	written, err = c.generateReports(now)
+	if err != nil {
+		t.Fatalf("second generateReports failed: %v", err)
+	}
+	if len(written) != 0 {
+		t.Errorf("Expected no reports on second run, got %v", written)
+	}
+}
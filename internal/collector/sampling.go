@@ -0,0 +1,253 @@
+package collector
+
+import (
+	"fmt"
+	"math/rand"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+const (
+	// SampleRateLabel is the stored label recording the fraction of a
+	// metric's samples that sampling kept, e.g. "0.1" for 1-in-10, so
+	// consumers know how dense the data is
+	SampleRateLabel = "sample_rate"
+	// samplingFlushInterval is how often completed reservoir intervals are
+	// checked for samples to store
+	samplingFlushInterval = time.Second
+)
+
+// SamplingRule thins a chatty metric before it is stored. Exactly one of
+// Every or Reservoir is set.
+type SamplingRule struct {
+	// Metric is a metric name or glob pattern; "*" samples every metric on
+	// the collector's topic
+	Metric string
+	// Every keeps one sample in Every, per GPU
+	Every int
+	// Reservoir keeps up to Reservoir samples per GPU from each Interval,
+	// chosen uniformly at random
+	Reservoir int
+	Interval  time.Duration
+}
+
+// String returns the rule in the form accepted by ParseSamplingRules
+func (r SamplingRule) String() string {
+	if r.Reservoir > 0 {
+		return fmt.Sprintf("%s=%d/%s", r.Metric, r.Reservoir, r.Interval)
+	}
+	return fmt.Sprintf("%s=1/%d", r.Metric, r.Every)
+}
+
+// ParseSamplingRules parses a comma-separated list of "metric=1/N" (keep one
+// sample in N) and "metric=K/interval" (keep K random samples per interval)
+// entries, e.g. "DCGM_FI_DEV_SM_CLOCK=1/10,DCGM_FI_PROF_*=5/1m". Metric names
+// may use glob patterns; the first matching rule applies.
+func ParseSamplingRules(spec string) ([]SamplingRule, error) {
+	var rules []SamplingRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		metric, rate, ok := strings.Cut(entry, "=")
+		metric, rate = strings.TrimSpace(metric), strings.TrimSpace(rate)
+		if !ok || metric == "" {
+			return nil, fmt.Errorf("invalid sampling rule %q (expected metric=1/N or metric=K/interval)", entry)
+		}
+		if _, err := path.Match(metric, ""); err != nil {
+			return nil, fmt.Errorf("invalid sampling rule %q: bad metric pattern: %w", entry, err)
+		}
+
+		count, per, ok := strings.Cut(rate, "/")
+		k, err := strconv.Atoi(strings.TrimSpace(count))
+		if !ok || err != nil || k < 1 {
+			return nil, fmt.Errorf("invalid sampling rate %q for %s (expected 1/N or K/interval)", rate, metric)
+		}
+
+		rule := SamplingRule{Metric: metric}
+		per = strings.TrimSpace(per)
+		if n, err := strconv.Atoi(per); err == nil {
+			if k != 1 || n < 1 {
+				return nil, fmt.Errorf("invalid sampling rate %q for %s: 1-in-N sampling is written 1/N", rate, metric)
+			}
+			rule.Every = n
+		} else {
+			interval, err := time.ParseDuration(per)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("invalid sampling rate %q for %s (expected 1/N or K/interval)", rate, metric)
+			}
+			rule.Reservoir = k
+			rule.Interval = interval
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// sampleKey identifies the samples counted together: one metric of one GPU
+type sampleKey struct {
+	rule   int
+	gpuID  string
+	metric string
+}
+
+// reservoir holds the samples chosen so far from one interval
+type reservoir struct {
+	start time.Time
+	seen  int
+	kept  []*Telemetry
+}
+
+// Sampler applies sampling rules to telemetry. Samples are matched on their
+// metric names and kept or dropped whole; kept samples carry SampleRateLabel.
+type Sampler struct {
+	rules []SamplingRule
+
+	mu         sync.Mutex
+	counts     map[sampleKey]int64
+	reservoirs map[sampleKey]*reservoir
+	ready      []*Telemetry // from intervals ended before their flush
+	rand       *rand.Rand
+
+	sampled *metrics.CounterVec
+}
+
+// NewSampler creates a sampler for rules, registering its metrics in registry
+func NewSampler(rules []SamplingRule, registry *metrics.Registry) *Sampler {
+	return &Sampler{
+		rules:      rules,
+		counts:     make(map[sampleKey]int64),
+		reservoirs: make(map[sampleKey]*reservoir),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		sampled: registry.NewCounterVec("collector_sampled_total",
+			"Samples seen by each sampling rule, by whether they were kept or dropped.", "rule", "outcome"),
+	}
+}
+
+// hasReservoir reports whether any rule buffers samples until its interval
+// ends
+func (s *Sampler) hasReservoir() bool {
+	for _, rule := range s.rules {
+		if rule.Reservoir > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the first rule matching one of the sample's metrics and the
+// metric it matched
+func (s *Sampler) match(telemetry *Telemetry) (int, string, bool) {
+	names := make([]string, 0, len(telemetry.Metrics))
+	for name := range telemetry.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, rule := range s.rules {
+		for _, name := range names {
+			if ok, _ := path.Match(rule.Metric, name); ok {
+				return i, name, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// Sample decides whether to keep a sample received at now. It returns the
+// sample to store, or nil if it was dropped or is held in a reservoir until
+// its interval ends.
+func (s *Sampler) Sample(telemetry *Telemetry, now time.Time) *Telemetry {
+	i, metric, ok := s.match(telemetry)
+	if !ok {
+		return telemetry
+	}
+	rule := s.rules[i]
+	key := sampleKey{rule: i, gpuID: telemetry.GPUId, metric: metric}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rule.Every > 0 {
+		count := s.counts[key]
+		s.counts[key] = count + 1
+		if count%int64(rule.Every) != 0 {
+			s.sampled.WithLabelValues(rule.Metric, "dropped").Inc()
+			return nil
+		}
+		s.sampled.WithLabelValues(rule.Metric, "kept").Inc()
+		setSampleRate(telemetry, 1/float64(rule.Every))
+		return telemetry
+	}
+
+	start := now.Truncate(rule.Interval)
+	r, ok := s.reservoirs[key]
+	if ok && r.start.Before(start) {
+		s.ready = append(s.ready, s.drain(rule, r)...)
+		ok = false
+	}
+	if !ok {
+		r = &reservoir{start: start}
+		s.reservoirs[key] = r
+	}
+
+	// Algorithm R: each of the samples seen so far is kept with equal
+	// probability
+	r.seen++
+	if len(r.kept) < rule.Reservoir {
+		r.kept = append(r.kept, telemetry)
+	} else if j := s.rand.Intn(r.seen); j < rule.Reservoir {
+		r.kept[j] = telemetry
+	}
+	return nil
+}
+
+// Flush returns the samples kept from reservoir intervals ended by now, in
+// timestamp order. A zero now flushes every interval, for shutdown.
+func (s *Sampler) Flush(now time.Time) []*Telemetry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := s.ready
+	s.ready = nil
+	for key, r := range s.reservoirs {
+		rule := s.rules[key.rule]
+		if !now.IsZero() && now.Before(r.start.Add(rule.Interval)) {
+			continue
+		}
+		out = append(out, s.drain(rule, r)...)
+		delete(s.reservoirs, key)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Timestamp.Before(out[j].Timestamp)
+	})
+	return out
+}
+
+// drain labels and returns a completed reservoir's samples. Called with s.mu
+// held.
+func (s *Sampler) drain(rule SamplingRule, r *reservoir) []*Telemetry {
+	rate := float64(len(r.kept)) / float64(r.seen)
+	for _, telemetry := range r.kept {
+		setSampleRate(telemetry, rate)
+	}
+	s.sampled.WithLabelValues(rule.Metric, "kept").Add(float64(len(r.kept)))
+	s.sampled.WithLabelValues(rule.Metric, "dropped").Add(float64(r.seen - len(r.kept)))
+	return r.kept
+}
+
+func setSampleRate(telemetry *Telemetry, rate float64) {
+	if telemetry.Labels == nil {
+		telemetry.Labels = make(map[string]string)
+	}
+	telemetry.Labels[SampleRateLabel] = strconv.FormatFloat(rate, 'g', 4, 64)
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestParseSamplingRules(t *testing.T) {
+	rules, err := ParseSamplingRules("DCGM_FI_DEV_SM_CLOCK=1/10, DCGM_FI_PROF_*=5/1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SamplingRule{
+		{Metric: "DCGM_FI_DEV_SM_CLOCK", Every: 10},
+		{Metric: "DCGM_FI_PROF_*", Reservoir: 5, Interval: time.Minute},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("Expected %d rules, got %v", len(want), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("Rule %d: expected %+v, got %+v", i, want[i], rules[i])
+		}
+	}
+	if got := rules[1].String(); got != "DCGM_FI_PROF_*=5/1m0s" {
+		t.Errorf("Unexpected rule string %q", got)
+	}
+
+	for _, spec := range []string{"m", "=1/10", "m=0/10", "m=2/10", "m=1/0", "m=1/x", "m=5/-1m", "[=1/2"} {
+		if _, err := ParseSamplingRules(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestSamplingEveryN(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 100,
+		Sampling:         []SamplingRule{{Metric: "DCGM_FI_DEV_SM_*", Every: 3}},
+	})
+
+	base := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		for _, metric := range []string{"DCGM_FI_DEV_SM_CLOCK", "DCGM_FI_DEV_GPU_TEMP"} {
+			payload, _ := json.Marshal(StreamerMessage{
+				Timestamp: base.Add(time.Duration(i) * time.Second),
+				Fields:    map[string]interface{}{"gpu_id": "gpu_0", "metric_name": metric, "value": float64(i)},
+			})
+			if err := c.handleMessage(0, mq.Message{Payload: payload}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var clocks, temps int
+	for _, tel := range c.memoryStorage.GetTelemetryForGPU("gpu_0") {
+		if v, ok := tel.Metrics["DCGM_FI_DEV_SM_CLOCK"]; ok {
+			clocks++
+			if v != 0 && v != 3 && v != 6 {
+				t.Errorf("Unexpected SM clock sample %v kept", v)
+			}
+			if tel.Labels[SampleRateLabel] != "0.3333" {
+				t.Errorf("Expected sample_rate 0.3333, got %v", tel.Labels)
+			}
+		} else {
+			temps++
+			if tel.Labels != nil {
+				t.Errorf("Expected unsampled metric without labels, got %v", tel.Labels)
+			}
+		}
+	}
+	if clocks != 3 || temps != 7 {
+		t.Errorf("Expected 3 SM clock and 7 temperature samples, got %d and %d", clocks, temps)
+	}
+
+	// The label is persisted with the sample
+	stored := c.GetTelemetryForGPU("gpu_0", 0)
+	if len(stored) != 10 || stored[0].Labels[SampleRateLabel] != "0.3333" {
+		t.Errorf("Expected the sample rate label in stored telemetry, got %d samples, first %+v", len(stored), stored[0])
+	}
+}
+
+func TestSamplingReservoir(t *testing.T) {
+	s := NewSampler([]SamplingRule{{Metric: "*", Reservoir: 2, Interval: time.Minute}}, metrics.NewRegistry())
+	start := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+
+	sample := func(gpuID string, value float64, at time.Time) {
+		t.Helper()
+		tel := &Telemetry{GPUId: gpuID, Metrics: map[string]float64{"m": value}, Timestamp: at}
+		if kept := s.Sample(tel, at); kept != nil {
+			t.Fatal("Expected reservoir samples to be held until the interval ends")
+		}
+	}
+	for i := 0; i < 10; i++ {
+		sample("gpu_0", float64(i), start.Add(time.Duration(i)*time.Second))
+	}
+	sample("gpu_1", 0, start)
+
+	if out := s.Flush(start.Add(30 * time.Second)); len(out) != 0 {
+		t.Fatalf("Expected nothing flushed mid-interval, got %d samples", len(out))
+	}
+
+	out := s.Flush(start.Add(time.Minute))
+	if len(out) != 3 {
+		t.Fatalf("Expected 2 samples from gpu_0 and 1 from gpu_1, got %d", len(out))
+	}
+	rates := map[string]string{}
+	for i, tel := range out {
+		rates[tel.GPUId] = tel.Labels[SampleRateLabel]
+		if i > 0 && tel.Timestamp.Before(out[i-1].Timestamp) {
+			t.Error("Expected flushed samples in timestamp order")
+		}
+	}
+	if rates["gpu_0"] != "0.2" || rates["gpu_1"] != "1" {
+		t.Errorf("Unexpected sample rates %v", rates)
+	}
+
+	// A sample from the next interval flushes the previous one, even before
+	// the flush loop runs
+	sample("gpu_0", 1, start.Add(time.Minute+time.Second))
+	sample("gpu_0", 2, start.Add(2*time.Minute+time.Second))
+	if out := s.Flush(start.Add(2*time.Minute + 2*time.Second)); len(out) != 1 || out[0].Metrics["m"] != 1 {
+		t.Errorf("Expected the earlier interval's sample, got %+v", out)
+	}
+
+	// Shutdown flushes what is still held
+	if out := s.Flush(time.Time{}); len(out) != 1 || out[0].Metrics["m"] != 2 {
+		t.Errorf("Expected the held sample on shutdown, got %+v", out)
+	}
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// DefaultSchemaVersion is assumed for messages without a schema version
+// header, which come from streamers that predate schema versions
+const DefaultSchemaVersion = "1"
+
+// ErrUnknownSchemaVersion is returned for messages in a schema version the
+// collector has no decoder for
+var ErrUnknownSchemaVersion = errors.New("unknown schema version")
+
+// SchemaDecoder parses a payload in one schema version
+type SchemaDecoder func(payload []byte, msg *StreamerMessage) error
+
+// SchemaRegistry holds a decoder for each version of the streamer message
+// format the collector accepts. A format change registers a decoder for its
+// version next to the existing ones, so collectors can be upgraded before
+// the streamers and both versions are accepted while the streamers roll out.
+// The old decoder is removed once no streamer publishes its version.
+type SchemaRegistry struct {
+	decoders map[string]SchemaDecoder
+	decoded  *metrics.CounterVec
+}
+
+// NewSchemaRegistry returns a registry accepting the versions this build
+// supports, counting the messages decoded in each on registry
+func NewSchemaRegistry(registry *metrics.Registry) *SchemaRegistry {
+	r := &SchemaRegistry{
+		decoders: make(map[string]SchemaDecoder),
+		decoded: registry.NewCounterVec("collector_messages_decoded_total",
+			"Messages decoded, by the schema version they were published in.", "schema_version"),
+	}
+	r.Register("1", decodeStreamerMessage)
+	return r
+}
+
+// Register adds or replaces the decoder for version. It must not be called
+// once the collector has started.
+func (r *SchemaRegistry) Register(version string, decoder SchemaDecoder) {
+	r.decoders[version] = decoder
+}
+
+// Versions returns the accepted versions in order
+func (r *SchemaRegistry) Versions() []string {
+	versions := make([]string, 0, len(r.decoders))
+	for version := range r.decoders {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Decode parses msg with the decoder for the version in its schema version
+// header
+func (r *SchemaRegistry) Decode(msg mq.Message) (StreamerMessage, error) {
+	var decoded StreamerMessage
+	version := msg.Headers[mq.HeaderSchemaVersion]
+	if version == "" {
+		version = DefaultSchemaVersion
+	}
+	decoder, ok := r.decoders[version]
+	if !ok {
+		return decoded, fmt.Errorf("%w %q (accepted: %v)", ErrUnknownSchemaVersion, version, r.Versions())
+	}
+	if err := decoder(msg.Payload, &decoded); err != nil {
+		return decoded, fmt.Errorf("failed to unmarshal message in schema version %s: %w", version, err)
+	}
+	r.decoded.WithLabelValues(version).Inc()
+	return decoded, nil
+}
+
+// Schemas returns the registry of the schema versions the collector accepts
+func (c *Collector) Schemas() *SchemaRegistry {
+	return c.schemas
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	schemas := NewSchemaRegistry(metrics.NewRegistry())
+	v1 := []byte(`{"fields":{"gpu_id":"gpu_0"}}`)
+
+	// Messages from streamers without versions are version 1
+	for _, headers := range []map[string]string{nil, {mq.HeaderSchemaVersion: "1"}} {
+		msg, err := schemas.Decode(mq.Message{Payload: v1, Headers: headers})
+		if err != nil || msg.Fields["gpu_id"] != "gpu_0" {
+			t.Errorf("Decode with headers %v = %+v, %v", headers, msg, err)
+		}
+	}
+
+	v2 := mq.Message{Payload: []byte(`{"gpu":"gpu_1"}`), Headers: map[string]string{mq.HeaderSchemaVersion: "2"}}
+	if _, err := schemas.Decode(v2); !errors.Is(err, ErrUnknownSchemaVersion) {
+		t.Fatalf("Expected ErrUnknownSchemaVersion, got %v", err)
+	}
+
+	// While a new version rolls out both are accepted
+	schemas.Register("2", func(payload []byte, msg *StreamerMessage) error {
+		var v2 struct {
+			GPU string `json:"gpu"`
+		}
+		if err := json.Unmarshal(payload, &v2); err != nil {
+			return err
+		}
+		msg.Fields = map[string]interface{}{"gpu_id": v2.GPU}
+		return nil
+	})
+	if msg, err := schemas.Decode(v2); err != nil || msg.Fields["gpu_id"] != "gpu_1" {
+		t.Errorf("Decode version 2 = %+v, %v", msg, err)
+	}
+	if _, err := schemas.Decode(mq.Message{Payload: v1}); err != nil {
+		t.Errorf("Expected version 1 still accepted, got %v", err)
+	}
+	if versions := schemas.Versions(); len(versions) != 2 || versions[0] != "1" || versions[1] != "2" {
+		t.Errorf("Unexpected versions %v", versions)
+	}
+	if decoded := schemas.decoded.WithLabelValues("1").Value(); decoded != 3 {
+		t.Errorf("Expected 3 version 1 messages counted, got %v", decoded)
+	}
+}
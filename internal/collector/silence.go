@@ -0,0 +1,285 @@
+package collector
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// silenceStateFile holds silences in the data directory
+const silenceStateFile = "silences.json"
+
+// ErrSilenceNotFound is returned for an unknown silence ID
+var ErrSilenceNotFound = errors.New("silence not found")
+
+// SilenceState is where a silence is in its lifetime
+type SilenceState string
+
+const (
+	SilencePending SilenceState = "pending"
+	SilenceActive  SilenceState = "active"
+	SilenceExpired SilenceState = "expired"
+)
+
+// Silence suppresses health alerts whose labels match all of its matchers
+// between StartsAt and EndsAt. Matcher values may use shell glob patterns,
+// e.g. {"hostname": "dgx-rack4-*"}. Alert labels are gpu_id, hostname and
+// state.
+type Silence struct {
+	ID        string            `json:"id"`
+	Matchers  map[string]string `json:"matchers"`
+	StartsAt  time.Time         `json:"starts_at"`
+	EndsAt    time.Time         `json:"ends_at"`
+	Comment   string            `json:"comment,omitempty"`
+	CreatedBy string            `json:"created_by"`
+	CreatedAt time.Time         `json:"created_at"`
+	// ExpiredBy records who ended the silence early, if anyone
+	ExpiredBy string       `json:"expired_by,omitempty"`
+	State     SilenceState `json:"state"`
+}
+
+// stateAt returns the silence's state at now
+func (s *Silence) stateAt(now time.Time) SilenceState {
+	switch {
+	case now.Before(s.StartsAt):
+		return SilencePending
+	case now.Before(s.EndsAt):
+		return SilenceActive
+	default:
+		return SilenceExpired
+	}
+}
+
+// Matches reports whether every matcher matches the labels
+func (s *Silence) Matches(labels map[string]string) bool {
+	for name, pattern := range s.Matchers {
+		value, ok := labels[name]
+		if !ok {
+			return false
+		}
+		if matched, err := path.Match(pattern, value); err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// SilenceStore keeps silences, persisted so maintenance windows survive
+// restarts. Expired silences are kept as an audit trail.
+type SilenceStore struct {
+	mu       sync.Mutex
+	store    *persistence.FileStore
+	silences []*Silence
+	now      func() time.Time
+}
+
+// NewSilenceStore creates a store persisting to path
+func NewSilenceStore(path string) *SilenceStore {
+	return &SilenceStore{
+		store: persistence.NewFileStore(path),
+		now:   time.Now,
+	}
+}
+
+// Load restores saved silences
+func (s *SilenceStore) Load() error {
+	var silences []*Silence
+	if err := s.store.Load(&silences); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to load silences: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.silences = silences
+	return nil
+}
+
+// Add validates and stores a new silence. StartsAt defaults to now.
+func (s *SilenceStore) Add(silence Silence) (Silence, error) {
+	now := s.now()
+
+	silence.CreatedBy = strings.TrimSpace(silence.CreatedBy)
+	if silence.CreatedBy == "" {
+		return Silence{}, fmt.Errorf("created_by is required")
+	}
+	if len(silence.Matchers) == 0 {
+		return Silence{}, fmt.Errorf("at least one matcher is required")
+	}
+	for name, pattern := range silence.Matchers {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Silence{}, fmt.Errorf("invalid pattern %q for %s: %w", pattern, name, err)
+		}
+	}
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return Silence{}, fmt.Errorf("ends_at must be after starts_at")
+	}
+	if !silence.EndsAt.After(now) {
+		return Silence{}, fmt.Errorf("ends_at must be in the future")
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Silence{}, fmt.Errorf("failed to generate silence ID: %w", err)
+	}
+	silence.ID = hex.EncodeToString(id)
+	silence.CreatedAt = now
+	silence.ExpiredBy = ""
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := silence
+	s.silences = append(s.silences, &stored)
+	if err := s.store.Save(s.silences); err != nil {
+		s.silences = s.silences[:len(s.silences)-1]
+		return Silence{}, fmt.Errorf("failed to save silences: %w", err)
+	}
+
+	silence.State = silence.stateAt(now)
+	return silence, nil
+}
+
+// Expire ends a pending or active silence now, recording who ended it
+func (s *SilenceStore) Expire(id, by string) (Silence, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, silence := range s.silences {
+		if silence.ID != id {
+			continue
+		}
+		if silence.stateAt(now) != SilenceExpired {
+			previous := *silence
+			if now.Before(silence.StartsAt) {
+				silence.StartsAt = now
+			}
+			silence.EndsAt = now
+			silence.ExpiredBy = by
+			if err := s.store.Save(s.silences); err != nil {
+				*silence = previous
+				return Silence{}, fmt.Errorf("failed to save silences: %w", err)
+			}
+		}
+		expired := *silence
+		expired.State = expired.stateAt(now)
+		return expired, nil
+	}
+	return Silence{}, ErrSilenceNotFound
+}
+
+// List returns silences, newest first, optionally including expired ones
+func (s *SilenceStore) List(includeExpired bool) []Silence {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silences := []Silence{}
+	for _, silence := range s.silences {
+		state := silence.stateAt(now)
+		if state == SilenceExpired && !includeExpired {
+			continue
+		}
+		listed := *silence
+		listed.State = state
+		silences = append(silences, listed)
+	}
+	sort.SliceStable(silences, func(i, j int) bool {
+		return silences[i].CreatedAt.After(silences[j].CreatedAt)
+	})
+	return silences
+}
+
+// Silencing returns the ID of an active silence matching labels, or ""
+func (s *SilenceStore) Silencing(labels map[string]string) string {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, silence := range s.silences {
+		if silence.stateAt(now) == SilenceActive && silence.Matches(labels) {
+			return silence.ID
+		}
+	}
+	return ""
+}
+
+// serveSilences handles GET and POST /api/v1/silences
+func (c *Collector) serveSilences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, c.silences.List(r.URL.Query().Get("all") == "true"))
+	case http.MethodPost:
+		var silence Silence
+		if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidSilence, "invalid silence: "+err.Error())
+			return
+		}
+		created, err := c.silences.Add(silence)
+		if err != nil {
+			apierror.Write(w, http.StatusBadRequest, apierror.InvalidSilence, "invalid silence: "+err.Error())
+			return
+		}
+		c.logger.Info("Silence created",
+			"silence_id", created.ID,
+			"created_by", created.CreatedBy,
+			"matchers", created.Matchers,
+			"starts_at", created.StartsAt,
+			"ends_at", created.EndsAt,
+			"comment", created.Comment)
+		writeJSON(w, http.StatusCreated, created)
+	default:
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+	}
+}
+
+// serveSilence handles DELETE /api/v1/silences/{id}?expired_by=...
+func (c *Collector) serveSilence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/silences/")
+	by := r.URL.Query().Get("expired_by")
+	expired, err := c.silences.Expire(id, by)
+	if errors.Is(err, ErrSilenceNotFound) {
+		apierror.Write(w, http.StatusNotFound, apierror.SilenceNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, apierror.Internal, err.Error())
+		return
+	}
+
+	c.logger.Info("Silence expired", "silence_id", expired.ID, "expired_by", by, "created_by", expired.CreatedBy)
+	writeJSON(w, http.StatusOK, expired)
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
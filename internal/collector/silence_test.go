@@ -0,0 +1,162 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestSilenceMatches(t *testing.T) {
+	silence := Silence{Matchers: map[string]string{"hostname": "dgx-rack4-*", "state": "degraded"}}
+
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{"gpu_id": "gpu_0", "hostname": "dgx-rack4-07", "state": "degraded"}, true},
+		{map[string]string{"gpu_id": "gpu_0", "hostname": "dgx-rack5-01", "state": "degraded"}, false},
+		{map[string]string{"gpu_id": "gpu_0", "hostname": "dgx-rack4-07", "state": "unknown"}, false},
+		{map[string]string{"gpu_id": "gpu_0", "state": "degraded"}, false},
+	}
+	for _, tt := range tests {
+		if got := silence.Matches(tt.labels); got != tt.want {
+			t.Errorf("Matches(%v) = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestSilenceStoreAdd(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewSilenceStore(filepath.Join(t.TempDir(), silenceStateFile))
+	store.now = func() time.Time { return now }
+
+	invalid := []Silence{
+		{Matchers: map[string]string{"gpu_id": "gpu_0"}, EndsAt: now.Add(time.Hour)},
+		{CreatedBy: "alice", EndsAt: now.Add(time.Hour)},
+		{CreatedBy: "alice", Matchers: map[string]string{"gpu_id": "gpu_["}, EndsAt: now.Add(time.Hour)},
+		{CreatedBy: "alice", Matchers: map[string]string{"gpu_id": "gpu_0"}, StartsAt: now.Add(time.Hour), EndsAt: now},
+		{CreatedBy: "alice", Matchers: map[string]string{"gpu_id": "gpu_0"}, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)},
+	}
+	for _, silence := range invalid {
+		if _, err := store.Add(silence); err == nil {
+			t.Errorf("Expected error adding %+v", silence)
+		}
+	}
+
+	active, err := store.Add(Silence{CreatedBy: "alice", Matchers: map[string]string{"gpu_id": "gpu_0"}, EndsAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active.ID == "" || active.State != SilenceActive || !active.StartsAt.Equal(now) || !active.CreatedAt.Equal(now) {
+		t.Errorf("Unexpected silence %+v", active)
+	}
+
+	pending, err := store.Add(Silence{CreatedBy: "bob", Matchers: map[string]string{"hostname": "host-a"},
+		StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.State != SilencePending {
+		t.Errorf("Expected pending silence, got %s", pending.State)
+	}
+
+	if got := store.Silencing(map[string]string{"gpu_id": "gpu_0", "hostname": "host-a", "state": "degraded"}); got != active.ID {
+		t.Errorf("Expected active silence %s to match, got %q", active.ID, got)
+	}
+	if got := store.Silencing(map[string]string{"gpu_id": "gpu_1", "hostname": "host-a", "state": "degraded"}); got != "" {
+		t.Errorf("Expected pending silence not to match, got %q", got)
+	}
+}
+
+func TestSilenceStoreExpireAndLoad(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), silenceStateFile)
+	store := NewSilenceStore(path)
+	store.now = func() time.Time { return now }
+
+	silence, err := store.Add(Silence{CreatedBy: "alice", Matchers: map[string]string{"gpu_id": "gpu_0"}, EndsAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(10 * time.Minute)
+	expired, err := store.Expire(silence.ID, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired.State != SilenceExpired || expired.ExpiredBy != "bob" || !expired.EndsAt.Equal(now) {
+		t.Errorf("Unexpected expired silence %+v", expired)
+	}
+	if _, err := store.Expire("missing", "bob"); !errors.Is(err, ErrSilenceNotFound) {
+		t.Errorf("Expected ErrSilenceNotFound, got %v", err)
+	}
+
+	// Expired silences survive a restart as an audit trail
+	restored := NewSilenceStore(path)
+	restored.now = store.now
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.List(false); len(got) != 0 {
+		t.Errorf("Expected no current silences, got %+v", got)
+	}
+	all := restored.List(true)
+	if len(all) != 1 || all[0].CreatedBy != "alice" || all[0].ExpiredBy != "bob" || all[0].State != SilenceExpired {
+		t.Errorf("Unexpected audit trail %+v", all)
+	}
+}
+
+func TestCollectorSilenceEndpoints(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{DataDir: t.TempDir()})
+
+	body, _ := json.Marshal(Silence{
+		Matchers:  map[string]string{"hostname": "host-a"},
+		EndsAt:    time.Now().Add(time.Hour),
+		Comment:   "driver upgrade",
+		CreatedBy: "alice",
+	})
+	rr := httptest.NewRecorder()
+	c.serveSilences(rr, httptest.NewRequest("POST", "/api/v1/silences", bytes.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created Silence
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	c.serveSilences(rr, httptest.NewRequest("POST", "/api/v1/silences", bytes.NewReader([]byte(`{"matchers":{"gpu_id":"gpu_0"}}`))))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without created_by, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	c.serveSilence(rr, httptest.NewRequest("DELETE", "/api/v1/silences/"+created.ID+"?expired_by=bob", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	c.serveSilence(rr, httptest.NewRequest("DELETE", "/api/v1/silences/missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	c.serveSilences(rr, httptest.NewRequest("GET", "/api/v1/silences?all=true", nil))
+	var silences []Silence
+	if err := json.Unmarshal(rr.Body.Bytes(), &silences); err != nil {
+		t.Fatal(err)
+	}
+	if len(silences) != 1 || silences[0].ExpiredBy != "bob" {
+		t.Errorf("Unexpected silences %+v", silences)
+	}
+}
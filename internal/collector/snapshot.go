@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/apierror"
+)
+
+// Sources of a fleet snapshot
+const (
+	SnapshotSourceLive    = "live"
+	SnapshotSourceArchive = "archive"
+)
+
+// GPUSnapshot is the latest value of each metric a GPU reported up to a
+// point in time
+type GPUSnapshot struct {
+	GPUId    string             `json:"gpu_id" example:"gpu_0"`
+	Hostname string             `json:"hostname" example:"mtv5-dgx1-hgpu-031"`
+	Metrics  map[string]float64 `json:"metrics" example:"DCGM_FI_DEV_GPU_UTIL:87,DCGM_FI_DEV_GPU_TEMP:61.5"`
+	// Units are the units of the metrics with a known unit
+	Units map[string]string `json:"units,omitempty" example:"DCGM_FI_DEV_GPU_UTIL:%,DCGM_FI_DEV_GPU_TEMP:C"`
+	// LastSeen is the time of the GPU's newest sample up to the snapshot
+	LastSeen time.Time `json:"last_seen" example:"2025-07-18T13:59:58Z"`
+}
+
+// FleetSnapshot is the state of every GPU as of a point in time
+type FleetSnapshot struct {
+	AsOf time.Time `json:"as_of" example:"2025-07-18T14:00:00Z"`
+	// Source is live for the collector's cache of recent samples or archive
+	// for its stored telemetry
+	Source string        `json:"source" example:"archive"`
+	Hosts  int           `json:"hosts" example:"2"`
+	GPUs   []GPUSnapshot `json:"gpus"`
+	Total  int           `json:"total" example:"16"`
+}
+
+// ParseAsOf parses an as_of query parameter, an RFC3339 timestamp. Empty
+// means now and returns the zero time.
+func ParseAsOf(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	asOf, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid as_of %q (expected RFC3339)", s)
+	}
+	return asOf.UTC(), nil
+}
+
+// Snapshot returns the latest metric values of every GPU. With a zero asOf
+// they come from the cache of recent samples, or from storage for GPUs with
+// nothing cached; otherwise they are rebuilt from stored telemetry, cold
+// storage included, using only samples at or before asOf, so past states of
+// the fleet can be inspected. GPUs with no samples by then are left out.
+func (c *Collector) Snapshot(asOf time.Time) FleetSnapshot {
+	snapshot := FleetSnapshot{AsOf: asOf, Source: SnapshotSourceArchive, GPUs: []GPUSnapshot{}}
+	if asOf.IsZero() {
+		snapshot.AsOf = time.Now().UTC()
+		snapshot.Source = SnapshotSourceLive
+		for _, gpuID := range c.knownGPUs() {
+			var samples []*Telemetry
+			for _, sample := range c.memoryStorage.GetTelemetryForGPU(gpuID) {
+				samples = append(samples, &Telemetry{Hostname: sample.Hostname, Metrics: sample.Metrics, Timestamp: sample.Timestamp})
+			}
+			if len(samples) == 0 {
+				// Read through to storage for GPUs no longer cached
+				samples = c.GetTelemetryForGPU(gpuID, 0)
+			}
+			snapshot.add(gpuID, samples)
+		}
+	} else {
+		gpuIDs, err := c.fileStorage.ListGPUFiles()
+		if err != nil {
+			c.logger.Error("Failed to list telemetry files", "error", err)
+		}
+		seen := make(map[string]bool, len(gpuIDs))
+		for _, gpuID := range gpuIDs {
+			gpuID = c.aliases.Resolve(gpuID)
+			if seen[gpuID] {
+				continue
+			}
+			seen[gpuID] = true
+
+			var samples []*Telemetry
+			for _, sample := range c.GetTelemetryForGPU(gpuID, 0) {
+				if !sample.Timestamp.After(asOf) {
+					samples = append(samples, sample)
+				}
+			}
+			snapshot.add(gpuID, samples)
+		}
+	}
+
+	sort.Slice(snapshot.GPUs, func(i, j int) bool {
+		return snapshot.GPUs[i].GPUId < snapshot.GPUs[j].GPUId
+	})
+	hosts := make(map[string]bool)
+	for _, gpu := range snapshot.GPUs {
+		hosts[gpu.Hostname] = true
+	}
+	snapshot.Hosts = len(hosts)
+	snapshot.Total = len(snapshot.GPUs)
+	return snapshot
+}
+
+// add appends a GPU with the newest value of each metric in samples,
+// unless it has none
+func (s *FleetSnapshot) add(gpuID string, samples []*Telemetry) {
+	if len(samples) == 0 {
+		return
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Timestamp.Before(samples[j].Timestamp)
+	})
+
+	gpu := GPUSnapshot{GPUId: gpuID, Metrics: make(map[string]float64)}
+	for _, sample := range samples {
+		for name, value := range sample.Metrics {
+			gpu.Metrics[name] = value
+		}
+	}
+	// No conversions, so the units are those reported
+	gpu.Units = UnitConversions{}.Apply(gpu.Metrics)
+	latest := samples[len(samples)-1]
+	gpu.Hostname = latest.Hostname
+	gpu.LastSeen = latest.Timestamp
+	s.GPUs = append(s.GPUs, gpu)
+}
+
+// serveFleet handles GET /api/v1/fleet[?as_of=...]
+func (c *Collector) serveFleet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Write(w, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	asOf, err := ParseAsOf(r.URL.Query().Get("as_of"))
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.InvalidTimeRange, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, c.Snapshot(asOf))
+}
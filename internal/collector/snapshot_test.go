@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence/persistencetest"
+)
+
+func TestCollectorSnapshot(t *testing.T) {
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		Writers:          1,
+		DataDir:          t.TempDir(),
+		MaxEntriesPerGPU: 10,
+		Storage:          persistencetest.NewTelemetryStore(),
+	})
+
+	ingest := func(payload string) {
+		t.Helper()
+		telemetry, err := c.decode(mq.Message{Payload: []byte(payload)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.store(telemetry)
+	}
+	ingest(`{"timestamp":"2025-07-18T13:00:00Z","fields":{"gpu_id":"gpu_0","Hostname":"host-1","metric_name":"util","value":10}}`)
+	ingest(`{"timestamp":"2025-07-18T13:30:00Z","fields":{"gpu_id":"gpu_0","Hostname":"host-1","metric_name":"temp","value":60}}`)
+	ingest(`{"timestamp":"2025-07-18T15:00:00Z","fields":{"gpu_id":"gpu_0","Hostname":"host-1","metric_name":"util","value":90}}`)
+	ingest(`{"timestamp":"2025-07-18T15:00:00Z","fields":{"gpu_id":"gpu_1","Hostname":"host-2","metric_name":"util","value":50}}`)
+
+	live := c.Snapshot(time.Time{})
+	if live.Source != SnapshotSourceLive || live.Total != 2 || live.Hosts != 2 {
+		t.Fatalf("Unexpected live snapshot %+v", live)
+	}
+	if got := live.GPUs[0].Metrics; got["util"] != 90 || got["temp"] != 60 {
+		t.Errorf("Expected the newest gpu_0 values, got %v", got)
+	}
+
+	// Only samples up to as_of count, and GPUs with none are left out
+	past := c.Snapshot(time.Date(2025, 7, 18, 14, 0, 0, 0, time.UTC))
+	if past.Source != SnapshotSourceArchive || past.Total != 1 || past.Hosts != 1 {
+		t.Fatalf("Unexpected archive snapshot %+v", past)
+	}
+	gpu := past.GPUs[0]
+	if gpu.GPUId != "gpu_0" || gpu.Metrics["util"] != 10 || gpu.Metrics["temp"] != 60 || !gpu.LastSeen.Equal(time.Date(2025, 7, 18, 13, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected gpu_0 snapshot %+v", gpu)
+	}
+
+	rr := httptest.NewRecorder()
+	c.serveFleet(rr, httptest.NewRequest("GET", "/api/v1/fleet?as_of=yesterday", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid as_of, got %d", rr.Code)
+	}
+}
+
+func TestCollectorReadsThroughToStorage(t *testing.T) {
+	storage := persistencetest.NewTelemetryStore()
+	newCollector := func() *Collector {
+		return NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+			Writers:          1,
+			DataDir:          t.TempDir(),
+			MaxEntriesPerGPU: 10,
+			Storage:          storage,
+		})
+	}
+
+	before := newCollector()
+	telemetry, err := before.decode(mq.Message{Payload: []byte(`{"timestamp":"2025-07-18T13:00:00Z","fields":{"gpu_id":"gpu_0","Hostname":"host-1","metric_name":"util","value":10}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before.store(telemetry)
+
+	// A restarted collector has nothing cached but still finds gpu_0
+	c := newCollector()
+	if got := c.knownGPUs(); len(got) != 1 || got[0] != "gpu_0" {
+		t.Fatalf("Expected gpu_0 known from storage, got %v", got)
+	}
+
+	live := c.Snapshot(time.Time{})
+	if live.Total != 1 || live.GPUs[0].Metrics["util"] != 10 {
+		t.Errorf("Expected gpu_0 read through to storage, got %+v", live)
+	}
+
+	rr := httptest.NewRecorder()
+	c.serveGPUHealth(rr, httptest.NewRequest("GET", "/api/v1/gpus", nil))
+	if !strings.Contains(rr.Body.String(), `"gpu_id":"gpu_0"`) {
+		t.Errorf("Expected gpu_0 listed, got %s", rr.Body.String())
+	}
+}
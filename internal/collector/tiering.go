@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+)
+
+// DefaultTieringInterval is how often aged telemetry is moved down a tier
+const DefaultTieringInterval = 10 * time.Minute
+
+// Storage tiers, hottest first. Telemetry reads report the tiers that held
+// the data they returned.
+const (
+	TierHot  = "hot"  // the in-memory cache
+	TierWarm = "warm" // the telemetry files in the data directory
+	TierCold = "cold" // segments in the cold object store
+)
+
+// TiersHeader lists the tiers that served a telemetry read, comma-separated
+const TiersHeader = "X-Telemetry-Tiers"
+
+// TieringConfig moves telemetry to cheaper storage as it ages
+type TieringConfig struct {
+	// HotAge evicts cached samples older than this; zero keeps the newest
+	// MaxEntriesPerGPU samples whatever their age
+	HotAge time.Duration
+	// WarmAge moves samples older than this from the telemetry files to
+	// ColdDir; zero keeps them in the telemetry files
+	WarmAge time.Duration
+	// ColdDir holds cold segments, e.g. a mounted object storage bucket
+	ColdDir string
+	// Interval between tiering runs; zero uses DefaultTieringInterval
+	Interval time.Duration
+}
+
+// enabled reports whether any tiering is configured
+func (t TieringConfig) enabled() bool {
+	return t.HotAge > 0 || (t.WarmAge > 0 && t.ColdDir != "")
+}
+
+// tierTelemetry evicts samples older than HotAge from the cache and moves
+// samples older than WarmAge to cold storage, returning how many moved
+func (c *Collector) tierTelemetry(now time.Time) int {
+	tiering := c.config.Tiering
+	if tiering.HotAge > 0 {
+		c.memoryStorage.ClearOldEntries(tiering.HotAge)
+	}
+	if c.cold == nil || tiering.WarmAge <= 0 {
+		return 0
+	}
+
+	gpuIDs, err := c.fileStorage.ListGPUFiles()
+	if err != nil {
+		c.logger.Error("Failed to list telemetry files for tiering", "error", err)
+		return 0
+	}
+	cutoff := now.Add(-tiering.WarmAge)
+	moved := 0
+	for _, gpuID := range gpuIDs {
+		n, err := c.cold.ArchiveTelemetry(gpuID, cutoff)
+		if err != nil {
+			c.logger.Error("Failed to move telemetry to cold storage", "gpu_id", gpuID, "error", err)
+			continue
+		}
+		moved += n
+	}
+	return moved
+}
+
+// tieringLoop moves aged telemetry down the tiers at startup and then on
+// every interval
+func (c *Collector) tieringLoop() {
+	defer c.wg.Done()
+
+	interval := c.config.Tiering.Interval
+	if interval <= 0 {
+		interval = DefaultTieringInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if moved := c.tierTelemetry(time.Now()); moved > 0 {
+			c.logger.Info("Moved telemetry to cold storage", "samples", moved, "older_than", c.config.Tiering.WarmAge)
+			c.events.emit(EventEviction, "Moved telemetry to cold storage",
+				"store", "cold", "samples", strconv.Itoa(moved), "older_than", c.config.Tiering.WarmAge.String())
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+func TestCollectorTiering(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCollector(mq.NewBroker(mq.DefaultBrokerConfig()), CollectorConfig{
+		Writers:          1,
+		DataDir:          filepath.Join(dir, "data"),
+		MaxEntriesPerGPU: 10,
+		Tiering: TieringConfig{
+			HotAge:  time.Hour,
+			WarmAge: 24 * time.Hour,
+			ColdDir: filepath.Join(dir, "cold"),
+		},
+	})
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, age := range []time.Duration{48 * time.Hour, 30 * time.Hour, 2 * time.Hour, time.Minute} {
+		payload := fmt.Sprintf(`{"timestamp":%q,"fields":{"gpu_id":"gpu_0","Hostname":"host-1","metric_name":"util","value":%d}}`,
+			now.Add(-age).Format(time.RFC3339), i)
+		telemetry, err := c.decode(mq.Message{Payload: []byte(payload)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.store(telemetry)
+	}
+
+	if _, tiers := c.readTelemetry("gpu_0", time.Time{}, 0); !reflect.DeepEqual(tiers, []string{TierWarm}) {
+		t.Errorf("Expected only the warm tier before tiering, got %v", tiers)
+	}
+
+	if moved := c.tierTelemetry(now); moved != 2 {
+		t.Errorf("Expected 2 samples moved to cold storage, got %d", moved)
+	}
+	if cached := c.memoryStorage.GetTelemetryForGPU("gpu_0"); len(cached) != 1 {
+		t.Errorf("Expected 1 sample left in the cache, got %d", len(cached))
+	}
+
+	// Reads span the tiers, in timestamp order
+	data, tiers := c.readTelemetry("gpu_0", time.Time{}, 0)
+	if !reflect.DeepEqual(tiers, []string{TierCold, TierWarm}) {
+		t.Errorf("Expected the cold and warm tiers, got %v", tiers)
+	}
+	if len(data) != 4 || data[0].Metrics["util"] != 0 || data[3].Metrics["util"] != 3 {
+		t.Errorf("Unexpected telemetry across tiers: %+v", data)
+	}
+
+	// Cold segments before the range are not read
+	if _, tiers := c.readTelemetry("gpu_0", now.Add(-3*time.Hour), 0); !reflect.DeepEqual(tiers, []string{TierWarm}) {
+		t.Errorf("Expected only the warm tier for a recent range, got %v", tiers)
+	}
+
+	if report := c.compareGPU("gpu_0"); report.MissingInFile != 0 || report.MissingInMemory != 0 {
+		t.Errorf("Expected cache and storage consistent after tiering, got %+v", report)
+	}
+}
@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Units the DCGM metrics are reported in
+const (
+	UnitCelsius     = "C"
+	UnitWatts       = "W"
+	UnitPercent     = "%"
+	UnitMiB         = "MiB"
+	UnitMHz         = "MHz"
+	UnitMillijoules = "mJ"
+)
+
+// metricUnits are the units of the DCGM fields the exporter reports. Metrics
+// not listed have no known unit and are never converted.
+var metricUnits = map[string]string{
+	MetricGPUTemp:                          UnitCelsius,
+	"DCGM_FI_DEV_MEMORY_TEMP":              UnitCelsius,
+	MetricPowerUsage:                       UnitWatts,
+	"DCGM_FI_DEV_POWER_MGMT_LIMIT":         UnitWatts,
+	"DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION": UnitMillijoules,
+	MetricGPUUtil:                          UnitPercent,
+	"DCGM_FI_DEV_MEM_COPY_UTIL":            UnitPercent,
+	"DCGM_FI_DEV_ENC_UTIL":                 UnitPercent,
+	"DCGM_FI_DEV_DEC_UTIL":                 UnitPercent,
+	"DCGM_FI_DEV_FB_FREE":                  UnitMiB,
+	"DCGM_FI_DEV_FB_USED":                  UnitMiB,
+	"DCGM_FI_DEV_FB_RESERVED":              UnitMiB,
+	"DCGM_FI_DEV_FB_TOTAL":                 UnitMiB,
+	"DCGM_FI_DEV_SM_CLOCK":                 UnitMHz,
+	"DCGM_FI_DEV_MEM_CLOCK":                UnitMHz,
+}
+
+// ErrUnknownUnit is returned for a unit the collector cannot convert to
+var ErrUnknownUnit = errors.New("unknown unit")
+
+// unitScale relates a unit to the base unit of its dimension: a value in the
+// unit is value*factor+offset in the base unit
+type unitScale struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// unitScales are the units values can be converted between
+var unitScales = map[string]unitScale{
+	UnitCelsius:     {dimension: "temperature", factor: 1},
+	"F":             {dimension: "temperature", factor: 5.0 / 9, offset: -32 * 5.0 / 9},
+	"K":             {dimension: "temperature", factor: 1, offset: -273.15},
+	"mW":            {dimension: "power", factor: 1e-3},
+	UnitWatts:       {dimension: "power", factor: 1},
+	"kW":            {dimension: "power", factor: 1e3},
+	UnitPercent:     {dimension: "utilization", factor: 1},
+	"B":             {dimension: "memory", factor: 1},
+	"KiB":           {dimension: "memory", factor: 1 << 10},
+	UnitMiB:         {dimension: "memory", factor: 1 << 20},
+	"GiB":           {dimension: "memory", factor: 1 << 30},
+	"TiB":           {dimension: "memory", factor: 1 << 40},
+	UnitMHz:         {dimension: "frequency", factor: 1},
+	"GHz":           {dimension: "frequency", factor: 1e3},
+	UnitMillijoules: {dimension: "energy", factor: 1e-3},
+	"J":             {dimension: "energy", factor: 1},
+	"kJ":            {dimension: "energy", factor: 1e3},
+	"Wh":            {dimension: "energy", factor: 3600},
+	"kWh":           {dimension: "energy", factor: 3.6e6},
+}
+
+// MetricUnit returns the unit a metric is reported in, or "" if it is not
+// known
+func MetricUnit(metric string) string {
+	return metricUnits[metric]
+}
+
+// Units returns the units values can be converted to, in order
+func Units() []string {
+	units := make([]string, 0, len(unitScales))
+	for unit := range unitScales {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return units
+}
+
+// ConvertUnit converts a value between two units of the same dimension
+func ConvertUnit(value float64, from, to string) (float64, error) {
+	fromScale, ok := unitScales[from]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownUnit, from)
+	}
+	toScale, ok := unitScales[to]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownUnit, to)
+	}
+	if fromScale.dimension != toScale.dimension {
+		return 0, fmt.Errorf("cannot convert %s %s to %s %s", fromScale.dimension, from, toScale.dimension, to)
+	}
+	if from == to {
+		return value, nil
+	}
+	base := value*fromScale.factor + fromScale.offset
+	return roundSignificant((base-toScale.offset)/toScale.factor, 12), nil
+}
+
+// roundSignificant rounds value to digits significant digits, dropping the
+// floating point error of a conversion, so 50 C is 122 F rather than
+// 121.99999999999999 F
+func roundSignificant(value float64, digits int) float64 {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(value, 'g', digits, 64), 64)
+	if err != nil {
+		return value
+	}
+	return rounded
+}
+
+// UnitConversions are the units wanted for values, at most one for each
+// dimension such as memory or temperature. Values of metrics with a known
+// unit in a dimension are converted to its wanted unit.
+type UnitConversions map[string]string
+
+// ParseUnitConversions parses a comma-separated list of wanted units, such
+// as "GiB,F"
+func ParseUnitConversions(list string) (UnitConversions, error) {
+	conversions := make(UnitConversions)
+	for _, unit := range strings.Split(list, ",") {
+		unit = strings.TrimSpace(unit)
+		if unit == "" {
+			continue
+		}
+		scale, ok := unitScales[unit]
+		if !ok {
+			return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownUnit, unit, strings.Join(Units(), ", "))
+		}
+		if wanted, ok := conversions[scale.dimension]; ok && wanted != unit {
+			return nil, fmt.Errorf("units %s and %s are both %s units", wanted, unit, scale.dimension)
+		}
+		conversions[scale.dimension] = unit
+	}
+	return conversions, nil
+}
+
+// String lists the wanted units in order, as ParseUnitConversions takes them
+func (u UnitConversions) String() string {
+	units := make([]string, 0, len(u))
+	for _, unit := range u {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return strings.Join(units, ",")
+}
+
+// Unit returns the unit a metric's values are given in once converted, or
+// "" if the metric's unit is not known
+func (u UnitConversions) Unit(metric string) string {
+	unit := MetricUnit(metric)
+	if wanted, ok := u[unitScales[unit].dimension]; ok && unit != "" {
+		return wanted
+	}
+	return unit
+}
+
+// Convert returns a metric's value in the unit given by Unit
+func (u UnitConversions) Convert(metric string, value float64) float64 {
+	unit := MetricUnit(metric)
+	wanted := u.Unit(metric)
+	if wanted == unit {
+		return value
+	}
+	// The wanted unit is of the same dimension, so the conversion succeeds
+	converted, _ := ConvertUnit(value, unit, wanted)
+	return converted
+}
+
+// Apply converts metrics in place and returns the unit of each metric with a
+// known unit, or nil if none has one
+func (u UnitConversions) Apply(metrics map[string]float64) map[string]string {
+	var units map[string]string
+	for metric, value := range metrics {
+		unit := u.Unit(metric)
+		if unit == "" {
+			continue
+		}
+		if units == nil {
+			units = make(map[string]string, len(metrics))
+		}
+		units[metric] = unit
+		metrics[metric] = u.Convert(metric, value)
+	}
+	return units
+}
@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConvertUnit(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{4096, UnitMiB, "GiB", 4},
+		{1, "GiB", UnitMiB, 1024},
+		{100, UnitCelsius, "F", 212},
+		{32, "F", "K", 273.15},
+		{250, UnitWatts, "kW", 0.25},
+		{3.6e9, UnitMillijoules, "kWh", 1},
+		{1980, UnitMHz, "GHz", 1.98},
+		{87, UnitPercent, UnitPercent, 87},
+	}
+	for _, tt := range tests {
+		got, err := ConvertUnit(tt.value, tt.from, tt.to)
+		if err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ConvertUnit(%v, %s, %s) = %v, %v, want %v", tt.value, tt.from, tt.to, got, err, tt.want)
+		}
+	}
+
+	if _, err := ConvertUnit(1, UnitMiB, "F"); err == nil {
+		t.Error("Expected converting memory to a temperature to fail")
+	}
+	if _, err := ConvertUnit(1, UnitMiB, "parsecs"); !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("Expected ErrUnknownUnit, got %v", err)
+	}
+}
+
+func TestUnitConversions(t *testing.T) {
+	units, err := ParseUnitConversions(" GiB, kW ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if units.String() != "GiB,kW" {
+		t.Errorf("Unexpected conversions %s", units)
+	}
+
+	metrics := map[string]float64{
+		"DCGM_FI_DEV_FB_USED":    512,
+		MetricPowerUsage:         300,
+		MetricGPUTemp:            61.5,
+		"DCGM_FI_PROF_SM_ACTIVE": 0.4,
+	}
+	got := units.Apply(metrics)
+	want := map[string]string{"DCGM_FI_DEV_FB_USED": "GiB", MetricPowerUsage: "kW", MetricGPUTemp: UnitCelsius}
+	if len(got) != len(want) {
+		t.Fatalf("Expected units %v, got %v", want, got)
+	}
+	for metric, unit := range want {
+		if got[metric] != unit {
+			t.Errorf("Expected %s in %s, got %q", metric, unit, got[metric])
+		}
+	}
+	if metrics["DCGM_FI_DEV_FB_USED"] != 0.5 || metrics[MetricPowerUsage] != 0.3 || metrics[MetricGPUTemp] != 61.5 || metrics["DCGM_FI_PROF_SM_ACTIVE"] != 0.4 {
+		t.Errorf("Unexpected converted metrics %v", metrics)
+	}
+
+	for _, list := range []string{"GiB,MiB", "lightyears"} {
+		if _, err := ParseUnitConversions(list); err == nil {
+			t.Errorf("Expected %q to be rejected", list)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package encryptiontest provides keys and keyrings for tests of encrypted
+// storage.
+package encryptiontest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+)
+
+// Key returns a new random base64-encoded AES-256 key
+func Key(t testing.TB) string {
+	t.Helper()
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	return key
+}
+
+// Keyring parses a keyring from entries of the form "<key id>=<base64 key>",
+// the first of which is the active key
+func Keyring(t testing.TB, entries ...string) *encryption.Keyring {
+	t.Helper()
+	k, err := encryption.ParseKeyring(strings.Join(entries, "\n"))
+	if err != nil {
+		t.Fatalf("ParseKeyring failed: %v", err)
+	}
+	return k
+}
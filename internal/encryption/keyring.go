@@ -0,0 +1,214 @@
+// Package encryption provides AES-GCM encryption at rest for persisted
+// telemetry and broker messages, with key IDs to support key rotation.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeysEnvVar holds keys in the same format as a key file, with entries
+// separated by commas instead of newlines
+const KeysEnvVar = "TELEMETRY_ENCRYPTION_KEYS"
+
+// tokenPrefix marks encrypted records: enc1:<key id>:<base64(nonce|ciphertext)>
+const tokenPrefix = "enc1:"
+
+// Keyring holds the AES-GCM keys used for encryption at rest. New data is
+// always sealed with the active key; every key in the ring can open data,
+// so old keys stay listed until a re-encryption pass has rotated them out.
+type Keyring struct {
+	activeID string
+	keys     map[string]cipher.AEAD
+}
+
+// ParseKeyring parses key entries of the form "<key id>=<base64 key>",
+// separated by newlines or commas. The first entry is the active key. Blank
+// lines and lines starting with '#' are ignored. Keys must decode to 16, 24
+// or 32 bytes (AES-128/192/256).
+func ParseKeyring(data string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+
+	entries := strings.FieldsFunc(data, func(r rune) bool { return r == '\n' || r == ',' })
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, "=")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid key entry: expected <key id>=<base64 key>")
+		}
+		if strings.Contains(id, ":") {
+			return nil, fmt.Errorf("key id %q must not contain ':'", id)
+		}
+		if _, exists := k.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+
+		k.keys[id] = aead
+		if k.activeID == "" {
+			k.activeID = id
+		}
+	}
+
+	if k.activeID == "" {
+		return nil, fmt.Errorf("no encryption keys found")
+	}
+
+	return k, nil
+}
+
+// LoadKeyringFile reads a keyring from a file, such as a secret mounted by a KMS
+func LoadKeyringFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+	}
+	return ParseKeyring(string(data))
+}
+
+// LoadKeyring loads the keyring from keyFile if set, otherwise from the
+// TELEMETRY_ENCRYPTION_KEYS environment variable. It returns nil without an
+// error when neither is configured, meaning encryption is disabled.
+func LoadKeyring(keyFile string) (*Keyring, error) {
+	if keyFile != "" {
+		return LoadKeyringFile(keyFile)
+	}
+	if keys := os.Getenv(KeysEnvVar); keys != "" {
+		return ParseKeyring(keys)
+	}
+	return nil, nil
+}
+
+// ActiveKeyID returns the ID of the key used to encrypt new data
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// Encrypt seals plaintext with the active key. The result is a single-line
+// ASCII token safe for line-oriented log files.
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	aead := k.keys[k.activeID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// The key ID is authenticated so a token cannot be relabelled
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(k.activeID))
+
+	token := make([]byte, 0, len(tokenPrefix)+len(k.activeID)+1+base64.StdEncoding.EncodedLen(len(sealed)))
+	token = append(token, tokenPrefix...)
+	token = append(token, k.activeID...)
+	token = append(token, ':')
+	token = base64.StdEncoding.AppendEncode(token, sealed)
+	return token, nil
+}
+
+// Decrypt opens a token produced by Encrypt using whichever key sealed it
+func (k *Keyring) Decrypt(token []byte) ([]byte, error) {
+	id, sealed, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("data encrypted with unknown key %q", id)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted data too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data with key %q: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+// NeedsRotation reports whether data is plaintext or sealed with a key other
+// than the active one
+func (k *Keyring) NeedsRotation(data []byte) bool {
+	id, _, err := parseToken(data)
+	return err != nil || id != k.activeID
+}
+
+// Open returns the plaintext of data, decrypting it if it is an encrypted
+// token. A nil keyring passes plaintext through and rejects encrypted data,
+// so callers can use it unconditionally.
+func (k *Keyring) Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if k == nil {
+		return nil, fmt.Errorf("data is encrypted but no encryption key is configured")
+	}
+	return k.Decrypt(data)
+}
+
+// Seal encrypts plaintext with the active key, or returns it unchanged when
+// the keyring is nil (encryption disabled)
+func (k *Keyring) Seal(plaintext []byte) ([]byte, error) {
+	if k == nil {
+		return plaintext, nil
+	}
+	return k.Encrypt(plaintext)
+}
+
+// IsEncrypted reports whether data is an encrypted token
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(tokenPrefix))
+}
+
+// GenerateKey returns a new random AES-256 key encoded as base64
+func GenerateKey() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// parseToken splits a token into its key ID and sealed bytes
+func parseToken(token []byte) (string, []byte, error) {
+	if !IsEncrypted(token) {
+		return "", nil, fmt.Errorf("data is not encrypted")
+	}
+
+	id, encoded, ok := bytes.Cut(token[len(tokenPrefix):], []byte(":"))
+	if !ok || len(id) == 0 {
+		return "", nil, fmt.Errorf("malformed encrypted data")
+	}
+
+	sealed, err := base64.StdEncoding.AppendDecode(nil, encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("malformed encrypted data: %w", err)
+	}
+	return string(id), sealed, nil
+}
@@ -0,0 +1,198 @@
+package encryption_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/encryption/encryptiontest"
+)
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	k, err := encryption.ParseKeyring("k1=" + encryptiontest.Key(t))
+	if err != nil {
+		t.Fatalf("ParseKeyring failed: %v", err)
+	}
+
+	plaintext := []byte(`{"gpu_id":"gpu-0"}`)
+	token, err := k.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	if !encryption.IsEncrypted(token) {
+		t.Errorf("expected token to be recognised as encrypted: %s", token)
+	}
+	if bytes.Contains(token, []byte("gpu-0")) {
+		t.Error("token leaks plaintext")
+	}
+	if bytes.ContainsAny(token, "\n\r") {
+		t.Error("token must be a single line")
+	}
+
+	decrypted, err := k.Decrypt(token)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decrypt = %s, want %s", decrypted, plaintext)
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	oldKey, newKey := encryptiontest.Key(t), encryptiontest.Key(t)
+
+	oldRing, err := encryption.ParseKeyring("old=" + oldKey)
+	if err != nil {
+		t.Fatalf("ParseKeyring failed: %v", err)
+	}
+	token, err := oldRing.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	// New key first, old key kept for decryption
+	rotated, err := encryption.ParseKeyring("# rotated\nnew=" + newKey + "\nold=" + oldKey + "\n")
+	if err != nil {
+		t.Fatalf("ParseKeyring failed: %v", err)
+	}
+	if rotated.ActiveKeyID() != "new" {
+		t.Errorf("ActiveKeyID = %q, want new", rotated.ActiveKeyID())
+	}
+
+	if !rotated.NeedsRotation(token) {
+		t.Error("token sealed with old key should need rotation")
+	}
+	plaintext, err := rotated.Decrypt(token)
+	if err != nil {
+		t.Fatalf("Decrypt with rotated keyring failed: %v", err)
+	}
+	if string(plaintext) != "secret" {
+		t.Errorf("Decrypt = %q, want secret", plaintext)
+	}
+
+	fresh, _ := rotated.Encrypt([]byte("secret"))
+	if rotated.NeedsRotation(fresh) {
+		t.Error("token sealed with active key should not need rotation")
+	}
+	if !rotated.NeedsRotation([]byte("plaintext")) {
+		t.Error("plaintext should need rotation")
+	}
+
+	// Once the old key is dropped its data is unreadable
+	newOnly, _ := encryption.ParseKeyring("new=" + newKey)
+	if _, err := newOnly.Decrypt(token); err == nil {
+		t.Error("expected error decrypting with unknown key")
+	}
+}
+
+func TestKeyring_TamperedToken(t *testing.T) {
+	k, _ := encryption.ParseKeyring("k1=" + encryptiontest.Key(t))
+	token, _ := k.Encrypt([]byte("payload"))
+
+	// Relabelling the key ID must fail authentication
+	k2, _ := encryption.ParseKeyring("k1=" + encryptiontest.Key(t))
+	if _, err := k2.Decrypt(token); err == nil {
+		t.Error("expected error decrypting with wrong key material")
+	}
+
+	tampered := append([]byte(nil), token...)
+	tampered[len(tampered)-3] ^= 0x01
+	if _, err := k.Decrypt(tampered); err == nil {
+		t.Error("expected error decrypting tampered token")
+	}
+}
+
+func TestKeyring_NilPassthrough(t *testing.T) {
+	var k *encryption.Keyring
+
+	sealed, err := k.Seal([]byte("plain"))
+	if err != nil || string(sealed) != "plain" {
+		t.Errorf("nil Seal = %q, %v; want plain", sealed, err)
+	}
+
+	opened, err := k.Open([]byte("plain"))
+	if err != nil || string(opened) != "plain" {
+		t.Errorf("nil Open = %q, %v; want plain", opened, err)
+	}
+
+	if _, err := k.Open([]byte("enc1:k1:AAAA")); err == nil {
+		t.Error("expected error opening encrypted data without a key")
+	}
+}
+
+func TestParseKeyring_Errors(t *testing.T) {
+	valid := encryptiontest.Key(t)
+	short := base64.StdEncoding.EncodeToString([]byte("too-short"))
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"comments_only", "# nothing here\n"},
+		{"missing_separator", valid},
+		{"missing_id", "=" + valid},
+		{"id_with_colon", "a:b=" + valid},
+		{"bad_base64", "k1=not base64!"},
+		{"bad_key_length", "k1=" + short},
+		{"duplicate_id", "k1=" + valid + ",k1=" + valid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := encryption.ParseKeyring(tt.input); err == nil {
+				t.Errorf("expected error for %q", tt.input)
+			}
+		})
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	key := encryptiontest.Key(t)
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv(encryption.KeysEnvVar, "")
+		k, err := encryption.LoadKeyring("")
+		if err != nil || k != nil {
+			t.Errorf("LoadKeyring = %v, %v; want nil, nil", k, err)
+		}
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv(encryption.KeysEnvVar, "env2="+key+",env1="+encryptiontest.Key(t))
+		k, err := encryption.LoadKeyring("")
+		if err != nil {
+			t.Fatalf("LoadKeyring failed: %v", err)
+		}
+		if k.ActiveKeyID() != "env2" {
+			t.Errorf("ActiveKeyID = %q, want env2", k.ActiveKeyID())
+		}
+	})
+
+	t.Run("file_takes_precedence", func(t *testing.T) {
+		t.Setenv(encryption.KeysEnvVar, "env="+key)
+		path := filepath.Join(t.TempDir(), "keys")
+		if err := os.WriteFile(path, []byte("file="+key+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		k, err := encryption.LoadKeyring(path)
+		if err != nil {
+			t.Fatalf("LoadKeyring failed: %v", err)
+		}
+		if k.ActiveKeyID() != "file" {
+			t.Errorf("ActiveKeyID = %q, want file", k.ActiveKeyID())
+		}
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		_, err := encryption.LoadKeyring(filepath.Join(t.TempDir(), "missing"))
+		if err == nil || !strings.Contains(err.Error(), "key file") {
+			t.Errorf("expected key file error, got %v", err)
+		}
+	})
+}
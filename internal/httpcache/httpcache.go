@@ -0,0 +1,77 @@
+// Package httpcache implements HTTP conditional request handling
+// (If-None-Match / If-Modified-Since) for JSON endpoints whose responses are
+// generated rather than served from files
+package httpcache
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SetValidators sets the ETag and Last-Modified response headers. Empty or
+// zero values are left unset.
+func SetValidators(w http.ResponseWriter, etag string, lastModified time.Time) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// NotModified reports whether the conditional headers on r show the client
+// already has the representation identified by etag and lastModified. As in
+// RFC 9110, If-Modified-Since is ignored when If-None-Match is present.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && MatchETag(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// Last-Modified has one-second resolution on the wire
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// MatchETag reports whether an If-None-Match header value matches etag,
+// using weak comparison
+func MatchETag(header, etag string) bool {
+	for _, tag := range ParseETags(header) {
+		if tag == "*" || opaqueTag(tag) == opaqueTag(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseETags splits an If-None-Match header value into its entity tags
+func ParseETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Opaque returns the quoted-string content of an entity tag, without any weak
+// prefix or quotes
+func Opaque(tag string) string {
+	return strings.Trim(opaqueTag(tag), `"`)
+}
+
+func opaqueTag(tag string) string {
+	return strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+}
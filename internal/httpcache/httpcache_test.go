@@ -0,0 +1,74 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2024, 1, 1, 12, 0, 0, 500, time.UTC)
+	etag := `"3-abc"`
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{"no conditionals", "GET", nil, false},
+		{"matching etag", "GET", map[string]string{"If-None-Match": `"3-abc"`}, true},
+		{"weak matching etag", "GET", map[string]string{"If-None-Match": `W/"3-abc"`}, true},
+		{"etag in list", "GET", map[string]string{"If-None-Match": `"1-x", "3-abc"`}, true},
+		{"wildcard", "GET", map[string]string{"If-None-Match": "*"}, true},
+		{"stale etag", "GET", map[string]string{"If-None-Match": `"2-abc"`}, false},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, true},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": lastModified.Add(-time.Minute).Format(http.TimeFormat)}, false},
+		{"invalid date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{"etag takes precedence", "GET", map[string]string{
+			"If-None-Match":     `"2-abc"`,
+			"If-Modified-Since": lastModified.Format(http.TimeFormat),
+		}, false},
+		{"head request", "HEAD", map[string]string{"If-None-Match": `"3-abc"`}, true},
+		{"post request", "POST", map[string]string{"If-None-Match": `"3-abc"`}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := NotModified(req, etag, lastModified); got != tt.want {
+				t.Errorf("NotModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetValidators(t *testing.T) {
+	rr := httptest.NewRecorder()
+	SetValidators(rr, `"1-a"`, time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("X", 3600)))
+
+	if got := rr.Header().Get("ETag"); got != `"1-a"` {
+		t.Errorf("ETag = %q", got)
+	}
+	if got := rr.Header().Get("Last-Modified"); got != "Mon, 01 Jan 2024 11:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	SetValidators(rr, "", time.Time{})
+	if len(rr.Header()) != 0 {
+		t.Errorf("Expected no headers, got %v", rr.Header())
+	}
+}
+
+func TestOpaque(t *testing.T) {
+	for tag, want := range map[string]string{`"a-b"`: "a-b", `W/"a-b"`: "a-b", ` "x" `: "x"} {
+		if got := Opaque(tag); got != want {
+			t.Errorf("Opaque(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
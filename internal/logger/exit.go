@@ -0,0 +1,207 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCrashReportRecords is how many recent log records a crash report
+// holds unless Config.CrashReportRecords says otherwise
+const DefaultCrashReportRecords = 500
+
+// exitHookTimeout bounds how long a fatal exit waits for its hooks, so a hook
+// stuck on a broken dependency cannot keep a failed service running
+const exitHookTimeout = 10 * time.Second
+
+// exitHook is a function run before a fatal exit
+type exitHook struct {
+	name string
+	fn   func()
+}
+
+var (
+	exitHooksMu sync.Mutex
+	exitHooks   []exitHook
+
+	// exiting is set once a fatal exit has begun, so a hook that fails
+	// fatally exits at once instead of running the hooks again
+	exiting atomic.Bool
+
+	// exit ends the process; replaced in tests
+	exit = os.Exit
+)
+
+// RegisterExitHook registers fn to run when the process exits through Fatal
+// or a panic reported by RecoverPanic, such as flushing storage or closing
+// the broker so a fatal error loses no more than it has to. Hooks run in the
+// reverse order of registration, like deferred calls, and together get 10
+// seconds; a hook that panics is logged and skipped. They do not run on a
+// normal exit.
+func RegisterExitHook(name string, fn func()) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	exitHooks = append(exitHooks, exitHook{name: name, fn: fn})
+}
+
+// runExitHooks runs the registered hooks, newest first, logging those that
+// panic or do not finish in time
+func (l *Logger) runExitHooks() {
+	exitHooksMu.Lock()
+	hooks := append([]exitHook(nil), exitHooks...)
+	exitHooksMu.Unlock()
+
+	done := make(chan struct{})
+	var current atomic.Value
+	go func() {
+		defer close(done)
+		for i := len(hooks) - 1; i >= 0; i-- {
+			current.Store(hooks[i].name)
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						l.Logger.Error("Exit hook panicked", "hook", hooks[i].name, "panic", fmt.Sprint(r))
+					}
+				}()
+				hooks[i].fn()
+			}()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(exitHookTimeout):
+		l.Logger.Error("Exit hooks timed out", "hook", current.Load(), "timeout", exitHookTimeout)
+	}
+}
+
+// fatalExit runs the exit hooks, writes a crash report if they are enabled,
+// flushes exported logs and exits with code
+func (l *Logger) fatalExit(code int, reason string) {
+	if exiting.Swap(true) {
+		l.Flush()
+		exit(code)
+		return
+	}
+	l.runExitHooks()
+	if path, err := l.writeCrashReport(reason); err != nil {
+		l.Logger.Error("Failed to write crash report", "error", err)
+	} else if path != "" {
+		l.Logger.Error("Wrote crash report", "path", path)
+	}
+	l.Flush()
+	exit(code)
+}
+
+// RecoverPanic reports a panic of the calling goroutine as a fatal error,
+// with its stack, and exits with status 2 like an unrecovered panic. It must
+// be deferred directly, and only covers the goroutine that defers it:
+//
+//	defer log.RecoverPanic()
+func (l *Logger) RecoverPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	reason := fmt.Sprint(r)
+	l.Logger.Error("Panic", "panic", reason, "stack", string(debug.Stack()))
+	l.fatalExit(2, "panic: "+reason)
+}
+
+// crashRing keeps the most recent log records, one per write, for crash
+// reports. Every logger with crash reports enabled shares it, so a report
+// holds the last records of the whole process.
+type crashRing struct {
+	mu      sync.Mutex
+	records [][]byte
+	next    int
+	full    bool
+}
+
+var (
+	crashRecordsOnce sync.Once
+	crashRecords     *crashRing
+)
+
+// sharedCrashRing returns the process's crash ring, sized by the first
+// logger that asks for it
+func sharedCrashRing(size int) *crashRing {
+	crashRecordsOnce.Do(func() {
+		if size <= 0 {
+			size = DefaultCrashReportRecords
+		}
+		crashRecords = &crashRing{records: make([][]byte, size)}
+	})
+	return crashRecords
+}
+
+// Write keeps p as one record; slog handlers write each record at once
+func (r *crashRing) Write(p []byte) (int, error) {
+	record := append([]byte(nil), p...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+	return len(p), nil
+}
+
+// snapshot returns the records kept, oldest first
+func (r *crashRing) snapshot() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([][]byte(nil), r.records[:r.next]...)
+	}
+	return append(append([][]byte(nil), r.records[r.next:]...), r.records[:r.next]...)
+}
+
+// writeCrashReport writes the reason for a fatal exit, the last log records
+// and the stacks of all goroutines to a new file in the crash report
+// directory, returning its path, or "" if crash reports are disabled
+func (l *Logger) writeCrashReport(reason string) (string, error) {
+	if l.crashDir == "" {
+		return "", nil
+	}
+	if err := os.MkdirAll(l.crashDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create crash report directory: %w", err)
+	}
+
+	executable := filepath.Base(os.Args[0])
+	now := time.Now().UTC()
+	var report bytes.Buffer
+	fmt.Fprintf(&report, "Crash report for %s (pid %d) at %s\n", executable, os.Getpid(), now.Format(time.RFC3339))
+	fmt.Fprintf(&report, "Reason: %s\n", reason)
+
+	records := sharedCrashRing(0).snapshot()
+	fmt.Fprintf(&report, "\nLast %d log records:\n", len(records))
+	for _, record := range records {
+		report.Write(record)
+	}
+
+	// Grow the buffer until every goroutine's stack fits
+	stacks := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(stacks, true)
+		if n < len(stacks) {
+			stacks = stacks[:n]
+			break
+		}
+		stacks = make([]byte, 2*len(stacks))
+	}
+	fmt.Fprintf(&report, "\nGoroutine stacks:\n%s", stacks)
+
+	path := filepath.Join(l.crashDir, fmt.Sprintf("crash-%s-%s-%d.log", executable, now.Format("20060102T150405Z"), os.Getpid()))
+	if err := os.WriteFile(path, report.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeExit makes fatal exits record their code instead of exiting, and
+// clears the exit hooks afterwards
+func fakeExit(t *testing.T) *int {
+	code := -1
+	exit = func(c int) { code = c }
+	t.Cleanup(func() {
+		exit = os.Exit
+		exiting.Store(false)
+		exitHooksMu.Lock()
+		exitHooks = nil
+		exitHooksMu.Unlock()
+	})
+	return &code
+}
+
+func TestFatal_RunsExitHooksAndWritesCrashReport(t *testing.T) {
+	code := fakeExit(t)
+	dir := t.TempDir()
+
+	var order []string
+	RegisterExitHook("storage", func() { order = append(order, "storage") })
+	RegisterExitHook("broken", func() { panic("boom") })
+	RegisterExitHook("broker", func() { order = append(order, "broker") })
+
+	var buf bytes.Buffer
+	log := New(Config{Level: INFO, Format: "text", Output: &buf, CrashReportDir: dir})
+	log.Info("before the crash", "gpu_id", "gpu-0")
+	log.With("component", "writer").Fatal("disk on fire", "token", "s3cr3t")
+
+	if *code != 1 {
+		t.Errorf("Expected exit status 1, got %d", *code)
+	}
+	if strings.Join(order, ",") != "broker,storage" {
+		t.Errorf("Expected hooks to run newest first, got %v", order)
+	}
+	if !strings.Contains(buf.String(), `msg="Exit hook panicked" component=writer hook=broken panic=boom`) {
+		t.Errorf("Expected the panicking hook logged, got %q", buf.String())
+	}
+
+	reports, _ := filepath.Glob(filepath.Join(dir, "crash-*.log"))
+	if len(reports) != 1 {
+		t.Fatalf("Expected one crash report, got %v", reports)
+	}
+	data, err := os.ReadFile(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{"Reason: disk on fire", "before the crash", `msg="disk on fire" component=writer token=[REDACTED]`, "Goroutine stacks:", "goroutine "} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Crash report missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "s3cr3t") {
+		t.Error("Crash report leaks a redacted value")
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	code := fakeExit(t)
+	ran := false
+	RegisterExitHook("flush", func() { ran = true })
+
+	var buf bytes.Buffer
+	log := New(Config{Level: INFO, Format: "text", Output: &buf})
+	func() {
+		defer log.RecoverPanic()
+		panic("nil map")
+	}()
+
+	if *code != 2 || !ran {
+		t.Errorf("Expected exit status 2 after the hooks ran, got %d (hook ran: %v)", *code, ran)
+	}
+	if !strings.Contains(buf.String(), `msg=Panic panic="nil map" stack=`) {
+		t.Errorf("Expected the panic logged with its stack, got %q", buf.String())
+	}
+}
+
+func TestCrashRing(t *testing.T) {
+	ring := &crashRing{records: make([][]byte, 2)}
+	for _, record := range []string{"a\n", "b\n", "c\n"} {
+		_, _ = ring.Write([]byte(record))
+	}
+	if got := string(bytes.Join(ring.snapshot(), nil)); got != "b\nc\n" {
+		t.Errorf("Expected the last two records oldest first, got %q", got)
+	}
+}
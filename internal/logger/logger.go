@@ -5,6 +5,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -22,9 +23,25 @@ const (
 // Config holds the logger configuration
 type Config struct {
 	Level     LogLevel
-	Format    string // "json" or "text"
+	Format    string // "json", "text" or "otlp"
 	Output    io.Writer
 	AddSource bool
+	// OTLP configures the export of logs with the "otlp" format, which
+	// also writes them to Output as text
+	OTLP OTLPConfig
+	// RedactKeys are the patterns of attribute keys, such as "token", whose
+	// values are masked before records are written or exported. Keys match
+	// a pattern they contain, ignoring case. Nil uses DefaultRedactKeys; an
+	// empty slice turns redaction off.
+	RedactKeys []string
+	// CrashReportDir, if set, is where Fatal and RecoverPanic write a crash
+	// report with the last CrashReportRecords log records (default
+	// DefaultCrashReportRecords) and the stacks of all goroutines
+	CrashReportDir     string
+	CrashReportRecords int
+
+	// exporter is shared by loggers created from the environment
+	exporter *otlpExporter
 }
 
 // DefaultConfig returns a default logger configuration
@@ -40,7 +57,9 @@ func DefaultConfig() Config {
 // Logger wraps slog.Logger with additional functionality
 type Logger struct {
 	*slog.Logger
-	level slog.Level
+	level    slog.Level
+	exporter *otlpExporter // nil unless logs are exported over OTLP
+	crashDir string        // "" unless crash reports are written
 }
 
 // New creates a new logger with the given configuration
@@ -75,15 +94,39 @@ func New(config Config) *Logger {
 	}
 
 	var handler slog.Handler
-	if config.Format == "json" {
+	var exporter *otlpExporter
+	switch config.Format {
+	case "json":
 		handler = slog.NewJSONHandler(config.Output, opts)
-	} else {
+	case "otlp":
+		exporter = config.exporter
+		if exporter == nil {
+			exporter = newOTLPExporter(config.OTLP)
+		}
+		handler = teeHandler{
+			slog.NewTextHandler(config.Output, opts),
+			&otlpHandler{exporter: exporter, level: level},
+		}
+	default:
 		handler = slog.NewTextHandler(config.Output, opts)
 	}
 
+	// Keep recent records for crash reports
+	if config.CrashReportDir != "" {
+		handler = teeHandler{handler, slog.NewTextHandler(sharedCrashRing(config.CrashReportRecords), opts)}
+	}
+
+	redactKeys := config.RedactKeys
+	if redactKeys == nil {
+		redactKeys = DefaultRedactKeys
+	}
+	handler = newRedactHandler(handler, redactKeys)
+
 	return &Logger{
-		Logger: slog.New(handler),
-		level:  level,
+		Logger:   slog.New(handler),
+		level:    level,
+		exporter: exporter,
+		crashDir: config.CrashReportDir,
 	}
 }
 
@@ -106,41 +149,82 @@ func NewFromEnv() *Logger {
 		config.AddSource = true
 	}
 
+	// Mask the values of sensitive keys
+	config.RedactKeys = redactKeysFromEnv()
+
+	// Write crash reports on fatal errors if asked to
+	config.CrashReportDir = os.Getenv("LOG_CRASH_DIR")
+	if records, err := strconv.Atoi(os.Getenv("LOG_CRASH_RECORDS")); err == nil && records > 0 {
+		config.CrashReportRecords = records
+	}
+
+	// Export over OTLP as configured by the OTEL_* variables
+	if config.Format == "otlp" {
+		config.exporter = sharedEnvExporter()
+	}
+
 	return New(config)
 }
 
 // With adds key-value pairs to all log messages
 func (l *Logger) With(keysAndValues ...interface{}) *Logger {
 	return &Logger{
-		Logger: l.Logger.With(keysAndValues...),
-		level:  l.level,
+		Logger:   l.Logger.With(keysAndValues...),
+		level:    l.level,
+		exporter: l.exporter,
+		crashDir: l.crashDir,
 	}
 }
 
 // WithComponent adds a component field to all log messages
 func (l *Logger) WithComponent(component string) *Logger {
 	return &Logger{
-		Logger: l.Logger.With("component", component),
-		level:  l.level,
+		Logger:   l.Logger.With("component", component),
+		level:    l.level,
+		exporter: l.exporter,
+		crashDir: l.crashDir,
 	}
 }
 
 // WithRequestID adds a request ID field to all log messages
 func (l *Logger) WithRequestID(requestID string) *Logger {
 	return &Logger{
-		Logger: l.Logger.With("request_id", requestID),
-		level:  l.level,
+		Logger:   l.Logger.With("request_id", requestID),
+		level:    l.level,
+		exporter: l.exporter,
+		crashDir: l.crashDir,
 	}
 }
 
 // WithContext creates a logger that uses the given context
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	return &Logger{
-		Logger: l.Logger.With(),
-		level:  l.level,
+		Logger:   l.Logger.With(),
+		level:    l.level,
+		exporter: l.exporter,
+		crashDir: l.crashDir,
 	}
 }
 
+// contextKey is the context key of the logger stored by NewContext
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l. Middleware stores a logger
+// with a request's correlation fields this way, so code handling the request
+// logs them without passing the logger down.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx by NewContext, or the global
+// logger if there is none
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	return globalLogger
+}
+
 // IsDebugEnabled returns true if debug logging is enabled
 func (l *Logger) IsDebugEnabled() bool {
 	return l.level <= slog.LevelDebug
@@ -191,16 +275,25 @@ func (l *Logger) ErrorContext(ctx context.Context, msg string, keysAndValues ...
 	l.Logger.ErrorContext(ctx, msg, keysAndValues...)
 }
 
-// Fatal logs a fatal error and calls os.Exit(1)
+// Fatal logs a fatal error, runs the exit hooks, writes a crash report if
+// enabled and calls os.Exit(1)
 func (l *Logger) Fatal(msg string, keysAndValues ...interface{}) {
 	l.Logger.Error(msg, keysAndValues...)
-	os.Exit(1)
+	l.fatalExit(1, msg)
 }
 
 // FatalContext logs a fatal error with context and calls os.Exit(1)
 func (l *Logger) FatalContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
 	l.Logger.ErrorContext(ctx, msg, keysAndValues...)
-	os.Exit(1)
+	l.fatalExit(1, msg)
+}
+
+// Flush exports the records logged so far when logs are exported over
+// OTLP, waiting until they are sent. Services call it before exiting.
+func (l *Logger) Flush() {
+	if l.exporter != nil {
+		l.exporter.flush()
+	}
 }
 
 // Global logger instance
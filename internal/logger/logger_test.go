@@ -284,6 +284,24 @@ func TestLogger_WithContext(t *testing.T) {
 	}
 }
 
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != GetGlobalLogger() {
+		t.Error("Expected the global logger for a context without one")
+	}
+
+	var buf bytes.Buffer
+	requestLogger := New(Config{Level: INFO, Format: "text", Output: &buf}).WithRequestID("req-42")
+	ctx := NewContext(context.Background(), requestLogger)
+	if FromContext(ctx) != requestLogger {
+		t.Fatal("Expected the logger stored in the context")
+	}
+
+	FromContext(ctx).Info("deep call site")
+	if !strings.Contains(buf.String(), "request_id=req-42") {
+		t.Errorf("Output %q does not carry the request's fields", buf.String())
+	}
+}
+
 func TestLogger_LevelChecks(t *testing.T) {
 	tests := []struct {
 		name         string
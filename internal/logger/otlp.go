@@ -0,0 +1,498 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults for exporting logs over OTLP
+const (
+	DefaultOTLPEndpoint      = "http://localhost:4318/v1/logs"
+	DefaultOTLPBatchSize     = 512
+	DefaultOTLPFlushInterval = 5 * time.Second
+	DefaultOTLPQueueSize     = 4096
+	DefaultOTLPTimeout       = 10 * time.Second
+)
+
+// OTLPConfig configures exporting logs to an OpenTelemetry collector with
+// OTLP/HTTP, used when Config.Format is "otlp"
+type OTLPConfig struct {
+	// Endpoint is the full URL logs are posted to
+	Endpoint string
+	// Headers are added to every export, e.g. for authentication
+	Headers map[string]string
+	// Resource attributes identifying the service. Empty ones default to
+	// the executable's name, the module version it was built from and the
+	// hostname.
+	ServiceName       string
+	ServiceVersion    string
+	ServiceInstanceID string
+	// Attributes are further resource attributes, such as
+	// deployment.environment
+	Attributes map[string]string
+	// Records are exported in batches of BatchSize, or after FlushInterval
+	// if fewer are waiting. Up to QueueSize records wait; further ones are
+	// dropped rather than slowing the service down.
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+	// Timeout bounds each export request
+	Timeout time.Duration
+}
+
+// OTLPConfigFromEnv reads the standard OpenTelemetry exporter variables:
+// OTEL_EXPORTER_OTLP_LOGS_ENDPOINT (or OTEL_EXPORTER_OTLP_ENDPOINT, to which
+// /v1/logs is appended), OTEL_EXPORTER_OTLP_HEADERS, OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES
+func OTLPConfigFromEnv() OTLPConfig {
+	config := OTLPConfig{
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"),
+		Headers:     parseKeyValues(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		Attributes:  parseKeyValues(os.Getenv("OTEL_RESOURCE_ATTRIBUTES")),
+	}
+	if config.Endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			config.Endpoint = strings.TrimSuffix(base, "/") + "/v1/logs"
+		}
+	}
+	// Resource attributes may name the service too; OTEL_SERVICE_NAME wins
+	if config.ServiceName == "" {
+		config.ServiceName = config.Attributes["service.name"]
+	}
+	config.ServiceVersion = config.Attributes["service.version"]
+	config.ServiceInstanceID = config.Attributes["service.instance.id"]
+	return config
+}
+
+// parseKeyValues parses a comma-separated list of key=value pairs
+func parseKeyValues(s string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); ok && key != "" {
+			values[key] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+// withDefaults fills in unset fields
+func (c OTLPConfig) withDefaults() OTLPConfig {
+	if c.Endpoint == "" {
+		c.Endpoint = DefaultOTLPEndpoint
+	}
+	if c.ServiceName == "" {
+		c.ServiceName = filepath.Base(os.Args[0])
+	}
+	if c.ServiceVersion == "" {
+		c.ServiceVersion = "unknown"
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+			c.ServiceVersion = info.Main.Version
+		}
+	}
+	if c.ServiceInstanceID == "" {
+		hostname, _ := os.Hostname()
+		c.ServiceInstanceID = hostname
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultOTLPBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultOTLPFlushInterval
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultOTLPQueueSize
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultOTLPTimeout
+	}
+	return c
+}
+
+// otlpExporter batches log records and posts them to an OTLP/HTTP endpoint
+// in the JSON encoding
+type otlpExporter struct {
+	config   OTLPConfig
+	client   *http.Client
+	resource otlpResource
+	queue    chan otlpLogRecord
+	flushes  chan chan struct{}
+	dropped  atomic.Int64
+	// errorOutput reports failed exports, at most once per flush interval
+	errorOutput  *os.File
+	lastReported time.Time
+}
+
+func newOTLPExporter(config OTLPConfig) *otlpExporter {
+	config = config.withDefaults()
+	attributes := map[string]string{
+		"service.name":        config.ServiceName,
+		"service.version":     config.ServiceVersion,
+		"service.instance.id": config.ServiceInstanceID,
+	}
+	for key, value := range config.Attributes {
+		if _, set := attributes[key]; !set {
+			attributes[key] = value
+		}
+	}
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resource := otlpResource{}
+	for _, key := range keys {
+		resource.Attributes = append(resource.Attributes, otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: strPtr(attributes[key])}})
+	}
+
+	e := &otlpExporter{
+		config:      config,
+		client:      &http.Client{Timeout: config.Timeout},
+		resource:    resource,
+		queue:       make(chan otlpLogRecord, config.QueueSize),
+		flushes:     make(chan chan struct{}),
+		errorOutput: os.Stderr,
+	}
+	go e.run()
+	return e
+}
+
+// enqueue queues a record, dropping it if the queue is full
+func (e *otlpExporter) enqueue(record otlpLogRecord) {
+	select {
+	case e.queue <- record:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// flush exports the records queued so far and waits until they are sent
+func (e *otlpExporter) flush() {
+	done := make(chan struct{})
+	e.flushes <- done
+	<-done
+}
+
+// run exports batches until the process exits
+func (e *otlpExporter) run() {
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpLogRecord, 0, e.config.BatchSize)
+	send := func() {
+		if len(batch) > 0 {
+			e.export(batch)
+			batch = batch[:0]
+		}
+	}
+	for {
+		select {
+		case record := <-e.queue:
+			batch = append(batch, record)
+			if len(batch) >= e.config.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-e.flushes:
+			for drained := false; !drained; {
+				select {
+				case record := <-e.queue:
+					batch = append(batch, record)
+					if len(batch) >= e.config.BatchSize {
+						send()
+					}
+				default:
+					drained = true
+				}
+			}
+			send()
+			close(done)
+		}
+	}
+}
+
+// export posts a batch of records
+func (e *otlpExporter) export(records []otlpLogRecord) {
+	request := otlpExportRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource: e.resource,
+		ScopeLogs: []otlpScopeLogs{{
+			Scope:      otlpScope{Name: "github.com/harishb93/telemetry-pipeline/internal/logger"},
+			LogRecords: records,
+		}},
+	}}}
+	body, err := json.Marshal(request)
+	if err == nil {
+		err = e.post(body)
+	}
+	if err != nil {
+		e.dropped.Add(int64(len(records)))
+		if time.Since(e.lastReported) >= e.config.FlushInterval {
+			e.lastReported = time.Now()
+			fmt.Fprintf(e.errorOutput, "Warning: failed to export %d log records to %s (%d dropped so far): %v\n",
+				len(records), e.config.Endpoint, e.dropped.Load(), err)
+		}
+	}
+}
+
+func (e *otlpExporter) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// otlpHandler is a slog.Handler converting records for an otlpExporter
+type otlpHandler struct {
+	exporter *otlpExporter
+	level    slog.Leveler
+	attrs    []otlpKeyValue
+	groups   []string
+}
+
+func (h *otlpHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *otlpHandler) Handle(ctx context.Context, r slog.Record) error {
+	record := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(r.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       severityNumber(r.Level),
+		SeverityText:         r.Level.String(),
+		Body:                 otlpAnyValue{StringValue: strPtr(r.Message)},
+		Attributes:           append([]otlpKeyValue(nil), h.attrs...),
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if kv, ok := otlpAttr(h.groups, a); ok {
+			record.Attributes = append(record.Attributes, kv)
+		}
+		return true
+	})
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		traceID, spanID := span.TraceID(), span.SpanID()
+		record.TraceID = hex.EncodeToString(traceID[:])
+		record.SpanID = hex.EncodeToString(spanID[:])
+	}
+	h.exporter.enqueue(record)
+	return nil
+}
+
+func (h *otlpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]otlpKeyValue(nil), h.attrs...)
+	for _, a := range attrs {
+		if kv, ok := otlpAttr(h.groups, a); ok {
+			clone.attrs = append(clone.attrs, kv)
+		}
+	}
+	return &clone
+}
+
+func (h *otlpHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string(nil), h.groups...), name)
+	return &clone
+}
+
+// teeHandler passes records to several handlers
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// severityNumber maps slog levels to OpenTelemetry severity numbers
+func severityNumber(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 17
+	case level >= slog.LevelWarn:
+		return 13
+	case level >= slog.LevelInfo:
+		return 9
+	default:
+		return 5
+	}
+}
+
+// otlpAttr converts an attribute, prefixing its key with the open groups.
+// Empty attributes are skipped, as slog handlers do.
+func otlpAttr(groups []string, a slog.Attr) (otlpKeyValue, bool) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return otlpKeyValue{}, false
+	}
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + key
+	}
+	return otlpKeyValue{Key: key, Value: otlpValue(a.Value)}, true
+}
+
+// otlpValue converts a slog value to an OTLP AnyValue
+func otlpValue(v slog.Value) otlpAnyValue {
+	switch v.Kind() {
+	case slog.KindString:
+		return otlpAnyValue{StringValue: strPtr(v.String())}
+	case slog.KindInt64:
+		return otlpAnyValue{IntValue: strPtr(strconv.FormatInt(v.Int64(), 10))}
+	case slog.KindUint64:
+		return otlpAnyValue{IntValue: strPtr(strconv.FormatUint(v.Uint64(), 10))}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return otlpAnyValue{DoubleValue: &f}
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpAnyValue{BoolValue: &b}
+	case slog.KindTime:
+		return otlpAnyValue{StringValue: strPtr(v.Time().Format(time.RFC3339Nano))}
+	case slog.KindGroup:
+		list := &otlpKeyValueList{}
+		for _, a := range v.Group() {
+			if kv, ok := otlpAttr(nil, a); ok {
+				list.Values = append(list.Values, kv)
+			}
+		}
+		return otlpAnyValue{KvlistValue: list}
+	default:
+		return otlpAnyValue{StringValue: strPtr(v.String())}
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// The OTLP/HTTP JSON encoding of an ExportLogsServiceRequest. 64-bit
+// integers are encoded as decimal strings, and trace and span IDs in hex.
+type otlpExportRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string           `json:"stringValue,omitempty"`
+	IntValue    *string           `json:"intValue,omitempty"`
+	DoubleValue *float64          `json:"doubleValue,omitempty"`
+	BoolValue   *bool             `json:"boolValue,omitempty"`
+	KvlistValue *otlpKeyValueList `json:"kvlistValue,omitempty"`
+}
+
+type otlpKeyValueList struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+// envExporter is shared by every logger created from the environment with
+// LOG_FORMAT=otlp, so a process runs one export loop however many loggers
+// its packages create
+var (
+	envExporterOnce sync.Once
+	envExporter     *otlpExporter
+)
+
+func sharedEnvExporter() *otlpExporter {
+	envExporterOnce.Do(func() {
+		envExporter = newOTLPExporter(OTLPConfigFromEnv())
+	})
+	return envExporter
+}
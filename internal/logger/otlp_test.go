@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOTLPExport(t *testing.T) {
+	var mu sync.Mutex
+	var requests []otlpExportRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected export %s %s %v", r.Method, r.URL.Path, r.Header)
+		}
+		var request otlpExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+	log := New(Config{
+		Level:  INFO,
+		Format: "otlp",
+		Output: &output,
+		OTLP: OTLPConfig{
+			Endpoint:          server.URL + "/v1/logs",
+			Headers:           map[string]string{"Authorization": "Bearer token"},
+			ServiceName:       "mq-service",
+			ServiceVersion:    "1.2.3",
+			ServiceInstanceID: "mq-0",
+			Attributes:        map[string]string{"deployment.environment": "test"},
+		},
+	}).WithComponent("broker")
+
+	log.Debug("not exported")
+	log.Warn("Queue is full", "topic", "telemetry", "depth", 100)
+	log.Flush()
+
+	// Records are still written locally
+	if !strings.Contains(output.String(), "Queue is full") {
+		t.Errorf("Expected the record written to the output, got %q", output.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 || len(requests[0].ResourceLogs) != 1 {
+		t.Fatalf("Expected one export, got %+v", requests)
+	}
+	resourceLogs := requests[0].ResourceLogs[0]
+	resource := make(map[string]string)
+	for _, kv := range resourceLogs.Resource.Attributes {
+		resource[kv.Key] = *kv.Value.StringValue
+	}
+	if resource["service.name"] != "mq-service" || resource["service.version"] != "1.2.3" ||
+		resource["service.instance.id"] != "mq-0" || resource["deployment.environment"] != "test" {
+		t.Errorf("Unexpected resource attributes %v", resource)
+	}
+
+	records := resourceLogs.ScopeLogs[0].LogRecords
+	if len(records) != 1 {
+		t.Fatalf("Expected only the warning exported, got %+v", records)
+	}
+	record := records[0]
+	if *record.Body.StringValue != "Queue is full" || record.SeverityNumber != 13 || record.SeverityText != "WARN" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	attributes := make(map[string]otlpAnyValue)
+	for _, kv := range record.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	if v := attributes["component"]; v.StringValue == nil || *v.StringValue != "broker" {
+		t.Errorf("Expected the logger's component attribute, got %+v", record.Attributes)
+	}
+	if v := attributes["depth"]; v.IntValue == nil || *v.IntValue != "100" {
+		t.Errorf("Expected an integer attribute, got %+v", record.Attributes)
+	}
+}
+
+func TestOTLPConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://otel:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=secret")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.name=collector, service.version=2.0")
+
+	config := OTLPConfigFromEnv()
+	if config.Endpoint != "http://otel:4318/v1/logs" || config.Headers["x-api-key"] != "secret" ||
+		config.ServiceName != "collector" || config.ServiceVersion != "2.0" {
+		t.Errorf("Unexpected config %+v", config)
+	}
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// RedactedValue replaces the values of sensitive attributes
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactKeys are the key patterns whose values are redacted unless
+// Config.RedactKeys says otherwise
+var DefaultRedactKeys = []string{
+	"password", "passwd", "secret", "token", "authorization", "api_key", "apikey",
+	"dsn", "cookie", "credential", "private_key",
+}
+
+// redactKeysFromEnv returns the patterns in LOG_REDACT_KEYS, a comma-separated
+// list replacing DefaultRedactKeys, or nil for the defaults. "none" turns
+// redaction off.
+func redactKeysFromEnv() []string {
+	spec := strings.TrimSpace(os.Getenv("LOG_REDACT_KEYS"))
+	if spec == "" {
+		return nil
+	}
+	keys := []string{}
+	if strings.EqualFold(spec, "none") {
+		return keys
+	}
+	for _, key := range strings.Split(spec, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// redactHandler masks the values of attributes whose keys contain one of its
+// patterns, ignoring case, before the wrapped handler sees them. Attributes
+// added with With are masked once, when they are added.
+type redactHandler struct {
+	slog.Handler
+	patterns []string // lower case
+}
+
+// newRedactHandler wraps handler to redact keys matching patterns, or returns
+// it unchanged if there are none
+func newRedactHandler(handler slog.Handler, patterns []string) slog.Handler {
+	lower := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			lower = append(lower, pattern)
+		}
+	}
+	if len(lower) == 0 {
+		return handler
+	}
+	return &redactHandler{Handler: handler, patterns: lower}
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	sensitive := false
+	r.Attrs(func(a slog.Attr) bool {
+		sensitive = h.sensitive(a)
+		return !sensitive
+	})
+	if !sensitive {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redact(a)
+	}
+	return &redactHandler{Handler: h.Handler.WithAttrs(redacted), patterns: h.patterns}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{Handler: h.Handler.WithGroup(name), patterns: h.patterns}
+}
+
+// matches reports whether key contains one of the patterns
+func (h *redactHandler) matches(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range h.patterns {
+		if strings.Contains(key, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitive reports whether a, or an attribute in a group a holds, must be
+// redacted
+func (h *redactHandler) sensitive(a slog.Attr) bool {
+	if h.matches(a.Key) {
+		return true
+	}
+	if v := a.Value.Resolve(); v.Kind() == slog.KindGroup {
+		for _, member := range v.Group() {
+			if h.sensitive(member) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redact returns a with its value, or the values of sensitive members of a
+// group, replaced by RedactedValue
+func (h *redactHandler) redact(a slog.Attr) slog.Attr {
+	if h.matches(a.Key) {
+		return slog.String(a.Key, RedactedValue)
+	}
+	if v := a.Value.Resolve(); v.Kind() == slog.KindGroup {
+		members := v.Group()
+		redacted := make([]slog.Attr, len(members))
+		for i, member := range members {
+			redacted[i] = h.redact(member)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	}
+	return a
+}
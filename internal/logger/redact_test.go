@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Level: INFO, Format: "text", Output: &buf}).With("db_dsn", "postgres://u:p@db/telemetry")
+
+	log.Info("connected",
+		"Authorization", "Bearer abc",
+		"gpu_id", "gpu-0",
+		slog.Group("upstream", "access_token", "xyz", "url", "http://collector"))
+
+	out := buf.String()
+	for _, secret := range []string{"postgres://", "Bearer abc", "xyz"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Output %q leaks %q", out, secret)
+		}
+	}
+	for _, want := range []string{"db_dsn=[REDACTED]", "Authorization=[REDACTED]", "upstream.access_token=[REDACTED]", "gpu_id=gpu-0", "upstream.url=http://collector"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output %q missing %q", out, want)
+		}
+	}
+}
+
+func TestRedaction_Config(t *testing.T) {
+	var buf bytes.Buffer
+	New(Config{Level: INFO, Format: "json", Output: &buf, RedactKeys: []string{"tenant"}}).Info("m", "tenant", "acme", "token", "t")
+	if out := buf.String(); !strings.Contains(out, `"tenant":"[REDACTED]"`) || !strings.Contains(out, `"token":"t"`) {
+		t.Errorf("Expected only the configured keys redacted, got %q", out)
+	}
+
+	buf.Reset()
+	New(Config{Level: INFO, Format: "text", Output: &buf, RedactKeys: []string{}}).Info("m", "token", "t")
+	if !strings.Contains(buf.String(), "token=t") {
+		t.Errorf("Expected redaction off, got %q", buf.String())
+	}
+}
+
+func TestRedactKeysFromEnv(t *testing.T) {
+	t.Setenv("LOG_REDACT_KEYS", "")
+	if keys := redactKeysFromEnv(); keys != nil {
+		t.Errorf("Expected the defaults, got %v", keys)
+	}
+	t.Setenv("LOG_REDACT_KEYS", " session , pin ")
+	if keys := redactKeysFromEnv(); len(keys) != 2 || keys[0] != "session" || keys[1] != "pin" {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	t.Setenv("LOG_REDACT_KEYS", "none")
+	if keys := redactKeysFromEnv(); keys == nil || len(keys) != 0 {
+		t.Errorf("Expected redaction off, got %v", keys)
+	}
+}
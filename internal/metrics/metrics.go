@@ -0,0 +1,490 @@
+// Package metrics implements the subset of Prometheus instrumentation the
+// pipeline needs (counters, gauges, histograms) and renders it in the
+// Prometheus text exposition format, without external dependencies.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the Content-Type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are histogram buckets for request latencies in seconds
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// family is a named metric with HELP and TYPE metadata
+type family interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metric families and renders them for scraping
+type Registry struct {
+	mu       sync.Mutex
+	names    map[string]bool
+	families []family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register adds a family, panicking on duplicate names since that is always a
+// programming error
+func (r *Registry) register(name string, f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", name))
+	}
+	r.names[name] = true
+	r.families = append(r.families, f)
+}
+
+// WriteTo renders every family in registration order
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, f := range families {
+		f.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler returns an HTTP handler serving the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// vec tracks one child per distinct set of label values
+type vec[T any] struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+	newChild   func() *T
+
+	mu       sync.RWMutex
+	children map[string]*T
+	labels   map[string][]string
+}
+
+func newVec[T any](name, help, typ string, labelNames []string, newChild func() *T) *vec[T] {
+	return &vec[T]{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: labelNames,
+		newChild:   newChild,
+		children:   make(map[string]*T),
+		labels:     make(map[string][]string),
+	}
+}
+
+// with returns the child for labelValues, creating it on first use
+func (v *vec[T]) with(labelValues []string) *T {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.RLock()
+	child, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return child
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if child, ok := v.children[key]; ok {
+		return child
+	}
+	child = v.newChild()
+	v.children[key] = child
+	v.labels[key] = append([]string(nil), labelValues...)
+	return child
+}
+
+// delete removes the child for labelValues
+func (v *vec[T]) delete(labelValues []string) {
+	key := strings.Join(labelValues, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.children, key)
+	delete(v.labels, key)
+}
+
+// each calls fn for every child in a stable order
+func (v *vec[T]) each(fn func(labelValues []string, child *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]*T, len(keys))
+	labels := make([][]string, len(keys))
+	for i, key := range keys {
+		children[i] = v.children[key]
+		labels[i] = v.labels[key]
+	}
+	v.mu.RUnlock()
+
+	for i := range keys {
+		fn(labels[i], children[i])
+	}
+}
+
+func (v *vec[T]) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.typ)
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Inc increments the counter by 1
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add increments the counter by delta, which must not be negative
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.mu.Lock()
+	c.value += delta
+	c.mu.Unlock()
+}
+
+// Value returns the current count
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct{ v *vec[Counter] }
+
+// NewCounterVec registers a counter. By convention its name ends in _total.
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{v: newVec(name, help, "counter", labelNames, func() *Counter { return &Counter{} })}
+	r.register(name, c)
+	return c
+}
+
+// WithLabelValues returns the counter for the given label values
+func (c *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	return c.v.with(labelValues)
+}
+
+// DeleteLabelValues removes the series for the given label values
+func (c *CounterVec) DeleteLabelValues(labelValues ...string) {
+	c.v.delete(labelValues)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.v.writeHeader(w)
+	c.v.each(func(labelValues []string, child *Counter) {
+		writeSample(w, c.v.name, c.v.labelNames, labelValues, "", "", child.Value())
+	})
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Set sets the gauge to value
+func (g *Gauge) Set(value float64) {
+	g.mu.Lock()
+	g.value = value
+	g.mu.Unlock()
+}
+
+// Add adds delta, which may be negative
+func (g *Gauge) Add(delta float64) {
+	g.mu.Lock()
+	g.value += delta
+	g.mu.Unlock()
+}
+
+// Inc increments the gauge by 1
+func (g *Gauge) Inc() { g.Add(1) }
+
+// Dec decrements the gauge by 1
+func (g *Gauge) Dec() { g.Add(-1) }
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct{ v *vec[Gauge] }
+
+// NewGaugeVec registers a gauge
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{v: newVec(name, help, "gauge", labelNames, func() *Gauge { return &Gauge{} })}
+	r.register(name, g)
+	return g
+}
+
+// WithLabelValues returns the gauge for the given label values
+func (g *GaugeVec) WithLabelValues(labelValues ...string) *Gauge {
+	return g.v.with(labelValues)
+}
+
+// DeleteLabelValues removes the series for the given label values
+func (g *GaugeVec) DeleteLabelValues(labelValues ...string) {
+	g.v.delete(labelValues)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.v.writeHeader(w)
+	g.v.each(func(labelValues []string, child *Gauge) {
+		writeSample(w, g.v.name, g.v.labelNames, labelValues, "", "", child.Value())
+	})
+}
+
+// gaugeFunc is a gauge, or a counter, whose series are computed at scrape
+// time
+type gaugeFunc struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+	collect    func(emit func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc registers a gauge whose series are produced by collect on every
+// scrape, for values that already live elsewhere such as queue lengths
+func (r *Registry) NewGaugeFunc(name, help string, labelNames []string, collect func(emit func(value float64, labelValues ...string))) {
+	r.register(name, &gaugeFunc{name: name, help: help, typ: "gauge", labelNames: labelNames, collect: collect})
+}
+
+// NewCounterFunc registers a counter whose series are produced by collect on
+// every scrape, for totals already kept elsewhere. Values must only increase.
+func (r *Registry) NewCounterFunc(name, help string, labelNames []string, collect func(emit func(value float64, labelValues ...string))) {
+	r.register(name, &gaugeFunc{name: name, help: help, typ: "counter", labelNames: labelNames, collect: collect})
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", g.name, escapeHelp(g.help), g.name, g.typ)
+
+	type sample struct {
+		labelValues []string
+		value       float64
+	}
+	var samples []sample
+	g.collect(func(value float64, labelValues ...string) {
+		samples = append(samples, sample{labelValues: labelValues, value: value})
+	})
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].labelValues, "\xff") < strings.Join(samples[j].labelValues, "\xff")
+	})
+	for _, s := range samples {
+		writeSample(w, g.name, g.labelNames, s.labelValues, "", "", s.value)
+	}
+}
+
+// Histogram samples observations into cumulative buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// Observe records a single observation
+func (h *Histogram) Observe(value float64) {
+	idx := sort.SearchFloat64s(h.buckets, value)
+	h.mu.Lock()
+	if idx < len(h.counts) {
+		h.counts[idx]++
+	}
+	h.sum += value
+	h.count++
+	h.mu.Unlock()
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Sum returns the sum of all observations
+func (h *Histogram) Sum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
+
+// Quantile estimates the q-quantile (0 <= q <= 1) by linear interpolation
+// within buckets, like PromQL's histogram_quantile. Observations above the
+// highest bound are reported as that bound. It returns NaN when empty.
+func (h *Histogram) Quantile(q float64) float64 {
+	cumulative, _, count := h.snapshot()
+	if count == 0 || len(h.buckets) == 0 {
+		return math.NaN()
+	}
+
+	rank := q * float64(count)
+	for i, bound := range h.buckets {
+		if float64(cumulative[i]) < rank {
+			continue
+		}
+		lower, below := 0.0, uint64(0)
+		if i > 0 {
+			lower, below = h.buckets[i-1], cumulative[i-1]
+		}
+		inBucket := cumulative[i] - below
+		if inBucket == 0 {
+			return bound
+		}
+		return lower + (bound-lower)*(rank-float64(below))/float64(inBucket)
+	}
+	return h.buckets[len(h.buckets)-1]
+}
+
+// snapshot returns cumulative bucket counts, sum and count
+func (h *Histogram) snapshot() ([]uint64, float64, uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cumulative := make([]uint64, len(h.counts))
+	var running uint64
+	for i, c := range h.counts {
+		running += c
+		cumulative[i] = running
+	}
+	return cumulative, h.sum, h.count
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	v       *vec[Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram with the given upper bucket bounds.
+// Bounds are sorted; the +Inf bucket is implicit.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+
+	h := &HistogramVec{buckets: bounds}
+	h.v = newVec(name, help, "histogram", labelNames, func() *Histogram {
+		return &Histogram{buckets: bounds, counts: make([]uint64, len(bounds))}
+	})
+	r.register(name, h)
+	return h
+}
+
+// WithLabelValues returns the histogram for the given label values
+func (h *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	return h.v.with(labelValues)
+}
+
+// DeleteLabelValues removes the series for the given label values
+func (h *HistogramVec) DeleteLabelValues(labelValues ...string) {
+	h.v.delete(labelValues)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.v.writeHeader(w)
+	h.v.each(func(labelValues []string, child *Histogram) {
+		cumulative, sum, count := child.snapshot()
+		for i, bound := range h.buckets {
+			writeSample(w, h.v.name+"_bucket", h.v.labelNames, labelValues, "le", formatFloat(bound), float64(cumulative[i]))
+		}
+		writeSample(w, h.v.name+"_bucket", h.v.labelNames, labelValues, "le", "+Inf", float64(count))
+		writeSample(w, h.v.name+"_sum", h.v.labelNames, labelValues, "", "", sum)
+		writeSample(w, h.v.name+"_count", h.v.labelNames, labelValues, "", "", float64(count))
+	})
+}
+
+// writeSample writes one exposition line; extraName/extraValue add a trailing
+// label such as a histogram's "le"
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", labelName, escapeLabelValue(labelValues[i]))
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatFloat formats a sample value the way Prometheus expects
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelEscaper.Replace(s) }
+
+// countingWriter counts bytes written for WriteTo
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	return sb.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("requests_total", "Total requests.", "method", "code")
+
+	c.WithLabelValues("GET", "200").Inc()
+	c.WithLabelValues("GET", "200").Add(2)
+	c.WithLabelValues("POST", "500").Inc()
+
+	expected := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 3
+requests_total{method="POST",code="500"} 1
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestGaugeVecAndEscaping(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGaugeVec("queue_depth", "Depth with \\ and\nnewline.", "topic")
+
+	g.WithLabelValues(`a"b\c`).Set(5)
+	g.WithLabelValues("plain").Inc()
+	g.WithLabelValues("plain").Dec()
+	g.WithLabelValues("removed").Set(1)
+	g.DeleteLabelValues("removed")
+
+	out := render(t, r)
+	for _, want := range []string{
+		`# HELP queue_depth Depth with \\ and\nnewline.`,
+		`queue_depth{topic="a\"b\\c"} 5`,
+		`queue_depth{topic="plain"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "removed") {
+		t.Errorf("Deleted series still exported:\n%s", out)
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("temperature", "Temperature.", []string{"gpu"}, func(emit func(float64, ...string)) {
+		emit(71.5, "gpu-1")
+		emit(65, "gpu-0")
+	})
+
+	out := render(t, r)
+	if !strings.Contains(out, "# TYPE temperature gauge\ntemperature{gpu=\"gpu-0\"} 65\ntemperature{gpu=\"gpu-1\"} 71.5\n") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+}
+
+func TestCounterFunc(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterFunc("delivered_total", "Delivered.", []string{"topic"}, func(emit func(float64, ...string)) {
+		emit(3, "events")
+	})
+
+	out := render(t, r)
+	if !strings.Contains(out, "# TYPE delivered_total counter\ndelivered_total{topic=\"events\"} 3\n") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{1, 0.1}, "op")
+
+	for _, v := range []float64{0.05, 0.1, 0.5, 2} {
+		h.WithLabelValues("read").Observe(v)
+	}
+
+	expected := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="read",le="0.1"} 2
+latency_seconds_bucket{op="read",le="1"} 3
+latency_seconds_bucket{op="read",le="+Inf"} 4
+latency_seconds_sum{op="read"} 2.65
+latency_seconds_count{op="read"} 4
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("q_seconds", "Q.", []float64{1, 2, 4}).WithLabelValues()
+
+	if !math.IsNaN(h.Quantile(0.5)) {
+		t.Error("Expected NaN for an empty histogram")
+	}
+
+	// 4 observations in (0,1], 4 in (1,2], 2 above the last bound
+	for _, v := range []float64{0.1, 0.2, 0.3, 0.4, 1.5, 1.5, 1.5, 1.5, 10, 10} {
+		h.Observe(v)
+	}
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.2, 0.5}, // rank 2 of 4 in the first bucket
+		{0.6, 1.5}, // rank 6: 2 of 4 into the second bucket
+		{0.8, 2},   // rank 8: top of the second bucket
+		{0.99, 4},  // beyond the last finite bound
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	if h.Count() != 10 || math.Abs(h.Sum()-27) > 1e-9 {
+		t.Errorf("Count/Sum = %d/%v, want 10/27", h.Count(), h.Sum())
+	}
+}
+
+func TestUnlabelledMetric(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("events_total", "Events.").WithLabelValues().Inc()
+
+	if out := render(t, r); !strings.Contains(out, "\nevents_total 1\n") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("dup_total", "Dup.", "a")
+
+	assertPanics(t, "duplicate name", func() { r.NewGaugeVec("dup_total", "Dup.") })
+	assertPanics(t, "label count", func() { c.WithLabelValues("x", "y") })
+	assertPanics(t, "negative counter", func() { c.WithLabelValues("x").Add(-1) })
+}
+
+func assertPanics(t *testing.T, name string, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: expected panic", name)
+		}
+	}()
+	fn()
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("hits_total", "Hits.").WithLabelValues().Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Expected content type %q, got %q", ContentType, ct)
+	}
+	if !strings.Contains(rec.Body.String(), "hits_total 1") {
+		t.Errorf("Unexpected body:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,107 @@
+package mq
+
+import (
+	"sync"
+	"time"
+)
+
+// ackReceipt identifies a message published to a local Broker
+type ackReceipt struct {
+	broker    *Broker
+	topic     string
+	msgID     string
+	published time.Time
+}
+
+// AckBatcher collects acknowledgments and applies them together once Size
+// messages are waiting or Interval has passed since the first of them, so
+// busy consumers don't take the broker lock once per message. Messages from a
+// local Broker are acknowledged under a single lock per batch; others fall
+// back to their own Ack. A Size of 1 or less acknowledges every message
+// immediately.
+type AckBatcher struct {
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []Message
+	timer   *time.Timer
+}
+
+// NewAckBatcher creates a batcher flushing every size messages or interval
+func NewAckBatcher(size int, interval time.Duration) *AckBatcher {
+	return &AckBatcher{size: size, interval: interval}
+}
+
+// Ack queues a processed message for acknowledgment
+func (a *AckBatcher) Ack(msg Message) {
+	if a.size <= 1 {
+		ackAll([]Message{msg})
+		return
+	}
+
+	a.mu.Lock()
+	a.pending = append(a.pending, msg)
+	if len(a.pending) >= a.size {
+		batch := a.take()
+		a.mu.Unlock()
+		ackAll(batch)
+		return
+	}
+	if a.timer == nil && a.interval > 0 {
+		a.timer = time.AfterFunc(a.interval, a.Flush)
+	}
+	a.mu.Unlock()
+}
+
+// Flush acknowledges every queued message. Call it before shutting down, or
+// the broker will redeliver them.
+func (a *AckBatcher) Flush() {
+	a.mu.Lock()
+	batch := a.take()
+	a.mu.Unlock()
+	ackAll(batch)
+}
+
+// Pending returns the number of messages waiting to be acknowledged
+func (a *AckBatcher) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.pending)
+}
+
+// take removes the queued messages. Caller must hold a.mu.
+func (a *AckBatcher) take() []Message {
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+	batch := a.pending
+	a.pending = nil
+	return batch
+}
+
+// ackAll acknowledges messages, grouping those from the same local broker
+func ackAll(msgs []Message) {
+	var byBroker map[*Broker][]*ackReceipt
+	for _, msg := range msgs {
+		if msg.receipt == nil {
+			if msg.Ack != nil {
+				msg.Ack()
+			}
+			continue
+		}
+		if byBroker == nil {
+			byBroker = make(map[*Broker][]*ackReceipt)
+		}
+		byBroker[msg.receipt.broker] = append(byBroker[msg.receipt.broker], msg.receipt)
+	}
+
+	for b, receipts := range byBroker {
+		b.mu.Lock()
+		for _, receipt := range receipts {
+			b.ack(receipt)
+		}
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,80 @@
+package mq
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func pendingMessages(b *Broker, topic string) int {
+	return b.GetStats().Topics[topic].PendingMessages
+}
+
+func TestAckBatcher(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+
+	topic := "ack-batch"
+	ch, unsubscribe, err := broker.SubscribeWithAck(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	for i := 0; i < 5; i++ {
+		if err := broker.Publish(topic, Message{Payload: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	acks := NewAckBatcher(3, time.Hour)
+	for i := 0; i < 5; i++ {
+		acks.Ack(<-ch)
+		if i == 1 && pendingMessages(broker, topic) != 5 {
+			t.Fatal("Expected no acks applied before the batch is full")
+		}
+	}
+	if got := pendingMessages(broker, topic); got != 2 {
+		t.Errorf("Expected the first batch of 3 acknowledged, %d pending", got)
+	}
+	if acks.Pending() != 2 {
+		t.Errorf("Expected 2 queued acks, got %d", acks.Pending())
+	}
+
+	acks.Flush()
+	if got := pendingMessages(broker, topic); got != 0 {
+		t.Errorf("Expected all messages acknowledged after flush, %d pending", got)
+	}
+}
+
+func TestAckBatcherInterval(t *testing.T) {
+	var acked atomic.Int32
+	msg := Message{Payload: []byte("x"), Ack: func() { acked.Add(1) }}
+
+	acks := NewAckBatcher(100, 20*time.Millisecond)
+	acks.Ack(msg)
+	acks.Ack(msg)
+	if acked.Load() != 0 {
+		t.Fatal("Expected acks to wait for the interval")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for acked.Load() != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if acked.Load() != 2 {
+		t.Errorf("Expected both messages acknowledged after the interval, got %d", acked.Load())
+	}
+}
+
+func TestAckBatcherPerMessage(t *testing.T) {
+	var acked atomic.Int32
+	acks := NewAckBatcher(1, time.Hour)
+	acks.Ack(Message{Ack: func() { acked.Add(1) }})
+	if acked.Load() != 1 || acks.Pending() != 0 {
+		t.Errorf("Expected an immediate ack with a batch size of 1")
+	}
+	// Messages without an ack function are ignored
+	acks.Ack(Message{})
+}
@@ -0,0 +1,373 @@
+package mq
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/yaml.v2"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+// Permission is what a client may do with a topic
+type Permission string
+
+const (
+	// PermissionPublish allows publishing to a topic
+	PermissionPublish Permission = "publish"
+	// PermissionSubscribe allows subscribing to a topic and acknowledging
+	// its messages
+	PermissionSubscribe Permission = "subscribe"
+)
+
+// grpcPermissions are the permissions the gRPC methods need on the topic of
+// their request. Reading, acknowledging and purging a topic's dead letters
+// are a subscriber's business; re-driving them publishes to the topic again.
+// Other methods are not subject to the ACL.
+var grpcPermissions = map[string]Permission{
+	pb.MQService_Publish_FullMethodName:            PermissionPublish,
+	pb.MQService_PublishBatch_FullMethodName:       PermissionPublish,
+	pb.MQService_Subscribe_FullMethodName:          PermissionSubscribe,
+	pb.MQService_Ack_FullMethodName:                PermissionSubscribe,
+	pb.MQService_ListDeadLetters_FullMethodName:    PermissionSubscribe,
+	pb.MQService_RedriveDeadLetters_FullMethodName: PermissionPublish,
+	pb.MQService_PurgeDeadLetters_FullMethodName:   PermissionSubscribe,
+}
+
+// httpPublishPaths are the HTTP path prefixes publishing to the topic that
+// follows them
+var httpPublishPaths = []string{"/publish/", "/publish-batch/"}
+
+// httpTopicAdminPath is the HTTP path prefix of the per-topic routes,
+// followed by the topic and the operation
+const httpTopicAdminPath = "/admin/topics/"
+
+// httpPermission returns the permission an HTTP request needs and the topic
+// it needs it on, or false for requests not subject to the ACL. Pending
+// messages and dead letters need the permissions of their gRPC
+// counterparts; the other per-topic routes need the admin token instead.
+func httpPermission(urlPath string) (Permission, string, bool) {
+	for _, prefix := range httpPublishPaths {
+		if topic, ok := strings.CutPrefix(urlPath, prefix); ok {
+			return PermissionPublish, topic, true
+		}
+	}
+	rest, ok := strings.CutPrefix(urlPath, httpTopicAdminPath)
+	if !ok {
+		return "", "", false
+	}
+	segments := strings.Split(rest, "/")
+	if len(segments) < 2 {
+		return "", "", false
+	}
+	switch topic := segments[0]; segments[1] {
+	case "pending":
+		return PermissionSubscribe, topic, true
+	case "dlq":
+		if segments[len(segments)-1] == "redrive" {
+			return PermissionPublish, topic, true
+		}
+		return PermissionSubscribe, topic, true
+	}
+	return "", "", false
+}
+
+// ACLClient is a client of an ACL file: the bearer token it presents and
+// the topics it may publish and subscribe to. Topics are patterns matched
+// with path.Match, so "telemetry.*" covers every telemetry.<host> topic.
+type ACLClient struct {
+	Name      string   `yaml:"name"`
+	Token     string   `yaml:"token"`
+	Publish   []string `yaml:"publish"`
+	Subscribe []string `yaml:"subscribe"`
+}
+
+// aclFile is the YAML layout of an ACL file
+type aclFile struct {
+	Clients []ACLClient `yaml:"clients"`
+}
+
+// allows reports whether the client has permission on topic
+func (c ACLClient) allows(permission Permission, topic string) bool {
+	patterns := c.Publish
+	if permission == PermissionSubscribe {
+		patterns = c.Subscribe
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, topic); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ACL authenticates clients by the bearer token they present and authorizes
+// them per topic, from a YAML file that Reload reads again:
+//
+//	clients:
+//	  - name: streamer
+//	    token: <secret>
+//	    publish: ["telemetry", "telemetry.*"]
+//	  - name: collector
+//	    token: <secret>
+//	    subscribe: ["telemetry"]
+type ACL struct {
+	path string
+
+	mu      sync.RWMutex
+	clients []ACLClient
+	denied  *metrics.CounterVec
+}
+
+// LoadACL reads the ACL file at path
+func LoadACL(path string) (*ACL, error) {
+	clients, err := readACL(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ACL{path: path, clients: clients}, nil
+}
+
+// readACL reads and checks an ACL file
+func readACL(aclPath string) ([]ACLClient, error) {
+	data, err := os.ReadFile(aclPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACL file: %w", err)
+	}
+	var file aclFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse ACL file %s: %w", aclPath, err)
+	}
+
+	tokens := make(map[string]string, len(file.Clients))
+	for i, client := range file.Clients {
+		if client.Name == "" {
+			return nil, fmt.Errorf("client %d in %s has no name", i+1, aclPath)
+		}
+		if client.Token == "" {
+			return nil, fmt.Errorf("client %q in %s has no token", client.Name, aclPath)
+		}
+		if other, exists := tokens[client.Token]; exists {
+			return nil, fmt.Errorf("clients %q and %q in %s share a token", other, client.Name, aclPath)
+		}
+		tokens[client.Token] = client.Name
+		for _, pattern := range append(append([]string(nil), client.Publish...), client.Subscribe...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("client %q in %s: topic pattern %q: %w", client.Name, aclPath, pattern, err)
+			}
+		}
+	}
+	return file.Clients, nil
+}
+
+// Reload reads the ACL file again. A file that fails to load leaves the
+// current clients in place.
+func (a *ACL) Reload() error {
+	clients, err := readACL(a.path)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.clients = clients
+	a.mu.Unlock()
+	return nil
+}
+
+// Clients returns the number of clients the ACL knows
+func (a *ACL) Clients() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.clients)
+}
+
+// RegisterMetrics adds mq_auth_denied_total to registry
+func (a *ACL) RegisterMetrics(registry *metrics.Registry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.denied = registry.NewCounterVec("mq_auth_denied_total",
+		"Requests refused by the ACL, by permission and reason.", "permission", "reason")
+}
+
+// Authorize checks that token belongs to a client with permission on topic,
+// returning the client's name. An unknown token fails with ErrUnauthorized, a
+// client without the permission with ErrForbidden.
+func (a *ACL) Authorize(token string, permission Permission, topic string) (string, error) {
+	client, err := a.authenticate(token, permission)
+	if err != nil {
+		return "", err
+	}
+	if !client.allows(permission, topic) {
+		a.deny(permission, "forbidden")
+		return "", fmt.Errorf("%w: client %s may not %s topic %s", ErrForbidden, client.Name, permission, topic)
+	}
+	return client.Name, nil
+}
+
+// authenticate returns the client token belongs to, failing with
+// ErrUnauthorized for a missing or unknown token
+func (a *ACL) authenticate(token string, permission Permission) (ACLClient, error) {
+	a.mu.RLock()
+	// Every token is compared, so timing does not reveal which matched
+	match := -1
+	for i := range a.clients {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.clients[i].Token)) == 1 {
+			match = i
+		}
+	}
+	var client ACLClient
+	if match >= 0 {
+		client = a.clients[match]
+	}
+	a.mu.RUnlock()
+
+	if token == "" || match < 0 {
+		a.deny(permission, "unauthenticated")
+		return ACLClient{}, fmt.Errorf("%w: missing or unknown token", ErrUnauthorized)
+	}
+	return client, nil
+}
+
+// deny counts a refused request
+func (a *ACL) deny(permission Permission, reason string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.denied != nil {
+		a.denied.WithLabelValues(string(permission), reason).Inc()
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" value, or ""
+func bearerToken(authorization string) string {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
+
+// topicRequest is a gRPC request naming a topic
+type topicRequest interface {
+	GetTopic() string
+}
+
+// authorizeGRPC checks the token in the metadata of ctx for permission on
+// the topic of req
+func (a *ACL) authorizeGRPC(ctx context.Context, method string, permission Permission, req interface{}) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	topic := ""
+	if r, ok := req.(topicRequest); ok {
+		topic = r.GetTopic()
+	}
+	client, err := a.Authorize(bearerToken(firstValue(md, "authorization")), permission, topic)
+	if err != nil {
+		logger.FromContext(ctx).Debug("Refused by ACL", "method", method, "topic", topic, "error", err)
+		return GRPCStatus(err)
+	}
+	logger.FromContext(ctx).Debug("Authorized by ACL", "method", method, "topic", topic, "client", client)
+	return nil
+}
+
+// UnaryServerInterceptor authorizes publishes and acknowledgments
+func (a *ACL) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if permission, ok := grpcPermissions[info.FullMethod]; ok {
+			if err := a.authorizeGRPC(ctx, info.FullMethod, permission, req); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authorizes subscriptions when their request,
+// which names the topic, is received
+func (a *ACL) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		permission, ok := grpcPermissions[info.FullMethod]
+		if !ok {
+			return handler(srv, ss)
+		}
+		// Refuse a stream without a known token before reading anything
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		if _, err := a.authenticate(bearerToken(firstValue(md, "authorization")), permission); err != nil {
+			return GRPCStatus(err)
+		}
+		return handler(srv, &authorizedServerStream{ServerStream: ss, acl: a, method: info.FullMethod, permission: permission})
+	}
+}
+
+// authorizedServerStream authorizes each request received on a stream
+type authorizedServerStream struct {
+	grpc.ServerStream
+	acl        *ACL
+	method     string
+	permission Permission
+}
+
+func (s *authorizedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.acl.authorizeGRPC(s.Context(), s.method, s.permission, m)
+}
+
+// HTTPMiddleware authorizes HTTP publishes and the pending message and dead
+// letter routes, refusing unknown tokens with 401 and clients without the
+// permission with 403
+func (a *ACL) HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		permission, topic, ok := httpPermission(r.URL.Path)
+		if !ok || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, err := a.Authorize(bearerToken(r.Header.Get("Authorization")), permission, topic); err != nil {
+			if errors.Is(err, ErrUnauthorized) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			http.Error(w, err.Error(), HTTPStatus(err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenCredentials send a client's bearer token, once set, with every gRPC
+// call
+type tokenCredentials struct {
+	mu    sync.RWMutex
+	token string
+}
+
+func (c *tokenCredentials) set(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+func (c *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.token == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+// RequireTransportSecurity allows tokens over the plaintext connections the
+// clients use; deployments sending them across untrusted networks should
+// terminate TLS in front of the service
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,181 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+const testACL = `clients:
+  - name: streamer
+    token: streamer-secret
+    publish: ["telemetry", "telemetry.*"]
+  - name: collector
+    token: collector-secret
+    subscribe: ["telemetry"]
+`
+
+// writeACL writes an ACL file to a temporary directory and returns its path
+func writeACL(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "acl.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestACL_Authorize(t *testing.T) {
+	acl, err := LoadACL(writeACL(t, testACL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := metrics.NewRegistry()
+	acl.RegisterMetrics(registry)
+	if n := acl.Clients(); n != 2 {
+		t.Errorf("Expected 2 clients, got %d", n)
+	}
+
+	for _, tc := range []struct {
+		token      string
+		permission Permission
+		topic      string
+		want       error
+	}{
+		{"streamer-secret", PermissionPublish, "telemetry", nil},
+		{"streamer-secret", PermissionPublish, "telemetry.host-1", nil},
+		{"streamer-secret", PermissionPublish, "events", ErrForbidden},
+		{"streamer-secret", PermissionSubscribe, "telemetry", ErrForbidden},
+		{"collector-secret", PermissionSubscribe, "telemetry", nil},
+		{"collector-secret", PermissionPublish, "telemetry", ErrForbidden},
+		{"", PermissionPublish, "telemetry", ErrUnauthorized},
+		{"unknown", PermissionSubscribe, "telemetry", ErrUnauthorized},
+	} {
+		_, err := acl.Authorize(tc.token, tc.permission, tc.topic)
+		if !errors.Is(err, tc.want) || (tc.want == nil && err != nil) {
+			t.Errorf("%s %s %s: expected %v, got %v", tc.token, tc.permission, tc.topic, tc.want, err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`mq_auth_denied_total{permission="publish",reason="forbidden"} 2`,
+		`mq_auth_denied_total{permission="subscribe",reason="unauthenticated"} 1`,
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("Expected %q in metrics:\n%s", want, rr.Body.String())
+		}
+	}
+}
+
+func TestACL_Reload(t *testing.T) {
+	path := writeACL(t, testACL)
+	acl, err := LoadACL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := strings.Replace(testACL, "streamer-secret", "rotated-secret", 1)
+	if err := os.WriteFile(path, []byte(rotated), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := acl.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acl.Authorize("streamer-secret", PermissionPublish, "telemetry"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected the old token refused after a reload, got %v", err)
+	}
+	if _, err := acl.Authorize("rotated-secret", PermissionPublish, "telemetry"); err != nil {
+		t.Errorf("Expected the new token accepted after a reload, got %v", err)
+	}
+
+	// A broken file keeps the clients loaded before it
+	if err := os.WriteFile(path, []byte("clients: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := acl.Reload(); err == nil {
+		t.Error("Expected a broken file to fail to reload")
+	}
+	if _, err := acl.Authorize("rotated-secret", PermissionPublish, "telemetry"); err != nil {
+		t.Errorf("Expected the previous clients kept, got %v", err)
+	}
+}
+
+func TestLoadACL_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"missing name":   "clients:\n  - token: a\n",
+		"missing token":  "clients:\n  - name: a\n",
+		"shared token":   "clients:\n  - name: a\n    token: t\n  - name: b\n    token: t\n",
+		"bad pattern":    "clients:\n  - name: a\n    token: t\n    publish: [\"telemetry[\"]\n",
+		"unknown field":  "clients:\n  - name: a\n    token: t\n    admin: true\n",
+		"not a document": "clients: [\n",
+	} {
+		if _, err := LoadACL(writeACL(t, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := LoadACL(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestACL_HTTPMiddleware(t *testing.T) {
+	acl, err := LoadACL(writeACL(t, testACL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := acl.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	for _, tc := range []struct {
+		method, path, token string
+		want                int
+	}{
+		{"POST", "/publish/telemetry", "streamer-secret", http.StatusOK},
+		{"POST", "/publish-batch/telemetry.host-1", "streamer-secret", http.StatusOK},
+		{"POST", "/publish/events", "streamer-secret", http.StatusForbidden},
+		{"POST", "/publish/telemetry", "collector-secret", http.StatusForbidden},
+		{"POST", "/publish/telemetry", "", http.StatusUnauthorized},
+		{"OPTIONS", "/publish/telemetry", "", http.StatusOK},
+		{"GET", "/admin/topics/telemetry/pending", "", http.StatusUnauthorized},
+		{"GET", "/admin/topics/telemetry/pending", "streamer-secret", http.StatusForbidden},
+		{"POST", "/admin/topics/telemetry/pending/m1/redeliver", "collector-secret", http.StatusOK},
+		{"GET", "/admin/topics/telemetry/dlq", "streamer-secret", http.StatusForbidden},
+		{"DELETE", "/admin/topics/telemetry/dlq/m1", "collector-secret", http.StatusOK},
+		{"POST", "/admin/topics/telemetry/dlq/redrive", "collector-secret", http.StatusForbidden},
+		{"POST", "/admin/topics/telemetry/dlq/m1/redrive", "streamer-secret", http.StatusOK},
+		{"PUT", "/admin/topics/telemetry/config", "", http.StatusOK},
+		{"GET", "/health", "", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s %s with %q: expected %d, got %d", tc.method, tc.path, tc.token, tc.want, rr.Code)
+		}
+		if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s %s: expected a Bearer challenge", tc.method, tc.path)
+		}
+	}
+}
+
+func TestTokenCredentials(t *testing.T) {
+	var credentials tokenCredentials
+	if md, _ := credentials.GetRequestMetadata(context.Background()); len(md) != 0 {
+		t.Errorf("Expected no metadata without a token, got %v", md)
+	}
+	credentials.set("secret")
+	if md, _ := credentials.GetRequestMetadata(context.Background()); md["authorization"] != "Bearer secret" {
+		t.Errorf("Expected a bearer token, got %v", md)
+	}
+}
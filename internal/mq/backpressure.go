@@ -0,0 +1,144 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// OverflowPolicy is what a publish does when its topic's queue holds
+// BrokerConfig.MaxQueueSize messages, or its TopicConfig's
+type OverflowPolicy string
+
+const (
+	// OverflowReject refuses the publish with ErrQueueFull
+	OverflowReject OverflowPolicy = "reject"
+	// OverflowBlock makes the publisher wait until a message leaves the
+	// queue, its context is done or the broker closes
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest drops the topic's oldest queued message to make
+	// room for the new one
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest discards the new message, reporting success to the
+	// publisher
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// DefaultOverflowPolicy is used when BrokerConfig.OverflowPolicy is unset
+const DefaultOverflowPolicy = OverflowReject
+
+// ParseOverflowPolicy parses an overflow policy name, "" meaning
+// DefaultOverflowPolicy
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return DefaultOverflowPolicy, nil
+	case OverflowReject, OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q (want %s, %s, %s or %s)",
+			name, OverflowReject, OverflowBlock, OverflowDropOldest, OverflowDropNewest)
+	}
+}
+
+// overflowPolicy returns a topic's policy, the broker's if the topic has
+// none, or the default if neither is set. Caller must hold b.mu.
+func (b *Broker) overflowPolicy(topicData *TopicData) OverflowPolicy {
+	if topicData.config.OverflowPolicy != "" {
+		return topicData.config.OverflowPolicy
+	}
+	if b.config.OverflowPolicy == "" {
+		return DefaultOverflowPolicy
+	}
+	return b.config.OverflowPolicy
+}
+
+// queueFull reports whether a topic's queue is at its limit. Dead-letter and
+// poison topics are not bounded, so messages given up on are never lost.
+// Caller must hold b.mu.
+func (b *Broker) queueFull(topic string, topicData *TopicData) bool {
+	limit := b.maxQueueSize(topicData)
+	return limit > 0 && len(topicData.messageQueue) >= limit &&
+		!IsDeadLetterTopic(topic) && !strings.HasSuffix(topic, PoisonTopicSuffix)
+}
+
+// admit applies the overflow policy to a message about to be published to a
+// full topic, and reports whether the message should be discarded. Blocking
+// publishers have waited in PublishCtx already; messages the broker moves
+// itself, such as re-driven dead letters, are not held up. Caller must hold
+// b.mu.
+func (b *Broker) admit(topic string, topicData *TopicData) (bool, error) {
+	if !b.queueFull(topic, topicData) {
+		return false, nil
+	}
+
+	policy := b.overflowPolicy(topicData)
+	switch policy {
+	case OverflowBlock:
+		return false, nil
+	case OverflowDropNewest:
+		topicData.counters.droppedNewest++
+		b.metrics.overflow.WithLabelValues(topic, string(policy)).Inc()
+		return true, nil
+	case OverflowDropOldest:
+		for b.queueFull(topic, topicData) {
+			if !b.dropOldest(topic, topicData) {
+				break
+			}
+			topicData.counters.droppedOldest++
+			b.metrics.overflow.WithLabelValues(topic, string(policy)).Inc()
+		}
+		return false, nil
+	default:
+		topicData.counters.rejected++
+		b.metrics.overflow.WithLabelValues(topic, string(policy)).Inc()
+		return false, fmt.Errorf("%w: %s holds %d messages", ErrQueueFull, topic, len(topicData.messageQueue))
+	}
+}
+
+// dropOldest removes the topic's earliest queued message copy, whether or
+// not it has been delivered, and reports whether there was one. Caller must
+// hold b.mu.
+func (b *Broker) dropOldest(topic string, topicData *TopicData) bool {
+	var oldest *PendingMessage
+	for _, pending := range topicData.messageQueue {
+		if oldest == nil || pending.seq < oldest.seq {
+			oldest = pending
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	return b.removePendingMessage(topic, oldest.Message.receipt.msgID)
+}
+
+// waitForRoom releases b.mu until a message leaves the topic's queue, ctx
+// is done or the broker closes, and takes it again before returning. Caller
+// must hold b.mu.
+func (b *Broker) waitForRoom(ctx context.Context, topicData *TopicData) error {
+	if topicData.room == nil {
+		topicData.room = make(chan struct{})
+	}
+	room := topicData.room
+
+	b.mu.Unlock()
+	defer b.mu.Lock()
+
+	select {
+	case <-room:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.stopChan:
+		return ErrClosed
+	}
+}
+
+// signalRoom wakes publishers blocked on a full topic. Caller must hold
+// b.mu.
+func (t *TopicData) signalRoom() {
+	if t.room != nil {
+		close(t.room)
+		t.room = nil
+	}
+}
@@ -0,0 +1,121 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBroker_OverflowPolicies(t *testing.T) {
+	publish := func(broker *Broker, ids ...string) error {
+		for _, id := range ids {
+			if err := broker.Publish("telemetry", Message{ID: id, Payload: []byte(`{}`)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	queued := func(broker *Broker) map[string]bool {
+		pending, _ := broker.PendingMessages("telemetry")
+		ids := make(map[string]bool)
+		for _, p := range pending {
+			ids[p.MessageID] = true
+		}
+		return ids
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		broker := NewBroker(BrokerConfig{AckTimeout: time.Minute, MaxQueueSize: 2})
+		defer broker.Close()
+		if err := publish(broker, "m1", "m2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := publish(broker, "m3"); !errors.Is(err, ErrQueueFull) {
+			t.Fatalf("Expected ErrQueueFull, got %v", err)
+		}
+		if stats, _ := broker.GetTopicStats("telemetry"); stats.RejectedMessages != 1 || stats.QueueSize != 2 {
+			t.Errorf("Expected one rejection and a full queue, got %+v", stats)
+		}
+	})
+
+	t.Run("drop-newest", func(t *testing.T) {
+		broker := NewBroker(BrokerConfig{AckTimeout: time.Minute, MaxQueueSize: 2, OverflowPolicy: OverflowDropNewest})
+		defer broker.Close()
+		if err := publish(broker, "m1", "m2", "m3"); err != nil {
+			t.Fatal(err)
+		}
+		if ids := queued(broker); len(ids) != 2 || !ids["m1"] || !ids["m2"] {
+			t.Errorf("Expected the newest message dropped, got %v", ids)
+		}
+		if stats, _ := broker.GetTopicStats("telemetry"); stats.DroppedNewestMessages != 1 {
+			t.Errorf("Expected one dropped message, got %+v", stats)
+		}
+	})
+
+	t.Run("drop-oldest", func(t *testing.T) {
+		broker := NewBroker(BrokerConfig{AckTimeout: time.Minute, MaxQueueSize: 2, OverflowPolicy: OverflowDropOldest})
+		defer broker.Close()
+		if err := publish(broker, "m1", "m2", "m3", "m4"); err != nil {
+			t.Fatal(err)
+		}
+		if ids := queued(broker); len(ids) != 2 || !ids["m3"] || !ids["m4"] {
+			t.Errorf("Expected the oldest messages dropped, got %v", ids)
+		}
+		if stats, _ := broker.GetTopicStats("telemetry"); stats.DroppedOldestMessages != 2 {
+			t.Errorf("Expected two dropped messages, got %+v", stats)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		broker := NewBroker(BrokerConfig{AckTimeout: time.Minute, MaxQueueSize: 1, OverflowPolicy: OverflowBlock})
+		defer broker.Close()
+		messages, unsubscribe, err := broker.SubscribeWithAck("telemetry")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer unsubscribe()
+		if err := publish(broker, "m1"); err != nil {
+			t.Fatal(err)
+		}
+
+		// A blocked publisher gives up with its context
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := broker.PublishCtx(ctx, "telemetry", Message{ID: "m2"}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected the publish to wait until its deadline, got %v", err)
+		}
+
+		// and resumes once a message is acknowledged
+		done := make(chan error, 1)
+		go func() { done <- publish(broker, "m2") }()
+		select {
+		case err := <-done:
+			t.Fatalf("Expected the publish to block, got %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		(<-messages).Ack()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the publish to resume after an acknowledgment")
+		}
+		if ids := queued(broker); len(ids) != 1 || !ids["m2"] {
+			t.Errorf("Expected the blocked message queued, got %v", ids)
+		}
+	})
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for name, want := range map[string]OverflowPolicy{"": OverflowReject, "block": OverflowBlock, "Drop-Oldest": OverflowDropOldest} {
+		if policy, err := ParseOverflowPolicy(name); err != nil || policy != want {
+			t.Errorf("ParseOverflowPolicy(%q) = %q, %v, want %q", name, policy, err, want)
+		}
+	}
+	if _, err := ParseOverflowPolicy("spill"); err == nil {
+		t.Error("Expected an unknown policy to be refused")
+	}
+}
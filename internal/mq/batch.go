@@ -0,0 +1,69 @@
+package mq
+
+import (
+	"context"
+	"time"
+)
+
+// PublishBatch publishes messages to a topic under a single acquisition of
+// the broker's lock, giving each message without an ID one in msgs. It
+// returns one result per message, nil if the message was published: a
+// message refused, such as by a full queue, does not stop the rest of the
+// batch. Under the block overflow policy the batch releases the lock while
+// it waits for room, and messages not yet published when ctx is done or the
+// broker closes are refused with that error. The returned error is set only
+// if the whole batch was refused.
+func (b *Broker) PublishBatch(ctx context.Context, topic string, msgs []Message) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ValidateTopic(topic); err != nil {
+		return nil, err
+	}
+	for i := range msgs {
+		if msgs[i].ID == "" {
+			msgs[i].ID = NewMessageID()
+		}
+	}
+
+	start := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	results := make([]error, len(msgs))
+	for i, msg := range msgs {
+		if err := b.waitToPublish(ctx, topic); err != nil {
+			for j := i; j < len(msgs); j++ {
+				results[j] = err
+			}
+			break
+		}
+		if results[i] = b.publishLocked(topic, msg); results[i] == nil {
+			b.metrics.publishDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+		}
+	}
+	return results, nil
+}
+
+// waitToPublish waits for room on a full topic under the block overflow
+// policy, returning at once under the others. Caller must hold b.mu.
+func (b *Broker) waitToPublish(ctx context.Context, topic string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	topicData := b.topicDataLocked(topic)
+	for b.overflowPolicy(topicData) == OverflowBlock && b.queueFull(topic, topicData) {
+		if err := b.waitForRoom(ctx, topicData); err != nil {
+			return err
+		}
+		if b.closed {
+			return ErrClosed
+		}
+		topicData = b.topicDataLocked(topic)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBroker_PublishBatch(t *testing.T) {
+	broker := NewBroker(BrokerConfig{AckTimeout: time.Minute, MaxQueueSize: 3})
+	defer broker.Close()
+
+	ch, unsubscribe, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	msgs := []Message{{ID: "m1", Payload: []byte(`1`)}, {Payload: []byte(`2`)}, {Payload: []byte(`3`)}, {Payload: []byte(`4`)}}
+	results, err := broker.PublishBatch(context.Background(), "telemetry", msgs)
+	if err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+	if len(results) != 4 || results[0] != nil || results[1] != nil || results[2] != nil {
+		t.Fatalf("Expected the first three messages published, got %v", results)
+	}
+	if !errors.Is(results[3], ErrQueueFull) {
+		t.Errorf("Expected the fourth message refused with ErrQueueFull, got %v", results[3])
+	}
+	if msgs[0].ID != "m1" || msgs[1].ID == "" || msgs[1].ID == msgs[2].ID {
+		t.Errorf("Expected IDs kept or assigned, got %q, %q, %q", msgs[0].ID, msgs[1].ID, msgs[2].ID)
+	}
+
+	// Delivered in order
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-ch:
+			if msg.ID != msgs[i].ID {
+				t.Errorf("Delivery %d: expected %s, got %s", i, msgs[i].ID, msg.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Delivery %d timed out", i)
+		}
+	}
+}
+
+func TestBroker_PublishBatchRefused(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+
+	if _, err := broker.PublishBatch(context.Background(), "bad topic!", []Message{{}}); err == nil {
+		t.Error("Expected an invalid topic to be refused")
+	}
+
+	broker.Close()
+	if _, err := broker.PublishBatch(context.Background(), "telemetry", []Message{{}}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestBroker_PublishBatchBlockCancelled(t *testing.T) {
+	broker := NewBroker(BrokerConfig{AckTimeout: time.Minute, MaxQueueSize: 1, OverflowPolicy: OverflowBlock})
+	defer broker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results, err := broker.PublishBatch(ctx, "telemetry", []Message{{Payload: []byte(`1`)}, {Payload: []byte(`2`)}, {Payload: []byte(`3`)}})
+	if err != nil {
+		t.Fatalf("PublishBatch failed: %v", err)
+	}
+	if results[0] != nil || !errors.Is(results[1], context.DeadlineExceeded) || !errors.Is(results[2], context.DeadlineExceeded) {
+		t.Errorf("Expected the messages after the first to time out waiting for room, got %v", results)
+	}
+}
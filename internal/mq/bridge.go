@@ -0,0 +1,276 @@
+package mq
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+// BridgeConfig configures a Bridge
+type BridgeConfig struct {
+	// Topics are forwarded from the source under the same names
+	Topics []string
+	// BufferSize is how many messages are held while the sink is slow or
+	// unavailable; once full the bridge stops reading from the source, which
+	// keeps queueing them
+	BufferSize int
+	// RetryInterval is the first delay before reconnecting to the source or
+	// republishing to the sink; it doubles up to MaxRetryInterval
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+}
+
+// DefaultBridgeConfig returns a bridge configuration forwarding telemetry
+func DefaultBridgeConfig() BridgeConfig {
+	return BridgeConfig{
+		Topics:           []string{"telemetry"},
+		BufferSize:       1000,
+		RetryInterval:    500 * time.Millisecond,
+		MaxRetryInterval: 30 * time.Second,
+	}
+}
+
+// BridgeStats reports a bridge's progress
+type BridgeStats struct {
+	Source     string `json:"source"`
+	Connected  int    `json:"connected_topics"`
+	Buffered   int    `json:"buffered_messages"`
+	Received   uint64 `json:"received_messages"`
+	Forwarded  uint64 `json:"forwarded_messages"`
+	Dropped    uint64 `json:"dropped_messages"`
+	Reconnects uint64 `json:"reconnects"`
+}
+
+// bridgedMessage is a message received from the source awaiting the sink
+type bridgedMessage struct {
+	topic   string
+	id      string // the ID given at the source, kept when republished
+	payload []byte
+	headers map[string]string
+	ack     func() // acknowledges the message at the source once republished
+}
+
+// Bridge subscribes to topics on a remote mq-service, typically at an edge
+// site, and republishes their messages to a sink, typically the central
+// broker. A lost source connection is resumed with backoff; the source
+// queues messages meanwhile. Messages the sink refuses are buffered and
+// retried in order.
+type Bridge struct {
+	source *GRPCBrokerClient
+	sink   BrokerInterface
+	config BridgeConfig
+	log    *logger.Logger
+
+	buffer chan bridgedMessage
+	ctx    context.Context
+	cancel context.CancelFunc
+	// receivers and forwarder are waited for separately so the buffer can
+	// be drained once nothing more is received
+	receivers sync.WaitGroup
+	forwarder sync.WaitGroup
+	stopOnce  sync.Once
+
+	connected  atomic.Int32
+	received   atomic.Uint64
+	forwarded  atomic.Uint64
+	dropped    atomic.Uint64
+	reconnects atomic.Uint64
+
+	forwardedTotal  *metrics.CounterVec
+	reconnectsTotal *metrics.CounterVec
+}
+
+// NewBridge creates a bridge from source to sink; call Start to run it
+func NewBridge(source *GRPCBrokerClient, sink BrokerInterface, config BridgeConfig, log *logger.Logger) *Bridge {
+	defaults := DefaultBridgeConfig()
+	if len(config.Topics) == 0 {
+		config.Topics = defaults.Topics
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaults.BufferSize
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaults.RetryInterval
+	}
+	if config.MaxRetryInterval < config.RetryInterval {
+		config.MaxRetryInterval = max(defaults.MaxRetryInterval, config.RetryInterval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bridge{
+		source: source,
+		sink:   sink,
+		config: config,
+		log:    log.With("source", source.serverAddr),
+		buffer: make(chan bridgedMessage, config.BufferSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// RegisterBridgeMetrics adds the bridges' metrics to registry:
+// mq_bridge_forwarded_messages_total, mq_bridge_reconnects_total and
+// mq_bridge_buffered_messages
+func RegisterBridgeMetrics(registry *metrics.Registry, bridges ...*Bridge) {
+	forwarded := registry.NewCounterVec("mq_bridge_forwarded_messages_total",
+		"Messages forwarded from a remote mq-service to the sink.", "source", "topic")
+	reconnects := registry.NewCounterVec("mq_bridge_reconnects_total",
+		"Subscriptions to a remote mq-service that were lost and resumed.", "source", "topic")
+	for _, b := range bridges {
+		b.forwardedTotal = forwarded
+		b.reconnectsTotal = reconnects
+	}
+
+	registry.NewGaugeFunc("mq_bridge_buffered_messages",
+		"Messages received from a remote mq-service awaiting the sink.", []string{"source"},
+		func(emit func(float64, ...string)) {
+			for _, b := range bridges {
+				emit(float64(len(b.buffer)), b.source.serverAddr)
+			}
+		})
+}
+
+// Start subscribes to every topic and starts forwarding
+func (b *Bridge) Start() {
+	b.log.Info("Starting bridge", "topics", b.config.Topics, "buffer_size", b.config.BufferSize)
+
+	b.forwarder.Add(1)
+	go b.forward()
+	for _, topic := range b.config.Topics {
+		b.receivers.Add(1)
+		go b.receive(topic)
+	}
+}
+
+// Stop stops receiving, then makes one more attempt to publish each buffered
+// message before returning. Messages the sink still refuses are dropped.
+func (b *Bridge) Stop() {
+	b.stopOnce.Do(func() {
+		b.cancel()
+		b.receivers.Wait()
+		close(b.buffer)
+		b.forwarder.Wait()
+		b.log.Info("Bridge stopped", "forwarded", b.forwarded.Load(), "dropped", b.dropped.Load())
+	})
+}
+
+// Stats returns the bridge's progress
+func (b *Bridge) Stats() BridgeStats {
+	return BridgeStats{
+		Source:     b.source.serverAddr,
+		Connected:  int(b.connected.Load()),
+		Buffered:   len(b.buffer),
+		Received:   b.received.Load(),
+		Forwarded:  b.forwarded.Load(),
+		Dropped:    b.dropped.Load(),
+		Reconnects: b.reconnects.Load(),
+	}
+}
+
+// receive keeps a subscription to topic open, resuming it with backoff
+// whenever it is lost, until the bridge stops
+func (b *Bridge) receive(topic string) {
+	defer b.receivers.Done()
+
+	delay := b.config.RetryInterval
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			b.reconnects.Add(1)
+			if b.reconnectsTotal != nil {
+				b.reconnectsTotal.WithLabelValues(b.source.serverAddr, topic).Inc()
+			}
+			if !b.sleep(delay) {
+				return
+			}
+			delay = min(delay*2, b.config.MaxRetryInterval)
+		}
+
+		received, err := b.stream(topic)
+		if b.ctx.Err() != nil {
+			return
+		}
+		if received > 0 {
+			// The connection worked; retry promptly
+			delay = b.config.RetryInterval
+		}
+		b.log.Warn("Bridge subscription lost, resuming", "topic", topic, "error", err, "retry_in", delay)
+	}
+}
+
+// stream forwards messages from one subscription to topic into the buffer
+// until it fails, returning how many it received
+func (b *Bridge) stream(topic string) (int, error) {
+	stream, err := b.source.subscribeStream(b.ctx, topic, "bridge")
+	if err != nil {
+		return 0, err
+	}
+
+	b.connected.Add(1)
+	defer b.connected.Add(-1)
+
+	received := 0
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received++
+		b.received.Add(1)
+
+		// Blocking here once the buffer is full stops reading the stream,
+		// which leaves further messages queued at the source
+		select {
+		case b.buffer <- bridgedMessage{topic: topic, id: msg.Id, payload: msg.Payload, headers: MessageHeaders(msg.Headers), ack: b.source.ackFunc(topic, "bridge", msg.Id)}:
+		case <-b.ctx.Done():
+			b.dropped.Add(1)
+			return received, b.ctx.Err()
+		}
+	}
+}
+
+// forward publishes buffered messages to the sink in order, retrying each
+// until it is accepted or the bridge stops
+func (b *Bridge) forward() {
+	defer b.forwarder.Done()
+
+	for msg := range b.buffer {
+		delay := b.config.RetryInterval
+		for {
+			err := b.sink.Publish(msg.topic, Message{ID: msg.id, Payload: msg.payload, Headers: msg.headers})
+			if err == nil {
+				b.forwarded.Add(1)
+				if b.forwardedTotal != nil {
+					b.forwardedTotal.WithLabelValues(b.source.serverAddr, msg.topic).Inc()
+				}
+				msg.ack()
+				break
+			}
+			if b.ctx.Err() != nil {
+				b.dropped.Add(1)
+				b.log.Error("Dropping bridged message on shutdown", "topic", msg.topic, "error", err)
+				break
+			}
+			b.log.Warn("Failed to republish bridged message, retrying", "topic", msg.topic, "error", err, "retry_in", delay)
+			if !b.sleep(delay) {
+				continue
+			}
+			delay = min(delay*2, b.config.MaxRetryInterval)
+		}
+	}
+}
+
+// sleep waits for d, returning false if the bridge stopped first
+func (b *Bridge) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-b.ctx.Done():
+		return false
+	}
+}
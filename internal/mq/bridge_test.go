@@ -0,0 +1,196 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+// brokerServer streams a local broker's messages to gRPC subscribers, like
+// mq-service
+type brokerServer struct {
+	pb.UnimplementedMQServiceServer
+	broker *Broker
+}
+
+func (s brokerServer) Subscribe(req *pb.SubscribeRequest, stream pb.MQService_SubscribeServer) error {
+	msgCh, unsubscribe, err := s.broker.SubscribeWithAck(req.Topic)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case msg, ok := <-msgCh:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&pb.Message{Topic: req.Topic, Payload: msg.Payload}); err != nil {
+				return err
+			}
+			msg.Ack()
+		}
+	}
+}
+
+func (s brokerServer) Publish(ctx context.Context, req *pb.PublishRequest) (*pb.PublishResponse, error) {
+	if err := s.broker.Publish(req.Topic, Message{Payload: req.Payload}); err != nil {
+		return nil, err
+	}
+	return &pb.PublishResponse{Success: true}, nil
+}
+
+func (s brokerServer) Health(ctx context.Context, req *pb.HealthRequest) (*pb.HealthResponse, error) {
+	return &pb.HealthResponse{Status: "healthy"}, nil
+}
+
+// serveBroker serves broker over gRPC on addr, or a free port if addr is
+// empty, returning the address and a function stopping the server
+func serveBroker(t *testing.T, broker *Broker, addr string) (string, func()) {
+	t.Helper()
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterMQServiceServer(server, brokerServer{broker: broker})
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String(), server.Stop
+}
+
+// collectPayloads subscribes to topic on broker and gathers payloads
+func collectPayloads(t *testing.T, broker *Broker, topic string) func(want int) []string {
+	t.Helper()
+	ch, unsubscribe, err := broker.SubscribeWithAck(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(unsubscribe)
+
+	return func(want int) []string {
+		var payloads []string
+		timeout := time.After(5 * time.Second)
+		for len(payloads) < want {
+			select {
+			case msg := <-ch:
+				payloads = append(payloads, string(msg.Payload))
+				msg.Ack()
+			case <-timeout:
+				t.Fatalf("Expected %d messages, got %v", want, payloads)
+			}
+		}
+		return payloads
+	}
+}
+
+func testBridgeConfig() BridgeConfig {
+	return BridgeConfig{
+		Topics:           []string{"telemetry"},
+		BufferSize:       10,
+		RetryInterval:    10 * time.Millisecond,
+		MaxRetryInterval: 50 * time.Millisecond,
+	}
+}
+
+func TestBridge_ForwardsAndResumes(t *testing.T) {
+	edge := NewBroker(DefaultBrokerConfig())
+	defer edge.Close()
+	central := NewBroker(DefaultBrokerConfig())
+	defer central.Close()
+	received := collectPayloads(t, central, "telemetry")
+
+	addr, stop := serveBroker(t, edge, "")
+	source, err := NewGRPCBrokerClient(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	bridge := NewBridge(source, central, testBridgeConfig(), logger.NewFromEnv())
+	bridge.Start()
+	defer bridge.Stop()
+
+	for i := 0; i < 3; i++ {
+		_ = edge.Publish("telemetry", Message{Payload: []byte(fmt.Sprintf("before-%d", i))})
+	}
+	if got := received(3); got[0] != "before-0" || got[2] != "before-2" {
+		t.Errorf("Expected messages in order, got %v", got)
+	}
+
+	// Messages published while the edge is unreachable are queued there and
+	// forwarded once the bridge resumes
+	stop()
+	for i := 0; i < 3; i++ {
+		_ = edge.Publish("telemetry", Message{Payload: []byte(fmt.Sprintf("during-%d", i))})
+	}
+	serveBroker(t, edge, addr)
+
+	if got := received(3); got[0] != "during-0" || got[2] != "during-2" {
+		t.Errorf("Expected queued messages after resuming, got %v", got)
+	}
+	if stats := bridge.Stats(); stats.Forwarded != 6 || stats.Reconnects == 0 {
+		t.Errorf("Expected 6 forwarded messages and a reconnect, got %+v", stats)
+	}
+}
+
+// flakySink refuses publishes until failures are used up
+type flakySink struct {
+	*Broker
+	mu       sync.Mutex
+	failures int
+}
+
+func (s *flakySink) Publish(topic string, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("central unavailable")
+	}
+	return s.Broker.Publish(topic, msg)
+}
+
+func TestBridge_BuffersWhileSinkUnavailable(t *testing.T) {
+	edge := NewBroker(DefaultBrokerConfig())
+	defer edge.Close()
+	central := NewBroker(DefaultBrokerConfig())
+	defer central.Close()
+	received := collectPayloads(t, central, "telemetry")
+
+	addr, _ := serveBroker(t, edge, "")
+	source, err := NewGRPCBrokerClient(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	bridge := NewBridge(source, &flakySink{Broker: central, failures: 4}, testBridgeConfig(), logger.NewFromEnv())
+	bridge.Start()
+	defer bridge.Stop()
+
+	for i := 0; i < 5; i++ {
+		_ = edge.Publish("telemetry", Message{Payload: []byte(fmt.Sprintf("msg-%d", i))})
+	}
+	got := received(5)
+	for i, payload := range got {
+		if want := fmt.Sprintf("msg-%d", i); payload != want {
+			t.Errorf("Message %d = %q, want %q", i, payload, want)
+		}
+	}
+}
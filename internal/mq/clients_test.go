@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -61,6 +62,22 @@ func TestHTTPBroker_Publish_Success(t *testing.T) {
 	}
 }
 
+func TestHTTPBroker_Publish_MessageHeaders(t *testing.T) {
+	received := make(chan map[string]string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- HTTPMessageHeaders(r.Header)
+	}))
+	defer server.Close()
+
+	headers := map[string]string{HeaderSchemaVersion: "2", "source": "host-1"}
+	if err := NewHTTPBroker(server.URL).Publish("test-topic", Message{Payload: []byte("{}"), Headers: headers}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; len(got) != 2 || got[HeaderSchemaVersion] != "2" || got["source"] != "host-1" {
+		t.Errorf("Expected the message headers sent, got %v", got)
+	}
+}
+
 func TestHTTPBroker_Publish_ServerError(t *testing.T) {
 	// Create a test server that returns an error
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -186,6 +203,50 @@ func TestHTTPBroker_ConcurrentPublish(t *testing.T) {
 	}
 }
 
+func TestHTTPBroker_ReusesConnections(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	config := DefaultHTTPClientConfig()
+	config.Timeout = 5 * time.Second
+	broker := NewHTTPBrokerWithConfig(server.URL, defaultProtocolGuard(), config)
+	if broker.client.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout 5s, got %v", broker.client.Timeout)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				if err := broker.Publish("topic", Message{Payload: []byte("{}")}); err != nil {
+					t.Errorf("Publish failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if conns > 4 {
+		t.Errorf("Expected at most one connection per concurrent publisher, got %d for 100 publishes", conns)
+	}
+}
+
 func TestDirectBrokerClient_NewDirectBrokerClient(t *testing.T) {
 	client := NewDirectBrokerClient("http://localhost:9090")
 
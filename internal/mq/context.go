@@ -0,0 +1,79 @@
+package mq
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextBroker is a BrokerInterface whose calls can be cancelled. A
+// cancelled or expired ctx aborts a publish in flight, returning ctx's
+// error, and ends a subscription as if it had been unsubscribed. Every broker
+// and client in this package implements it; the methods without a context
+// behave like their Ctx variants given context.Background().
+type ContextBroker interface {
+	BrokerInterface
+	PublishCtx(ctx context.Context, topic string, msg Message) error
+	SubscribeCtx(ctx context.Context, topic string) (chan []byte, func(), error)
+	SubscribeWithAckCtx(ctx context.Context, topic string) (chan Message, func(), error)
+}
+
+var (
+	_ ContextBroker = (*Broker)(nil)
+	_ ContextBroker = (*HTTPBroker)(nil)
+	_ ContextBroker = (*DirectBrokerClient)(nil)
+	_ ContextBroker = (*GRPCBrokerClient)(nil)
+	_ ContextBroker = (*FailoverBrokerClient)(nil)
+)
+
+// PublishContext publishes msg to topic on broker, honouring ctx if broker is
+// a ContextBroker. Other brokers are only checked for cancellation before
+// publishing.
+func PublishContext(ctx context.Context, broker BrokerInterface, topic string, msg Message) error {
+	if cb, ok := broker.(ContextBroker); ok {
+		return cb.PublishCtx(ctx, topic, msg)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return broker.Publish(topic, msg)
+}
+
+// SubscribeWithAckContext subscribes to topic on broker until ctx is done or
+// the returned function is called, whichever comes first
+func SubscribeWithAckContext(ctx context.Context, broker BrokerInterface, topic string) (chan Message, func(), error) {
+	if cb, ok := broker.(ContextBroker); ok {
+		return cb.SubscribeWithAckCtx(ctx, topic)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	ch, unsubscribe, err := broker.SubscribeWithAck(topic)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, unsubscribeOnDone(ctx, unsubscribe), nil
+}
+
+// unsubscribeOnDone calls unsubscribe once ctx is done. The returned
+// function unsubscribes straight away; unsubscribe runs at most once either
+// way.
+func unsubscribeOnDone(ctx context.Context, unsubscribe func()) func() {
+	var once sync.Once
+	done := func() { once.Do(unsubscribe) }
+	stop := context.AfterFunc(ctx, done)
+	return func() {
+		stop()
+		done()
+	}
+}
+
+// joinContext returns a context that is done when either ctx or closed is,
+// used to bound a caller's call by the lifetime of the client making it
+func joinContext(ctx, closed context.Context) (context.Context, context.CancelFunc) {
+	joined, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(closed, cancel)
+	return joined, func() {
+		stop()
+		cancel()
+	}
+}
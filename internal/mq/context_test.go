@@ -0,0 +1,153 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+)
+
+// plainBroker is a BrokerInterface without the Ctx methods
+type plainBroker struct{ broker *Broker }
+
+func (p plainBroker) Publish(topic string, msg Message) error { return p.broker.Publish(topic, msg) }
+func (p plainBroker) Subscribe(topic string) (chan []byte, func(), error) {
+	return p.broker.Subscribe(topic)
+}
+func (p plainBroker) SubscribeWithAck(topic string) (chan Message, func(), error) {
+	return p.broker.SubscribeWithAck(topic)
+}
+func (p plainBroker) Close() { p.broker.Close() }
+
+func expectClosed(t *testing.T, ch chan Message) {
+	t.Helper()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("Expected the subscription channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Subscription was not closed when its context was cancelled")
+	}
+}
+
+func TestBrokerContext(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.PersistenceEnabled = false
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := broker.PublishCtx(cancelled, "topic", Message{Payload: []byte("a")}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if size := broker.GetQueueSize("topic"); size != 0 {
+		t.Errorf("Cancelled publish should not queue a message, queue size %d", size)
+	}
+	if _, _, err := broker.SubscribeWithAckCtx(cancelled, "topic"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, unsubscribe, err := broker.SubscribeWithAckCtx(ctx, "topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	if err := broker.PublishCtx(ctx, "topic", Message{Payload: []byte("b")}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-ch; string(msg.Payload) != "b" {
+		t.Errorf("Expected payload b, got %q", msg.Payload)
+	}
+	cancel()
+	expectClosed(t, ch)
+	if count := broker.GetSubscriberCount("topic"); count != 0 {
+		t.Errorf("Expected no subscribers after cancel, got %d", count)
+	}
+}
+
+func TestContextFallback(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.PersistenceEnabled = false
+	broker := NewBroker(config)
+	defer broker.Close()
+	plain := plainBroker{broker}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, unsubscribe, err := SubscribeWithAckContext(ctx, plain, "topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	if err := PublishContext(ctx, plain, "topic", Message{Payload: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	cancel()
+	expectClosed(t, ch)
+	if err := PublishContext(ctx, plain, "topic", Message{Payload: []byte("b")}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	// Unsubscribing after the context ended is harmless
+	unsubscribe()
+}
+
+func TestHTTPBrokerPublishCtxDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := NewHTTPBroker(server.URL).PublishCtx(ctx, "topic", Message{Payload: []byte("{}")})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Publish took %s despite the deadline", elapsed)
+	}
+}
+
+func TestFailoverBrokerClientContext(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+	addr, _ := serveBroker(t, broker, "")
+
+	client, err := NewFailoverBrokerClient([]string{addr}, defaultProtocolGuard(), testFailoverConfig(), logger.NewFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, unsubscribe, err := client.SubscribeWithAckCtx(ctx, "telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	if err := client.PublishCtx(ctx, "telemetry", Message{Payload: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := receivePayloads(t, ch, 1); got[0] != "a" {
+		t.Errorf("Expected payload a, got %v", got)
+	}
+
+	cancel()
+	expectClosed(t, ch)
+	if err := client.PublishCtx(ctx, "telemetry", Message{Payload: []byte("b")}); err == nil {
+		t.Error("Expected a publish with a cancelled context to fail")
+	}
+}
@@ -0,0 +1,260 @@
+package mq
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeadLetterTopicSuffix is appended to a topic's name to form the topic its
+// messages are moved to once they run out of retries
+const DeadLetterTopicSuffix = ".dlq"
+
+// Headers added to a dead-lettered message
+const (
+	// HeaderDeadLetterTopic is the topic the message was dead-lettered from
+	HeaderDeadLetterTopic = "x-dlq-topic"
+	// HeaderDeadLetterGroup is the consumer group whose copy of the message
+	// was dead-lettered, if any
+	HeaderDeadLetterGroup = "x-dlq-group"
+	// HeaderDeadLetterError is the error of the message's last failure
+	HeaderDeadLetterError = "x-dlq-error"
+	// HeaderDeadLetterRetries is how many times the message was redelivered
+	HeaderDeadLetterRetries = "x-dlq-retries"
+	// HeaderDeadLetterAt is when the message was dead-lettered, in RFC 3339
+	HeaderDeadLetterAt = "x-dlq-at"
+)
+
+// errRetriesExhausted is the reason recorded for a dead-lettered message
+// that never failed explicitly
+const errRetriesExhausted = "delivery retries exhausted"
+
+// DeadLetterTopic returns the topic messages of topic are dead-lettered to
+func DeadLetterTopic(topic string) string {
+	return topic + DeadLetterTopicSuffix
+}
+
+// IsDeadLetterTopic reports whether topic is a dead-letter topic
+func IsDeadLetterTopic(topic string) bool {
+	return strings.HasSuffix(topic, DeadLetterTopicSuffix)
+}
+
+// DeadLetterInfo describes a message held on a dead-letter topic
+type DeadLetterInfo struct {
+	// ID is the ID to re-drive or purge the message by
+	ID        string `json:"id"`
+	MessageID string `json:"message_id"`
+	// Group is the consumer group of the original topic whose copy was
+	// dead-lettered, if any
+	Group          string            `json:"group,omitempty"`
+	PublishedAt    time.Time         `json:"published_at"`
+	DeadLetteredAt time.Time         `json:"dead_lettered_at"`
+	Retries        int               `json:"retries"`
+	Error          string            `json:"error,omitempty"`
+	SizeBytes      int               `json:"size_bytes"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Payload        []byte            `json:"payload"`
+}
+
+// deadLetterHeaders returns a pending message's headers with the headers
+// describing why it was dead-lettered added
+func deadLetterHeaders(topic string, pendingMsg *PendingMessage, now time.Time) map[string]string {
+	headers := make(map[string]string, len(pendingMsg.Message.Headers)+5)
+	for name, value := range pendingMsg.Message.Headers {
+		headers[name] = value
+	}
+	reason := pendingMsg.lastError
+	if reason == "" {
+		reason = errRetriesExhausted
+	}
+	headers[HeaderDeadLetterTopic] = topic
+	headers[HeaderDeadLetterError] = reason
+	headers[HeaderDeadLetterRetries] = strconv.Itoa(pendingMsg.Retries)
+	headers[HeaderDeadLetterAt] = now.UTC().Format(time.RFC3339Nano)
+	if pendingMsg.group != "" {
+		headers[HeaderDeadLetterGroup] = pendingMsg.group
+	}
+	return headers
+}
+
+// moveToDeadLetterTopic publishes a pending message to its topic's
+// dead-letter topic. Messages of dead-letter and poison topics are not moved.
+// Caller must hold b.mu.
+func (b *Broker) moveToDeadLetterTopic(topic string, pendingMsg *PendingMessage) error {
+	if IsDeadLetterTopic(topic) || strings.HasSuffix(topic, PoisonTopicSuffix) {
+		return nil
+	}
+	return b.publishLocked(DeadLetterTopic(topic), Message{
+		ID:        pendingMsg.Message.ID,
+		Timestamp: pendingMsg.Message.Timestamp,
+		Payload:   pendingMsg.Message.Payload,
+		Headers:   deadLetterHeaders(topic, pendingMsg, time.Now()),
+	})
+}
+
+// DeadLetters lists the messages held on a topic's dead-letter topic, oldest
+// first
+func (b *Broker) DeadLetters(topic string) ([]DeadLetterInfo, error) {
+	if err := ValidateTopic(topic); err != nil {
+		return nil, err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	topicData, exists := b.topics[DeadLetterTopic(topic)]
+	if !exists {
+		return []DeadLetterInfo{}, nil
+	}
+	letters := make([]DeadLetterInfo, 0, len(topicData.pendingMsgs))
+	for id, pendingMsg := range topicData.pendingMsgs {
+		headers := make(map[string]string, len(pendingMsg.Message.Headers))
+		for name, value := range pendingMsg.Message.Headers {
+			headers[name] = value
+		}
+		retries, _ := strconv.Atoi(headers[HeaderDeadLetterRetries])
+		deadLetteredAt, _ := time.Parse(time.RFC3339Nano, headers[HeaderDeadLetterAt])
+		letters = append(letters, DeadLetterInfo{
+			ID:             id,
+			MessageID:      pendingMsg.MessageID,
+			Group:          headers[HeaderDeadLetterGroup],
+			PublishedAt:    pendingMsg.Message.Timestamp,
+			DeadLetteredAt: deadLetteredAt,
+			Retries:        retries,
+			Error:          headers[HeaderDeadLetterError],
+			SizeBytes:      len(pendingMsg.Message.Payload),
+			Headers:        headers,
+			Payload:        pendingMsg.Message.Payload,
+		})
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if !letters[i].DeadLetteredAt.Equal(letters[j].DeadLetteredAt) {
+			return letters[i].DeadLetteredAt.Before(letters[j].DeadLetteredAt)
+		}
+		return letters[i].ID < letters[j].ID
+	})
+	return letters, nil
+}
+
+// RedriveDeadLetters publishes messages held on a topic's dead-letter topic
+// to the topic again, with fresh retries, and removes them from the
+// dead-letter topic. A message dead-lettered from a consumer group goes back
+// to that group only. With no ids every held message is re-driven. It
+// returns how many were.
+func (b *Broker) RedriveDeadLetters(topic string, ids ...string) (int, error) {
+	if err := ValidateTopic(topic); err != nil {
+		return 0, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dlq := DeadLetterTopic(topic)
+	letters, err := b.lookupDeadLetters(dlq, ids)
+	if err != nil {
+		return 0, err
+	}
+	redriven := 0
+	for _, id := range letters {
+		pendingMsg := b.topics[dlq].pendingMsgs[id]
+		headers := make(map[string]string, len(pendingMsg.Message.Headers))
+		for name, value := range pendingMsg.Message.Headers {
+			if !strings.HasPrefix(name, "x-dlq-") {
+				headers[name] = value
+			}
+		}
+		msg := Message{
+			ID:        pendingMsg.Message.ID,
+			Timestamp: pendingMsg.Message.Timestamp,
+			Payload:   pendingMsg.Message.Payload,
+			Headers:   headers,
+		}
+		if err := b.redriveLocked(topic, pendingMsg.Message.Headers[HeaderDeadLetterGroup], msg); err != nil {
+			return redriven, fmt.Errorf("failed to re-drive message %s: %w", id, err)
+		}
+		b.removePendingMessage(dlq, id)
+		redriven++
+	}
+	return redriven, nil
+}
+
+// redriveLocked publishes a re-driven message to topic, or only to group if
+// the message came from a consumer group the topic still has. Caller must
+// hold b.mu.
+func (b *Broker) redriveLocked(topic, group string, msg Message) error {
+	topicData := b.topicDataLocked(topic)
+	if _, exists := topicData.groups[group]; group == "" || !exists {
+		return b.publishLocked(topic, msg)
+	}
+
+	var offset uint64
+	if b.config.PersistenceEnabled {
+		var err error
+		if offset, err = b.persistMessage(topic, msg); err != nil {
+			return fmt.Errorf("failed to persist message: %w", err)
+		}
+		// Only the group has a copy to replay after a restart
+		log := b.logs[topic]
+		for _, other := range append(topicData.groupNames(), "") {
+			if other == group {
+				continue
+			}
+			if err := log.resolve(offset, other); err != nil {
+				return err
+			}
+		}
+	}
+	b.deliver(topicData, b.track(topic, topicData, msg, offset, group, time.Now()))
+	return nil
+}
+
+// PurgeDeadLetters discards messages held on a topic's dead-letter topic.
+// With no ids every held message is discarded. It returns how many were.
+func (b *Broker) PurgeDeadLetters(topic string, ids ...string) (int, error) {
+	if err := ValidateTopic(topic); err != nil {
+		return 0, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dlq := DeadLetterTopic(topic)
+	letters, err := b.lookupDeadLetters(dlq, ids)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range letters {
+		b.removePendingMessage(dlq, id)
+	}
+	return len(letters), nil
+}
+
+// lookupDeadLetters returns the IDs of the messages held on a dead-letter
+// topic out of ids, or all of them if ids is empty. Caller must hold b.mu.
+func (b *Broker) lookupDeadLetters(dlq string, ids []string) ([]string, error) {
+	if b.closed {
+		return nil, ErrClosed
+	}
+	topicData, exists := b.topics[dlq]
+	if !exists {
+		if len(ids) > 0 {
+			return nil, fmt.Errorf("%w: %s on topic %s", ErrMessageNotFound, ids[0], dlq)
+		}
+		return nil, nil
+	}
+	if len(ids) == 0 {
+		for id := range topicData.pendingMsgs {
+			ids = append(ids, id)
+		}
+		// Re-drive in the order the messages were dead-lettered
+		sort.Slice(ids, func(i, j int) bool {
+			return topicData.pendingMsgs[ids[i]].queueIndex < topicData.pendingMsgs[ids[j]].queueIndex
+		})
+		return ids, nil
+	}
+	for _, id := range ids {
+		if _, exists := topicData.pendingMsgs[id]; !exists {
+			return nil, fmt.Errorf("%w: %s on topic %s", ErrMessageNotFound, id, dlq)
+		}
+	}
+	return ids, nil
+}
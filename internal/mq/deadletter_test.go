@@ -0,0 +1,125 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBroker_DeadLetterTopic(t *testing.T) {
+	config := persistentConfig(t.TempDir())
+	config.MaxRetries = 1
+	broker := NewBroker(config)
+
+	messages, unsubscribe, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"m1", "m2"} {
+		if err := broker.Publish("telemetry", Message{ID: id, Payload: []byte(id), Headers: map[string]string{"source": "host-1"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// m1 fails until it runs out of retries, m2 is dead-lettered by hand
+	first := <-messages
+	<-messages
+	first.Nack(errors.New("invalid character"))
+	(<-messages).Nack(errors.New("invalid character"))
+	if err := broker.DeadLetter("telemetry", "m2"); err != nil {
+		t.Fatal(err)
+	}
+	unsubscribe()
+
+	letters, err := broker.DeadLetters("telemetry")
+	if err != nil || len(letters) != 2 {
+		t.Fatalf("Expected 2 dead letters, got %+v, %v", letters, err)
+	}
+	if letters[0].ID != "m1" || letters[0].Retries != 1 || letters[0].Error != "invalid character" || letters[0].Headers["source"] != "host-1" {
+		t.Errorf("Unexpected dead letter %+v", letters[0])
+	}
+	if letters[1].ID != "m2" || letters[1].Error != errRetriesExhausted {
+		t.Errorf("Unexpected dead letter %+v", letters[1])
+	}
+
+	// Dead letters outlast their ack timeout and a restart
+	broker.config.AckTimeout = 0
+	broker.processAckTimeouts()
+	broker.Close()
+	broker = NewBroker(config)
+	defer broker.Close()
+	if letters, _ := broker.DeadLetters("telemetry"); len(letters) != 2 {
+		t.Fatalf("Expected the dead letters kept, got %+v", letters)
+	}
+
+	// A re-driven message is published again with fresh retries and without
+	// the dead-letter headers
+	messages, unsubscribe, err = broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	if n, err := broker.RedriveDeadLetters("telemetry", "m1"); err != nil || n != 1 {
+		t.Fatalf("Expected 1 message re-driven, got %d, %v", n, err)
+	}
+	select {
+	case msg := <-messages:
+		if msg.ID != "m1" || msg.Retries != 0 || msg.Headers["source"] != "host-1" || msg.Headers[HeaderDeadLetterError] != "" {
+			t.Errorf("Unexpected re-driven message %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message re-driven")
+	}
+
+	if _, err := broker.PurgeDeadLetters("telemetry", "m1"); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("Expected a re-driven message gone from the dead-letter topic, got %v", err)
+	}
+	if n, err := broker.PurgeDeadLetters("telemetry"); err != nil || n != 1 {
+		t.Errorf("Expected 1 message purged, got %d, %v", n, err)
+	}
+	if letters, _ := broker.DeadLetters("telemetry"); len(letters) != 0 {
+		t.Errorf("Expected no dead letters left, got %+v", letters)
+	}
+}
+
+func TestBroker_RedrivesToConsumerGroup(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.MaxRetries = 0
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	ingest, unsubscribeIngest, err := broker.SubscribeGroup("telemetry", "ingest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribeIngest()
+	audit, unsubscribeAudit, err := broker.SubscribeGroup("telemetry", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribeAudit()
+
+	if err := broker.Publish("telemetry", Message{ID: "m1", Payload: []byte("m1")}); err != nil {
+		t.Fatal(err)
+	}
+	(<-ingest).Nack(nil)
+	(<-audit).Ack()
+
+	letters, _ := broker.DeadLetters("telemetry")
+	if len(letters) != 1 || letters[0].Group != "ingest" {
+		t.Fatalf("Expected the ingest group's copy dead-lettered, got %+v", letters)
+	}
+	if _, err := broker.RedriveDeadLetters("telemetry"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-ingest:
+		msg.Ack()
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message re-driven to the ingest group")
+	}
+	select {
+	case msg := <-audit:
+		t.Errorf("Expected nothing re-driven to the audit group, got %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -2,10 +2,15 @@ package mq
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
 )
 
 // DirectBrokerClient connects directly to the MQ service's broker
@@ -14,28 +19,47 @@ type DirectBrokerClient struct {
 	client  *http.Client
 }
 
-// NewDirectBrokerClient creates a new direct broker client
+// NewDirectBrokerClient creates a new direct broker client. baseURL may be a
+// unix: socket address.
 func NewDirectBrokerClient(baseURL string) *DirectBrokerClient {
+	return NewDirectBrokerClientWithConfig(baseURL, DefaultHTTPClientConfig())
+}
+
+// NewDirectBrokerClientWithConfig creates a new direct broker client whose
+// connections are tuned by config
+func NewDirectBrokerClientWithConfig(baseURL string, config netutil.ClientConfig) *DirectBrokerClient {
+	client, baseURL := netutil.NewHTTPClient(baseURL, config)
 	return &DirectBrokerClient{
 		baseURL: baseURL,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:  client,
 	}
 }
 
 // Publish publishes a message to a topic
 func (d *DirectBrokerClient) Publish(topic string, msg Message) error {
+	return d.PublishCtx(context.Background(), topic, msg)
+}
+
+// PublishCtx publishes a message to a topic, abandoning the request once ctx
+// is done
+func (d *DirectBrokerClient) PublishCtx(ctx context.Context, topic string, msg Message) error {
 	url := fmt.Sprintf("%s/publish/%s", d.baseURL, topic)
 
-	resp, err := d.client.Post(url, "application/json", bytes.NewBuffer(msg.Payload))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(msg.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to create publish request for %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setHTTPMessageHeaders(req.Header, msg.Headers)
+	resp, err := d.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to publish to %s: %w", url, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("publish failed with status %d", resp.StatusCode)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("publish failed: %w", errorFromHTTPStatus(resp.StatusCode, strings.TrimSpace(string(body))))
 	}
 
 	return nil
@@ -46,41 +70,49 @@ func (d *DirectBrokerClient) Subscribe(topic string) (chan []byte, func(), error
 	return nil, nil, fmt.Errorf("Subscribe not supported via HTTP - use direct broker connection")
 }
 
+// SubscribeCtx is not implemented for HTTP
+func (d *DirectBrokerClient) SubscribeCtx(ctx context.Context, topic string) (chan []byte, func(), error) {
+	return d.Subscribe(topic)
+}
+
 // SubscribeWithAck creates a subscription with acknowledgment using polling
 func (d *DirectBrokerClient) SubscribeWithAck(topic string) (chan Message, func(), error) {
-	msgCh := make(chan Message, 100)
-	stopCh := make(chan struct{})
-
-	// Start polling goroutine
-	go d.pollMessages(topic, msgCh, stopCh)
+	return d.SubscribeWithAckCtx(context.Background(), topic)
+}
 
-	unsubscribe := func() {
-		select {
-		case <-stopCh:
-			// Already stopped
-		default:
-			close(stopCh)
-		}
-		// Don't close msgCh here - let the polling goroutine handle it
+// SubscribeWithAckCtx creates a subscription with acknowledgment using
+// polling, which stops once ctx is done or the returned function is called
+func (d *DirectBrokerClient) SubscribeWithAckCtx(ctx context.Context, topic string) (chan Message, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
+	msgCh := make(chan Message, 100)
+	ctx, cancel := context.WithCancel(ctx)
 
-	return msgCh, unsubscribe, nil
+	// Start polling goroutine; it closes msgCh once it stops
+	go d.pollMessages(ctx, topic, msgCh)
+
+	return msgCh, cancel, nil
 }
 
 // pollMessages continuously polls for messages from the MQ service
-func (d *DirectBrokerClient) pollMessages(topic string, msgCh chan Message, stopCh chan struct{}) {
+func (d *DirectBrokerClient) pollMessages(ctx context.Context, topic string, msgCh chan Message) {
 	ticker := time.NewTicker(1 * time.Second) // Poll every second
 	defer ticker.Stop()
 	defer close(msgCh) // Close message channel when polling stops
 
 	for {
 		select {
-		case <-stopCh:
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			// Poll for messages
 			url := fmt.Sprintf("%s/consume/%s?timeout=2&max_messages=10", d.baseURL, topic)
-			resp, err := d.client.Get(url)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				continue
+			}
+			resp, err := d.client.Do(req)
 			if err != nil {
 				// Log error but continue polling
 				continue
@@ -102,7 +134,7 @@ func (d *DirectBrokerClient) pollMessages(topic string, msgCh chan Message, stop
 							Payload: []byte(msg.Payload),
 							Ack:     func() {}, // No-op ack since we already consumed from HTTP
 						}:
-						case <-stopCh:
+						case <-ctx.Done():
 							_ = resp.Body.Close()
 							return
 						default:
@@ -0,0 +1,113 @@
+package mq
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/encryption/encryptiontest"
+)
+
+func TestBrokerPersistence_Encrypted(t *testing.T) {
+	keyring := encryptiontest.Keyring(t, "k1="+encryptiontest.Key(t))
+
+	config := DefaultBrokerConfig()
+	config.PersistenceEnabled = true
+	config.PersistenceDir = t.TempDir()
+	config.Keyring = keyring
+
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	if err := broker.Publish("secure", Message{Payload: []byte("top secret payload")}); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	data, err := os.ReadFile(segmentPath(filepath.Join(config.PersistenceDir, "secure"), 0, segmentExt))
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if bytes.Contains(data, []byte("top secret")) {
+		t.Fatal("Expected the payload encrypted in the log")
+	}
+	body, _, err := readFrame(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Invalid frame: %v", err)
+	}
+	if flags, content, _ := frameContent(body); flags&flagSealed == 0 || !encryption.IsEncrypted(content) {
+		t.Fatalf("Expected sealed content, got flags %d", flags)
+	}
+
+	record, err := decodeFrame(body, keyring)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if string(record.Payload) != "top secret payload" {
+		t.Errorf("Expected payload %q, got %q", "top secret payload", record.Payload)
+	}
+}
+
+func TestReencryptPersistence(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, newKey := encryptiontest.Key(t), encryptiontest.Key(t)
+	oldRing := encryptiontest.Keyring(t, "old="+oldKey)
+
+	// One topic in plaintext, one sealed with the old key
+	plainDir := filepath.Join(dir, "plain")
+	sealedDir := filepath.Join(dir, "sealed")
+	if err := writeSegment(plainDir, []logRecord{{Payload: []byte("a")}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSegment(sealedDir, []logRecord{{Payload: []byte("b")}}, oldRing); err != nil {
+		t.Fatal(err)
+	}
+
+	// Topics are migrated one at a time, so the plaintext topic is already
+	// done when the sealed one fails
+	count, err := ReencryptPersistence(dir, encryptiontest.Keyring(t, "new="+newKey))
+	if err == nil {
+		t.Fatal("Expected error when the old key is missing from the keyring")
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 line rewritten before the failure, got %d", count)
+	}
+
+	// Rotated keyring: new key active, old key kept for decryption
+	rotated := encryptiontest.Keyring(t, "new="+newKey, "old="+oldKey)
+	count, err = ReencryptPersistence(dir, rotated)
+	if err != nil {
+		t.Fatalf("ReencryptPersistence failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 line rewritten, got %d", count)
+	}
+
+	for topicDir, want := range map[string]string{plainDir: "a", sealedDir: "b"} {
+		data, err := os.ReadFile(segmentPath(topicDir, 0, segmentExt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _, err := readFrame(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, content, _ := frameContent(body); rotated.NeedsRotation(content) {
+			t.Errorf("%s not sealed with the active key", topicDir)
+		}
+		record, err := decodeFrame(body, rotated)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if string(record.Payload) != want {
+			t.Errorf("%s: unexpected payload %q", topicDir, record.Payload)
+		}
+	}
+
+	// A second pass has nothing left to do
+	count, err = ReencryptPersistence(dir, rotated)
+	if err != nil || count != 0 {
+		t.Errorf("Second pass = %d, %v; want 0, nil", count, err)
+	}
+}
@@ -0,0 +1,125 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+var (
+	// ErrClosed is returned by a broker or client that has been closed
+	ErrClosed = errors.New("broker is closed")
+	// ErrTopicNotFound is returned when asking about a topic nothing has
+	// published or subscribed to
+	ErrTopicNotFound = errors.New("topic not found")
+	// ErrQueueFull is returned when publishing to a topic whose queue is at
+	// its limit
+	ErrQueueFull = errors.New("topic queue is full")
+	// ErrDiskFull is returned when publishing while the persistence
+	// directory's filesystem has less free space than the broker keeps
+	ErrDiskFull = persistence.ErrDiskFull
+	// ErrUnauthorized is returned when a client may not use a topic
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned when a client's token does not permit what it
+	// asked of a topic
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidTopic is returned when publishing or subscribing to a topic
+	// whose name breaks the rules of ValidateTopic
+	ErrInvalidTopic = errors.New("invalid topic name")
+)
+
+// typedError pairs an error with its HTTP status and gRPC code
+type typedError struct {
+	err    error
+	status int
+	code   codes.Code
+}
+
+// brokerErrors are the typed errors a remote broker's responses are mapped
+// back to. ErrDiskFull shares ErrQueueFull's gRPC code, so over gRPC both
+// come back as ErrQueueFull.
+var brokerErrors = []typedError{
+	{ErrClosed, http.StatusServiceUnavailable, codes.Unavailable},
+	{ErrTopicNotFound, http.StatusNotFound, codes.NotFound},
+	{ErrQueueFull, http.StatusTooManyRequests, codes.ResourceExhausted},
+	{ErrDiskFull, http.StatusInsufficientStorage, codes.ResourceExhausted},
+	{ErrUnauthorized, http.StatusUnauthorized, codes.Unauthenticated},
+	{ErrForbidden, http.StatusForbidden, codes.PermissionDenied},
+}
+
+// requestErrors are other errors with a status of their own
+var requestErrors = []typedError{
+	{ErrInvalidRule, http.StatusBadRequest, codes.InvalidArgument},
+	{ErrInvalidTopic, http.StatusBadRequest, codes.InvalidArgument},
+	{ErrRuleNotFound, http.StatusNotFound, codes.NotFound},
+	{ErrMessageNotFound, http.StatusNotFound, codes.NotFound},
+	{ErrIncompatibleProtocol, http.StatusBadRequest, codes.FailedPrecondition},
+	{ErrTopicExists, http.StatusConflict, codes.AlreadyExists},
+	{ErrInvalidTopicConfig, http.StatusBadRequest, codes.InvalidArgument},
+	{ErrOffsetOutOfRange, http.StatusRequestedRangeNotSatisfiable, codes.OutOfRange},
+}
+
+// lookupError finds the typed error err wraps
+func lookupError(err error) (typedError, bool) {
+	for _, list := range [][]typedError{brokerErrors, requestErrors} {
+		for _, typed := range list {
+			if errors.Is(err, typed.err) {
+				return typed, true
+			}
+		}
+	}
+	return typedError{}, false
+}
+
+// HTTPStatus returns the HTTP status code for a broker error, 500 for
+// untyped errors
+func HTTPStatus(err error) int {
+	if typed, ok := lookupError(err); ok {
+		return typed.status
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCStatus converts a broker error to a gRPC status error carrying the
+// matching code, codes.Internal for untyped errors. Status errors are
+// returned as they are.
+func GRPCStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if typed, ok := lookupError(err); ok {
+		return status.Error(typed.code, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// errorFromHTTPStatus returns an error for a failed response, wrapping the
+// typed error matching its status so callers can use errors.Is
+func errorFromHTTPStatus(code int, message string) error {
+	for _, typed := range brokerErrors {
+		if code == typed.status {
+			return fmt.Errorf("%w: status %d: %s", typed.err, code, message)
+		}
+	}
+	return fmt.Errorf("status %d: %s", code, message)
+}
+
+// errorFromGRPC wraps err with the typed error matching its gRPC code, if
+// any, so callers can use errors.Is
+func errorFromGRPC(err error) error {
+	code := status.Code(err)
+	for _, typed := range brokerErrors {
+		if code == typed.code {
+			return fmt.Errorf("%w: %w", typed.err, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+func TestErrorStatusMapping(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   codes.Code
+	}{
+		{ErrClosed, http.StatusServiceUnavailable, codes.Unavailable},
+		{fmt.Errorf("%w: gpu-metrics", ErrTopicNotFound), http.StatusNotFound, codes.NotFound},
+		{fmt.Errorf("publish: %w", ErrQueueFull), http.StatusTooManyRequests, codes.ResourceExhausted},
+		{fmt.Errorf("/data/mq has 0 bytes free: %w", ErrDiskFull), http.StatusInsufficientStorage, codes.ResourceExhausted},
+		{ErrUnauthorized, http.StatusUnauthorized, codes.Unauthenticated},
+		{fmt.Errorf("%w: empty topic", ErrInvalidRule), http.StatusBadRequest, codes.InvalidArgument},
+		{fmt.Errorf("%w: topic name is empty", ErrInvalidTopic), http.StatusBadRequest, codes.InvalidArgument},
+		{errors.New("disk on fire"), http.StatusInternalServerError, codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatus(tt.err); got != tt.status {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.status)
+		}
+		if got := status.Code(GRPCStatus(tt.err)); got != tt.code {
+			t.Errorf("GRPCStatus(%v) code = %v, want %v", tt.err, got, tt.code)
+		}
+	}
+
+	if GRPCStatus(nil) != nil {
+		t.Error("GRPCStatus(nil) should be nil")
+	}
+	existing := status.Error(codes.Aborted, "aborted")
+	if got := GRPCStatus(existing); got != existing {
+		t.Errorf("GRPCStatus should return status errors as they are, got %v", got)
+	}
+}
+
+func TestRemoteErrorsWrapTypedErrors(t *testing.T) {
+	if err := errorFromHTTPStatus(http.StatusTooManyRequests, "queue full"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("429 should wrap ErrQueueFull, got %v", err)
+	}
+	if err := errorFromHTTPStatus(http.StatusInsufficientStorage, "disk full"); !errors.Is(err, ErrDiskFull) {
+		t.Errorf("507 should wrap ErrDiskFull, got %v", err)
+	}
+	if err := errorFromHTTPStatus(http.StatusBadRequest, "bad"); HTTPStatus(err) != http.StatusInternalServerError {
+		t.Errorf("400 should not wrap a broker error, got %v", err)
+	}
+
+	err := errorFromGRPC(status.Error(codes.Unavailable, "broker is closed"))
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("Unavailable should wrap ErrClosed, got %v", err)
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("wrapped error should keep its gRPC code, got %v", status.Code(err))
+	}
+}
+
+func TestBrokerTypedErrors(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.PersistenceEnabled = false
+	broker := NewBroker(config)
+
+	if _, err := broker.GetTopicStats("missing"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("GetTopicStats of an unknown topic should return ErrTopicNotFound, got %v", err)
+	}
+	if err := broker.Publish("known", Message{Payload: []byte("{}")}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if stats, err := broker.GetTopicStats("known"); err != nil || stats.QueueSize != 1 {
+		t.Errorf("GetTopicStats(known) = %+v, %v", stats, err)
+	}
+
+	broker.Close()
+	if err := broker.Publish("known", Message{Payload: []byte("{}")}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close should return ErrClosed, got %v", err)
+	}
+	if _, _, err := broker.SubscribeWithAck("known"); !errors.Is(err, ErrClosed) {
+		t.Errorf("SubscribeWithAck after Close should return ErrClosed, got %v", err)
+	}
+}
+
+func TestBrokerRefusesPublishOnFullDisk(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := persistence.DiskUsage(dir); err != nil {
+		t.Skipf("Disk usage not supported: %v", err)
+	}
+	config := DefaultBrokerConfig()
+	config.PersistenceEnabled = true
+	config.PersistenceDir = dir
+	// No filesystem has this much free
+	config.MinFreeDiskBytes = 1 << 62
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	if err := broker.Publish("telemetry", Message{Payload: []byte("{}")}); !errors.Is(err, ErrDiskFull) {
+		t.Errorf("Publish on a full disk should return ErrDiskFull, got %v", err)
+	}
+	if stats, _ := broker.GetTopicStats("telemetry"); stats.QueueSize != 0 {
+		t.Errorf("Expected the refused message not queued, got %+v", stats)
+	}
+	if refused := broker.metrics.diskFull.WithLabelValues("telemetry").Value(); refused != 1 {
+		t.Errorf("Expected 1 refusal counted, got %v", refused)
+	}
+}
+
+func TestHTTPBrokerPublishTypedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broker is closed", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewDirectBrokerClient(server.URL).Publish("topic", Message{Payload: []byte("{}")})
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("503 from the broker should wrap ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+// FailoverConfig configures a FailoverBrokerClient
+type FailoverConfig struct {
+	// HealthTimeout bounds each endpoint's health check
+	HealthTimeout time.Duration
+	// RetryInterval is the first delay before resubscribing when no other
+	// endpoint is healthy; it doubles up to MaxRetryInterval
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+}
+
+// DefaultFailoverConfig returns the failover configuration used by the
+// collector
+func DefaultFailoverConfig() FailoverConfig {
+	return FailoverConfig{
+		HealthTimeout:    2 * time.Second,
+		RetryInterval:    500 * time.Millisecond,
+		MaxRetryInterval: 30 * time.Second,
+	}
+}
+
+// FailoverStats reports which MQ endpoint a FailoverBrokerClient uses
+type FailoverStats struct {
+	Endpoints []string `json:"endpoints"`
+	Active    string   `json:"active_endpoint"`
+	Failovers uint64   `json:"failovers"`
+}
+
+// FailoverBrokerClient talks over gRPC to the first healthy of several MQ
+// service endpoints. When the active endpoint becomes unreachable it
+// switches to the next healthy one, in order and wrapping around, and every
+// subscription is resumed there. Messages the lost endpoint had not yet
+// delivered stay queued on it.
+type FailoverBrokerClient struct {
+	endpoints []*GRPCBrokerClient
+	config    FailoverConfig
+	log       *logger.Logger
+
+	// mu guards active and serialises failovers
+	mu        sync.RWMutex
+	active    int
+	failovers atomic.Uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewFailoverBrokerClient creates a client for the MQ services at addrs, in
+// order of preference, checking their protocol versions with guard. It
+// starts on the first healthy endpoint, or the first endpoint if none is
+// healthy yet.
+func NewFailoverBrokerClient(addrs []string, guard *ProtocolGuard, config FailoverConfig, log *logger.Logger) (*FailoverBrokerClient, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no MQ endpoints given")
+	}
+	defaults := DefaultFailoverConfig()
+	if config.HealthTimeout <= 0 {
+		config.HealthTimeout = defaults.HealthTimeout
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaults.RetryInterval
+	}
+	if config.MaxRetryInterval < config.RetryInterval {
+		config.MaxRetryInterval = max(defaults.MaxRetryInterval, config.RetryInterval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &FailoverBrokerClient{
+		config: config,
+		log:    log,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, addr := range addrs {
+		client, err := NewGRPCBrokerClientWithGuard(addr, guard)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.endpoints = append(f.endpoints, client)
+	}
+
+	if i := f.firstHealthy(0); i >= 0 {
+		f.active = i
+	} else {
+		log.Warn("No MQ endpoint is healthy yet", "endpoints", addrs)
+	}
+	log.Info("Using MQ endpoint", "endpoint", f.ActiveEndpoint(), "endpoints", len(addrs))
+	return f, nil
+}
+
+// SetToken sends token as a bearer token to every endpoint, for services
+// with an ACL
+func (f *FailoverBrokerClient) SetToken(token string) {
+	for _, client := range f.endpoints {
+		client.SetToken(token)
+	}
+}
+
+// ActiveEndpoint returns the address of the endpoint in use
+func (f *FailoverBrokerClient) ActiveEndpoint() string {
+	return f.current().serverAddr
+}
+
+// Stats returns the endpoints, the active one and how often it changed
+func (f *FailoverBrokerClient) Stats() FailoverStats {
+	endpoints := make([]string, len(f.endpoints))
+	for i, client := range f.endpoints {
+		endpoints[i] = client.serverAddr
+	}
+	return FailoverStats{
+		Endpoints: endpoints,
+		Active:    f.ActiveEndpoint(),
+		Failovers: f.failovers.Load(),
+	}
+}
+
+// QueueSize returns the number of messages in a topic's queue on the
+// active endpoint
+func (f *FailoverBrokerClient) QueueSize(ctx context.Context, topic string) (int, error) {
+	return f.current().QueueSize(ctx, topic)
+}
+
+// current returns the active endpoint
+func (f *FailoverBrokerClient) current() *GRPCBrokerClient {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.endpoints[f.active]
+}
+
+// healthy reports whether an endpoint answers a health check
+func (f *FailoverBrokerClient) healthy(client *GRPCBrokerClient) bool {
+	ctx, cancel := context.WithTimeout(f.ctx, f.config.HealthTimeout)
+	defer cancel()
+	_, err := client.client.Health(ctx, &pb.HealthRequest{})
+	return err == nil
+}
+
+// firstHealthy returns the index of the first healthy endpoint from start,
+// wrapping around, or -1 if none is healthy
+func (f *FailoverBrokerClient) firstHealthy(start int) int {
+	for n := 0; n < len(f.endpoints); n++ {
+		i := (start + n) % len(f.endpoints)
+		if f.healthy(f.endpoints[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// failover is called when a request to failed went wrong. If failed is still
+// active but no longer healthy, the next healthy endpoint becomes active. It
+// reports whether the active endpoint is now a different one.
+func (f *FailoverBrokerClient) failover(failed *GRPCBrokerClient, cause error) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.endpoints[f.active] != failed {
+		// Another request has already failed over
+		return true
+	}
+	next := f.firstHealthy(f.active)
+	if next < 0 || next == f.active {
+		return false
+	}
+
+	f.log.Warn("MQ endpoint failover", "from", failed.serverAddr, "to", f.endpoints[next].serverAddr, "error", cause)
+	f.active = next
+	f.failovers.Add(1)
+	return true
+}
+
+// Publish publishes a message to the active endpoint, failing over and
+// retrying once if it is unreachable
+func (f *FailoverBrokerClient) Publish(topic string, msg Message) error {
+	return f.PublishCtx(context.Background(), topic, msg)
+}
+
+// PublishCtx publishes a message to the active endpoint like Publish. Once
+// ctx is done the publish is abandoned without failing over.
+func (f *FailoverBrokerClient) PublishCtx(ctx context.Context, topic string, msg Message) error {
+	client := f.current()
+	err := client.PublishCtx(ctx, topic, msg)
+	if err == nil || ctx.Err() != nil || f.ctx.Err() != nil || !f.failover(client, err) {
+		return err
+	}
+	return f.current().PublishCtx(ctx, topic, msg)
+}
+
+// Subscribe is not supported over gRPC - use SubscribeWithAck
+func (f *FailoverBrokerClient) Subscribe(topic string) (chan []byte, func(), error) {
+	return nil, nil, fmt.Errorf("Subscribe not supported in gRPC broker - use SubscribeWithAck")
+}
+
+// SubscribeCtx is not supported over gRPC - use SubscribeWithAckCtx
+func (f *FailoverBrokerClient) SubscribeCtx(ctx context.Context, topic string) (chan []byte, func(), error) {
+	return f.Subscribe(topic)
+}
+
+// SubscribeWithAck subscribes to a topic on the active endpoint. The
+// subscription follows failovers until unsubscribed.
+func (f *FailoverBrokerClient) SubscribeWithAck(topic string) (chan Message, func(), error) {
+	return f.SubscribeWithAckCtx(context.Background(), topic)
+}
+
+// SubscribeWithAckCtx subscribes to a topic like SubscribeWithAck until ctx
+// is done or the returned function is called
+func (f *FailoverBrokerClient) SubscribeWithAckCtx(ctx context.Context, topic string) (chan Message, func(), error) {
+	if f.ctx.Err() != nil {
+		return nil, nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	subCtx, cancel := joinContext(ctx, f.ctx)
+	msgCh := make(chan Message, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.subscribe(subCtx, topic, msgCh)
+	}()
+
+	unsubscribe := func() {
+		cancel()
+		<-done
+		close(msgCh)
+	}
+	return msgCh, unsubscribeOnDone(ctx, unsubscribe), nil
+}
+
+// subscribe keeps a subscription to topic open on the active endpoint until
+// ctx is cancelled: straight away after a failover, otherwise with backoff
+func (f *FailoverBrokerClient) subscribe(ctx context.Context, topic string, msgCh chan Message) {
+	delay := f.config.RetryInterval
+	for {
+		client := f.current()
+		received, err := f.stream(ctx, client, topic, msgCh)
+		if ctx.Err() != nil {
+			return
+		}
+		if received > 0 {
+			// The subscription worked; retry promptly
+			delay = f.config.RetryInterval
+		}
+		if f.failover(client, err) {
+			f.log.Info("Resubscribing after MQ failover", "topic", topic, "endpoint", f.ActiveEndpoint())
+			continue
+		}
+
+		f.log.Warn("MQ subscription lost, resuming", "topic", topic, "endpoint", client.serverAddr, "error", err, "retry_in", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		delay = min(delay*2, f.config.MaxRetryInterval)
+	}
+}
+
+// stream passes messages from one subscription to topic on client to msgCh
+// until it fails, returning how many it received
+func (f *FailoverBrokerClient) stream(ctx context.Context, client *GRPCBrokerClient, topic string, msgCh chan Message) (int, error) {
+	stream, err := client.subscribeStream(ctx, topic, DefaultConsumerGroup)
+	if err != nil {
+		return 0, err
+	}
+
+	received := 0
+	for {
+		pbMsg, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received++
+
+		select {
+		case msgCh <- messageFromProto(pbMsg, client.ackFunc(topic, DefaultConsumerGroup, pbMsg.Id)):
+		case <-ctx.Done():
+			return received, ctx.Err()
+		}
+	}
+}
+
+// Close stops all subscriptions and closes the connections to every endpoint
+func (f *FailoverBrokerClient) Close() {
+	f.cancel()
+	for _, client := range f.endpoints {
+		client.Close()
+	}
+}
+
+// Ensure FailoverBrokerClient implements BrokerInterface
+var _ BrokerInterface = (*FailoverBrokerClient)(nil)
@@ -0,0 +1,111 @@
+package mq
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+)
+
+func testFailoverConfig() FailoverConfig {
+	return FailoverConfig{
+		HealthTimeout:    500 * time.Millisecond,
+		RetryInterval:    10 * time.Millisecond,
+		MaxRetryInterval: 50 * time.Millisecond,
+	}
+}
+
+// receivePayloads reads want messages from ch
+func receivePayloads(t *testing.T, ch chan Message, want int) []string {
+	t.Helper()
+	var payloads []string
+	timeout := time.After(5 * time.Second)
+	for len(payloads) < want {
+		select {
+		case msg := <-ch:
+			payloads = append(payloads, string(msg.Payload))
+		case <-timeout:
+			t.Fatalf("Expected %d messages, got %v", want, payloads)
+		}
+	}
+	return payloads
+}
+
+func TestFailoverBrokerClient_FirstHealthy(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+	down, stop := serveBroker(t, broker, "")
+	stop()
+	up, _ := serveBroker(t, broker, "")
+
+	client, err := NewFailoverBrokerClient([]string{down, up}, defaultProtocolGuard(), testFailoverConfig(), logger.NewFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if got := client.ActiveEndpoint(); got != up {
+		t.Errorf("Expected the first healthy endpoint %s, got %s", up, got)
+	}
+	if err := client.Publish("telemetry", Message{Payload: []byte("a")}); err != nil {
+		t.Errorf("Expected publish to the healthy endpoint, got %v", err)
+	}
+
+	if _, err := NewFailoverBrokerClient(nil, defaultProtocolGuard(), testFailoverConfig(), logger.NewFromEnv()); err == nil {
+		t.Error("Expected an error without endpoints")
+	}
+}
+
+func TestFailoverBrokerClient_FailsOver(t *testing.T) {
+	primary := NewBroker(DefaultBrokerConfig())
+	defer primary.Close()
+	secondary := NewBroker(DefaultBrokerConfig())
+	defer secondary.Close()
+	primaryAddr, stopPrimary := serveBroker(t, primary, "")
+	secondaryAddr, _ := serveBroker(t, secondary, "")
+
+	client, err := NewFailoverBrokerClient([]string{primaryAddr, secondaryAddr}, defaultProtocolGuard(), testFailoverConfig(), logger.NewFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ch, unsubscribe, err := client.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	// Publish until the subscription is open on the primary
+	publish := func(broker *Broker, payload string) {
+		if err := broker.Publish("telemetry", Message{Payload: []byte(payload)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	publish(primary, "primary")
+	if got := receivePayloads(t, ch, 1); got[0] != "primary" {
+		t.Fatalf("Expected the primary's message, got %v", got)
+	}
+
+	// Once the primary is unreachable the subscription resumes on the
+	// secondary, whose queued messages are delivered
+	stopPrimary()
+	for i := 0; i < 3; i++ {
+		publish(secondary, fmt.Sprintf("secondary-%d", i))
+	}
+	if got := receivePayloads(t, ch, 3); got[0] != "secondary-0" || got[2] != "secondary-2" {
+		t.Errorf("Expected the secondary's messages in order, got %v", got)
+	}
+
+	stats := client.Stats()
+	if stats.Active != secondaryAddr || stats.Failovers != 1 || len(stats.Endpoints) != 2 {
+		t.Errorf("Expected one failover to %s, got %+v", secondaryAddr, stats)
+	}
+	if err := client.Publish("telemetry", Message{Payload: []byte("published")}); err != nil {
+		t.Errorf("Expected publish to the secondary, got %v", err)
+	}
+	if got := receivePayloads(t, ch, 1); got[0] != "published" {
+		t.Errorf("Expected the published message via the secondary, got %v", got)
+	}
+}
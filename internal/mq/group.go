@@ -0,0 +1,150 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// DefaultConsumerGroup is the consumer group gRPC clients subscribe as, so
+// replicas of a consumer such as the collector share a topic's messages
+const DefaultConsumerGroup = "default"
+
+// consumerGroup is a set of subscribers sharing a topic's messages: each
+// message is delivered to one member, in turn
+type consumerGroup struct {
+	members []chan Message
+	// next is the member to try first for the next delivery
+	next int
+}
+
+// deliver hands msg to the next member with room in its channel, and
+// reports whether one took it. A redelivery thus goes to another member than
+// the one that failed it, if the group has one.
+func (g *consumerGroup) deliver(msg Message) bool {
+	for i := range g.members {
+		idx := (g.next + i) % len(g.members)
+		select {
+		case g.members[idx] <- msg:
+			g.next = idx + 1
+			return true
+		default:
+			// Channel is full, try the next member
+		}
+	}
+	return false
+}
+
+// remove removes a member and reports whether it was one
+func (g *consumerGroup) remove(ch chan Message) bool {
+	for i, member := range g.members {
+		if member == ch {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			if g.next > i {
+				g.next--
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// groupNames returns the topic's consumer groups in order
+func (t *TopicData) groupNames() []string {
+	names := make([]string, 0, len(t.groups))
+	for name := range t.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// subscriberCount counts the topic's subscribers, group members included
+func (t *TopicData) subscriberCount() int {
+	count := len(t.subscribers) + len(t.ackSubscribers)
+	for _, group := range t.groups {
+		count += len(group.members)
+	}
+	return count
+}
+
+// SubscribeGroup subscribes to a topic with acknowledgment support as a
+// member of a consumer group. Each message is delivered to one member of
+// every group, spreading a group's messages across its members, and to
+// every subscriber outside a group. A message a member fails to acknowledge
+// is redelivered to the next member.
+//
+// A group is kept once it has subscribed, so messages published while it
+// has no members queue for it until one joins or they run out of retries.
+func (b *Broker) SubscribeGroup(topic, group string) (chan Message, func(), error) {
+	if err := ValidateTopic(topic); err != nil {
+		return nil, nil, err
+	}
+	if group == "" {
+		return nil, nil, fmt.Errorf("consumer group of topic %s is empty", topic)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+
+	topicData := b.topicDataLocked(topic)
+	consumers, exists := topicData.groups[group]
+	if !exists {
+		// Keep the group across restarts, so messages logged for it are
+		// replayed for it
+		if b.config.PersistenceEnabled {
+			log, err := b.topicLogLocked(topic)
+			if err == nil {
+				err = log.addGroup(group)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to persist consumer group %s of topic %s: %w", group, topic, err)
+			}
+		}
+		consumers = &consumerGroup{}
+		topicData.groups[group] = consumers
+	}
+
+	ch := make(chan Message, 100) // Buffered channel
+	consumers.members = append(consumers.members, ch)
+
+	// Send the group's queued messages to the new member, unless delivery
+	// is paused
+	for _, pending := range topicData.messageQueue {
+		if pending.group != group || topicData.paused {
+			continue
+		}
+		select {
+		case ch <- pending.Message:
+			topicData.counters.delivered++
+		default:
+			// Channel is full, skip
+		}
+	}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if consumers.remove(ch) {
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+// SubscribeGroupCtx subscribes to a topic as a member of a consumer group
+// until ctx is done or the returned function is called
+func (b *Broker) SubscribeGroupCtx(ctx context.Context, topic, group string) (chan Message, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	ch, unsubscribe, err := b.SubscribeGroup(topic, group)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, unsubscribeOnDone(ctx, unsubscribe), nil
+}
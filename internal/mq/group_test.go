@@ -0,0 +1,141 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// receive reads the messages waiting on ch
+func receive(ch chan Message) []Message {
+	var msgs []Message
+	for {
+		select {
+		case msg := <-ch:
+			msgs = append(msgs, msg)
+		case <-time.After(50 * time.Millisecond):
+			return msgs
+		}
+	}
+}
+
+func TestBroker_ConsumerGroups(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+
+	collector1, unsubscribe1, err := broker.SubscribeGroup("telemetry", "collectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe1()
+	collector2, unsubscribe2, err := broker.SubscribeGroup("telemetry", "collectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe2()
+	archiver, unsubscribeArchiver, err := broker.SubscribeGroup("telemetry", "archivers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribeArchiver()
+	monitor, unsubscribeMonitor, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribeMonitor()
+
+	for i := 0; i < 10; i++ {
+		if err := broker.Publish("telemetry", Message{ID: fmt.Sprintf("m%d", i), Payload: []byte("x")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The collectors share the messages, each other group and the
+	// ungrouped subscriber get all of them
+	first, second := receive(collector1), receive(collector2)
+	if len(first) != 5 || len(second) != 5 {
+		t.Errorf("Expected the collectors to get 5 messages each, got %d and %d", len(first), len(second))
+	}
+	seen := make(map[string]bool)
+	for _, msg := range append(first, second...) {
+		if seen[msg.ID] {
+			t.Errorf("Message %s delivered to both collectors", msg.ID)
+		}
+		seen[msg.ID] = true
+		msg.Ack()
+	}
+	archived := receive(archiver)
+	if len(archived) != 10 || len(receive(monitor)) != 10 {
+		t.Fatalf("Expected every message delivered to the archivers and the monitor, got %d", len(archived))
+	}
+
+	// Acknowledging in one group leaves the others' copies pending
+	stats, err := broker.GetTopicStats("telemetry")
+	if err != nil || stats.PendingMessages != 20 || stats.ConsumerGroups != 2 || stats.SubscriberCount != 4 {
+		t.Errorf("Unexpected stats %+v, %v", stats, err)
+	}
+	pending, _ := broker.PendingMessages("telemetry")
+	for _, info := range pending {
+		if info.Group == "collectors" {
+			t.Errorf("Expected the collectors' copies acknowledged, %+v pending", info)
+		}
+	}
+}
+
+func TestBroker_ConsumerGroupRedelivery(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+
+	member1, unsubscribe1, err := broker.SubscribeGroup("telemetry", "collectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe1()
+	member2, unsubscribe2, err := broker.SubscribeGroup("telemetry", "collectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := broker.Publish("telemetry", Message{ID: "a", Payload: []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A nacked message moves to the other member
+	(<-member1).Nack(errors.New("failed"))
+	select {
+	case msg := <-member2:
+		if msg.ID != "a" || msg.Retries != 1 {
+			t.Errorf("Expected a redelivered to the other member, got %s retry %d", msg.ID, msg.Retries)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the nacked message redelivered to the other member")
+	}
+
+	// A group without members queues its messages for the next to join
+	unsubscribe1()
+	unsubscribe2()
+	if err := broker.Publish("telemetry", Message{ID: "b", Payload: []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+	joined, unsubscribe, err := broker.SubscribeGroup("telemetry", "collectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	ids := make(map[string]bool)
+	for _, msg := range receive(joined) {
+		ids[msg.ID] = true
+	}
+	if !ids["a"] || !ids["b"] {
+		t.Errorf("Expected the group's pending messages delivered on joining, got %v", ids)
+	}
+
+	// Only the group's copies were kept: it has no ungrouped subscribers
+	if stats, _ := broker.GetTopicStats("telemetry"); stats.PendingMessages != 2 {
+		t.Errorf("Expected 2 pending messages, got %d", stats.PendingMessages)
+	}
+	if _, _, err := broker.SubscribeGroup("telemetry", ""); err == nil {
+		t.Error("Expected an empty group refused")
+	}
+}
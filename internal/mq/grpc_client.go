@@ -9,7 +9,9 @@ import (
 
 	pb "github.com/harishb93/telemetry-pipeline/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 // GRPCBrokerClient is a gRPC client for the MQ service
@@ -20,23 +22,38 @@ type GRPCBrokerClient struct {
 	ctx           context.Context
 	cancel        context.CancelFunc
 	subscriptions map[string]*grpcSubscription
+	protocol      *ProtocolGuard
+	credentials   *tokenCredentials
 	mu            sync.RWMutex
 }
 
 type grpcSubscription struct {
 	topic  string
+	group  string
 	msgCh  chan Message
 	stream pb.MQService_SubscribeClient
 	cancel context.CancelFunc
 	stopCh chan struct{}
 }
 
-// NewGRPCBrokerClient creates a new gRPC broker client
+// NewGRPCBrokerClient creates a new gRPC broker client that warns about an
+// incompatible server
 func NewGRPCBrokerClient(serverAddr string) (*GRPCBrokerClient, error) {
-	ctx, cancel := context.WithCancel(context.Background())
+	return NewGRPCBrokerClientWithGuard(serverAddr, defaultProtocolGuard())
+}
 
-	// Connect to gRPC server
-	conn, err := grpc.NewClient(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewGRPCBrokerClientWithGuard creates a new gRPC broker client checking the
+// server's protocol version with guard
+func NewGRPCBrokerClientWithGuard(serverAddr string, guard *ProtocolGuard) (*GRPCBrokerClient, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	credentials := &tokenCredentials{}
+
+	// Connect to gRPC server, exchanging protocol versions on every call
+	conn, err := grpc.NewClient(serverAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(credentials),
+		grpc.WithUnaryInterceptor(guard.unaryClientInterceptor()),
+		grpc.WithStreamInterceptor(guard.streamClientInterceptor()))
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to connect to gRPC server at %s: %w", serverAddr, err)
@@ -51,20 +68,45 @@ func NewGRPCBrokerClient(serverAddr string) (*GRPCBrokerClient, error) {
 		ctx:           ctx,
 		cancel:        cancel,
 		subscriptions: make(map[string]*grpcSubscription),
+		protocol:      guard,
+		credentials:   credentials,
 	}, nil
 }
 
+// SetToken sends token as a bearer token with every call, for services
+// with an ACL
+func (g *GRPCBrokerClient) SetToken(token string) {
+	g.credentials.set(token)
+}
+
+// ProtocolGuard returns the guard checking the server's protocol version
+func (g *GRPCBrokerClient) ProtocolGuard() *ProtocolGuard {
+	return g.protocol
+}
+
 // Publish publishes a message to a topic via gRPC
 func (g *GRPCBrokerClient) Publish(topic string, msg Message) error {
+	return g.PublishCtx(context.Background(), topic, msg)
+}
+
+// PublishCtx publishes a message to a topic via gRPC, abandoning the call
+// once ctx is done or the client is closed
+func (g *GRPCBrokerClient) PublishCtx(ctx context.Context, topic string, msg Message) error {
+	if err := ValidateTopic(topic); err != nil {
+		return err
+	}
+	ctx, cancel := joinContext(ctx, g.ctx)
+	defer cancel()
+
 	req := &pb.PublishRequest{
 		Topic:   topic,
 		Payload: msg.Payload,
-		Headers: make(map[string]string),
+		Headers: WireHeaders(msg.Headers),
 	}
 
-	resp, err := g.client.Publish(g.ctx, req)
+	resp, err := g.client.Publish(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to publish message via gRPC: %w", err)
+		return fmt.Errorf("failed to publish message via gRPC: %w", errorFromGRPC(err))
 	}
 
 	if !resp.Success {
@@ -79,8 +121,49 @@ func (g *GRPCBrokerClient) Subscribe(topic string) (chan []byte, func(), error)
 	return nil, nil, fmt.Errorf("Subscribe not supported in gRPC broker - use SubscribeWithAck")
 }
 
+// SubscribeCtx is not supported over gRPC - use SubscribeWithAckCtx
+func (g *GRPCBrokerClient) SubscribeCtx(ctx context.Context, topic string) (chan []byte, func(), error) {
+	return g.Subscribe(topic)
+}
+
 // SubscribeWithAck subscribes to a topic with acknowledgment via gRPC streaming
 func (g *GRPCBrokerClient) SubscribeWithAck(topic string) (chan Message, func(), error) {
+	return g.SubscribeWithAckCtx(context.Background(), topic)
+}
+
+// SubscribeWithAckCtx subscribes to a topic with acknowledgment via gRPC
+// streaming until ctx is done or the returned function is called
+func (g *GRPCBrokerClient) SubscribeWithAckCtx(ctx context.Context, topic string) (chan Message, func(), error) {
+	return g.subscribe(ctx, &pb.SubscribeRequest{
+		Topic:          topic,
+		ConsumerGroup:  DefaultConsumerGroup,
+		BatchSize:      10,
+		TimeoutSeconds: 30,
+	})
+}
+
+// SubscribeFrom subscribes to a topic outside any consumer group, first
+// replaying its persisted messages from offset on; see Broker.SubscribeFrom
+func (g *GRPCBrokerClient) SubscribeFrom(topic string, offset uint64) (chan Message, func(), error) {
+	return g.subscribe(context.Background(), &pb.SubscribeRequest{
+		Topic:          topic,
+		BatchSize:      10,
+		TimeoutSeconds: 30,
+		Replay:         true,
+		FromOffset:     offset,
+	})
+}
+
+// subscribe opens a Subscribe stream for req, delivering its messages on
+// the returned channel until ctx is done or the returned function is called
+func (g *GRPCBrokerClient) subscribe(ctx context.Context, req *pb.SubscribeRequest) (chan Message, func(), error) {
+	topic := req.Topic
+	if err := ValidateTopic(topic); err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -92,22 +175,14 @@ func (g *GRPCBrokerClient) SubscribeWithAck(topic string) (chan Message, func(),
 		return nil, nil, fmt.Errorf("too many subscriptions active")
 	}
 
-	// Create subscription context
-	subCtx, subCancel := context.WithCancel(g.ctx)
-
-	// Create subscription request
-	req := &pb.SubscribeRequest{
-		Topic:          topic,
-		ConsumerGroup:  "default",
-		BatchSize:      10,
-		TimeoutSeconds: 30,
-	}
+	// Create subscription context, ended by the caller or by Close
+	subCtx, subCancel := joinContext(ctx, g.ctx)
 
 	// Start gRPC stream
 	stream, err := g.client.Subscribe(subCtx, req)
 	if err != nil {
 		subCancel()
-		return nil, nil, fmt.Errorf("failed to create gRPC subscription for topic %s: %w", topic, err)
+		return nil, nil, fmt.Errorf("failed to create gRPC subscription for topic %s: %w", topic, errorFromGRPC(err))
 	}
 
 	// Create message channel and subscription
@@ -116,6 +191,7 @@ func (g *GRPCBrokerClient) SubscribeWithAck(topic string) (chan Message, func(),
 
 	subscription := &grpcSubscription{
 		topic:  topic,
+		group:  req.ConsumerGroup,
 		msgCh:  msgCh,
 		stream: stream,
 		cancel: subCancel,
@@ -140,7 +216,22 @@ func (g *GRPCBrokerClient) SubscribeWithAck(topic string) (chan Message, func(),
 		}
 	}
 
-	return msgCh, unsubscribe, nil
+	return msgCh, unsubscribeOnDone(ctx, unsubscribe), nil
+}
+
+// subscribeStream opens a Subscribe stream to topic as group, closed by
+// cancelling ctx. Unlike SubscribeWithAck, stream errors reach the caller.
+func (g *GRPCBrokerClient) subscribeStream(ctx context.Context, topic, group string) (pb.MQService_SubscribeClient, error) {
+	stream, err := g.client.Subscribe(ctx, &pb.SubscribeRequest{
+		Topic:          topic,
+		ConsumerGroup:  group,
+		BatchSize:      10,
+		TimeoutSeconds: 30,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC subscription for topic %s: %w", topic, errorFromGRPC(err))
+	}
+	return stream, nil
 }
 
 // receiveMessages handles receiving messages from gRPC stream
@@ -170,10 +261,7 @@ func (g *GRPCBrokerClient) receiveMessages(sub *grpcSubscription) {
 			}
 
 			// Convert protobuf message to internal message
-			msg := Message{
-				Payload: pbMsg.Payload,
-				Ack:     func() {}, // gRPC acknowledgment is handled automatically
-			}
+			msg := messageFromProto(pbMsg, g.ackFunc(sub.topic, sub.group, pbMsg.Id))
 
 			// Send to message channel
 			select {
@@ -189,6 +277,50 @@ func (g *GRPCBrokerClient) receiveMessages(sub *grpcSubscription) {
 	}
 }
 
+// messageFromProto converts a message received from the MQ service,
+// acknowledged by calling ack
+func messageFromProto(pbMsg *pb.Message, ack func()) Message {
+	msg := Message{
+		ID:      pbMsg.Id,
+		Retries: int(pbMsg.RetryCount),
+		Offset:  pbMsg.Offset,
+		Payload: pbMsg.Payload,
+		Headers: MessageHeaders(pbMsg.Headers),
+		Ack:     ack,
+	}
+	if pbMsg.PublishedAtUnixNano != 0 {
+		msg.Timestamp = time.Unix(0, pbMsg.PublishedAtUnixNano)
+	}
+	return msg
+}
+
+// ackFunc returns a function acknowledging a message streamed to group with
+// the Ack RPC. A failed acknowledgment is logged; the server redelivers the
+// message once its ack timeout passes.
+func (g *GRPCBrokerClient) ackFunc(topic, group, id string) func() {
+	return func() {
+		if err := g.ack(topic, group, id); err != nil {
+			fmt.Printf("Warning: failed to acknowledge message %s of topic %s: %v\n", id, topic, err)
+		}
+	}
+}
+
+// ack acknowledges a message streamed to group
+func (g *GRPCBrokerClient) ack(topic, group, id string) error {
+	ctx, cancel := context.WithTimeout(g.ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := g.client.Ack(ctx, &pb.AckRequest{Topic: topic, MessageId: id, ConsumerGroup: group})
+	if status.Code(err) == codes.Unimplemented {
+		// Servers predating the Ack RPC acknowledge messages as they send them
+		return nil
+	}
+	if err != nil {
+		return errorFromGRPC(err)
+	}
+	return nil
+}
+
 // Close closes the gRPC connection and all subscriptions
 func (g *GRPCBrokerClient) Close() {
 	g.mu.Lock()
@@ -238,11 +370,17 @@ func (g *GRPCBrokerClient) GetStats() (map[string]interface{}, error) {
 	topics := make(map[string]interface{})
 	for topicName, topicStats := range resp.Topics {
 		topics[topicName] = map[string]interface{}{
-			"queue_size":         topicStats.QueueSize,
-			"subscriber_count":   topicStats.SubscriberCount,
-			"pending_messages":   topicStats.PendingMessages,
-			"published_messages": topicStats.PublishedMessages,
-			"consumed_messages":  topicStats.ConsumedMessages,
+			"queue_size":              topicStats.QueueSize,
+			"subscriber_count":        topicStats.SubscriberCount,
+			"pending_messages":        topicStats.PendingMessages,
+			"published_messages":      topicStats.PublishedMessages,
+			"consumed_messages":       topicStats.ConsumedMessages,
+			"delivered_messages":      topicStats.DeliveredMessages,
+			"redelivered_messages":    topicStats.RedeliveredMessages,
+			"dead_lettered_messages":  topicStats.DeadLetteredMessages,
+			"dropped_oldest_messages": topicStats.DroppedOldestMessages,
+			"dropped_newest_messages": topicStats.DroppedNewestMessages,
+			"rejected_messages":       topicStats.RejectedMessages,
 		}
 	}
 	stats["topics"] = topics
@@ -250,5 +388,17 @@ func (g *GRPCBrokerClient) GetStats() (map[string]interface{}, error) {
 	return stats, nil
 }
 
+// QueueSize returns the number of messages in a topic's queue on the server
+func (g *GRPCBrokerClient) QueueSize(ctx context.Context, topic string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := g.client.GetStats(ctx, &pb.StatsRequest{})
+	if err != nil {
+		return 0, errorFromGRPC(err)
+	}
+	return int(resp.Topics[topic].GetQueueSize()), nil
+}
+
 // Ensure GRPCBrokerClient implements BrokerInterface
 var _ BrokerInterface = (*GRPCBrokerClient)(nil)
@@ -2,30 +2,100 @@ package mq
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
 )
 
+// DefaultHTTPClientConfig returns the connection settings of HTTPBroker and
+// DirectBrokerClient: requests time out after 30s, and up to 100 idle
+// connections are kept for 90s so concurrent publishers reuse connections
+// instead of dialling the MQ service for each publish
+func DefaultHTTPClientConfig() netutil.ClientConfig {
+	return netutil.ClientConfig{
+		Timeout:         30 * time.Second,
+		DialTimeout:     5 * time.Second,
+		KeepAlive:       30 * time.Second,
+		MaxIdleConns:    100,
+		IdleConnTimeout: 90 * time.Second,
+	}
+}
+
 // HTTPBroker is a client for connecting to a remote MQ broker via HTTP
 type HTTPBroker struct {
-	baseURL string
-	client  *http.Client
+	baseURL  string
+	client   *http.Client
+	protocol *ProtocolGuard
+	token    string
+
+	// refused is set once a strict guard has refused the server, so no
+	// further messages are sent to it
+	refusedMu sync.Mutex
+	refused   error
 }
 
-// NewHTTPBroker creates a new HTTP broker client
+// NewHTTPBroker creates a new HTTP broker client that warns about an
+// incompatible server. baseURL may be a unix: socket address.
 func NewHTTPBroker(baseURL string) *HTTPBroker {
+	return NewHTTPBrokerWithGuard(baseURL, defaultProtocolGuard())
+}
+
+// NewHTTPBrokerWithGuard creates a new HTTP broker client checking the
+// server's protocol version with guard
+func NewHTTPBrokerWithGuard(baseURL string, guard *ProtocolGuard) *HTTPBroker {
+	return NewHTTPBrokerWithConfig(baseURL, guard, DefaultHTTPClientConfig())
+}
+
+// NewHTTPBrokerWithConfig creates a new HTTP broker client checking the
+// server's protocol version with guard, its connections tuned by config
+func NewHTTPBrokerWithConfig(baseURL string, guard *ProtocolGuard, config netutil.ClientConfig) *HTTPBroker {
+	client, baseURL := netutil.NewHTTPClient(baseURL, config)
 	return &HTTPBroker{
-		baseURL: baseURL,
-		client:  &http.Client{},
+		baseURL:  baseURL,
+		client:   client,
+		protocol: guard,
 	}
 }
 
 // Publish publishes a message to a topic via HTTP
 func (h *HTTPBroker) Publish(topic string, msg Message) error {
+	return h.PublishCtx(context.Background(), topic, msg)
+}
+
+// PublishCtx publishes a message to a topic via HTTP, abandoning the request
+// once ctx is done
+func (h *HTTPBroker) PublishCtx(ctx context.Context, topic string, msg Message) error {
+	h.refusedMu.Lock()
+	refused := h.refused
+	h.refusedMu.Unlock()
+	if refused != nil {
+		return refused
+	}
+	if err := ValidateTopic(topic); err != nil {
+		return err
+	}
+
 	url := fmt.Sprintf("%s/publish/%s", h.baseURL, topic)
 
 	// Send the payload directly as JSON (it's already JSON from the streamer)
-	resp, err := h.client.Post(url, "application/json", bytes.NewBuffer(msg.Payload))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(msg.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to create publish request for %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setHTTPMessageHeaders(req.Header, msg.Headers)
+	req.Header.Set(ProtocolHeader, ProtocolVersion)
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to publish to %s: %w", url, err)
 	}
@@ -36,13 +106,34 @@ func (h *HTTPBroker) Publish(topic string, msg Message) error {
 		}
 	}()
 
+	// The server's version arrives with its first response; an
+	// incompatible server is refused from then on
+	if err := h.protocol.Check(h.baseURL, resp.Header.Get(ProtocolHeader)); err != nil {
+		err = fmt.Errorf("refusing to publish to %s: %w", h.baseURL, err)
+		h.refusedMu.Lock()
+		h.refused = err
+		h.refusedMu.Unlock()
+		return err
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("publish failed with status %d", resp.StatusCode)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("publish failed: %w", errorFromHTTPStatus(resp.StatusCode, strings.TrimSpace(string(body))))
 	}
 
 	return nil
 }
 
+// SetToken sends token as a bearer token with every request, for services
+// with an ACL. Call it before publishing.
+func (h *HTTPBroker) SetToken(token string) {
+	h.token = token
+}
+
+// ProtocolGuard returns the guard checking the server's protocol version
+func (h *HTTPBroker) ProtocolGuard() *ProtocolGuard {
+	return h.protocol
+}
+
 // Subscribe is not implemented for HTTP broker (would require websockets or polling)
 func (h *HTTPBroker) Subscribe(topic string) (chan []byte, func(), error) {
 	return nil, nil, fmt.Errorf("Subscribe not supported in HTTP broker")
@@ -53,6 +144,16 @@ func (h *HTTPBroker) SubscribeWithAck(topic string) (chan Message, func(), error
 	return nil, nil, fmt.Errorf("SubscribeWithAck not supported in HTTP broker")
 }
 
+// SubscribeCtx is not implemented for HTTP broker
+func (h *HTTPBroker) SubscribeCtx(ctx context.Context, topic string) (chan []byte, func(), error) {
+	return h.Subscribe(topic)
+}
+
+// SubscribeWithAckCtx is not implemented for HTTP broker
+func (h *HTTPBroker) SubscribeWithAckCtx(ctx context.Context, topic string) (chan Message, func(), error) {
+	return h.SubscribeWithAck(topic)
+}
+
 // Close closes the HTTP client
 func (h *HTTPBroker) Close() {
 	// HTTP client doesn't need explicit closing
@@ -0,0 +1,86 @@
+package mq
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// crockford is the ULID alphabet: Crockford's base32, which leaves out
+// letters easily mistaken for digits
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// idGenerator generates ULIDs: a 48-bit millisecond timestamp followed by 80
+// random bits, so IDs sort by publish time. Within a millisecond the random
+// part is incremented rather than redrawn, so IDs from one generator are
+// strictly increasing and cannot collide however fast messages are
+// published.
+type idGenerator struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	lastMS  uint64
+	entropy [10]byte
+}
+
+// messageIDs generates the IDs of messages published to this process's
+// brokers and services
+var messageIDs = &idGenerator{now: time.Now}
+
+// NewMessageID returns a new unique message ID, a 26-character ULID such as
+// 01K0EXAMPLE0000000000000000
+func NewMessageID() string {
+	return messageIDs.next()
+}
+
+// next returns the next ID
+func (g *idGenerator) next() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms > g.lastMS || !increment(g.entropy[:]) {
+		// A new millisecond, or the random part overflowed: draw afresh.
+		// After an overflow the timestamp is advanced to stay monotonic.
+		if ms <= g.lastMS {
+			ms = g.lastMS + 1
+		}
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			panic("mq: failed to read random bytes for message ID: " + err.Error())
+		}
+		g.lastMS = ms
+	}
+
+	var id [16]byte
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], g.lastMS)
+	copy(id[:6], timestamp[2:])
+	copy(id[6:], g.entropy[:])
+	return encodeULID(id)
+}
+
+// increment adds one to a big-endian number, reporting false on overflow
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeULID encodes 128 bits as 26 base32 characters, the first carrying
+// the top 3 bits
+func encodeULID(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
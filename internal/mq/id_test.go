@@ -0,0 +1,161 @@
+package mq
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewMessageID_UniqueAndOrdered(t *testing.T) {
+	const goroutines, perGoroutine = 8, 5000
+	var mu sync.Mutex
+	seen := make(map[string]bool, goroutines*perGoroutine)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			previous := ""
+			for i := 0; i < perGoroutine; i++ {
+				id := NewMessageID()
+				if id <= previous {
+					t.Errorf("Expected increasing IDs, got %s after %s", id, previous)
+					return
+				}
+				previous = id
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != goroutines*perGoroutine {
+		t.Errorf("Expected %d unique IDs, got %d", goroutines*perGoroutine, len(seen))
+	}
+	for id := range seen {
+		if len(id) != 26 || strings.Trim(id, crockford) != "" {
+			t.Fatalf("Expected a 26-character ULID, got %q", id)
+		}
+		break
+	}
+}
+
+func TestIDGenerator_Monotonic(t *testing.T) {
+	now := time.UnixMilli(1752870154000)
+	g := &idGenerator{now: func() time.Time { return now }}
+
+	first := g.next()
+	var timestamp [16]byte
+	binary.BigEndian.PutUint64(timestamp[:8], uint64(now.UnixMilli())<<16)
+	if !strings.HasPrefix(first, encodeULID(timestamp)[:10]) {
+		t.Errorf("Expected the timestamp in the first 10 characters, got %s", first)
+	}
+
+	// Within a millisecond, and when the clock steps back, IDs keep rising
+	second := g.next()
+	now = now.Add(-time.Second)
+	third := g.next()
+	if !(first < second && second < third) {
+		t.Errorf("Expected increasing IDs, got %s, %s, %s", first, second, third)
+	}
+
+	// Exhausting the random part moves on to the next millisecond
+	for i := range g.entropy {
+		g.entropy[i] = 0xff
+	}
+	lastMS := g.lastMS
+	if next := g.next(); next <= third || g.lastMS != lastMS+1 {
+		t.Errorf("Expected overflow to advance the timestamp, got %s at %d", next, g.lastMS)
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	if got := encodeULID([16]byte{}); got != "00000000000000000000000000" {
+		t.Errorf("Expected zeros, got %s", got)
+	}
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := encodeULID(max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("Expected the largest ULID, got %s", got)
+	}
+}
+
+func TestBroker_MessageIDs(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.AckTimeout = 50 * time.Millisecond
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	ch, unsubscribe, err := broker.SubscribeWithAck("ids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	if err := broker.Publish("ids", Message{Payload: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish("ids", Message{ID: "edge-1", Payload: []byte("b")}); err != nil {
+		t.Fatal(err)
+	}
+
+	assigned := <-ch
+	if len(assigned.ID) != 26 {
+		t.Errorf("Expected the broker to assign a ULID, got %q", assigned.ID)
+	}
+	supplied := <-ch
+	if supplied.ID != "edge-1" {
+		t.Errorf("Expected the publisher's ID kept, got %q", supplied.ID)
+	}
+	supplied.Ack()
+
+	// An unacknowledged message is redelivered with the same ID
+	time.Sleep(2 * config.AckTimeout)
+	broker.processAckTimeouts()
+	select {
+	case redelivered := <-ch:
+		if redelivered.ID != assigned.ID {
+			t.Errorf("Expected redelivery to keep ID %s, got %s", assigned.ID, redelivered.ID)
+		}
+		redelivered.Ack()
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the unacknowledged message to be redelivered")
+	}
+}
+
+func TestBroker_DeliveryMetadata(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.AckTimeout = 50 * time.Millisecond
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	ch, unsubscribe, err := broker.SubscribeWithAck("metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	before := time.Now()
+	if err := broker.Publish("metadata", Message{Payload: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+	first := <-ch
+	if first.Timestamp.Before(before) || first.Timestamp.After(time.Now()) || first.Retries != 0 {
+		t.Errorf("Expected the publish time and no retries, got %v and %d", first.Timestamp, first.Retries)
+	}
+
+	// Redeliveries count up and keep the original publish time
+	for want := 1; want <= 2; want++ {
+		time.Sleep(2 * config.AckTimeout)
+		broker.processAckTimeouts()
+		redelivered := <-ch
+		if redelivered.Retries != want || !redelivered.Timestamp.Equal(first.Timestamp) {
+			t.Errorf("Expected retry %d published at %v, got %d at %v", want, first.Timestamp, redelivered.Retries, redelivered.Timestamp)
+		}
+	}
+}
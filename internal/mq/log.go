@@ -0,0 +1,772 @@
+package mq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// Topic logs (persistence format version 2) keep each topic's messages in a
+// directory of segments named by the offset of their first message:
+//
+//	<topic>/<base offset, 20 digits>.log  frames in offset order
+//	<topic>/<base offset, 20 digits>.idx  sparse index into the frames
+//
+// A frame is
+//
+//	<body length uint32> <CRC-32C of body uint32> <body>
+//
+// and a body holds the message's offset (uint64), timestamp (int64 Unix
+// nanoseconds), flags (uint8), ID length (uint16) and ID, then its content:
+// the header count (uint16), each header's key length (uint16), key, value
+// length (uint32) and value, and the payload. With flagSealed the content is
+// an encryption token instead. Integers are big-endian.
+//
+// The index holds an <offset uint64> <position uint64> entry about every
+// indexInterval bytes of frames, so reads from an offset and recovery after a
+// crash skip most of a segment. Offsets increase by one per message and are
+// never reused, even once compaction has removed the messages.
+
+const (
+	segmentExt      = ".log"
+	indexExt        = ".idx"
+	frameHeaderSize = 8
+	indexEntrySize  = 16
+	// maxSegmentBytes is the size at which a new segment is started
+	maxSegmentBytes = 64 << 20
+	// indexInterval is roughly how many bytes of frames each index entry covers
+	indexInterval = 4096
+	// maxFrameBytes bounds frame bodies, so a corrupt length is not allocated
+	maxFrameBytes = 256 << 20
+	// flagSealed marks content encrypted with the broker's keyring
+	flagSealed = 1
+)
+
+// legacyLogFile is the JSON-lines log of each topic in format version 1
+const legacyLogFile = "messages.log"
+
+// crcTable is the Castagnoli table used for frame checksums
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornFrame marks a frame cut short by a crash mid-write
+var errTornFrame = errors.New("incomplete frame")
+
+// PersistenceFormat is the layout of a broker persistence directory. Version
+// 1 kept a messages.log of JSON lines per topic; version 2 keeps topic logs.
+// keyring opens encrypted version 1 logs while they are converted.
+func PersistenceFormat(keyring *encryption.Keyring) *persistence.Format {
+	return &persistence.Format{
+		Name: "mq",
+		Migrations: []persistence.Migration{
+			{Version: 2, Description: "convert JSON-lines logs to topic logs", Migrate: func(dir string, progress func(done, total int)) error {
+				return convertLegacyLogs(dir, keyring, progress)
+			}},
+		},
+		Legacy: hasLegacyLogs,
+	}
+}
+
+// hasLegacyLogs reports whether dir holds any version 1 topic logs
+func hasLegacyLogs(dir string) (bool, error) {
+	logs, err := filepath.Glob(filepath.Join(dir, "*", legacyLogFile))
+	return len(logs) > 0, err
+}
+
+// logRecord is a message as stored in a topic log
+type logRecord struct {
+	Offset    uint64
+	ID        string
+	Timestamp time.Time
+	Headers   map[string]string
+	Payload   []byte
+}
+
+// encodeFrame frames a record, sealing its content when keyring is set
+func encodeFrame(record logRecord, keyring *encryption.Keyring) ([]byte, error) {
+	keys := make([]string, 0, len(record.Headers))
+	for key := range record.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	content := binary.BigEndian.AppendUint16(nil, uint16(len(keys)))
+	for _, key := range keys {
+		content = binary.BigEndian.AppendUint16(content, uint16(len(key)))
+		content = append(content, key...)
+		content = binary.BigEndian.AppendUint32(content, uint32(len(record.Headers[key])))
+		content = append(content, record.Headers[key]...)
+	}
+	content = append(content, record.Payload...)
+
+	var flags byte
+	if keyring != nil {
+		sealed, err := keyring.Encrypt(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt message: %w", err)
+		}
+		content = sealed
+		flags |= flagSealed
+	}
+
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+19+len(record.ID)+len(content))
+	frame = binary.BigEndian.AppendUint64(frame, record.Offset)
+	frame = binary.BigEndian.AppendUint64(frame, uint64(record.Timestamp.UnixNano()))
+	frame = append(frame, flags)
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(record.ID)))
+	frame = append(frame, record.ID...)
+	frame = append(frame, content...)
+
+	body := frame[frameHeaderSize:]
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(body, crcTable))
+	return frame, nil
+}
+
+// readFrame reads the next frame from r and returns its body and size. It
+// returns io.EOF at a clean end and errTornFrame for a truncated frame.
+func readFrame(r io.Reader) ([]byte, int64, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, 0, errTornFrame
+		}
+		return nil, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length < 19 || length > maxFrameBytes {
+		return nil, 0, fmt.Errorf("invalid frame length %d", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, 0, errTornFrame
+		}
+		return nil, 0, err
+	}
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, fmt.Errorf("frame checksum mismatch")
+	}
+	return body, int64(frameHeaderSize + length), nil
+}
+
+// frameOffset returns the offset of the message in a frame body
+func frameOffset(body []byte) uint64 {
+	return binary.BigEndian.Uint64(body[0:8])
+}
+
+// frameContent splits a frame body into its flags and content
+func frameContent(body []byte) (byte, []byte, error) {
+	idLength := int(binary.BigEndian.Uint16(body[17:19]))
+	if 19+idLength > len(body) {
+		return 0, nil, fmt.Errorf("invalid message ID length %d", idLength)
+	}
+	return body[16], body[19+idLength:], nil
+}
+
+// decodeFrame decodes a frame body, opening sealed content with keyring
+func decodeFrame(body []byte, keyring *encryption.Keyring) (logRecord, error) {
+	flags, content, err := frameContent(body)
+	if err != nil {
+		return logRecord{}, err
+	}
+	record := logRecord{
+		Offset:    frameOffset(body),
+		Timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(body[8:16]))),
+		ID:        string(body[19 : len(body)-len(content)]),
+	}
+
+	if flags&flagSealed != 0 {
+		if keyring == nil {
+			return logRecord{}, fmt.Errorf("message %d is encrypted but no encryption key is configured", record.Offset)
+		}
+		if content, err = keyring.Decrypt(content); err != nil {
+			return logRecord{}, fmt.Errorf("failed to decrypt message %d: %w", record.Offset, err)
+		}
+	}
+
+	if len(content) < 2 {
+		return logRecord{}, fmt.Errorf("message %d has no header count", record.Offset)
+	}
+	count := int(binary.BigEndian.Uint16(content))
+	content = content[2:]
+	for i := 0; i < count; i++ {
+		if len(content) < 2 {
+			return logRecord{}, fmt.Errorf("message %d has a truncated header", record.Offset)
+		}
+		keyLength := int(binary.BigEndian.Uint16(content))
+		if len(content) < 2+keyLength+4 {
+			return logRecord{}, fmt.Errorf("message %d has a truncated header", record.Offset)
+		}
+		key := string(content[2 : 2+keyLength])
+		valueLength := int(binary.BigEndian.Uint32(content[2+keyLength:]))
+		content = content[2+keyLength+4:]
+		if len(content) < valueLength {
+			return logRecord{}, fmt.Errorf("message %d has a truncated header", record.Offset)
+		}
+		if record.Headers == nil {
+			record.Headers = make(map[string]string, count)
+		}
+		record.Headers[key] = string(content[:valueLength])
+		content = content[valueLength:]
+	}
+	record.Payload = append([]byte(nil), content...)
+	return record, nil
+}
+
+// topicLog appends a topic's messages to its segments. It is not safe for
+// concurrent use; the broker serializes access under its lock.
+type topicLog struct {
+	dir     string
+	keyring *encryption.Keyring
+	// bases are the base offsets of the segments, oldest first; the last is
+	// the active segment
+	bases   []uint64
+	log     *os.File
+	index   *os.File
+	size    int64 // bytes in the active segment
+	indexed int64 // position of the active segment's last index entry
+	next    uint64
+	// acks is the open acks file (see replay.go), once a copy was resolved
+	acks *os.File
+}
+
+// openTopicLog opens the log in dir, creating it if needed. A frame torn by
+// a crash at the end of the active segment is truncated.
+func openTopicLog(dir string, keyring *encryption.Keyring) (*topicLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create topic log directory: %w", err)
+	}
+	bases, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &topicLog{dir: dir, keyring: keyring}
+	if len(bases) == 0 {
+		if err := l.createSegment(0); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	l.bases = bases
+	active := bases[len(bases)-1]
+	if l.size, l.indexed, l.next, err = recoverSegment(dir, active); err != nil {
+		return nil, err
+	}
+	if err := l.openSegment(active); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// listSegments returns the base offsets of the segments in dir, sorted
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list topic log %s: %w", dir, err)
+	}
+
+	var bases []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if base, err := strconv.ParseUint(name, 10, 64); err == nil {
+			bases = append(bases, base)
+		}
+	}
+	sort.Slice(bases, func(i, j int) bool { return bases[i] < bases[j] })
+	return bases, nil
+}
+
+// segmentPath returns the path of a segment file with the given extension
+func segmentPath(dir string, base uint64, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", base, ext))
+}
+
+// readIndex returns a segment's index entries, ignoring a torn final entry
+func readIndex(dir string, base uint64) ([][2]uint64, error) {
+	data, err := os.ReadFile(segmentPath(dir, base, indexExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	entries := make([][2]uint64, len(data)/indexEntrySize)
+	for i := range entries {
+		entry := data[i*indexEntrySize:]
+		entries[i] = [2]uint64{binary.BigEndian.Uint64(entry), binary.BigEndian.Uint64(entry[8:])}
+	}
+	return entries, nil
+}
+
+// recoverSegment finds the end of a segment's valid frames, starting from
+// its last index entry that still points at the frame it names, and
+// truncates anything after them along with index entries past that entry.
+// It returns the segment's size, the position of its last index entry and
+// the offset following its last message.
+func recoverSegment(dir string, base uint64) (int64, int64, uint64, error) {
+	logPath := segmentPath(dir, base, segmentExt)
+	file, err := os.OpenFile(logPath, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to open segment %s: %w", logPath, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", err)
+		}
+	}()
+
+	entries, err := readIndex(dir, base)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var start int64
+	kept := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		offset, position := entries[i][0], int64(entries[i][1])
+		if _, err := file.Seek(position, io.SeekStart); err != nil {
+			return 0, 0, 0, err
+		}
+		if body, _, err := readFrame(file); err == nil && frameOffset(body) == offset {
+			start, kept = position, i+1
+			break
+		}
+	}
+	if kept < len(entries) {
+		if err := os.Truncate(segmentPath(dir, base, indexExt), int64(kept*indexEntrySize)); err != nil {
+			return 0, 0, 0, fmt.Errorf("failed to truncate index of %s: %w", logPath, err)
+		}
+	}
+
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return 0, 0, 0, err
+	}
+	reader := bufio.NewReader(file)
+	end, next := start, base
+	for {
+		body, n, err := readFrame(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Printf("Warning: truncating segment %s at %d: %v\n", logPath, end, err)
+			if err := file.Truncate(end); err != nil {
+				return 0, 0, 0, fmt.Errorf("failed to truncate segment %s: %w", logPath, err)
+			}
+			break
+		}
+		end += n
+		next = frameOffset(body) + 1
+	}
+	return end, start, next, nil
+}
+
+// createSegment starts a new, empty active segment at base
+func (l *topicLog) createSegment(base uint64) error {
+	for _, ext := range []string{segmentExt, indexExt} {
+		file, err := os.OpenFile(segmentPath(l.dir, base, ext), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create segment: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	l.bases = append(l.bases, base)
+	l.size, l.indexed, l.next = 0, 0, base
+	return l.openSegment(base)
+}
+
+// openSegment opens a segment's files for appending as the active segment
+func (l *topicLog) openSegment(base uint64) error {
+	log, err := os.OpenFile(segmentPath(l.dir, base, segmentExt), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+	index, err := os.OpenFile(segmentPath(l.dir, base, indexExt), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		_ = log.Close()
+		return fmt.Errorf("failed to open index: %w", err)
+	}
+	l.log, l.index = log, index
+	return nil
+}
+
+// append writes a message at the next offset and returns the offset
+func (l *topicLog) append(record logRecord) (uint64, error) {
+	if l.size >= maxSegmentBytes {
+		if err := l.roll(); err != nil {
+			return 0, err
+		}
+	}
+
+	record.Offset = l.next
+	frame, err := encodeFrame(record, l.keyring)
+	if err != nil {
+		return 0, err
+	}
+
+	if l.size-l.indexed >= indexInterval {
+		entry := binary.BigEndian.AppendUint64(nil, record.Offset)
+		entry = binary.BigEndian.AppendUint64(entry, uint64(l.size))
+		if _, err := l.index.Write(entry); err != nil {
+			return 0, fmt.Errorf("failed to write index: %w", err)
+		}
+		l.indexed = l.size
+	}
+	if _, err := l.log.Write(frame); err != nil {
+		return 0, err
+	}
+	l.size += int64(len(frame))
+	l.next++
+	return record.Offset, nil
+}
+
+// roll closes the active segment and starts a new one at the next offset
+func (l *topicLog) roll() error {
+	if err := l.closeSegment(); err != nil {
+		return err
+	}
+	return l.createSegment(l.next)
+}
+
+// read calls fn for each message from offset on, in offset order, stopping
+// at the first error fn returns
+func (l *topicLog) read(from uint64, fn func(logRecord) error) error {
+	first := 0
+	for i, base := range l.bases {
+		if base <= from {
+			first = i
+		}
+	}
+
+	for i := first; i < len(l.bases); i++ {
+		base := l.bases[i]
+		var start int64
+		if i == first {
+			entries, err := readIndex(l.dir, base)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if entry[0] > from {
+					break
+				}
+				start = int64(entry[1])
+			}
+		}
+		if err := l.readSegment(base, start, from, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSegment calls fn for each message in a segment from position start
+// with an offset of at least from
+func (l *topicLog) readSegment(base uint64, start int64, from uint64, fn func(logRecord) error) error {
+	file, err := os.Open(segmentPath(l.dir, base, segmentExt))
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", err)
+		}
+	}()
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		body, _, err := readFrame(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read segment %s: %w", file.Name(), err)
+		}
+		if frameOffset(body) < from {
+			continue
+		}
+		record, err := decodeFrame(body, l.keyring)
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// compact removes the segments holding only messages before offset before,
+// starting a new segment first if that includes the active one, and returns
+// how many it removed
+func (l *topicLog) compact(before uint64) (int, error) {
+	if l.next <= before && l.size > 0 {
+		if err := l.roll(); err != nil {
+			return 0, err
+		}
+	}
+
+	removed := 0
+	for len(l.bases) > 1 && l.bases[1] <= before {
+		for _, ext := range []string{segmentExt, indexExt} {
+			if err := os.Remove(segmentPath(l.dir, l.bases[0], ext)); err != nil && !os.IsNotExist(err) {
+				return removed, fmt.Errorf("failed to remove segment: %w", err)
+			}
+		}
+		l.bases = l.bases[1:]
+		removed++
+	}
+	return removed, nil
+}
+
+// closeSegment closes the active segment's files
+func (l *topicLog) closeSegment() error {
+	err := l.log.Close()
+	if indexErr := l.index.Close(); err == nil {
+		err = indexErr
+	}
+	return err
+}
+
+// close closes the log
+func (l *topicLog) close() error {
+	err := l.closeSegment()
+	if l.acks != nil {
+		if acksErr := l.acks.Close(); err == nil {
+			err = acksErr
+		}
+	}
+	return err
+}
+
+// writeSegment writes records as the only segment of the topic log in dir,
+// replacing any segments there
+func writeSegment(dir string, records []logRecord, keyring *encryption.Keyring) error {
+	var base uint64
+	if len(records) > 0 {
+		base = records[0].Offset
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create topic log directory: %w", err)
+	}
+	existing, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := rewriteSegment(dir, base, records, keyring); err != nil {
+		return err
+	}
+	for _, old := range existing {
+		if old == base {
+			continue
+		}
+		for _, ext := range []string{segmentExt, indexExt} {
+			if err := os.Remove(segmentPath(dir, old, ext)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove segment: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// rewriteSegment replaces the segment at base and its index with records
+func rewriteSegment(dir string, base uint64, records []logRecord, keyring *encryption.Keyring) error {
+	var log, index bytes.Buffer
+	var indexed int64
+	for _, record := range records {
+		frame, err := encodeFrame(record, keyring)
+		if err != nil {
+			return err
+		}
+		if size := int64(log.Len()); size-indexed >= indexInterval {
+			entry := binary.BigEndian.AppendUint64(nil, record.Offset)
+			index.Write(binary.BigEndian.AppendUint64(entry, uint64(size)))
+			indexed = size
+		}
+		log.Write(frame)
+	}
+
+	// An index left ahead of its segment by a crash is repaired on recovery
+	for _, file := range []struct {
+		path string
+		data []byte
+	}{
+		{segmentPath(dir, base, indexExt), index.Bytes()},
+		{segmentPath(dir, base, segmentExt), log.Bytes()},
+	} {
+		if err := persistence.WriteFileAtomic(file.path, func(w io.Writer) error {
+			_, err := w.Write(file.data)
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.path, err)
+		}
+	}
+	return nil
+}
+
+// convertLegacyLogs converts every version 1 topic log in dir to a topic log,
+// numbering its messages from offset 0. A torn final line is dropped.
+func convertLegacyLogs(dir string, keyring *encryption.Keyring, progress func(done, total int)) error {
+	logs, err := filepath.Glob(filepath.Join(dir, "*", legacyLogFile))
+	if err != nil {
+		return err
+	}
+
+	progress(0, len(logs))
+	for i, logPath := range logs {
+		data, err := os.ReadFile(logPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", logPath, err)
+		}
+
+		var records []logRecord
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			plaintext, err := keyring.Open(line)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", logPath, err)
+			}
+			var legacy struct {
+				ID        string `json:"id"`
+				Timestamp int64  `json:"timestamp"`
+				Payload   []byte `json:"payload"`
+			}
+			if err := json.Unmarshal(plaintext, &legacy); err != nil {
+				fmt.Printf("Warning: skipping unreadable line in %s: %v\n", logPath, err)
+				continue
+			}
+			records = append(records, logRecord{
+				Offset:    uint64(len(records)),
+				ID:        legacy.ID,
+				Timestamp: time.Unix(legacy.Timestamp, 0),
+				Payload:   legacy.Payload,
+			})
+		}
+
+		// The legacy log goes last, so an interrupted conversion is redone
+		if err := writeSegment(filepath.Dir(logPath), records, keyring); err != nil {
+			return err
+		}
+		if err := os.Remove(logPath); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", logPath, err)
+		}
+		progress(i+1, len(logs))
+	}
+	return nil
+}
+
+// ReencryptPersistence rewrites every topic log under persistenceDir so all
+// messages are sealed with the active key of keyring, migrating plaintext
+// messages and messages sealed with rotated-out keys. Segments that are
+// already current are left untouched. It returns the number of messages
+// rewritten. The broker must not be running against persistenceDir, which
+// must be at the current format version.
+func ReencryptPersistence(persistenceDir string, keyring *encryption.Keyring) (int, error) {
+	if keyring == nil {
+		return 0, fmt.Errorf("no encryption key configured")
+	}
+
+	topics, err := os.ReadDir(persistenceDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	total := 0
+	for _, topic := range topics {
+		if !topic.IsDir() {
+			continue
+		}
+		dir := filepath.Join(persistenceDir, topic.Name())
+		bases, err := listSegments(dir)
+		if err != nil {
+			return total, err
+		}
+		for _, base := range bases {
+			n, err := reencryptSegment(dir, base, keyring)
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// reencryptSegment rewrites a single segment with the active key. A torn
+// final frame is dropped.
+func reencryptSegment(dir string, base uint64, keyring *encryption.Keyring) (int, error) {
+	logPath := segmentPath(dir, base, segmentExt)
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", logPath, err)
+	}
+
+	var records []logRecord
+	stale := 0
+	torn := false
+	reader := bytes.NewReader(data)
+	for {
+		body, _, err := readFrame(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Printf("Warning: dropping the rest of %s: %v\n", logPath, err)
+			torn = true
+			break
+		}
+		flags, content, err := frameContent(body)
+		if err != nil {
+			return 0, fmt.Errorf("invalid frame in %s: %w", logPath, err)
+		}
+		if flags&flagSealed == 0 || keyring.NeedsRotation(content) {
+			stale++
+		}
+		record, err := decodeFrame(body, keyring)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode %s: %w", logPath, err)
+		}
+		records = append(records, record)
+	}
+	if stale == 0 && !torn {
+		return 0, nil
+	}
+
+	if err := rewriteSegment(dir, base, records, keyring); err != nil {
+		return 0, err
+	}
+	return stale, nil
+}
@@ -0,0 +1,241 @@
+package mq
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption/encryptiontest"
+)
+
+// readAll returns the offsets and payloads of a log's messages from offset on
+func readAll(t *testing.T, l *topicLog, from uint64) ([]uint64, []string) {
+	t.Helper()
+	var offsets []uint64
+	var payloads []string
+	if err := l.read(from, func(record logRecord) error {
+		offsets = append(offsets, record.Offset)
+		payloads = append(payloads, string(record.Payload))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return offsets, payloads
+}
+
+func TestTopicLog_AppendReadReopen(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openTopicLog(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := time.Date(2025, 7, 18, 20, 42, 34, 5, time.UTC)
+	for i := 0; i < 500; i++ {
+		record := logRecord{ID: fmt.Sprintf("msg-%d", i), Timestamp: timestamp, Payload: []byte(fmt.Sprintf("payload %d", i))}
+		if i == 7 {
+			record.Headers = map[string]string{"source": "host-1", "worker": "3"}
+		}
+		if offset, err := l.append(record); err != nil || offset != uint64(i) {
+			t.Fatalf("append %d = %d, %v", i, offset, err)
+		}
+	}
+	if entries, _ := readIndex(dir, 0); len(entries) == 0 {
+		t.Error("Expected index entries for a segment larger than the index interval")
+	}
+
+	// Reads from an offset start at the nearest index entry
+	offsets, payloads := readAll(t, l, 250)
+	if len(offsets) != 250 || offsets[0] != 250 || payloads[0] != "payload 250" {
+		t.Fatalf("Expected messages 250-499, got %d from %v", len(offsets), offsets[:1])
+	}
+
+	var seventh logRecord
+	_ = l.read(7, func(record logRecord) error {
+		seventh = record
+		return fmt.Errorf("stop")
+	})
+	if seventh.ID != "msg-7" || !seventh.Timestamp.Equal(timestamp) || seventh.Headers["source"] != "host-1" || seventh.Headers["worker"] != "3" {
+		t.Errorf("Unexpected record %+v", seventh)
+	}
+
+	if err := l.close(); err != nil {
+		t.Fatal(err)
+	}
+	l, err = openTopicLog(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.close() }()
+	if offset, err := l.append(logRecord{Payload: []byte("after restart")}); err != nil || offset != 500 {
+		t.Errorf("Expected offsets to continue at 500 after reopening, got %d, %v", offset, err)
+	}
+}
+
+func TestTopicLog_RecoversTornFrame(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openTopicLog(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := l.append(logRecord{Payload: []byte("ok")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = l.close()
+
+	// A crash mid-write leaves half a frame, and an index entry past the end
+	frame, _ := encodeFrame(logRecord{Offset: 3, Payload: []byte("torn")}, nil)
+	file, err := os.OpenFile(segmentPath(dir, 0, segmentExt), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.Write(frame[:len(frame)/2])
+	_ = file.Close()
+	if err := os.WriteFile(segmentPath(dir, 0, indexExt), make([]byte, indexEntrySize+5), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = openTopicLog(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.close() }()
+	if offset, err := l.append(logRecord{Payload: []byte("next")}); err != nil || offset != 3 {
+		t.Fatalf("Expected the torn message's offset reused, got %d, %v", offset, err)
+	}
+	if offsets, payloads := readAll(t, l, 0); len(offsets) != 4 || payloads[3] != "next" {
+		t.Errorf("Unexpected messages after recovery: %v %v", offsets, payloads)
+	}
+}
+
+func TestTopicLog_Compact(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openTopicLog(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN := func(n int) {
+		for i := 0; i < n; i++ {
+			if _, err := l.append(logRecord{Payload: []byte("m")}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	appendN(3)
+	if err := l.roll(); err != nil {
+		t.Fatal(err)
+	}
+	appendN(2)
+
+	// Segments are only removed once all of their messages are done
+	if removed, err := l.compact(2); err != nil || removed != 0 {
+		t.Fatalf("compact(2) = %d, %v", removed, err)
+	}
+	if removed, err := l.compact(4); err != nil || removed != 1 {
+		t.Fatalf("compact(4) = %d, %v", removed, err)
+	}
+	if offsets, _ := readAll(t, l, 0); len(offsets) != 2 || offsets[0] != 3 {
+		t.Errorf("Expected messages 3 and 4 left, got %v", offsets)
+	}
+
+	// Compacting everything keeps an empty segment, so offsets are not reused
+	if _, err := l.compact(5); err != nil {
+		t.Fatal(err)
+	}
+	_ = l.close()
+	if bases, _ := listSegments(dir); len(bases) != 1 || bases[0] != 5 {
+		t.Fatalf("Expected a single empty segment at 5, got %v", bases)
+	}
+	l, err = openTopicLog(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.close() }()
+	if offset, _ := l.append(logRecord{Payload: []byte("m")}); offset != 5 {
+		t.Errorf("Expected offset 5 after compaction, got %d", offset)
+	}
+}
+
+func TestBroker_CompactsAcknowledgedMessages(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.PersistenceEnabled = true
+	config.PersistenceDir = t.TempDir()
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	messages, unsubscribe, err := broker.SubscribeWithAck("compact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	for i := 0; i < 2; i++ {
+		if err := broker.Publish("compact", Message{Payload: []byte("m")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	topicDir := filepath.Join(config.PersistenceDir, "compact")
+	(<-messages).Ack()
+	broker.compactLogs()
+	if bases, _ := listSegments(topicDir); len(bases) != 1 || bases[0] != 0 {
+		t.Fatalf("Expected the segment kept while a message is pending, got %v", bases)
+	}
+
+	(<-messages).Ack()
+	broker.compactLogs()
+	if bases, _ := listSegments(topicDir); len(bases) != 1 || bases[0] != 2 {
+		t.Errorf("Expected acknowledged messages compacted away, got %v", bases)
+	}
+}
+
+func TestPersistenceFormat_ConvertsLegacyLogs(t *testing.T) {
+	dir := t.TempDir()
+	keyring := encryptiontest.Keyring(t, "k1="+encryptiontest.Key(t))
+
+	topicDir := filepath.Join(dir, "telemetry")
+	if err := os.MkdirAll(topicDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := keyring.Encrypt([]byte(`{"id":"b","timestamp":1752871355,"payload":"Yg=="}`))
+	legacy := `{"id":"a","timestamp":1752871354,"payload":"YQ=="}` + "\n" + string(sealed) + "\n" + `{"id":"c","tim`
+	if err := os.WriteFile(filepath.Join(topicDir, legacyLogFile), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Encrypted logs need the key
+	if _, err := PersistenceFormat(nil).Migrate(dir, nil); err == nil {
+		t.Fatal("Expected converting an encrypted log without its key to fail")
+	}
+
+	from, err := PersistenceFormat(keyring).Migrate(dir, nil)
+	if err != nil || from != 1 {
+		t.Fatalf("Migrate = %d, %v", from, err)
+	}
+	if _, err := os.Stat(filepath.Join(topicDir, legacyLogFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the legacy log removed, got %v", err)
+	}
+
+	l, err := openTopicLog(topicDir, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.close() }()
+	var records []logRecord
+	_ = l.read(0, func(record logRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if len(records) != 2 || records[0].ID != "a" || string(records[1].Payload) != "b" || records[1].Offset != 1 {
+		t.Fatalf("Unexpected converted records %+v", records)
+	}
+	if !records[0].Timestamp.Equal(time.Unix(1752871354, 0)) {
+		t.Errorf("Unexpected timestamp %s", records[0].Timestamp)
+	}
+	if offset, _ := l.append(logRecord{Payload: []byte("c")}); offset != 2 {
+		t.Errorf("Expected new messages after the converted ones, got offset %d", offset)
+	}
+}
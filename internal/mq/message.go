@@ -1,6 +1,18 @@
 package mq
 
-import "time"
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HeaderSchemaVersion names the version of the format a message's payload is
+// encoded in, so consumers can accept producers on more than one version
+const HeaderSchemaVersion = "schema_version"
+
+// MessageHeaderPrefix prefixes the HTTP headers carrying the headers of a
+// message published over HTTP
+const MessageHeaderPrefix = "X-Message-"
 
 type GpuMetric struct {
 	Timestamp  time.Time         `json:"timestamp"`
@@ -14,6 +26,77 @@ type GpuMetric struct {
 }
 
 type Message struct {
+	// ID identifies the message. The broker assigns one on publish unless
+	// the publisher set it, and keeps it when redelivering and bridging.
+	ID string
+	// Timestamp is when the message was originally published
+	Timestamp time.Time
+	// Retries counts the redeliveries before this delivery
+	Retries int
+	// Offset is the message's position in its topic's log, when the broker
+	// persists messages; see SubscribeFrom
+	Offset  uint64
 	Payload []byte
+	// Headers are metadata carried alongside the payload, such as the error
+	// a poison message was quarantined with
+	Headers map[string]string
 	Ack     func()
+	// Nack reports that processing the message failed with err, so it is
+	// redelivered or, after repeated failures, quarantined. It is nil for
+	// clients that cannot report failures.
+	Nack func(err error)
+	// receipt lets an AckBatcher acknowledge messages from a local broker
+	// together, under a single lock
+	receipt *ackReceipt
+}
+
+// WireHeaders returns the headers sent with a message over gRPC: the
+// message's own and the sender's protocol version
+func WireHeaders(headers map[string]string) map[string]string {
+	wire := make(map[string]string, len(headers)+1)
+	for name, value := range headers {
+		wire[name] = value
+	}
+	wire[ProtocolHeader] = ProtocolVersion
+	return wire
+}
+
+// MessageHeaders returns the message's own headers among those received with
+// it over gRPC, or nil if there are none
+func MessageHeaders(wire map[string]string) map[string]string {
+	var headers map[string]string
+	for name, value := range wire {
+		if name == ProtocolHeader {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string, len(wire))
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// setHTTPMessageHeaders adds a message's headers to an HTTP publish request
+func setHTTPMessageHeaders(header http.Header, headers map[string]string) {
+	for name, value := range headers {
+		header.Set(MessageHeaderPrefix+name, value)
+	}
+}
+
+// HTTPMessageHeaders returns the message headers sent with an HTTP publish
+// request, or nil if there are none. HTTP header names are case-insensitive,
+// so the names are returned in lower case.
+func HTTPMessageHeaders(header http.Header) map[string]string {
+	var headers map[string]string
+	for name := range header {
+		if len(name) <= len(MessageHeaderPrefix) || !strings.EqualFold(name[:len(MessageHeaderPrefix)], MessageHeaderPrefix) {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[strings.ToLower(name[len(MessageHeaderPrefix):])] = header.Get(name)
+	}
+	return headers
 }
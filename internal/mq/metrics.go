@@ -0,0 +1,165 @@
+package mq
+
+import (
+	"net/http"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+// publishBuckets cover in-memory publishes (microseconds) up to publishes
+// that wait on a slow persistence disk
+var publishBuckets = []float64{0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1}
+
+// ackBuckets cover consumers that ack immediately up to ones that only ack
+// after several redeliveries with the default 30s timeout
+var ackBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// brokerMetrics holds the broker's Prometheus instrumentation
+type brokerMetrics struct {
+	registry        *metrics.Registry
+	published       *metrics.CounterVec
+	redeliveries    *metrics.CounterVec
+	expired         *metrics.CounterVec
+	poisoned        *metrics.CounterVec
+	diskFull        *metrics.CounterVec
+	overflow        *metrics.CounterVec
+	publishDuration *metrics.HistogramVec
+	ackLatency      *metrics.HistogramVec
+}
+
+// newBrokerMetrics registers broker metrics. Delivery and acknowledgment
+// totals, and the queue depth, pending and subscriber gauges, are read from
+// broker state at scrape time.
+func newBrokerMetrics(b *Broker) *brokerMetrics {
+	registry := metrics.NewRegistry()
+
+	m := &brokerMetrics{
+		registry: registry,
+		published: registry.NewCounterVec("mq_published_messages_total",
+			"Messages published to a topic.", "topic"),
+		redeliveries: registry.NewCounterVec("mq_redeliveries_total",
+			"Messages redelivered after their acknowledgment timeout expired, or on request.", "topic"),
+		expired: registry.NewCounterVec("mq_expired_messages_total",
+			"Messages dropped after exhausting their delivery retries.", "topic"),
+		poisoned: registry.NewCounterVec("mq_poison_messages_total",
+			"Messages quarantined to their topic's poison topic after failing too many times.", "topic"),
+		diskFull: registry.NewCounterVec("mq_disk_full_rejections_total",
+			"Publishes refused because the persistence directory's disk was nearly full.", "topic"),
+		overflow: registry.NewCounterVec("mq_queue_overflow_messages_total",
+			"Messages dropped or publishes refused because their topic's queue was full, by overflow policy.", "topic", "policy"),
+		publishDuration: registry.NewHistogramVec("mq_publish_duration_seconds",
+			"Time taken to publish a message, including persistence.", publishBuckets, "topic"),
+		ackLatency: registry.NewHistogramVec("mq_ack_latency_seconds",
+			"Time from publish until the message was acknowledged, including redeliveries.", ackBuckets, "topic"),
+	}
+
+	registry.NewCounterFunc("mq_delivered_messages_total",
+		"Messages delivered to subscribers, including redeliveries.", []string{"topic"},
+		func(emit func(float64, ...string)) {
+			b.mu.RLock()
+			defer b.mu.RUnlock()
+			for name, topic := range b.topics {
+				emit(float64(topic.counters.delivered), name)
+			}
+		})
+
+	registry.NewCounterFunc("mq_acked_messages_total",
+		"Messages acknowledged by their subscribers.", []string{"topic"},
+		func(emit func(float64, ...string)) {
+			b.mu.RLock()
+			defer b.mu.RUnlock()
+			for name, topic := range b.topics {
+				emit(float64(topic.counters.acked), name)
+			}
+		})
+
+	registry.NewGaugeFunc("mq_topic_queue_depth",
+		"Messages queued on a topic and not yet acknowledged.", []string{"topic"},
+		func(emit func(float64, ...string)) {
+			b.mu.RLock()
+			defer b.mu.RUnlock()
+			for name, topic := range b.topics {
+				emit(float64(len(topic.messageQueue)), name)
+			}
+		})
+
+	registry.NewGaugeFunc("mq_pending_messages",
+		"Messages delivered and awaiting acknowledgment.", []string{"topic"},
+		func(emit func(float64, ...string)) {
+			b.mu.RLock()
+			defer b.mu.RUnlock()
+			for name, topic := range b.topics {
+				emit(float64(len(topic.pendingMsgs)), name)
+			}
+		})
+
+	registry.NewGaugeFunc("mq_topic_subscribers",
+		"Active subscribers on a topic.", []string{"topic"},
+		func(emit func(float64, ...string)) {
+			b.mu.RLock()
+			defer b.mu.RUnlock()
+			for name, topic := range b.topics {
+				emit(float64(topic.subscriberCount()), name)
+			}
+		})
+
+	if b.disk != nil {
+		registry.NewGaugeFunc("mq_disk_free_bytes",
+			"Free bytes on the filesystem holding the persistence directory.", []string{"dir"},
+			func(emit func(float64, ...string)) {
+				if used, size, err := b.disk.Usage(); err == nil {
+					emit(float64(size-used), b.disk.Dir())
+				}
+			})
+		registry.NewGaugeFunc("mq_disk_size_bytes",
+			"Size of the filesystem holding the persistence directory.", []string{"dir"},
+			func(emit func(float64, ...string)) {
+				if _, size, err := b.disk.Usage(); err == nil {
+					emit(float64(size), b.disk.Dir())
+				}
+			})
+	}
+
+	return m
+}
+
+// LatencySummary condenses a latency histogram for the JSON stats endpoints.
+// Quantiles are estimated from histogram buckets.
+type LatencySummary struct {
+	Count       uint64  `json:"count"`
+	MeanSeconds float64 `json:"mean_seconds"`
+	P50Seconds  float64 `json:"p50_seconds"`
+	P90Seconds  float64 `json:"p90_seconds"`
+	P99Seconds  float64 `json:"p99_seconds"`
+}
+
+// summarize returns a summary of h, or nil if nothing has been observed
+func summarize(h *metrics.Histogram) *LatencySummary {
+	count := h.Count()
+	if count == 0 {
+		return nil
+	}
+	return &LatencySummary{
+		Count:       count,
+		MeanSeconds: h.Sum() / float64(count),
+		P50Seconds:  h.Quantile(0.5),
+		P90Seconds:  h.Quantile(0.9),
+		P99Seconds:  h.Quantile(0.99),
+	}
+}
+
+// topicLatency returns publish and ack latency summaries for a topic
+func (m *brokerMetrics) topicLatency(topic string) (publish, ack *LatencySummary) {
+	return summarize(m.publishDuration.WithLabelValues(topic)), summarize(m.ackLatency.WithLabelValues(topic))
+}
+
+// Metrics returns the registry holding the broker's metrics, so services
+// embedding the broker can add their own metrics to the same endpoint
+func (b *Broker) Metrics() *metrics.Registry {
+	return b.metrics.registry
+}
+
+// MetricsHandler serves broker metrics in the Prometheus text format
+func (b *Broker) MetricsHandler() http.Handler {
+	return b.metrics.registry.Handler()
+}
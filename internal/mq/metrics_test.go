@@ -0,0 +1,126 @@
+package mq
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrapeBroker(t *testing.T, b *Broker) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	b.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func TestBrokerMetrics(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.AckTimeout = 10 * time.Millisecond
+	config.MaxRetries = 1
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	ch, unsubscribe, err := broker.SubscribeWithAck("gpu")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer unsubscribe()
+
+	for i := 0; i < 3; i++ {
+		if err := broker.Publish("gpu", Message{Payload: []byte("m")}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	// Acknowledge one message, leave two pending
+	(<-ch).Ack()
+
+	out := scrapeBroker(t, broker)
+	for _, want := range []string{
+		"# TYPE mq_published_messages_total counter",
+		`mq_published_messages_total{topic="gpu"} 3`,
+		"# TYPE mq_delivered_messages_total counter",
+		`mq_delivered_messages_total{topic="gpu"} 3`,
+		"# TYPE mq_acked_messages_total counter",
+		`mq_acked_messages_total{topic="gpu"} 1`,
+		"# TYPE mq_topic_queue_depth gauge",
+		`mq_topic_queue_depth{topic="gpu"} 2`,
+		`mq_pending_messages{topic="gpu"} 2`,
+		`mq_topic_subscribers{topic="gpu"} 1`,
+		"# TYPE mq_publish_duration_seconds histogram",
+		`mq_publish_duration_seconds_count{topic="gpu"} 3`,
+		`mq_publish_duration_seconds_bucket{topic="gpu",le="+Inf"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Metrics missing %q:\n%s", want, out)
+		}
+	}
+
+	// One redelivery each, then both expire
+	broker.processAckTimeouts()
+	time.Sleep(20 * time.Millisecond)
+	broker.processAckTimeouts()
+	time.Sleep(20 * time.Millisecond)
+	broker.processAckTimeouts()
+
+	out = scrapeBroker(t, broker)
+	for _, want := range []string{
+		`mq_redeliveries_total{topic="gpu"} 2`,
+		`mq_expired_messages_total{topic="gpu"} 2`,
+		`mq_pending_messages{topic="gpu"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Metrics missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestBrokerLatencyStats(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+
+	ch, unsubscribe, err := broker.SubscribeWithAck("lat")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer unsubscribe()
+
+	if err := broker.Publish("lat", Message{Payload: []byte("m")}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	// No ack yet: only publish latency is reported
+	stats := broker.GetStats().Topics["lat"]
+	if stats.PublishLatency == nil || stats.PublishLatency.Count != 1 {
+		t.Fatalf("Expected publish latency for 1 message, got %+v", stats.PublishLatency)
+	}
+	if stats.AckLatency != nil {
+		t.Errorf("Expected no ack latency before ack, got %+v", stats.AckLatency)
+	}
+
+	msg := <-ch
+	time.Sleep(15 * time.Millisecond)
+	msg.Ack()
+	msg.Ack() // duplicate acks are not double counted
+
+	stats = broker.GetStats().Topics["lat"]
+	if stats.AckLatency == nil || stats.AckLatency.Count != 1 {
+		t.Fatalf("Expected ack latency for 1 message, got %+v", stats.AckLatency)
+	}
+	if stats.AckLatency.MeanSeconds < 0.015 {
+		t.Errorf("Expected mean ack latency >= 15ms, got %v", stats.AckLatency.MeanSeconds)
+	}
+	if stats.AckLatency.P50Seconds < 0.01 {
+		t.Errorf("Expected p50 of at least the 10-25ms bucket, got %v", stats.AckLatency.P50Seconds)
+	}
+
+	out := scrapeBroker(t, broker)
+	if !strings.Contains(out, `mq_ack_latency_seconds_count{topic="lat"} 1`) {
+		t.Errorf("Metrics missing ack latency:\n%s", out)
+	}
+}
@@ -1,6 +1,7 @@
 package mq
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,6 +9,10 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/netutil"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
 )
 
 // Broker configuration
@@ -16,8 +21,30 @@ type BrokerConfig struct {
 	PersistenceDir     string
 	AckTimeout         time.Duration
 	MaxRetries         int
+	// Keyring encrypts persisted messages at rest when set
+	Keyring *encryption.Keyring
+	// PoisonThreshold quarantines a message to its topic's poison topic
+	// once it has failed, by a nack or an ack timeout, more than this many
+	// times; zero disables quarantine
+	PoisonThreshold int
+	// CompactInterval is how often log segments holding only messages that
+	// are no longer pending are removed; zero uses DefaultCompactInterval
+	CompactInterval time.Duration
+	// MinFreeDiskBytes refuses publishes with ErrDiskFull while the
+	// filesystem holding PersistenceDir has less free space; zero never
+	// refuses them
+	MinFreeDiskBytes int64
+	// MaxQueueSize bounds how many messages each topic queues until they
+	// are acknowledged; zero leaves queues unbounded
+	MaxQueueSize int
+	// OverflowPolicy is what a publish to a full topic does; empty uses
+	// DefaultOverflowPolicy
+	OverflowPolicy OverflowPolicy
 }
 
+// DefaultCompactInterval is how often persisted topic logs are compacted
+const DefaultCompactInterval = time.Minute
+
 // DefaultBrokerConfig returns a default configuration
 func DefaultBrokerConfig() BrokerConfig {
 	return BrokerConfig{
@@ -36,6 +63,17 @@ type PendingMessage struct {
 	TopicName  string
 	MessageID  string
 	queueIndex int
+	// offset is the message's position in its topic's log, if persisted
+	offset uint64
+	// failures counts the deliveries that were nacked or timed out, and
+	// lastError is the error the latest of them failed with
+	failures  int
+	lastError string
+	// group is the consumer group this copy of the message is delivered to,
+	// or "" for the topic's subscribers outside any group
+	group string
+	// seq orders the topic's copies by when they were queued
+	seq uint64
 }
 
 // TopicData holds topic-specific data
@@ -44,6 +82,33 @@ type TopicData struct {
 	ackSubscribers map[chan Message]struct{} // Subscribers that support acknowledgment
 	messageQueue   []*PendingMessage
 	pendingMsgs    map[string]*PendingMessage // messageID -> PendingMessage
+	groups         map[string]*consumerGroup
+	counters       topicCounters
+	// seq numbers the copies queued on the topic
+	seq uint64
+	// room is closed when a message leaves the queue, waking publishers
+	// blocked on a full topic; nil if none is waiting
+	room chan struct{}
+	// config is the topic's own configuration, see CreateTopic
+	config TopicConfig
+	// paused holds deliveries until the topic is resumed
+	paused bool
+}
+
+// topicCounters are cumulative message counts for a topic. Deliveries count
+// each copy handed to a subscriber, including redeliveries.
+type topicCounters struct {
+	published    int64
+	delivered    int64
+	acked        int64
+	redelivered  int64
+	deadLettered int64
+	poisoned     int64
+	replayed     int64
+	// Messages dropped or refused because the topic's queue was full
+	droppedOldest int64
+	droppedNewest int64
+	rejected      int64
 }
 
 // Broker implements the message broker
@@ -53,6 +118,12 @@ type Broker struct {
 	config   BrokerConfig
 	closed   bool
 	stopChan chan struct{}
+	metrics  *brokerMetrics
+	// logs are the open topic logs when persistence is enabled
+	logs map[string]*topicLog
+	// disk guards PersistenceDir, nil without persistence or a free space
+	// minimum
+	disk *persistence.DiskGuard
 }
 
 // NewBroker creates a new message broker with the given configuration
@@ -61,14 +132,21 @@ func NewBroker(config BrokerConfig) *Broker {
 		topics:   make(map[string]*TopicData),
 		config:   config,
 		stopChan: make(chan struct{}),
+		logs:     make(map[string]*topicLog),
+	}
+	if config.PersistenceEnabled && config.MinFreeDiskBytes > 0 {
+		b.disk = persistence.NewDiskGuard(config.PersistenceDir, uint64(config.MinFreeDiskBytes))
 	}
+	b.metrics = newBrokerMetrics(b)
 
-	// Create persistence directory if needed
+	// Create persistence directory if needed, and queue again the messages
+	// left unacknowledged by the previous run
 	if config.PersistenceEnabled {
 		if err := os.MkdirAll(config.PersistenceDir, 0755); err != nil {
 			// Log error but don't fail broker creation
 			fmt.Printf("Warning: failed to create persistence directory: %v\n", err)
 		}
+		b.replay()
 	}
 
 	// Start background goroutine for handling acknowledgment timeouts
@@ -79,14 +157,39 @@ func NewBroker(config BrokerConfig) *Broker {
 
 // Publish publishes a message to the specified topic
 func (b *Broker) Publish(topic string, msg Message) error {
+	return b.PublishCtx(context.Background(), topic, msg)
+}
+
+// PublishCtx publishes a message to the specified topic unless ctx is done
+// before the broker's lock is taken
+func (b *Broker) PublishCtx(ctx context.Context, topic string, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ValidateTopic(topic); err != nil {
+		return err
+	}
+	start := time.Now()
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	if b.closed {
-		return fmt.Errorf("broker is closed")
+		return ErrClosed
+	}
+	if err := b.waitToPublish(ctx, topic); err != nil {
+		return err
 	}
 
-	// Get or create topic
+	if err := b.publishLocked(topic, msg); err != nil {
+		return err
+	}
+	b.metrics.publishDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// topicDataLocked returns a topic's data, creating it on first use. Caller
+// must hold b.mu.
+func (b *Broker) topicDataLocked(topic string) *TopicData {
 	topicData, exists := b.topics[topic]
 	if !exists {
 		topicData = &TopicData{
@@ -94,46 +197,131 @@ func (b *Broker) Publish(topic string, msg Message) error {
 			ackSubscribers: make(map[chan Message]struct{}),
 			messageQueue:   make([]*PendingMessage, 0),
 			pendingMsgs:    make(map[string]*PendingMessage),
+			groups:         make(map[string]*consumerGroup),
 		}
 		b.topics[topic] = topicData
 	}
+	return topicData
+}
+
+// publishLocked queues, persists and delivers a message: one copy to the
+// subscribers outside any group, and one to each consumer group. Caller
+// must hold b.mu.
+func (b *Broker) publishLocked(topic string, msg Message) error {
+	topicData := b.topicDataLocked(topic)
+
+	if msg.ID == "" {
+		msg.ID = NewMessageID()
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+
+	discard, err := b.admit(topic, topicData)
+	if err != nil || discard {
+		return err
+	}
 
-	// Persist message if enabled
+	// Persist message if enabled, refusing it rather than filling the disk
+	var offset uint64
 	if b.config.PersistenceEnabled {
-		if err := b.persistMessage(topic, msg); err != nil {
+		if b.disk != nil {
+			if err := b.disk.Check(); err != nil {
+				b.metrics.diskFull.WithLabelValues(topic).Inc()
+				return err
+			}
+		}
+		var err error
+		if offset, err = b.persistMessage(topic, msg); err != nil {
 			return fmt.Errorf("failed to persist message: %w", err)
 		}
 	}
 
-	// Generate message ID for acknowledgment tracking
+	b.metrics.published.WithLabelValues(topic).Inc()
+	topicData.counters.published++
+
+	// A topic whose consumers are all in groups keeps no copy for ungrouped
+	// subscribers, which would only time out
 	now := time.Now()
-	msgID := fmt.Sprintf("%s-%d", topic, now.UnixNano())
+	if len(topicData.groups) == 0 || len(topicData.subscribers)+len(topicData.ackSubscribers) > 0 {
+		b.deliver(topicData, b.track(topic, topicData, msg, offset, "", now))
+	}
+	for _, name := range topicData.groupNames() {
+		b.deliver(topicData, b.track(topic, topicData, msg, offset, name, now))
+	}
+	return nil
+}
+
+// track queues a copy of a published message for delivery to group and
+// tracks it until it is acknowledged. Caller must hold b.mu.
+func (b *Broker) track(topic string, topicData *TopicData, msg Message, offset uint64, group string, now time.Time) *PendingMessage {
+	// Track the copy under the message's own ID for acknowledgment, unless
+	// a copy with that ID is already pending (such as one for another group,
+	// or a message bridged twice), in which case it is tracked under a
+	// fresh one
+	msgID := msg.ID
+	if _, pending := topicData.pendingMsgs[msgID]; pending {
+		msgID = NewMessageID()
+	}
 
 	pendingMsg := &PendingMessage{
 		Message: Message{
-			Payload: msg.Payload,
+			ID:        msg.ID,
+			Timestamp: msg.Timestamp,
+			Payload:   msg.Payload,
+			Headers:   msg.Headers,
+			Offset:    offset,
 		},
 		Timestamp: now,
 		Retries:   0,
 		TopicName: topic,
-		MessageID: msgID,
+		MessageID: msg.ID,
+		offset:    offset,
+		group:     group,
 	}
+	topicData.seq++
+	pendingMsg.seq = topicData.seq
 
 	// Update message acknowledgment to remove the pending entry once processed
+	receipt := &ackReceipt{broker: b, topic: topic, msgID: msgID, published: now}
+	pendingMsg.Message.receipt = receipt
 	pendingMsg.Message.Ack = func() {
 		b.mu.Lock()
 		defer b.mu.Unlock()
-		b.removePendingMessage(topic, msgID)
+		b.ack(receipt)
+	}
+	pendingMsg.Message.Nack = func(err error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.nack(receipt, err)
 	}
 
 	pendingMsg.queueIndex = len(topicData.messageQueue)
 	topicData.messageQueue = append(topicData.messageQueue, pendingMsg)
 	topicData.pendingMsgs[msgID] = pendingMsg
+	return pendingMsg
+}
+
+// deliver hands a pending message to its subscribers: every subscriber
+// outside a group, or one member of its consumer group. Subscribers whose
+// channel is full are skipped, and nothing is delivered while the topic is
+// paused. Caller must hold b.mu.
+func (b *Broker) deliver(topicData *TopicData, pendingMsg *PendingMessage) {
+	if topicData.paused {
+		return
+	}
+	if pendingMsg.group != "" {
+		if group, exists := topicData.groups[pendingMsg.group]; exists && group.deliver(pendingMsg.Message) {
+			topicData.counters.delivered++
+		}
+		return
+	}
 
 	// Send to regular subscribers (payload only)
 	for ch := range topicData.subscribers {
 		select {
 		case ch <- pendingMsg.Message.Payload:
+			topicData.counters.delivered++
 		default:
 			// Channel is full, skip this subscriber
 		}
@@ -143,38 +331,53 @@ func (b *Broker) Publish(topic string, msg Message) error {
 	for ch := range topicData.ackSubscribers {
 		select {
 		case ch <- pendingMsg.Message:
+			topicData.counters.delivered++
 		default:
 			// Channel is full, skip this subscriber
 		}
 	}
+}
 
-	return nil
+// ack removes an acknowledged message. Only the first ack counts towards
+// latency; redelivered copies share the receipt. Caller must hold b.mu.
+func (b *Broker) ack(receipt *ackReceipt) {
+	if b.removePendingMessage(receipt.topic, receipt.msgID) {
+		b.topics[receipt.topic].counters.acked++
+		b.metrics.ackLatency.WithLabelValues(receipt.topic).Observe(time.Since(receipt.published).Seconds())
+	}
 }
 
-// removePendingMessage removes a message from tracking structures. Caller must hold b.mu.
-func (b *Broker) removePendingMessage(topic, msgID string) {
+// removePendingMessage removes a message from tracking structures and reports
+// whether it was still pending. Caller must hold b.mu.
+func (b *Broker) removePendingMessage(topic, msgID string) bool {
 	topicData, exists := b.topics[topic]
 	if !exists {
-		return
+		return false
 	}
 
 	pending, exists := topicData.pendingMsgs[msgID]
 	if !exists {
-		return
+		return false
 	}
 
 	delete(topicData.pendingMsgs, msgID)
+	topicData.signalRoom()
+	if log, exists := b.logs[topic]; exists {
+		if err := log.resolve(pending.offset, pending.group); err != nil {
+			fmt.Printf("Warning: failed to record acknowledgment on topic %s: %v\n", topic, err)
+		}
+	}
 
 	if len(topicData.messageQueue) == 0 {
 		pending.queueIndex = -1
-		return
+		return true
 	}
 
 	idx := pending.queueIndex
 	lastIdx := len(topicData.messageQueue) - 1
 	if idx < 0 || idx > lastIdx {
 		pending.queueIndex = -1
-		return
+		return true
 	}
 
 	if idx != lastIdx {
@@ -185,37 +388,35 @@ func (b *Broker) removePendingMessage(topic, msgID string) {
 	topicData.messageQueue[lastIdx] = nil
 	topicData.messageQueue = topicData.messageQueue[:lastIdx]
 	pending.queueIndex = -1
+	return true
 }
 
 // Subscribe subscribes to a topic and returns a channel for receiving messages
 func (b *Broker) Subscribe(topic string) (chan []byte, func(), error) {
+	if err := ValidateTopic(topic); err != nil {
+		return nil, nil, err
+	}
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	if b.closed {
-		return nil, nil, fmt.Errorf("broker is closed")
+		return nil, nil, ErrClosed
 	}
 
-	// Get or create topic
-	topicData, exists := b.topics[topic]
-	if !exists {
-		topicData = &TopicData{
-			subscribers:    make(map[chan []byte]struct{}),
-			ackSubscribers: make(map[chan Message]struct{}),
-			messageQueue:   make([]*PendingMessage, 0),
-			pendingMsgs:    make(map[string]*PendingMessage),
-		}
-		b.topics[topic] = topicData
-	}
+	topicData := b.topicDataLocked(topic)
 
 	// Create channel for subscriber
 	ch := make(chan []byte, 100) // Buffered channel
 	topicData.subscribers[ch] = struct{}{}
 
-	// Send any existing messages in the queue
+	// Send any existing messages in the queue, unless delivery is paused
 	for _, pending := range topicData.messageQueue {
+		if pending.group != "" || topicData.paused {
+			continue
+		}
 		select {
 		case ch <- pending.Message.Payload:
+			topicData.counters.delivered++
 		default:
 			// Channel is full, skip
 		}
@@ -236,35 +437,46 @@ func (b *Broker) Subscribe(topic string) (chan []byte, func(), error) {
 	return ch, unsubscribe, nil
 }
 
+// SubscribeCtx subscribes to a topic until ctx is done or the returned
+// function is called
+func (b *Broker) SubscribeCtx(ctx context.Context, topic string) (chan []byte, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	ch, unsubscribe, err := b.Subscribe(topic)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, unsubscribeOnDone(ctx, unsubscribe), nil
+}
+
 // SubscribeWithAck subscribes to a topic and returns a channel for receiving messages with acknowledgment support
 func (b *Broker) SubscribeWithAck(topic string) (chan Message, func(), error) {
+	if err := ValidateTopic(topic); err != nil {
+		return nil, nil, err
+	}
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	if b.closed {
-		return nil, nil, fmt.Errorf("broker is closed")
+		return nil, nil, ErrClosed
 	}
 
-	// Get or create topic
-	topicData, exists := b.topics[topic]
-	if !exists {
-		topicData = &TopicData{
-			subscribers:    make(map[chan []byte]struct{}),
-			ackSubscribers: make(map[chan Message]struct{}),
-			messageQueue:   make([]*PendingMessage, 0),
-			pendingMsgs:    make(map[string]*PendingMessage),
-		}
-		b.topics[topic] = topicData
-	}
+	topicData := b.topicDataLocked(topic)
 
 	// Create channel for subscriber and register it
 	ch := make(chan Message, 100) // Buffered channel
 	topicData.ackSubscribers[ch] = struct{}{}
 
-	// Send any existing messages in the queue with acknowledgment tracking
+	// Send any existing messages in the queue with acknowledgment tracking,
+	// unless delivery is paused
 	for _, pending := range topicData.messageQueue {
+		if pending.group != "" || topicData.paused {
+			continue
+		}
 		select {
 		case ch <- pending.Message:
+			topicData.counters.delivered++
 		default:
 			// Channel is full, skip
 		}
@@ -285,6 +497,19 @@ func (b *Broker) SubscribeWithAck(topic string) (chan Message, func(), error) {
 	return ch, unsubscribe, nil
 }
 
+// SubscribeWithAckCtx subscribes to a topic with acknowledgment support
+// until ctx is done or the returned function is called
+func (b *Broker) SubscribeWithAckCtx(ctx context.Context, topic string) (chan Message, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	ch, unsubscribe, err := b.SubscribeWithAck(topic)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, unsubscribeOnDone(ctx, unsubscribe), nil
+}
+
 // Close closes the broker and all its resources
 func (b *Broker) Close() {
 	b.mu.Lock()
@@ -305,10 +530,23 @@ func (b *Broker) Close() {
 		for ch := range topicData.ackSubscribers {
 			close(ch)
 		}
+		for _, group := range topicData.groups {
+			for _, ch := range group.members {
+				close(ch)
+			}
+			group.members = nil
+		}
 		// Clear subscribers maps to prevent double closing
 		topicData.subscribers = make(map[chan []byte]struct{})
 		topicData.ackSubscribers = make(map[chan Message]struct{})
 	}
+
+	for topic, log := range b.logs {
+		if err := log.close(); err != nil {
+			fmt.Printf("Warning: failed to close log of topic %s: %v\n", topic, err)
+		}
+	}
+	b.logs = make(map[string]*topicLog)
 }
 
 // GetQueueSize returns the number of messages in a topic's queue
@@ -322,13 +560,24 @@ func (b *Broker) GetQueueSize(topic string) int {
 	return 0
 }
 
+// QueueSizer is implemented by brokers and clients that can report how many
+// messages are queued on a topic
+type QueueSizer interface {
+	QueueSize(ctx context.Context, topic string) (int, error)
+}
+
+// QueueSize returns the number of messages in a topic's queue
+func (b *Broker) QueueSize(ctx context.Context, topic string) (int, error) {
+	return b.GetQueueSize(topic), nil
+}
+
 // GetSubscriberCount returns the number of subscribers for a topic
 func (b *Broker) GetSubscriberCount(topic string) int {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
 	if topicData, exists := b.topics[topic]; exists {
-		return len(topicData.subscribers) + len(topicData.ackSubscribers)
+		return topicData.subscriberCount()
 	}
 	return 0
 }
@@ -355,6 +604,32 @@ type TopicStats struct {
 	QueueSize       int `json:"queue_size"`
 	SubscriberCount int `json:"subscriber_count"`
 	PendingMessages int `json:"pending_messages"`
+	// Cumulative counts since the broker started
+	PublishedMessages    int64           `json:"published_messages"`
+	DeliveredMessages    int64           `json:"delivered_messages"`
+	AckedMessages        int64           `json:"acked_messages"`
+	RedeliveredMessages  int64           `json:"redelivered_messages"`
+	DeadLetteredMessages int64           `json:"dead_lettered_messages"`
+	PoisonedMessages     int64           `json:"poisoned_messages"`
+	PublishLatency       *LatencySummary `json:"publish_latency,omitempty"`
+	AckLatency           *LatencySummary `json:"ack_latency,omitempty"`
+	// ConsumerGroups counts the topic's consumer groups, including those
+	// that have no members left and queue messages for when one joins
+	ConsumerGroups int `json:"consumer_groups"`
+	// ReplayedMessages counts the message copies restored from the topic's
+	// log when the broker started
+	ReplayedMessages int64 `json:"replayed_messages"`
+	// Messages dropped or refused because the topic's queue was at
+	// BrokerConfig.MaxQueueSize, by overflow policy
+	DroppedOldestMessages int64 `json:"dropped_oldest_messages"`
+	DroppedNewestMessages int64 `json:"dropped_newest_messages"`
+	RejectedMessages      int64 `json:"rejected_messages"`
+	// Paused is set while delivery on the topic is paused
+	Paused bool `json:"paused,omitempty"`
+	// FirstOffset and NextOffset are the offsets the topic's log holds, from
+	// which SubscribeFrom can replay it, when the broker persists messages
+	FirstOffset uint64 `json:"first_offset,omitempty"`
+	NextOffset  uint64 `json:"next_offset,omitempty"`
 }
 
 // GetStats returns comprehensive broker statistics
@@ -367,17 +642,54 @@ func (b *Broker) GetStats() AdminStats {
 	}
 
 	for topicName, topicData := range b.topics {
-		stats.Topics[topicName] = TopicStats{
-			QueueSize:       len(topicData.messageQueue),
-			SubscriberCount: len(topicData.subscribers) + len(topicData.ackSubscribers),
-			PendingMessages: len(topicData.pendingMsgs),
-		}
+		stats.Topics[topicName] = b.topicStats(topicName, topicData)
 	}
 
 	return stats
 }
 
-// StartAdminServer starts an HTTP server for admin endpoints
+// topicStats returns a topic's statistics. Caller must hold b.mu.
+func (b *Broker) topicStats(topicName string, topicData *TopicData) TopicStats {
+	publishLatency, ackLatency := b.metrics.topicLatency(topicName)
+	firstOffset, nextOffset := b.offsetRange(topicName)
+	return TopicStats{
+		QueueSize:             len(topicData.messageQueue),
+		SubscriberCount:       topicData.subscriberCount(),
+		PendingMessages:       len(topicData.pendingMsgs),
+		PublishedMessages:     topicData.counters.published,
+		DeliveredMessages:     topicData.counters.delivered,
+		AckedMessages:         topicData.counters.acked,
+		RedeliveredMessages:   topicData.counters.redelivered,
+		DeadLetteredMessages:  topicData.counters.deadLettered,
+		PoisonedMessages:      topicData.counters.poisoned,
+		PublishLatency:        publishLatency,
+		AckLatency:            ackLatency,
+		ConsumerGroups:        len(topicData.groups),
+		ReplayedMessages:      topicData.counters.replayed,
+		DroppedOldestMessages: topicData.counters.droppedOldest,
+		DroppedNewestMessages: topicData.counters.droppedNewest,
+		RejectedMessages:      topicData.counters.rejected,
+		Paused:                topicData.paused,
+		FirstOffset:           firstOffset,
+		NextOffset:            nextOffset,
+	}
+}
+
+// GetTopicStats returns one topic's statistics, or ErrTopicNotFound if
+// nothing has published or subscribed to it
+func (b *Broker) GetTopicStats(topic string) (TopicStats, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	topicData, exists := b.topics[topic]
+	if !exists {
+		return TopicStats{}, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	}
+	return b.topicStats(topic, topicData), nil
+}
+
+// StartAdminServer starts an HTTP server for admin endpoints on a port,
+// host:port address or unix: socket
 func (b *Broker) StartAdminServer(port string) error {
 	mux := http.NewServeMux()
 
@@ -463,11 +775,12 @@ func (b *Broker) StartAdminServer(port string) error {
 
 		msg := Message{
 			Payload: []byte(payload),
+			Headers: HTTPMessageHeaders(r.Header),
 			Ack:     func() {}, // No-op for HTTP clients
 		}
 
 		if err := b.Publish(topicName, msg); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to publish: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Failed to publish: %v", err), HTTPStatus(err))
 			return
 		}
 
@@ -478,6 +791,9 @@ func (b *Broker) StartAdminServer(port string) error {
 		}
 	}))
 
+	// Prometheus metrics endpoint
+	mux.Handle("/metrics", b.MetricsHandler())
+
 	// Topic-specific stats endpoint
 	mux.HandleFunc("/stats/", corsHandler(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -491,21 +807,12 @@ func (b *Broker) StartAdminServer(port string) error {
 			return
 		}
 
-		b.mu.RLock()
-		topicData, exists := b.topics[topicName]
-		if !exists {
-			b.mu.RUnlock()
-			http.Error(w, "Topic not found", http.StatusNotFound)
+		stats, err := b.GetTopicStats(topicName)
+		if err != nil {
+			http.Error(w, "Topic not found", HTTPStatus(err))
 			return
 		}
 
-		stats := TopicStats{
-			QueueSize:       len(topicData.messageQueue),
-			SubscriberCount: len(topicData.subscribers) + len(topicData.ackSubscribers),
-			PendingMessages: len(topicData.pendingMsgs),
-		}
-		b.mu.RUnlock()
-
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(stats); err != nil {
 			fmt.Printf("Warning: failed to encode stats response: %v\n", err)
@@ -513,44 +820,63 @@ func (b *Broker) StartAdminServer(port string) error {
 		}
 	}))
 
-	return http.ListenAndServe(":"+port, mux)
-}
-
-// persistMessage writes a message to the persistence file for the topic
-func (b *Broker) persistMessage(topic string, msg Message) error {
-	if !b.config.PersistenceEnabled {
-		return nil
-	}
-
-	topicDir := filepath.Join(b.config.PersistenceDir, topic)
-	if err := os.MkdirAll(topicDir, 0755); err != nil {
-		return err
-	}
-
-	filename := filepath.Join(topicDir, "messages.log")
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	listener, err := netutil.Listen(port)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			fmt.Printf("Warning: failed to close file: %v\n", err)
-		}
-	}()
+	return http.Serve(listener, mux)
+}
 
-	// Write message as JSON line
-	msgData := map[string]interface{}{
-		"timestamp": time.Now().Unix(),
-		"payload":   msg.Payload,
+// topicLogLocked returns a topic's log, opening it on first use. Caller must
+// hold b.mu.
+func (b *Broker) topicLogLocked(topic string) (*topicLog, error) {
+	log, exists := b.logs[topic]
+	if !exists {
+		var err error
+		log, err = openTopicLog(filepath.Join(b.config.PersistenceDir, topic), b.config.Keyring)
+		if err != nil {
+			return nil, err
+		}
+		b.logs[topic] = log
 	}
+	return log, nil
+}
 
-	jsonData, err := json.Marshal(msgData)
+// persistMessage appends a message to the topic's log and returns its
+// offset. Caller must hold b.mu.
+func (b *Broker) persistMessage(topic string, msg Message) (uint64, error) {
+	log, err := b.topicLogLocked(topic)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return log.append(logRecord{ID: msg.ID, Timestamp: msg.Timestamp, Headers: msg.Headers, Payload: msg.Payload})
+}
+
+// compactLogs removes log segments holding only messages that are no longer
+// pending, that is acknowledged or given up on
+func (b *Broker) compactLogs() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	_, err = file.Write(append(jsonData, '\n'))
-	return err
+	for topic, log := range b.logs {
+		before := log.next
+		if topicData, exists := b.topics[topic]; exists {
+			for _, pending := range topicData.pendingMsgs {
+				if pending.offset < before {
+					before = pending.offset
+				}
+			}
+		}
+		removed, err := log.compact(before)
+		if err != nil {
+			fmt.Printf("Warning: failed to compact log of topic %s: %v\n", topic, err)
+		}
+		if removed > 0 {
+			if err := log.compactAcks(); err != nil {
+				fmt.Printf("Warning: failed to compact ack log of topic %s: %v\n", topic, err)
+			}
+		}
+	}
 }
 
 // handleAckTimeouts runs in background to handle message acknowledgment timeouts
@@ -558,12 +884,21 @@ func (b *Broker) handleAckTimeouts() {
 	ticker := time.NewTicker(5 * time.Second) // Check every 5 seconds
 	defer ticker.Stop()
 
+	compactInterval := b.config.CompactInterval
+	if compactInterval <= 0 {
+		compactInterval = DefaultCompactInterval
+	}
+	compactTicker := time.NewTicker(compactInterval)
+	defer compactTicker.Stop()
+
 	for {
 		select {
 		case <-b.stopChan:
 			return
 		case <-ticker.C:
 			b.processAckTimeouts()
+		case <-compactTicker.C:
+			b.compactLogs()
 		}
 	}
 }
@@ -576,38 +911,42 @@ func (b *Broker) processAckTimeouts() {
 	now := time.Now()
 
 	for topicName, topicData := range b.topics {
+		if topicData.paused {
+			// Nothing was delivered to time out
+			continue
+		}
+		timeout := b.ackTimeout(topicData)
 		for msgID, pendingMsg := range topicData.pendingMsgs {
-			if pendingMsg.queueIndex == -1 {
+			if pendingMsg.queueIndex == -1 || IsDeadLetterTopic(topicName) {
+				// Dead letters are held until re-driven or purged
 				continue
 			}
-			if now.Sub(pendingMsg.Timestamp) > b.config.AckTimeout {
-				if pendingMsg.Retries < b.config.MaxRetries {
-					// Redeliver message
-					pendingMsg.Retries++
-					pendingMsg.Timestamp = now
-
-					// Send to regular subscribers (payload only)
-					for ch := range topicData.subscribers {
-						select {
-						case ch <- pendingMsg.Message.Payload:
-						default:
-							// Channel is full, skip
-						}
-					}
-
-					// Send to acknowledgment subscribers (full message with ack function)
-					for ch := range topicData.ackSubscribers {
-						select {
-						case ch <- pendingMsg.Message:
-						default:
-							// Channel is full, skip
-						}
-					}
-				} else {
-					// Max retries exceeded, remove from pending
-					b.removePendingMessage(topicName, msgID)
-				}
+			if now.Sub(pendingMsg.Timestamp) > timeout {
+				b.fail(topicName, topicData, msgID, pendingMsg, errAckTimeout, now)
 			}
 		}
 	}
 }
+
+// redeliver sends a pending message to the topic's subscribers again.
+// Caller must hold b.mu.
+func (b *Broker) redeliver(topicName string, topicData *TopicData, pendingMsg *PendingMessage, now time.Time) {
+	pendingMsg.Retries++
+	pendingMsg.Timestamp = now
+	pendingMsg.Message.Retries = pendingMsg.Retries
+	topicData.counters.redelivered++
+	b.metrics.redeliveries.WithLabelValues(topicName).Inc()
+	b.deliver(topicData, pendingMsg)
+}
+
+// deadLetter gives up on a pending message, moving it to the topic's
+// dead-letter topic. Caller must hold b.mu.
+func (b *Broker) deadLetter(topicName string, topicData *TopicData, msgID string) {
+	if pendingMsg, exists := topicData.pendingMsgs[msgID]; exists {
+		if err := b.moveToDeadLetterTopic(topicName, pendingMsg); err != nil {
+			fmt.Printf("Warning: failed to dead-letter message %s of topic %s: %v\n", msgID, topicName, err)
+		}
+	}
+	b.removePendingMessage(topicName, msgID)
+	topicData.counters.deadLettered++
+}
@@ -647,3 +647,50 @@ func TestBrokerAdminEndpoint(t *testing.T) {
 
 	t.Logf("Admin stats test passed: %+v", topicStats)
 }
+
+func TestBrokerTopicCounters(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.AckTimeout = 20 * time.Millisecond
+	config.MaxRetries = 1
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	topic := "counters-topic"
+	ch, unsubscribe, err := broker.SubscribeWithAck(topic)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	for i := 0; i < 3; i++ {
+		if err := broker.Publish(topic, Message{Payload: []byte("counted")}); err != nil {
+			t.Fatalf("Failed to publish message %d: %v", i, err)
+		}
+	}
+	(<-ch).Ack()
+	(<-ch).Ack()
+	<-ch
+
+	// The unacknowledged message is redelivered once, then given up on
+	time.Sleep(2 * config.AckTimeout)
+	broker.processAckTimeouts()
+	<-ch
+	time.Sleep(2 * config.AckTimeout)
+	broker.processAckTimeouts()
+
+	stats := broker.GetStats().Topics[topic]
+	want := TopicStats{PublishedMessages: 3, DeliveredMessages: 4, AckedMessages: 2, RedeliveredMessages: 1, DeadLetteredMessages: 1}
+	got := TopicStats{
+		PublishedMessages:    stats.PublishedMessages,
+		DeliveredMessages:    stats.DeliveredMessages,
+		AckedMessages:        stats.AckedMessages,
+		RedeliveredMessages:  stats.RedeliveredMessages,
+		DeadLetteredMessages: stats.DeadLetteredMessages,
+	}
+	if got != want {
+		t.Errorf("Expected counters %+v, got %+v", want, got)
+	}
+	if stats.PendingMessages != 0 {
+		t.Errorf("Expected nothing pending, got %d", stats.PendingMessages)
+	}
+}
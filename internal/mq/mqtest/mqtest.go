@@ -0,0 +1,271 @@
+// Package mqtest is a conformance suite for mq.BrokerInterface
+// implementations. The in-memory broker, the HTTP client and the gRPC client
+// all run it, and so should any new adapter, e.g. for Kafka or NATS, so that
+// none silently diverges from the publish, subscribe, acknowledgment and
+// redelivery semantics the streamer and collector rely on:
+//
+//	func TestBrokerContract(t *testing.T) {
+//		mqtest.Run(t, func(t *testing.T) mqtest.Target {
+//			broker := mq.NewBroker(mq.DefaultBrokerConfig())
+//			t.Cleanup(broker.Close)
+//			return mqtest.Target{Broker: broker, Expire: mqtest.ExpireBroker(broker)}
+//		})
+//	}
+//
+// The suite covers SubscribeWithAck, which every consumer uses; Subscribe
+// without acknowledgment and publisher-assigned message IDs are not carried
+// by every implementation and are left to their own tests.
+package mqtest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// Topic is the topic the suite publishes to
+const Topic = "contract"
+
+// probeHeader marks the messages published to learn that a subscription is
+// registered, which the suite then skips
+const probeHeader = "mqtest-probe"
+
+// timeout bounds every wait for a message
+const timeout = 5 * time.Second
+
+// quiet is how long the suite waits to be sure nothing more is delivered
+const quiet = 200 * time.Millisecond
+
+// Target is a broker implementation under test
+type Target struct {
+	// Broker is the implementation under test
+	Broker mq.BrokerInterface
+	// Subscriber subscribes to what Broker publishes, for implementations
+	// that only publish, such as mq.HTTPBroker; nil subscribes with Broker
+	Subscriber mq.BrokerInterface
+	// Expire ends the acknowledgment timeout of a topic's unacknowledged
+	// messages now, so the suite need not wait it out to see them redelivered
+	Expire func(topic string)
+}
+
+// NewTarget starts a fresh implementation for a test, releasing it with
+// t.Cleanup
+type NewTarget func(t *testing.T) Target
+
+// ExpireBroker returns an Expire redelivering the messages pending on broker
+func ExpireBroker(broker *mq.Broker) func(topic string) {
+	return func(topic string) {
+		pending, err := broker.PendingMessages(topic)
+		if err != nil {
+			return
+		}
+		for _, info := range pending {
+			_ = broker.Redeliver(topic, info.ID)
+		}
+	}
+}
+
+// Run runs the suite against the implementations newTarget starts, one per
+// subtest
+func Run(t *testing.T, newTarget NewTarget) {
+	for _, test := range []struct {
+		name string
+		run  func(t *testing.T, target Target)
+	}{
+		{"DeliversPayloadAndHeaders", testDeliversPayloadAndHeaders},
+		{"DeliversInPublishOrder", testDeliversInPublishOrder},
+		{"AcknowledgedNotRedelivered", testAcknowledgedNotRedelivered},
+		{"UnacknowledgedRedelivered", testUnacknowledgedRedelivered},
+		{"UnsubscribeClosesChannel", testUnsubscribeClosesChannel},
+		{"RejectsInvalidTopic", testRejectsInvalidTopic},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			target := newTarget(t)
+			if target.Subscriber == nil {
+				target.Subscriber = target.Broker
+			}
+			test.run(t, target)
+		})
+	}
+}
+
+func testDeliversPayloadAndHeaders(t *testing.T, target Target) {
+	ch := subscribe(t, target)
+	publish(t, target, mq.Message{Payload: []byte(`{"gpu_id":"0"}`), Headers: map[string]string{"trace-id": "abc"}})
+
+	msg := receive(t, ch)
+	if string(msg.Payload) != `{"gpu_id":"0"}` {
+		t.Errorf("Expected the published payload, got %s", msg.Payload)
+	}
+	if msg.Headers["trace-id"] != "abc" {
+		t.Errorf("Expected header trace-id=abc, got %v", msg.Headers)
+	}
+	if msg.ID == "" {
+		t.Error("Expected the broker to assign an ID")
+	}
+	if msg.Timestamp.IsZero() {
+		t.Error("Expected the publish time")
+	}
+	if msg.Retries != 0 {
+		t.Errorf("Expected a first delivery, got %d retries", msg.Retries)
+	}
+	msg.Ack()
+}
+
+func testDeliversInPublishOrder(t *testing.T, target Target) {
+	ch := subscribe(t, target)
+	const count = 20
+	for i := 0; i < count; i++ {
+		publish(t, target, mq.Message{Payload: []byte(fmt.Sprintf(`{"seq":%d}`, i))})
+	}
+	for i := 0; i < count; i++ {
+		msg := receive(t, ch)
+		if want := fmt.Sprintf(`{"seq":%d}`, i); string(msg.Payload) != want {
+			t.Fatalf("Expected message %d to be %s, got %s", i, want, msg.Payload)
+		}
+		msg.Ack()
+	}
+}
+
+func testAcknowledgedNotRedelivered(t *testing.T, target Target) {
+	ch := subscribe(t, target)
+	publish(t, target, mq.Message{Payload: []byte(`{}`)})
+	receive(t, ch).Ack()
+
+	target.Expire(Topic)
+	expectNone(t, ch)
+}
+
+func testUnacknowledgedRedelivered(t *testing.T, target Target) {
+	ch := subscribe(t, target)
+	publish(t, target, mq.Message{Payload: []byte(`{"unacked":true}`)})
+	first := receive(t, ch)
+
+	target.Expire(Topic)
+	again := receive(t, ch)
+	if again.ID != first.ID || string(again.Payload) != string(first.Payload) {
+		t.Errorf("Expected %s redelivered, got %s (%s)", first.ID, again.ID, again.Payload)
+	}
+	if again.Retries != 1 {
+		t.Errorf("Expected the redelivery to count 1 retry, got %d", again.Retries)
+	}
+	again.Ack()
+
+	target.Expire(Topic)
+	expectNone(t, ch)
+}
+
+func testUnsubscribeClosesChannel(t *testing.T, target Target) {
+	ch, unsubscribe, err := target.Subscriber.SubscribeWithAck(Topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsubscribe()
+	unsubscribe() // a second call does nothing
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("Expected unsubscribing to close the channel")
+		}
+	}
+}
+
+func testRejectsInvalidTopic(t *testing.T, target Target) {
+	for _, topic := range []string{"", "bad topic", "../etc"} {
+		if err := target.Broker.Publish(topic, mq.Message{Payload: []byte(`{}`)}); !errors.Is(err, mq.ErrInvalidTopic) {
+			t.Errorf("Publish to %q: expected ErrInvalidTopic, got %v", topic, err)
+		}
+		if _, _, err := target.Subscriber.SubscribeWithAck(topic); !errors.Is(err, mq.ErrInvalidTopic) {
+			t.Errorf("SubscribeWithAck to %q: expected ErrInvalidTopic, got %v", topic, err)
+		}
+	}
+}
+
+// subscribe subscribes to Topic, returning once the subscription receives.
+// Subscriptions to a remote broker are registered after the call returns, so
+// probe messages are published until one arrives.
+func subscribe(t *testing.T, target Target) chan mq.Message {
+	t.Helper()
+	ch, unsubscribe, err := target.Subscriber.SubscribeWithAck(Topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(unsubscribe)
+
+	probe := mq.Message{Payload: []byte(`{}`), Headers: map[string]string{probeHeader: "1"}}
+	deadline := time.After(timeout)
+	for {
+		publish(t, target, probe)
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				t.Fatal("Subscription closed before receiving")
+			}
+			msg.Ack()
+			return ch
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("Timed out waiting for the subscription to receive")
+		}
+	}
+}
+
+// publish publishes msg to Topic
+func publish(t *testing.T, target Target, msg mq.Message) {
+	t.Helper()
+	if err := target.Broker.Publish(Topic, msg); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+}
+
+// receive returns the next message on ch other than a probe
+func receive(t *testing.T, ch chan mq.Message) mq.Message {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				t.Fatal("Subscription closed while waiting for a message")
+			}
+			if msg.Headers[probeHeader] != "" {
+				msg.Ack()
+				continue
+			}
+			return msg
+		case <-deadline:
+			t.Fatal("Timed out waiting for a message")
+		}
+	}
+}
+
+// expectNone fails the test if ch delivers anything but a probe for a while
+func expectNone(t *testing.T, ch chan mq.Message) {
+	t.Helper()
+	deadline := time.After(quiet)
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if msg.Headers[probeHeader] != "" {
+				msg.Ack()
+				continue
+			}
+			t.Errorf("Expected nothing delivered, got %s (%d retries)", msg.Payload, msg.Retries)
+			msg.Ack()
+		case <-deadline:
+			return
+		}
+	}
+}
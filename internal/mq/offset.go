@@ -0,0 +1,193 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOffsetOutOfRange is returned when replaying a topic from an offset its
+// log does not hold, because the messages there were compacted away or not
+// yet published, or because the broker does not persist messages
+var ErrOffsetOutOfRange = errors.New("offset out of range")
+
+// replayBatchSize is how many logged messages a replaying subscription reads
+// under the broker's lock at a time
+const replayBatchSize = 100
+
+// errReplayBatchFull stops reading a log once a batch is read
+var errReplayBatchFull = errors.New("replay batch full")
+
+// OffsetSubscriber is implemented by brokers and clients that can replay a
+// topic from an offset
+type OffsetSubscriber interface {
+	SubscribeFrom(topic string, offset uint64) (chan Message, func(), error)
+}
+
+// offsetSubscription is a subscription replaying a topic's log. Its fields
+// are guarded by the broker's lock.
+type offsetSubscription struct {
+	ch chan Message
+	// stop is closed by unsubscribing while the log is still replayed
+	stop    chan struct{}
+	stopped bool
+	// live is set once the subscription has caught up with the log and
+	// receives messages as they are published
+	live bool
+}
+
+// SubscribeFrom subscribes to a topic with acknowledgment support, first
+// replaying the messages persisted in its log from offset on, oldest first,
+// then switching to live delivery without a gap or a duplicate. Message.Offset
+// reports each message's position, so a consumer can resume after the last
+// one it handled. A replayed message still pending for the topic's
+// subscribers outside any group is acknowledged as usual; acknowledging one
+// that is not does nothing. Offsets are only kept with persistence, and
+// until compaction removes the segment holding them; other offsets fail with
+// ErrOffsetOutOfRange.
+func (b *Broker) SubscribeFrom(topic string, offset uint64) (chan Message, func(), error) {
+	if err := ValidateTopic(topic); err != nil {
+		return nil, nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+	if !b.config.PersistenceEnabled {
+		return nil, nil, fmt.Errorf("%w: topic %s is not persisted", ErrOffsetOutOfRange, topic)
+	}
+	first, next := b.offsetRange(topic)
+	if offset < first || offset > next {
+		return nil, nil, fmt.Errorf("%w: topic %s holds offsets %d to %d, not %d", ErrOffsetOutOfRange, topic, first, next, offset)
+	}
+
+	sub := &offsetSubscription{ch: make(chan Message, 100), stop: make(chan struct{})}
+	go b.replayFrom(topic, offset, sub)
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if sub.stopped {
+			return
+		}
+		sub.stopped = true
+		if !sub.live {
+			// The replay closes the channel once it sees the stop
+			close(sub.stop)
+			return
+		}
+		if topicData, exists := b.topics[topic]; exists {
+			if _, exists := topicData.ackSubscribers[sub.ch]; exists {
+				delete(topicData.ackSubscribers, sub.ch)
+				close(sub.ch)
+			}
+		}
+	}
+	return sub.ch, unsubscribe, nil
+}
+
+// offsetRange returns the first offset a topic's log holds and the offset
+// its next message gets. Caller must hold b.mu.
+func (b *Broker) offsetRange(topic string) (uint64, uint64) {
+	log, exists := b.logs[topic]
+	if !exists {
+		return 0, 0
+	}
+	if len(log.bases) == 0 {
+		return log.next, log.next
+	}
+	return log.bases[0], log.next
+}
+
+// replayFrom sends a subscription the topic's logged messages from offset
+// on, a batch at a time, and registers it for live delivery once it has
+// caught up. Sending happens without the lock, so a slow subscriber holds up
+// only itself.
+func (b *Broker) replayFrom(topic string, offset uint64, sub *offsetSubscription) {
+	for {
+		b.mu.Lock()
+		if b.closed || sub.stopped {
+			b.mu.Unlock()
+			close(sub.ch)
+			return
+		}
+		log, exists := b.logs[topic]
+		if !exists || offset >= log.next {
+			// Caught up: later messages are delivered as they are published
+			b.topicDataLocked(topic).ackSubscribers[sub.ch] = struct{}{}
+			sub.live = true
+			b.mu.Unlock()
+			return
+		}
+		batch, err := b.readReplayBatch(topic, log, offset)
+		b.mu.Unlock()
+		if err != nil {
+			fmt.Printf("Warning: failed to replay topic %s from offset %d: %v\n", topic, offset, err)
+			b.mu.Lock()
+			sub.stopped = true
+			b.mu.Unlock()
+			close(sub.ch)
+			return
+		}
+
+		for _, msg := range batch {
+			select {
+			case sub.ch <- msg:
+			case <-sub.stop:
+				close(sub.ch)
+				return
+			case <-b.stopChan:
+				close(sub.ch)
+				return
+			}
+		}
+		offset = batch[len(batch)-1].Offset + 1
+	}
+}
+
+// readReplayBatch reads up to replayBatchSize logged messages of a topic
+// from offset on. A message still pending for the subscribers outside any
+// group is returned as delivered to them, so acknowledging either copy
+// acknowledges it. Caller must hold b.mu.
+func (b *Broker) readReplayBatch(topic string, log *topicLog, offset uint64) ([]Message, error) {
+	topicData := b.topics[topic]
+	batch := make([]Message, 0, replayBatchSize)
+	err := log.read(offset, func(record logRecord) error {
+		if topicData != nil {
+			if pending, exists := topicData.pendingMsgs[record.ID]; exists && pending.offset == record.Offset && pending.group == "" {
+				batch = append(batch, pending.Message)
+				return replayBatchFull(batch)
+			}
+		}
+		batch = append(batch, Message{
+			ID:        record.ID,
+			Timestamp: record.Timestamp,
+			Payload:   record.Payload,
+			Headers:   record.Headers,
+			Offset:    record.Offset,
+			Ack:       func() {},
+		})
+		return replayBatchFull(batch)
+	})
+	if err != nil && !errors.Is(err, errReplayBatchFull) {
+		return nil, err
+	}
+	if len(batch) == 0 {
+		return nil, fmt.Errorf("%w: offset %d was compacted", ErrOffsetOutOfRange, offset)
+	}
+	return batch, nil
+}
+
+// replayBatchFull stops reading the log once batch holds replayBatchSize
+// messages
+func replayBatchFull(batch []Message) error {
+	if len(batch) >= replayBatchSize {
+		return errReplayBatchFull
+	}
+	return nil
+}
+
+// Ensure the broker and the gRPC client can replay topics
+var _ OffsetSubscriber = (*Broker)(nil)
+var _ OffsetSubscriber = (*GRPCBrokerClient)(nil)
@@ -0,0 +1,104 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// receiveOffsets receives n messages from ch, returning their offsets
+func receiveOffsets(t *testing.T, ch chan Message, n int) []uint64 {
+	t.Helper()
+	offsets := make([]uint64, 0, n)
+	for len(offsets) < n {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				t.Fatalf("Subscription closed after %d messages", len(offsets))
+			}
+			offsets = append(offsets, msg.Offset)
+			msg.Ack()
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %d messages, got offsets %v", n, offsets)
+		}
+	}
+	return offsets
+}
+
+func TestBroker_SubscribeFrom(t *testing.T) {
+	broker := NewBroker(persistentConfig(t.TempDir()))
+	defer broker.Close()
+
+	// More messages than a replay batch, acknowledged so only the log holds
+	// them
+	ch, unsubscribe, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 150; i++ {
+		if err := broker.Publish("telemetry", Message{ID: fmt.Sprintf("m%d", i), Payload: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+		(<-ch).Ack()
+	}
+	unsubscribe()
+
+	replay, unsubscribe, err := broker.SubscribeFrom("telemetry", 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	offsets := receiveOffsets(t, replay, 110)
+	for i, offset := range offsets {
+		if offset != uint64(40+i) {
+			t.Fatalf("Expected offsets 40 to 149 in order, got %d at %d", offset, i)
+		}
+	}
+
+	// Once caught up the subscription receives new messages live
+	if err := broker.Publish("telemetry", Message{ID: "live", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if offsets := receiveOffsets(t, replay, 1); offsets[0] != 150 {
+		t.Errorf("Expected the live message at offset 150, got %d", offsets[0])
+	}
+
+	if stats, _ := broker.GetTopicStats("telemetry"); stats.NextOffset != 151 {
+		t.Errorf("Expected next offset 151, got %+v", stats)
+	}
+	if _, _, err := broker.SubscribeFrom("telemetry", 500); !errors.Is(err, ErrOffsetOutOfRange) {
+		t.Errorf("Expected ErrOffsetOutOfRange past the end, got %v", err)
+	}
+}
+
+func TestBroker_SubscribeFromPendingMessage(t *testing.T) {
+	broker := NewBroker(persistentConfig(t.TempDir()))
+	defer broker.Close()
+	if err := broker.Publish("telemetry", Message{ID: "m1", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A replayed message still pending is acknowledged by the replayed copy
+	replay, unsubscribe, err := broker.SubscribeFrom("telemetry", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiveOffsets(t, replay, 1)
+	if stats, _ := broker.GetTopicStats("telemetry"); stats.PendingMessages != 0 {
+		t.Errorf("Expected the message acknowledged, got %+v", stats)
+	}
+
+	unsubscribe()
+	if _, ok := <-replay; ok {
+		t.Error("Expected the channel closed on unsubscribe")
+	}
+}
+
+func TestBroker_SubscribeFromWithoutPersistence(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+	if _, _, err := broker.SubscribeFrom("telemetry", 0); !errors.Is(err, ErrOffsetOutOfRange) {
+		t.Errorf("Expected ErrOffsetOutOfRange without persistence, got %v", err)
+	}
+}
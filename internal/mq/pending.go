@@ -0,0 +1,117 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrMessageNotFound is returned when a message is not pending on a topic,
+// because it was never published there, has been acknowledged or was given
+// up on
+var ErrMessageNotFound = errors.New("message not pending")
+
+// PendingInfo describes a message awaiting acknowledgment
+type PendingInfo struct {
+	// ID is the ID to redeliver or dead-letter the message by. It is the
+	// message's own ID unless another message with that ID was already
+	// pending when it was published.
+	ID        string `json:"id"`
+	MessageID string `json:"message_id"`
+	// Group is the consumer group the copy is pending for, if any
+	Group       string    `json:"group,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	// AgeSeconds is the time since the message was published
+	AgeSeconds float64 `json:"age_seconds"`
+	// LastDeliveredAt is when the message was last delivered, or published
+	// if it has not been redelivered
+	LastDeliveredAt time.Time `json:"last_delivered_at"`
+	Retries         int       `json:"retries"`
+	// Failures counts deliveries that were nacked or timed out, and
+	// LastError is what the latest of them failed with
+	Failures  int    `json:"failures"`
+	LastError string `json:"last_error,omitempty"`
+	SizeBytes int    `json:"size_bytes"`
+}
+
+// PendingMessages lists a topic's messages awaiting acknowledgment, oldest
+// first, or returns ErrTopicNotFound if nothing has used the topic
+func (b *Broker) PendingMessages(topic string) ([]PendingInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	topicData, exists := b.topics[topic]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	}
+
+	now := time.Now()
+	pending := make([]PendingInfo, 0, len(topicData.pendingMsgs))
+	for id, msg := range topicData.pendingMsgs {
+		pending = append(pending, PendingInfo{
+			ID:              id,
+			MessageID:       msg.MessageID,
+			Group:           msg.group,
+			PublishedAt:     msg.Message.Timestamp,
+			AgeSeconds:      now.Sub(msg.Message.Timestamp).Seconds(),
+			LastDeliveredAt: msg.Timestamp,
+			Retries:         msg.Retries,
+			Failures:        msg.failures,
+			LastError:       msg.lastError,
+			SizeBytes:       len(msg.Message.Payload),
+		})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].PublishedAt.Equal(pending[j].PublishedAt) {
+			return pending[i].PublishedAt.Before(pending[j].PublishedAt)
+		}
+		return pending[i].ID < pending[j].ID
+	})
+	return pending, nil
+}
+
+// Redeliver sends a pending message to the topic's subscribers now, without
+// waiting for its acknowledgment timeout. It counts as a retry and restarts
+// the timeout, but is allowed even once the retries are used up.
+func (b *Broker) Redeliver(topic, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topicData, pendingMsg, err := b.lookupPending(topic, id)
+	if err != nil {
+		return err
+	}
+	b.redeliver(topic, topicData, pendingMsg, time.Now())
+	return nil
+}
+
+// DeadLetter gives up on a pending message, as if it had run out of
+// retries, so a message that keeps failing need not wait them out
+func (b *Broker) DeadLetter(topic, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topicData, _, err := b.lookupPending(topic, id)
+	if err != nil {
+		return err
+	}
+	b.deadLetter(topic, topicData, id)
+	return nil
+}
+
+// lookupPending finds a pending message. Caller must hold b.mu.
+func (b *Broker) lookupPending(topic, id string) (*TopicData, *PendingMessage, error) {
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+	topicData, exists := b.topics[topic]
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	}
+	pendingMsg, exists := topicData.pendingMsgs[id]
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: %s on topic %s", ErrMessageNotFound, id, topic)
+	}
+	return topicData, pendingMsg, nil
+}
@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBroker_PendingAdmin(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+
+	if _, err := broker.PendingMessages("missing"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound for an unused topic, got %v", err)
+	}
+
+	messages, unsubscribe, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	published := time.Now().Add(-time.Minute)
+	for _, id := range []string{"poison", "healthy"} {
+		if err := broker.Publish("telemetry", Message{ID: id, Timestamp: published, Payload: []byte(id)}); err != nil {
+			t.Fatal(err)
+		}
+		<-messages
+	}
+
+	pending, err := broker.PendingMessages("telemetry")
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("PendingMessages = %+v, %v", pending, err)
+	}
+	if pending[0].ID != "healthy" || pending[0].AgeSeconds < 60 || pending[0].Retries != 0 || pending[0].SizeBytes != 7 {
+		t.Errorf("Unexpected pending message %+v", pending[0])
+	}
+
+	// Forcing a redelivery hands the message out again as a retry
+	if err := broker.Redeliver("telemetry", "poison"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-messages:
+		if msg.ID != "poison" || msg.Retries != 1 {
+			t.Errorf("Expected poison redelivered as retry 1, got %s retry %d", msg.ID, msg.Retries)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message redelivered immediately")
+	}
+
+	if err := broker.DeadLetter("telemetry", "poison"); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.DeadLetter("telemetry", "poison"); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound once dead-lettered, got %v", err)
+	}
+	if err := broker.Redeliver("telemetry", "missing"); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+
+	stats, _ := broker.GetTopicStats("telemetry")
+	if stats.PendingMessages != 1 || stats.DeadLetteredMessages != 1 || stats.RedeliveredMessages != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,106 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PoisonTopicSuffix is appended to a topic's name to form the topic its
+// poison messages are quarantined to
+const PoisonTopicSuffix = ".poison"
+
+// Headers added to a quarantined poison message
+const (
+	// HeaderPoisonError is the last error the message failed with
+	HeaderPoisonError = "x-poison-error"
+	// HeaderPoisonFailures is the number of times the message failed
+	HeaderPoisonFailures = "x-poison-failures"
+	// HeaderPoisonTopic is the topic the message was quarantined from
+	HeaderPoisonTopic = "x-poison-topic"
+)
+
+var (
+	// errAckTimeout is the failure recorded when a delivery is not
+	// acknowledged in time
+	errAckTimeout = errors.New("not acknowledged within the ack timeout")
+	// errNacked is the failure recorded for a Nack without an error
+	errNacked = errors.New("processing failed")
+)
+
+// PoisonTopic returns the topic poison messages from topic are quarantined to
+func PoisonTopic(topic string) string {
+	return topic + PoisonTopicSuffix
+}
+
+// nack records a failed delivery reported by a consumer. Caller must hold
+// b.mu.
+func (b *Broker) nack(receipt *ackReceipt, err error) {
+	topicData, exists := b.topics[receipt.topic]
+	if !exists {
+		return
+	}
+	pendingMsg, exists := topicData.pendingMsgs[receipt.msgID]
+	if !exists {
+		return
+	}
+	if err == nil {
+		err = errNacked
+	}
+	b.fail(receipt.topic, topicData, receipt.msgID, pendingMsg, err, time.Now())
+}
+
+// fail records a failed delivery of a pending message, then quarantines the
+// message if it has failed more than PoisonThreshold times, redelivers it
+// if it has retries left, or else gives up on it. Caller must hold b.mu.
+func (b *Broker) fail(topicName string, topicData *TopicData, msgID string, pendingMsg *PendingMessage, err error, now time.Time) {
+	pendingMsg.failures++
+	pendingMsg.lastError = err.Error()
+
+	threshold := b.config.PoisonThreshold
+	if threshold > 0 && pendingMsg.failures > threshold && !strings.HasSuffix(topicName, PoisonTopicSuffix) {
+		err := b.quarantine(topicName, topicData, msgID, pendingMsg)
+		if err == nil {
+			return
+		}
+		// Fall back to retrying the message
+		fmt.Printf("Warning: failed to quarantine poison message %s of topic %s: %v\n", msgID, topicName, err)
+	}
+
+	if pendingMsg.Retries < b.config.MaxRetries {
+		b.redeliver(topicName, topicData, pendingMsg, now)
+	} else {
+		// Max retries exceeded, remove from pending
+		b.deadLetter(topicName, topicData, msgID)
+		b.metrics.expired.WithLabelValues(topicName).Inc()
+	}
+}
+
+// quarantine moves a pending message to the topic's poison topic, with the
+// error it last failed with in its headers. Caller must hold b.mu.
+func (b *Broker) quarantine(topicName string, topicData *TopicData, msgID string, pendingMsg *PendingMessage) error {
+	headers := make(map[string]string, len(pendingMsg.Message.Headers)+3)
+	for name, value := range pendingMsg.Message.Headers {
+		headers[name] = value
+	}
+	headers[HeaderPoisonError] = pendingMsg.lastError
+	headers[HeaderPoisonFailures] = strconv.Itoa(pendingMsg.failures)
+	headers[HeaderPoisonTopic] = topicName
+
+	poison := Message{
+		ID:        pendingMsg.Message.ID,
+		Timestamp: pendingMsg.Message.Timestamp,
+		Payload:   pendingMsg.Message.Payload,
+		Headers:   headers,
+	}
+	if err := b.publishLocked(PoisonTopic(topicName), poison); err != nil {
+		return err
+	}
+
+	b.removePendingMessage(topicName, msgID)
+	topicData.counters.poisoned++
+	b.metrics.poisoned.WithLabelValues(topicName).Inc()
+	return nil
+}
@@ -0,0 +1,101 @@
+package mq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBroker_QuarantinesPoisonMessages(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.PoisonThreshold = 2
+	config.MaxRetries = 10
+	config.AckTimeout = 10 * time.Millisecond
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	messages, unsubscribe, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	poisoned, unsubscribePoison, err := broker.SubscribeWithAck(PoisonTopic("telemetry"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribePoison()
+
+	headers := map[string]string{"source": "host-1"}
+	if err := broker.Publish("telemetry", Message{ID: "poison", Payload: []byte("{"), Headers: headers}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nacked deliveries are redelivered at once until the threshold, and a
+	// timeout counts as a failure too
+	(<-messages).Nack(errors.New("unexpected end of JSON input"))
+	msg := <-messages
+	if msg.Retries != 1 || msg.Headers["source"] != "host-1" {
+		t.Fatalf("Expected a redelivery with the headers kept, got retry %d %v", msg.Retries, msg.Headers)
+	}
+	if pending, _ := broker.PendingMessages("telemetry"); len(pending) != 1 || pending[0].Failures != 1 || !strings.Contains(pending[0].LastError, "JSON") {
+		t.Fatalf("Unexpected pending messages %+v", pending)
+	}
+	time.Sleep(2 * config.AckTimeout)
+	broker.processAckTimeouts()
+	(<-messages).Nack(errors.New("invalid character"))
+
+	select {
+	case quarantined := <-poisoned:
+		if quarantined.ID != "poison" || string(quarantined.Payload) != "{" {
+			t.Errorf("Unexpected quarantined message %+v", quarantined)
+		}
+		for name, want := range map[string]string{
+			HeaderPoisonError:    "invalid character",
+			HeaderPoisonFailures: "3",
+			HeaderPoisonTopic:    "telemetry",
+			"source":             "host-1",
+		} {
+			if got := quarantined.Headers[name]; got != want {
+				t.Errorf("Header %s = %q, want %q", name, got, want)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message quarantined to the poison topic")
+	}
+
+	stats, _ := broker.GetTopicStats("telemetry")
+	if stats.PendingMessages != 0 || stats.PoisonedMessages != 1 || stats.DeadLetteredMessages != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if out := scrapeBroker(t, broker); !strings.Contains(out, `mq_poison_messages_total{topic="telemetry"} 1`) {
+		t.Errorf("Expected the quarantine counted:\n%s", out)
+	}
+}
+
+func TestBroker_PoisonTopicsAreNotQuarantined(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.PoisonThreshold = 1
+	config.MaxRetries = 1
+	broker := NewBroker(config)
+	defer broker.Close()
+
+	topic := PoisonTopic("telemetry")
+	messages, unsubscribe, err := broker.SubscribeWithAck(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	if err := broker.Publish(topic, Message{Payload: []byte("m")}); err != nil {
+		t.Fatal(err)
+	}
+	(<-messages).Nack(nil)
+	(<-messages).Nack(nil)
+
+	if _, err := broker.GetTopicStats(PoisonTopic(topic)); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("Expected no poison topic for a poison topic, got %v", err)
+	}
+	if stats, _ := broker.GetTopicStats(topic); stats.DeadLetteredMessages != 1 {
+		t.Errorf("Expected the message given up on after its retries, got %+v", stats)
+	}
+}
@@ -0,0 +1,266 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+// ProtocolVersion is the version of the protocol the services speak to each
+// other, as major.minor. Peers with a different major version are
+// incompatible; minor versions only add optional fields. Version 1.1 added
+// the Ack RPC.
+const ProtocolVersion = "1.1"
+
+// ProtocolHeader carries the sender's protocol version in gRPC metadata,
+// HTTP headers and message headers
+const ProtocolHeader = "x-telemetry-protocol"
+
+// legacyProtocolVersion is assumed for peers that send no version, which
+// predate the handshake
+const legacyProtocolVersion = "1.0"
+
+// ackProtocolMinor is the first minor version of protocol 1 whose gRPC
+// subscribers acknowledge streamed messages with the Ack RPC
+const ackProtocolMinor = 1
+
+// ErrIncompatibleProtocol is returned when a peer speaks an incompatible
+// protocol version and the guard is strict
+var ErrIncompatibleProtocol = errors.New("incompatible protocol version")
+
+// CheckProtocol returns an error wrapping ErrIncompatibleProtocol if a peer
+// speaking version is incompatible with this build. An empty version is a
+// peer that predates the handshake.
+func CheckProtocol(version string) error {
+	if version == "" {
+		version = legacyProtocolVersion
+	}
+	ours, _ := protocolMajor(ProtocolVersion)
+	theirs, ok := protocolMajor(version)
+	if !ok || theirs != ours {
+		return fmt.Errorf("%w: peer speaks %q, this service speaks %s", ErrIncompatibleProtocol, version, ProtocolVersion)
+	}
+	return nil
+}
+
+// protocolMajor returns the major part of a major.minor version
+func protocolMajor(version string) (int, bool) {
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	return n, err == nil && n >= 0
+}
+
+// protocolMinor returns the minor part of a major.minor version, 0 if it has
+// none
+func protocolMinor(version string) int {
+	_, minor, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(minor)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// PeerAcknowledges reports whether the gRPC client of ctx acknowledges
+// streamed messages with the Ack RPC. Clients speaking a version before 1.1,
+// or none, rely on the server acknowledging each message once it is sent.
+func PeerAcknowledges(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	version := firstValue(md, ProtocolHeader)
+	if version == "" {
+		version = legacyProtocolVersion
+	}
+	major, ok := protocolMajor(version)
+	if !ok {
+		return false
+	}
+	return major > 1 || protocolMinor(version) >= ackProtocolMinor
+}
+
+// ProtocolGuard checks the protocol version of peers. Each incompatible
+// version is logged once and every exchange with an incompatible peer is
+// counted; a strict guard also refuses them.
+type ProtocolGuard struct {
+	strict bool
+	log    *logger.Logger
+
+	mu           sync.Mutex
+	warned       map[string]bool
+	incompatible *metrics.CounterVec
+}
+
+// NewProtocolGuard creates a guard; strict guards refuse incompatible peers
+func NewProtocolGuard(strict bool, log *logger.Logger) *ProtocolGuard {
+	return &ProtocolGuard{
+		strict: strict,
+		log:    log,
+		warned: make(map[string]bool),
+	}
+}
+
+// defaultProtocolGuard is used by clients without a guard of their own
+func defaultProtocolGuard() *ProtocolGuard {
+	return NewProtocolGuard(false, logger.GetGlobalLogger().WithComponent("mq"))
+}
+
+// RegisterMetrics adds protocol_incompatible_peers_total to registry
+func (g *ProtocolGuard) RegisterMetrics(registry *metrics.Registry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.incompatible = registry.NewCounterVec("protocol_incompatible_peers_total",
+		"Exchanges with peers speaking an incompatible protocol version.", "peer_version")
+}
+
+// Check checks the version a peer sent. The error, wrapping
+// ErrIncompatibleProtocol, is only returned by strict guards.
+func (g *ProtocolGuard) Check(peer, version string) error {
+	err := CheckProtocol(version)
+	if err == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	first := !g.warned[version]
+	g.warned[version] = true
+	if g.incompatible != nil {
+		g.incompatible.WithLabelValues(version).Inc()
+	}
+	g.mu.Unlock()
+
+	if first {
+		g.log.Warn("Peer speaks an incompatible protocol version",
+			"peer", peer, "peer_version", version, "protocol_version", ProtocolVersion, "refused", g.strict)
+	}
+	if g.strict {
+		return err
+	}
+	return nil
+}
+
+// IncompatibleVersions returns the incompatible versions peers have sent
+func (g *ProtocolGuard) IncompatibleVersions() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	versions := make([]string, 0, len(g.warned))
+	for version := range g.warned {
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// firstValue returns the first value of key in md, or ""
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// serverCheck checks the version a gRPC client sent
+func (g *ProtocolGuard) serverCheck(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	address := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+	if err := g.Check(address, firstValue(md, ProtocolHeader)); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+// UnaryServerInterceptor checks clients' versions and sends the server's
+func (g *ProtocolGuard) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(ProtocolHeader, ProtocolVersion))
+		if err := g.serverCheck(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor checks clients' versions and sends the server's
+// as soon as the stream opens
+func (g *ProtocolGuard) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		_ = ss.SendHeader(metadata.Pairs(ProtocolHeader, ProtocolVersion))
+		if err := g.serverCheck(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// unaryClientInterceptor sends the client's version and checks the server's
+func (g *ProtocolGuard) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, ProtocolHeader, ProtocolVersion)
+		var header metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+		if err != nil {
+			return err
+		}
+		return g.Check(cc.Target(), firstValue(header, ProtocolHeader))
+	}
+}
+
+// streamClientInterceptor sends the client's version and checks the
+// server's with the first message received, so opening a stream to a server
+// that sends no headers until then does not block
+func (g *ProtocolGuard) streamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = metadata.AppendToOutgoingContext(ctx, ProtocolHeader, ProtocolVersion)
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &checkedClientStream{ClientStream: stream, guard: g, target: cc.Target()}, nil
+	}
+}
+
+// checkedClientStream checks the server's version on the first message
+type checkedClientStream struct {
+	grpc.ClientStream
+	guard  *ProtocolGuard
+	target string
+	once   sync.Once
+	err    error
+}
+
+func (s *checkedClientStream) RecvMsg(m interface{}) error {
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.once.Do(func() {
+		header, _ := s.ClientStream.Header()
+		s.err = s.guard.Check(s.target, firstValue(header, ProtocolHeader))
+	})
+	return s.err
+}
+
+// HTTPMiddleware checks the version HTTP clients send, refusing incompatible
+// ones with 400 if the guard is strict, and sends the server's
+func (g *ProtocolGuard) HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ProtocolHeader, ProtocolVersion)
+		if err := g.Check(r.RemoteAddr, r.Header.Get(ProtocolHeader)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
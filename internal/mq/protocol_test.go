@@ -0,0 +1,182 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+	pb "github.com/harishb93/telemetry-pipeline/proto"
+)
+
+func TestCheckProtocol(t *testing.T) {
+	for version, compatible := range map[string]bool{
+		"":     true, // predates the handshake
+		"1":    true,
+		"1.0":  true,
+		"1.7":  true,
+		"2.0":  false,
+		"0.9":  false,
+		"v1":   false,
+		"junk": false,
+	} {
+		err := CheckProtocol(version)
+		if (err == nil) != compatible {
+			t.Errorf("%q: expected compatible=%v, got %v", version, compatible, err)
+		}
+		if err != nil && !errors.Is(err, ErrIncompatibleProtocol) {
+			t.Errorf("%q: expected ErrIncompatibleProtocol, got %v", version, err)
+		}
+	}
+}
+
+func TestProtocolGuard(t *testing.T) {
+	registry := metrics.NewRegistry()
+	lenient := NewProtocolGuard(false, logger.NewFromEnv())
+	lenient.RegisterMetrics(registry)
+
+	for i := 0; i < 3; i++ {
+		if err := lenient.Check("peer", "2.0"); err != nil {
+			t.Fatalf("Expected a lenient guard only to warn, got %v", err)
+		}
+	}
+	if err := lenient.Check("peer", "1.1"); err != nil {
+		t.Fatalf("Expected 1.1 to be compatible, got %v", err)
+	}
+	if versions := lenient.IncompatibleVersions(); len(versions) != 1 || versions[0] != "2.0" {
+		t.Errorf("Expected incompatible versions [2.0], got %v", versions)
+	}
+
+	rr := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `protocol_incompatible_peers_total{peer_version="2.0"} 3`; !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("Expected %q in metrics:\n%s", want, rr.Body.String())
+	}
+
+	strict := NewProtocolGuard(true, logger.NewFromEnv())
+	if err := strict.Check("peer", "2.0"); !errors.Is(err, ErrIncompatibleProtocol) {
+		t.Errorf("Expected a strict guard to refuse 2.0, got %v", err)
+	}
+}
+
+func TestProtocolGuard_HTTPMiddleware(t *testing.T) {
+	handler := NewProtocolGuard(true, logger.NewFromEnv()).HTTPMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	for version, want := range map[string]int{"": http.StatusOK, "1.2": http.StatusOK, "2.0": http.StatusBadRequest} {
+		req := httptest.NewRequest("POST", "/publish/telemetry", nil)
+		if version != "" {
+			req.Header.Set(ProtocolHeader, version)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("%q: expected status %d, got %d", version, want, rr.Code)
+		}
+		if got := rr.Header().Get(ProtocolHeader); got != ProtocolVersion {
+			t.Errorf("%q: expected the server's version in the response, got %q", version, got)
+		}
+	}
+}
+
+func TestHTTPBroker_RefusesIncompatibleServer(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if got := r.Header.Get(ProtocolHeader); got != ProtocolVersion {
+			t.Errorf("Expected the client's version in the request, got %q", got)
+		}
+		w.Header().Set(ProtocolHeader, "2.0")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	broker := NewHTTPBrokerWithGuard(server.URL, NewProtocolGuard(true, logger.NewFromEnv()))
+	for i := 0; i < 2; i++ {
+		if err := broker.Publish("telemetry", Message{Payload: []byte(`{}`)}); !errors.Is(err, ErrIncompatibleProtocol) {
+			t.Fatalf("Publish %d: expected ErrIncompatibleProtocol, got %v", i, err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected nothing sent once the server was refused, got %d requests", n)
+	}
+}
+
+// healthServer answers Health, advertising version in its headers
+type healthServer struct {
+	pb.UnimplementedMQServiceServer
+}
+
+func (healthServer) Health(ctx context.Context, req *pb.HealthRequest) (*pb.HealthResponse, error) {
+	return &pb.HealthResponse{Status: "healthy"}, nil
+}
+
+// startGRPCServer serves healthServer behind a strict guard, or advertising
+// another version when advertise is set
+func startGRPCServer(t *testing.T, advertise string) string {
+	t.Helper()
+
+	interceptor := NewProtocolGuard(true, logger.NewFromEnv()).UnaryServerInterceptor()
+	if advertise != "" {
+		interceptor = func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(ProtocolHeader, advertise))
+			return handler(ctx, req)
+		}
+	}
+	server := grpc.NewServer(grpc.UnaryInterceptor(interceptor))
+	pb.RegisterMQServiceServer(server, healthServer{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestGRPCProtocolHandshake(t *testing.T) {
+	// Compatible client and server
+	client, err := NewGRPCBrokerClientWithGuard(startGRPCServer(t, ""), NewProtocolGuard(true, logger.NewFromEnv()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Health(); err != nil {
+		t.Errorf("Expected a compatible server to answer, got %v", err)
+	}
+
+	// A strict client refuses a server advertising another major version
+	client, err = NewGRPCBrokerClientWithGuard(startGRPCServer(t, "2.0"), NewProtocolGuard(true, logger.NewFromEnv()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Health(); !errors.Is(err, ErrIncompatibleProtocol) {
+		t.Errorf("Expected ErrIncompatibleProtocol, got %v", err)
+	}
+
+	// A strict server refuses a client sending another major version
+	conn, err := grpc.NewClient(startGRPCServer(t, ""), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ProtocolHeader, "2.0")
+	_, err = pb.NewMQServiceClient(conn).Health(ctx, &pb.HealthRequest{})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package mq
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// Unacknowledged messages survive a restart of the broker. Beside its
+// segments, each topic log directory holds an acks file recording every
+// copy of a message that is no longer pending, that is acknowledged, dead
+// lettered or poisoned, as
+//
+//	<offset uint64> <group length uint16> <group>
+//
+// with an empty group for the copy of the subscribers outside any group, a
+// groups.json of the topic's consumer groups and, once it was created with
+// a configuration or paused, a topic.json of its settings. On startup the broker queues
+// again every copy of a logged message the acks file does not record.
+const (
+	ackLogFile = "acks"
+	groupsFile = "groups.json"
+)
+
+// resolvedCopy identifies a copy of a message that is no longer pending
+type resolvedCopy struct {
+	offset uint64
+	group  string
+}
+
+// loggedGroup is a consumer group as kept in groups.json
+type loggedGroup struct {
+	Name string `json:"name"`
+	// FromOffset is the offset of the first message published after the
+	// group subscribed; earlier messages have no copy for it
+	FromOffset uint64 `json:"from_offset"`
+}
+
+// resolve records that the copy of the message at offset for group is no
+// longer pending
+func (l *topicLog) resolve(offset uint64, group string) error {
+	if l.acks == nil {
+		file, err := os.OpenFile(filepath.Join(l.dir, ackLogFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open ack log: %w", err)
+		}
+		l.acks = file
+	}
+	entry := binary.BigEndian.AppendUint64(nil, offset)
+	entry = binary.BigEndian.AppendUint16(entry, uint16(len(group)))
+	entry = append(entry, group...)
+	if _, err := l.acks.Write(entry); err != nil {
+		return fmt.Errorf("failed to write ack log: %w", err)
+	}
+	return nil
+}
+
+// readAcks returns the copies recorded in the acks file in dir, ignoring a
+// torn final entry
+func readAcks(dir string) (map[resolvedCopy]bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ackLogFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read ack log: %w", err)
+	}
+	resolved := make(map[resolvedCopy]bool)
+	for len(data) >= 10 {
+		length := int(binary.BigEndian.Uint16(data[8:10]))
+		if len(data) < 10+length {
+			break
+		}
+		resolved[resolvedCopy{binary.BigEndian.Uint64(data), string(data[10 : 10+length])}] = true
+		data = data[10+length:]
+	}
+	return resolved, nil
+}
+
+// compactAcks rewrites the acks file without the copies of messages whose
+// segments compaction has removed
+func (l *topicLog) compactAcks() error {
+	resolved, err := readAcks(l.dir)
+	if err != nil {
+		return err
+	}
+	if l.acks != nil {
+		if err := l.acks.Close(); err != nil {
+			return err
+		}
+		l.acks = nil
+	}
+	return persistence.WriteFileAtomic(filepath.Join(l.dir, ackLogFile), func(w io.Writer) error {
+		for done := range resolved {
+			if done.offset < l.bases[0] {
+				continue
+			}
+			entry := binary.BigEndian.AppendUint64(nil, done.offset)
+			entry = binary.BigEndian.AppendUint16(entry, uint16(len(done.group)))
+			if _, err := w.Write(append(entry, done.group...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readGroups returns the consumer groups kept in dir
+func readGroups(dir string) ([]loggedGroup, error) {
+	data, err := os.ReadFile(filepath.Join(dir, groupsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consumer groups: %w", err)
+	}
+	var groups []loggedGroup
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("failed to parse consumer groups: %w", err)
+	}
+	return groups, nil
+}
+
+// addGroup keeps a new consumer group of the topic, which gets a copy of the
+// messages from the next offset on
+func (l *topicLog) addGroup(name string) error {
+	groups, err := readGroups(l.dir)
+	if err != nil {
+		return err
+	}
+	groups = append(groups, loggedGroup{Name: name, FromOffset: l.next})
+	return persistence.WriteFileAtomic(filepath.Join(l.dir, groupsFile), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(groups)
+	})
+}
+
+// replay queues again the pending copies of the messages logged under the
+// persistence directory, so messages that were not acknowledged before the
+// broker stopped or crashed are delivered once their consumers subscribe.
+// Topics whose log cannot be read are skipped with a warning.
+func (b *Broker) replay() {
+	entries, err := os.ReadDir(b.config.PersistenceDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Warning: failed to list persisted topics: %v\n", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		topic := entry.Name()
+		if !entry.IsDir() || ValidateTopic(topic) != nil {
+			continue
+		}
+		if err := b.replayTopic(topic); err != nil {
+			fmt.Printf("Warning: failed to replay topic %s: %v\n", topic, err)
+		}
+	}
+}
+
+// replayTopic queues again the pending copies of a topic's logged messages:
+// one for each consumer group that had subscribed when the message was
+// published, and one for the subscribers outside any group if the topic has
+// no groups. Caller must hold b.mu, or the broker must not be in use yet.
+func (b *Broker) replayTopic(topic string) error {
+	dir := filepath.Join(b.config.PersistenceDir, topic)
+	if bases, err := listSegments(dir); err != nil || len(bases) == 0 {
+		return err
+	}
+	groups, err := readGroups(dir)
+	if err != nil {
+		return err
+	}
+	resolved, err := readAcks(dir)
+	if err != nil {
+		return err
+	}
+	settings, err := readTopicSettings(dir)
+	if err != nil {
+		return err
+	}
+	log, err := openTopicLog(dir, b.config.Keyring)
+	if err != nil {
+		return err
+	}
+	b.logs[topic] = log
+
+	topicData := b.topicDataLocked(topic)
+	topicData.config = settings.Config
+	topicData.paused = settings.Paused
+	for _, group := range groups {
+		topicData.groups[group.Name] = &consumerGroup{}
+	}
+	now := time.Now()
+	return log.read(log.bases[0], func(record logRecord) error {
+		msg := Message{ID: record.ID, Timestamp: record.Timestamp, Headers: record.Headers, Payload: record.Payload}
+		if len(groups) == 0 && !resolved[resolvedCopy{record.Offset, ""}] {
+			b.track(topic, topicData, msg, record.Offset, "", now)
+			topicData.counters.replayed++
+		}
+		for _, group := range groups {
+			if record.Offset >= group.FromOffset && !resolved[resolvedCopy{record.Offset, group.Name}] {
+				b.track(topic, topicData, msg, record.Offset, group.Name, now)
+				topicData.counters.replayed++
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,158 @@
+package mq
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// persistentConfig returns a broker configuration persisting to dir
+func persistentConfig(dir string) BrokerConfig {
+	config := DefaultBrokerConfig()
+	config.PersistenceEnabled = true
+	config.PersistenceDir = dir
+	return config
+}
+
+func TestBroker_ReplaysUnacknowledgedMessages(t *testing.T) {
+	config := persistentConfig(t.TempDir())
+	broker := NewBroker(config)
+	ch, _, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		msg := Message{ID: fmt.Sprintf("m%d", i), Payload: []byte(fmt.Sprintf("payload %d", i)), Headers: map[string]string{"worker": "1"}}
+		if err := broker.Publish("telemetry", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	(<-ch).Ack()
+	broker.Close()
+
+	// The restarted broker queues the two messages that were not
+	// acknowledged, for a subscriber to pick up
+	broker = NewBroker(config)
+	stats, err := broker.GetTopicStats("telemetry")
+	if err != nil || stats.PendingMessages != 2 || stats.ReplayedMessages != 2 {
+		t.Fatalf("Expected 2 messages replayed, got %+v, %v", stats, err)
+	}
+	ch, _, err = broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := receive(ch)
+	if len(replayed) != 2 || replayed[0].ID == "m0" || replayed[1].ID == "m0" || replayed[0].Headers["worker"] != "1" {
+		t.Fatalf("Expected m1 and m2 replayed, got %+v", replayed)
+	}
+	for _, msg := range replayed {
+		msg.Ack()
+	}
+
+	// New messages carry on after the logged ones, and nothing is replayed
+	// once everything was acknowledged
+	if err := broker.Publish("telemetry", Message{ID: "m3", Payload: []byte("payload 3")}); err != nil {
+		t.Fatal(err)
+	}
+	(<-ch).Ack()
+	broker.Close()
+	broker = NewBroker(config)
+	defer broker.Close()
+	if stats, _ := broker.GetTopicStats("telemetry"); stats.PendingMessages != 0 || stats.ReplayedMessages != 0 {
+		t.Errorf("Expected nothing replayed, got %+v", stats)
+	}
+}
+
+func TestBroker_ReplaysConsumerGroups(t *testing.T) {
+	config := persistentConfig(t.TempDir())
+	broker := NewBroker(config)
+	collectors, _, err := broker.SubscribeGroup("telemetry", "collectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := broker.Publish("telemetry", Message{ID: fmt.Sprintf("m%d", i), Payload: []byte("x")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A group joining later has no copy of the earlier messages
+	archivers, _, err := broker.SubscribeGroup("telemetry", "archivers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish("telemetry", Message{ID: "m2", Payload: []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+	(<-collectors).Ack()
+	receive(archivers)
+	broker.Close()
+
+	broker = NewBroker(config)
+	defer broker.Close()
+	stats, _ := broker.GetTopicStats("telemetry")
+	if stats.ConsumerGroups != 2 || stats.ReplayedMessages != 3 {
+		t.Fatalf("Expected both groups restored with 3 copies replayed, got %+v", stats)
+	}
+	collectors, _, err = broker.SubscribeGroup("telemetry", "collectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs := receive(collectors); len(msgs) != 2 || msgs[0].ID != "m1" || msgs[1].ID != "m2" {
+		t.Errorf("Expected m1 and m2 replayed to the collectors, got %+v", msgs)
+	}
+	archivers, _, err = broker.SubscribeGroup("telemetry", "archivers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs := receive(archivers); len(msgs) != 1 || msgs[0].ID != "m2" {
+		t.Errorf("Expected only m2 replayed to the archivers, got %+v", msgs)
+	}
+}
+
+func TestTopicLog_CompactsAcks(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openTopicLog(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.close() }()
+
+	for i := 0; i < 3; i++ {
+		if _, err := l.append(logRecord{ID: fmt.Sprintf("m%d", i), Payload: []byte("x")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.resolve(0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.resolve(1, "collectors"); err != nil {
+		t.Fatal(err)
+	}
+	// A torn final entry is ignored
+	file, _ := os.OpenFile(filepath.Join(dir, ackLogFile), os.O_WRONLY|os.O_APPEND, 0644)
+	_, _ = file.Write([]byte{0, 0, 0})
+	_ = file.Close()
+
+	resolved, err := readAcks(dir)
+	if err != nil || len(resolved) != 2 || !resolved[resolvedCopy{1, "collectors"}] {
+		t.Fatalf("Unexpected acks %v, %v", resolved, err)
+	}
+
+	// Compaction drops the acks of removed segments
+	if _, err := l.compact(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.compactAcks(); err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := readAcks(dir); len(resolved) != 0 {
+		t.Errorf("Expected the compacted acks dropped, got %v", resolved)
+	}
+	if err := l.resolve(3, ""); err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := readAcks(dir); !resolved[resolvedCopy{3, ""}] {
+		t.Errorf("Expected acks recorded after compaction, got %v", resolved)
+	}
+}
@@ -0,0 +1,340 @@
+package mq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+// ErrRuleNotFound is returned when removing a routing rule that does not exist
+var ErrRuleNotFound = errors.New("routing rule not found")
+
+// ErrInvalidRule is returned when adding an incomplete routing rule
+var ErrInvalidRule = errors.New("invalid routing rule")
+
+// RouteMatch is a condition on a published message: either a header or a
+// field of its JSON payload, addressed by a dotted path such as
+// fields.Hostname, must equal Equals
+type RouteMatch struct {
+	Header string `json:"header,omitempty"`
+	Field  string `json:"field,omitempty"`
+	Equals string `json:"equals"`
+}
+
+// RoutingRule republishes messages published to Topic that satisfy every
+// condition in Match to Target, optionally without some fields
+type RoutingRule struct {
+	ID         string       `json:"id"`
+	Topic      string       `json:"topic"`
+	Match      []RouteMatch `json:"match,omitempty"`
+	Target     string       `json:"target"`
+	DropFields []string     `json:"drop_fields,omitempty"`
+}
+
+// validate checks a rule is complete
+func (r RoutingRule) validate() error {
+	if r.Topic == "" || r.Target == "" {
+		return fmt.Errorf("%w: topic and target are required", ErrInvalidRule)
+	}
+	for _, topic := range []string{r.Topic, r.Target} {
+		if err := ValidateTopic(topic); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+	if r.Topic == r.Target {
+		return fmt.Errorf("%w: target must differ from topic", ErrInvalidRule)
+	}
+	for _, match := range r.Match {
+		if (match.Header == "") == (match.Field == "") {
+			return fmt.Errorf("%w: each match needs exactly one of header or field", ErrInvalidRule)
+		}
+	}
+	return nil
+}
+
+// needsPayload reports whether applying the rule requires decoding the
+// payload
+func (r RoutingRule) needsPayload() bool {
+	if len(r.DropFields) > 0 {
+		return true
+	}
+	for _, match := range r.Match {
+		if match.Field != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Publisher publishes messages; Broker and every broker client implement it
+type Publisher interface {
+	Publish(topic string, msg Message) error
+}
+
+// Router applies routing rules to published messages, so simple fan-out and
+// filtering need no consumer service of their own. Republished copies are
+// not routed again, which rules out loops. Rules are saved to a file, if one
+// is configured, whenever they change.
+type Router struct {
+	mu     sync.RWMutex
+	rules  []RoutingRule
+	path   string
+	routed *metrics.CounterVec
+}
+
+// NewRouter creates a router, loading its rules from path if it exists. An
+// empty path keeps rules in memory only.
+func NewRouter(path string) (*Router, error) {
+	r := &Router{path: path}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing rules: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.rules); err != nil {
+		return nil, fmt.Errorf("failed to parse routing rules %s: %w", path, err)
+	}
+	for _, rule := range r.rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %q in %s: %w", rule.ID, path, err)
+		}
+	}
+	return r, nil
+}
+
+// RegisterMetrics adds mq_routed_messages_total to registry
+func (r *Router) RegisterMetrics(registry *metrics.Registry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routed = registry.NewCounterVec("mq_routed_messages_total",
+		"Messages republished by a routing rule.", "rule", "target")
+}
+
+// Rules returns the routing rules in the order they are applied
+func (r *Router) Rules() []RoutingRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]RoutingRule(nil), r.rules...)
+}
+
+// AddRule adds a rule, or replaces the rule with the same ID. A rule without
+// an ID is given one.
+func (r *Router) AddRule(rule RoutingRule) (RoutingRule, error) {
+	if err := rule.validate(); err != nil {
+		return RoutingRule{}, err
+	}
+	for i, match := range rule.Match {
+		rule.Match[i].Header = strings.ToLower(match.Header)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("rule-%d", time.Now().UnixNano())
+	}
+
+	rules := append([]RoutingRule(nil), r.rules...)
+	replaced := false
+	for i := range rules {
+		if rules[i].ID == rule.ID {
+			rules[i], replaced = rule, true
+		}
+	}
+	if !replaced {
+		rules = append(rules, rule)
+	}
+	if err := r.save(rules); err != nil {
+		return RoutingRule{}, err
+	}
+	r.rules = rules
+	return rule, nil
+}
+
+// RemoveRule removes the rule with id
+func (r *Router) RemoveRule(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules := make([]RoutingRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		if rule.ID != id {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == len(r.rules) {
+		return ErrRuleNotFound
+	}
+	if err := r.save(rules); err != nil {
+		return err
+	}
+	r.rules = rules
+	return nil
+}
+
+// save writes rules to the router's file, if it has one. Caller must hold
+// r.mu.
+func (r *Router) save(rules []RoutingRule) error {
+	if r.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode routing rules: %w", err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save routing rules: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to save routing rules: %w", err)
+	}
+	return nil
+}
+
+// Route republishes a message just published to topic through publisher,
+// once for each rule it matches. headers are the message's headers, matched
+// case-insensitively and carried over to every copy.
+// Rules that inspect the payload skip payloads that are not JSON objects.
+func (r *Router) Route(publisher Publisher, topic string, headers map[string]string, payload []byte) error {
+	r.mu.RLock()
+	rules, routed := r.rules, r.routed
+	r.mu.RUnlock()
+
+	var document map[string]interface{}
+	decoded := false
+	var errs []error
+	for _, rule := range rules {
+		if rule.Topic != topic {
+			continue
+		}
+		if rule.needsPayload() && !decoded {
+			decoded = true
+			if err := json.Unmarshal(payload, &document); err != nil {
+				document = nil
+			}
+		}
+		if rule.needsPayload() && document == nil {
+			continue
+		}
+		if !rule.matches(headers, document) {
+			continue
+		}
+
+		out := payload
+		if len(rule.DropFields) > 0 {
+			var err error
+			if out, err = dropFields(document, rule.DropFields); err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: %w", rule.ID, err))
+				continue
+			}
+		}
+		if err := publisher.Publish(rule.Target, Message{Payload: out, Headers: maps.Clone(headers)}); err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: failed to publish to %s: %w", rule.ID, rule.Target, err))
+			continue
+		}
+		if routed != nil {
+			routed.WithLabelValues(rule.ID, rule.Target).Inc()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// matches reports whether every condition holds
+func (r RoutingRule) matches(headers map[string]string, document map[string]interface{}) bool {
+	for _, match := range r.Match {
+		var value string
+		var ok bool
+		if match.Header != "" {
+			value, ok = lookupHeader(headers, match.Header)
+		} else {
+			value, ok = lookupField(document, match.Field)
+		}
+		if !ok || value != match.Equals {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupHeader finds a header regardless of case
+func lookupHeader(headers map[string]string, name string) (string, bool) {
+	if value, ok := headers[name]; ok {
+		return value, true
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// lookupField finds a dotted path in a JSON document, formatting scalars as
+// strings
+func lookupField(document map[string]interface{}, path string) (string, bool) {
+	var value interface{} = document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = object[key]; !ok {
+			return "", false
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64, bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// dropFields encodes a copy of document without the dotted paths
+func dropFields(document map[string]interface{}, paths []string) ([]byte, error) {
+	out := copyObject(document)
+	for _, path := range paths {
+		object := out
+		keys := strings.Split(path, ".")
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := object[key].(map[string]interface{})
+			if !ok {
+				object = nil
+				break
+			}
+			object = child
+		}
+		if object != nil {
+			delete(object, keys[len(keys)-1])
+		}
+	}
+	return json.Marshal(out)
+}
+
+// copyObject copies the nested objects of a JSON document, so fields can be
+// dropped without affecting other rules
+func copyObject(object map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		if child, ok := value.(map[string]interface{}); ok {
+			value = copyObject(child)
+		}
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package mq
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harishb93/telemetry-pipeline/internal/metrics"
+)
+
+// recordingPublisher records what it is asked to publish
+type recordingPublisher struct {
+	published map[string][]string
+	headers   map[string][]map[string]string
+}
+
+func (p *recordingPublisher) Publish(topic string, msg Message) error {
+	if p.published == nil {
+		p.published = make(map[string][]string)
+		p.headers = make(map[string][]map[string]string)
+	}
+	p.published[topic] = append(p.published[topic], string(msg.Payload))
+	p.headers[topic] = append(p.headers[topic], msg.Headers)
+	return nil
+}
+
+func TestRouter_Route(t *testing.T) {
+	router, err := NewRouter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := metrics.NewRegistry()
+	router.RegisterMetrics(registry)
+
+	rules := []RoutingRule{
+		{ID: "site-a", Topic: "telemetry", Target: "telemetry.site-a",
+			Match: []RouteMatch{{Header: "X-Site", Equals: "a"}}},
+		{ID: "hot", Topic: "telemetry", Target: "alerts",
+			Match:      []RouteMatch{{Field: "fields.metric_name", Equals: "DCGM_FI_DEV_GPU_TEMP"}, {Field: "fields.gpu_id", Equals: "3"}},
+			DropFields: []string{"fields.labels_raw", "fields.pod"}},
+		{ID: "copy", Topic: "telemetry", Target: "archive"},
+	}
+	for _, rule := range rules {
+		if _, err := router.AddRule(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	payload := `{"timestamp":"2025-07-18T20:42:34Z","fields":{"metric_name":"DCGM_FI_DEV_GPU_TEMP","gpu_id":3,"pod":"p","labels_raw":"x"}}`
+	publisher := &recordingPublisher{}
+	if err := router.Route(publisher, "telemetry", map[string]string{"x-site": "a"}, []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.Route(publisher, "other", map[string]string{"x-site": "a"}, []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := publisher.published["telemetry.site-a"]; len(got) != 1 || got[0] != payload {
+		t.Errorf("Expected the payload routed by header unchanged, got %v", got)
+	}
+	if got := publisher.published["archive"]; len(got) != 1 {
+		t.Errorf("Expected a rule without conditions to match, got %v", got)
+	}
+	for topic, headers := range publisher.headers {
+		if len(headers) != 1 || headers[0]["x-site"] != "a" {
+			t.Errorf("Expected the copy on %s to keep the message headers, got %v", topic, headers)
+		}
+	}
+	alerts := publisher.published["alerts"]
+	if len(alerts) != 1 {
+		t.Fatalf("Expected the payload routed by fields, got %v", publisher.published)
+	}
+	var routed struct {
+		Fields map[string]interface{} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(alerts[0]), &routed); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := routed.Fields["labels_raw"]; ok || routed.Fields["pod"] != nil || routed.Fields["metric_name"] == nil {
+		t.Errorf("Expected labels_raw and pod dropped, got %v", routed)
+	}
+
+	// Non-JSON payloads only match rules that ignore the payload
+	publisher = &recordingPublisher{}
+	_ = router.Route(publisher, "telemetry", nil, []byte("not json"))
+	if len(publisher.published) != 1 || len(publisher.published["archive"]) != 1 {
+		t.Errorf("Expected only the unconditional rule to match, got %v", publisher.published)
+	}
+
+	var out strings.Builder
+	_, _ = registry.WriteTo(&out)
+	if want := `mq_routed_messages_total{rule="copy",target="archive"} 2`; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q in metrics:\n%s", want, out.String())
+	}
+}
+
+func TestRouter_Rules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	router, err := NewRouter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, invalid := range []RoutingRule{
+		{Topic: "telemetry"},
+		{Topic: "telemetry", Target: "telemetry"},
+		{Topic: "telemetry", Target: "../escape"},
+		{Topic: "telemetry", Target: "b", Match: []RouteMatch{{Header: "h", Field: "f"}}},
+	} {
+		if _, err := router.AddRule(invalid); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Expected %+v to be invalid, got %v", invalid, err)
+		}
+	}
+
+	added, err := router.AddRule(RoutingRule{Topic: "telemetry", Target: "a"})
+	if err != nil || added.ID == "" {
+		t.Fatalf("Expected the rule to be given an ID, got %+v, %v", added, err)
+	}
+	if _, err := router.AddRule(RoutingRule{ID: "b", Topic: "telemetry", Target: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := router.AddRule(RoutingRule{ID: "b", Topic: "telemetry", Target: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.RemoveRule(added.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.RemoveRule(added.ID); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound, got %v", err)
+	}
+
+	// Rules survive a restart
+	reloaded, err := NewRouter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := reloaded.Rules(); len(rules) != 1 || rules[0].ID != "b" || rules[0].Target != "c" {
+		t.Errorf("Expected the replaced rule b to be reloaded, got %+v", rules)
+	}
+}
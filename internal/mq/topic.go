@@ -0,0 +1,35 @@
+package mq
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxTopicLength is the longest topic name allowed, leaving room for topics
+// derived from it to stay within file name limits
+const MaxTopicLength = 200
+
+// ValidateTopic checks that topic is usable as a directory name and a URL
+// path segment: 1 to MaxTopicLength ASCII letters, digits, '.', '-' and '_',
+// not starting with '.', which is reserved for the broker's own files.
+// Invalid names are reported as ErrInvalidTopic.
+func ValidateTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("%w: topic name is empty", ErrInvalidTopic)
+	}
+	if len(topic) > MaxTopicLength {
+		return fmt.Errorf("%w: topic name is %d characters, longer than %d", ErrInvalidTopic, len(topic), MaxTopicLength)
+	}
+	if topic[0] == '.' {
+		return fmt.Errorf("%w: topic name %q starts with '.'", ErrInvalidTopic, topic)
+	}
+	for i := 0; i < len(topic); i++ {
+		switch c := topic[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '-', c == '_':
+		default:
+			r, _ := utf8.DecodeRuneInString(topic[i:])
+			return fmt.Errorf("%w: topic name %q contains %q; only letters, digits, '.', '-' and '_' are allowed", ErrInvalidTopic, topic, r)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package mq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateTopic(t *testing.T) {
+	for _, valid := range []string{"telemetry", "telemetry.host-1", "GPU_metrics.v2", "a", strings.Repeat("t", MaxTopicLength)} {
+		if err := ValidateTopic(valid); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", valid, err)
+		}
+	}
+
+	for _, invalid := range []string{"", ".format", "..", "../etc", "telemetry/host", "telemetry host", "télémétrie", "a?b", "a%2Fb", strings.Repeat("t", MaxTopicLength+1)} {
+		err := ValidateTopic(invalid)
+		if !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("Expected %q to be rejected with ErrInvalidTopic, got %v", invalid, err)
+		}
+	}
+}
+
+func TestBroker_RejectsInvalidTopics(t *testing.T) {
+	broker := NewBroker(DefaultBrokerConfig())
+	defer broker.Close()
+
+	if err := broker.Publish("../escape", Message{Payload: []byte("m")}); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("Publish = %v, want ErrInvalidTopic", err)
+	}
+	if _, _, err := broker.Subscribe("a/b"); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("Subscribe = %v, want ErrInvalidTopic", err)
+	}
+	if _, _, err := broker.SubscribeWithAck(""); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("SubscribeWithAck = %v, want ErrInvalidTopic", err)
+	}
+	if len(broker.GetStats().Topics) != 0 {
+		t.Error("Expected no topics created for invalid names")
+	}
+}
@@ -0,0 +1,303 @@
+package mq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+var (
+	// ErrTopicExists is returned when creating a topic that is already in
+	// use
+	ErrTopicExists = errors.New("topic already exists")
+	// ErrInvalidTopicConfig is returned when a topic's configuration is not
+	// usable
+	ErrInvalidTopicConfig = errors.New("invalid topic configuration")
+)
+
+// TopicConfig is a topic's own configuration, overriding the broker's for
+// that topic. Zero fields use the BrokerConfig.
+type TopicConfig struct {
+	// MaxQueueSize bounds the topic's queue in place of
+	// BrokerConfig.MaxQueueSize; negative leaves it unbounded
+	MaxQueueSize int `json:"max_queue_size,omitempty"`
+	// OverflowPolicy replaces BrokerConfig.OverflowPolicy
+	OverflowPolicy OverflowPolicy `json:"overflow_policy,omitempty"`
+	// AckTimeout replaces BrokerConfig.AckTimeout
+	AckTimeout time.Duration `json:"ack_timeout,omitempty"`
+}
+
+// Validate checks that the configuration is usable, reporting problems as
+// ErrInvalidTopicConfig
+func (c TopicConfig) Validate() error {
+	if c.OverflowPolicy != "" {
+		if _, err := ParseOverflowPolicy(string(c.OverflowPolicy)); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTopicConfig, err)
+		}
+	}
+	if c.AckTimeout < 0 {
+		return fmt.Errorf("%w: negative ack timeout %s", ErrInvalidTopicConfig, c.AckTimeout)
+	}
+	return nil
+}
+
+// topicFile keeps a topic's configuration and whether it is paused beside
+// its log, so both survive a restart
+const topicFile = "topic.json"
+
+// loggedTopic is a topic's settings as kept in topic.json
+type loggedTopic struct {
+	Config TopicConfig `json:"config"`
+	Paused bool        `json:"paused,omitempty"`
+}
+
+// readTopicSettings returns the topic settings kept in dir, if any
+func readTopicSettings(dir string) (loggedTopic, error) {
+	var settings loggedTopic
+	data, err := os.ReadFile(filepath.Join(dir, topicFile))
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to read topic settings: %w", err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("failed to parse topic settings: %w", err)
+	}
+	return settings, nil
+}
+
+// saveTopicSettings keeps a topic's configuration and pause state when
+// persistence is enabled. Caller must hold b.mu.
+func (b *Broker) saveTopicSettings(topic string, topicData *TopicData) error {
+	if !b.config.PersistenceEnabled {
+		return nil
+	}
+	log, err := b.topicLogLocked(topic)
+	if err != nil {
+		return err
+	}
+	settings := loggedTopic{Config: topicData.config, Paused: topicData.paused}
+	return persistence.WriteFileAtomic(filepath.Join(log.dir, topicFile), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(settings)
+	})
+}
+
+// existingTopicLocked returns a topic's data, or ErrTopicNotFound if nothing
+// has used the topic. Caller must hold b.mu.
+func (b *Broker) existingTopicLocked(topic string) (*TopicData, error) {
+	if b.closed {
+		return nil, ErrClosed
+	}
+	topicData, exists := b.topics[topic]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	}
+	return topicData, nil
+}
+
+// CreateTopic creates a topic with its own configuration before anything
+// publishes or subscribes to it, or returns ErrTopicExists if something
+// already has; ConfigureTopic changes the configuration of a topic in use.
+func (b *Broker) CreateTopic(topic string, config TopicConfig) error {
+	if err := ValidateTopic(topic); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+	if _, exists := b.topics[topic]; exists {
+		return fmt.Errorf("%w: %s", ErrTopicExists, topic)
+	}
+
+	topicData := b.topicDataLocked(topic)
+	topicData.config = config
+	if err := b.saveTopicSettings(topic, topicData); err != nil {
+		delete(b.topics, topic)
+		return fmt.Errorf("failed to persist configuration of topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// ConfigureTopic replaces a topic's configuration. A smaller queue limit
+// applies to the next publish; messages already queued are kept.
+func (b *Broker) ConfigureTopic(topic string, config TopicConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topicData, err := b.existingTopicLocked(topic)
+	if err != nil {
+		return err
+	}
+	previous := topicData.config
+	topicData.config = config
+	if err := b.saveTopicSettings(topic, topicData); err != nil {
+		topicData.config = previous
+		return fmt.Errorf("failed to persist configuration of topic %s: %w", topic, err)
+	}
+	// A larger limit or another policy may let blocked publishers in
+	topicData.signalRoom()
+	return nil
+}
+
+// GetTopicConfig returns the configuration a topic was created or
+// configured with, or ErrTopicNotFound if nothing has used the topic
+func (b *Broker) GetTopicConfig(topic string) (TopicConfig, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	topicData, err := b.existingTopicLocked(topic)
+	if err != nil {
+		return TopicConfig{}, err
+	}
+	return topicData.config, nil
+}
+
+// DeleteTopic removes a topic with its queued messages, configuration and
+// log. Its subscribers' channels are closed, as when the broker closes. The
+// topic's dead-letter and poison topics are separate topics and are kept.
+// Publishing or subscribing to the topic again starts it afresh.
+func (b *Broker) DeleteTopic(topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topicData, err := b.existingTopicLocked(topic)
+	if err != nil {
+		return err
+	}
+
+	for ch := range topicData.subscribers {
+		close(ch)
+	}
+	for ch := range topicData.ackSubscribers {
+		close(ch)
+	}
+	for _, group := range topicData.groups {
+		for _, ch := range group.members {
+			close(ch)
+		}
+		group.members = nil
+	}
+	delete(b.topics, topic)
+	topicData.signalRoom()
+
+	if log, exists := b.logs[topic]; exists {
+		if err := log.close(); err != nil {
+			fmt.Printf("Warning: failed to close log of topic %s: %v\n", topic, err)
+		}
+		delete(b.logs, topic)
+	}
+	if b.config.PersistenceEnabled {
+		if err := os.RemoveAll(filepath.Join(b.config.PersistenceDir, topic)); err != nil {
+			return fmt.Errorf("failed to remove log of topic %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// PurgeTopic discards every message queued on a topic, delivered or not,
+// keeping its subscribers and configuration. It returns how many message
+// copies were discarded, counting one per consumer group.
+func (b *Broker) PurgeTopic(topic string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topicData, err := b.existingTopicLocked(topic)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]string, 0, len(topicData.pendingMsgs))
+	for id := range topicData.pendingMsgs {
+		ids = append(ids, id)
+	}
+	purged := 0
+	for _, id := range ids {
+		if b.removePendingMessage(topic, id) {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// PauseTopic stops delivery on a topic: publishes are still queued, up to
+// its queue limit, but nothing is delivered and acknowledgment timeouts
+// stop running until ResumeTopic
+func (b *Broker) PauseTopic(topic string) error {
+	return b.setPaused(topic, true)
+}
+
+// ResumeTopic restarts delivery on a paused topic, delivering every queued
+// message, oldest first, and restarting its acknowledgment timeout.
+// Resuming a topic that is not paused does nothing.
+func (b *Broker) ResumeTopic(topic string) error {
+	return b.setPaused(topic, false)
+}
+
+// setPaused pauses or resumes delivery on a topic
+func (b *Broker) setPaused(topic string, paused bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topicData, err := b.existingTopicLocked(topic)
+	if err != nil {
+		return err
+	}
+	if topicData.paused == paused {
+		return nil
+	}
+	topicData.paused = paused
+	if err := b.saveTopicSettings(topic, topicData); err != nil {
+		topicData.paused = !paused
+		return fmt.Errorf("failed to persist state of topic %s: %w", topic, err)
+	}
+	if paused {
+		return nil
+	}
+
+	queued := append([]*PendingMessage(nil), topicData.messageQueue...)
+	sort.Slice(queued, func(i, j int) bool { return queued[i].seq < queued[j].seq })
+	now := time.Now()
+	for _, pending := range queued {
+		pending.Timestamp = now
+		b.deliver(topicData, pending)
+	}
+	return nil
+}
+
+// maxQueueSize returns a topic's queue limit, zero if it is unbounded.
+// Caller must hold b.mu.
+func (b *Broker) maxQueueSize(topicData *TopicData) int {
+	switch {
+	case topicData.config.MaxQueueSize > 0:
+		return topicData.config.MaxQueueSize
+	case topicData.config.MaxQueueSize < 0:
+		return 0
+	default:
+		return b.config.MaxQueueSize
+	}
+}
+
+// ackTimeout returns how long a topic's deliveries have to be
+// acknowledged. Caller must hold b.mu.
+func (b *Broker) ackTimeout(topicData *TopicData) time.Duration {
+	if topicData.config.AckTimeout > 0 {
+		return topicData.config.AckTimeout
+	}
+	return b.config.AckTimeout
+}
@@ -0,0 +1,159 @@
+package mq
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBroker_CreateTopic(t *testing.T) {
+	broker := NewBroker(BrokerConfig{AckTimeout: time.Minute, MaxQueueSize: 10})
+	defer broker.Close()
+
+	config := TopicConfig{MaxQueueSize: 1, OverflowPolicy: OverflowDropNewest}
+	if err := broker.CreateTopic("telemetry", config); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.CreateTopic("telemetry", TopicConfig{}); !errors.Is(err, ErrTopicExists) {
+		t.Errorf("Expected ErrTopicExists, got %v", err)
+	}
+	if err := broker.CreateTopic("other", TopicConfig{OverflowPolicy: "spill"}); !errors.Is(err, ErrInvalidTopicConfig) {
+		t.Errorf("Expected ErrInvalidTopicConfig, got %v", err)
+	}
+	if got, err := broker.GetTopicConfig("telemetry"); err != nil || got != config {
+		t.Errorf("Expected %+v, got %+v, %v", config, got, err)
+	}
+
+	// The topic's own limit and policy replace the broker's
+	for _, id := range []string{"m1", "m2"} {
+		if err := broker.Publish("telemetry", Message{ID: id, Payload: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats, _ := broker.GetTopicStats("telemetry"); stats.QueueSize != 1 || stats.DroppedNewestMessages != 1 {
+		t.Errorf("Expected the second message dropped, got %+v", stats)
+	}
+
+	if err := broker.ConfigureTopic("telemetry", TopicConfig{MaxQueueSize: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish("telemetry", Message{ID: "m3", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if size := broker.GetQueueSize("telemetry"); size != 2 {
+		t.Errorf("Expected an unbounded queue of 2, got %d", size)
+	}
+	if err := broker.ConfigureTopic("missing", TopicConfig{}); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound, got %v", err)
+	}
+}
+
+func TestBroker_DeleteTopic(t *testing.T) {
+	config := persistentConfig(t.TempDir())
+	broker := NewBroker(config)
+	ch, _, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish("telemetry", Message{ID: "m1", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := broker.DeleteTopic("telemetry"); err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	if _, ok := <-ch; ok {
+		t.Error("Expected the subscriber's channel closed")
+	}
+	if _, err := broker.GetTopicStats("telemetry"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("Expected the topic gone, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.PersistenceDir, "telemetry")); !os.IsNotExist(err) {
+		t.Errorf("Expected the topic's log removed, got %v", err)
+	}
+	if err := broker.DeleteTopic("telemetry"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound, got %v", err)
+	}
+	broker.Close()
+
+	// Nothing is replayed for a deleted topic
+	broker = NewBroker(config)
+	defer broker.Close()
+	if topics := broker.GetTopics(); len(topics) != 0 {
+		t.Errorf("Expected no topics after restart, got %v", topics)
+	}
+}
+
+func TestBroker_PurgeTopic(t *testing.T) {
+	broker := NewBroker(BrokerConfig{AckTimeout: time.Minute})
+	defer broker.Close()
+	for _, id := range []string{"m1", "m2", "m3"} {
+		if err := broker.Publish("telemetry", Message{ID: id, Payload: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purged, err := broker.PurgeTopic("telemetry")
+	if err != nil || purged != 3 {
+		t.Fatalf("Expected 3 messages purged, got %d, %v", purged, err)
+	}
+	if stats, _ := broker.GetTopicStats("telemetry"); stats.QueueSize != 0 || stats.PendingMessages != 0 {
+		t.Errorf("Expected an empty topic, got %+v", stats)
+	}
+	if _, err := broker.PurgeTopic("missing"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound, got %v", err)
+	}
+}
+
+func TestBroker_PauseTopic(t *testing.T) {
+	config := persistentConfig(t.TempDir())
+	broker := NewBroker(config)
+	ch, _, err := broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.PauseTopic("telemetry"); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish("telemetry", Message{ID: "m1", Payload: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-ch:
+		t.Fatalf("Expected nothing delivered while paused, got %s", msg.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if stats, _ := broker.GetTopicStats("telemetry"); !stats.Paused || stats.QueueSize != 1 {
+		t.Errorf("Expected a paused topic queuing the message, got %+v", stats)
+	}
+
+	// The pause survives a restart
+	broker.Close()
+	broker = NewBroker(config)
+	defer broker.Close()
+	ch, _, err = broker.SubscribeWithAck("telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := broker.GetTopicStats("telemetry"); !stats.Paused {
+		t.Errorf("Expected the topic still paused after restart, got %+v", stats)
+	}
+
+	if err := broker.ResumeTopic("telemetry"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-ch:
+		if msg.ID != "m1" {
+			t.Errorf("Expected m1 delivered on resume, got %s", msg.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the queued message delivered on resume")
+	}
+	if err := broker.PauseTopic("missing"); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package netutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SystemdPrefix marks a listen address as a socket passed in by systemd
+// socket activation, or any supervisor following its LISTEN_FDS protocol:
+// "systemd:" takes the first passed socket and "systemd:http" the one named
+// http with FileDescriptorName=. The supervisor keeps the socket open across
+// restarts, so connections queue instead of being refused while the service
+// restarts.
+const SystemdPrefix = "systemd:"
+
+// IsSystemd reports whether addr names a socket passed in by systemd
+func IsSystemd(addr string) bool {
+	return strings.HasPrefix(addr, SystemdPrefix)
+}
+
+// listenFDsStart is the first file descriptor passed by socket activation
+var listenFDsStart = 3
+
+// inherited holds the sockets passed in by socket activation, read from the
+// environment on first use. Each socket can be listened on once.
+var inherited struct {
+	once  sync.Once
+	mu    sync.Mutex
+	names []string
+	files []*os.File
+}
+
+// loadInherited reads LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES, then unsets
+// them so child processes do not mistake the sockets for their own
+func loadInherited() {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < count; i++ {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		fd := listenFDsStart + i
+		inherited.names = append(inherited.names, name)
+		inherited.files = append(inherited.files, os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd)))
+	}
+}
+
+// listenSystemd returns a listener for the passed socket addr names
+func listenSystemd(addr string) (net.Listener, error) {
+	inherited.once.Do(loadInherited)
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+
+	name := strings.TrimPrefix(addr, SystemdPrefix)
+	for i, file := range inherited.files {
+		if file == nil || (name != "" && inherited.names[i] != name) {
+			continue
+		}
+		inherited.files[i] = nil
+
+		listener, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("passed socket %s is not a listening socket: %w", addr, err)
+		}
+		return listener, nil
+	}
+	if len(inherited.files) == 0 {
+		return nil, fmt.Errorf("cannot listen on %s: no sockets were passed in (LISTEN_FDS is not set)", addr)
+	}
+	return nil, fmt.Errorf("cannot listen on %s: no unused passed socket named %q", addr, name)
+}
+
+// reusePort is set by SetReusePort
+var reusePort atomic.Bool
+
+// SetReusePort makes Listen open TCP listeners with SO_REUSEPORT. A
+// replacement process can then bind the same port and start serving before
+// the old one stops accepting and drains its connections.
+func SetReusePort(enabled bool) {
+	reusePort.Store(enabled)
+}
+
+// listenTCP opens a TCP listener, with SO_REUSEPORT if enabled
+func listenTCP(addr string) (net.Listener, error) {
+	if !reusePort.Load() {
+		return net.Listen("tcp", addr)
+	}
+	config := net.ListenConfig{Control: reusePortControl}
+	return config.Listen(context.Background(), "tcp", addr)
+}
@@ -0,0 +1,95 @@
+//go:build unix && !solaris && !illumos
+
+package netutil
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// passSocket makes a listening socket look passed in by socket activation
+// under name, returning its address
+func passSocket(t *testing.T, name string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := listener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+	_ = listener.Close()
+
+	inherited.once = sync.Once{}
+	inherited.names, inherited.files = nil, nil
+	start := listenFDsStart
+	listenFDsStart = fd
+	t.Cleanup(func() { listenFDsStart = start })
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", name)
+	return listener.Addr().String()
+}
+
+func TestListenSystemd(t *testing.T) {
+	addr := passSocket(t, "http")
+
+	if _, err := Listen("systemd:grpc"); err == nil {
+		t.Error("Expected an error for a socket that was not passed in")
+	}
+	listener, err := Listen("systemd:http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+	if listener.Addr().String() != addr {
+		t.Errorf("Expected the passed socket %s, got %s", addr, listener.Addr())
+	}
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Error("Expected LISTEN_FDS to be unset once read")
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect to the passed socket: %v", err)
+	}
+	_ = conn.Close()
+
+	if _, err := Listen("systemd:http"); err == nil {
+		t.Error("Expected a passed socket to be usable only once")
+	}
+	if err := Validate("systemd:http"); err != nil {
+		t.Errorf("Expected systemd addresses to be valid, got %v", err)
+	}
+	if got := URL("systemd:http"); got != "systemd:http" {
+		t.Errorf("Expected the address as its URL, got %s", got)
+	}
+}
+
+func TestListenReusePort(t *testing.T) {
+	SetReusePort(true)
+	defer SetReusePort(false)
+
+	first, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = first.Close() }()
+
+	// A replacement process can bind the same port while the first listens
+	second, err := Listen(first.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected a second listener on %s, got %v", first.Addr(), err)
+	}
+	_ = second.Close()
+}
@@ -0,0 +1,180 @@
+// Package netutil resolves the listen and dial addresses used by the
+// pipeline services. Anywhere a port is configured, a full host:port address
+// or a Unix domain socket ("unix:/run/telemetry/collector.sock") may be used
+// instead, and anywhere one is listened on, a socket passed in by systemd
+// ("systemd:http").
+package netutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnixPrefix marks an address as a Unix domain socket path
+const UnixPrefix = "unix:"
+
+// unixHost is the placeholder host used in URLs for requests sent over a
+// Unix domain socket
+const unixHost = "unix"
+
+// IsUnix reports whether addr names a Unix domain socket
+func IsUnix(addr string) bool {
+	return strings.HasPrefix(addr, UnixPrefix)
+}
+
+// SocketPath returns the filesystem path of a Unix socket address, accepting
+// both unix:/path and unix:///path
+func SocketPath(addr string) string {
+	if path, ok := strings.CutPrefix(addr, UnixPrefix+"//"); ok {
+		return path
+	}
+	return strings.TrimPrefix(addr, UnixPrefix)
+}
+
+// ListenAddress normalises a listen setting: a bare port such as "8081"
+// listens on all interfaces (":8081"), while host:port, unix: and systemd:
+// addresses are returned unchanged
+func ListenAddress(addr string) string {
+	if IsUnix(addr) || strings.Contains(addr, ":") {
+		return addr
+	}
+	return ":" + addr
+}
+
+// Validate checks that addr is a usable listen setting: a port in 1-65535, a
+// host:port address with such a port, a unix: address with a path or a
+// systemd: address
+func Validate(addr string) error {
+	if IsSystemd(addr) {
+		return nil
+	}
+	if IsUnix(addr) {
+		if SocketPath(addr) == "" {
+			return fmt.Errorf("unix socket address %q has no path", addr)
+		}
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(ListenAddress(addr))
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port in listen address %q", addr)
+	}
+	return nil
+}
+
+// Listen opens a listener for a port, host:port, unix: or systemd: address.
+// A stale socket file left behind by a previous run is removed first; the
+// socket file is removed again when the listener is closed.
+func Listen(addr string) (net.Listener, error) {
+	if IsSystemd(addr) {
+		return listenSystemd(addr)
+	}
+	if !IsUnix(addr) {
+		return listenTCP(ListenAddress(addr))
+	}
+
+	path := SocketPath(addr)
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("cannot listen on %s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// URL returns a human-readable URL for a listen address, for logging
+func URL(addr string) string {
+	if IsUnix(addr) || IsSystemd(addr) {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(ListenAddress(addr))
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// HTTPClient returns an HTTP client and base URL for target, which is either
+// an http(s) URL or a unix: socket address. Requests for a socket are sent to
+// the base URL "http://unix" and dialled over the socket.
+func HTTPClient(target string, timeout time.Duration) (*http.Client, string) {
+	if !IsUnix(target) {
+		return &http.Client{Timeout: timeout}, target
+	}
+
+	path := SocketPath(target)
+	var dialer net.Dialer
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		},
+		MaxIdleConns:    100,
+		IdleConnTimeout: 90 * time.Second,
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, "http://" + unixHost
+}
+
+// ClientConfig tunes the connections of an HTTP client. Zero values keep
+// the defaults of http.DefaultTransport.
+type ClientConfig struct {
+	// Timeout bounds each request, including reading the response; 0 means
+	// no limit
+	Timeout time.Duration
+	// DialTimeout bounds establishing a connection
+	DialTimeout time.Duration
+	// KeepAlive is the interval of TCP keep-alive probes on open
+	// connections; negative disables them
+	KeepAlive time.Duration
+	// MaxIdleConns is how many idle connections are kept for reuse, to the
+	// target and in total
+	MaxIdleConns int
+	// IdleConnTimeout is how long an idle connection is kept
+	IdleConnTimeout time.Duration
+}
+
+// NewHTTPClient returns an HTTP client and base URL for target like
+// HTTPClient, with its own transport configured by config. Requests made
+// with the client share the transport's pool of connections.
+func NewHTTPClient(target string, config ClientConfig) (*http.Client, string) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if config.DialTimeout > 0 {
+		dialer.Timeout = config.DialTimeout
+	}
+	if config.KeepAlive != 0 {
+		dialer.KeepAlive = config.KeepAlive
+	}
+	transport.DialContext = dialer.DialContext
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+		transport.MaxIdleConnsPerHost = config.MaxIdleConns
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+
+	if !IsUnix(target) {
+		return &http.Client{Transport: transport, Timeout: config.Timeout}, target
+	}
+	path := SocketPath(target)
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+	return &http.Client{Transport: transport, Timeout: config.Timeout}, "http://" + unixHost
+}
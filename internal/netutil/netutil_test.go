@@ -0,0 +1,194 @@
+package netutil
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListenAddress(t *testing.T) {
+	tests := map[string]string{
+		"8081":                   ":8081",
+		":8081":                  ":8081",
+		"127.0.0.1:8081":         "127.0.0.1:8081",
+		"[::1]:8081":             "[::1]:8081",
+		"unix:/run/gw.sock":      "unix:/run/gw.sock",
+		"unix:///run/gw.sock":    "unix:///run/gw.sock",
+		"localhost:0":            "localhost:0",
+		"telemetry-gateway:8081": "telemetry-gateway:8081",
+	}
+	for in, want := range tests {
+		if got := ListenAddress(in); got != want {
+			t.Errorf("ListenAddress(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	tests := map[string]string{
+		"unix:/run/gw.sock":   "/run/gw.sock",
+		"unix:///run/gw.sock": "/run/gw.sock",
+		"unix:gw.sock":        "gw.sock",
+	}
+	for in, want := range tests {
+		if got := SocketPath(in); got != want {
+			t.Errorf("SocketPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		addr  string
+		valid bool
+	}{
+		{"9091", true},
+		{"65535", true},
+		{"127.0.0.1:9091", true},
+		{"unix:/run/mq.sock", true},
+		{"0", false},
+		{"65536", false},
+		{"-1", false},
+		{"abc", false},
+		{"", false},
+		{"127.0.0.1:abc", false},
+		{"unix:", false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.addr); (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) error = %v, want valid %v", tt.addr, err, tt.valid)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	tests := map[string]string{
+		"8081":              "http://localhost:8081",
+		"0.0.0.0:8081":      "http://localhost:8081",
+		"127.0.0.1:8081":    "http://127.0.0.1:8081",
+		"unix:/run/gw.sock": "unix:/run/gw.sock",
+	}
+	for in, want := range tests {
+		if got := URL(in); got != want {
+			t.Errorf("URL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestListenTCP(t *testing.T) {
+	listener, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	if listener.Addr().Network() != "tcp" {
+		t.Errorf("Expected tcp listener, got %s", listener.Addr().Network())
+	}
+}
+
+func TestUnixSocketRoundTrip(t *testing.T) {
+	// Keep the path short; socket paths are limited to ~100 bytes
+	dir, err := os.MkdirTemp("", "netutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	addr := UnixPrefix + filepath.Join(dir, "svc.sock")
+
+	serve := func() *http.Server {
+		listener, err := Listen(addr)
+		if err != nil {
+			t.Fatalf("Listen(%q): %v", addr, err)
+		}
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, r.URL.Path)
+		})}
+		go func() { _ = server.Serve(listener) }()
+		return server
+	}
+
+	server := serve()
+	client, baseURL := HTTPClient(addr, 5*time.Second)
+	resp, err := client.Get(baseURL + "/health")
+	if err != nil {
+		t.Fatalf("GET over unix socket: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "/health" {
+		t.Errorf("Expected /health, got %q", body)
+	}
+	_ = server.Close()
+
+	// A socket file left behind by a crashed process must not block restarts
+	stale, err := net.Listen("unix", SocketPath(addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+	if _, err := os.Lstat(SocketPath(addr)); err != nil {
+		t.Fatalf("Expected stale socket file: %v", err)
+	}
+	server = serve()
+	_ = server.Close()
+
+	// Anything other than a socket is left alone
+	if err := os.WriteFile(filepath.Join(dir, "plain"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(UnixPrefix + filepath.Join(dir, "plain")); err == nil {
+		t.Error("Expected Listen to refuse to replace a regular file")
+	}
+}
+
+func TestHTTPClientPassesThroughURLs(t *testing.T) {
+	client, baseURL := HTTPClient("http://collector:8080", 3*time.Second)
+	if baseURL != "http://collector:8080" {
+		t.Errorf("Expected URL unchanged, got %q", baseURL)
+	}
+	if client.Timeout != 3*time.Second {
+		t.Errorf("Expected timeout 3s, got %v", client.Timeout)
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	client, baseURL := NewHTTPClient("http://mq:9090", ClientConfig{
+		Timeout:         7 * time.Second,
+		MaxIdleConns:    64,
+		IdleConnTimeout: time.Minute,
+	})
+	if baseURL != "http://mq:9090" {
+		t.Errorf("Expected URL unchanged, got %q", baseURL)
+	}
+	if client.Timeout != 7*time.Second {
+		t.Errorf("Expected timeout 7s, got %v", client.Timeout)
+	}
+	transport := client.Transport.(*http.Transport)
+	if transport.MaxIdleConns != 64 || transport.MaxIdleConnsPerHost != 64 {
+		t.Errorf("Expected 64 idle connections in total and per host, got %d and %d", transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != time.Minute {
+		t.Errorf("Expected idle timeout 1m, got %v", transport.IdleConnTimeout)
+	}
+	if transport == http.DefaultTransport {
+		t.Error("Expected a transport of its own")
+	}
+
+	// Zero values keep the default transport's settings
+	client, _ = NewHTTPClient("http://mq:9090", ClientConfig{})
+	defaults := http.DefaultTransport.(*http.Transport)
+	if got := client.Transport.(*http.Transport).IdleConnTimeout; got != defaults.IdleConnTimeout {
+		t.Errorf("Expected the default idle timeout %v, got %v", defaults.IdleConnTimeout, got)
+	}
+
+	_, baseURL = NewHTTPClient("unix:/run/mq.sock", ClientConfig{})
+	if baseURL != "http://"+unixHost {
+		t.Errorf("Expected the unix base URL, got %q", baseURL)
+	}
+}
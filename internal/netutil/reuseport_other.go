@@ -0,0 +1,14 @@
+//go:build !unix || solaris || illumos
+
+package netutil
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// reusePortControl fails: SO_REUSEPORT is not available on this platform
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return fmt.Errorf("SO_REUSEPORT is not supported on %s", runtime.GOOS)
+}
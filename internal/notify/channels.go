@@ -0,0 +1,270 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+const (
+	defaultSubject = `[{{if .Resolved}}RESOLVED{{else}}FIRING{{end}}] {{.Summary}}`
+	defaultEmail   = `{{.Summary}}
+{{range .Reasons}}
+- {{.}}{{end}}
+
+{{range $name, $value := .Labels}}{{$name}}: {{$value}}
+{{end}}`
+)
+
+// templateFuncs are available in notification templates
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	// json encodes a value, for building JSON webhook bodies
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func render(tmpl *template.Template, alert Alert) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, alert); err != nil {
+		return nil, permanentError{fmt.Errorf("failed to render %s template: %w", tmpl.Name(), err)}
+	}
+	return buf.Bytes(), nil
+}
+
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// post sends an HTTP request, treating 4xx responses other than 429 as
+// permanent failures
+func post(ctx context.Context, method, url string, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("%s returned status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(message)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return permanentError{err}
+	}
+	return err
+}
+
+// webhookSender posts alerts to a URL, as JSON or a templated body
+type webhookSender struct {
+	url    string
+	method string
+	header http.Header
+	body   *template.Template
+}
+
+func newWebhookSender(cc ChannelConfig) (*webhookSender, error) {
+	if cc.URL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	s := &webhookSender{url: cc.URL, method: cc.Method, header: http.Header{}}
+	if s.method == "" {
+		s.method = http.MethodPost
+	}
+	s.header.Set("Content-Type", "application/json")
+	for name, value := range cc.Headers {
+		s.header.Set(name, value)
+	}
+	if cc.Body != "" {
+		tmpl, err := parseTemplate("body", cc.Body)
+		if err != nil {
+			return nil, err
+		}
+		s.body = tmpl
+	}
+	return s, nil
+}
+
+func (s *webhookSender) send(ctx context.Context, alert Alert) error {
+	var body []byte
+	var err error
+	if s.body != nil {
+		body, err = render(s.body, alert)
+	} else {
+		body, err = json.Marshal(alert)
+	}
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.method, s.url, s.header, body)
+}
+
+// pagerDutySender triggers and resolves PagerDuty incidents, using the alert
+// key as the dedup key
+type pagerDutySender struct {
+	url        string
+	routingKey string
+	severity   string
+}
+
+// pagerDutyEvent is a PagerDuty Events API v2 event
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+func newPagerDutySender(cc ChannelConfig) (*pagerDutySender, error) {
+	if cc.RoutingKey == "" {
+		return nil, fmt.Errorf("pagerduty routing_key is required")
+	}
+	s := &pagerDutySender{url: cc.URL, routingKey: cc.RoutingKey, severity: cc.Severity}
+	if s.url == "" {
+		s.url = DefaultPagerDutyURL
+	}
+	switch s.severity {
+	case "":
+		s.severity = "error"
+	case "critical", "error", "warning", "info":
+	default:
+		return nil, fmt.Errorf("invalid pagerduty severity %q", cc.Severity)
+	}
+	return s, nil
+}
+
+func (s *pagerDutySender) send(ctx context.Context, alert Alert) error {
+	event := pagerDutyEvent{
+		RoutingKey:  s.routingKey,
+		EventAction: "trigger",
+		DedupKey:    alert.Key,
+	}
+	if alert.Resolved {
+		event.EventAction = "resolve"
+	} else {
+		event.Payload = &pagerDutyPayload{
+			Summary:   alert.Summary,
+			Source:    alert.Source,
+			Severity:  s.severity,
+			Timestamp: alert.Time.UTC().Format(time.RFC3339),
+			CustomDetails: map[string]interface{}{
+				"labels":  alert.Labels,
+				"reasons": alert.Reasons,
+				"rules":   alert.Rules,
+			},
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return permanentError{err}
+	}
+	return post(ctx, http.MethodPost, s.url, http.Header{"Content-Type": {"application/json"}}, body)
+}
+
+// smtpSender emails alerts
+type smtpSender struct {
+	addr     string
+	from     string
+	to       []string
+	auth     smtp.Auth
+	subject  *template.Template
+	body     *template.Template
+	sendMail func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func newSMTPSender(cc ChannelConfig) (*smtpSender, error) {
+	if cc.SMTPAddr == "" || cc.From == "" || len(cc.To) == 0 {
+		return nil, fmt.Errorf("smtp_addr, from and to are required")
+	}
+	host, _, err := net.SplitHostPort(cc.SMTPAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp_addr: %w", err)
+	}
+
+	s := &smtpSender{addr: cc.SMTPAddr, from: cc.From, to: cc.To, sendMail: smtp.SendMail}
+	if cc.Username != "" {
+		s.auth = smtp.PlainAuth("", cc.Username, cc.Password, host)
+	}
+
+	subject, body := cc.Subject, cc.Body
+	if subject == "" {
+		subject = defaultSubject
+	}
+	if body == "" {
+		body = defaultEmail
+	}
+	if s.subject, err = parseTemplate("subject", subject); err != nil {
+		return nil, err
+	}
+	if s.body, err = parseTemplate("body", body); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *smtpSender) send(ctx context.Context, alert Alert) error {
+	subject, err := render(s.subject, alert)
+	if err != nil {
+		return err
+	}
+	body, err := render(s.body, alert)
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.ReplaceAll(strings.TrimSpace(string(subject)), "\n", " "))
+	fmt.Fprintf(&msg, "Date: %s\r\n", alert.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(string(body), "\n", "\r\n"))
+
+	// net/smtp has no context support; on timeout the attempt is abandoned
+	// and the goroutine exits when the connection fails
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.sendMail(s.addr, s.auth, s.from, s.to, msg.Bytes()) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,388 @@
+// Package notify delivers alerts to on-call systems: email over SMTP,
+// PagerDuty Events v2 and generic webhooks. Channels are configured in a JSON
+// file and each can be limited to particular alert rules. Deliveries are
+// retried with backoff and rate limited per channel.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+)
+
+const (
+	// DefaultMaxAttempts is how many times a notification is tried
+	DefaultMaxAttempts = 3
+	// DefaultRetryBackoff is the delay before the first retry; it doubles on
+	// each further attempt
+	DefaultRetryBackoff = time.Second
+	// channelQueueSize bounds the notifications waiting for a channel
+	channelQueueSize = 100
+	// sendTimeout bounds a single delivery attempt
+	sendTimeout = 10 * time.Second
+)
+
+// Alert is a notification about an alert firing or resolving
+type Alert struct {
+	// Key identifies the alert across its firing and resolved notifications
+	Key string `json:"key"`
+	// Rules lists the alert rules that fired
+	Rules    []string          `json:"rules"`
+	Labels   map[string]string `json:"labels"`
+	Source   string            `json:"source"`
+	Summary  string            `json:"summary"`
+	Reasons  []string          `json:"reasons,omitempty"`
+	Resolved bool              `json:"resolved"`
+	Time     time.Time         `json:"time"`
+}
+
+// Duration is a time.Duration read from a JSON string such as "30s"
+type Duration time.Duration
+
+// UnmarshalJSON parses a Go duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Config lists the notification channels
+type Config struct {
+	Channels []ChannelConfig `json:"channels"`
+}
+
+// ChannelConfig configures one notification channel. Fields other than the
+// common ones apply to the channel's type only.
+type ChannelConfig struct {
+	Name string `json:"name"`
+	// Type is smtp, pagerduty or webhook
+	Type string `json:"type"`
+	// Rules limits the channel to alerts from these rules; empty means all
+	Rules []string `json:"rules,omitempty"`
+	// MaxAttempts is how many times a notification is tried; zero uses
+	// DefaultMaxAttempts
+	MaxAttempts  int      `json:"max_attempts,omitempty"`
+	RetryBackoff Duration `json:"retry_backoff,omitempty"`
+	// RateLimitPerMinute caps firing notifications per minute; further ones
+	// are dropped. Resolutions are always sent. Zero means no limit.
+	RateLimitPerMinute int `json:"rate_limit_per_minute,omitempty"`
+
+	// URL is the webhook URL, or overrides the PagerDuty events endpoint
+	URL string `json:"url,omitempty"`
+
+	// Webhook
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is a text/template for the request body; the default is the
+	// alert as JSON
+	Body string `json:"body,omitempty"`
+
+	// PagerDuty
+	RoutingKey string `json:"routing_key,omitempty"`
+	// Severity is critical, error, warning or info; the default is error
+	Severity string `json:"severity,omitempty"`
+
+	// SMTP; Subject and Body are templates for the message
+	SMTPAddr string   `json:"smtp_addr,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	Subject  string   `json:"subject,omitempty"`
+}
+
+// LoadConfig reads a JSON channel configuration. Secrets may be written as
+// ${VAR} and are expanded from the environment: url, headers, routing_key,
+// username and password.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read notifier config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse notifier config %s: %w", path, err)
+	}
+	for i := range config.Channels {
+		channel := &config.Channels[i]
+		channel.URL = os.ExpandEnv(channel.URL)
+		channel.RoutingKey = os.ExpandEnv(channel.RoutingKey)
+		channel.Username = os.ExpandEnv(channel.Username)
+		channel.Password = os.ExpandEnv(channel.Password)
+		for name, value := range channel.Headers {
+			channel.Headers[name] = os.ExpandEnv(value)
+		}
+	}
+	return config, nil
+}
+
+// sender delivers a notification to one channel
+type sender interface {
+	send(ctx context.Context, alert Alert) error
+}
+
+// permanentError marks a delivery failure that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// channel queues and delivers notifications for one configured channel
+type channel struct {
+	name         string
+	rules        map[string]bool
+	maxAttempts  int
+	retryBackoff time.Duration
+	rateLimit    int
+	sender       sender
+	queue        chan Alert
+	sent         []time.Time // firing notifications in the last minute
+}
+
+func (ch *channel) routes(alert Alert) bool {
+	if len(ch.rules) == 0 {
+		return true
+	}
+	for _, rule := range alert.Rules {
+		if ch.rules[rule] {
+			return true
+		}
+	}
+	return false
+}
+
+// allow applies the rate limit to a firing notification
+func (ch *channel) allow(now time.Time) bool {
+	if ch.rateLimit <= 0 {
+		return true
+	}
+	recent := ch.sent[:0]
+	for _, t := range ch.sent {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	ch.sent = recent
+	if len(ch.sent) >= ch.rateLimit {
+		return false
+	}
+	ch.sent = append(ch.sent, now)
+	return true
+}
+
+// Dispatcher routes alerts to channels, delivering each channel's
+// notifications in order on its own goroutine so a slow channel does not
+// hold up the others. A nil Dispatcher discards alerts.
+type Dispatcher struct {
+	channels []*channel
+	logger   *logger.Logger
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu sync.Mutex
+	// firing records which channels were notified of each firing alert, so
+	// its resolution goes to the same channels
+	firing map[string][]*channel
+	closed bool
+}
+
+// NewDispatcher validates the configuration and starts delivering
+func NewDispatcher(config Config) (*Dispatcher, error) {
+	if len(config.Channels) == 0 {
+		return nil, errors.New("no notification channels configured")
+	}
+
+	d := &Dispatcher{
+		logger: logger.NewFromEnv().WithComponent("notify"),
+		firing: make(map[string][]*channel),
+	}
+	names := make(map[string]bool)
+	for _, cc := range config.Channels {
+		if cc.Name == "" {
+			return nil, errors.New("notification channel name is required")
+		}
+		if names[cc.Name] {
+			return nil, fmt.Errorf("duplicate notification channel %q", cc.Name)
+		}
+		names[cc.Name] = true
+
+		s, err := newSender(cc)
+		if err != nil {
+			return nil, fmt.Errorf("channel %q: %w", cc.Name, err)
+		}
+		ch := &channel{
+			name:         cc.Name,
+			rules:        make(map[string]bool),
+			maxAttempts:  cc.MaxAttempts,
+			retryBackoff: time.Duration(cc.RetryBackoff),
+			rateLimit:    cc.RateLimitPerMinute,
+			sender:       s,
+			queue:        make(chan Alert, channelQueueSize),
+		}
+		if ch.maxAttempts <= 0 {
+			ch.maxAttempts = DefaultMaxAttempts
+		}
+		if ch.retryBackoff <= 0 {
+			ch.retryBackoff = DefaultRetryBackoff
+		}
+		for _, rule := range cc.Rules {
+			ch.rules[rule] = true
+		}
+		d.channels = append(d.channels, ch)
+	}
+
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	for _, ch := range d.channels {
+		d.wg.Add(1)
+		go d.deliver(ch)
+	}
+	return d, nil
+}
+
+func newSender(cc ChannelConfig) (sender, error) {
+	switch cc.Type {
+	case "webhook":
+		return newWebhookSender(cc)
+	case "pagerduty":
+		return newPagerDutySender(cc)
+	case "smtp":
+		return newSMTPSender(cc)
+	default:
+		return nil, fmt.Errorf("unknown channel type %q (expected smtp, pagerduty or webhook)", cc.Type)
+	}
+}
+
+// Notify queues an alert for the channels it is routed to. It never blocks;
+// if a channel's queue is full the notification is dropped and logged.
+func (d *Dispatcher) Notify(alert Alert) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+
+	var targets []*channel
+	if alert.Resolved {
+		targets = d.firing[alert.Key]
+		delete(d.firing, alert.Key)
+	} else {
+		for _, ch := range d.channels {
+			if ch.routes(alert) {
+				targets = append(targets, ch)
+			}
+		}
+		if len(targets) > 0 {
+			d.firing[alert.Key] = targets
+		}
+	}
+
+	for _, ch := range targets {
+		select {
+		case ch.queue <- alert:
+		default:
+			d.logger.Warn("Notification queue full, dropping notification",
+				"channel", ch.name, "alert", alert.Key, "resolved", alert.Resolved)
+		}
+	}
+}
+
+// deliver sends a channel's queued notifications until its queue is closed
+func (d *Dispatcher) deliver(ch *channel) {
+	defer d.wg.Done()
+
+	for alert := range ch.queue {
+		if !alert.Resolved && !ch.allow(time.Now()) {
+			d.logger.Warn("Notification rate limit reached, dropping notification",
+				"channel", ch.name, "alert", alert.Key, "limit_per_minute", ch.rateLimit)
+			continue
+		}
+
+		if err := d.send(ch, alert); err != nil {
+			d.logger.Error("Failed to deliver notification",
+				"channel", ch.name, "alert", alert.Key, "resolved", alert.Resolved, "error", err)
+			continue
+		}
+		d.logger.Info("Notification delivered", "channel", ch.name, "alert", alert.Key, "resolved", alert.Resolved)
+	}
+}
+
+// send delivers one notification, retrying with exponential backoff
+func (d *Dispatcher) send(ch *channel, alert Alert) error {
+	backoff := ch.retryBackoff
+	var err error
+	for attempt := 1; attempt <= ch.maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(d.ctx, sendTimeout)
+		err = ch.sender.send(ctx, alert)
+		cancel()
+
+		var permanent permanentError
+		if err == nil || errors.As(err, &permanent) || attempt == ch.maxAttempts {
+			break
+		}
+
+		d.logger.Warn("Notification attempt failed, retrying",
+			"channel", ch.name, "alert", alert.Key, "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-d.ctx.Done():
+			return fmt.Errorf("gave up after %d attempts on shutdown: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return err
+}
+
+// Close stops accepting alerts and waits up to timeout for queued
+// notifications to be delivered, then abandons the rest
+func (d *Dispatcher) Close(timeout time.Duration) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for _, ch := range d.channels {
+		close(ch.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		d.logger.Warn("Timed out delivering notifications on shutdown")
+		d.cancel()
+		<-done
+	}
+	d.cancel()
+}
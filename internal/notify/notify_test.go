@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a webhook endpoint that records request bodies and fails the
+// first failures requests with status
+type recorder struct {
+	mu       sync.Mutex
+	bodies   []string
+	failures int
+	status   int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, string(body))
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(r.status)
+	}
+}
+
+func (r *recorder) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.bodies...)
+}
+
+func testAlert(resolved bool) Alert {
+	return Alert{
+		Key:      "gpu-health/gpu_0",
+		Rules:    []string{"DCGM_FI_DEV_GPU_TEMP"},
+		Labels:   map[string]string{"gpu_id": "gpu_0", "hostname": "host-a", "state": "degraded"},
+		Source:   "host-a",
+		Summary:  "GPU gpu_0 on host-a is degraded",
+		Resolved: resolved,
+		Time:     time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDispatcherRouting(t *testing.T) {
+	temp, stale := &recorder{}, &recorder{}
+	tempServer, staleServer := httptest.NewServer(temp), httptest.NewServer(stale)
+	defer tempServer.Close()
+	defer staleServer.Close()
+
+	d, err := NewDispatcher(Config{Channels: []ChannelConfig{
+		{Name: "temp", Type: "webhook", URL: tempServer.URL, Rules: []string{"DCGM_FI_DEV_GPU_TEMP"}},
+		{Name: "stale", Type: "webhook", URL: staleServer.URL, Rules: []string{"stale"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Notify(testAlert(false))
+	d.Notify(testAlert(true))
+	// Not firing, so there is nothing to resolve
+	d.Notify(Alert{Key: "gpu-health/gpu_1", Resolved: true})
+	d.Close(5 * time.Second)
+
+	bodies := temp.received()
+	if len(bodies) != 2 {
+		t.Fatalf("Expected firing and resolved notifications, got %v", bodies)
+	}
+	var alert Alert
+	if err := json.Unmarshal([]byte(bodies[1]), &alert); err != nil || !alert.Resolved || alert.Key != "gpu-health/gpu_0" {
+		t.Errorf("Expected resolved alert, got %s", bodies[1])
+	}
+	if got := stale.received(); len(got) != 0 {
+		t.Errorf("Expected no notifications for unrouted channel, got %v", got)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	flaky := &recorder{failures: 2, status: http.StatusServiceUnavailable}
+	rejecting := &recorder{failures: 5, status: http.StatusBadRequest}
+	flakyServer, rejectingServer := httptest.NewServer(flaky), httptest.NewServer(rejecting)
+	defer flakyServer.Close()
+	defer rejectingServer.Close()
+
+	d, err := NewDispatcher(Config{Channels: []ChannelConfig{
+		{Name: "flaky", Type: "webhook", URL: flakyServer.URL, RetryBackoff: Duration(time.Millisecond)},
+		{Name: "rejecting", Type: "webhook", URL: rejectingServer.URL, RetryBackoff: Duration(time.Millisecond)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Notify(testAlert(false))
+	d.Close(5 * time.Second)
+
+	if got := len(flaky.received()); got != 3 {
+		t.Errorf("Expected 3 attempts after two 503s, got %d", got)
+	}
+	// 4xx responses are not retried
+	if got := len(rejecting.received()); got != 1 {
+		t.Errorf("Expected 1 attempt after a 400, got %d", got)
+	}
+}
+
+func TestDispatcherRateLimit(t *testing.T) {
+	hook := &recorder{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	d, err := NewDispatcher(Config{Channels: []ChannelConfig{
+		{Name: "hook", Type: "webhook", URL: server.URL, RateLimitPerMinute: 2},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		d.Notify(testAlert(false))
+	}
+	// Resolutions are not rate limited
+	d.Notify(testAlert(true))
+	d.Close(5 * time.Second)
+
+	if got := len(hook.received()); got != 3 {
+		t.Errorf("Expected 2 firing and 1 resolved notification, got %d", got)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	hook := &recorder{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	s, err := newWebhookSender(ChannelConfig{
+		URL:  server.URL,
+		Body: `{"text":{{json .Summary}},"host":"{{.Labels.hostname}}","rules":"{{join .Rules ","}}"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send(context.Background(), testAlert(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"text":"GPU gpu_0 on host-a is degraded","host":"host-a","rules":"DCGM_FI_DEV_GPU_TEMP"}`
+	if got := hook.received(); len(got) != 1 || got[0] != want {
+		t.Errorf("Got body %v, want %s", got, want)
+	}
+}
+
+func TestPagerDutyEvents(t *testing.T) {
+	hook := &recorder{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	s, err := newPagerDutySender(ChannelConfig{URL: server.URL, RoutingKey: "key", Severity: "critical"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, resolved := range []bool{false, true} {
+		if err := s.send(context.Background(), testAlert(resolved)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bodies := hook.received()
+	var trigger, resolve pagerDutyEvent
+	if err := json.Unmarshal([]byte(bodies[0]), &trigger); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(bodies[1]), &resolve); err != nil {
+		t.Fatal(err)
+	}
+	if trigger.EventAction != "trigger" || trigger.DedupKey != "gpu-health/gpu_0" || trigger.Payload == nil ||
+		trigger.Payload.Severity != "critical" || trigger.Payload.Source != "host-a" {
+		t.Errorf("Unexpected trigger event %s", bodies[0])
+	}
+	if resolve.EventAction != "resolve" || resolve.DedupKey != trigger.DedupKey || resolve.Payload != nil {
+		t.Errorf("Unexpected resolve event %s", bodies[1])
+	}
+
+	if _, err := newPagerDutySender(ChannelConfig{}); err == nil {
+		t.Error("Expected error without routing key")
+	}
+}
+
+func TestSMTPMessage(t *testing.T) {
+	s, err := newSMTPSender(ChannelConfig{SMTPAddr: "smtp.example.com:587", From: "alerts@example.com", To: []string{"oncall@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent string
+	s.sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		sent = string(msg)
+		return nil
+	}
+	if err := s.send(context.Background(), testAlert(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"To: oncall@example.com\r\n",
+		"Subject: [FIRING] GPU gpu_0 on host-a is degraded\r\n",
+		"hostname: host-a\r\n",
+	} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, sent)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_PD_KEY", "secret")
+	path := filepath.Join(t.TempDir(), "notify.json")
+	config := `{"channels":[{"name":"oncall","type":"pagerduty","routing_key":"${TEST_PD_KEY}","retry_backoff":"2s"}]}`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := loaded.Channels[0]
+	if channel.RoutingKey != "secret" || time.Duration(channel.RetryBackoff) != 2*time.Second {
+		t.Errorf("Unexpected channel %+v", channel)
+	}
+
+	if _, err := NewDispatcher(Config{Channels: []ChannelConfig{{Name: "x", Type: "pager"}}}); err == nil {
+		t.Error("Expected error for unknown channel type")
+	}
+}
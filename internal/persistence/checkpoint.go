@@ -1,11 +1,18 @@
 package persistence
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 )
 
+// CheckpointFileVersion is the version of the checkpoint file layout written
+// by this build. Version 1 files, a bare JSON object of checkpoints by name,
+// are still read and are rewritten as the current version on the next save.
+const CheckpointFileVersion = 2
+
 // Checkpoint represents a processing checkpoint
 type Checkpoint struct {
 	LastProcessedTime time.Time         `json:"last_processed_time"`
@@ -13,6 +20,22 @@ type Checkpoint struct {
 	Metadata          map[string]string `json:"metadata,omitempty"`
 }
 
+// checkpointFile is the on-disk layout of a checkpoint file
+type checkpointFile struct {
+	Version     int                    `json:"version"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	Checkpoints map[string]*Checkpoint `json:"checkpoints"`
+}
+
+// CompactResult describes what CompactCheckpoints changed
+type CompactResult struct {
+	// Removed are the checkpoints that were pruned, by name
+	Removed map[string]*Checkpoint
+	// MigratedFrom is the file version that was upgraded, or 0 if the file
+	// was already current
+	MigratedFrom int
+}
+
 // CheckpointManager manages checkpoint persistence
 type CheckpointManager struct {
 	fileStore   *FileStore
@@ -28,6 +51,52 @@ func NewCheckpointManager(checkpointFile string) *CheckpointManager {
 	}
 }
 
+// load reads every checkpoint from the file along with the file's version. A
+// missing file holds no checkpoints.
+func (cm *CheckpointManager) load() (map[string]*Checkpoint, int, error) {
+	data, err := os.ReadFile(cm.fileStore.filePath)
+	if os.IsNotExist(err) {
+		return make(map[string]*Checkpoint), CheckpointFileVersion, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, 0, fmt.Errorf("invalid checkpoint file %s: %w", cm.fileStore.filePath, err)
+	}
+	if _, versioned := fields["version"]; !versioned {
+		// Version 1: checkpoints by name at the top level
+		checkpoints := make(map[string]*Checkpoint)
+		if err := json.Unmarshal(data, &checkpoints); err != nil {
+			return nil, 0, fmt.Errorf("invalid checkpoint file %s: %w", cm.fileStore.filePath, err)
+		}
+		return checkpoints, 1, nil
+	}
+
+	var file checkpointFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, 0, fmt.Errorf("invalid checkpoint file %s: %w", cm.fileStore.filePath, err)
+	}
+	if file.Version > CheckpointFileVersion {
+		return nil, file.Version, fmt.Errorf("checkpoint file %s was %w (version %d, this build supports up to %d)", cm.fileStore.filePath, ErrNewerFormat, file.Version, CheckpointFileVersion)
+	}
+	if file.Checkpoints == nil {
+		file.Checkpoints = make(map[string]*Checkpoint)
+	}
+	return file.Checkpoints, file.Version, nil
+}
+
+// save writes every checkpoint to the file in the current layout
+func (cm *CheckpointManager) save(checkpoints map[string]*Checkpoint) error {
+	return cm.fileStore.Save(checkpointFile{
+		Version:     CheckpointFileVersion,
+		UpdatedAt:   time.Now().UTC(),
+		Checkpoints: checkpoints,
+	})
+}
+
 // SaveCheckpoint saves a checkpoint to both memory and file
 func (cm *CheckpointManager) SaveCheckpoint(name string, checkpoint *Checkpoint) error {
 	cm.mu.Lock()
@@ -36,12 +105,14 @@ func (cm *CheckpointManager) SaveCheckpoint(name string, checkpoint *Checkpoint)
 	// Save to memory for fast access
 	cm.memoryStore.Save(name, checkpoint)
 
-	// Save to file for persistence
-	allCheckpoints := make(map[string]*Checkpoint)
-
 	// Load existing checkpoints
-	if err := cm.fileStore.Load(&allCheckpoints); err != nil {
-		// If file doesn't exist or is empty, start with empty map
+	allCheckpoints, version, err := cm.load()
+	if version > CheckpointFileVersion {
+		// Never overwrite a file a newer build wrote
+		return err
+	}
+	if err != nil {
+		// If the file is unreadable, start with empty map
 		allCheckpoints = make(map[string]*Checkpoint)
 	}
 
@@ -49,7 +120,7 @@ func (cm *CheckpointManager) SaveCheckpoint(name string, checkpoint *Checkpoint)
 	allCheckpoints[name] = checkpoint
 
 	// Save back to file
-	return cm.fileStore.Save(allCheckpoints)
+	return cm.save(allCheckpoints)
 }
 
 // LoadCheckpoint loads a checkpoint, preferring memory cache
@@ -65,8 +136,8 @@ func (cm *CheckpointManager) LoadCheckpoint(name string) (*Checkpoint, error) {
 	}
 
 	// Load from file
-	allCheckpoints := make(map[string]*Checkpoint)
-	if err := cm.fileStore.Load(&allCheckpoints); err != nil {
+	allCheckpoints, _, err := cm.load()
+	if err != nil {
 		return nil, fmt.Errorf("failed to load checkpoints from file: %w", err)
 	}
 
@@ -86,8 +157,8 @@ func (cm *CheckpointManager) GetAllCheckpoints() (map[string]*Checkpoint, error)
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
-	allCheckpoints := make(map[string]*Checkpoint)
-	if err := cm.fileStore.Load(&allCheckpoints); err != nil {
+	allCheckpoints, _, err := cm.load()
+	if err != nil {
 		return make(map[string]*Checkpoint), nil // Return empty map if no file
 	}
 
@@ -103,13 +174,49 @@ func (cm *CheckpointManager) DeleteCheckpoint(name string) error {
 	cm.memoryStore.Delete(name)
 
 	// Remove from file
-	allCheckpoints := make(map[string]*Checkpoint)
-	if err := cm.fileStore.Load(&allCheckpoints); err != nil {
+	allCheckpoints, _, err := cm.load()
+	if err != nil {
 		return nil // Nothing to delete if file doesn't exist
 	}
 
 	delete(allCheckpoints, name)
-	return cm.fileStore.Save(allCheckpoints)
+	return cm.save(allCheckpoints)
+}
+
+// CompactCheckpoints removes the checkpoints keep rejects and rewrites the
+// file in the current layout, migrating it from an older version if needed.
+// A nil keep keeps every checkpoint. The file is only rewritten when
+// something changed.
+func (cm *CheckpointManager) CompactCheckpoints(keep func(name string, checkpoint *Checkpoint) bool) (*CompactResult, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	allCheckpoints, version, err := cm.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoints from file: %w", err)
+	}
+
+	result := &CompactResult{Removed: make(map[string]*Checkpoint)}
+	if version < CheckpointFileVersion {
+		result.MigratedFrom = version
+	}
+	if keep != nil {
+		for name, checkpoint := range allCheckpoints {
+			if !keep(name, checkpoint) {
+				result.Removed[name] = checkpoint
+				delete(allCheckpoints, name)
+				cm.memoryStore.Delete(name)
+			}
+		}
+	}
+
+	if len(result.Removed) == 0 && result.MigratedFrom == 0 {
+		return result, nil
+	}
+	if err := cm.save(allCheckpoints); err != nil {
+		return nil, fmt.Errorf("failed to save compacted checkpoints: %w", err)
+	}
+	return result, nil
 }
 
 // UpdateProcessedCount increments the processed count for a checkpoint
@@ -0,0 +1,111 @@
+package persistence
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointManager_VersionedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	cm := NewCheckpointManager(path)
+	if err := cm.SaveCheckpoint("writer-0", &Checkpoint{ProcessedCount: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	var file checkpointFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != CheckpointFileVersion || file.Checkpoints["writer-0"].ProcessedCount != 100 || file.UpdatedAt.IsZero() {
+		t.Errorf("Expected a version %d file holding the checkpoint, got %s", CheckpointFileVersion, data)
+	}
+
+	loaded, err := NewCheckpointManager(path).LoadCheckpoint("writer-0")
+	if err != nil || loaded.ProcessedCount != 100 {
+		t.Errorf("Expected the checkpoint to load back, got %+v, %v", loaded, err)
+	}
+}
+
+func TestCheckpointManager_MigratesVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	legacy := `{"worker-0":{"last_processed_time":"2025-07-18T12:00:00Z","processed_count":300},` +
+		`"writer-1":{"last_processed_time":"2025-07-18T12:00:00Z","processed_count":200}}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cm := NewCheckpointManager(path)
+	loaded, err := cm.LoadCheckpoint("writer-1")
+	if err != nil || loaded.ProcessedCount != 200 {
+		t.Fatalf("Expected version 1 checkpoints readable, got %+v, %v", loaded, err)
+	}
+
+	result, err := cm.CompactCheckpoints(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MigratedFrom != 1 || len(result.Removed) != 0 {
+		t.Errorf("Expected a migration from version 1 and nothing removed, got %+v", result)
+	}
+	all, _ := NewCheckpointManager(path).GetAllCheckpoints()
+	if len(all) != 2 || all["worker-0"].ProcessedCount != 300 {
+		t.Errorf("Expected both checkpoints kept, got %v", all)
+	}
+
+	// A current file is left alone
+	if result, err := cm.CompactCheckpoints(nil); err != nil || result.MigratedFrom != 0 {
+		t.Errorf("Expected no second migration, got %+v, %v", result, err)
+	}
+}
+
+func TestCheckpointManager_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	cm := NewCheckpointManager(path)
+	for _, name := range []string{"writer-0", "writer-1", "writer-2"} {
+		if err := cm.SaveCheckpoint(name, &Checkpoint{ProcessedCount: 10, LastProcessedTime: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := cm.CompactCheckpoints(func(name string, _ *Checkpoint) bool { return name == "writer-0" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 2 || result.Removed["writer-2"] == nil {
+		t.Errorf("Expected writer-1 and writer-2 removed, got %v", result.Removed)
+	}
+	if _, err := cm.LoadCheckpoint("writer-1"); err == nil {
+		t.Error("Expected the pruned checkpoint gone from the cache too")
+	}
+	all, _ := NewCheckpointManager(path).GetAllCheckpoints()
+	if len(all) != 1 || all["writer-0"] == nil {
+		t.Errorf("Expected only writer-0 left on disk, got %v", all)
+	}
+}
+
+func TestCheckpointManager_NewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	newer := `{"version":99,"checkpoints":{}}`
+	if err := os.WriteFile(path, []byte(newer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cm := NewCheckpointManager(path)
+	if err := cm.SaveCheckpoint("writer-0", &Checkpoint{ProcessedCount: 1}); err == nil {
+		t.Error("Expected saving over a newer file to fail")
+	}
+	if _, err := cm.CompactCheckpoints(nil); !errors.Is(err, ErrNewerFormat) {
+		t.Errorf("Expected compacting a newer file to fail with ErrNewerFormat, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != newer {
+		t.Errorf("Expected the newer file untouched, got %s", data)
+	}
+}
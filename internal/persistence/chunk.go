@@ -0,0 +1,119 @@
+package persistence
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// chunk holds a block of samples compressed with the encodings in gorilla.go
+type chunk struct {
+	first   uint64
+	count   int
+	times   []byte
+	hosts   []hostRun
+	labels  map[int]map[string]string // by row, for rows with labels
+	metrics map[string]*packedColumn
+}
+
+// hostRun starts a run of rows sharing a hostname
+type hostRun struct {
+	row      int
+	hostname string
+}
+
+// packedColumn holds one metric's compressed values
+type packedColumn struct {
+	rows   []uint64 // bitmap of the rows with a value, nil if all have one
+	values []byte
+}
+
+// sealBlock compresses a block
+func sealBlock(b *block) *chunk {
+	c := &chunk{
+		first:   b.first,
+		count:   len(b.times),
+		times:   encodeTimes(b.times),
+		metrics: make(map[string]*packedColumn, len(b.metrics)),
+	}
+
+	for row, hostname := range b.hostnames {
+		if row == 0 || hostname != b.hostnames[row-1] {
+			c.hosts = append(c.hosts, hostRun{row: row, hostname: hostname})
+		}
+	}
+	c.hosts = slices.Clip(c.hosts)
+
+	for row, labels := range b.labels {
+		if labels == nil {
+			continue
+		}
+		if c.labels == nil {
+			c.labels = make(map[int]map[string]string)
+		}
+		c.labels[row] = labels
+	}
+
+	for name, column := range b.metrics {
+		packed := &packedColumn{values: encodeValues(column.values)}
+		if len(column.seqs) < c.count {
+			packed.rows = make([]uint64, (c.count+63)/64)
+			for _, seq := range column.seqs {
+				row := seq - c.first
+				packed.rows[row/64] |= 1 << (row % 64)
+			}
+		}
+		c.metrics[name] = packed
+	}
+	return c
+}
+
+// decode decompresses the chunk
+func (c *chunk) decode() *block {
+	b := &block{
+		first:     c.first,
+		times:     decodeTimes(c.times, c.count),
+		hostnames: make([]string, c.count),
+		metrics:   make(map[string]*metricColumn, len(c.metrics)),
+	}
+
+	for i, run := range c.hosts {
+		end := c.count
+		if i+1 < len(c.hosts) {
+			end = c.hosts[i+1].row
+		}
+		for row := run.row; row < end; row++ {
+			b.hostnames[row] = run.hostname
+		}
+	}
+
+	if c.labels != nil {
+		b.labels = make([]map[string]string, c.count)
+		for row, labels := range c.labels {
+			b.labels[row] = labels
+		}
+	}
+
+	for name, packed := range c.metrics {
+		b.metrics[name] = c.column(packed)
+	}
+	return b
+}
+
+// column decompresses one metric's values
+func (c *chunk) column(packed *packedColumn) *metricColumn {
+	var seqs []uint64
+	if packed.rows == nil {
+		seqs = make([]uint64, c.count)
+		for row := range seqs {
+			seqs[row] = c.first + uint64(row)
+		}
+	} else {
+		for i, word := range packed.rows {
+			for word != 0 {
+				seqs = append(seqs, c.first+uint64(i*64+bits.TrailingZeros64(word)))
+				word &= word - 1
+			}
+		}
+	}
+	return &metricColumn{seqs: seqs, values: decodeValues(packed.values, len(seqs))}
+}
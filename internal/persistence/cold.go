@@ -0,0 +1,159 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cold segments are named <escaped GPU ID>/<first>-<last>.jsonl, the
+// timestamps of their oldest and newest records in zero-padded Unix
+// nanoseconds, so keys sort by age and reads can skip segments by name. They
+// hold records framed and sealed exactly as in the GPU's telemetry file.
+const coldSegmentExt = ".jsonl"
+
+// SetColdStore sets the object store ArchiveTelemetry moves records to
+func (fs *FileStorage) SetColdStore(store ObjectStore) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.cold = store
+}
+
+// ArchiveTelemetry moves a GPU's records with timestamps before cutoff into a
+// new cold segment and returns how many moved. The segment is written before
+// the records are removed from the telemetry file, so a crash in between
+// leaves them in both tiers rather than in neither.
+func (fs *FileStorage) ArchiveTelemetry(gpuID string, cutoff time.Time) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.cold == nil {
+		return 0, fmt.Errorf("no cold store configured")
+	}
+
+	filePath := fs.gpuFilePath(gpuID)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	var archived, kept bytes.Buffer
+	var first, last time.Time
+	moved := 0
+	if _, err := scanFrames(bytes.NewReader(data), func(payload []byte) {
+		frame := encodeFrame(payload)
+
+		// Records that cannot be read stay where they are
+		var record struct {
+			Timestamp time.Time `json:"timestamp"`
+		}
+		plain, err := fs.keyring.Open(payload)
+		if err != nil || json.Unmarshal(plain, &record) != nil || record.Timestamp.IsZero() || !record.Timestamp.Before(cutoff) {
+			kept.Write(frame)
+			return
+		}
+
+		archived.Write(frame)
+		if moved == 0 || record.Timestamp.Before(first) {
+			first = record.Timestamp
+		}
+		if moved == 0 || record.Timestamp.After(last) {
+			last = record.Timestamp
+		}
+		moved++
+	}); err != nil {
+		return 0, fmt.Errorf("telemetry file %s is corrupt (%v); run recovery before archiving", filePath, err)
+	}
+	if moved == 0 {
+		return 0, nil
+	}
+
+	if err := fs.cold.Put(coldSegmentKey(gpuID, first, last), archived.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to write cold segment for %s: %w", gpuID, err)
+	}
+
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, kept.Bytes(), 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := replaceFile(tmpPath, filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to replace %s: %w", filePath, err)
+	}
+
+	return moved, nil
+}
+
+// ReadColdTelemetry returns a GPU's archived records, oldest segment first,
+// skipping segments whose newest record is before since
+func (fs *FileStorage) ReadColdTelemetry(gpuID string, since time.Time) ([]json.RawMessage, error) {
+	fs.mu.Lock()
+	cold, keyring := fs.cold, fs.keyring
+	fs.mu.Unlock()
+
+	if cold == nil {
+		return []json.RawMessage{}, nil
+	}
+
+	keys, err := cold.List(escapeFileName(gpuID) + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []json.RawMessage{}
+	for _, key := range keys {
+		_, last, ok := parseColdSegmentKey(key)
+		if !ok || (!since.IsZero() && last.Before(since)) {
+			continue
+		}
+
+		data, err := cold.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cold segment %s: %w", key, err)
+		}
+		if _, err := scanFrames(bytes.NewReader(data), func(payload []byte) {
+			record, err := keyring.Open(payload)
+			if err != nil {
+				fmt.Printf("Warning: skipping record in cold segment %s: %v\n", key, err)
+				return
+			}
+			messages = append(messages, append(json.RawMessage(nil), record...))
+		}); err != nil {
+			fmt.Printf("Warning: stopped reading cold segment %s at corrupt record: %v\n", key, err)
+		}
+	}
+
+	return messages, nil
+}
+
+// coldSegmentKey names the cold segment of a GPU's records from first to last
+func coldSegmentKey(gpuID string, first, last time.Time) string {
+	return fmt.Sprintf("%s/%020d-%020d%s", escapeFileName(gpuID), first.UnixNano(), last.UnixNano(), coldSegmentExt)
+}
+
+// parseColdSegmentKey returns the time span in a cold segment's key
+func parseColdSegmentKey(key string) (time.Time, time.Time, bool) {
+	name := strings.TrimSuffix(path.Base(key), coldSegmentExt)
+	firstNanos, lastNanos, found := strings.Cut(name, "-")
+	if !found {
+		return time.Time{}, time.Time{}, false
+	}
+	first, err := strconv.ParseInt(firstNanos, 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	last, err := strconv.ParseInt(lastNanos, 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(0, first), time.Unix(0, last), true
+}
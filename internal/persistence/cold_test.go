@@ -0,0 +1,96 @@
+package persistence
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStorage_ArchiveTelemetry(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewFileStorage(filepath.Join(dir, "data"))
+	if _, err := storage.ArchiveTelemetry("MIG-GPU-abc/1/0", time.Now()); err == nil {
+		t.Error("Expected error archiving without a cold store")
+	}
+	cold := NewDirObjectStore(filepath.Join(dir, "cold"))
+	storage.SetColdStore(cold)
+
+	gpuID := "MIG-GPU-abc/1/0"
+	base := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		tel := Telemetry{GPUId: gpuID, Hostname: "host-1", Metrics: map[string]float64{"util": float64(i)}, Timestamp: base.Add(time.Duration(i) * time.Hour)}
+		if err := storage.WriteTelemetry(tel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := storage.ArchiveTelemetry(gpuID, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Fatalf("Expected 2 records moved, got %d", moved)
+	}
+	if moved, _ := storage.ArchiveTelemetry(gpuID, base.Add(2*time.Hour)); moved != 0 {
+		t.Errorf("Expected nothing left to move, got %d", moved)
+	}
+	if _, err := storage.ArchiveTelemetry(gpuID, base.Add(3*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	warm, err := storage.ReadTelemetryFile(gpuID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := utilValues(t, warm); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("Expected samples 3 and 4 left in the telemetry file, got %v", got)
+	}
+
+	keys, err := cold.List(escapeFileName(gpuID) + "/")
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("Expected 2 cold segments, got %v, %v", keys, err)
+	}
+	all, err := storage.ReadColdTelemetry(gpuID, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := utilValues(t, all); len(got) != 3 || got[0] != 0 || got[2] != 2 {
+		t.Errorf("Expected samples 0 to 2 in cold storage, got %v", got)
+	}
+
+	// Segments ending before since are skipped whole
+	recent, err := storage.ReadColdTelemetry(gpuID, base.Add(90*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := utilValues(t, recent); len(got) != 1 || got[0] != 2 {
+		t.Errorf("Expected only the segment holding sample 2, got %v", got)
+	}
+}
+
+func TestDirObjectStore_RejectsEscapingKeys(t *testing.T) {
+	store := NewDirObjectStore(t.TempDir())
+	for _, key := range []string{"", "../x", "/abs", "a/../../x", "a//b"} {
+		if err := store.Put(key, []byte("x")); err == nil {
+			t.Errorf("Expected error for key %q", key)
+		}
+	}
+	if keys, err := store.List("missing/"); err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys under a missing prefix, got %v, %v", keys, err)
+	}
+}
+
+func utilValues(t *testing.T, records []json.RawMessage) []float64 {
+	t.Helper()
+
+	var values []float64
+	for _, record := range records {
+		var tel Telemetry
+		if err := json.Unmarshal(record, &tel); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, tel.Metrics["util"])
+	}
+	return values
+}
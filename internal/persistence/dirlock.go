@@ -0,0 +1,100 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned when a lock file is already held by another process
+var ErrLocked = errors.New("already in use by another process")
+
+// errLockHeld is returned by the platform tryLockFile implementations
+var errLockHeld = errors.New("lock held")
+
+// dirLockFileName is the name of the lock file created inside locked directories
+const dirLockFileName = ".lock"
+
+// LockFile is an exclusive lock held through an open lock file. The lock is
+// owned by the open file handle, so the OS releases it if the process dies and
+// a leftover lock file never blocks a restart.
+type LockFile struct {
+	path string
+	file *os.File
+}
+
+// AcquireDirLock takes an exclusive lock on dir, creating the directory if
+// needed. It fails immediately with ErrLocked if another process holds it.
+func AcquireDirLock(dir string) (*LockFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	return AcquireLockFile(filepath.Join(dir, dirLockFileName))
+}
+
+// AcquireLockFile takes an exclusive lock on the file at path, creating it if
+// needed. It fails immediately with ErrLocked if another process holds it.
+func AcquireLockFile(path string) (*LockFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for lock file %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	if err := tryLockFile(file); err != nil {
+		_ = file.Close()
+		if errors.Is(err, errLockHeld) {
+			if holder := readLockHolder(path); holder != "" {
+				return nil, fmt.Errorf("%s is %w (%s)", path, ErrLocked, holder)
+			}
+			return nil, fmt.Errorf("%s is %w", path, ErrLocked)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// Record the owner so a conflicting process can report who holds the lock
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf("pid=%d host=%s since=%s\n", os.Getpid(), hostname, time.Now().UTC().Format(time.RFC3339))
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(holder), 0)
+	}
+
+	return &LockFile{path: path, file: file}, nil
+}
+
+// Path returns the lock file path
+func (l *LockFile) Path() string {
+	return l.path
+}
+
+// Release unlocks and closes the lock file. The file itself is left in place;
+// removing it could race with another process acquiring the lock.
+func (l *LockFile) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	unlockErr := unlockFile(l.file)
+	closeErr := l.file.Close()
+	l.file = nil
+
+	if unlockErr != nil {
+		return fmt.Errorf("failed to unlock %s: %w", l.path, unlockErr)
+	}
+	return closeErr
+}
+
+// readLockHolder returns the owner recorded in a lock file, if readable
+func readLockHolder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,86 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrDiskFull is returned when a write is refused because the filesystem it
+// would go to has less free space than its guard keeps
+var ErrDiskFull = errors.New("insufficient free disk space")
+
+// DefaultMinFreeDiskBytes is the free space writers keep on their data
+// directory's filesystem by default
+const DefaultMinFreeDiskBytes = 256 << 20
+
+// diskCheckInterval is how long a measurement of the free space is reused,
+// so that guarding every write does not statfs on every write
+const diskCheckInterval = time.Second
+
+// DiskGuard refuses writes to a directory while its filesystem has less free
+// space than a minimum, so that a full disk stops writes cleanly rather than
+// failing them halfway
+type DiskGuard struct {
+	dir     string
+	minFree uint64
+
+	mu       sync.Mutex
+	checked  time.Time
+	used     uint64
+	size     uint64
+	err      error
+	now      func() time.Time
+	measured func(dir string) (used, size uint64, err error)
+}
+
+// NewDiskGuard creates a guard keeping minFree bytes free on the filesystem
+// holding dir
+func NewDiskGuard(dir string, minFree uint64) *DiskGuard {
+	return &DiskGuard{
+		dir:      dir,
+		minFree:  minFree,
+		now:      time.Now,
+		measured: DiskUsage,
+	}
+}
+
+// Dir returns the guarded directory
+func (g *DiskGuard) Dir() string {
+	return g.dir
+}
+
+// measure refreshes the measurement of the filesystem if it is stale. The
+// caller must hold g.mu.
+func (g *DiskGuard) measure() {
+	now := g.now()
+	if !g.checked.IsZero() && now.Sub(g.checked) < diskCheckInterval {
+		return
+	}
+	g.used, g.size, g.err = g.measured(g.dir)
+	g.checked = now
+}
+
+// Check returns an error wrapping ErrDiskFull if a write should be refused.
+// A filesystem that cannot be measured is not guarded.
+func (g *DiskGuard) Check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.measure()
+	if g.err != nil {
+		return nil
+	}
+	if free := g.size - g.used; free < g.minFree {
+		return fmt.Errorf("%s has %d bytes free, below the minimum of %d: %w", g.dir, free, g.minFree, ErrDiskFull)
+	}
+	return nil
+}
+
+// Usage returns the bytes used on the guarded filesystem and its size
+func (g *DiskGuard) Usage() (used, size uint64, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.measure()
+	return g.used, g.size, g.err
+}
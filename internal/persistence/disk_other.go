@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+
+package persistence
+
+import "errors"
+
+// DiskUsage is not supported on platforms without statfs (windows, the other
+// BSDs, solaris, js, wasip1, plan9)
+func DiskUsage(dir string) (used, size uint64, err error) {
+	return 0, 0, errors.New("disk usage is not supported on this platform")
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd
+
+package persistence
+
+import "syscall"
+
+// DiskUsage returns the bytes used on the filesystem holding dir and its
+// size, counting only the space available to unprivileged users as free
+func DiskUsage(dir string) (used, size uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, 0, err
+	}
+	blockSize := uint64(stat.Bsize)
+	used = (uint64(stat.Blocks) - uint64(stat.Bfree)) * blockSize
+	return used, used + uint64(stat.Bavail)*blockSize, nil
+}
@@ -0,0 +1,59 @@
+package persistence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDiskGuard(t *testing.T) {
+	now := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+	free := uint64(1000)
+	measurements := 0
+	guard := NewDiskGuard(t.TempDir(), 500)
+	guard.now = func() time.Time { return now }
+	guard.measured = func(string) (uint64, uint64, error) {
+		measurements++
+		return 10000 - free, 10000, nil
+	}
+
+	if err := guard.Check(); err != nil {
+		t.Fatalf("Expected a write allowed with 1000 bytes free, got %v", err)
+	}
+
+	// The measurement is reused until it goes stale
+	free = 100
+	if err := guard.Check(); err != nil || measurements != 1 {
+		t.Errorf("Expected the first measurement reused, got %v after %d", err, measurements)
+	}
+	now = now.Add(diskCheckInterval)
+	if err := guard.Check(); !errors.Is(err, ErrDiskFull) {
+		t.Errorf("Expected ErrDiskFull with 100 bytes free, got %v", err)
+	}
+	if used, size, _ := guard.Usage(); used != 9900 || size != 10000 {
+		t.Errorf("Unexpected usage %d of %d", used, size)
+	}
+
+	// Writes resume once space is freed, and an unmeasurable disk is not
+	// guarded
+	free = 600
+	now = now.Add(diskCheckInterval)
+	if err := guard.Check(); err != nil {
+		t.Errorf("Expected writes resumed, got %v", err)
+	}
+	guard.measured = func(string) (uint64, uint64, error) { return 0, 0, errors.New("unsupported") }
+	now = now.Add(diskCheckInterval)
+	if err := guard.Check(); err != nil {
+		t.Errorf("Expected an unmeasurable disk not guarded, got %v", err)
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	used, size, err := DiskUsage(t.TempDir())
+	if err != nil {
+		t.Skipf("Disk usage not supported: %v", err)
+	}
+	if size == 0 || used > size {
+		t.Errorf("Unexpected usage %d of %d", used, size)
+	}
+}
@@ -0,0 +1,124 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption/encryptiontest"
+)
+
+func TestFileStorage_Encrypted(t *testing.T) {
+	fs := NewFileStorage(t.TempDir())
+	fs.SetKeyring(encryptiontest.Keyring(t, "k1="+encryptiontest.Key(t)))
+
+	tel := Telemetry{
+		GPUId:     "gpu-secret",
+		Hostname:  "host-secret",
+		Metrics:   map[string]float64{"temperature": 71},
+		Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := fs.WriteTelemetry(tel); err != nil {
+		t.Fatalf("WriteTelemetry failed: %v", err)
+	}
+	// Duplicate detection must still work on encrypted records
+	if err := fs.WriteTelemetry(tel); err != nil {
+		t.Fatalf("WriteTelemetry failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(fs.gpuFilePath(tel.GPUId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("host-secret")) {
+		t.Error("Telemetry file contains plaintext")
+	}
+	if lines := bytes.Count(raw, []byte("\n")); lines != 1 {
+		t.Errorf("Expected 1 record, got %d", lines)
+	}
+
+	records, err := fs.ReadTelemetryFile(tel.GPUId)
+	if err != nil {
+		t.Fatalf("ReadTelemetryFile failed: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	var got Telemetry
+	if err := json.Unmarshal(records[0], &got); err != nil {
+		t.Fatalf("Invalid record: %v", err)
+	}
+	if got.Hostname != "host-secret" {
+		t.Errorf("Expected hostname host-secret, got %s", got.Hostname)
+	}
+
+	// Without a key the records are skipped rather than returned as ciphertext
+	plain := NewFileStorage(fs.dataDir)
+	records, err = plain.ReadTelemetryFile(tel.GPUId)
+	if err != nil {
+		t.Fatalf("ReadTelemetryFile failed: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no readable records without a key, got %d", len(records))
+	}
+}
+
+func TestFileStorage_Reencrypt(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, newKey := encryptiontest.Key(t), encryptiontest.Key(t)
+
+	// Plaintext record from before encryption was enabled
+	fs := NewFileStorage(dir)
+	if err := fs.WriteTelemetry(Telemetry{GPUId: "gpu-0", Hostname: "h", Metrics: map[string]float64{"m": 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Record sealed with the old key
+	fs.SetKeyring(encryptiontest.Keyring(t, "old="+oldKey))
+	if err := fs.WriteTelemetry(Telemetry{GPUId: "gpu-0", Hostname: "h", Metrics: map[string]float64{"m": 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := encryptiontest.Keyring(t, "new="+newKey, "old="+oldKey)
+	fs.SetKeyring(rotated)
+	count, err := fs.Reencrypt()
+	if err != nil {
+		t.Fatalf("Reencrypt failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 records rewritten, got %d", count)
+	}
+
+	// Every record is now readable with the new key alone
+	fs.SetKeyring(encryptiontest.Keyring(t, "new="+newKey))
+	records, err := fs.ReadTelemetryFile("gpu-0")
+	if err != nil {
+		t.Fatalf("ReadTelemetryFile failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	for i, record := range records {
+		var tel Telemetry
+		if err := json.Unmarshal(record, &tel); err != nil {
+			t.Fatalf("Invalid record: %v", err)
+		}
+		if tel.Metrics["m"] != float64(i+1) {
+			t.Errorf("Record %d: expected m=%d, got %v", i, i+1, tel.Metrics["m"])
+		}
+	}
+
+	count, err = fs.Reencrypt()
+	if err != nil || count != 0 {
+		t.Errorf("Second pass = %d, %v; want 0, nil", count, err)
+	}
+}
+
+func TestFileStorage_ReencryptRequiresKey(t *testing.T) {
+	fs := NewFileStorage(t.TempDir())
+	if _, err := fs.Reencrypt(); err == nil {
+		t.Error("Expected error without a keyring")
+	}
+}
@@ -1,16 +1,19 @@
 package persistence
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
-	"syscall"
 	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
 )
 
 // FileStore handles basic file operations
@@ -23,23 +26,51 @@ func NewFileStore(filePath string) *FileStore {
 	return &FileStore{filePath: filePath}
 }
 
+// Save writes data as JSON. The file is written to a temporary sibling and
+// renamed into place so readers never observe a partially written file.
 func (fs *FileStore) Save(data interface{}) error {
 	fs.lock.Lock()
 	defer fs.lock.Unlock()
 
-	file, err := os.OpenFile(fs.filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	return WriteFileAtomic(fs.filePath, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(data)
+	})
+}
+
+// WriteFileAtomic writes path through write, via a temporary sibling that is
+// synced and renamed into place, so readers see either the old or the new
+// contents and never a partial file
+func WriteFileAtomic(path string, write func(w io.Writer) error) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			// Note: Can't return error from defer, just log
-			fmt.Printf("Warning: failed to close file: %v\n", err)
-		}
-	}()
 
-	encoder := json.NewEncoder(file)
-	return encoder.Encode(data)
+	if err := write(file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	// Windows cannot rename a file that is still open
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := replaceFile(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
 }
 
 func (fs *FileStore) Load(target interface{}) error {
@@ -64,6 +95,8 @@ func (fs *FileStore) Load(target interface{}) error {
 type FileStorage struct {
 	dataDir string
 	mu      sync.Mutex
+	keyring *encryption.Keyring // nil means records are stored in plaintext
+	cold    ObjectStore         // nil until SetColdStore
 }
 
 // NewFileStorage creates a new file storage instance
@@ -73,6 +106,14 @@ func NewFileStorage(dataDir string) *FileStorage {
 	}
 }
 
+// SetKeyring enables encryption at rest for records written from now on.
+// Existing plaintext records stay readable; use Reencrypt to migrate them.
+func (fs *FileStorage) SetKeyring(keyring *encryption.Keyring) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.keyring = keyring
+}
+
 // WriteTelemetry writes telemetry data to per-GPU JSONL files
 func (fs *FileStorage) WriteTelemetry(telemetry interface{}) error {
 	fs.mu.Lock()
@@ -108,7 +149,7 @@ func (fs *FileStorage) WriteTelemetry(telemetry interface{}) error {
 	}
 
 	// Create file path
-	filePath := filepath.Join(fs.dataDir, fmt.Sprintf("%s.jsonl", gpuID))
+	filePath := fs.gpuFilePath(gpuID)
 
 	// Open file for appending
 	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
@@ -122,11 +163,11 @@ func (fs *FileStorage) WriteTelemetry(telemetry interface{}) error {
 	}()
 
 	// cross-process critical section
-	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+	if err := lockFile(file); err != nil {
 		return fmt.Errorf("failed to lock file %s: %w", filePath, err)
 	}
 	defer func() {
-		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_UN); err != nil {
+		if err := unlockFile(file); err != nil {
 			fmt.Printf("Warning: failed to unlock file: %v\n", err)
 		}
 	}()
@@ -147,15 +188,30 @@ func (fs *FileStorage) WriteTelemetry(telemetry interface{}) error {
 		return fmt.Errorf("failed to rewind file %s: %w", filePath, err)
 	}
 
-	decoder := json.NewDecoder(bufio.NewReader(file))
-	for decoder.More() {
+	duplicate := false
+	validBytes, scanErr := scanFrames(file, func(payload []byte) {
+		if duplicate {
+			return
+		}
+		record, err := fs.keyring.Open(payload)
+		if err != nil {
+			return // Skip records we cannot decrypt
+		}
 		var existing Telemetry
-		if err := decoder.Decode(&existing); err != nil {
-			continue // Skip malformed lines
+		if err := json.Unmarshal(record, &existing); err != nil {
+			return // Skip records that are not telemetry
 		}
 		if reflect.DeepEqual(existing, target) {
-			return nil // Data already exists, skip writing
+			duplicate = true
 		}
+	})
+	if duplicate {
+		return nil // Data already exists, skip writing
+	}
+
+	// Never append after a torn or corrupt record; it would hide the new one
+	if scanErr != nil {
+		return fmt.Errorf("telemetry file %s is corrupt at offset %d (%v); run recovery before writing", filePath, validBytes, scanErr)
 	}
 
 	// append after duplicate check
@@ -163,17 +219,46 @@ func (fs *FileStorage) WriteTelemetry(telemetry interface{}) error {
 		return fmt.Errorf("failed to seek file %s: %w", filePath, err)
 	}
 
-	// Write JSON line
-	if _, err := file.Write(append(jsonData, '\n')); err != nil {
+	payload, err := fs.keyring.Seal(jsonData)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt telemetry data: %w", err)
+	}
+
+	// Write framed JSON line
+	if _, err := file.Write(encodeFrame(payload)); err != nil {
 		return fmt.Errorf("failed to write to file %s: %w", filePath, err)
 	}
 
 	return nil
 }
 
+// SyncTelemetry flushes the telemetry files of the given GPUs to disk.
+// WriteTelemetry leaves that to the OS, so a storage writer can sync a whole
+// batch of writes at once.
+func (fs *FileStorage) SyncTelemetry(gpuIDs []string) error {
+	for _, gpuID := range gpuIDs {
+		filePath := fs.gpuFilePath(gpuID)
+		file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to open file %s: %w", filePath, err)
+		}
+		syncErr := file.Sync()
+		if err := file.Close(); err != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", err)
+		}
+		if syncErr != nil {
+			return fmt.Errorf("failed to sync file %s: %w", filePath, syncErr)
+		}
+	}
+	return nil
+}
+
 // ReadTelemetryFile reads all telemetry data from a specific GPU file
 func (fs *FileStorage) ReadTelemetryFile(gpuID string) ([]json.RawMessage, error) {
-	filePath := filepath.Join(fs.dataDir, fmt.Sprintf("%s.jsonl", gpuID))
+	filePath := fs.gpuFilePath(gpuID)
 
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -188,21 +273,195 @@ func (fs *FileStorage) ReadTelemetryFile(gpuID string) ([]json.RawMessage, error
 		}
 	}()
 
-	var messages []json.RawMessage
-	decoder := json.NewDecoder(file)
+	// Records after a corrupt or partially written one are not returned;
+	// Recover truncates such files on startup.
+	fs.mu.Lock()
+	keyring := fs.keyring
+	fs.mu.Unlock()
 
-	for decoder.More() {
-		var msg json.RawMessage
-		if err := decoder.Decode(&msg); err != nil {
-			// Log error but continue reading
-			continue
+	var messages []json.RawMessage
+	if _, err := scanFrames(file, func(payload []byte) {
+		record, err := keyring.Open(payload)
+		if err != nil {
+			fmt.Printf("Warning: skipping record in %s: %v\n", filePath, err)
+			return
 		}
-		messages = append(messages, msg)
+		messages = append(messages, append(json.RawMessage(nil), record...))
+	}); err != nil && err != errIncompleteRecord {
+		fmt.Printf("Warning: stopped reading %s at corrupt record: %v\n", filePath, err)
 	}
 
 	return messages, nil
 }
 
+// RecoveryResult describes a telemetry file repaired by Recover
+type RecoveryResult struct {
+	GPUId          string
+	BytesDiscarded int64
+	Reason         string
+}
+
+// Recover scans every telemetry file and truncates it at the first corrupt or
+// incomplete record, so a crash mid-write cannot poison later reads or writes.
+// It returns one result per file that had to be truncated.
+func (fs *FileStorage) Recover() ([]RecoveryResult, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	gpuIDs, err := fs.ListGPUFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []RecoveryResult
+	for _, gpuID := range gpuIDs {
+		result, err := fs.recoverFile(gpuID)
+		if err != nil {
+			return results, err
+		}
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results, nil
+}
+
+// recoverFile truncates a single GPU file at its first corrupt record. Caller must hold fs.mu.
+func (fs *FileStorage) recoverFile(gpuID string) (*RecoveryResult, error) {
+	filePath := fs.gpuFilePath(gpuID)
+
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", err)
+		}
+	}()
+
+	if err := lockFile(file); err != nil {
+		return nil, fmt.Errorf("failed to lock file %s: %w", filePath, err)
+	}
+	defer func() {
+		if err := unlockFile(file); err != nil {
+			fmt.Printf("Warning: failed to unlock file: %v\n", err)
+		}
+	}()
+
+	validBytes, scanErr := scanFrames(file, func([]byte) {})
+	if scanErr == nil {
+		return nil, nil
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", filePath, err)
+	}
+
+	if err := file.Truncate(validBytes); err != nil {
+		return nil, fmt.Errorf("failed to truncate file %s: %w", filePath, err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync file %s: %w", filePath, err)
+	}
+
+	return &RecoveryResult{
+		GPUId:          gpuID,
+		BytesDiscarded: info.Size() - validBytes,
+		Reason:         scanErr.Error(),
+	}, nil
+}
+
+// Reencrypt rewrites every telemetry file so all records are sealed with the
+// active key of the configured keyring, migrating plaintext records and
+// records sealed with rotated-out keys. Files that are already current are
+// left untouched. It returns the number of records rewritten.
+//
+// Reencrypt must not run while a collector is writing to the directory.
+func (fs *FileStorage) Reencrypt() (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.keyring == nil {
+		return 0, fmt.Errorf("no encryption key configured")
+	}
+
+	gpuIDs, err := fs.ListGPUFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, gpuID := range gpuIDs {
+		n, err := fs.reencryptFile(gpuID)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// reencryptFile rewrites a single GPU file with the active key. Caller must hold fs.mu.
+func (fs *FileStorage) reencryptFile(gpuID string) (int, error) {
+	filePath := fs.gpuFilePath(gpuID)
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	var records [][]byte
+	stale := 0
+	var openErr error
+	if _, err := scanFrames(bytes.NewReader(data), func(payload []byte) {
+		if openErr != nil {
+			return
+		}
+		if fs.keyring.NeedsRotation(payload) {
+			stale++
+		}
+		record, err := fs.keyring.Open(payload)
+		if err != nil {
+			openErr = err
+			return
+		}
+		records = append(records, append([]byte(nil), record...))
+	}); err != nil {
+		return 0, fmt.Errorf("telemetry file %s is corrupt (%v); run recovery before re-encrypting", filePath, err)
+	}
+	if openErr != nil {
+		return 0, fmt.Errorf("failed to decrypt %s: %w", filePath, openErr)
+	}
+	if stale == 0 {
+		return 0, nil
+	}
+
+	var out bytes.Buffer
+	for _, record := range records {
+		payload, err := fs.keyring.Encrypt(record)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt record in %s: %w", filePath, err)
+		}
+		out.Write(encodeFrame(payload))
+	}
+
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, out.Bytes(), 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := replaceFile(tmpPath, filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to replace %s: %w", filePath, err)
+	}
+
+	return stale, nil
+}
+
 // ListGPUFiles returns a list of all GPU IDs that have data files
 func (fs *FileStorage) ListGPUFiles() ([]string, error) {
 	entries, err := os.ReadDir(fs.dataDir)
@@ -216,7 +475,7 @@ func (fs *FileStorage) ListGPUFiles() ([]string, error) {
 	var gpuIDs []string
 	for _, entry := range entries {
 		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".jsonl" {
-			gpuID := entry.Name()[:len(entry.Name())-6] // Remove .jsonl extension
+			gpuID := unescapeFileName(entry.Name()[:len(entry.Name())-6]) // Remove .jsonl extension
 			gpuIDs = append(gpuIDs, gpuID)
 		}
 	}
@@ -311,8 +570,7 @@ func (fs *FileStorage) GetFileStats() (map[string]interface{}, error) {
 	// Add file sizes
 	fileSizes := make(map[string]int64)
 	for _, gpuID := range gpuIDs {
-		filePath := filepath.Join(fs.dataDir, fmt.Sprintf("%s.jsonl", gpuID))
-		if info, err := os.Stat(filePath); err == nil {
+		if info, err := os.Stat(fs.gpuFilePath(gpuID)); err == nil {
 			fileSizes[gpuID] = info.Size()
 		}
 	}
@@ -321,10 +579,131 @@ func (fs *FileStorage) GetFileStats() (map[string]interface{}, error) {
 	return stats, nil
 }
 
+// gpuFilePath returns the JSONL file path for a GPU ID
+func (fs *FileStorage) gpuFilePath(gpuID string) string {
+	return filepath.Join(fs.dataDir, escapeFileName(gpuID)+".jsonl")
+}
+
+// reservedFileChars are characters that are path separators or invalid in
+// file names on at least one supported platform (Windows rejects <>:"|?*).
+// '%' is included so escaping stays reversible.
+const reservedFileChars = `/\<>:"|?*%`
+
+// escapeFileName percent-encodes characters in name that cannot appear in a
+// file name on every platform, so data directories stay portable and IDs such
+// as MIG UUIDs ("MIG-GPU-.../1/0") cannot escape the data directory.
+func escapeFileName(name string) string {
+	if name == "." || name == ".." {
+		return strings.ReplaceAll(name, ".", "%2E")
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte(reservedFileChars, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// escapeLegacyFileNames renames the telemetry files in dir named after a GPU
+// ID as it is, from before IDs were escaped, to their escaped names. A name
+// that is already a valid escaped name is left alone, so an old ID such as
+// "gpu%3A0" reads as the ID it escapes, "gpu:0". Should both names exist,
+// the old file's records are appended to the new one's complete records.
+func escapeLegacyFileNames(dir string, progress func(done, total int)) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return err
+	}
+
+	progress(0, len(files))
+	for i, filePath := range files {
+		name := strings.TrimSuffix(filepath.Base(filePath), ".jsonl")
+		if escapeFileName(unescapeFileName(name)) != name {
+			target := filepath.Join(dir, escapeFileName(name)+".jsonl")
+			if err := moveTelemetryFile(filePath, target); err != nil {
+				return err
+			}
+		}
+		progress(i+1, len(files))
+	}
+	return nil
+}
+
+// moveTelemetryFile renames a telemetry file, appending its records to
+// target if that exists already
+func moveTelemetryFile(filePath, target string) error {
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		if err := os.Rename(filePath, target); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", filePath, err)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	existing, err := os.ReadFile(target)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", target, err)
+	}
+	// A torn record at the end of target would hide the appended ones from
+	// recovery, which truncates at the first bad record
+	validBytes, _ := scanFrames(bytes.NewReader(existing), func([]byte) {})
+	existing = existing[:validBytes]
+	if err := WriteFileAtomic(target, func(w io.Writer) error {
+		if _, err := w.Write(existing); err != nil {
+			return err
+		}
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to merge %s into %s: %w", filePath, target, err)
+	}
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// unescapeFileName reverses escapeFileName
+func unescapeFileName(name string) string {
+	if !strings.Contains(name, "%") {
+		return name
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '%' && i+2 < len(name) {
+			if c, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// ProvenanceLabelPrefix starts the names of labels recording where a sample
+// came from, such as the streamer's source_file and source_row. File storage
+// keeps them; the memory cache does not.
+const ProvenanceLabelPrefix = "source_"
+
 // Telemetry represents a typed telemetry data point
 type Telemetry struct {
 	GPUId     string             `json:"gpu_id"`
 	Hostname  string             `json:"hostname"`
 	Metrics   map[string]float64 `json:"metrics"`
 	Timestamp time.Time          `json:"timestamp"`
+	Labels    map[string]string  `json:"labels,omitempty"`
+	// CorrelationID traces the sample to its source record. File storage
+	// keeps it; the memory cache does not.
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
@@ -0,0 +1,147 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestEscapeFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain_uuid", "GPU-5fd4f087-86f3-7a43-b711-4771313afc50", "GPU-5fd4f087-86f3-7a43-b711-4771313afc50"},
+		{"mig_uuid", "MIG-GPU-abc/1/0", "MIG-GPU-abc%2F1%2F0"},
+		{"windows_reserved", `gpu:0<1>"2"|?*`, "gpu%3A0%3C1%3E%222%22%7C%3F%2A"},
+		{"backslash", `..\gpu`, "..%5Cgpu"},
+		{"percent", "gpu%0", "gpu%250"},
+		{"dot", ".", "%2E"},
+		{"dotdot", "..", "%2E%2E"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			escaped := escapeFileName(tt.input)
+			if escaped != tt.expected {
+				t.Errorf("escapeFileName(%q) = %q, want %q", tt.input, escaped, tt.expected)
+			}
+			if unescaped := unescapeFileName(escaped); unescaped != tt.input {
+				t.Errorf("unescapeFileName(%q) = %q, want %q", escaped, unescaped, tt.input)
+			}
+		})
+	}
+}
+
+func TestFileStorage_PortableGPUFileNames(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewFileStorage(dir)
+
+	ids := []string{"MIG-GPU-abc/1/0", "gpu:0", "GPU-plain"}
+	for _, id := range ids {
+		tel := Telemetry{GPUId: id, Hostname: "host-1", Metrics: map[string]float64{"temp": 1}, Timestamp: time.Now()}
+		if err := storage.WriteTelemetry(tel); err != nil {
+			t.Fatalf("WriteTelemetry(%q) failed: %v", id, err)
+		}
+	}
+
+	// Every file must live directly in the data directory
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read data dir: %v", err)
+	}
+	if len(entries) != len(ids) {
+		t.Fatalf("Expected %d files in data dir, got %d", len(ids), len(entries))
+	}
+
+	listed, err := storage.ListGPUFiles()
+	if err != nil {
+		t.Fatalf("ListGPUFiles failed: %v", err)
+	}
+	sort.Strings(listed)
+	want := append([]string(nil), ids...)
+	sort.Strings(want)
+	for i := range want {
+		if listed[i] != want[i] {
+			t.Errorf("ListGPUFiles()[%d] = %q, want %q", i, listed[i], want[i])
+		}
+	}
+
+	for _, id := range ids {
+		data, err := storage.ReadTelemetryFile(id)
+		if err != nil || len(data) != 1 {
+			t.Errorf("ReadTelemetryFile(%q) = %d entries, err %v", id, len(data), err)
+		}
+	}
+}
+
+func TestFileStore_AtomicSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := NewFileStore(path)
+
+	if err := store.Save(map[string]int{"a": 1}); err != nil {
+		t.Fatalf("First save failed: %v", err)
+	}
+	if err := store.Save(map[string]int{"b": 2}); err != nil {
+		t.Fatalf("Overwriting save failed: %v", err)
+	}
+
+	var loaded map[string]int
+	if err := store.Load(&loaded); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded) != 1 || loaded["b"] != 2 {
+		t.Errorf("Expected only the second save to be visible, got %v", loaded)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Temporary file should not remain after save, stat err: %v", err)
+	}
+}
+
+func TestFileStore_SaveWhileReaderOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := NewFileStore(path)
+
+	if err := store.Save(map[string]int{"a": 1}); err != nil {
+		t.Fatalf("Initial save failed: %v", err)
+	}
+
+	// An open reader must not block replacement; on Windows this exercises
+	// the sharing-violation retry in replaceFile.
+	reader, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open reader: %v", err)
+	}
+	if runtime.GOOS == "windows" {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			_ = reader.Close()
+		}()
+	} else {
+		defer func() { _ = reader.Close() }()
+	}
+
+	if err := store.Save(map[string]int{"a": 2}); err != nil {
+		t.Fatalf("Save with open reader failed: %v", err)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "lock"))
+	if err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := lockFile(f); err != nil {
+		t.Fatalf("lockFile failed: %v", err)
+	}
+	if err := unlockFile(f); err != nil {
+		t.Fatalf("unlockFile failed: %v", err)
+	}
+}
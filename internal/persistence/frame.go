@@ -0,0 +1,159 @@
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Telemetry files hold one record per line, framed as
+//
+//	<payload length> <CRC-32C of payload, 8 hex digits> <JSON payload>\n
+//
+// so torn writes and bit rot are detected instead of being parsed as data.
+// Lines starting with '{' are unframed records written by earlier versions;
+// they are accepted as long as they are valid JSON, and TelemetryFormat's
+// migration to version 2 frames them.
+
+// crcTable is the Castagnoli table used for record checksums
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errIncompleteRecord marks a final record without its terminating newline
+var errIncompleteRecord = errors.New("incomplete record")
+
+// encodeFrame frames a JSON payload as a single telemetry file line
+func encodeFrame(payload []byte) []byte {
+	header := fmt.Sprintf("%d %08x ", len(payload), crc32.Checksum(payload, crcTable))
+	frame := make([]byte, 0, len(header)+len(payload)+1)
+	frame = append(frame, header...)
+	frame = append(frame, payload...)
+	return append(frame, '\n')
+}
+
+// decodeFrame validates a line (without its newline) and returns the payload
+func decodeFrame(line []byte) ([]byte, error) {
+	if len(line) > 0 && line[0] == '{' {
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("invalid legacy JSON record")
+		}
+		return line, nil
+	}
+
+	lengthEnd := bytes.IndexByte(line, ' ')
+	if lengthEnd <= 0 {
+		return nil, fmt.Errorf("missing record length")
+	}
+	length, err := strconv.Atoi(string(line[:lengthEnd]))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid record length %q", line[:lengthEnd])
+	}
+
+	rest := line[lengthEnd+1:]
+	if len(rest) < 9 || rest[8] != ' ' {
+		return nil, fmt.Errorf("missing record checksum")
+	}
+	checksum, err := strconv.ParseUint(string(rest[:8]), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record checksum %q", rest[:8])
+	}
+
+	payload := rest[9:]
+	if len(payload) != length {
+		return nil, fmt.Errorf("record length mismatch: header %d, actual %d", length, len(payload))
+	}
+	if crc32.Checksum(payload, crcTable) != uint32(checksum) {
+		return nil, fmt.Errorf("record checksum mismatch")
+	}
+
+	return payload, nil
+}
+
+// scanFrames calls fn for each valid record in r, in order. It stops at the
+// first corrupt or incomplete record and returns the offset at which valid
+// data ends together with the reason; err is nil if r ended cleanly.
+func scanFrames(r io.Reader, fn func(payload []byte)) (validBytes int64, err error) {
+	reader := bufio.NewReader(r)
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) == 0 && readErr == io.EOF {
+			return validBytes, nil
+		}
+		if readErr != nil && readErr != io.EOF {
+			return validBytes, readErr
+		}
+		if readErr == io.EOF {
+			return validBytes, errIncompleteRecord
+		}
+
+		payload, err := decodeFrame(line[:len(line)-1])
+		if err != nil {
+			return validBytes, err
+		}
+
+		fn(payload)
+		validBytes += int64(len(line))
+	}
+}
+
+// TelemetryFormat is the layout of a collector data directory. Version 1
+// directories may hold unframed records, and version 2 ones files named
+// after GPU IDs with characters that are now escaped.
+var TelemetryFormat = &Format{
+	Name: "telemetry",
+	Migrations: []Migration{
+		{Version: 2, Description: "frame unframed records", Migrate: frameLegacyRecords},
+		{Version: 3, Description: "escape reserved characters in file names", Migrate: escapeLegacyFileNames},
+	},
+	Legacy: hasTelemetryFiles,
+}
+
+// hasTelemetryFiles reports whether dir holds any telemetry files
+func hasTelemetryFiles(dir string) (bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	return len(files) > 0, err
+}
+
+// frameLegacyRecords rewrites the telemetry files in dir that hold unframed
+// records with every record framed. Data after a corrupt record is kept as
+// it is, for recovery to deal with.
+func frameLegacyRecords(dir string, progress func(done, total int)) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return err
+	}
+
+	progress(0, len(files))
+	for i, filePath := range files {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+		if !bytes.HasPrefix(data, []byte("{")) && !bytes.Contains(data, []byte("\n{")) {
+			progress(i+1, len(files))
+			continue
+		}
+
+		var out bytes.Buffer
+		validBytes, _ := scanFrames(bytes.NewReader(data), func(payload []byte) {
+			out.Write(encodeFrame(payload))
+		})
+		out.Write(data[validBytes:])
+
+		if err := WriteFileAtomic(filePath, func(w io.Writer) error {
+			_, err := w.Write(out.Bytes())
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", filePath, err)
+		}
+		progress(i+1, len(files))
+	}
+	return nil
+}
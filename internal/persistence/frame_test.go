@@ -0,0 +1,181 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	payload := []byte(`{"gpu_id":"gpu_0","metrics":{"temp":70}}`)
+	frame := encodeFrame(payload)
+
+	if frame[len(frame)-1] != '\n' {
+		t.Fatal("Frame must end with a newline")
+	}
+
+	decoded, err := decodeFrame(frame[:len(frame)-1])
+	if err != nil {
+		t.Fatalf("decodeFrame failed: %v", err)
+	}
+	if !bytes.Equal(decoded, payload) {
+		t.Errorf("Expected payload %s, got %s", payload, decoded)
+	}
+}
+
+func TestDecodeFrame_Corruption(t *testing.T) {
+	payload := []byte(`{"gpu_id":"gpu_0"}`)
+	frame := encodeFrame(payload)
+	line := frame[:len(frame)-1]
+
+	flipped := append([]byte(nil), line...)
+	flipped[len(flipped)-3] ^= 0x01
+
+	tests := []struct {
+		name string
+		line []byte
+	}{
+		{"flipped_payload_bit", flipped},
+		{"truncated_payload", line[:len(line)-2]},
+		{"missing_header", []byte("garbage")},
+		{"bad_checksum", []byte("2 zzzzzzzz {}")},
+		{"invalid_legacy_json", []byte(`{"gpu_id":`)},
+		{"empty", []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeFrame(tt.line); err == nil {
+				t.Errorf("Expected corruption to be detected in %q", tt.line)
+			}
+		})
+	}
+
+	if _, err := decodeFrame([]byte(`{"gpu_id":"legacy"}`)); err != nil {
+		t.Errorf("Legacy unframed record should be accepted: %v", err)
+	}
+}
+
+func TestScanFrames_StopsAtFirstCorruptRecord(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(encodeFrame([]byte(`{"n":1}`)))
+	buf.WriteString(`{"n":2}` + "\n") // legacy record
+	valid := int64(buf.Len())
+	buf.WriteString("5 00000000 {\"n\"\n")
+	buf.Write(encodeFrame([]byte(`{"n":4}`)))
+
+	var seen []string
+	validBytes, err := scanFrames(&buf, func(p []byte) { seen = append(seen, string(p)) })
+	if err == nil {
+		t.Fatal("Expected scan to report corruption")
+	}
+	if validBytes != valid {
+		t.Errorf("Expected valid bytes %d, got %d", valid, validBytes)
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected 2 records before corruption, got %v", seen)
+	}
+
+	// A final record without newline is a torn write
+	torn := append(encodeFrame([]byte(`{"n":1}`)), []byte("9 0000")...)
+	if _, err := scanFrames(bytes.NewReader(torn), func([]byte) {}); err != errIncompleteRecord {
+		t.Errorf("Expected errIncompleteRecord, got %v", err)
+	}
+}
+
+func TestFileStorage_Recover(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewFileStorage(dir)
+
+	for i := 0; i < 3; i++ {
+		tel := Telemetry{GPUId: "gpu_0", Hostname: "host", Metrics: map[string]float64{"n": float64(i)}, Timestamp: time.Unix(int64(i), 0).UTC()}
+		if err := storage.WriteTelemetry(tel); err != nil {
+			t.Fatalf("WriteTelemetry failed: %v", err)
+		}
+	}
+	if err := storage.WriteTelemetry(Telemetry{GPUId: "gpu_1", Metrics: map[string]float64{"n": 1}}); err != nil {
+		t.Fatalf("WriteTelemetry failed: %v", err)
+	}
+
+	path := storage.gpuFilePath("gpu_0")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	cleanSize := info.Size()
+
+	// Simulate a crash mid-append
+	garbage := []byte("120 deadbeef {\"gpu_id\":\"gpu_0\",\"met")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	_, _ = f.Write(garbage)
+	_ = f.Close()
+
+	// Writes are refused until the file is repaired
+	if err := storage.WriteTelemetry(Telemetry{GPUId: "gpu_0", Metrics: map[string]float64{"n": 9}}); err == nil {
+		t.Error("Expected write to a corrupt file to fail")
+	}
+
+	results, err := storage.Recover()
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 repaired file, got %d: %+v", len(results), results)
+	}
+	if results[0].GPUId != "gpu_0" || results[0].BytesDiscarded != int64(len(garbage)) {
+		t.Errorf("Unexpected recovery result: %+v", results[0])
+	}
+
+	info, _ = os.Stat(path)
+	if info.Size() != cleanSize {
+		t.Errorf("Expected file truncated to %d bytes, got %d", cleanSize, info.Size())
+	}
+
+	records, err := storage.ReadTelemetryFile("gpu_0")
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected 3 records after recovery, got %d (err %v)", len(records), err)
+	}
+	var tel Telemetry
+	if err := json.Unmarshal(records[2], &tel); err != nil || tel.Metrics["n"] != 2 {
+		t.Errorf("Unexpected last record %s", records[2])
+	}
+
+	// Recovering a clean directory is a no-op
+	results, err = storage.Recover()
+	if err != nil || len(results) != 0 {
+		t.Errorf("Expected no repairs on clean files, got %+v (err %v)", results, err)
+	}
+
+	if err := storage.WriteTelemetry(Telemetry{GPUId: "gpu_0", Metrics: map[string]float64{"n": 9}}); err != nil {
+		t.Errorf("Write after recovery failed: %v", err)
+	}
+}
+
+func TestFileStorage_ReadsLegacyJSONL(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"gpu_id":"gpu_0","hostname":"h","metrics":{"n":1},"timestamp":"2024-01-01T00:00:00Z"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "gpu_0.jsonl"), []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+
+	storage := NewFileStorage(dir)
+	results, err := storage.Recover()
+	if err != nil || len(results) != 0 {
+		t.Fatalf("Legacy file should not need recovery: %+v (err %v)", results, err)
+	}
+
+	if err := storage.WriteTelemetry(Telemetry{GPUId: "gpu_0", Hostname: "h", Metrics: map[string]float64{"n": 2}}); err != nil {
+		t.Fatalf("Append to legacy file failed: %v", err)
+	}
+
+	records, err := storage.ReadTelemetryFile("gpu_0")
+	if err != nil || len(records) != 2 {
+		t.Errorf("Expected legacy and framed records, got %d (err %v)", len(records), err)
+	}
+}
@@ -0,0 +1,220 @@
+package persistence
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Compressed series use the encodings of Facebook's Gorilla paper:
+// timestamps are stored as deltas of deltas, so samples at a steady interval
+// take one bit each, and values as the XOR with the previous value, so
+// unchanged values take one bit and slowly changing ones a few bits.
+
+// bitWriter appends bits to a byte slice, most significant first
+type bitWriter struct {
+	buf  []byte
+	free int // unused bits in the last byte
+}
+
+// writeBits writes the low n bits of v
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+		take := min(n, w.free)
+		chunk := (v >> (n - take)) & (1<<take - 1)
+		w.buf[len(w.buf)-1] |= byte(chunk << (w.free - take))
+		w.free -= take
+		n -= take
+	}
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// bytes returns the written bits in a slice of their own
+func (w *bitWriter) bytes() []byte {
+	return append(make([]byte, 0, len(w.buf)), w.buf...)
+}
+
+// bitReader reads bits written by a bitWriter. Reads past the end return
+// zero bits.
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+// readBits reads n bits into the low bits of the result
+func (r *bitReader) readBits(n int) uint64 {
+	var v uint64
+	for n > 0 {
+		i := r.pos / 8
+		if i >= len(r.data) {
+			return v << n
+		}
+		offset := r.pos % 8
+		take := min(n, 8-offset)
+		chunk := uint64(r.data[i]>>(8-offset-take)) & (1<<take - 1)
+		v = v<<take | chunk
+		r.pos += take
+		n -= take
+	}
+	return v
+}
+
+func (r *bitReader) readBit() bool {
+	return r.readBits(1) == 1
+}
+
+// dodBuckets are the widths delta-of-delta values are stored in, each after
+// a prefix of as many 1 bits as its index followed by a 0; the last width
+// follows a prefix of all 1s. Nanosecond timestamps from a steady source vary
+// by microseconds to milliseconds, which the middle widths cover.
+var dodBuckets = [...]int{14, 24, 36, 64}
+
+// encodeTimes compresses Unix nanosecond timestamps. Arithmetic wraps, so any
+// int64, including noTimestamp, round-trips.
+func encodeTimes(times []int64) []byte {
+	var w bitWriter
+	var prev, delta int64
+	for i, t := range times {
+		switch i {
+		case 0:
+			w.writeBits(uint64(t), 64)
+		case 1:
+			delta = t - prev
+			w.writeBits(uint64(delta), 64)
+		default:
+			next := t - prev
+			dod := next - delta
+			delta = next
+			if dod == 0 {
+				w.writeBit(false)
+				break
+			}
+			for b, width := range dodBuckets {
+				if b == len(dodBuckets)-1 || fitsSigned(dod, width) {
+					w.writeBits(1<<(b+1)-1, b+1)
+					if b < len(dodBuckets)-1 {
+						w.writeBit(false)
+					}
+					w.writeBits(uint64(dod), width)
+					break
+				}
+			}
+		}
+		prev = t
+	}
+	return w.bytes()
+}
+
+// decodeTimes decompresses n timestamps written by encodeTimes
+func decodeTimes(data []byte, n int) []int64 {
+	times := make([]int64, n)
+	r := bitReader{data: data}
+	var prev, delta int64
+	for i := range times {
+		switch i {
+		case 0:
+			prev = int64(r.readBits(64))
+		case 1:
+			delta = int64(r.readBits(64))
+			prev += delta
+		default:
+			b := 0
+			for b < len(dodBuckets) && r.readBit() {
+				b++
+			}
+			if b > 0 {
+				width := dodBuckets[b-1]
+				delta += signExtend(r.readBits(width), width)
+			}
+			prev += delta
+		}
+		times[i] = prev
+	}
+	return times
+}
+
+// encodeValues compresses float values
+func encodeValues(values []float64) []byte {
+	var w bitWriter
+	var prev uint64
+	leading, trailing := -1, 0
+	for i, value := range values {
+		v := math.Float64bits(value)
+		if i == 0 {
+			w.writeBits(v, 64)
+			prev = v
+			continue
+		}
+
+		xor := v ^ prev
+		prev = v
+		if xor == 0 {
+			w.writeBit(false)
+			continue
+		}
+		w.writeBit(true)
+
+		lead, trail := min(bits.LeadingZeros64(xor), 31), bits.TrailingZeros64(xor)
+		if leading >= 0 && lead >= leading && trail >= trailing {
+			// The meaningful bits fit in the previous window
+			w.writeBit(false)
+			w.writeBits(xor>>trailing, 64-leading-trailing)
+			continue
+		}
+		leading, trailing = lead, trail
+		significant := 64 - lead - trail
+		w.writeBit(true)
+		w.writeBits(uint64(lead), 5)
+		w.writeBits(uint64(significant&63), 6) // 64 is written as 0
+		w.writeBits(xor>>trail, significant)
+	}
+	return w.bytes()
+}
+
+// decodeValues decompresses n values written by encodeValues
+func decodeValues(data []byte, n int) []float64 {
+	values := make([]float64, n)
+	r := bitReader{data: data}
+	var prev uint64
+	leading, trailing := 0, 0
+	for i := range values {
+		switch {
+		case i == 0:
+			prev = r.readBits(64)
+		case !r.readBit():
+			// Unchanged
+		default:
+			if r.readBit() {
+				leading = int(r.readBits(5))
+				significant := int(r.readBits(6))
+				if significant == 0 {
+					significant = 64
+				}
+				trailing = 64 - leading - significant
+			}
+			prev ^= r.readBits(64-leading-trailing) << trailing
+		}
+		values[i] = math.Float64frombits(prev)
+	}
+	return values
+}
+
+// fitsSigned reports whether v is representable in n bits two's complement
+func fitsSigned(v int64, n int) bool {
+	return n >= 64 || (v >= -1<<(n-1) && v < 1<<(n-1))
+}
+
+// signExtend interprets the low n bits of v as two's complement
+func signExtend(v uint64, n int) int64 {
+	return int64(v<<(64-n)) >> (64 - n)
+}
@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeTimesRoundTrip(t *testing.T) {
+	base := time.Date(2025, 7, 18, 20, 42, 0, 0, time.UTC).UnixNano()
+	cases := map[string][]int64{
+		"empty":  {},
+		"single": {base},
+		"steady": {base, base + 1e9, base + 2e9, base + 3e9, base + 4e9},
+		"jitter": {base, base + 1e9 + 37, base + 2e9 - 1200, base + 3e9 + 850_000, base + 4e9 - 3_000_000},
+		"gaps":   {base, base + 1e9, base + 3600e9, base + 3601e9, base - 86400e9},
+		"zero":   {noTimestamp, base, noTimestamp, noTimestamp, math.MaxInt64, 0},
+	}
+	for name, times := range cases {
+		got := decodeTimes(encodeTimes(times), len(times))
+		if !reflect.DeepEqual(got, times) {
+			t.Errorf("%s: expected %v, got %v", name, times, got)
+		}
+	}
+
+	steady := make([]int64, chunkRows)
+	for i := range steady {
+		steady[i] = base + int64(i)*1e9
+	}
+	// 128 bits for the first timestamp and delta, then a bit per sample
+	if size := len(encodeTimes(steady)); size > 16+chunkRows/8+1 {
+		t.Errorf("Expected steady timestamps to take about a bit each, got %d bytes", size)
+	}
+}
+
+func TestEncodeValuesRoundTrip(t *testing.T) {
+	cases := map[string][]float64{
+		"single":   {42},
+		"constant": {71, 71, 71, 71},
+		"counter":  {1, 2, 3, 4, 5, 100, 1000, 0},
+		"mixed":    {350.5, 349.25, -0.1, 1e300, -1e-300, 0, math.Inf(1), math.Inf(-1), math.MaxFloat64, math.SmallestNonzeroFloat64},
+	}
+	for name, values := range cases {
+		if got := decodeValues(encodeValues(values), len(values)); !reflect.DeepEqual(got, values) {
+			t.Errorf("%s: expected %v, got %v", name, values, got)
+		}
+	}
+
+	nan := decodeValues(encodeValues([]float64{1, math.NaN(), 2}), 3)
+	if nan[0] != 1 || !math.IsNaN(nan[1]) || nan[2] != 2 {
+		t.Errorf("Expected NaN to round-trip, got %v", nan)
+	}
+}
+
+func TestSealBlockRoundTrip(t *testing.T) {
+	b := newBlock(1000)
+	for i := 0; i < 70; i++ {
+		sample := decodeSample(1, i)
+		if i >= 40 {
+			sample.Hostname = "other-host"
+		}
+		if i%7 == 0 {
+			sample.Labels = map[string]string{"sample_rate": "0.1"}
+		}
+		if i%2 == 0 {
+			delete(sample.Metrics, "DCGM_FI_DEV_POWER_USAGE")
+		}
+		b.append(sample)
+	}
+
+	decoded := sealBlock(b).decode()
+	if !reflect.DeepEqual(decoded, b) {
+		t.Errorf("Expected the decoded chunk to match the block\nwant %+v\n got %+v", b, decoded)
+	}
+}
@@ -0,0 +1,31 @@
+package persistence
+
+import (
+	"strings"
+	"unique"
+)
+
+// intern returns the canonical copy of s. Every sample repeats the same GPU
+// IDs, hostnames and metric names, and each decoded message allocates its
+// own copies; keeping one copy of each cuts the memory held by cached
+// samples. Strings no longer used anywhere are reclaimed by the GC.
+func intern(s string) string {
+	return unique.Make(s).Value()
+}
+
+// internLabels returns a copy of labels with interned names and values, or
+// nil if there are none. Provenance labels are left out: they are unique to
+// nearly every sample, so interning and caching them would only cost memory.
+func internLabels(labels map[string]string) map[string]string {
+	var interned map[string]string
+	for name, value := range labels {
+		if strings.HasPrefix(name, ProvenanceLabelPrefix) {
+			continue
+		}
+		if interned == nil {
+			interned = make(map[string]string, len(labels))
+		}
+		interned[intern(name)] = intern(value)
+	}
+	return interned
+}
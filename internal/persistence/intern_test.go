@@ -0,0 +1,118 @@
+package persistence
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// decodeSample returns a sample with its own copies of every string, as a
+// decoder allocating the strings of each message produces
+func decodeSample(gpu, i int) Telemetry {
+	return Telemetry{
+		GPUId:    fmt.Sprintf("GPU-5fd4f087-86f3-7a43-b711-4771313a%04d", gpu),
+		Hostname: fmt.Sprintf("mtv5-dgx1-hgpu-%03d", gpu/8),
+		Metrics: map[string]float64{
+			strings.Clone("DCGM_FI_DEV_GPU_UTIL"):    float64(i),
+			strings.Clone("DCGM_FI_DEV_GPU_TEMP"):    71,
+			strings.Clone("DCGM_FI_DEV_POWER_USAGE"): 350.5 + float64(i%13)/4,
+		},
+		// Samples arrive about a second apart, give or take a millisecond
+		Timestamp: time.Date(2025, 7, 18, 20, 42, i, i*7919%1000*int(time.Microsecond), time.UTC),
+	}
+}
+
+func TestStoreTelemetryInternsStrings(t *testing.T) {
+	ms := NewMemoryStorage(10)
+	first, second := decodeSample(1, 1), decodeSample(1, 2)
+	second.Labels = map[string]string{strings.Clone("sample_rate"): strings.Clone("0.1")}
+	ms.StoreTelemetry(first)
+	ms.StoreTelemetry(second)
+
+	stored := ms.GetTelemetryForGPU(first.GPUId)
+	if len(stored) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(stored))
+	}
+	if unsafe.StringData(stored[0].GPUId) != unsafe.StringData(stored[1].GPUId) ||
+		unsafe.StringData(stored[0].Hostname) != unsafe.StringData(stored[1].Hostname) {
+		t.Error("Expected stored samples to share GPU ID and hostname strings")
+	}
+	for name := range stored[1].Metrics {
+		if unsafe.StringData(name) != unsafe.StringData(intern(name)) {
+			t.Errorf("Expected metric name %s to be interned", name)
+		}
+	}
+	if stored[1].Metrics["DCGM_FI_DEV_GPU_UTIL"] != 2 || stored[1].Labels["sample_rate"] != "0.1" {
+		t.Errorf("Unexpected stored sample %+v", stored[1])
+	}
+
+	// Provenance labels are not cached
+	third := decodeSample(1, 3)
+	third.Labels = map[string]string{"source_file": "a.csv", "source_row": "3", "sample_rate": "0.1"}
+	fourth := decodeSample(1, 4)
+	fourth.Labels = map[string]string{"source_file": "a.csv", "source_row": "4"}
+	ms.StoreTelemetry(third)
+	ms.StoreTelemetry(fourth)
+	stored = ms.GetTelemetryForGPU(first.GPUId)
+	if labels := stored[2].Labels; len(labels) != 1 || labels["sample_rate"] != "0.1" {
+		t.Errorf("Expected only the sample_rate label cached, got %v", labels)
+	}
+	if stored[3].Labels != nil {
+		t.Errorf("Expected no labels cached, got %v", stored[3].Labels)
+	}
+
+	// The caller's maps are left alone
+	second.Metrics["extra"] = 1
+	if _, ok := ms.GetTelemetryForGPU(first.GPUId)[1].Metrics["extra"]; ok {
+		t.Error("Expected stored metrics not to share the caller's map")
+	}
+}
+
+// BenchmarkMemoryStorageHeap reports the heap retained per cached sample,
+// with samples kept as decoded ("decoded") and stored by StoreTelemetry
+// ("stored")
+func BenchmarkMemoryStorageHeap(b *testing.B) {
+	const gpus, perGPU = 64, 1200
+
+	measure := func(b *testing.B, newStore func() (store func(Telemetry), keepAlive func())) {
+		var total uint64
+		for n := 0; n < b.N; n++ {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			store, keepAlive := newStore()
+			for i := 0; i < perGPU; i++ {
+				for gpu := 0; gpu < gpus; gpu++ {
+					store(decodeSample(gpu, i))
+				}
+			}
+
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			total += after.HeapAlloc - before.HeapAlloc
+			keepAlive()
+		}
+		b.ReportMetric(float64(total)/float64(b.N*gpus*perGPU), "heap-B/sample")
+	}
+
+	b.Run("decoded", func(b *testing.B) {
+		measure(b, func() (func(Telemetry), func()) {
+			data := make(map[string][]Telemetry)
+			return func(telemetry Telemetry) {
+					data[telemetry.GPUId] = append(data[telemetry.GPUId], telemetry)
+				}, func() {
+					runtime.KeepAlive(data)
+				}
+		})
+	})
+	b.Run("stored", func(b *testing.B) {
+		measure(b, func() (func(Telemetry), func()) {
+			ms := NewMemoryStorage(perGPU)
+			return ms.StoreTelemetry, func() { runtime.KeepAlive(ms) }
+		})
+	})
+}
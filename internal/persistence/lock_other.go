@@ -0,0 +1,25 @@
+//go:build !unix && !windows
+
+package persistence
+
+import "os"
+
+// lockFile is a no-op on platforms without file locking support (js, wasip1, plan9)
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without file locking support
+func unlockFile(f *os.File) error {
+	return nil
+}
+
+// replaceFile moves src over dst
+func replaceFile(src, dst string) error {
+	return os.Rename(src, dst)
+}
+
+// tryLockFile is a no-op on platforms without file locking support
+func tryLockFile(f *os.File) error {
+	return nil
+}
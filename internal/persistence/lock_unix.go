@@ -0,0 +1,33 @@
+//go:build unix
+
+package persistence
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile blocks until an exclusive advisory lock is held on f
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases a lock acquired with lockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// replaceFile atomically moves src over dst
+func replaceFile(src, dst string) error {
+	return os.Rename(src, dst)
+}
+
+// tryLockFile acquires an exclusive advisory lock on f without blocking,
+// returning errLockHeld if another open file description holds it
+func tryLockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errLockHeld
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package persistence
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile blocks until an exclusive lock is held on the first byte of f.
+// Windows locks are mandatory, but every writer goes through lockFile so the
+// semantics match flock for our purposes.
+func lockFile(f *os.File) error {
+	ol := new(windows.Overlapped)
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, ol)
+}
+
+// unlockFile releases a lock acquired with lockFile
+func unlockFile(f *os.File) error {
+	ol := new(windows.Overlapped)
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, ol)
+}
+
+// replaceFile moves src over dst. os.Rename uses MoveFileEx with
+// MOVEFILE_REPLACE_EXISTING, which fails with ERROR_ACCESS_DENIED or
+// ERROR_SHARING_VIOLATION while another handle (e.g. a reader or antivirus
+// scanner) has dst open, so retry briefly before giving up.
+func replaceFile(src, dst string) error {
+	var err error
+	for attempt := 0; attempt < 10; attempt++ {
+		if err = os.Rename(src, dst); err == nil {
+			return nil
+		}
+		if !errors.Is(err, windows.ERROR_ACCESS_DENIED) && !errors.Is(err, windows.ERROR_SHARING_VIOLATION) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+	}
+	return err
+}
+
+// tryLockFile acquires an exclusive lock on f without blocking, returning
+// errLockHeld if another handle holds it
+func tryLockFile(f *os.File) error {
+	ol := new(windows.Overlapped)
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+	return err
+}
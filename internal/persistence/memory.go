@@ -1,6 +1,7 @@
 package persistence
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -36,39 +37,46 @@ func (ms *MemoryStore) Delete(key string) {
 	delete(ms.data, key)
 }
 
-// MemoryStorage handles telemetry-specific memory persistence
+// MemoryStorage handles telemetry-specific memory persistence. Each GPU's
+// samples are held in columns rather than as Telemetry values, one typed
+// column per metric, which takes a fraction of the memory of a map per sample
+// and lets a metric's series be read without touching the others. Older
+// samples are compressed in chunks (see chunk.go). The Telemetry methods
+// rebuild samples from the columns.
 type MemoryStorage struct {
-	data       map[string][]Telemetry // GPU ID -> telemetry entries
+	data       map[string]*gpuSeries // GPU ID -> cached samples
 	maxEntries int
 	mu         sync.RWMutex
 }
 
+// MetricSeries is one metric's cached values for a GPU, oldest first
+type MetricSeries struct {
+	Timestamps []time.Time
+	Values     []float64
+}
+
 // NewMemoryStorage creates a new memory storage instance
 func NewMemoryStorage(maxEntriesPerGPU int) *MemoryStorage {
 	return &MemoryStorage{
-		data:       make(map[string][]Telemetry),
+		data:       make(map[string]*gpuSeries),
 		maxEntries: maxEntriesPerGPU,
 	}
 }
 
-// StoreTelemetry stores telemetry data in memory with LRU eviction
+// StoreTelemetry stores telemetry data in memory, evicting the oldest samples
+// beyond the per-GPU limit. Its strings are interned, so cached samples share
+// one copy of each GPU ID, hostname, metric name and label.
 func (ms *MemoryStorage) StoreTelemetry(telemetry Telemetry) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	gpuID := telemetry.GPUId
-	entries := ms.data[gpuID]
-
-	// Add new entry
-	entries = append(entries, telemetry)
-
-	// Implement LRU eviction if needed
-	if len(entries) > ms.maxEntries {
-		// Remove oldest entries
-		entries = entries[len(entries)-ms.maxEntries:]
+	series, exists := ms.data[telemetry.GPUId]
+	if !exists {
+		series = newGPUSeries()
+		ms.data[intern(telemetry.GPUId)] = series
 	}
-
-	ms.data[gpuID] = entries
+	series.append(telemetry)
+	series.evict(ms.maxEntries)
 }
 
 // GetTelemetryForGPU returns all telemetry data for a specific GPU
@@ -76,15 +84,26 @@ func (ms *MemoryStorage) GetTelemetryForGPU(gpuID string) []Telemetry {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
-	entries, exists := ms.data[gpuID]
+	series, exists := ms.data[gpuID]
 	if !exists {
 		return []Telemetry{}
 	}
+	return series.samples(gpuID, series.window(ms.maxEntries))
+}
 
-	// Return a copy to avoid concurrent modification
-	result := make([]Telemetry, len(entries))
-	copy(result, entries)
-	return result
+// GetMetricSeries returns one metric's cached values for a GPU, reading only
+// that metric's column. It returns false if the GPU has no cached samples of
+// the metric.
+func (ms *MemoryStorage) GetMetricSeries(gpuID, metric string) (MetricSeries, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	series, exists := ms.data[gpuID]
+	if !exists {
+		return MetricSeries{}, false
+	}
+	result := series.metricSeries(metric, series.window(ms.maxEntries))
+	return result, len(result.Values) > 0
 }
 
 // GetAllGPUIDs returns all GPU IDs that have data
@@ -104,12 +123,12 @@ func (ms *MemoryStorage) GetLatestTelemetryForGPU(gpuID string) (*Telemetry, boo
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
-	entries, exists := ms.data[gpuID]
-	if !exists || len(entries) == 0 {
+	series, exists := ms.data[gpuID]
+	if !exists || series.window(ms.maxEntries) == series.len() {
 		return nil, false
 	}
 
-	latest := entries[len(entries)-1]
+	latest := series.samples(gpuID, series.len()-1)[0]
 	return &latest, true
 }
 
@@ -121,8 +140,8 @@ func (ms *MemoryStorage) GetStats() map[string]interface{} {
 	totalEntries := 0
 	gpuCounts := make(map[string]int)
 
-	for gpuID, entries := range ms.data {
-		count := len(entries)
+	for gpuID, series := range ms.data {
+		count := series.len() - series.window(ms.maxEntries)
 		totalEntries += count
 		gpuCounts[gpuID] = count
 	}
@@ -142,6 +161,38 @@ func (ms *MemoryStorage) ClearGPUData(gpuID string) {
 	delete(ms.data, gpuID)
 }
 
+// MergeGPU moves the cached samples of GPU alias to GPU canonical, merged
+// with canonical's own in timestamp order, and drops alias. The merged
+// samples are limited to the newest maxEntries like any others. It returns
+// the number of samples moved.
+func (ms *MemoryStorage) MergeGPU(alias, canonical string) int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	from, exists := ms.data[alias]
+	if !exists || alias == canonical {
+		return 0
+	}
+	delete(ms.data, alias)
+
+	moved := from.samples(alias, from.window(ms.maxEntries))
+	merged := moved
+	if into, exists := ms.data[canonical]; exists {
+		merged = append(into.samples(canonical, into.window(ms.maxEntries)), moved...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	series := newGPUSeries()
+	for _, telemetry := range merged {
+		series.append(telemetry)
+	}
+	series.evict(ms.maxEntries)
+	ms.data[intern(canonical)] = series
+	return len(moved)
+}
+
 // ClearOldEntries removes entries older than the specified duration
 func (ms *MemoryStorage) ClearOldEntries(olderThan time.Duration) {
 	ms.mu.Lock()
@@ -149,14 +200,14 @@ func (ms *MemoryStorage) ClearOldEntries(olderThan time.Duration) {
 
 	cutoff := time.Now().Add(-olderThan)
 
-	for gpuID, entries := range ms.data {
-		var newEntries []Telemetry
-		for _, entry := range entries {
+	for gpuID, series := range ms.data {
+		kept := newGPUSeries()
+		for _, entry := range series.samples(gpuID, series.window(ms.maxEntries)) {
 			if entry.Timestamp.After(cutoff) {
-				newEntries = append(newEntries, entry)
+				kept.append(entry)
 			}
 		}
-		ms.data[gpuID] = newEntries
+		ms.data[gpuID] = kept
 	}
 }
 
@@ -166,10 +217,10 @@ func (ms *MemoryStorage) GetAllHosts() []string {
 	defer ms.mu.RUnlock()
 
 	hostsMap := make(map[string]bool)
-	for _, entries := range ms.data {
-		for _, entry := range entries {
-			if entry.Hostname != "" {
-				hostsMap[entry.Hostname] = true
+	for _, series := range ms.data {
+		for _, hostname := range series.hostnames(series.window(ms.maxEntries)) {
+			if hostname != "" {
+				hostsMap[hostname] = true
 			}
 		}
 	}
@@ -187,9 +238,9 @@ func (ms *MemoryStorage) GetGPUsForHost(hostname string) []string {
 	defer ms.mu.RUnlock()
 
 	gpusMap := make(map[string]bool)
-	for gpuID, entries := range ms.data {
-		for _, entry := range entries {
-			if entry.Hostname == hostname {
+	for gpuID, series := range ms.data {
+		for _, h := range series.hostnames(series.window(ms.maxEntries)) {
+			if h == hostname {
 				gpusMap[gpuID] = true
 				break // Found this GPU on the host, no need to check more entries
 			}
@@ -0,0 +1,184 @@
+package persistence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryStorage_EvictsOldestSamples(t *testing.T) {
+	// Enough samples to seal and evict several chunks
+	ms := NewMemoryStorage(150)
+	for i := 0; i < 400; i++ {
+		sample := decodeSample(1, i)
+		// Metrics missing from some samples leave gaps in their columns
+		if i%3 == 0 {
+			delete(sample.Metrics, "DCGM_FI_DEV_GPU_TEMP")
+		}
+		ms.StoreTelemetry(sample)
+
+		stored := ms.GetTelemetryForGPU(sample.GPUId)
+		if want := min(i+1, 150); len(stored) != want {
+			t.Fatalf("After %d samples: expected %d cached, got %d", i+1, want, len(stored))
+		}
+		for j, entry := range stored {
+			n := i + 1 - len(stored) + j
+			if entry.Metrics["DCGM_FI_DEV_GPU_UTIL"] != float64(n) || !entry.Timestamp.Equal(decodeSample(1, n).Timestamp) {
+				t.Fatalf("After %d samples: unexpected sample %d: %+v", i+1, j, entry)
+			}
+			if _, ok := entry.Metrics["DCGM_FI_DEV_GPU_TEMP"]; ok == (n%3 == 0) {
+				t.Fatalf("After %d samples: sample %d has the wrong metrics: %v", i+1, j, entry.Metrics)
+			}
+		}
+	}
+
+	latest, ok := ms.GetLatestTelemetryForGPU(decodeSample(1, 0).GPUId)
+	if !ok || latest.Metrics["DCGM_FI_DEV_GPU_UTIL"] != 399 {
+		t.Errorf("Expected the latest sample to be the last stored, got %+v", latest)
+	}
+	if total := ms.GetStats()["total_entries"]; total != 150 {
+		t.Errorf("Expected 150 entries in stats, got %v", total)
+	}
+	if series, _ := ms.GetMetricSeries(latest.GPUId, "DCGM_FI_DEV_GPU_TEMP"); len(series.Values) != 100 {
+		t.Errorf("Expected 100 temperature values, got %d", len(series.Values))
+	}
+}
+
+func TestMemoryStorage_CompatibilityView(t *testing.T) {
+	ms := NewMemoryStorage(10)
+	samples := []Telemetry{
+		{GPUId: "gpu_0", Hostname: "host-a", Metrics: map[string]float64{"a": 1, "b": 2}, Timestamp: time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)},
+		{GPUId: "gpu_0", Hostname: "host-b", Metrics: map[string]float64{}, Timestamp: time.Date(2025, 7, 18, 12, 0, 1, 0, time.UTC)},
+		{GPUId: "gpu_0", Hostname: "host-b", Metrics: map[string]float64{"b": 3}, Labels: map[string]string{"sample_rate": "0.5"}, Timestamp: time.Date(2025, 7, 18, 12, 0, 2, 0, time.UTC)},
+		{GPUId: "gpu_0", Metrics: map[string]float64{"a": 4}},
+	}
+	for _, sample := range samples {
+		ms.StoreTelemetry(sample)
+	}
+
+	if stored := ms.GetTelemetryForGPU("gpu_0"); !reflect.DeepEqual(stored, samples) {
+		t.Errorf("Expected samples back as stored\nwant %+v\n got %+v", samples, stored)
+	}
+	if hosts := ms.GetAllHosts(); len(hosts) != 2 {
+		t.Errorf("Expected 2 hosts, got %v", hosts)
+	}
+	if gpus := ms.GetGPUsForHost("host-b"); len(gpus) != 1 || gpus[0] != "gpu_0" {
+		t.Errorf("Expected gpu_0 on host-b, got %v", gpus)
+	}
+}
+
+func TestMemoryStorage_GetMetricSeries(t *testing.T) {
+	ms := NewMemoryStorage(4)
+	base := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		metrics := map[string]float64{"util": float64(i)}
+		if i%2 == 1 {
+			metrics["temp"] = float64(60 + i)
+		}
+		ms.StoreTelemetry(Telemetry{GPUId: "gpu_0", Metrics: metrics, Timestamp: base.Add(time.Duration(i) * time.Second)})
+	}
+
+	series, ok := ms.GetMetricSeries("gpu_0", "temp")
+	if !ok {
+		t.Fatal("Expected a temp series")
+	}
+	want := MetricSeries{
+		Timestamps: []time.Time{base.Add(3 * time.Second), base.Add(5 * time.Second)},
+		Values:     []float64{63, 65},
+	}
+	if !reflect.DeepEqual(series, want) {
+		t.Errorf("Expected %+v, got %+v", want, series)
+	}
+
+	if series, _ := ms.GetMetricSeries("gpu_0", "util"); !reflect.DeepEqual(series.Values, []float64{2, 3, 4, 5}) {
+		t.Errorf("Expected the last 4 util values, got %v", series.Values)
+	}
+	if _, ok := ms.GetMetricSeries("gpu_0", "missing"); ok {
+		t.Error("Expected no series for an unknown metric")
+	}
+	if _, ok := ms.GetMetricSeries("gpu_1", "util"); ok {
+		t.Error("Expected no series for an unknown GPU")
+	}
+}
+
+func TestMemoryStorage_ClearOldEntries(t *testing.T) {
+	ms := NewMemoryStorage(10)
+	now := time.Now()
+	ms.StoreTelemetry(Telemetry{GPUId: "gpu_0", Metrics: map[string]float64{"old": 1}, Timestamp: now.Add(-time.Hour)})
+	ms.StoreTelemetry(Telemetry{GPUId: "gpu_0", Metrics: map[string]float64{"new": 2}, Timestamp: now})
+
+	ms.ClearOldEntries(time.Minute)
+
+	stored := ms.GetTelemetryForGPU("gpu_0")
+	if len(stored) != 1 || stored[0].Metrics["new"] != 2 {
+		t.Fatalf("Expected only the recent sample, got %+v", stored)
+	}
+	if _, ok := ms.GetMetricSeries("gpu_0", "old"); ok {
+		t.Error("Expected the cleared metric's column dropped")
+	}
+}
+
+func TestMemoryStorage_MergeGPU(t *testing.T) {
+	ms := NewMemoryStorage(3)
+	base := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+	ms.StoreTelemetry(Telemetry{GPUId: "GPU-abc", Hostname: "host-a", Metrics: map[string]float64{"util": 1}, Timestamp: base})
+	ms.StoreTelemetry(Telemetry{GPUId: "GPU-abc", Hostname: "host-a", Metrics: map[string]float64{"util": 3}, Timestamp: base.Add(2 * time.Second)})
+	ms.StoreTelemetry(Telemetry{GPUId: "gpu_0", Hostname: "host-a", Metrics: map[string]float64{"util": 0}, Timestamp: base.Add(-time.Second)})
+	ms.StoreTelemetry(Telemetry{GPUId: "gpu_0", Hostname: "host-a", Metrics: map[string]float64{"temp": 70}, Timestamp: base.Add(time.Second)})
+
+	if moved := ms.MergeGPU("gpu_0", "GPU-abc"); moved != 2 {
+		t.Errorf("Expected 2 samples moved, got %d", moved)
+	}
+	if ids := ms.GetAllGPUIDs(); len(ids) != 1 || ids[0] != "GPU-abc" {
+		t.Errorf("Expected only the canonical GPU cached, got %v", ids)
+	}
+
+	// Merged in timestamp order and limited to the newest 3
+	stored := ms.GetTelemetryForGPU("GPU-abc")
+	if len(stored) != 3 || stored[0].Metrics["util"] != 1 || stored[1].Metrics["temp"] != 70 || stored[2].Metrics["util"] != 3 {
+		t.Fatalf("Unexpected merged samples %+v", stored)
+	}
+	if stored[1].GPUId != "GPU-abc" {
+		t.Errorf("Expected merged samples under the canonical ID, got %s", stored[1].GPUId)
+	}
+
+	if moved := ms.MergeGPU("unknown", "GPU-abc"); moved != 0 {
+		t.Errorf("Expected nothing moved from an unknown GPU, got %d", moved)
+	}
+}
+
+// BenchmarkMemoryStorageAverage compares averaging a metric from its column
+// with averaging it from rebuilt samples
+func BenchmarkMemoryStorageAverage(b *testing.B) {
+	const perGPU = 1000
+	ms := NewMemoryStorage(perGPU)
+	for i := 0; i < perGPU; i++ {
+		ms.StoreTelemetry(decodeSample(1, i))
+	}
+	gpuID := decodeSample(1, 0).GPUId
+
+	b.Run("column", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			series, _ := ms.GetMetricSeries(gpuID, "DCGM_FI_DEV_GPU_UTIL")
+			var sum float64
+			for _, v := range series.Values {
+				sum += v
+			}
+			sink = sum / float64(len(series.Values))
+		}
+	})
+	b.Run("samples", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			var sum float64
+			samples := ms.GetTelemetryForGPU(gpuID)
+			for _, sample := range samples {
+				sum += sample.Metrics["DCGM_FI_DEV_GPU_UTIL"]
+			}
+			sink = sum / float64(len(samples))
+		}
+	})
+}
+
+var sink any
@@ -0,0 +1,158 @@
+package persistence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FormatVersionFile records which layout the data in a directory is stored in
+const FormatVersionFile = ".format"
+
+// ErrNewerFormat is returned for data written by a newer version in a layout
+// this build does not know, which it must neither read nor overwrite
+var ErrNewerFormat = errors.New("written by a newer version")
+
+// migrationProgressInterval is how often a running migration reports progress
+const migrationProgressInterval = 5 * time.Second
+
+// Migration upgrades a directory to Version from the version before it
+type Migration struct {
+	Version     int
+	Description string
+	// Migrate rewrites the data in dir, calling progress with the number of
+	// items done out of total. A crash can interrupt it, in which case it
+	// runs again on the partly migrated directory at the next start.
+	Migrate func(dir string, progress func(done, total int)) error
+}
+
+// MigrationProgress reports how far a migration has got
+type MigrationProgress struct {
+	Format      string
+	Version     int
+	Description string
+	Done        int
+	Total       int
+}
+
+// Format is a versioned on-disk layout and the migrations from each earlier
+// version. Version 1 is the layout from before directories recorded their
+// version, and each migration produces the next version.
+type Format struct {
+	Name       string
+	Migrations []Migration
+	// Legacy reports whether a directory without a version file holds version
+	// 1 data; other directories are new and start at the current version
+	Legacy func(dir string) (bool, error)
+}
+
+// formatVersion is the content of a directory's FormatVersionFile
+type formatVersion struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Version is the version of the layout this build writes
+func (f *Format) Version() int {
+	return len(f.Migrations) + 1
+}
+
+// Migrate brings dir up to the current version, one migration at a time,
+// recording the version reached after each so an interrupted run resumes
+// where it stopped. It returns the version dir was at, and fails with
+// ErrNewerFormat if that is newer than this build knows. progress, if not
+// nil, is called as each migration starts and finishes and at most every
+// few seconds in between.
+func (f *Format) Migrate(dir string, progress func(MigrationProgress)) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	from, err := f.readVersion(dir)
+	if err != nil {
+		return 0, err
+	}
+	if from > f.Version() {
+		return from, fmt.Errorf("%s data in %s was %w (format version %d, this build supports up to %d)", f.Name, dir, ErrNewerFormat, from, f.Version())
+	}
+
+	for version := from; version < f.Version(); version++ {
+		migration := f.Migrations[version-1]
+		var last time.Time
+		report := func(done, total int) {
+			if progress == nil {
+				return
+			}
+			if done != 0 && done < total && time.Since(last) < migrationProgressInterval {
+				return
+			}
+			last = time.Now()
+			progress(MigrationProgress{
+				Format:      f.Name,
+				Version:     migration.Version,
+				Description: migration.Description,
+				Done:        done,
+				Total:       total,
+			})
+		}
+
+		if err := migration.Migrate(dir, report); err != nil {
+			return from, fmt.Errorf("failed to migrate %s data in %s to format version %d (%s): %w", f.Name, dir, migration.Version, migration.Description, err)
+		}
+		if err := f.writeVersion(dir, migration.Version); err != nil {
+			return from, err
+		}
+	}
+
+	if from == f.Version() {
+		if _, err := os.Stat(filepath.Join(dir, FormatVersionFile)); os.IsNotExist(err) {
+			return from, f.writeVersion(dir, from)
+		}
+	}
+	return from, nil
+}
+
+// readVersion returns the version of the data in dir
+func (f *Format) readVersion(dir string) (int, error) {
+	path := filepath.Join(dir, FormatVersionFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		legacy, err := f.Legacy(dir)
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect %s: %w", dir, err)
+		}
+		if legacy {
+			return 1, nil
+		}
+		return f.Version(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var recorded formatVersion
+	if err := json.Unmarshal(data, &recorded); err != nil || recorded.Version < 1 {
+		return 0, fmt.Errorf("invalid format version file %s", path)
+	}
+	if recorded.Format != f.Name {
+		return 0, fmt.Errorf("%s holds %s data, not %s data", dir, recorded.Format, f.Name)
+	}
+	return recorded.Version, nil
+}
+
+// writeVersion records that the data in dir is at version
+func (f *Format) writeVersion(dir string, version int) error {
+	path := filepath.Join(dir, FormatVersionFile)
+	err := WriteFileAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(formatVersion{Format: f.Name, Version: version, UpdatedAt: time.Now().UTC()})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
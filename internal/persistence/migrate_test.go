@@ -0,0 +1,174 @@
+package persistence
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelemetryFormat_MigratesUnframedRecords(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"gpu_id":"gpu_0","timestamp":"2025-07-18T20:42:34Z"}` + "\n" +
+		string(encodeFrame([]byte(`{"gpu_id":"gpu_0","timestamp":"2025-07-18T20:42:35Z"}`))) +
+		`{"gpu_id":"gpu_0","timestamp":"2025-07-18T20:42:36Z"}` + "\n" +
+		`{"gpu_id":"gpu_0","tim`
+	path := filepath.Join(dir, "gpu_0.jsonl")
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []MigrationProgress
+	from, err := TelemetryFormat.Migrate(dir, func(p MigrationProgress) {
+		reports = append(reports, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != 1 {
+		t.Errorf("Expected a directory without a version file to be version 1, got %d", from)
+	}
+	if len(reports) < 2 || reports[0].Done != 0 || reports[1].Done != 1 || reports[1].Total != 1 || reports[1].Version != 2 {
+		t.Errorf("Unexpected progress reports %+v", reports)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(lines) != 4 || strings.HasPrefix(lines[0], "{") || strings.HasPrefix(lines[2], "{") {
+		t.Fatalf("Expected every complete record framed, got %q", data)
+	}
+	if lines[3] != `{"gpu_id":"gpu_0","tim` {
+		t.Errorf("Expected the torn record left for recovery, got %q", lines[3])
+	}
+
+	// Migrated directories are not migrated again
+	reports = nil
+	if from, err := TelemetryFormat.Migrate(dir, func(p MigrationProgress) { reports = append(reports, p) }); err != nil || from != TelemetryFormat.Version() || len(reports) != 0 {
+		t.Errorf("Expected nothing to migrate, got version %d, %d reports, %v", from, len(reports), err)
+	}
+}
+
+func TestTelemetryFormat_EscapesLegacyFileNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Legacy file names hold characters Windows rejects")
+	}
+	dir := t.TempDir()
+	storage := NewFileStorage(dir)
+	for _, id := range []string{"gpu:0", "gpu:1", "gpu:1", "gpu|2", "GPU-plain"} {
+		tel := Telemetry{GPUId: id, Hostname: "host-1", Metrics: map[string]float64{"temp": 1}, Timestamp: time.Now()}
+		if err := storage.WriteTelemetry(tel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// gpu:0 and gpu|2 only have files under their unescaped names, as written
+	// before IDs were escaped; gpu:1 has records under both names
+	for _, rename := range [][2]string{{"gpu%3A0", "gpu:0"}, {"gpu%7C2", "gpu|2"}} {
+		if err := os.Rename(filepath.Join(dir, rename[0]+".jsonl"), filepath.Join(dir, rename[1]+".jsonl")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "gpu%3A1.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := data[:bytes.IndexByte(data, '\n')+1]
+	if err := os.WriteFile(filepath.Join(dir, "gpu:1.jsonl"), first, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "gpu%3A1.jsonl"), data[len(first):], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FormatVersionFile), []byte(`{"format":"telemetry","version":2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if from, err := TelemetryFormat.Migrate(dir, nil); err != nil || from != 2 {
+		t.Fatalf("Expected to migrate from version 2, got %d, %v", from, err)
+	}
+
+	ids, err := storage.ListGPUFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	if want := []string{"GPU-plain", "gpu:0", "gpu:1", "gpu|2"}; strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected GPU files %v, got %v", want, ids)
+	}
+	for id, want := range map[string]int{"gpu:0": 1, "gpu:1": 2, "gpu|2": 1, "GPU-plain": 1} {
+		if records, err := storage.ReadTelemetryFile(id); err != nil || len(records) != want {
+			t.Errorf("%s: expected %d records, got %d, %v", id, want, len(records), err)
+		}
+	}
+}
+
+func TestFormat_NewDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	if from, err := TelemetryFormat.Migrate(dir, nil); err != nil || from != TelemetryFormat.Version() {
+		t.Fatalf("Expected a new directory at the current version, got %d, %v", from, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, FormatVersionFile)); err != nil {
+		t.Errorf("Expected the version recorded: %v", err)
+	}
+}
+
+func TestFormat_RefusesUnknownVersions(t *testing.T) {
+	dir := t.TempDir()
+	newer := `{"format":"telemetry","version":99}`
+	if err := os.WriteFile(filepath.Join(dir, FormatVersionFile), []byte(newer), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TelemetryFormat.Migrate(dir, nil); !errors.Is(err, ErrNewerFormat) {
+		t.Errorf("Expected ErrNewerFormat, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, FormatVersionFile)); string(data) != newer {
+		t.Errorf("Expected the version file untouched, got %s", data)
+	}
+
+	other := &Format{Name: "mq", Legacy: func(string) (bool, error) { return false, nil }}
+	if err := os.WriteFile(filepath.Join(dir, FormatVersionFile), []byte(`{"format":"telemetry","version":1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Migrate(dir, nil); err == nil {
+		t.Error("Expected a directory of another format to be refused")
+	}
+}
+
+func TestFormat_ResumesInterruptedMigration(t *testing.T) {
+	dir := t.TempDir()
+	runs := map[int]int{}
+	fail := true
+	format := &Format{
+		Name:   "test",
+		Legacy: func(string) (bool, error) { return true, nil },
+		Migrations: []Migration{
+			{Version: 2, Description: "first", Migrate: func(string, func(int, int)) error { runs[2]++; return nil }},
+			{Version: 3, Description: "second", Migrate: func(string, func(int, int)) error {
+				runs[3]++
+				if fail {
+					return errors.New("disk full")
+				}
+				return nil
+			}},
+		},
+	}
+
+	if _, err := format.Migrate(dir, nil); err == nil {
+		t.Fatal("Expected the failed migration to be reported")
+	}
+	fail = false
+	if from, err := format.Migrate(dir, nil); err != nil || from != 2 {
+		t.Fatalf("Expected to resume from version 2, got %d, %v", from, err)
+	}
+	if runs[2] != 1 || runs[3] != 2 {
+		t.Errorf("Expected only the failed migration rerun, got %v", runs)
+	}
+}
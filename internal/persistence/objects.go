@@ -0,0 +1,91 @@
+package persistence
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirObjectStore is an ObjectStore keeping each object in a file under a
+// directory, which may be a mounted bucket. Keys are slash-separated paths
+// relative to the directory.
+type DirObjectStore struct {
+	dir string
+}
+
+// NewDirObjectStore creates an object store in dir
+func NewDirObjectStore(dir string) *DirObjectStore {
+	return &DirObjectStore{dir: dir}
+}
+
+// Put writes an object atomically, replacing any object with the same key
+func (s *DirObjectStore) Put(key string, data []byte) error {
+	objectPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+	return WriteFileAtomic(objectPath, func(w io.Writer) error {
+		_, err := io.Copy(w, bytes.NewReader(data))
+		return err
+	})
+}
+
+// Get reads an object
+func (s *DirObjectStore) Get(key string) ([]byte, error) {
+	objectPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(objectPath)
+}
+
+// List returns the keys starting with prefix, sorted
+func (s *DirObjectStore) List(prefix string) ([]string, error) {
+	// Only walk the deepest directory the prefix names
+	root := s.dir
+	if dir := path.Dir(prefix + "x"); dir != "." {
+		root = filepath.Join(s.dir, filepath.FromSlash(dir))
+	}
+
+	var keys []string
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// path maps a key to a file, refusing keys that would leave the directory
+func (s *DirObjectStore) path(key string) (string, error) {
+	clean := path.Clean(key)
+	if key == "" || clean != key || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
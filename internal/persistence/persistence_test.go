@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -121,6 +122,11 @@ func TestFileStore_ErrorCases(t *testing.T) {
 	})
 
 	t.Run("permission_denied", func(t *testing.T) {
+		// Directory mode bits are not enforced on Windows or for root
+		if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+			t.Skip("POSIX directory permissions are not enforced in this environment")
+		}
+
 		// Create a read-only directory
 		testDir := "readonly_test_dir"
 		if err := os.Mkdir(testDir, 0444); err != nil {
@@ -525,6 +531,11 @@ func TestFileStore_FilePermissions(t *testing.T) {
 		t.Fatalf("Failed to stat file: %v", err)
 	}
 
+	// Windows only reports the read-only attribute
+	if runtime.GOOS == "windows" {
+		return
+	}
+
 	mode := fileInfo.Mode()
 	if mode&0600 != 0600 { // Owner should have read/write
 		t.Errorf("File permissions too restrictive: %v", mode)
@@ -0,0 +1,221 @@
+// Package persistencetest provides in-memory implementations of the
+// persistence interfaces for tests.
+package persistencetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// TelemetryStore is an in-memory persistence.TelemetryStore. Like
+// FileStorage, it skips samples identical to one already stored.
+type TelemetryStore struct {
+	mu       sync.Mutex
+	samples  map[string][]persistence.Telemetry
+	writeErr error
+}
+
+// NewTelemetryStore creates an empty store
+func NewTelemetryStore() *TelemetryStore {
+	return &TelemetryStore{samples: make(map[string][]persistence.Telemetry)}
+}
+
+// FailWrites makes every following write return err, or succeed again if err
+// is nil
+func (s *TelemetryStore) FailWrites(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeErr = err
+}
+
+// WriteTelemetry stores a sample
+func (s *TelemetryStore) WriteTelemetry(telemetry interface{}) error {
+	data, err := json.Marshal(telemetry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry data: %w", err)
+	}
+	var sample persistence.Telemetry
+	if err := json.Unmarshal(data, &sample); err != nil {
+		return fmt.Errorf("failed to unmarshal telemetry data: %w", err)
+	}
+	if sample.GPUId == "" {
+		return fmt.Errorf("cannot determine GPU ID from telemetry data")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	for _, existing := range s.samples[sample.GPUId] {
+		if reflect.DeepEqual(existing, sample) {
+			return nil
+		}
+	}
+	s.samples[sample.GPUId] = append(s.samples[sample.GPUId], sample)
+	return nil
+}
+
+// Samples returns the samples stored for a GPU, oldest first
+func (s *TelemetryStore) Samples(gpuID string) []persistence.Telemetry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]persistence.Telemetry(nil), s.samples[gpuID]...)
+}
+
+// ReadTelemetryFile returns the samples stored for a GPU as JSON
+func (s *TelemetryStore) ReadTelemetryFile(gpuID string) ([]json.RawMessage, error) {
+	records := []json.RawMessage{}
+	for _, sample := range s.Samples(gpuID) {
+		data, err := json.Marshal(sample)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, data)
+	}
+	return records, nil
+}
+
+// ListGPUFiles returns the GPUs with stored samples, sorted
+func (s *TelemetryStore) ListGPUFiles() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gpuIDs := make([]string, 0, len(s.samples))
+	for gpuID := range s.samples {
+		gpuIDs = append(gpuIDs, gpuID)
+	}
+	sort.Strings(gpuIDs)
+	return gpuIDs, nil
+}
+
+// GetAllHosts returns the hostnames of stored samples, sorted
+func (s *TelemetryStore) GetAllHosts() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]bool)
+	hosts := []string{}
+	for _, samples := range s.samples {
+		for _, sample := range samples {
+			if sample.Hostname != "" && !seen[sample.Hostname] {
+				seen[sample.Hostname] = true
+				hosts = append(hosts, sample.Hostname)
+			}
+		}
+	}
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+// GetGPUsForHost returns the GPUs with samples from a host, sorted
+func (s *TelemetryStore) GetGPUsForHost(hostname string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gpus := []string{}
+	for gpuID, samples := range s.samples {
+		for _, sample := range samples {
+			if sample.Hostname == hostname {
+				gpus = append(gpus, gpuID)
+				break
+			}
+		}
+	}
+	sort.Strings(gpus)
+	return gpus, nil
+}
+
+// Recover has nothing to repair
+func (s *TelemetryStore) Recover() ([]persistence.RecoveryResult, error) {
+	return nil, nil
+}
+
+// CheckpointStore is an in-memory persistence.CheckpointStore
+type CheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]*persistence.Checkpoint
+}
+
+// NewCheckpointStore creates an empty store
+func NewCheckpointStore() *CheckpointStore {
+	return &CheckpointStore{checkpoints: make(map[string]*persistence.Checkpoint)}
+}
+
+// SaveCheckpoint stores a checkpoint
+func (s *CheckpointStore) SaveCheckpoint(name string, checkpoint *persistence.Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[name] = checkpoint
+	return nil
+}
+
+// LoadCheckpoint returns a checkpoint
+func (s *CheckpointStore) LoadCheckpoint(name string) (*persistence.Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoint, ok := s.checkpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("checkpoint %s not found", name)
+	}
+	return checkpoint, nil
+}
+
+// GetAllCheckpoints returns a copy of every checkpoint by name
+func (s *CheckpointStore) GetAllCheckpoints() (map[string]*persistence.Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make(map[string]*persistence.Checkpoint, len(s.checkpoints))
+	for name, checkpoint := range s.checkpoints {
+		all[name] = checkpoint
+	}
+	return all, nil
+}
+
+// DeleteCheckpoint removes a checkpoint
+func (s *CheckpointStore) DeleteCheckpoint(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.checkpoints, name)
+	return nil
+}
+
+// UpdateProcessedCount adds to a checkpoint's processed count, creating it
+// if needed
+func (s *CheckpointStore) UpdateProcessedCount(name string, increment int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoint, ok := s.checkpoints[name]
+	if !ok {
+		checkpoint = &persistence.Checkpoint{Metadata: make(map[string]string)}
+		s.checkpoints[name] = checkpoint
+	}
+	checkpoint.ProcessedCount += increment
+	checkpoint.LastProcessedTime = time.Now()
+	return nil
+}
+
+// CompactCheckpoints removes the checkpoints keep rejects
+func (s *CheckpointStore) CompactCheckpoints(keep func(name string, checkpoint *persistence.Checkpoint) bool) (*persistence.CompactResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &persistence.CompactResult{Removed: make(map[string]*persistence.Checkpoint)}
+	if keep == nil {
+		return result, nil
+	}
+	for name, checkpoint := range s.checkpoints {
+		if !keep(name, checkpoint) {
+			result.Removed[name] = checkpoint
+			delete(s.checkpoints, name)
+		}
+	}
+	return result, nil
+}
+
+var (
+	_ persistence.TelemetryStore  = (*TelemetryStore)(nil)
+	_ persistence.CheckpointStore = (*CheckpointStore)(nil)
+)
@@ -0,0 +1,73 @@
+package persistencetest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+func TestTelemetryStore(t *testing.T) {
+	store := NewTelemetryStore()
+	sample := persistence.Telemetry{GPUId: "gpu_0", Hostname: "host-1", Timestamp: time.Unix(1752870154, 0).UTC(), Metrics: map[string]float64{"util": 1}}
+	for i := 0; i < 2; i++ {
+		if err := store.WriteTelemetry(sample); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.WriteTelemetry(map[string]interface{}{"gpu_id": "gpu_1", "hostname": "host-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.WriteTelemetry(map[string]interface{}{"hostname": "host-2"}); err == nil {
+		t.Error("Expected a sample without a GPU ID rejected")
+	}
+
+	records, _ := store.ReadTelemetryFile("gpu_0")
+	var read persistence.Telemetry
+	if len(records) != 1 || json.Unmarshal(records[0], &read) != nil || !read.Timestamp.Equal(sample.Timestamp) {
+		t.Errorf("Expected one deduplicated record, got %s", records)
+	}
+	if gpus, _ := store.ListGPUFiles(); len(gpus) != 2 || gpus[0] != "gpu_0" {
+		t.Errorf("Expected gpu_0 and gpu_1, got %v", gpus)
+	}
+	if gpus, _ := store.GetGPUsForHost("host-2"); len(gpus) != 1 || gpus[0] != "gpu_1" {
+		t.Errorf("Expected gpu_1 on host-2, got %v", gpus)
+	}
+	if hosts, _ := store.GetAllHosts(); len(hosts) != 2 {
+		t.Errorf("Expected 2 hosts, got %v", hosts)
+	}
+	if records, err := store.ReadTelemetryFile("missing"); err != nil || len(records) != 0 {
+		t.Errorf("Expected no records for an unknown GPU, got %v, %v", records, err)
+	}
+}
+
+func TestCheckpointStore(t *testing.T) {
+	store := NewCheckpointStore()
+	if _, err := store.LoadCheckpoint("writer-0"); err == nil {
+		t.Error("Expected a missing checkpoint to fail to load")
+	}
+	if err := store.UpdateProcessedCount("writer-0", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateProcessedCount("writer-0", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveCheckpoint("writer-1", &persistence.Checkpoint{ProcessedCount: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint, _ := store.LoadCheckpoint("writer-0"); checkpoint.ProcessedCount != 200 {
+		t.Errorf("Expected 200 processed, got %d", checkpoint.ProcessedCount)
+	}
+
+	result, _ := store.CompactCheckpoints(func(name string, _ *persistence.Checkpoint) bool { return name == "writer-0" })
+	if len(result.Removed) != 1 || result.Removed["writer-1"] == nil {
+		t.Errorf("Expected writer-1 removed, got %v", result.Removed)
+	}
+	if err := store.DeleteCheckpoint("writer-0"); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := store.GetAllCheckpoints(); len(all) != 0 {
+		t.Errorf("Expected no checkpoints left, got %v", all)
+	}
+}
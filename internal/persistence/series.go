@@ -0,0 +1,243 @@
+package persistence
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// noTimestamp stands for the zero time.Time, which has no UnixNano
+const noTimestamp = math.MinInt64
+
+// chunkRows is the number of samples compressed together into a chunk
+const chunkRows = 120
+
+// gpuSeries holds one GPU's cached samples. Samples are numbered in the order
+// they were stored; the newest are held uncompressed in the head and, once
+// the head fills, compressed into a chunk.
+type gpuSeries struct {
+	chunks []*chunk // oldest first
+	sealed int      // samples held in chunks
+	head   *block
+}
+
+// block holds samples in columns. The sample at row i has sequence number
+// first+i; each metric column records the sequence numbers of the samples
+// carrying the metric, in order.
+type block struct {
+	first     uint64
+	times     []int64  // Unix nanoseconds per row
+	hostnames []string // per row, interned
+	labels    []map[string]string
+	metrics   map[string]*metricColumn
+}
+
+// metricColumn holds one metric's values
+type metricColumn struct {
+	seqs   []uint64
+	values []float64
+}
+
+func newGPUSeries() *gpuSeries {
+	return &gpuSeries{head: newBlock(0)}
+}
+
+func newBlock(first uint64) *block {
+	return &block{first: first, metrics: make(map[string]*metricColumn)}
+}
+
+// from returns the index of the first value at or after sequence number seq
+func (c *metricColumn) from(seq uint64) int {
+	return sort.Search(len(c.seqs), func(i int) bool { return c.seqs[i] >= seq })
+}
+
+// len returns the number of samples held
+func (s *gpuSeries) len() int {
+	return s.sealed + len(s.head.times)
+}
+
+// first returns the sequence number of the oldest sample held
+func (s *gpuSeries) first() uint64 {
+	if len(s.chunks) > 0 {
+		return s.chunks[0].first
+	}
+	return s.head.first
+}
+
+// append adds a sample as the newest
+func (s *gpuSeries) append(telemetry Telemetry) {
+	s.head.append(telemetry)
+	if len(s.head.times) == chunkRows {
+		s.chunks = append(s.chunks, sealBlock(s.head))
+		s.sealed += chunkRows
+		s.head = newBlock(s.head.first + chunkRows)
+	}
+}
+
+// window returns the index of the oldest of the newest maxEntries samples
+func (s *gpuSeries) window(maxEntries int) int {
+	return max(0, s.len()-max(0, maxEntries))
+}
+
+// evict drops chunks holding only samples older than the newest maxEntries.
+// Up to a chunk's worth of older samples stays behind; readers only see the
+// newest maxEntries.
+func (s *gpuSeries) evict(maxEntries int) {
+	visible := s.first() + uint64(s.window(maxEntries))
+	drop := 0
+	for drop < len(s.chunks) && s.chunks[drop].first+uint64(s.chunks[drop].count) <= visible {
+		s.sealed -= s.chunks[drop].count
+		drop++
+	}
+	if drop > 0 {
+		s.chunks = shift(s.chunks, drop)
+	}
+}
+
+// samples rebuilds the samples from index start on as Telemetry values
+func (s *gpuSeries) samples(gpuID string, start int) []Telemetry {
+	result := make([]Telemetry, s.len()-start)
+	startSeq := s.first() + uint64(start)
+	for _, c := range s.chunks {
+		if c.first+uint64(c.count) > startSeq {
+			c.decode().fill(gpuID, startSeq, result)
+		}
+	}
+	s.head.fill(gpuID, startSeq, result)
+	return result
+}
+
+// metricSeries returns one metric's values from index start on
+func (s *gpuSeries) metricSeries(metric string, start int) MetricSeries {
+	var result MetricSeries
+	startSeq := s.first() + uint64(start)
+	for _, c := range s.chunks {
+		if c.first+uint64(c.count) <= startSeq {
+			continue
+		}
+		if column, ok := c.metrics[metric]; ok {
+			result.add(decodeTimes(c.times, c.count), c.first, c.column(column), startSeq)
+		}
+	}
+	if column, ok := s.head.metrics[metric]; ok {
+		result.add(s.head.times, s.head.first, column, startSeq)
+	}
+	return result
+}
+
+// hostnames returns the hostnames of the samples from index start on, with
+// consecutive repeats collapsed
+func (s *gpuSeries) hostnames(start int) []string {
+	var hosts []string
+	add := func(hostname string) {
+		if len(hosts) == 0 || hosts[len(hosts)-1] != hostname {
+			hosts = append(hosts, hostname)
+		}
+	}
+
+	startSeq := s.first() + uint64(start)
+	for _, c := range s.chunks {
+		for i, run := range c.hosts {
+			end := c.count
+			if i+1 < len(c.hosts) {
+				end = c.hosts[i+1].row
+			}
+			if c.first+uint64(end) > startSeq {
+				add(run.hostname)
+			}
+		}
+	}
+	for i, hostname := range s.head.hostnames {
+		if s.head.first+uint64(i) >= startSeq {
+			add(hostname)
+		}
+	}
+	return hosts
+}
+
+// add appends a column's values from sequence number startSeq on, where
+// times are the timestamps of the column's block
+func (m *MetricSeries) add(times []int64, first uint64, column *metricColumn, startSeq uint64) {
+	for j := column.from(startSeq); j < len(column.seqs); j++ {
+		m.Timestamps = append(m.Timestamps, fromNanos(times[column.seqs[j]-first]))
+		m.Values = append(m.Values, column.values[j])
+	}
+}
+
+// append adds a sample as the newest row
+func (b *block) append(telemetry Telemetry) {
+	seq := b.first + uint64(len(b.times))
+
+	b.times = append(b.times, toNanos(telemetry.Timestamp))
+	b.hostnames = append(b.hostnames, intern(telemetry.Hostname))
+
+	// Most samples have no labels, so the column is only created once one
+	// does
+	labels := internLabels(telemetry.Labels)
+	if labels != nil && b.labels == nil {
+		b.labels = make([]map[string]string, len(b.times)-1, cap(b.times))
+	}
+	if b.labels != nil {
+		b.labels = append(b.labels, labels)
+	}
+
+	for name, value := range telemetry.Metrics {
+		column, exists := b.metrics[name]
+		if !exists {
+			column = &metricColumn{}
+			b.metrics[intern(name)] = column
+		}
+		column.seqs = append(column.seqs, seq)
+		column.values = append(column.values, value)
+	}
+}
+
+// fill rebuilds the block's samples from sequence number startSeq on into
+// result, which starts at startSeq
+func (b *block) fill(gpuID string, startSeq uint64, result []Telemetry) {
+	for i := range b.times {
+		seq := b.first + uint64(i)
+		if seq < startSeq {
+			continue
+		}
+		telemetry := &result[seq-startSeq]
+		*telemetry = Telemetry{
+			GPUId:     gpuID,
+			Hostname:  b.hostnames[i],
+			Metrics:   make(map[string]float64),
+			Timestamp: fromNanos(b.times[i]),
+		}
+		if b.labels != nil {
+			telemetry.Labels = b.labels[i]
+		}
+	}
+
+	for name, column := range b.metrics {
+		for j := column.from(startSeq); j < len(column.seqs); j++ {
+			result[column.seqs[j]-startSeq].Metrics[name] = column.values[j]
+		}
+	}
+}
+
+// shift removes the first n elements in place, clearing the vacated tail so
+// it holds no references
+func shift[T any](s []T, n int) []T {
+	m := copy(s, s[n:])
+	clear(s[m:])
+	return s[:m]
+}
+
+func toNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return noTimestamp
+	}
+	return t.UnixNano()
+}
+
+// fromNanos returns a stored timestamp, in UTC
+func fromNanos(n int64) time.Time {
+	if n == noTimestamp {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
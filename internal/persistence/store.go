@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TelemetryWriter appends telemetry samples to durable storage
+type TelemetryWriter interface {
+	// WriteTelemetry stores a Telemetry value, pointer or map with a gpu_id.
+	// Writing a sample identical to one already stored is a no-op.
+	WriteTelemetry(telemetry interface{}) error
+}
+
+// TelemetryReader reads telemetry back from durable storage
+type TelemetryReader interface {
+	// ReadTelemetryFile returns a GPU's records as JSON, oldest first, and
+	// no records for an unknown GPU
+	ReadTelemetryFile(gpuID string) ([]json.RawMessage, error)
+	// ListGPUFiles returns the IDs of GPUs with stored telemetry
+	ListGPUFiles() ([]string, error)
+	GetAllHosts() ([]string, error)
+	GetGPUsForHost(hostname string) ([]string, error)
+}
+
+// TelemetryStore is durable telemetry storage. FileStorage implements it
+// with one file per GPU.
+type TelemetryStore interface {
+	TelemetryWriter
+	TelemetryReader
+	// Recover repairs storage left inconsistent by a crash, reporting what
+	// was discarded
+	Recover() ([]RecoveryResult, error)
+}
+
+// TelemetrySyncer flushes written telemetry to stable storage. FileStorage
+// implements it; stores without it are durable once written.
+type TelemetrySyncer interface {
+	// SyncTelemetry flushes the given GPUs' telemetry to stable storage
+	SyncTelemetry(gpuIDs []string) error
+}
+
+// ColdTelemetryStore moves aged telemetry out of durable storage into an
+// ObjectStore and reads it back. FileStorage implements it once given one
+// with SetColdStore.
+type ColdTelemetryStore interface {
+	// ArchiveTelemetry moves a GPU's records from before cutoff to cold
+	// storage, returning how many moved
+	ArchiveTelemetry(gpuID string, cutoff time.Time) (int, error)
+	// ReadColdTelemetry returns a GPU's cold records as JSON, oldest first,
+	// skipping whole segments that end before since
+	ReadColdTelemetry(gpuID string, since time.Time) ([]json.RawMessage, error)
+}
+
+// ObjectStore keeps immutable objects by slash-separated key, such as cold
+// telemetry segments. DirObjectStore implements it on a directory.
+type ObjectStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	// List returns the keys starting with prefix, sorted
+	List(prefix string) ([]string, error)
+}
+
+// TelemetryCache holds the most recent samples of each GPU for fast reads.
+// MemoryStorage implements it.
+type TelemetryCache interface {
+	StoreTelemetry(telemetry Telemetry)
+	GetTelemetryForGPU(gpuID string) []Telemetry
+	GetAllGPUIDs() []string
+	GetAllHosts() []string
+	GetGPUsForHost(hostname string) []string
+	GetStats() map[string]interface{}
+	// MergeGPU moves a GPU's samples to another GPU ID
+	MergeGPU(alias, canonical string) int
+	// ClearOldEntries evicts samples older than the given age
+	ClearOldEntries(olderThan time.Duration)
+}
+
+// CheckpointStore keeps named processing checkpoints. CheckpointManager
+// implements it with a single versioned file.
+type CheckpointStore interface {
+	SaveCheckpoint(name string, checkpoint *Checkpoint) error
+	LoadCheckpoint(name string) (*Checkpoint, error)
+	GetAllCheckpoints() (map[string]*Checkpoint, error)
+	DeleteCheckpoint(name string) error
+	UpdateProcessedCount(name string, increment int64) error
+	CompactCheckpoints(keep func(name string, checkpoint *Checkpoint) bool) (*CompactResult, error)
+}
+
+var (
+	_ TelemetryStore     = (*FileStorage)(nil)
+	_ TelemetryCache     = (*MemoryStorage)(nil)
+	_ ColdTelemetryStore = (*FileStorage)(nil)
+	_ TelemetrySyncer    = (*FileStorage)(nil)
+	_ ObjectStore        = (*DirObjectStore)(nil)
+	_ CheckpointStore    = (*CheckpointManager)(nil)
+)
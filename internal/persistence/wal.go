@@ -0,0 +1,137 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+)
+
+// TelemetryWAL is a write-ahead log of telemetry samples, framed like the
+// telemetry files. A storage writer appends and syncs each batch of samples
+// before acknowledging their messages, and resets the log once the samples
+// have been synced to their telemetry files, so a sample acknowledged
+// before a crash is stored on restart by ReplayTelemetryWAL.
+type TelemetryWAL struct {
+	path    string
+	file    *os.File
+	keyring *encryption.Keyring // nil means records are logged in plaintext
+}
+
+// OpenTelemetryWAL opens the log at path, creating it and its directory if
+// needed. Records already in it are kept until Reset.
+func OpenTelemetryWAL(path string, keyring *encryption.Keyring) (*TelemetryWAL, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL %s: %w", path, err)
+	}
+	return &TelemetryWAL{path: path, file: file, keyring: keyring}, nil
+}
+
+// Path returns the log's path
+func (w *TelemetryWAL) Path() string {
+	return w.path
+}
+
+// Append logs samples and syncs them to disk before returning
+func (w *TelemetryWAL) Append(samples []Telemetry) error {
+	var frames []byte
+	for _, sample := range samples {
+		jsonData, err := json.Marshal(sample)
+		if err != nil {
+			return fmt.Errorf("failed to marshal telemetry data: %w", err)
+		}
+		payload, err := w.keyring.Seal(jsonData)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt telemetry data: %w", err)
+		}
+		frames = append(frames, encodeFrame(payload)...)
+	}
+	if _, err := w.file.Write(frames); err != nil {
+		return fmt.Errorf("failed to write to WAL %s: %w", w.path, err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL %s: %w", w.path, err)
+	}
+	return nil
+}
+
+// Reset empties the log. The samples in it must have been stored and synced.
+func (w *TelemetryWAL) Reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL %s: %w", w.path, err)
+	}
+	return w.file.Sync()
+}
+
+// Close closes the log, keeping its records
+func (w *TelemetryWAL) Close() error {
+	return w.file.Close()
+}
+
+// ReplayTelemetryWAL writes the samples logged at path to store, then syncs
+// them if store is a TelemetrySyncer. Samples already stored are skipped by
+// the store, so replaying is safe however far the writer got. A torn final
+// record, from a crash while it was appended, was never acknowledged and is
+// ignored. It returns the GPUs replayed samples were written for.
+func ReplayTelemetryWAL(path string, keyring *encryption.Keyring, store TelemetryWriter) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open WAL %s: %w", path, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", err)
+		}
+	}()
+
+	var samples []Telemetry
+	var decodeErr error
+	if _, err := scanFrames(file, func(payload []byte) {
+		if decodeErr != nil {
+			return
+		}
+		record, err := keyring.Open(payload)
+		if err != nil {
+			decodeErr = err
+			return
+		}
+		var sample Telemetry
+		if err := json.Unmarshal(record, &sample); err != nil {
+			decodeErr = err
+			return
+		}
+		samples = append(samples, sample)
+	}); err != nil && err != errIncompleteRecord {
+		fmt.Printf("Warning: stopped reading WAL %s at corrupt record: %v\n", path, err)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to read WAL %s: %w", path, decodeErr)
+	}
+
+	seen := make(map[string]bool)
+	var gpuIDs []string
+	for _, sample := range samples {
+		if err := store.WriteTelemetry(sample); err != nil {
+			return gpuIDs, fmt.Errorf("failed to replay WAL %s: %w", path, err)
+		}
+		if !seen[sample.GPUId] {
+			seen[sample.GPUId] = true
+			gpuIDs = append(gpuIDs, sample.GPUId)
+		}
+	}
+	if syncer, ok := store.(TelemetrySyncer); ok {
+		if err := syncer.SyncTelemetry(gpuIDs); err != nil {
+			return gpuIDs, err
+		}
+	}
+	return gpuIDs, nil
+}
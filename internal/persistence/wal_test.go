@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTelemetryWAL_Replay(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewFileStorage(dir)
+	base := time.Date(2025, 7, 18, 12, 0, 0, 0, time.UTC)
+	sample := func(gpuID string, i int) Telemetry {
+		return Telemetry{GPUId: gpuID, Hostname: "host-1", Timestamp: base.Add(time.Duration(i) * time.Second), Metrics: map[string]float64{"m": float64(i)}}
+	}
+	if err := storage.WriteTelemetry(sample("gpu_0", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "wal", "writer-0.wal")
+	wal, err := OpenTelemetryWAL(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wal.Append([]Telemetry{sample("gpu_0", 0), sample("gpu_0", 1), sample("gpu_1", 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// A crash while a batch was appended leaves a torn record
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString(`40 0000000 {"gpu_id":"gpu_2"`)
+	_ = file.Close()
+
+	gpuIDs, err := ReplayTelemetryWAL(path, nil, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gpuIDs) != 2 {
+		t.Errorf("Expected gpu_0 and gpu_1 replayed, got %v", gpuIDs)
+	}
+	if records, _ := storage.ReadTelemetryFile("gpu_0"); len(records) != 2 {
+		t.Errorf("Expected the stored sample skipped and the other replayed, got %d records", len(records))
+	}
+	if records, _ := storage.ReadTelemetryFile("gpu_1"); len(records) != 1 {
+		t.Errorf("Expected 1 record for gpu_1, got %d", len(records))
+	}
+
+	// Replaying again stores nothing new, and a reset log replays nothing
+	if _, err := ReplayTelemetryWAL(path, nil, storage); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := storage.ReadTelemetryFile("gpu_0"); len(records) != 2 {
+		t.Errorf("Expected a second replay to be a no-op, got %d records", len(records))
+	}
+	wal, err = OpenTelemetryWAL(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = wal.Close() }()
+	if err := wal.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if gpuIDs, err := ReplayTelemetryWAL(path, nil, storage); err != nil || len(gpuIDs) != 0 {
+		t.Errorf("Expected nothing replayed from a reset log, got %v, %v", gpuIDs, err)
+	}
+	if gpuIDs, err := ReplayTelemetryWAL(filepath.Join(dir, "missing.wal"), nil, storage); err != nil || gpuIDs != nil {
+		t.Errorf("Expected nothing replayed from a missing log, got %v, %v", gpuIDs, err)
+	}
+}
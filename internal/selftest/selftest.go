@@ -0,0 +1,293 @@
+// Package selftest runs the startup checks behind each binary's --selftest
+// flag: quick round trips through the parts of the service that touch the
+// disk, the broker and the clock, reported as a table for container init and
+// health checks.
+package selftest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/encryption"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+	"github.com/harishb93/telemetry-pipeline/internal/persistence"
+)
+
+// checkTimeout bounds each check
+const checkTimeout = 10 * time.Second
+
+// earliestSaneTime is before any build of the pipeline; a clock showing an
+// earlier time has not been set
+var earliestSaneTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Check is a named self-test
+type Check struct {
+	Name string
+	Run  func() error
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Report is the outcome of a self-test run
+type Report struct {
+	Binary  string
+	Results []Result
+}
+
+// Run runs every check in order. A check taking longer than checkTimeout
+// fails, though it is left to finish in the background.
+func Run(binary string, checks ...Check) *Report {
+	report := &Report{Binary: binary}
+	for _, check := range checks {
+		start := time.Now()
+		done := make(chan error, 1)
+		go func() { done <- check.Run() }()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-time.After(checkTimeout):
+			err = fmt.Errorf("timed out after %s", checkTimeout)
+		}
+		report.Results = append(report.Results, Result{Name: check.Name, Err: err, Duration: time.Since(start)})
+	}
+	return report
+}
+
+// Passed reports whether every check passed
+func (r *Report) Passed() bool {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Print writes the report as a table
+func (r *Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Self-test of %s\n", r.Binary)
+	failed := 0
+	for _, result := range r.Results {
+		status := "ok"
+		if result.Err != nil {
+			status = "FAIL: " + result.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", result.Name, result.Duration.Round(time.Millisecond), status)
+	}
+	if failed > 0 {
+		fmt.Fprintf(tw, "%d of %d checks failed\n", failed, len(r.Results))
+	} else {
+		fmt.Fprintf(tw, "All %d checks passed\n", len(r.Results))
+	}
+	return tw.Flush()
+}
+
+// Exit prints the report to stdout and exits, with status 1 if a check
+// failed
+func (r *Report) Exit() {
+	_ = r.Print(os.Stdout)
+	if !r.Passed() {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Clock checks that the wall clock has been set and that time moves forward
+func Clock() Check {
+	return Check{Name: "clock", Run: func() error {
+		start := time.Now()
+		if start.Before(earliestSaneTime) {
+			return fmt.Errorf("wall clock reads %s; it has not been set", start.UTC().Format(time.RFC3339))
+		}
+		time.Sleep(10 * time.Millisecond)
+		if elapsed := time.Since(start); elapsed < 10*time.Millisecond || elapsed > time.Second {
+			return fmt.Errorf("10ms sleep measured as %s", elapsed)
+		}
+		return nil
+	}}
+}
+
+// BrokerLoopback checks that a message published to an in-memory broker is
+// delivered to a subscriber and acknowledged
+func BrokerLoopback() Check {
+	return Check{Name: "broker loopback", Run: func() error {
+		config := mq.DefaultBrokerConfig()
+		config.PersistenceEnabled = false
+		broker := mq.NewBroker(config)
+		defer broker.Close()
+		return loopback(broker)
+	}}
+}
+
+// BrokerPersistence checks that a broker persisting to dir can publish,
+// deliver and acknowledge a message, using a scratch directory inside dir
+func BrokerPersistence(dir string, keyring *encryption.Keyring) Check {
+	return Check{Name: "broker persistence", Run: func() error {
+		scratch, cleanup, err := scratchDir(dir)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		config := mq.DefaultBrokerConfig()
+		config.PersistenceEnabled = true
+		config.PersistenceDir = scratch
+		config.Keyring = keyring
+		broker := mq.NewBroker(config)
+		defer broker.Close()
+		if err := loopback(broker); err != nil {
+			return err
+		}
+		if entries, err := os.ReadDir(scratch); err != nil || len(entries) == 0 {
+			return fmt.Errorf("nothing persisted to %s", dir)
+		}
+		return nil
+	}}
+}
+
+// loopback publishes a message to broker and waits for it
+func loopback(broker *mq.Broker) error {
+	const topic = "selftest"
+	ch, unsubscribe, err := broker.SubscribeWithAck(topic)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	if err := broker.Publish(topic, mq.Message{Payload: []byte(`{"selftest":true}`)}); err != nil {
+		return err
+	}
+	select {
+	case msg := <-ch:
+		if string(msg.Payload) != `{"selftest":true}` {
+			return fmt.Errorf("received %q", msg.Payload)
+		}
+		msg.Ack()
+	case <-time.After(time.Second):
+		return fmt.Errorf("published message was not delivered")
+	}
+	if pending := broker.GetStats().Topics[topic].PendingMessages; pending != 0 {
+		return fmt.Errorf("%d messages still pending after the ack", pending)
+	}
+	return nil
+}
+
+// TelemetryStorage checks that telemetry written to dir, encrypted with
+// keyring if set, reads back intact, using a scratch directory inside dir
+func TelemetryStorage(dir string, keyring *encryption.Keyring) Check {
+	return Check{Name: "persistence round-trip", Run: func() error {
+		scratch, cleanup, err := scratchDir(dir)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		storage := persistence.NewFileStorage(scratch)
+		storage.SetKeyring(keyring)
+		written := persistence.Telemetry{
+			GPUId:     "selftest",
+			Hostname:  "selftest",
+			Metrics:   map[string]float64{"DCGM_FI_DEV_GPU_UTIL": 42},
+			Timestamp: time.Now().UTC(),
+		}
+		if err := storage.WriteTelemetry(written); err != nil {
+			return err
+		}
+		records, err := storage.ReadTelemetryFile("selftest")
+		if err != nil {
+			return err
+		}
+		if len(records) != 1 {
+			return fmt.Errorf("wrote 1 record, read back %d", len(records))
+		}
+		return nil
+	}}
+}
+
+// Checkpoints checks that a checkpoint saved next to checkpointFile loads back
+func Checkpoints(checkpointFile string) Check {
+	return Check{Name: "checkpoint write", Run: func() error {
+		dir := filepath.Dir(checkpointFile)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		file, err := os.CreateTemp(dir, ".selftest-checkpoint-*.json")
+		if err != nil {
+			return err
+		}
+		path := file.Name()
+		_ = file.Close()
+		defer func() { _ = os.Remove(path) }()
+
+		manager := persistence.NewCheckpointManager(path)
+		if err := manager.SaveCheckpoint("selftest", &persistence.Checkpoint{ProcessedCount: 42, LastProcessedTime: time.Now()}); err != nil {
+			return err
+		}
+		// A fresh manager reads the file rather than its memory cache
+		loaded, err := persistence.NewCheckpointManager(path).LoadCheckpoint("selftest")
+		if err != nil {
+			return err
+		}
+		if loaded.ProcessedCount != 42 {
+			return fmt.Errorf("saved processed count 42, loaded %d", loaded.ProcessedCount)
+		}
+		return nil
+	}}
+}
+
+// Readable checks that path can be opened for reading, or listed if it is a
+// directory
+func Readable(name, path string) Check {
+	return Check{Name: name, Run: func() error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			_, err = os.ReadDir(path)
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		return file.Close()
+	}}
+}
+
+// scratchDir creates a temporary directory inside dir, creating dir if
+// needed, and returns a function removing it
+func scratchDir(dir string) (string, func(), error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, err
+	}
+	scratch, err := os.MkdirTemp(dir, ".selftest-")
+	if err != nil {
+		return "", nil, err
+	}
+	return scratch, func() { _ = os.RemoveAll(scratch) }, nil
+}
+
+// Writable checks that a file can be created in dir, creating dir if needed
+func Writable(name, dir string) Check {
+	return Check{Name: name, Run: func() error {
+		_, cleanup, err := scratchDir(dir)
+		if err != nil {
+			return err
+		}
+		cleanup()
+		return nil
+	}}
+}
@@ -0,0 +1,63 @@
+package selftest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChecksPass(t *testing.T) {
+	dir := t.TempDir()
+	report := Run("test",
+		Clock(),
+		BrokerLoopback(),
+		BrokerPersistence(filepath.Join(dir, "mq"), nil),
+		TelemetryStorage(filepath.Join(dir, "data"), nil),
+		Checkpoints(filepath.Join(dir, "checkpoints", "checkpoint.json")),
+		Readable("data dir readable", dir),
+		Writable("spool dir writable", filepath.Join(dir, "spool")),
+	)
+	for _, result := range report.Results {
+		if result.Err != nil {
+			t.Errorf("Expected %s to pass, got %v", result.Name, result.Err)
+		}
+	}
+	if !report.Passed() {
+		t.Error("Expected the report to pass")
+	}
+
+	// Checks clean up after themselves
+	for _, sub := range []string{"mq", "data", "checkpoints", "spool"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil || len(entries) != 0 {
+			t.Errorf("Expected %s left empty, got %v (%v)", sub, entries, err)
+		}
+	}
+}
+
+func TestReport_Failure(t *testing.T) {
+	report := Run("test",
+		Check{Name: "fine", Run: func() error { return nil }},
+		Readable("missing input", filepath.Join(t.TempDir(), "missing.csv")),
+		Check{Name: "broken", Run: func() error { return errors.New("disk on fire") }},
+	)
+	if report.Passed() {
+		t.Fatal("Expected the report to fail")
+	}
+	if len(report.Results) != 3 {
+		t.Fatalf("Expected every check to run, got %d results", len(report.Results))
+	}
+
+	var out bytes.Buffer
+	if err := report.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Self-test of test", "fine", "ok", "FAIL: disk on fire", "2 of 3 checks failed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the report, got:\n%s", want, out.String())
+		}
+	}
+}
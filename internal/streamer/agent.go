@@ -0,0 +1,417 @@
+package streamer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// AgentConfig configures an edge Agent
+type AgentConfig struct {
+	// Exporters are the metrics URLs of local DCGM exporters
+	Exporters      []string
+	ScrapeInterval time.Duration
+	Topic          string
+	// SpoolDir holds scraped messages until they are forwarded
+	SpoolDir string
+	// MaxSpoolBytes bounds the spool; the oldest messages are dropped
+	// beyond it. Zero is unlimited.
+	MaxSpoolBytes int64
+	// MaxBandwidth caps forwarded payload bytes per second; zero is
+	// unlimited
+	MaxBandwidth int
+	// Instance labels forwarded samples as LabelInstance, the hostname if
+	// empty
+	Instance string
+	// ShipWindows restricts forwarding to daily local-time windows, such as
+	// "22:00-06:00,12:00-13:00"; empty forwards at any time
+	ShipWindows string
+}
+
+// AgentStats reports an agent's progress
+type AgentStats struct {
+	Scraped      uint64 `json:"scraped_messages"`
+	ScrapeErrors uint64 `json:"scrape_errors"`
+	Forwarded    uint64 `json:"forwarded_messages"`
+	BacklogBytes int64  `json:"backlog_bytes"`
+	DroppedBytes int64  `json:"dropped_bytes"`
+	Shipping     bool   `json:"shipping"`
+}
+
+// Agent runs the streamer as an edge agent for sites with constrained
+// uplinks: it scrapes local exporters into a disk spool and forwards the
+// spool to the MQ service within a bandwidth cap and shipping windows, so
+// telemetry survives uplink outages and restarts and is shipped when the
+// link is cheap.
+type Agent struct {
+	config   AgentConfig
+	hostname string // the HeaderSourceHost of forwarded messages
+	windows  []shipWindow
+	broker   mq.BrokerInterface
+	client   *http.Client
+	logger   *logger.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex // guards spool
+	spool   *spool
+	limiter *bandwidthLimiter
+	wake    chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	scraped      atomic.Uint64
+	scrapeErrors atomic.Uint64
+	forwarded    atomic.Uint64
+}
+
+// NewAgent creates an agent, opening its spool
+func NewAgent(config AgentConfig, broker mq.BrokerInterface) (*Agent, error) {
+	if len(config.Exporters) == 0 {
+		return nil, fmt.Errorf("at least one exporter is required")
+	}
+	if config.ScrapeInterval <= 0 {
+		config.ScrapeInterval = 10 * time.Second
+	}
+	if config.Topic == "" {
+		config.Topic = "telemetry"
+	}
+	if err := mq.ValidateTopic(config.Topic); err != nil {
+		return nil, err
+	}
+	if config.Instance == "" {
+		config.Instance = DefaultInstance()
+	}
+	windows, err := parseShipWindows(config.ShipWindows)
+	if err != nil {
+		return nil, err
+	}
+	spool, err := openSpool(config.SpoolDir, config.MaxSpoolBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Agent{
+		config:   config,
+		hostname: DefaultInstance(),
+		windows:  windows,
+		broker:   broker,
+		client:   &http.Client{Timeout: config.ScrapeInterval},
+		logger:   logger.NewFromEnv().WithComponent("edge-agent"),
+		now:      time.Now,
+		spool:    spool,
+		limiter:  newBandwidthLimiter(config.MaxBandwidth),
+		wake:     make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+// Start starts scraping and forwarding
+func (a *Agent) Start() {
+	a.logger.Info("Edge agent starting",
+		"exporters", a.config.Exporters,
+		"instance", a.config.Instance,
+		"scrape_interval", a.config.ScrapeInterval,
+		"spool_dir", a.config.SpoolDir,
+		"backlog_bytes", a.Stats().BacklogBytes,
+		"max_bandwidth", a.config.MaxBandwidth,
+		"ship_windows", a.config.ShipWindows)
+
+	a.wg.Add(2)
+	go a.scrapeLoop()
+	go a.forwardLoop()
+}
+
+// Stop stops the agent; anything not yet forwarded stays in the spool for
+// the next start
+func (a *Agent) Stop() {
+	a.cancel()
+	a.wg.Wait()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.spool.close(); err != nil {
+		a.logger.Warn("Failed to close spool", "error", err)
+	}
+	a.logger.Info("Edge agent stopped", "forwarded", a.forwarded.Load(), "backlog_bytes", a.spool.backlog())
+}
+
+// Stats returns the agent's progress
+func (a *Agent) Stats() AgentStats {
+	a.mu.Lock()
+	backlog, dropped := a.spool.backlog(), a.spool.dropped
+	a.mu.Unlock()
+	return AgentStats{
+		Scraped:      a.scraped.Load(),
+		ScrapeErrors: a.scrapeErrors.Load(),
+		Forwarded:    a.forwarded.Load(),
+		BacklogBytes: backlog,
+		DroppedBytes: dropped,
+		Shipping:     inShipWindow(a.windows, a.now()),
+	}
+}
+
+// scrapeLoop scrapes every exporter into the spool each interval
+func (a *Agent) scrapeLoop() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(a.config.ScrapeInterval)
+	defer ticker.Stop()
+
+	for {
+		a.scrape()
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrape spools one scrape of every exporter
+func (a *Agent) scrape() {
+	now := a.now()
+	for _, url := range a.config.Exporters {
+		samples, err := scrapeExporter(a.ctx, a.client, url, now)
+		if err != nil {
+			if a.ctx.Err() == nil {
+				a.scrapeErrors.Add(1)
+				a.logger.Warn("Failed to scrape exporter", "exporter", url, "error", err)
+			}
+			continue
+		}
+
+		labels := targetProvenance(a.config.Instance, url)
+		a.mu.Lock()
+		for _, sample := range samples {
+			sample.Labels = labels
+			payload, err := json.Marshal(sample)
+			if err != nil {
+				a.logger.Error("Error marshaling to JSON", "error", err)
+				continue
+			}
+			if err := a.spool.append(payload); err != nil {
+				a.logger.Error("Failed to spool message", "error", err)
+				break
+			}
+			a.scraped.Add(1)
+		}
+		a.mu.Unlock()
+	}
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// forwardLoop publishes spooled messages in order while a shipping window
+// is open, retrying failed publishes with backoff
+func (a *Agent) forwardLoop() {
+	defer a.wg.Done()
+	const maxRetryDelay = 30 * time.Second
+	retryDelay := time.Second
+	holding := false
+
+	for {
+		if now := a.now(); !inShipWindow(a.windows, now) {
+			next := nextShipWindow(a.windows, now)
+			if !holding {
+				a.logger.Info("Outside shipping windows, holding spool", "next_window", next)
+				holding = true
+			}
+			// Recheck at least every minute in case the clock jumps
+			if !a.sleep(min(next.Sub(now), time.Minute), false) {
+				return
+			}
+			continue
+		}
+		if holding {
+			a.logger.Info("Shipping window open, forwarding spool", "backlog_bytes", a.Stats().BacklogBytes)
+			holding = false
+		}
+
+		a.mu.Lock()
+		payload, err := a.spool.peek()
+		a.mu.Unlock()
+		if err != nil {
+			a.logger.Error("Failed to read spool", "error", err)
+			if !a.sleep(retryDelay, false) {
+				return
+			}
+			continue
+		}
+		if payload == nil {
+			if !a.sleep(a.config.ScrapeInterval, true) {
+				return
+			}
+			continue
+		}
+
+		if err := a.limiter.wait(a.ctx, len(payload)); err != nil {
+			return
+		}
+		if err := mq.PublishContext(a.ctx, a.broker, a.config.Topic, mq.Message{Payload: payload, Headers: sourceHeaders(a.hostname)}); err != nil {
+			a.logger.Warn("Failed to forward spooled message, retrying", "error", err, "retry_in", retryDelay)
+			if !a.sleep(retryDelay, false) {
+				return
+			}
+			retryDelay = min(retryDelay*2, maxRetryDelay)
+			continue
+		}
+		retryDelay = time.Second
+
+		a.mu.Lock()
+		err = a.spool.commit()
+		a.mu.Unlock()
+		if err != nil {
+			a.logger.Error("Failed to record forwarded message", "error", err)
+		}
+		a.forwarded.Add(1)
+	}
+}
+
+// sleep waits for d, or for the next scrape if wakeable, returning false if
+// the agent stopped first
+func (a *Agent) sleep(d time.Duration, wakeable bool) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	var wake chan struct{}
+	if wakeable {
+		wake = a.wake
+	}
+	select {
+	case <-timer.C:
+	case <-wake:
+	case <-a.ctx.Done():
+		return false
+	}
+	return true
+}
+
+// shipWindow is a daily local-time range during which messages are
+// forwarded, as offsets from midnight. A window ending before it starts
+// spans midnight.
+type shipWindow struct {
+	start, end time.Duration
+}
+
+// parseShipWindows parses comma-separated HH:MM-HH:MM windows
+func parseShipWindows(spec string) ([]shipWindow, error) {
+	var windows []shipWindow
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(part, "-")
+		start, err1 := parseTimeOfDay(from)
+		end, err2 := parseTimeOfDay(to)
+		if !ok || err1 != nil || err2 != nil || start == end {
+			return nil, fmt.Errorf("invalid shipping window %q: expected HH:MM-HH:MM", part)
+		}
+		windows = append(windows, shipWindow{start: start, end: end})
+	}
+	return windows, nil
+}
+
+// parseTimeOfDay parses HH:MM as an offset from midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// sinceMidnight returns how far into its day t is
+func sinceMidnight(t time.Time) time.Duration {
+	year, month, day := t.Date()
+	return t.Sub(time.Date(year, month, day, 0, 0, 0, 0, t.Location()))
+}
+
+// inShipWindow reports whether t falls in any window; no windows means
+// always
+func inShipWindow(windows []shipWindow, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	offset := sinceMidnight(t)
+	for _, w := range windows {
+		if w.start < w.end && offset >= w.start && offset < w.end {
+			return true
+		}
+		if w.start > w.end && (offset >= w.start || offset < w.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextShipWindow returns when the next window after t opens
+func nextShipWindow(windows []shipWindow, t time.Time) time.Time {
+	if len(windows) == 0 {
+		return t
+	}
+	offset := sinceMidnight(t)
+	midnight := t.Add(-offset)
+	var next time.Time
+	for _, w := range windows {
+		opens := midnight.Add(w.start)
+		if w.start <= offset {
+			opens = opens.AddDate(0, 0, 1)
+		}
+		if next.IsZero() || opens.Before(next) {
+			next = opens
+		}
+	}
+	return next
+}
+
+// bandwidthLimiter paces forwarded bytes to a rate. A message larger than
+// the remaining allowance is sent and the debt paid off by waiting before
+// the next one.
+type bandwidthLimiter struct {
+	rate   float64 // bytes per second; zero is unlimited
+	tokens float64
+	last   time.Time
+}
+
+// newBandwidthLimiter creates a limiter allowing bytesPerSecond, with up to
+// a second's worth in a burst
+func newBandwidthLimiter(bytesPerSecond int) *bandwidthLimiter {
+	return &bandwidthLimiter{rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), last: time.Now()}
+}
+
+// wait takes n bytes of allowance, waiting if it is overdrawn
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
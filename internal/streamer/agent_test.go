@@ -0,0 +1,270 @@
+package streamer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testExposition = `# HELP DCGM_FI_DEV_GPU_UTIL GPU utilization (in %).
+# TYPE DCGM_FI_DEV_GPU_UTIL gauge
+DCGM_FI_DEV_GPU_UTIL{gpu="0",UUID="GPU-5fd4f087",device="nvidia0",modelName="NVIDIA H100 80GB HBM3",Hostname="host-1",DCGM_FI_DRIVER_VERSION="535.129.03",container="",namespace="",pod=""} 87
+DCGM_FI_DEV_GPU_TEMP{gpu="1",UUID="GPU-bc7a12ab",device="nvidia1",modelName="NVIDIA H100 80GB HBM3",Hostname="host-1",note="say \"hi\"\\now"} 61.5 1721335354000
+# HELP go_goroutines Number of goroutines.
+go_goroutines 12
+`
+
+func TestParseExposition(t *testing.T) {
+	now := time.Date(2025, 7, 18, 20, 42, 34, 0, time.UTC)
+	samples, err := parseExposition(strings.NewReader(testExposition), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("Expected the two GPU samples, got %d", len(samples))
+	}
+
+	util := samples[0]
+	if !util.Timestamp.Equal(now) {
+		t.Errorf("Expected the scrape time, got %v", util.Timestamp)
+	}
+	for field, want := range map[string]interface{}{
+		"metric_name": "DCGM_FI_DEV_GPU_UTIL",
+		"gpu_id":      0.0,
+		"uuid":        "GPU-5fd4f087",
+		"Hostname":    "host-1",
+		"modelName":   "NVIDIA H100 80GB HBM3",
+		"value":       87.0,
+		"timestamp":   "2025-07-18T20:42:34Z",
+	} {
+		if got := util.Fields[field]; got != want {
+			t.Errorf("%s = %v (%T), want %v", field, got, got, want)
+		}
+	}
+	if raw := util.Fields["labels_raw"].(string); !strings.Contains(raw, `DCGM_FI_DRIVER_VERSION="535.129.03"`) || !strings.Contains(raw, `__name__="DCGM_FI_DEV_GPU_UTIL"`) {
+		t.Errorf("Expected labels_raw to keep every label, got %s", raw)
+	}
+	if raw := samples[1].Fields["labels_raw"].(string); !strings.Contains(raw, `note="say \"hi\"\\now"`) {
+		t.Errorf("Expected escaped label values to round-trip, got %s", raw)
+	}
+
+	if _, err := parseExposition(strings.NewReader(`DCGM_FI_DEV_GPU_UTIL{gpu="0} 1`), now); err == nil {
+		t.Error("Expected an unterminated label to be rejected")
+	}
+}
+
+func readSpool(t *testing.T, s *spool) []string {
+	t.Helper()
+	var payloads []string
+	for {
+		payload, err := s.peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload == nil {
+			return payloads
+		}
+		payloads = append(payloads, string(payload))
+		if err := s.commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSpool_ResumesAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.segmentBytes = 16
+	for i := 0; i < 6; i++ {
+		if err := s.append([]byte(fmt.Sprintf("message-%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Forward two, and read a third without committing it
+	for i := 0; i < 2; i++ {
+		if _, err := s.peek(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if payload, _ := s.peek(); string(payload) != "message-2" {
+		t.Fatalf("Expected message-2, got %q", payload)
+	}
+	_ = s.close()
+
+	// A crash mid-append leaves a partial line, which is discarded
+	segments, _ := filepath.Glob(filepath.Join(dir, "segment-*.log"))
+	last, _ := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0644)
+	_, _ = last.WriteString("partial")
+	_ = last.Close()
+
+	s, err = openSpool(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.close() }()
+	want := []string{"message-2", "message-3", "message-4", "message-5"}
+	if got := readSpool(t, s); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v after reopening, got %v", want, got)
+	}
+	if s.backlog() != 0 {
+		t.Errorf("Expected an empty backlog, got %d bytes", s.backlog())
+	}
+	if remaining, _ := filepath.Glob(filepath.Join(dir, "segment-*.log")); len(remaining) > 1 {
+		t.Errorf("Expected forwarded segments to be deleted, got %v", remaining)
+	}
+}
+
+func TestSpool_DropsOldestBeyondLimit(t *testing.T) {
+	s, err := openSpool(t.TempDir(), 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.close() }()
+	s.segmentBytes = 20
+
+	// Each message is 10 bytes, two to a segment
+	for i := 0; i < 8; i++ {
+		if err := s.append([]byte(fmt.Sprintf("message-%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.size() > 40 {
+		t.Errorf("Expected the spool within 40 bytes, got %d", s.size())
+	}
+	if s.dropped != 40 {
+		t.Errorf("Expected 40 dropped bytes, got %d", s.dropped)
+	}
+	if got := readSpool(t, s); len(got) != 4 || got[0] != "message-4" {
+		t.Errorf("Expected the newest four messages, got %v", got)
+	}
+}
+
+func TestShipWindows(t *testing.T) {
+	windows, err := parseShipWindows("22:00-06:00, 12:00-13:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 7, 18, hour, minute, 0, 0, time.UTC)
+	}
+
+	for _, tt := range []struct {
+		at   time.Time
+		open bool
+		next time.Time
+	}{
+		{at(23, 0), true, at(12, 0).AddDate(0, 0, 1)},
+		{at(5, 59), true, at(12, 0)},
+		{at(6, 0), false, at(12, 0)},
+		{at(13, 29), true, at(22, 0)},
+		{at(13, 30), false, at(22, 0)},
+	} {
+		if got := inShipWindow(windows, tt.at); got != tt.open {
+			t.Errorf("%s: expected open=%v", tt.at.Format("15:04"), tt.open)
+		}
+		if got := nextShipWindow(windows, tt.at); !got.Equal(tt.next) {
+			t.Errorf("%s: expected next window at %v, got %v", tt.at.Format("15:04"), tt.next, got)
+		}
+	}
+
+	if !inShipWindow(nil, at(3, 0)) {
+		t.Error("Expected no windows to always ship")
+	}
+	for _, spec := range []string{"22:00", "25:00-01:00", "10:00-10:00"} {
+		if _, err := parseShipWindows(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestBandwidthLimiter(t *testing.T) {
+	limiter := newBandwidthLimiter(1000)
+	ctx := t.Context()
+
+	// A second's worth goes out at once; the next 200 bytes wait ~200ms
+	start := time.Now()
+	for i := 0; i < 12; i++ {
+		if err := limiter.wait(ctx, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected 1200 bytes at 1000 B/s to take about 200ms, took %v", elapsed)
+	}
+}
+
+func TestAgent_SpoolsOutsideWindowsAndForwardsLater(t *testing.T) {
+	exporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testExposition))
+	}))
+	defer exporter.Close()
+
+	config := AgentConfig{
+		Exporters:      []string{exporter.URL},
+		ScrapeInterval: 20 * time.Millisecond,
+		SpoolDir:       t.TempDir(),
+		Instance:       "edge-1",
+	}
+
+	// A window that is never open now: everything stays in the spool
+	now := time.Now()
+	closed := config
+	closed.ShipWindows = fmt.Sprintf("%s-%s", now.Add(2*time.Hour).Format("15:04"), now.Add(3*time.Hour).Format("15:04"))
+	broker := NewMockBroker()
+	agent, err := NewAgent(closed, broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.Start()
+	time.Sleep(100 * time.Millisecond)
+	agent.Stop()
+
+	stats := agent.Stats()
+	if stats.Scraped < 2 || stats.BacklogBytes == 0 || stats.Shipping {
+		t.Fatalf("Expected scraped samples held in the spool, got %+v", stats)
+	}
+	if n := len(broker.GetMessages()); n != 0 {
+		t.Fatalf("Expected nothing forwarded outside the window, got %d", n)
+	}
+
+	// Restarted without windows, the agent forwards the spool in order
+	agent, err = NewAgent(config, broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.Start()
+	defer agent.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(broker.GetMessages()) < int(stats.Scraped) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	messages := broker.GetMessages()
+	if len(messages) < int(stats.Scraped) {
+		t.Fatalf("Expected the %d spooled samples forwarded, got %d", stats.Scraped, len(messages))
+	}
+
+	var first TelemetryData
+	if err := json.Unmarshal(messages[0].Payload, &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Fields["metric_name"] != "DCGM_FI_DEV_GPU_UTIL" || first.Timestamp.After(now.Add(time.Second)) {
+		t.Errorf("Expected the first spooled sample with its scrape time, got %+v", first)
+	}
+	if first.Labels[LabelInstance] != "edge-1" || first.Labels[LabelTarget] != exporter.URL {
+		t.Errorf("Expected the sample labelled with the agent and exporter, got %v", first.Labels)
+	}
+}
@@ -0,0 +1,178 @@
+package streamer
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/logger"
+)
+
+// Kinds of corruption injected by chaos mode
+const (
+	chaosMalformed     = "malformed"
+	chaosMissingField  = "missing_field"
+	chaosExtremeValue  = "extreme_value"
+	chaosDuplicateUUID = "duplicate_uuid"
+)
+
+// chaosFields are removed by preference when injecting a missing field, as
+// the collector relies on them
+var chaosFields = []string{"value", "metric_name", "uuid", "gpu_id", "Hostname"}
+
+// chaosValues are the extreme values injected into numeric fields
+var chaosValues = []float64{1e308, -1e308, -1, 0, 1e18, 4294967295}
+
+// chaosMutator corrupts a percentage of records so that staging exercises
+// the collector's validation paths
+type chaosMutator struct {
+	percent float64
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	lastUUID string // uuid of the last record, for duplicates
+	counts   map[string]uint64
+}
+
+// newChaosMutator corrupts percent of records. A zero seed picks one from
+// the clock; the same non-zero seed corrupts the same records the same way
+// for a single worker.
+func newChaosMutator(percent float64, seed int64) *chaosMutator {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &chaosMutator{
+		percent: percent,
+		rand:    rand.New(rand.NewSource(seed)),
+		counts:  make(map[string]uint64),
+	}
+}
+
+// encode marshals data, corrupting it first with probability percent/100.
+// It returns the kind of corruption injected, or "" for none.
+func (c *chaosMutator) encode(data *TelemetryData) ([]byte, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	uuid, _ := data.Fields["uuid"].(string)
+	previous := c.lastUUID
+	if uuid != "" {
+		c.lastUUID = uuid
+	}
+	if c.rand.Float64()*100 >= c.percent {
+		payload, err := json.Marshal(data)
+		return payload, "", err
+	}
+
+	kinds := []string{chaosMalformed, chaosMissingField, chaosExtremeValue}
+	if uuid != "" && previous != "" && previous != uuid {
+		kinds = append(kinds, chaosDuplicateUUID)
+	}
+	kind := kinds[c.rand.Intn(len(kinds))]
+
+	mutated := &TelemetryData{Timestamp: data.Timestamp, Fields: make(map[string]interface{}, len(data.Fields)), CorrelationID: data.CorrelationID, Labels: data.Labels}
+	for key, value := range data.Fields {
+		mutated.Fields[key] = value
+	}
+	switch kind {
+	case chaosMissingField:
+		delete(mutated.Fields, c.pickField(mutated.Fields))
+	case chaosExtremeValue:
+		mutated.Fields[c.pickNumericField(mutated.Fields)] = chaosValues[c.rand.Intn(len(chaosValues))]
+	case chaosDuplicateUUID:
+		mutated.Fields["uuid"] = previous
+	}
+
+	payload, err := json.Marshal(mutated)
+	if err != nil {
+		return nil, "", err
+	}
+	if kind == chaosMalformed {
+		// Truncated JSON, as from a writer that died mid-message
+		payload = payload[:c.rand.Intn(len(payload))]
+	}
+	c.counts[kind]++
+	return payload, kind, nil
+}
+
+// pickField chooses a field to remove, preferring those the collector needs
+func (c *chaosMutator) pickField(fields map[string]interface{}) string {
+	var candidates []string
+	for _, field := range chaosFields {
+		if _, ok := fields[field]; ok {
+			candidates = append(candidates, field)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = sortedKeys(fields)
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[c.rand.Intn(len(candidates))]
+}
+
+// pickNumericField chooses a field to give an extreme value: value if the
+// record has one, otherwise any numeric field, otherwise value
+func (c *chaosMutator) pickNumericField(fields map[string]interface{}) string {
+	if _, ok := fields["value"]; ok {
+		return "value"
+	}
+	var numeric []string
+	for _, key := range sortedKeys(fields) {
+		if _, ok := fields[key].(float64); ok {
+			numeric = append(numeric, key)
+		}
+	}
+	if len(numeric) == 0 {
+		return "value"
+	}
+	return numeric[c.rand.Intn(len(numeric))]
+}
+
+// injected returns how many records were corrupted, by kind
+func (c *chaosMutator) injected() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]uint64, len(c.counts))
+	for kind, count := range c.counts {
+		counts[kind] = count
+	}
+	return counts
+}
+
+// sortedKeys returns the keys of fields in order, so seeded runs repeat
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SetChaos makes the streamer corrupt percent of the records it publishes
+// with malformed JSON, missing fields, extreme values and duplicate UUIDs,
+// to exercise collector validation in staging. A non-zero seed makes the
+// corruption repeatable.
+func (s *Streamer) SetChaos(percent float64, seed int64) {
+	if percent <= 0 {
+		s.chaos = nil
+		return
+	}
+	s.chaos = newChaosMutator(percent, seed)
+}
+
+// encode marshals data for publishing, through chaos mode if enabled
+func (s *Streamer) encode(data *TelemetryData, workerLogger *logger.Logger) ([]byte, error) {
+	if s.chaos == nil {
+		return json.Marshal(data)
+	}
+	payload, kind, err := s.chaos.encode(data)
+	if kind != "" {
+		workerLogger.Debug("Injected chaos into record", "kind", kind)
+	}
+	return payload, err
+}
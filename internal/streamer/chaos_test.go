@@ -0,0 +1,82 @@
+package streamer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestChaosMutator_Encode(t *testing.T) {
+	chaos := newChaosMutator(100, 42)
+	kinds := map[string]int{}
+	for i := 0; i < 400; i++ {
+		uuid := "GPU-a"
+		if i%2 == 1 {
+			uuid = "GPU-b"
+		}
+		data := &TelemetryData{Timestamp: time.Now(), Fields: map[string]interface{}{
+			"uuid": uuid, "metric_name": "DCGM_FI_DEV_GPU_UTIL", "value": 42.0, "Hostname": "host-1",
+		}}
+		payload, kind, err := chaos.encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kinds[kind]++
+
+		var decoded TelemetryData
+		err = json.Unmarshal(payload, &decoded)
+		switch kind {
+		case chaosMalformed:
+			if err == nil {
+				t.Fatalf("Expected malformed JSON, got %s", payload)
+			}
+		case chaosMissingField:
+			if err != nil || len(decoded.Fields) != 3 {
+				t.Fatalf("Expected a field removed, got %s", payload)
+			}
+		case chaosExtremeValue:
+			if err != nil || decoded.Fields["value"] == 42.0 {
+				t.Fatalf("Expected an extreme value, got %s", payload)
+			}
+		case chaosDuplicateUUID:
+			if err != nil || decoded.Fields["uuid"] == uuid {
+				t.Fatalf("Expected another record's uuid, got %s", payload)
+			}
+		default:
+			t.Fatalf("Expected every record corrupted at 100%%, got %q", kind)
+		}
+		if data.Fields["value"] != 42.0 || data.Fields["uuid"] != uuid {
+			t.Fatal("Expected the original record to be left intact")
+		}
+	}
+	for _, kind := range []string{chaosMalformed, chaosMissingField, chaosExtremeValue, chaosDuplicateUUID} {
+		if kinds[kind] == 0 || chaos.injected()[kind] != uint64(kinds[kind]) {
+			t.Errorf("Expected %s injected and counted, got %d and %d", kind, kinds[kind], chaos.injected()[kind])
+		}
+	}
+}
+
+func TestChaosMutator_Percent(t *testing.T) {
+	chaos := newChaosMutator(10, 7)
+	corrupted := 0
+	for i := 0; i < 10000; i++ {
+		_, kind, _ := chaos.encode(&TelemetryData{Fields: map[string]interface{}{"value": 1.0}})
+		if kind != "" {
+			corrupted++
+		}
+	}
+	if corrupted < 800 || corrupted > 1200 {
+		t.Errorf("Expected about 10%% of records corrupted, got %d of 10000", corrupted)
+	}
+
+	// The same seed corrupts the same records
+	a, b := newChaosMutator(50, 3), newChaosMutator(50, 3)
+	for i := 0; i < 100; i++ {
+		data := &TelemetryData{Fields: map[string]interface{}{"value": float64(i), "uuid": "GPU-a"}}
+		pa, _, _ := a.encode(data)
+		pb, _, _ := b.encode(data)
+		if string(pa) != string(pb) {
+			t.Fatalf("Expected seeded runs to match, got %s and %s", pa, pb)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package streamer
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ControlResponse reports whether the streamer is paused after a control
+// request
+type ControlResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// Pause halts publishing until Resume. Workers finish the message in hand
+// and then wait where they are, so streaming resumes from the same position
+// in the file.
+func (s *Streamer) Pause() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.resumed != nil {
+		return
+	}
+	s.resumed = make(chan struct{})
+	s.logger.Info("Streamer paused")
+}
+
+// Resume continues publishing after Pause
+func (s *Streamer) Resume() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.resumed == nil {
+		return
+	}
+	close(s.resumed)
+	s.resumed = nil
+	s.logger.Info("Streamer resumed")
+}
+
+// Paused reports whether the streamer is paused
+func (s *Streamer) Paused() bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	return s.resumed != nil
+}
+
+// waitWhilePaused blocks while the streamer is paused, returning false if it
+// is stopped meanwhile
+func (s *Streamer) waitWhilePaused() bool {
+	s.pauseMu.Lock()
+	resumed := s.resumed
+	s.pauseMu.Unlock()
+	if resumed == nil {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// ControlHandler serves POST /control/pause and /control/resume
+func (s *Streamer) ControlHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /control/pause", func(w http.ResponseWriter, r *http.Request) {
+		s.Pause()
+		s.writeControlResponse(w)
+	})
+	mux.HandleFunc("POST /control/resume", func(w http.ResponseWriter, r *http.Request) {
+		s.Resume()
+		s.writeControlResponse(w)
+	})
+	return mux
+}
+
+func (s *Streamer) writeControlResponse(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ControlResponse{Paused: s.Paused()}); err != nil {
+		s.logger.Error("Failed to encode control response", "error", err)
+	}
+}
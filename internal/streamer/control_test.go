@@ -0,0 +1,110 @@
+package streamer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// pausingBroker pauses the streamer from within a given publish, so the
+// messages published before the pause are known exactly, and reports the
+// count of published messages after each publish
+type pausingBroker struct {
+	*MockBroker
+	pauseAt   int
+	streamer  *Streamer
+	published chan int
+}
+
+func (b *pausingBroker) Publish(topic string, msg mq.Message) error {
+	err := b.MockBroker.Publish(topic, msg)
+	n := len(b.GetMessages())
+	if n == b.pauseAt {
+		b.streamer.Pause()
+	}
+	b.published <- n
+	return err
+}
+
+// waitForPublished waits until count messages are published
+func waitForPublished(t *testing.T, published chan int, count int) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case n := <-published:
+			if n >= count {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("Timed out waiting for %d messages", count)
+		}
+	}
+}
+
+func TestStreamer_PauseResume(t *testing.T) {
+	var content strings.Builder
+	content.WriteString("id,value\n")
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&content, "%d,%d\n", i, i*10)
+	}
+	broker := &pausingBroker{MockBroker: NewMockBroker(), pauseAt: 5, published: make(chan int, 1000)}
+	defer broker.Close()
+
+	streamer := NewStreamer(createTestCSVWithContent(t, content.String()), 1, 200.0, "test-topic", broker)
+	broker.streamer = streamer
+	if err := streamer.Start(); err != nil {
+		t.Fatalf("Failed to start streamer: %v", err)
+	}
+	defer streamer.Stop()
+
+	waitForPublished(t, broker.published, broker.pauseAt)
+	if !streamer.Stats().Paused {
+		t.Error("Expected stats to report the pause")
+	}
+	select {
+	case n := <-broker.published:
+		t.Fatalf("Expected no publishing while paused, got %d messages", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	streamer.Resume()
+	waitForPublished(t, broker.published, broker.pauseAt+1)
+
+	// Streaming carries on from where it paused rather than from the start
+	var next TelemetryData
+	if err := json.Unmarshal(broker.GetMessages()[broker.pauseAt].Payload, &next); err != nil {
+		t.Fatal(err)
+	}
+	if id := next.Fields["id"]; id != float64(broker.pauseAt+1) {
+		t.Errorf("Expected row %d after resuming, got %v", broker.pauseAt+1, id)
+	}
+}
+
+func TestStreamer_ControlHandler(t *testing.T) {
+	streamer := NewStreamer("unused.csv", 1, 1.0, "test-topic", NewMockBroker())
+	handler := streamer.ControlHandler()
+	control := func(method, path string) (int, ControlResponse) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		var resp ControlResponse
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp
+	}
+
+	if code, resp := control("POST", "/control/pause"); code != http.StatusOK || !resp.Paused {
+		t.Errorf("Expected pause to report paused, got %d %+v", code, resp)
+	}
+	if code, resp := control("POST", "/control/resume"); code != http.StatusOK || resp.Paused {
+		t.Errorf("Expected resume to report running, got %d %+v", code, resp)
+	}
+	if code, _ := control("GET", "/control/pause"); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be rejected, got %d", code)
+	}
+}
@@ -0,0 +1,45 @@
+package streamer
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+)
+
+// DemoRate is the default per-worker publish rate used in demo mode. A DCGM
+// exporter scraped every few seconds across two 8-GPU hosts produces roughly
+// this many samples per second.
+const DemoRate = 20.0
+
+// demoDataset is a small DCGM export (2 hosts, 16 GPUs, 10 metrics each)
+// shipped with the binary so the pipeline can be exercised without input data.
+//
+//go:embed demodata/dcgm_sample.csv
+var demoDataset []byte
+
+// DemoDataset returns the embedded DCGM sample dataset
+func DemoDataset() []byte {
+	return demoDataset
+}
+
+// WriteDemoDataset writes the embedded DCGM sample dataset to a temporary CSV
+// file and returns its path. The caller is responsible for removing the file.
+func WriteDemoDataset() (string, error) {
+	tempFile, err := os.CreateTemp("", "demo_telemetry_*.csv")
+	if err != nil {
+		return "", fmt.Errorf("failed to create demo dataset file: %w", err)
+	}
+
+	if _, err := tempFile.Write(demoDataset); err != nil {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+		return "", fmt.Errorf("failed to write demo dataset: %w", err)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		_ = os.Remove(tempFile.Name())
+		return "", fmt.Errorf("failed to close demo dataset file: %w", err)
+	}
+
+	return tempFile.Name(), nil
+}
@@ -0,0 +1,64 @@
+package streamer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"testing"
+)
+
+func TestDemoDataset(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(DemoDataset())).ReadAll()
+	if err != nil {
+		t.Fatalf("Embedded demo dataset is not valid CSV: %v", err)
+	}
+
+	if len(records) < 2 {
+		t.Fatalf("Expected header and data rows, got %d rows", len(records))
+	}
+
+	headers := records[0]
+	required := map[string]bool{"timestamp": false, "metric_name": false, "uuid": false, "Hostname": false, "value": false}
+	for _, h := range headers {
+		if _, ok := required[h]; ok {
+			required[h] = true
+		}
+	}
+	for h, found := range required {
+		if !found {
+			t.Errorf("Demo dataset missing required column %q", h)
+		}
+	}
+
+	s := NewStreamer("", 1, DemoRate, "telemetry", NewMockBroker())
+	for i, record := range records[1:] {
+		if _, err := s.parseRecord(headers, record); err != nil {
+			t.Fatalf("Failed to parse demo record %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestWriteDemoDataset(t *testing.T) {
+	path, err := WriteDemoDataset()
+	if err != nil {
+		t.Fatalf("WriteDemoDataset failed: %v", err)
+	}
+	defer func() { _ = os.Remove(path) }()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read demo dataset file: %v", err)
+	}
+	if !bytes.Equal(data, DemoDataset()) {
+		t.Error("Written demo dataset does not match embedded data")
+	}
+
+	s := NewStreamer(path, 1, DemoRate, "telemetry", NewMockBroker())
+	headers, err := s.readHeaders()
+	if err != nil {
+		t.Fatalf("Failed to read demo headers: %v", err)
+	}
+	if len(headers) == 0 {
+		t.Error("Expected demo dataset headers")
+	}
+}
@@ -0,0 +1,257 @@
+package streamer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// Column types inferred by a dry run, following the typing of published
+// fields
+const (
+	columnBool   = "bool"
+	columnNumber = "number"
+	columnString = "string"
+	columnMixed  = "mixed"
+	columnEmpty  = "empty"
+)
+
+// ColumnSummary describes the values of one CSV column. Type is bool, number
+// or string when every non-empty value has that type, mixed otherwise, and
+// empty when the column has no values.
+type ColumnSummary struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Empty int    `json:"empty"`
+}
+
+// DryRunReport summarises a CSV file as the streamer would publish it
+type DryRunReport struct {
+	File      string          `json:"file"`
+	Files     int             `json:"files"`
+	Records   int             `json:"records"`
+	Malformed int             `json:"malformed"`
+	Columns   []ColumnSummary `json:"columns"`
+	Hosts     int             `json:"hosts"`
+	GPUs      int             `json:"gpus"`
+	// Topics counts the topics a --topic template resolves to
+	Topics int `json:"topics"`
+	// MissingGPU counts rows with neither uuid nor gpu_id, which the
+	// collector rejects
+	MissingGPU int     `json:"missing_gpu"`
+	Workers    int     `json:"workers"`
+	Rate       float64 `json:"rate"`
+	// PassDuration is how long one pass over the file takes at Rate. Every
+	// worker publishes every row, so a pass publishes MessagesPerPass.
+	PassDuration    time.Duration `json:"pass_duration"`
+	MessagesPerPass int           `json:"messages_per_pass"`
+
+	malformedSummary string
+}
+
+// DryRun parses and validates the whole input without publishing anything,
+// reporting what streaming it to topic with workers at rate would do.
+// csvPath may list several files or globs, as for the streamer. Malformed
+// rows are counted, and written to errorFile unless it is empty.
+func DryRun(csvPath, topic string, workers int, rate float64, errorFile string) (*DryRunReport, error) {
+	files, err := ResolveCSVFiles(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := readCSVHeaders(files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV headers: %w", err)
+	}
+	topics, err := parseTopicTemplate(topic, headers)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := openRowReport(errorFile)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &dryRun{
+		report:   &DryRunReport{File: csvPath, Files: len(files), Workers: workers, Rate: rate},
+		headers:  headers,
+		topics:   topics,
+		rows:     rows,
+		types:    make([]map[string]int, len(headers)),
+		hosts:    make(map[string]bool),
+		gpus:     make(map[string]bool),
+		topicSet: make(map[string]bool),
+	}
+	for i := range run.types {
+		run.types[i] = make(map[string]int)
+	}
+	for _, path := range files {
+		if err := run.file(path, len(files) > 1); err != nil {
+			_ = rows.close()
+			return nil, err
+		}
+	}
+
+	report := run.report
+	for i, header := range headers {
+		if header == "" {
+			continue // Empty headers are not published
+		}
+		report.Columns = append(report.Columns, summariseColumn(header, run.types[i]))
+	}
+	report.Malformed = rows.skipped()
+	report.malformedSummary = rows.summary()
+	report.Hosts = len(run.hosts)
+	report.GPUs = len(run.gpus)
+	report.Topics = len(run.topicSet)
+	report.MessagesPerPass = report.Records * workers
+	if rate > 0 {
+		report.PassDuration = time.Duration(float64(report.Records) / rate * float64(time.Second))
+	}
+	return report, rows.close()
+}
+
+// dryRun accumulates a DryRunReport over the input files
+type dryRun struct {
+	report   *DryRunReport
+	headers  []string
+	topics   *topicTemplate
+	rows     *rowReport
+	types    []map[string]int // value types seen per column
+	hosts    map[string]bool
+	gpus     map[string]bool
+	topicSet map[string]bool
+}
+
+// file profiles one input file
+func (d *dryRun) file(path string, multiFile bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to access CSV file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := newCSVReader(file)
+	if _, err := reader.Read(); err != nil {
+		return fmt.Errorf("failed to read CSV headers: %w", err)
+	}
+
+	for {
+		record, err := readRow(reader, d.headers)
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			if multiFile {
+				rowErr.File = path
+			}
+			if _, err := d.rows.add(rowErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV %s: %w", path, err)
+		}
+
+		d.report.Records++
+		d.topicSet[d.topics.resolve(record)] = true
+		for i, value := range record {
+			d.types[i][valueType(value)]++
+		}
+
+		data, err := recordToTelemetry(d.headers, record)
+		if err != nil {
+			continue
+		}
+		if host, ok := data.Fields["Hostname"].(string); ok && host != "" {
+			d.hosts[host] = true
+		}
+		if gpu := gpuKey(data.Fields); gpu != "" {
+			d.gpus[gpu] = true
+		} else {
+			d.report.MissingGPU++
+		}
+	}
+}
+
+// valueType types a CSV value the way recordToTelemetry does
+func valueType(value string) string {
+	if value == "" {
+		return columnEmpty
+	}
+	if _, err := parseBool(value); err == nil {
+		return columnBool
+	}
+	if _, err := parseFloat(value); err == nil {
+		return columnNumber
+	}
+	return columnString
+}
+
+// summariseColumn infers a column's type from the counts of each value type
+func summariseColumn(name string, counts map[string]int) ColumnSummary {
+	summary := ColumnSummary{Name: name, Type: columnEmpty, Empty: counts[columnEmpty]}
+	for _, kind := range []string{columnBool, columnNumber, columnString} {
+		if counts[kind] == 0 {
+			continue
+		}
+		if summary.Type != columnEmpty {
+			summary.Type = columnMixed
+			break
+		}
+		summary.Type = kind
+	}
+	return summary
+}
+
+// gpuKey returns the GPU a published record belongs to, identified as the
+// collector does: by uuid if the record has one, otherwise by gpu_id
+func gpuKey(fields map[string]interface{}) string {
+	if uuid, ok := fields["uuid"]; ok {
+		id, _ := uuid.(string)
+		return id
+	}
+	switch id := fields["gpu_id"].(type) {
+	case string:
+		return id
+	case float64:
+		return fmt.Sprintf("gpu-%03.0f", id)
+	}
+	return ""
+}
+
+// Print writes the report for a person to read
+func (r *DryRunReport) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Dry run of %s (nothing was published)\n", r.File)
+	if r.Files > 1 {
+		fmt.Fprintf(tw, "Files:\t%d\n", r.Files)
+	}
+	fmt.Fprintf(tw, "Records:\t%d\n", r.Records)
+	if r.Malformed > 0 {
+		fmt.Fprintf(tw, "Malformed rows:\t%s\n", r.malformedSummary)
+	} else {
+		fmt.Fprintf(tw, "Malformed rows:\t0\n")
+	}
+	fmt.Fprintf(tw, "Hosts:\t%d\n", r.Hosts)
+	fmt.Fprintf(tw, "GPUs:\t%d\n", r.GPUs)
+	fmt.Fprintf(tw, "Topics:\t%d\n", r.Topics)
+	if r.MissingGPU > 0 {
+		fmt.Fprintf(tw, "Rows without uuid or gpu_id:\t%d (rejected by the collector)\n", r.MissingGPU)
+	}
+	fmt.Fprintf(tw, "Publish time per pass:\t%s at %g msg/s per worker (%d messages from %d workers)\n",
+		r.PassDuration.Round(time.Second), r.Rate, r.MessagesPerPass, r.Workers)
+	fmt.Fprintf(tw, "Columns:\n")
+	for _, column := range r.Columns {
+		if column.Empty > 0 && column.Type != columnEmpty {
+			fmt.Fprintf(tw, "  %s\t%s (%d empty)\n", column.Name, column.Type, column.Empty)
+		} else {
+			fmt.Fprintf(tw, "  %s\t%s\n", column.Name, column.Type)
+		}
+	}
+	return tw.Flush()
+}
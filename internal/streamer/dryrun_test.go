@@ -0,0 +1,69 @@
+package streamer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRun(t *testing.T) {
+	csvPath := createTestCSVWithContent(t, "timestamp,gpu_id,uuid,Hostname,value,healthy,note\n"+
+		"2025-07-18T20:42:34Z,0,GPU-a,host-1,10,true,\n"+
+		"2025-07-18T20:42:34Z,1,GPU-b,host-1,n/a,false,\n"+
+		"2025-07-18T20:42:34Z,0,GPU-a,host-2,12,true,ok\n"+
+		"bad,row\n"+
+		"2025-07-18T20:42:34Z,2,,host-2,13,true,\n")
+	errorFile := filepath.Join(t.TempDir(), "errors.txt")
+
+	report, err := DryRun(csvPath, "telemetry.{hostname}", 2, 2.0, errorFile)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+
+	if report.Records != 4 || report.Malformed != 1 {
+		t.Errorf("Expected 4 records and 1 malformed row, got %d and %d", report.Records, report.Malformed)
+	}
+	// A uuid column identifies GPUs even where it is empty, as in the collector
+	if report.Hosts != 2 || report.GPUs != 2 || report.MissingGPU != 1 || report.Topics != 2 {
+		t.Errorf("Expected 2 hosts and topics, 2 GPUs and 1 row without one, got %+v", report)
+	}
+	if report.MessagesPerPass != 8 || report.PassDuration != 2*time.Second {
+		t.Errorf("Expected 8 messages over 2s per pass, got %d over %s", report.MessagesPerPass, report.PassDuration)
+	}
+
+	types := map[string]string{}
+	for _, column := range report.Columns {
+		types[column.Name] = column.Type
+	}
+	want := map[string]string{
+		"timestamp": "string", "gpu_id": "number", "uuid": "string", "Hostname": "string",
+		"value": "mixed", "healthy": "bool", "note": "string",
+	}
+	for name, typ := range want {
+		if types[name] != typ {
+			t.Errorf("Expected column %s to be %s, got %q", name, typ, types[name])
+		}
+	}
+
+	if data, _ := os.ReadFile(errorFile); !strings.HasPrefix(string(data), "line 5: column count") {
+		t.Errorf("Expected the malformed row in the error file, got %q", data)
+	}
+
+	var out strings.Builder
+	if err := report.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"Records:", "1 malformed rows (column count: 1)", "value", "mixed"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %q in the printed report:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestDryRun_MissingFile(t *testing.T) {
+	if _, err := DryRun(filepath.Join(t.TempDir(), "missing.csv"), "telemetry", 1, 1.0, ""); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,171 @@
+package streamer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// exporterHeaders are the columns of records built from exporter samples,
+// matching DCGM CSV exports so the collector handles both alike
+var exporterHeaders = []string{"timestamp", "metric_name", "gpu_id", "device", "uuid", "modelName", "Hostname", "container", "pod", "namespace", "value", "labels_raw"}
+
+// exporterColumns maps exporter labels to record columns
+var exporterColumns = map[string]string{
+	"gpu":       "gpu_id",
+	"device":    "device",
+	"UUID":      "uuid",
+	"modelName": "modelName",
+	"Hostname":  "Hostname",
+	"container": "container",
+	"pod":       "pod",
+	"namespace": "namespace",
+}
+
+// scrapeExporter fetches a DCGM exporter's metrics from url and converts its
+// GPU samples to telemetry stamped with now
+func scrapeExporter(ctx context.Context, client *http.Client, url string, now time.Time) ([]*TelemetryData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scrape request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape of %s failed with status %d", url, resp.StatusCode)
+	}
+	return parseExposition(resp.Body, now)
+}
+
+// parseExposition converts the samples of a Prometheus text exposition that
+// carry a gpu or UUID label to telemetry stamped with now. Other samples,
+// such as the exporter's own process metrics, are skipped.
+func parseExposition(r io.Reader, now time.Time) ([]*TelemetryData, error) {
+	var samples []*TelemetryData
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, labels, value, err := parseSampleLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if labels["gpu"] == "" && labels["UUID"] == "" {
+			continue
+		}
+
+		columns := map[string]string{
+			"timestamp":   now.UTC().Format(time.RFC3339),
+			"metric_name": name,
+			"value":       value,
+			"labels_raw":  rawLabels(name, labels),
+		}
+		for label, column := range exporterColumns {
+			columns[column] = labels[label]
+		}
+		record := make([]string, len(exporterHeaders))
+		for i, header := range exporterHeaders {
+			record[i] = columns[header]
+		}
+
+		telemetry, err := recordToTelemetry(exporterHeaders, record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		telemetry.Timestamp = now
+		samples = append(samples, telemetry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read exposition: %w", err)
+	}
+	return samples, nil
+}
+
+// parseSampleLine splits `name{label="value",...} value [timestamp]`
+func parseSampleLine(line string) (string, map[string]string, string, error) {
+	labels := make(map[string]string)
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return "", nil, "", fmt.Errorf("malformed sample %q", line)
+	}
+	name, rest := line[:end], line[end:]
+
+	if strings.HasPrefix(rest, "{") {
+		rest = rest[1:]
+		for {
+			rest = strings.TrimLeft(rest, " \t,")
+			if strings.HasPrefix(rest, "}") {
+				rest = rest[1:]
+				break
+			}
+			eq := strings.IndexByte(rest, '=')
+			if eq <= 0 || len(rest) < eq+2 || rest[eq+1] != '"' {
+				return "", nil, "", fmt.Errorf("malformed labels in %q", line)
+			}
+			label := strings.TrimSpace(rest[:eq])
+			value, remainder, ok := unquoteLabel(rest[eq+2:])
+			if !ok {
+				return "", nil, "", fmt.Errorf("unterminated label value in %q", line)
+			}
+			labels[label] = value
+			rest = remainder
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, "", fmt.Errorf("missing value in %q", line)
+	}
+	return name, labels, fields[0], nil
+}
+
+// unquoteLabel reads an escaped label value up to its closing quote,
+// returning the value and what follows the quote
+func unquoteLabel(s string) (string, string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), s[i+1:], true
+		case '\\':
+			if i+1 == len(s) {
+				return "", "", false
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
+
+// rawLabels formats labels as in the labels_raw column of DCGM CSV exports
+func rawLabels(name string, labels map[string]string) string {
+	pairs := make([]string, 0, len(labels)+1)
+	pairs = append(pairs, fmt.Sprintf("__name__=%q", name))
+	for label, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", label, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
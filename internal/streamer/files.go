@@ -0,0 +1,99 @@
+package streamer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrHeaderMismatch is returned when the files of a multi-file input do not
+// share the same header
+var ErrHeaderMismatch = errors.New("CSV headers differ between files")
+
+// ResolveCSVFiles expands a --csv-file value, a comma-separated list of paths
+// and globs such as data/*.csv, into the files to stream in order. Each glob's
+// matches are sorted, so dated daily exports replay in date order.
+func ResolveCSVFiles(spec string) ([]string, error) {
+	var files []string
+	for _, pattern := range strings.Split(spec, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			files = append(files, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV file pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no CSV files match %q", pattern)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no CSV files given")
+	}
+	return files, nil
+}
+
+// readCSVHeader reads the header row of a CSV file
+func readCSVHeader(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return newCSVReader(file).Read()
+}
+
+// readCSVHeaders reads the header of every file, returning the header they
+// share or an error describing the first file whose header differs
+func readCSVHeaders(files []string) ([]string, error) {
+	var headers []string
+	for i, path := range files {
+		header, err := readCSVHeader(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if i == 0 {
+			headers = header
+			continue
+		}
+		if diff := headerDifference(headers, header); diff != "" {
+			return nil, fmt.Errorf("%w: %s %s compared with %s", ErrHeaderMismatch, path, diff, files[0])
+		}
+	}
+	return headers, nil
+}
+
+// headerDifference describes how header differs from want, or returns ""
+// if they are the same
+func headerDifference(want, header []string) string {
+	for i := 0; i < min(len(want), len(header)); i++ {
+		if want[i] != header[i] {
+			return fmt.Sprintf("has column %d %q where %q was expected", i+1, header[i], want[i])
+		}
+	}
+	switch {
+	case len(header) > len(want):
+		return fmt.Sprintf("has extra columns %v", header[len(want):])
+	case len(header) < len(want):
+		return fmt.Sprintf("is missing columns %v", want[len(header):])
+	}
+	return ""
+}
+
+// inputFiles returns the files the streamer reads, in order
+func (s *Streamer) inputFiles() []string {
+	if s.files == nil {
+		return []string{s.csvPath}
+	}
+	return s.files
+}
@@ -0,0 +1,142 @@
+package streamer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCSVFiles writes each file's content into a temporary directory
+func writeCSVFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolveCSVFiles(t *testing.T) {
+	dir := writeCSVFiles(t, map[string]string{
+		"2025-07-02.csv": "", "2025-07-01.csv": "", "notes.txt": "",
+	})
+	join := func(names ...string) string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, name)
+		}
+		return strings.Join(paths, ",")
+	}
+
+	files, err := ResolveCSVFiles(filepath.Join(dir, "*.csv") + ", " + join("notes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(files, ","); got != join("2025-07-01.csv", "2025-07-02.csv", "notes.txt") {
+		t.Errorf("Expected sorted glob matches then the listed file, got %v", files)
+	}
+
+	// Plain paths are kept for Start to report if they are missing
+	if files, err := ResolveCSVFiles("missing.csv"); err != nil || len(files) != 1 {
+		t.Errorf("Expected a plain path kept as is, got %v, %v", files, err)
+	}
+	for _, invalid := range []string{filepath.Join(dir, "*.json"), " , ", "["} {
+		if _, err := ResolveCSVFiles(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestReadCSVHeaders_Mismatch(t *testing.T) {
+	dir := writeCSVFiles(t, map[string]string{
+		"a.csv": "id,value\n1,100\n",
+		"b.csv": "id,value\n2,200\n",
+		"c.csv": "id,metric\n3,300\n",
+		"d.csv": "id,value,extra\n4,400,x\n",
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	if headers, err := readCSVHeaders([]string{path("a.csv"), path("b.csv")}); err != nil || len(headers) != 2 {
+		t.Errorf("Expected matching headers to be accepted, got %v, %v", headers, err)
+	}
+
+	_, err := readCSVHeaders([]string{path("a.csv"), path("c.csv")})
+	if !errors.Is(err, ErrHeaderMismatch) || !strings.Contains(err.Error(), `column 2 "metric" where "value" was expected`) {
+		t.Errorf("Expected the differing column named, got %v", err)
+	}
+	_, err = readCSVHeaders([]string{path("a.csv"), path("d.csv")})
+	if !errors.Is(err, ErrHeaderMismatch) || !strings.Contains(err.Error(), "extra columns [extra]") {
+		t.Errorf("Expected the extra column named, got %v", err)
+	}
+
+	broker := NewMockBroker()
+	defer broker.Close()
+	streamer := NewStreamer(path("a.csv")+","+path("c.csv"), 1, 100.0, "test-topic", broker)
+	if err := streamer.Start(); !errors.Is(err, ErrHeaderMismatch) {
+		streamer.Stop()
+		t.Errorf("Expected Start to refuse files with different headers, got %v", err)
+	}
+}
+
+func TestStreamer_MultipleFiles(t *testing.T) {
+	dir := writeCSVFiles(t, map[string]string{
+		"day-1.csv": "id,value\n1,100\n2,200\n",
+		"day-2.csv": "id,value\n3,300\nbad\n4,400\n",
+	})
+	errorFile := filepath.Join(t.TempDir(), "errors.txt")
+	broker := NewMockBroker()
+	defer broker.Close()
+
+	streamer := NewStreamer(filepath.Join(dir, "day-*.csv"), 1, 500.0, "test-topic", broker)
+	streamer.SetErrorFile(errorFile)
+	if err := streamer.Start(); err != nil {
+		t.Fatalf("Failed to start streamer: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	streamer.Stop()
+
+	messages := broker.GetMessages()
+	if len(messages) < 5 {
+		t.Fatalf("Expected both files streamed and looped, got %d messages", len(messages))
+	}
+	for i, want := range []float64{1, 2, 3, 4, 1} {
+		var data TelemetryData
+		if err := json.Unmarshal(messages[i].Payload, &data); err != nil {
+			t.Fatal(err)
+		}
+		if data.Fields["id"] != want {
+			t.Errorf("Message %d: expected id %v, got %v", i, want, data.Fields["id"])
+		}
+	}
+	if loops := streamer.Stats().Loops; loops == 0 {
+		t.Error("Expected a pass over both files to count as a loop")
+	}
+
+	data, err := os.ReadFile(errorFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "day-2.csv") + " line 3: column count"; !strings.HasPrefix(string(data), want) {
+		t.Errorf("Expected the malformed row attributed to its file, got %q", data)
+	}
+}
+
+func TestDryRun_MultipleFiles(t *testing.T) {
+	dir := writeCSVFiles(t, map[string]string{
+		"a.csv": "Hostname,value\nhost-1,1\n",
+		"b.csv": "Hostname,value\nhost-2,2\nhost-3,3\n",
+	})
+	report, err := DryRun(filepath.Join(dir, "*.csv"), "telemetry", 1, 1.0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 2 || report.Records != 3 || report.Hosts != 3 {
+		t.Errorf("Expected 3 records and hosts across 2 files, got %+v", report)
+	}
+}
@@ -0,0 +1,50 @@
+package streamer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/harishb93/telemetry-pipeline/internal/testdata"
+)
+
+// TestRecordToTelemetry_Golden checks the records parsed from the shared DCGM
+// dataset against its golden, which the collector and API tests consume
+func TestRecordToTelemetry_Golden(t *testing.T) {
+	file, err := os.Open(testdata.WriteCSV(t, testdata.Basic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := newCSVReader(file)
+	headers, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []testdata.Record
+	for {
+		record, err := readRow(reader, headers)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := recordToTelemetry(headers, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Pin the processing time to the row's sample time
+		timestamp, err := time.Parse(time.RFC3339, data.Fields["timestamp"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, testdata.Record{Timestamp: timestamp, Fields: data.Fields})
+	}
+
+	testdata.AssertGolden(t, testdata.Basic+".records", records)
+}
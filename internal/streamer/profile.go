@@ -0,0 +1,178 @@
+package streamer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPaceStep bounds how long a worker waits before re-reading a profile's
+// rate, so a low rate does not hold a worker back once the rate rises
+const maxPaceStep = 100 * time.Millisecond
+
+// RatePoint sets the rate, in messages per second per worker, at an offset
+// from the start of streaming
+type RatePoint struct {
+	At   string  `json:"at"`
+	Rate float64 `json:"rate"`
+}
+
+// RateProfileFile is the format of --rate-profile files. The rate changes
+// linearly between points, and is held before the first and after the last;
+// two points at the same offset make a step. With Repeat the profile starts
+// over after that long, such as every 24h for a diurnal curve.
+type RateProfileFile struct {
+	Repeat string      `json:"repeat,omitempty"`
+	Points []RatePoint `json:"points"`
+}
+
+// RateProfile is a rate that varies over time
+type RateProfile struct {
+	points []profilePoint
+	repeat time.Duration
+}
+
+type profilePoint struct {
+	at   time.Duration
+	rate float64
+}
+
+// NewRateProfile validates and compiles a profile
+func NewRateProfile(file RateProfileFile) (*RateProfile, error) {
+	if len(file.Points) == 0 {
+		return nil, fmt.Errorf("rate profile has no points")
+	}
+	profile := &RateProfile{}
+	publishes := false
+	for i, point := range file.Points {
+		at, err := time.ParseDuration(point.At)
+		if err != nil {
+			return nil, fmt.Errorf("rate profile point %d: invalid offset %q: %w", i, point.At, err)
+		}
+		if at < 0 || (i > 0 && at < profile.points[i-1].at) {
+			return nil, fmt.Errorf("rate profile point %d: offsets must not be negative or decrease", i)
+		}
+		if point.Rate < 0 || math.IsNaN(point.Rate) || math.IsInf(point.Rate, 0) {
+			return nil, fmt.Errorf("rate profile point %d: invalid rate %v", i, point.Rate)
+		}
+		publishes = publishes || point.Rate > 0
+		profile.points = append(profile.points, profilePoint{at: at, rate: point.Rate})
+	}
+	if !publishes {
+		return nil, fmt.Errorf("rate profile never publishes: every rate is 0")
+	}
+	if file.Repeat != "" {
+		repeat, err := time.ParseDuration(file.Repeat)
+		if err != nil {
+			return nil, fmt.Errorf("rate profile: invalid repeat %q: %w", file.Repeat, err)
+		}
+		if last := profile.points[len(profile.points)-1].at; repeat <= 0 || repeat < last {
+			return nil, fmt.Errorf("rate profile: repeat %s must be positive and not before the last point (%s)", repeat, last)
+		}
+		profile.repeat = repeat
+	}
+	return profile, nil
+}
+
+// LoadRateProfile reads a JSON rate profile file
+func LoadRateProfile(path string) (*RateProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate profile: %w", err)
+	}
+	var file RateProfileFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rate profile %s: %w", path, err)
+	}
+	return NewRateProfile(file)
+}
+
+// ParseRamp parses a ramp such as "0..500msg/s@10m": the rate rises linearly
+// from 0 to 500 messages per second per worker over ten minutes, then holds
+func ParseRamp(spec string) (*RateProfile, error) {
+	rates, duration, ok := strings.Cut(spec, "@")
+	if !ok {
+		return nil, fmt.Errorf("invalid ramp %q: expected FROM..TO[msg/s]@DURATION", spec)
+	}
+	from, to, ok := strings.Cut(strings.TrimSuffix(rates, "msg/s"), "..")
+	if !ok {
+		return nil, fmt.Errorf("invalid ramp %q: expected FROM..TO[msg/s]@DURATION", spec)
+	}
+	fromRate, err := strconv.ParseFloat(from, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ramp %q: start rate: %w", spec, err)
+	}
+	toRate, err := strconv.ParseFloat(to, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ramp %q: end rate: %w", spec, err)
+	}
+	return NewRateProfile(RateProfileFile{Points: []RatePoint{
+		{At: "0s", Rate: fromRate},
+		{At: duration, Rate: toRate},
+	}})
+}
+
+// rateAt returns the rate elapsed after the start of streaming
+func (p *RateProfile) rateAt(elapsed time.Duration) float64 {
+	if p.repeat > 0 {
+		elapsed %= p.repeat
+	}
+	// The last point at or before elapsed; later points at the same offset
+	// override earlier ones
+	i := -1
+	for i+1 < len(p.points) && p.points[i+1].at <= elapsed {
+		i++
+	}
+	if i < 0 {
+		return p.points[0].rate
+	}
+	if i == len(p.points)-1 {
+		return p.points[i].rate
+	}
+	from, to := p.points[i], p.points[i+1]
+	progress := float64(elapsed-from.at) / float64(to.at-from.at)
+	return from.rate + (to.rate-from.rate)*progress
+}
+
+// SetRateProfile makes the streamer's rate per worker follow profile,
+// measured from Start, instead of the constant rate it was created with
+func (s *Streamer) SetRateProfile(profile *RateProfile) {
+	s.profile = profile
+}
+
+// currentRate returns the rate per worker now
+func (s *Streamer) currentRate() float64 {
+	if s.profile == nil {
+		return s.rate
+	}
+	return s.profile.rateAt(time.Since(s.startedAt))
+}
+
+// pace waits between messages: rateInterval at a constant rate, or until
+// the profile's rate, re-read every maxPaceStep, has accrued one message
+func (s *Streamer) pace(rateInterval time.Duration) {
+	if s.profile == nil {
+		if rateInterval > 0 {
+			time.Sleep(rateInterval)
+		}
+		return
+	}
+
+	for credit := 0.0; credit < 1-1e-9; {
+		rate := s.currentRate()
+		step := maxPaceStep
+		if rate > 0 {
+			step = min(step, time.Duration(math.Ceil((1-credit)/rate*float64(time.Second))))
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(step):
+		}
+		credit += rate * step.Seconds()
+	}
+}
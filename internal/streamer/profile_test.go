@@ -0,0 +1,114 @@
+package streamer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRateProfile_RateAt(t *testing.T) {
+	profile, err := NewRateProfile(RateProfileFile{
+		Repeat: "24h",
+		Points: []RatePoint{
+			{At: "1h", Rate: 10},
+			{At: "3h", Rate: 30},
+			{At: "3h", Rate: 100},
+			{At: "4h", Rate: 100},
+			{At: "4h", Rate: 20},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{0, 10},                  // Held before the first point
+		{2 * time.Hour, 20},      // Halfway between 10 and 30
+		{3 * time.Hour, 100},     // The later point at a step wins
+		{210 * time.Minute, 100}, // During the spike
+		{5 * time.Hour, 20},      // Held after the last point
+		{26 * time.Hour, 20},     // Repeats daily
+		{24*time.Hour + 30*time.Minute, 10},
+	}
+	for _, tt := range tests {
+		if got := profile.rateAt(tt.elapsed); got != tt.want {
+			t.Errorf("rateAt(%s) = %v, want %v", tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestParseRamp(t *testing.T) {
+	profile, err := ParseRamp("0..500msg/s@10m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for elapsed, want := range map[time.Duration]float64{0: 0, 5 * time.Minute: 250, time.Hour: 500} {
+		if got := profile.rateAt(elapsed); got != want {
+			t.Errorf("rateAt(%s) = %v, want %v", elapsed, got, want)
+		}
+	}
+	if _, err := ParseRamp("1.5..2@30s"); err != nil {
+		t.Errorf("Expected the msg/s suffix to be optional, got %v", err)
+	}
+
+	for _, invalid := range []string{"0..500msg/s", "500msg/s@10m", "a..5@1m", "0..5@soon", "0..0@1m", "-1..5@1m"} {
+		if _, err := ParseRamp(invalid); err == nil {
+			t.Errorf("Expected ramp %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestLoadRateProfile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if _, err := LoadRateProfile(write("ok.json", `{"repeat":"1h","points":[{"at":"0s","rate":1},{"at":"30m","rate":5}]}`)); err != nil {
+		t.Errorf("Expected a valid profile to load, got %v", err)
+	}
+	invalid := map[string]string{
+		"empty.json":      `{"points":[]}`,
+		"decreasing.json": `{"points":[{"at":"10m","rate":1},{"at":"5m","rate":2}]}`,
+		"repeat.json":     `{"repeat":"5m","points":[{"at":"0s","rate":1},{"at":"10m","rate":2}]}`,
+		"syntax.json":     `{"points":`,
+	}
+	for name, content := range invalid {
+		if _, err := LoadRateProfile(write(name, content)); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestStreamer_RateProfile(t *testing.T) {
+	broker := NewMockBroker()
+	defer broker.Close()
+
+	// The profile's 1000 msg/s, not the constant 1 msg/s, paces the worker
+	streamer := NewStreamer(createTestCSVWithContent(t, "id,value\n1,100\n2,200\n"), 1, 1.0, "test-topic", broker)
+	profile, err := ParseRamp("1000..1000@1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamer.SetRateProfile(profile)
+	if err := streamer.Start(); err != nil {
+		t.Fatalf("Failed to start streamer: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	streamer.Stop()
+
+	if n := len(broker.GetMessages()); n < 10 {
+		t.Errorf("Expected the profile's rate to apply, got %d messages", n)
+	}
+	if target := streamer.Stats().TargetRate; target != 1000 {
+		t.Errorf("Expected the target rate to follow the profile, got %v", target)
+	}
+}
@@ -0,0 +1,78 @@
+package streamer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// Provenance labels record where a published record came from. The collector
+// stores them with the sample, so a bad value returned by the API can be
+// traced to the streamer and the file row or exporter that produced it.
+const (
+	// LabelInstance is the streamer instance that published the record
+	LabelInstance = "source_instance"
+	// LabelFile is the CSV file the record was read from
+	LabelFile = "source_file"
+	// LabelRow is the record's line in LabelFile
+	LabelRow = "source_row"
+	// LabelTarget is the exporter URL the sample was scraped from
+	LabelTarget = "source_target"
+)
+
+// Message headers carrying where a message was published from, so routing
+// rules and consumers can tell sources apart without decoding payloads
+const (
+	// HeaderSourceHost is the host of the streamer or edge agent
+	HeaderSourceHost = "x-source-host"
+	// HeaderSourceFile is the base name of the CSV file the record was read
+	// from
+	HeaderSourceFile = "x-source-file"
+	// HeaderWorkerID is the streamer worker that published the record
+	HeaderWorkerID = "x-worker-id"
+)
+
+// DefaultInstance names a streamer instance after its host
+func DefaultInstance() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "unknown"
+	}
+	return host
+}
+
+// fileProvenance labels a record read from line of the CSV file at path
+func fileProvenance(instance, path string, line int) map[string]string {
+	return map[string]string{
+		LabelInstance: instance,
+		LabelFile:     path,
+		LabelRow:      strconv.Itoa(line),
+	}
+}
+
+// targetProvenance labels a sample scraped from the exporter at url
+func targetProvenance(instance, url string) map[string]string {
+	return map[string]string{
+		LabelInstance: instance,
+		LabelTarget:   url,
+	}
+}
+
+// sourceHeaders returns the headers of a message published from host
+func sourceHeaders(host string) map[string]string {
+	return map[string]string{
+		mq.HeaderSchemaVersion: SchemaVersion,
+		HeaderSourceHost:       host,
+	}
+}
+
+// fileHeaders returns the headers of a message a streamer worker on host
+// published from a record of the CSV file at path
+func fileHeaders(host, path string, workerID int) map[string]string {
+	headers := sourceHeaders(host)
+	headers[HeaderSourceFile] = filepath.Base(path)
+	headers[HeaderWorkerID] = strconv.Itoa(workerID)
+	return headers
+}
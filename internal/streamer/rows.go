@@ -0,0 +1,199 @@
+package streamer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of malformed CSV rows
+const (
+	rowErrSyntax  = "csv syntax"
+	rowErrColumns = "column count"
+)
+
+// RowError describes a malformed CSV row. Record is nil when the row could
+// not be split into fields. File is only set when streaming several files.
+type RowError struct {
+	File   string
+	Line   int
+	Kind   string
+	Err    error
+	Record []string
+}
+
+func (e *RowError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s line %d: %s: %v", e.File, e.Line, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Kind, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// newCSVReader creates a reader that leaves column counts to readRow, so a
+// row of the wrong width can be skipped rather than ending the pass
+func newCSVReader(file *os.File) *csv.Reader {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// readRow reads the next record. A malformed row that the reader can continue
+// past is returned as a *RowError; other errors, including io.EOF, as is.
+func readRow(reader *csv.Reader, headers []string) ([]string, error) {
+	record, err := reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &RowError{Line: parseErr.StartLine, Kind: rowErrSyntax, Err: parseErr.Err}
+		}
+		return nil, err
+	}
+	if len(record) != len(headers) {
+		line, _ := reader.FieldPos(0)
+		return nil, &RowError{
+			Line:   line,
+			Kind:   rowErrColumns,
+			Err:    fmt.Errorf("expected %d columns, got %d", len(headers), len(record)),
+			Record: record,
+		}
+	}
+	return record, nil
+}
+
+// validateCSV checks every row of a CSV file, returning the first malformed
+// one
+func validateCSV(path string, headers []string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := newCSVReader(file)
+	if _, err := reader.Read(); err != nil {
+		return err
+	}
+	for {
+		if _, err := readRow(reader, headers); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// rowReport collects the malformed rows skipped while streaming, counting
+// each row once however many workers and passes meet it, and writes them to
+// an error file if one is configured
+type rowReport struct {
+	mu     sync.Mutex
+	file   *os.File
+	seen   map[rowPosition]bool
+	counts map[string]int
+}
+
+// rowPosition identifies a row of the input
+type rowPosition struct {
+	file string
+	line int
+}
+
+// openRowReport creates a report, writing to path unless it is empty
+func openRowReport(path string) (*rowReport, error) {
+	report := &rowReport{seen: make(map[rowPosition]bool), counts: make(map[string]int)}
+	if path == "" {
+		return report, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create error file: %w", err)
+	}
+	report.file = file
+	return report, nil
+}
+
+// add records a malformed row, returning whether it was new
+func (r *rowReport) add(rowErr *RowError) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	position := rowPosition{file: rowErr.File, line: rowErr.Line}
+	if r.seen[position] {
+		return false, nil
+	}
+	r.seen[position] = true
+	r.counts[rowErr.Kind]++
+
+	if r.file == nil {
+		return true, nil
+	}
+	line := rowErr.Error()
+	if rowErr.Record != nil {
+		line += ": " + formatRecord(rowErr.Record)
+	}
+	if _, err := fmt.Fprintln(r.file, line); err != nil {
+		return true, fmt.Errorf("failed to write error file: %w", err)
+	}
+	return true, nil
+}
+
+// skipped returns the number of malformed rows
+func (r *rowReport) skipped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.seen)
+}
+
+// summary describes the malformed rows by kind, e.g. "3 malformed rows
+// (column count: 2, csv syntax: 1)"
+func (r *rowReport) summary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kinds := make([]string, 0, len(r.counts))
+	for kind, count := range r.counts {
+		kinds = append(kinds, fmt.Sprintf("%s: %d", kind, count))
+	}
+	sort.Strings(kinds)
+	summary := fmt.Sprintf("%d malformed rows", len(r.seen))
+	if len(kinds) > 0 {
+		summary += " (" + strings.Join(kinds, ", ") + ")"
+	}
+	return summary
+}
+
+// close writes the summary to the error file and closes it
+func (r *rowReport) close() error {
+	if r.file == nil {
+		return nil
+	}
+	summary := r.summary()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := fmt.Fprintf(r.file, "# summary: %s\n", summary)
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	return err
+}
+
+// formatRecord renders record as a CSV line
+func formatRecord(record []string) string {
+	var b strings.Builder
+	writer := csv.NewWriter(&b)
+	_ = writer.Write(record)
+	writer.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,92 @@
+package streamer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const malformedCSV = "id,value\n1,100\ninvalid,csv,too,many\n2,ba\"d\n3,300\n"
+
+func TestStreamer_SkipsMalformedRowsToErrorFile(t *testing.T) {
+	csvPath := createTestCSVWithContent(t, malformedCSV)
+	errorFile := filepath.Join(t.TempDir(), "errors.txt")
+
+	broker := NewMockBroker()
+	defer broker.Close()
+
+	// Two workers each meet every bad row, repeatedly
+	streamer := NewStreamer(csvPath, 2, 200.0, "test-topic", broker)
+	streamer.SetErrorFile(errorFile)
+	if err := streamer.Start(); err != nil {
+		t.Fatalf("Failed to start streamer: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	streamer.Stop()
+
+	if streamer.Err() != nil {
+		t.Errorf("Expected a lenient streamer to keep going, got %v", streamer.Err())
+	}
+	ids := map[string]bool{}
+	for _, msg := range broker.GetMessages() {
+		if strings.Contains(string(msg.Payload), `"id":3`) {
+			ids["3"] = true
+		}
+		if strings.Contains(string(msg.Payload), "invalid") {
+			t.Fatalf("Expected malformed rows to be skipped, got %s", msg.Payload)
+		}
+	}
+	if !ids["3"] {
+		t.Error("Expected rows after malformed ones to be streamed")
+	}
+
+	data, err := os.ReadFile(errorFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "line 3: column count: expected 2 columns, got 4: invalid,csv,too,many\n" +
+		"line 4: csv syntax: bare \" in non-quoted-field\n" +
+		"# summary: 2 malformed rows (column count: 1, csv syntax: 1)\n"
+	if string(data) != want {
+		t.Errorf("Unexpected error file:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestStreamer_StrictRejectsMalformedFile(t *testing.T) {
+	csvPath := createTestCSVWithContent(t, malformedCSV)
+	errorFile := filepath.Join(t.TempDir(), "errors.txt")
+
+	broker := NewMockBroker()
+	defer broker.Close()
+
+	streamer := NewStreamer(csvPath, 1, 10.0, "test-topic", broker)
+	streamer.SetStrict(true)
+	streamer.SetErrorFile(errorFile)
+
+	err := streamer.Start()
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || rowErr.Line != 3 || rowErr.Kind != rowErrColumns {
+		t.Fatalf("Expected line 3 rejected for its column count, got %v", err)
+	}
+	if n := len(broker.GetMessages()); n != 0 {
+		t.Errorf("Expected nothing published from a rejected file, got %d messages", n)
+	}
+	if data, _ := os.ReadFile(errorFile); !strings.HasPrefix(string(data), "line 3: column count") {
+		t.Errorf("Expected the rejected row in the error file, got %q", data)
+	}
+
+	// A well-formed file streams as usual
+	valid := NewStreamer(createTestCSVWithContent(t, "id,value\n1,100\n"), 1, 100.0, "test-topic", broker)
+	valid.SetStrict(true)
+	if err := valid.Start(); err != nil {
+		t.Fatalf("Expected a valid file to pass strict mode, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	valid.Stop()
+	if len(broker.GetMessages()) == 0 {
+		t.Error("Expected messages from the valid file")
+	}
+}
@@ -0,0 +1,310 @@
+package streamer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// spoolSegmentBytes is the size at which the spool starts a new segment file
+const spoolSegmentBytes = 4 << 20
+
+// spoolCursorFile records how far the spool has been forwarded
+const spoolCursorFile = "cursor"
+
+// spoolSegment is one file of the spool
+type spoolSegment struct {
+	seq  uint64
+	size int64
+}
+
+// spool is an on-disk FIFO of message payloads, so an edge agent keeps what
+// it scraped across uplink outages and restarts. Payloads are appended as
+// lines to numbered segment files; a cursor file records the forwarded
+// position, and fully forwarded segments are deleted. Once the spool exceeds
+// maxBytes its oldest segments are dropped. A spool is not safe for
+// concurrent use.
+type spool struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+
+	segments []*spoolSegment // oldest first
+	writer   *os.File        // appends to the last segment
+	readFile *os.File        // reads the first segment
+	reader   *bufio.Reader
+	offset   int64  // bytes of the first segment already forwarded
+	pending  []byte // line returned by peek and not yet committed
+	dropped  int64  // bytes dropped unforwarded to stay within maxBytes
+}
+
+// openSpool opens the spool in dir, creating it if needed; maxBytes of zero
+// is unlimited
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	s := &spool{dir: dir, maxBytes: maxBytes, segmentBytes: spoolSegmentBytes}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+	for _, entry := range entries {
+		var seq uint64
+		if _, err := fmt.Sscanf(entry.Name(), "segment-%d.log", &seq); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat spool segment: %w", err)
+		}
+		s.segments = append(s.segments, &spoolSegment{seq: seq, size: info.Size()})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+
+	if err := s.repairTail(); err != nil {
+		return nil, err
+	}
+	if err := s.loadCursor(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// segmentPath returns the path of segment seq
+func (s *spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("segment-%020d.log", seq))
+}
+
+// repairTail truncates a line left partially written by a crash from the
+// last segment
+func (s *spool) repairTail() error {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	last := s.segments[len(s.segments)-1]
+	data, err := os.ReadFile(s.segmentPath(last.seq))
+	if err != nil {
+		return fmt.Errorf("failed to read spool segment: %w", err)
+	}
+	complete := int64(bytes.LastIndexByte(data, '\n') + 1)
+	if complete == last.size {
+		return nil
+	}
+	if err := os.Truncate(s.segmentPath(last.seq), complete); err != nil {
+		return fmt.Errorf("failed to repair spool segment: %w", err)
+	}
+	last.size = complete
+	return nil
+}
+
+// loadCursor restores the forwarded position, deleting segments before it
+func (s *spool) loadCursor() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, spoolCursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read spool cursor: %w", err)
+	}
+	var seq uint64
+	var offset int64
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(data)), "%d %d", &seq, &offset); err != nil {
+		return fmt.Errorf("invalid spool cursor %q: %w", data, err)
+	}
+
+	for len(s.segments) > 0 && s.segments[0].seq < seq {
+		if err := s.removeHead(); err != nil {
+			return err
+		}
+	}
+	if len(s.segments) > 0 && s.segments[0].seq == seq {
+		s.offset = min(offset, s.segments[0].size)
+	}
+	return nil
+}
+
+// saveCursor records the forwarded position
+func (s *spool) saveCursor() error {
+	var seq uint64
+	if len(s.segments) > 0 {
+		seq = s.segments[0].seq
+	}
+	path := filepath.Join(s.dir, spoolCursorFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", seq, s.offset)), 0644); err != nil {
+		return fmt.Errorf("failed to write spool cursor: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write spool cursor: %w", err)
+	}
+	return nil
+}
+
+// append adds payload, which must not contain a newline, to the spool
+func (s *spool) append(payload []byte) error {
+	if s.writer == nil || s.segments[len(s.segments)-1].size >= s.segmentBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	line := make([]byte, 0, len(payload)+1)
+	line = append(append(line, payload...), '\n')
+	n, err := s.writer.Write(line)
+	s.segments[len(s.segments)-1].size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write to spool: %w", err)
+	}
+	return s.enforceLimit()
+}
+
+// rotate starts a new segment for appends
+func (s *spool) rotate() error {
+	if s.writer != nil {
+		if err := s.writer.Close(); err != nil {
+			return fmt.Errorf("failed to close spool segment: %w", err)
+		}
+		s.writer = nil
+	}
+
+	seq := uint64(1)
+	if len(s.segments) > 0 {
+		seq = s.segments[len(s.segments)-1].seq + 1
+	}
+	file, err := os.OpenFile(s.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+	s.writer = file
+	s.segments = append(s.segments, &spoolSegment{seq: seq})
+	return nil
+}
+
+// enforceLimit drops the oldest segments, forwarded or not, until the spool
+// fits in maxBytes. The segment being written is never dropped.
+func (s *spool) enforceLimit() error {
+	if s.maxBytes <= 0 {
+		return nil
+	}
+	for len(s.segments) > 1 && s.size() > s.maxBytes {
+		s.dropped += s.segments[0].size - s.offset
+		if err := s.removeHead(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeHead deletes the first segment, resetting the read position
+func (s *spool) removeHead() error {
+	if s.readFile != nil {
+		_ = s.readFile.Close()
+		s.readFile, s.reader = nil, nil
+	}
+	head := s.segments[0]
+	s.segments = s.segments[1:]
+	s.offset, s.pending = 0, nil
+	if err := os.Remove(s.segmentPath(head.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove spool segment: %w", err)
+	}
+	return nil
+}
+
+// size returns the bytes held on disk
+func (s *spool) size() int64 {
+	var total int64
+	for _, segment := range s.segments {
+		total += segment.size
+	}
+	return total
+}
+
+// backlog returns the bytes not yet forwarded
+func (s *spool) backlog() int64 {
+	return s.size() - s.offset
+}
+
+// peek returns the oldest payload not yet forwarded, or nil if there is
+// none. The same payload is returned until it is committed.
+func (s *spool) peek() ([]byte, error) {
+	if s.pending != nil {
+		return s.pending[:len(s.pending)-1], nil
+	}
+
+	for len(s.segments) > 0 {
+		if s.reader == nil {
+			file, err := os.Open(s.segmentPath(s.segments[0].seq))
+			if err != nil {
+				return nil, fmt.Errorf("failed to open spool segment: %w", err)
+			}
+			if _, err := file.Seek(s.offset, io.SeekStart); err != nil {
+				_ = file.Close()
+				return nil, fmt.Errorf("failed to seek spool segment: %w", err)
+			}
+			s.readFile, s.reader = file, bufio.NewReader(file)
+		}
+
+		line, err := s.reader.ReadBytes('\n')
+		if err == nil {
+			s.pending = line
+			return line[:len(line)-1], nil
+		}
+		if err != io.EOF {
+			return nil, fmt.Errorf("failed to read spool segment: %w", err)
+		}
+		if len(line) > 0 {
+			// Appends are whole lines, so this is only seen mid-write;
+			// reread from the committed offset next time
+			_ = s.readFile.Close()
+			s.readFile, s.reader = nil, nil
+			return nil, nil
+		}
+
+		// The first segment is forwarded; keep it only if it is still
+		// being written
+		if len(s.segments) == 1 && s.writer != nil {
+			return nil, nil
+		}
+		if err := s.removeHead(); err != nil {
+			return nil, err
+		}
+		if err := s.saveCursor(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// commit marks the payload returned by peek as forwarded
+func (s *spool) commit() error {
+	if s.pending == nil {
+		return nil
+	}
+	s.offset += int64(len(s.pending))
+	s.pending = nil
+	return s.saveCursor()
+}
+
+// close closes the spool's files
+func (s *spool) close() error {
+	if s.readFile != nil {
+		_ = s.readFile.Close()
+		s.readFile, s.reader = nil, nil
+	}
+	if s.writer != nil {
+		err := s.writer.Close()
+		s.writer = nil
+		if err != nil {
+			return fmt.Errorf("failed to close spool segment: %w", err)
+		}
+	}
+	return nil
+}
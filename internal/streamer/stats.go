@@ -0,0 +1,181 @@
+package streamer
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProgressInterval is how often a streamer logs its progress
+const DefaultProgressInterval = 30 * time.Second
+
+// rateWindow is the period the current publish rate is measured over
+const rateWindow = 10 * time.Second
+
+// WorkerStats reports one worker's progress
+type WorkerStats struct {
+	ID        int    `json:"id"`
+	Published uint64 `json:"published"`
+	Errors    uint64 `json:"errors"`
+	Loops     uint64 `json:"loops"`
+	// Row is the number of rows read in the worker's current pass
+	Row uint64 `json:"row"`
+}
+
+// StreamerStats reports a streamer's progress. Errors counts failed
+// publishes and Loops completed passes over the file, summed over workers.
+type StreamerStats struct {
+	StartedAt time.Time `json:"started_at"`
+	Paused    bool      `json:"paused"`
+	Published uint64    `json:"published"`
+	Errors    uint64    `json:"errors"`
+	Malformed int       `json:"malformed_rows"`
+	Loops     uint64    `json:"loops"`
+	// CurrentRate is messages published per second over the last ten
+	// seconds; TargetRate is what the workers and the configured rate, or
+	// the rate profile's current rate, allow
+	CurrentRate float64       `json:"current_rate"`
+	TargetRate  float64       `json:"target_rate"`
+	Workers     []WorkerStats `json:"workers"`
+	// Chaos counts records corrupted by chaos mode, by kind
+	Chaos map[string]uint64 `json:"chaos,omitempty"`
+}
+
+// workerProgress counts a worker's progress
+type workerProgress struct {
+	published atomic.Uint64
+	errors    atomic.Uint64
+	loops     atomic.Uint64
+	row       atomic.Uint64
+}
+
+// rateSample is the number of messages published by a point in time
+type rateSample struct {
+	at        time.Time
+	published uint64
+}
+
+// rateMeter measures the publish rate over rateWindow from samples taken
+// every second
+type rateMeter struct {
+	mu      sync.Mutex
+	samples []rateSample
+}
+
+// add records a sample, discarding those older than the window
+func (m *rateMeter) add(sample rateSample) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, sample)
+	for len(m.samples) > 2 && sample.at.Sub(m.samples[1].at) >= rateWindow {
+		m.samples = m.samples[1:]
+	}
+}
+
+// rate returns messages per second between the oldest and newest samples
+func (m *rateMeter) rate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.samples) < 2 {
+		return 0
+	}
+	first, last := m.samples[0], m.samples[len(m.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.published-first.published) / elapsed
+}
+
+// SetProgressInterval sets how often progress is logged; zero disables the
+// progress logs
+func (s *Streamer) SetProgressInterval(interval time.Duration) {
+	s.progressInterval = interval
+}
+
+// Stats returns the streamer's progress
+func (s *Streamer) Stats() StreamerStats {
+	stats := StreamerStats{
+		StartedAt:   s.startedAt,
+		Paused:      s.Paused(),
+		CurrentRate: s.meter.rate(),
+		TargetRate:  float64(s.workers) * s.currentRate(),
+		Workers:     make([]WorkerStats, 0, len(s.progress)),
+	}
+	for id, progress := range s.progress {
+		worker := WorkerStats{
+			ID:        id,
+			Published: progress.published.Load(),
+			Errors:    progress.errors.Load(),
+			Loops:     progress.loops.Load(),
+			Row:       progress.row.Load(),
+		}
+		stats.Published += worker.Published
+		stats.Errors += worker.Errors
+		stats.Loops += worker.Loops
+		stats.Workers = append(stats.Workers, worker)
+	}
+	if s.rows != nil {
+		stats.Malformed = s.rows.skipped()
+	}
+	if s.chaos != nil {
+		stats.Chaos = s.chaos.injected()
+	}
+	return stats
+}
+
+// StatsHandler serves the streamer's progress as JSON, for /stats
+func (s *Streamer) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.Stats()); err != nil {
+			s.logger.Error("Failed to encode stats", "error", err)
+		}
+	})
+}
+
+// workerProgress returns the counters of a worker. Before Start, when there
+// are none, it returns counters that are not reported.
+func (s *Streamer) workerProgress(workerID int) *workerProgress {
+	if workerID < 0 || workerID >= len(s.progress) {
+		return &workerProgress{}
+	}
+	return s.progress[workerID]
+}
+
+// monitor samples the publish rate every second and logs progress every
+// progress interval until the streamer stops
+func (s *Streamer) monitor() {
+	defer s.wg.Done()
+	sampler := time.NewTicker(time.Second)
+	defer sampler.Stop()
+
+	var logs <-chan time.Time
+	if s.progressInterval > 0 {
+		ticker := time.NewTicker(s.progressInterval)
+		defer ticker.Stop()
+		logs = ticker.C
+	}
+
+	s.meter.add(rateSample{at: time.Now()})
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-sampler.C:
+			s.meter.add(rateSample{at: now, published: s.Stats().Published})
+		case <-logs:
+			stats := s.Stats()
+			s.logger.Info("Streamer progress",
+				"paused", stats.Paused,
+				"published", stats.Published,
+				"errors", stats.Errors,
+				"malformed_rows", stats.Malformed,
+				"loops", stats.Loops,
+				"current_rate", stats.CurrentRate,
+				"target_rate", stats.TargetRate)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package streamer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateMeter(t *testing.T) {
+	var meter rateMeter
+	if meter.rate() != 0 {
+		t.Error("Expected no rate without samples")
+	}
+
+	start := time.Now()
+	for i := 0; i <= 20; i++ {
+		// 5 msg/s for ten seconds, then 20 msg/s
+		published := uint64(i * 5)
+		if i > 10 {
+			published = 50 + uint64(i-10)*20
+		}
+		meter.add(rateSample{at: start.Add(time.Duration(i) * time.Second), published: published})
+	}
+	if rate := meter.rate(); rate != 20 {
+		t.Errorf("Expected the rate over the last ten seconds, got %v", rate)
+	}
+}
+
+func TestStreamer_Stats(t *testing.T) {
+	csvPath := createTestCSVWithContent(t, "id,value\n1,100\n2,200\n3,300\n")
+	broker := NewMockBroker()
+	defer broker.Close()
+
+	streamer := NewStreamer(csvPath, 2, 200.0, "test-topic", broker)
+	if stats := streamer.Stats(); stats.Published != 0 || len(stats.Workers) != 0 {
+		t.Errorf("Expected empty stats before Start, got %+v", stats)
+	}
+	if err := streamer.Start(); err != nil {
+		t.Fatalf("Failed to start streamer: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	broker.SetPublishError(errors.New("unavailable"))
+	time.Sleep(30 * time.Millisecond)
+	streamer.Stop()
+
+	rr := httptest.NewRecorder()
+	streamer.StatsHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/stats", nil))
+	var stats StreamerStats
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stats.Workers) != 2 || stats.TargetRate != 400 {
+		t.Fatalf("Expected two workers at 400 msg/s, got %+v", stats)
+	}
+	if stats.Published != uint64(len(broker.GetMessages())) {
+		t.Errorf("Expected %d published, got %d", len(broker.GetMessages()), stats.Published)
+	}
+	if stats.Errors == 0 || stats.Loops == 0 {
+		t.Errorf("Expected failed publishes and completed passes, got %+v", stats)
+	}
+	for _, worker := range stats.Workers {
+		if worker.Published == 0 || worker.Row > 3 {
+			t.Errorf("Expected worker %d to publish within a 3-row file, got %+v", worker.ID, worker)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package streamer
 import (
 	"context"
 	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -16,10 +16,31 @@ import (
 	"github.com/harishb93/telemetry-pipeline/internal/mq"
 )
 
+// SchemaVersion is the version of the TelemetryData format the streamer
+// publishes, sent in each message's mq.HeaderSchemaVersion header. A format
+// change bumps it once the collectors have a decoder for the new version.
+const SchemaVersion = "1"
+
 // TelemetryData represents a flexible telemetry data point
 type TelemetryData struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Fields    map[string]interface{} `json:"fields"`
+	// CorrelationID identifies the source record the data point was read
+	// from, see correlationID
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Labels record the data point's provenance, see LabelInstance
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// correlationID identifies a CSV record as
+// <run>.<worker>.<pass>.<file>.<line>: the ULID of the streamer run, logged
+// with the input files when it starts, the worker that read the record, how
+// many times the worker had already streamed the input, the index of the
+// file in the input files and the record's line in the file. The collector
+// stores it with the data point, so a stored value can be traced back to the
+// row it came from.
+func correlationID(run string, worker int, pass uint64, file, line int) string {
+	return fmt.Sprintf("%s.%d.%d.%d.%d", run, worker, pass, file, line)
 }
 
 // PreProcessCSVByHostNames filters the CSV file by the provided hostnames and creates a new filtered CSV file
@@ -166,15 +187,37 @@ func PreProcessCSVByHostNames(csvPath, hostList string) (string, error) {
 
 // Streamer handles streaming CSV data to MQ
 type Streamer struct {
-	csvPath string
-	workers int
-	rate    float64
-	topic   string
-	broker  mq.BrokerInterface
-	ctx     context.Context
-	cancel  context.CancelFunc
-	wg      sync.WaitGroup
-	logger  *logger.Logger
+	csvPath  string   // a path, glob or comma-separated list of them
+	files    []string // csvPath resolved, set by Start
+	workers  int
+	rate     float64
+	profile  *RateProfile // overrides rate when set
+	topic    string
+	topics   *topicTemplate // topic resolved per record, set by Start
+	runID    string         // prefixes correlation IDs, set by Start
+	instance string         // the LabelInstance of published records
+	hostname string         // the HeaderSourceHost of published messages
+	chaos    *chaosMutator  // corrupts records when set
+	broker   mq.BrokerInterface
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	logger   *logger.Logger
+
+	strict    bool
+	errorFile string
+	rows      *rowReport
+
+	errMu sync.Mutex
+	err   error // why a strict streamer stopped
+
+	progressInterval time.Duration
+	startedAt        time.Time
+	progress         []*workerProgress // per worker, set by Start
+	meter            rateMeter
+
+	pauseMu sync.Mutex
+	resumed chan struct{} // closed on Resume; nil unless paused
 }
 
 // NewStreamer creates a new streamer instance
@@ -189,30 +232,112 @@ func NewStreamer(csvPath string, workers int, rate float64, topic string, broker
 		ctx:     ctx,
 		cancel:  cancel,
 		logger:  logger.NewFromEnv().WithComponent("streamer"),
+
+		instance:         DefaultInstance(),
+		hostname:         DefaultInstance(),
+		progressInterval: DefaultProgressInterval,
 	}
 }
 
+// SetStrict makes the streamer fail on the first malformed row instead of
+// skipping it. Start then checks the whole file before publishing anything.
+func (s *Streamer) SetStrict(strict bool) {
+	s.strict = strict
+}
+
+// SetInstance sets the name published records are labelled with as their
+// source instance, the hostname by default
+func (s *Streamer) SetInstance(name string) {
+	s.instance = name
+}
+
+// SetErrorFile makes the streamer write each malformed row, with the reason it
+// was rejected, to path, followed by a summary when it stops
+func (s *Streamer) SetErrorFile(path string) {
+	s.errorFile = path
+}
+
+// Done is closed when the streamer stops, either by Stop or because a strict
+// streamer met a malformed row
+func (s *Streamer) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Err returns the malformed row that stopped a strict streamer, if any
+func (s *Streamer) Err() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
+
 // Start begins streaming CSV data to MQ with specified number of workers
 func (s *Streamer) Start() error {
+	s.runID = mq.NewMessageID()
 	s.logger.Info("Streamer starting",
+		"run_id", s.runID,
+		"instance", s.instance,
 		"workers", s.workers,
 		"rate_per_worker", s.rate,
 		"csv_file", s.csvPath)
 
-	// Check if CSV file is accessible
-	if _, err := os.Stat(s.csvPath); err != nil {
-		s.logger.Error("CSV file not accessible", "file", s.csvPath, "error", err)
-		return fmt.Errorf("failed to access CSV file: %w", err)
+	files, err := ResolveCSVFiles(s.csvPath)
+	if err != nil {
+		return err
+	}
+
+	// Check if CSV files are accessible
+	for _, path := range files {
+		if _, err := os.Stat(path); err != nil {
+			s.logger.Error("CSV file not accessible", "file", path, "error", err)
+			return fmt.Errorf("failed to access CSV file: %w", err)
+		}
 	}
+	s.files = files
 
-	// Read CSV headers first
+	// Read CSV headers first, checking every file has the same
 	headers, err := s.readHeaders()
 	if err != nil {
 		s.logger.Error("Failed to read CSV headers", "error", err)
 		return fmt.Errorf("failed to read CSV headers: %w", err)
 	}
 
-	s.logger.Info("CSV headers parsed", "headers", headers, "count", len(headers))
+	s.logger.Info("CSV headers parsed", "headers", headers, "count", len(headers), "files", files)
+
+	topics, err := parseTopicTemplate(s.topic, headers)
+	if err != nil {
+		return err
+	}
+	s.topics = topics
+
+	rows, err := openRowReport(s.errorFile)
+	if err != nil {
+		return err
+	}
+	s.rows = rows
+
+	if s.strict {
+		for _, path := range files {
+			if err := validateCSV(path, headers); err != nil {
+				var rowErr *RowError
+				if errors.As(err, &rowErr) {
+					if len(files) > 1 {
+						rowErr.File = path
+					}
+					_, _ = s.rows.add(rowErr)
+				}
+				_ = s.rows.close()
+				return fmt.Errorf("strict mode: malformed CSV: %w", err)
+			}
+		}
+	}
+
+	s.startedAt = time.Now()
+	s.progress = make([]*workerProgress, s.workers)
+	for i := range s.progress {
+		s.progress[i] = &workerProgress{}
+	}
+	s.wg.Add(1)
+	go s.monitor()
 
 	// Start workers
 	for i := 0; i < s.workers; i++ {
@@ -230,28 +355,21 @@ func (s *Streamer) Stop() {
 	s.cancel()
 	s.wg.Wait()
 	s.logger.Info("All workers stopped")
-}
 
-// readHeaders reads the CSV file headers
-func (s *Streamer) readHeaders() ([]string, error) {
-	file, err := os.Open(s.csvPath)
-	if err != nil {
-		return nil, err
+	if s.rows == nil {
+		return
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			// Can't return error from defer in this context
-			fmt.Printf("Warning: failed to close file: %v\n", err)
-		}
-	}()
-
-	reader := csv.NewReader(file)
-	headers, err := reader.Read()
-	if err != nil {
-		return nil, err
+	if skipped := s.rows.skipped(); skipped > 0 {
+		s.logger.Warn("Malformed CSV rows were skipped", "summary", s.rows.summary(), "error_file", s.errorFile)
 	}
+	if err := s.rows.close(); err != nil {
+		s.logger.Warn("Failed to write error file", "error", err)
+	}
+}
 
-	return headers, nil
+// readHeaders reads the header shared by the input files
+func (s *Streamer) readHeaders() ([]string, error) {
+	return readCSVHeaders(s.inputFiles())
 }
 
 // worker runs a single worker goroutine
@@ -285,9 +403,34 @@ func (s *Streamer) worker(workerID int, headers []string) {
 	}
 }
 
-// processCSVLoop processes the entire CSV file once
-func (s *Streamer) processCSVLoop(_ int, headers []string, recordsProcessed *int, rateInterval time.Duration, workerLogger *logger.Logger) error {
-	file, err := os.Open(s.csvPath)
+// processCSVLoop processes every input file once, in order
+func (s *Streamer) processCSVLoop(workerID int, headers []string, recordsProcessed *int, rateInterval time.Duration, workerLogger *logger.Logger) error {
+	progress := s.workerProgress(workerID)
+	progress.row.Store(0)
+
+	files := s.inputFiles()
+	pass := progress.loops.Load()
+	for i, path := range files {
+		source := func(line int) string { return correlationID(s.runID, workerID, pass, i, line) }
+		if err := s.processCSVFile(workerID, path, len(files) > 1, source, headers, progress, recordsProcessed, rateInterval, workerLogger); err != nil {
+			return err
+		}
+		if s.ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	workerLogger.Debug("Reached end of CSV, restarting from beginning")
+	progress.loops.Add(1)
+	return nil // Return to restart the loop
+}
+
+// processCSVFile processes one input file. Malformed rows are attributed to
+// the file when there are several. source returns the correlation ID of the
+// record on a line; records are also labelled with their provenance, and
+// their messages carry it in headers.
+func (s *Streamer) processCSVFile(workerID int, path string, multiFile bool, source func(line int) string, headers []string, progress *workerProgress, recordsProcessed *int, rateInterval time.Duration, workerLogger *logger.Logger) error {
+	file, err := os.Open(path)
 	if err != nil {
 		return err
 	}
@@ -297,7 +440,7 @@ func (s *Streamer) processCSVLoop(_ int, headers []string, recordsProcessed *int
 		}
 	}()
 
-	reader := csv.NewReader(file)
+	reader := newCSVReader(file)
 
 	// Skip headers
 	if _, err := reader.Read(); err != nil {
@@ -309,11 +452,24 @@ func (s *Streamer) processCSVLoop(_ int, headers []string, recordsProcessed *int
 		case <-s.ctx.Done():
 			return nil
 		default:
-			record, err := reader.Read()
+			if !s.waitWhilePaused() {
+				return nil
+			}
+			record, err := readRow(reader, headers)
+			if err != io.EOF {
+				progress.row.Add(1)
+			}
+			var rowErr *RowError
+			if errors.As(err, &rowErr) {
+				if multiFile {
+					rowErr.File = path
+				}
+				s.rejectRow(rowErr, workerLogger)
+				continue
+			}
 			if err != nil {
 				if err == io.EOF {
-					workerLogger.Debug("Reached end of CSV, restarting from beginning")
-					return nil // Return to restart the loop
+					return nil
 				}
 				return err
 			}
@@ -324,9 +480,12 @@ func (s *Streamer) processCSVLoop(_ int, headers []string, recordsProcessed *int
 				workerLogger.Warn("Error parsing record", "error", err, "record", record)
 				continue
 			}
+			line, _ := reader.FieldPos(0)
+			telemetryData.CorrelationID = source(line)
+			telemetryData.Labels = fileProvenance(s.instance, path, line)
 
 			// Convert to JSON
-			jsonData, err := json.Marshal(telemetryData)
+			jsonData, err := s.encode(telemetryData, workerLogger)
 			if err != nil {
 				workerLogger.Error("Error marshaling to JSON", "error", err)
 				continue
@@ -335,29 +494,80 @@ func (s *Streamer) processCSVLoop(_ int, headers []string, recordsProcessed *int
 			// Create MQ message
 			msg := mq.Message{
 				Payload: jsonData,
+				Headers: fileHeaders(s.hostname, path, workerID),
 				Ack:     func() {}, // Will be overridden by broker
 			}
 
 			// Publish to MQ
-			if err := s.broker.Publish(s.topic, msg); err != nil {
-				workerLogger.Error("Error publishing message", "error", err)
+			if err := mq.PublishContext(s.ctx, s.broker, s.topicFor(record), msg); err != nil {
+				if s.ctx.Err() != nil {
+					// Stopped mid-publish; the record was not sent
+					return nil
+				}
+				workerLogger.Error("Error publishing message", "error", err, "correlation_id", telemetryData.CorrelationID)
+				progress.errors.Add(1)
 			} else {
 				*recordsProcessed++
+				progress.published.Add(1)
 				if *recordsProcessed%100 == 0 {
 					workerLogger.Info("Processed records", "count", *recordsProcessed)
 				}
 			}
 
 			// Rate limiting
-			if rateInterval > 0 {
-				time.Sleep(rateInterval)
-			}
+			s.pace(rateInterval)
 		}
 	}
 }
 
+// topicFor returns the topic record is published to
+func (s *Streamer) topicFor(record []string) string {
+	if s.topics == nil {
+		return s.topic
+	}
+	return s.topics.resolve(record)
+}
+
+// fileOf returns the file a malformed row is in
+func (s *Streamer) fileOf(rowErr *RowError) string {
+	if rowErr.File != "" {
+		return rowErr.File
+	}
+	return s.inputFiles()[0]
+}
+
+// rejectRow records a malformed row, and stops a strict streamer
+func (s *Streamer) rejectRow(rowErr *RowError, workerLogger *logger.Logger) {
+	if s.rows == nil {
+		return
+	}
+	first, err := s.rows.add(rowErr)
+	if err != nil {
+		workerLogger.Warn("Failed to write error file", "error", err)
+	}
+	if first {
+		workerLogger.Warn("Skipping malformed CSV row", "file", s.fileOf(rowErr), "line", rowErr.Line, "reason", rowErr.Kind, "error", rowErr.Err)
+	}
+
+	if s.strict {
+		s.errMu.Lock()
+		if s.err == nil {
+			s.err = fmt.Errorf("strict mode: malformed CSV: %w", rowErr)
+		}
+		s.errMu.Unlock()
+		s.cancel()
+	}
+}
+
 // parseRecord converts CSV record to flexible telemetry data format
 func (s *Streamer) parseRecord(headers, record []string) (*TelemetryData, error) {
+	return recordToTelemetry(headers, record)
+}
+
+// recordToTelemetry converts a record of header columns to telemetry data
+// stamped with the current time, typing each field as a bool, float or
+// string
+func recordToTelemetry(headers, record []string) (*TelemetryData, error) {
 	if len(headers) != len(record) {
 		return nil, fmt.Errorf("header count (%d) doesn't match record count (%d)", len(headers), len(record))
 	}
@@ -18,6 +18,7 @@ import (
 // MockBroker implements BrokerInterface for testing
 type MockBroker struct {
 	messages     []mq.Message
+	topics       map[string]int
 	publishError error
 	mu           sync.Mutex
 	closed       bool
@@ -26,6 +27,7 @@ type MockBroker struct {
 func NewMockBroker() *MockBroker {
 	return &MockBroker{
 		messages: make([]mq.Message, 0),
+		topics:   make(map[string]int),
 		closed:   false,
 	}
 }
@@ -43,6 +45,7 @@ func (m *MockBroker) Publish(topic string, msg mq.Message) error {
 	}
 
 	m.messages = append(m.messages, msg)
+	m.topics[topic]++
 	return nil
 }
 
@@ -69,6 +72,18 @@ func (m *MockBroker) GetMessages() []mq.Message {
 	return result
 }
 
+// GetTopics returns the number of messages published to each topic
+func (m *MockBroker) GetTopics() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]int, len(m.topics))
+	for topic, count := range m.topics {
+		result[topic] = count
+	}
+	return result
+}
+
 func (m *MockBroker) SetPublishError(err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -911,6 +926,60 @@ func TestStreamer_ProcessCSVLoop_RateLimit(t *testing.T) {
 	}
 }
 
+func TestStreamer_CorrelationIDs(t *testing.T) {
+	headers := []string{"id", "value"}
+	first := createTestCSV(t, headers, [][]string{{"1", "100"}, {"2", "200"}})
+	second := createTestCSV(t, headers, [][]string{{"3", "300"}})
+
+	broker := NewMockBroker()
+	defer broker.Close()
+
+	streamer := NewStreamer(first+","+second, 1, 1.0, "test-topic", broker)
+	streamer.files = []string{first, second}
+	streamer.runID = "RUN"
+	streamer.SetInstance("streamer-0")
+	streamer.progress = []*workerProgress{{}}
+	streamer.progress[0].loops.Store(2)
+
+	recordsProcessed := 0
+	if err := streamer.processCSVLoop(0, headers, &recordsProcessed, 0, streamer.logger.WithComponent("test")); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids, files []string
+	var labels []map[string]string
+	for _, msg := range broker.GetMessages() {
+		var data TelemetryData
+		if err := json.Unmarshal(msg.Payload, &data); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Headers[mq.HeaderSchemaVersion] != SchemaVersion {
+			t.Errorf("Expected schema version %s, got headers %v", SchemaVersion, msg.Headers)
+		}
+		ids = append(ids, data.CorrelationID)
+		labels = append(labels, data.Labels)
+		files = append(files, msg.Headers[HeaderSourceFile])
+		if msg.Headers[HeaderWorkerID] != "0" || msg.Headers[HeaderSourceHost] != DefaultInstance() {
+			t.Errorf("Expected worker and host headers, got %v", msg.Headers)
+		}
+	}
+	if wantFiles := []string{filepath.Base(first), filepath.Base(first), filepath.Base(second)}; !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("Expected source file headers %v, got %v", wantFiles, files)
+	}
+	want := []string{"RUN.0.2.0.2", "RUN.0.2.0.3", "RUN.0.2.1.2"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected correlation IDs %v, got %v", want, ids)
+	}
+	wantLabels := []map[string]string{
+		{LabelInstance: "streamer-0", LabelFile: first, LabelRow: "2"},
+		{LabelInstance: "streamer-0", LabelFile: first, LabelRow: "3"},
+		{LabelInstance: "streamer-0", LabelFile: second, LabelRow: "2"},
+	}
+	if !reflect.DeepEqual(labels, wantLabels) {
+		t.Errorf("Expected provenance labels %v, got %v", wantLabels, labels)
+	}
+}
+
 // ==== JSON Marshaling Tests ====
 
 func TestStreamer_JSONMarshaling(t *testing.T) {
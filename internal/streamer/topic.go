@@ -0,0 +1,106 @@
+package streamer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/harishb93/telemetry-pipeline/internal/mq"
+)
+
+// unknownTopicValue replaces an empty column value in a resolved topic
+const unknownTopicValue = "unknown"
+
+// topicTemplate is a --topic with {column} placeholders, such as
+// telemetry.{hostname}, resolved per record so data can be partitioned into
+// topics at the source
+type topicTemplate struct {
+	// literals surround the placeholders: literals[i] precedes the column
+	// columns[i], and the last literal follows the last placeholder
+	literals []string
+	columns  []int
+}
+
+// parseTopicTemplate resolves the placeholders of template to columns of
+// headers. Column names match case-insensitively when no header matches
+// exactly, so {hostname} selects the Hostname column.
+func parseTopicTemplate(template string, headers []string) (*topicTemplate, error) {
+	t := &topicTemplate{}
+	rest := template
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			if strings.IndexByte(rest, '}') >= 0 {
+				return nil, fmt.Errorf("invalid topic template %q: unmatched }", template)
+			}
+			t.literals = append(t.literals, rest)
+			// Check the fixed parts of the name; resolved values are sanitised
+			if err := mq.ValidateTopic(strings.Join(t.literals, unknownTopicValue)); err != nil {
+				return nil, fmt.Errorf("invalid topic template %q: %w", template, err)
+			}
+			return t, nil
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid topic template %q: unclosed {", template)
+		}
+		literal, name := rest[:open], rest[open+1:open+end]
+		if strings.IndexByte(literal, '}') >= 0 {
+			return nil, fmt.Errorf("invalid topic template %q: unmatched }", template)
+		}
+		column := columnIndex(headers, name)
+		if column < 0 {
+			return nil, fmt.Errorf("invalid topic template %q: no column named %q", template, name)
+		}
+		t.literals = append(t.literals, literal)
+		t.columns = append(t.columns, column)
+		rest = rest[open+end+1:]
+	}
+}
+
+// columnIndex finds the column called name, preferring an exact match
+func columnIndex(headers []string, name string) int {
+	if name == "" {
+		return -1
+	}
+	for i, header := range headers {
+		if header == name {
+			return i
+		}
+	}
+	for i, header := range headers {
+		if strings.EqualFold(header, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolve returns the topic for record. Column values are sanitised so the
+// topic stays usable in a publish URL: characters other than letters, digits,
+// '.', '-' and '_' become '_', and empty values become "unknown".
+func (t *topicTemplate) resolve(record []string) string {
+	if len(t.columns) == 0 {
+		return t.literals[0]
+	}
+	var b strings.Builder
+	for i, column := range t.columns {
+		b.WriteString(t.literals[i])
+		b.WriteString(topicValue(record[column]))
+	}
+	b.WriteString(t.literals[len(t.columns)])
+	return b.String()
+}
+
+// topicValue sanitises a column value for use in a topic name
+func topicValue(value string) string {
+	if value == "" {
+		return unknownTopicValue
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, value)
+}
@@ -0,0 +1,66 @@
+package streamer
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParseTopicTemplate(t *testing.T) {
+	headers := []string{"metric_name", "gpu_id", "Hostname", "hostname_alias"}
+	record := []string{"DCGM_FI_DEV_GPU_UTIL", "0", "host 1/a", ""}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"telemetry", "telemetry"},
+		{"telemetry.{hostname}", "telemetry.host_1_a"},
+		{"telemetry.{metric_name}.{gpu_id}", "telemetry.DCGM_FI_DEV_GPU_UTIL.0"},
+		{"{hostname_alias}-gpus", "unknown-gpus"},
+	}
+	for _, tt := range tests {
+		topics, err := parseTopicTemplate(tt.template, headers)
+		if err != nil {
+			t.Errorf("parseTopicTemplate(%q) failed: %v", tt.template, err)
+			continue
+		}
+		if got := topics.resolve(record); got != tt.want {
+			t.Errorf("%q resolved to %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"telemetry.{host", "telemetry.}", "telemetry.{}", "telemetry.{pod}", "telemetry/{hostname}", "../{hostname}", ".{hostname}"} {
+		if _, err := parseTopicTemplate(invalid, headers); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestStreamer_TopicTemplate(t *testing.T) {
+	csvPath := createTestCSVWithContent(t, "Hostname,value\nhost-a,1\nhost-b,2\n")
+	broker := NewMockBroker()
+	defer broker.Close()
+
+	streamer := NewStreamer(csvPath, 1, 100.0, "telemetry.{hostname}", broker)
+	if err := streamer.Start(); err != nil {
+		t.Fatalf("Failed to start streamer: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	streamer.Stop()
+
+	var topics []string
+	for topic := range broker.GetTopics() {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	if len(topics) != 2 || topics[0] != "telemetry.host-a" || topics[1] != "telemetry.host-b" {
+		t.Errorf("Expected a topic per host, got %v", topics)
+	}
+
+	unknown := NewStreamer(csvPath, 1, 100.0, "telemetry.{pod}", broker)
+	if err := unknown.Start(); err == nil {
+		unknown.Stop()
+		t.Error("Expected a template naming a missing column to fail Start")
+	}
+}
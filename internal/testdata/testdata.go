@@ -0,0 +1,136 @@
+// Package testdata holds representative DCGM exports shared by the streamer,
+// collector and API tests, and the golden outputs each stage produces from
+// them, so a change in parsing or in a response shows up as a golden diff
+// instead of being re-encoded in every test's inline CSV.
+//
+// Datasets live in dcgm/<name>.csv. Goldens live in golden/<name>.<stage>.json,
+// e.g. golden/basic.records.json for the records the streamer publishes from
+// dcgm/basic.csv. Run the tests with UPDATE_GOLDEN=1 to rewrite the goldens
+// from the current output, then review the diff.
+//
+// The go tool leaves directories named testdata out of ./... patterns, so the
+// package is only built by the tests that import it.
+package testdata
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Basic is a DCGM export of two hosts with two GPUs each, reporting
+// utilization, temperature and power usage
+const Basic = "basic"
+
+//go:embed dcgm/*.csv golden/*.json
+var files embed.FS
+
+// Record is a telemetry record as the streamer publishes it, with its
+// timestamp taken from the row's timestamp column so goldens are stable
+type Record struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+// CSV returns the named DCGM dataset
+func CSV(tb testing.TB, name string) []byte {
+	tb.Helper()
+	data, err := files.ReadFile("dcgm/" + name + ".csv")
+	if err != nil {
+		tb.Fatalf("Failed to read dataset %s: %v", name, err)
+	}
+	return data
+}
+
+// WriteCSV writes the named DCGM dataset to a file in a temporary directory
+// removed when the test ends, and returns its path
+func WriteCSV(tb testing.TB, name string) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), name+".csv")
+	if err := os.WriteFile(path, CSV(tb, name), 0644); err != nil {
+		tb.Fatalf("Failed to write dataset %s: %v", name, err)
+	}
+	return path
+}
+
+// Records returns the records the streamer publishes from the named dataset,
+// in row order, from its golden
+func Records(tb testing.TB, name string) []Record {
+	tb.Helper()
+	var records []Record
+	Golden(tb, name+".records", &records)
+	return records
+}
+
+// Messages returns the records of the named dataset encoded as message
+// payloads, ready to publish to a collector's topic
+func Messages(tb testing.TB, name string) [][]byte {
+	tb.Helper()
+	records := Records(tb, name)
+	payloads := make([][]byte, len(records))
+	for i, record := range records {
+		payload, err := json.Marshal(record)
+		if err != nil {
+			tb.Fatalf("Failed to encode record %d of %s: %v", i, name, err)
+		}
+		payloads[i] = payload
+	}
+	return payloads
+}
+
+// Golden decodes the named golden into v
+func Golden(tb testing.TB, name string, v interface{}) {
+	tb.Helper()
+	data, err := files.ReadFile("golden/" + name + ".json")
+	if err != nil {
+		tb.Fatalf("Failed to read golden %s: %v", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		tb.Fatalf("Failed to decode golden %s: %v", name, err)
+	}
+}
+
+// AssertGolden fails the test unless got encodes to the same JSON as the
+// named golden, ignoring formatting and key order. With UPDATE_GOLDEN=1 set
+// it rewrites the golden from got instead.
+func AssertGolden(tb testing.TB, name string, got interface{}) {
+	tb.Helper()
+	encoded, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		tb.Fatalf("Failed to encode output for golden %s: %v", name, err)
+	}
+	encoded = append(encoded, '\n')
+
+	if os.Getenv("UPDATE_GOLDEN") == "1" {
+		path := filepath.Join(sourceDir(), "golden", name+".json")
+		if err := os.WriteFile(path, encoded, 0644); err != nil {
+			tb.Fatalf("Failed to update golden %s: %v", name, err)
+		}
+		tb.Logf("Updated golden %s", path)
+		return
+	}
+
+	var want, have interface{}
+	Golden(tb, name, &want)
+	if err := json.Unmarshal(encoded, &have); err != nil {
+		tb.Fatalf("Failed to decode output for golden %s: %v", name, err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		expected, _ := files.ReadFile("golden/" + name + ".json")
+		tb.Errorf("Output differs from golden %s (rerun with UPDATE_GOLDEN=1 to accept it)\ngot:\n%s\nwant:\n%s",
+			name, encoded, bytes.TrimSpace(expected))
+	}
+}
+
+// sourceDir returns the package's directory in the source tree, where
+// goldens are rewritten
+func sourceDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}
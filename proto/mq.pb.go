@@ -143,6 +143,159 @@ func (x *PublishResponse) GetError() string {
 	return ""
 }
 
+// PublishBatchRequest represents a request to publish messages to a topic
+// together
+type PublishBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Messages      []*BatchMessage        `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishBatchRequest) Reset() {
+	*x = PublishBatchRequest{}
+	mi := &file_proto_mq_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishBatchRequest) ProtoMessage() {}
+
+func (x *PublishBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishBatchRequest.ProtoReflect.Descriptor instead.
+func (*PublishBatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PublishBatchRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *PublishBatchRequest) GetMessages() []*BatchMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+// BatchMessage is one message of a PublishBatchRequest
+type BatchMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payload       []byte                 `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchMessage) Reset() {
+	*x = BatchMessage{}
+	mi := &file_proto_mq_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchMessage) ProtoMessage() {}
+
+func (x *BatchMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchMessage.ProtoReflect.Descriptor instead.
+func (*BatchMessage) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchMessage) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *BatchMessage) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+// PublishBatchResponse holds the result of each message of a batch, in the
+// order they were sent
+type PublishBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*PublishResponse     `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishBatchResponse) Reset() {
+	*x = PublishBatchResponse{}
+	mi := &file_proto_mq_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishBatchResponse) ProtoMessage() {}
+
+func (x *PublishBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishBatchResponse.ProtoReflect.Descriptor instead.
+func (*PublishBatchResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PublishBatchResponse) GetResults() []*PublishResponse {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
 // SubscribeRequest represents a request to subscribe to a topic
 type SubscribeRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
@@ -150,13 +303,18 @@ type SubscribeRequest struct {
 	ConsumerGroup  string                 `protobuf:"bytes,2,opt,name=consumer_group,json=consumerGroup,proto3" json:"consumer_group,omitempty"`
 	BatchSize      int32                  `protobuf:"varint,3,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
 	TimeoutSeconds int32                  `protobuf:"varint,4,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	// With replay, the subscription first replays the topic's persisted
+	// messages from from_offset on, then continues live; not for consumer
+	// groups
+	Replay        bool   `protobuf:"varint,5,opt,name=replay,proto3" json:"replay,omitempty"`
+	FromOffset    uint64 `protobuf:"varint,6,opt,name=from_offset,json=fromOffset,proto3" json:"from_offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SubscribeRequest) Reset() {
 	*x = SubscribeRequest{}
-	mi := &file_proto_mq_proto_msgTypes[2]
+	mi := &file_proto_mq_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -168,7 +326,7 @@ func (x *SubscribeRequest) String() string {
 func (*SubscribeRequest) ProtoMessage() {}
 
 func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mq_proto_msgTypes[2]
+	mi := &file_proto_mq_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -181,7 +339,7 @@ func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
 func (*SubscribeRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mq_proto_rawDescGZIP(), []int{2}
+	return file_proto_mq_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *SubscribeRequest) GetTopic() string {
@@ -212,21 +370,42 @@ func (x *SubscribeRequest) GetTimeoutSeconds() int32 {
 	return 0
 }
 
+func (x *SubscribeRequest) GetReplay() bool {
+	if x != nil {
+		return x.Replay
+	}
+	return false
+}
+
+func (x *SubscribeRequest) GetFromOffset() uint64 {
+	if x != nil {
+		return x.FromOffset
+	}
+	return 0
+}
+
 // Message represents a message in the queue
 type Message struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Topic         string                 `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
-	Payload       []byte                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Headers       map[string]string      `protobuf:"bytes,5,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Topic     string                 `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
+	Payload   []byte                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Timestamp int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Headers   map[string]string      `protobuf:"bytes,5,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Number of times the message was redelivered before this delivery
+	RetryCount int32 `protobuf:"varint,6,opt,name=retry_count,json=retryCount,proto3" json:"retry_count,omitempty"`
+	// Time the message was originally published, in Unix nanoseconds
+	PublishedAtUnixNano int64 `protobuf:"varint,7,opt,name=published_at_unix_nano,json=publishedAtUnixNano,proto3" json:"published_at_unix_nano,omitempty"`
+	// Position of the message in its topic's log, when the broker persists
+	// messages
+	Offset        uint64 `protobuf:"varint,8,opt,name=offset,proto3" json:"offset,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Message) Reset() {
 	*x = Message{}
-	mi := &file_proto_mq_proto_msgTypes[3]
+	mi := &file_proto_mq_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -238,7 +417,7 @@ func (x *Message) String() string {
 func (*Message) ProtoMessage() {}
 
 func (x *Message) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mq_proto_msgTypes[3]
+	mi := &file_proto_mq_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -251,7 +430,7 @@ func (x *Message) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Message.ProtoReflect.Descriptor instead.
 func (*Message) Descriptor() ([]byte, []int) {
-	return file_proto_mq_proto_rawDescGZIP(), []int{3}
+	return file_proto_mq_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *Message) GetId() string {
@@ -289,6 +468,126 @@ func (x *Message) GetHeaders() map[string]string {
 	return nil
 }
 
+func (x *Message) GetRetryCount() int32 {
+	if x != nil {
+		return x.RetryCount
+	}
+	return 0
+}
+
+func (x *Message) GetPublishedAtUnixNano() int64 {
+	if x != nil {
+		return x.PublishedAtUnixNano
+	}
+	return 0
+}
+
+func (x *Message) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// AckRequest acknowledges a message streamed to a subscriber, identified by
+// the topic and consumer group of its subscription and its message ID
+type AckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	MessageId     string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	ConsumerGroup string                 `protobuf:"bytes,3,opt,name=consumer_group,json=consumerGroup,proto3" json:"consumer_group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AckRequest) Reset() {
+	*x = AckRequest{}
+	mi := &file_proto_mq_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AckRequest) ProtoMessage() {}
+
+func (x *AckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AckRequest.ProtoReflect.Descriptor instead.
+func (*AckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AckRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *AckRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *AckRequest) GetConsumerGroup() string {
+	if x != nil {
+		return x.ConsumerGroup
+	}
+	return ""
+}
+
+// AckResponse represents the response to an acknowledgment
+type AckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AckResponse) Reset() {
+	*x = AckResponse{}
+	mi := &file_proto_mq_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AckResponse) ProtoMessage() {}
+
+func (x *AckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AckResponse.ProtoReflect.Descriptor instead.
+func (*AckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{8}
+}
+
 // HealthRequest represents a health check request
 type HealthRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -298,7 +597,7 @@ type HealthRequest struct {
 
 func (x *HealthRequest) Reset() {
 	*x = HealthRequest{}
-	mi := &file_proto_mq_proto_msgTypes[4]
+	mi := &file_proto_mq_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -310,7 +609,7 @@ func (x *HealthRequest) String() string {
 func (*HealthRequest) ProtoMessage() {}
 
 func (x *HealthRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mq_proto_msgTypes[4]
+	mi := &file_proto_mq_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -323,7 +622,7 @@ func (x *HealthRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
 func (*HealthRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mq_proto_rawDescGZIP(), []int{4}
+	return file_proto_mq_proto_rawDescGZIP(), []int{9}
 }
 
 // HealthResponse represents a health check response
@@ -339,7 +638,7 @@ type HealthResponse struct {
 
 func (x *HealthResponse) Reset() {
 	*x = HealthResponse{}
-	mi := &file_proto_mq_proto_msgTypes[5]
+	mi := &file_proto_mq_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -351,7 +650,7 @@ func (x *HealthResponse) String() string {
 func (*HealthResponse) ProtoMessage() {}
 
 func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mq_proto_msgTypes[5]
+	mi := &file_proto_mq_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -364,7 +663,7 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
 func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mq_proto_rawDescGZIP(), []int{5}
+	return file_proto_mq_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *HealthResponse) GetStatus() string {
@@ -404,7 +703,7 @@ type StatsRequest struct {
 
 func (x *StatsRequest) Reset() {
 	*x = StatsRequest{}
-	mi := &file_proto_mq_proto_msgTypes[6]
+	mi := &file_proto_mq_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -416,7 +715,7 @@ func (x *StatsRequest) String() string {
 func (*StatsRequest) ProtoMessage() {}
 
 func (x *StatsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mq_proto_msgTypes[6]
+	mi := &file_proto_mq_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -429,7 +728,7 @@ func (x *StatsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
 func (*StatsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mq_proto_rawDescGZIP(), []int{6}
+	return file_proto_mq_proto_rawDescGZIP(), []int{11}
 }
 
 // StatsResponse represents statistics response
@@ -444,7 +743,7 @@ type StatsResponse struct {
 
 func (x *StatsResponse) Reset() {
 	*x = StatsResponse{}
-	mi := &file_proto_mq_proto_msgTypes[7]
+	mi := &file_proto_mq_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -456,7 +755,7 @@ func (x *StatsResponse) String() string {
 func (*StatsResponse) ProtoMessage() {}
 
 func (x *StatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mq_proto_msgTypes[7]
+	mi := &file_proto_mq_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -469,7 +768,7 @@ func (x *StatsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
 func (*StatsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mq_proto_rawDescGZIP(), []int{7}
+	return file_proto_mq_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *StatsResponse) GetTopics() map[string]*TopicStats {
@@ -495,20 +794,32 @@ func (x *StatsResponse) GetTimestamp() int64 {
 
 // TopicStats represents statistics for a specific topic
 type TopicStats struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	Topic             string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
-	QueueSize         int64                  `protobuf:"varint,2,opt,name=queue_size,json=queueSize,proto3" json:"queue_size,omitempty"`
-	SubscriberCount   int32                  `protobuf:"varint,3,opt,name=subscriber_count,json=subscriberCount,proto3" json:"subscriber_count,omitempty"`
-	PendingMessages   int64                  `protobuf:"varint,4,opt,name=pending_messages,json=pendingMessages,proto3" json:"pending_messages,omitempty"`
-	PublishedMessages int64                  `protobuf:"varint,5,opt,name=published_messages,json=publishedMessages,proto3" json:"published_messages,omitempty"`
-	ConsumedMessages  int64                  `protobuf:"varint,6,opt,name=consumed_messages,json=consumedMessages,proto3" json:"consumed_messages,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Topic                string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	QueueSize            int64                  `protobuf:"varint,2,opt,name=queue_size,json=queueSize,proto3" json:"queue_size,omitempty"`
+	SubscriberCount      int32                  `protobuf:"varint,3,opt,name=subscriber_count,json=subscriberCount,proto3" json:"subscriber_count,omitempty"`
+	PendingMessages      int64                  `protobuf:"varint,4,opt,name=pending_messages,json=pendingMessages,proto3" json:"pending_messages,omitempty"`
+	PublishedMessages    int64                  `protobuf:"varint,5,opt,name=published_messages,json=publishedMessages,proto3" json:"published_messages,omitempty"`
+	ConsumedMessages     int64                  `protobuf:"varint,6,opt,name=consumed_messages,json=consumedMessages,proto3" json:"consumed_messages,omitempty"`
+	DeliveredMessages    int64                  `protobuf:"varint,7,opt,name=delivered_messages,json=deliveredMessages,proto3" json:"delivered_messages,omitempty"`
+	RedeliveredMessages  int64                  `protobuf:"varint,8,opt,name=redelivered_messages,json=redeliveredMessages,proto3" json:"redelivered_messages,omitempty"`
+	DeadLetteredMessages int64                  `protobuf:"varint,9,opt,name=dead_lettered_messages,json=deadLetteredMessages,proto3" json:"dead_lettered_messages,omitempty"`
+	// Messages dropped or refused because the topic's queue was full
+	DroppedOldestMessages int64 `protobuf:"varint,10,opt,name=dropped_oldest_messages,json=droppedOldestMessages,proto3" json:"dropped_oldest_messages,omitempty"`
+	DroppedNewestMessages int64 `protobuf:"varint,11,opt,name=dropped_newest_messages,json=droppedNewestMessages,proto3" json:"dropped_newest_messages,omitempty"`
+	RejectedMessages      int64 `protobuf:"varint,12,opt,name=rejected_messages,json=rejectedMessages,proto3" json:"rejected_messages,omitempty"`
+	// Set while delivery on the topic is paused
+	Paused bool `protobuf:"varint,13,opt,name=paused,proto3" json:"paused,omitempty"`
+	// Offsets the topic's log holds, when the broker persists messages
+	FirstOffset   uint64 `protobuf:"varint,14,opt,name=first_offset,json=firstOffset,proto3" json:"first_offset,omitempty"`
+	NextOffset    uint64 `protobuf:"varint,15,opt,name=next_offset,json=nextOffset,proto3" json:"next_offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TopicStats) Reset() {
 	*x = TopicStats{}
-	mi := &file_proto_mq_proto_msgTypes[8]
+	mi := &file_proto_mq_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -520,7 +831,7 @@ func (x *TopicStats) String() string {
 func (*TopicStats) ProtoMessage() {}
 
 func (x *TopicStats) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mq_proto_msgTypes[8]
+	mi := &file_proto_mq_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -533,7 +844,7 @@ func (x *TopicStats) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TopicStats.ProtoReflect.Descriptor instead.
 func (*TopicStats) Descriptor() ([]byte, []int) {
-	return file_proto_mq_proto_rawDescGZIP(), []int{8}
+	return file_proto_mq_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *TopicStats) GetTopic() string {
@@ -578,12 +889,652 @@ func (x *TopicStats) GetConsumedMessages() int64 {
 	return 0
 }
 
-var File_proto_mq_proto protoreflect.FileDescriptor
+func (x *TopicStats) GetDeliveredMessages() int64 {
+	if x != nil {
+		return x.DeliveredMessages
+	}
+	return 0
+}
 
-const file_proto_mq_proto_rawDesc = "" +
-	"\n" +
-	"\x0eproto/mq.proto\x12\x02mq\"\xb7\x01\n" +
-	"\x0ePublishRequest\x12\x14\n" +
+func (x *TopicStats) GetRedeliveredMessages() int64 {
+	if x != nil {
+		return x.RedeliveredMessages
+	}
+	return 0
+}
+
+func (x *TopicStats) GetDeadLetteredMessages() int64 {
+	if x != nil {
+		return x.DeadLetteredMessages
+	}
+	return 0
+}
+
+func (x *TopicStats) GetDroppedOldestMessages() int64 {
+	if x != nil {
+		return x.DroppedOldestMessages
+	}
+	return 0
+}
+
+func (x *TopicStats) GetDroppedNewestMessages() int64 {
+	if x != nil {
+		return x.DroppedNewestMessages
+	}
+	return 0
+}
+
+func (x *TopicStats) GetRejectedMessages() int64 {
+	if x != nil {
+		return x.RejectedMessages
+	}
+	return 0
+}
+
+func (x *TopicStats) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *TopicStats) GetFirstOffset() uint64 {
+	if x != nil {
+		return x.FirstOffset
+	}
+	return 0
+}
+
+func (x *TopicStats) GetNextOffset() uint64 {
+	if x != nil {
+		return x.NextOffset
+	}
+	return 0
+}
+
+// DeadLettersRequest represents a request to list a topic's dead letters
+type DeadLettersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeadLettersRequest) Reset() {
+	*x = DeadLettersRequest{}
+	mi := &file_proto_mq_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeadLettersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLettersRequest) ProtoMessage() {}
+
+func (x *DeadLettersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLettersRequest.ProtoReflect.Descriptor instead.
+func (*DeadLettersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeadLettersRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+// DeadLettersResponse lists a topic's dead letters, oldest first
+type DeadLettersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	DeadLetters   []*DeadLetter          `protobuf:"bytes,2,rep,name=dead_letters,json=deadLetters,proto3" json:"dead_letters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeadLettersResponse) Reset() {
+	*x = DeadLettersResponse{}
+	mi := &file_proto_mq_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeadLettersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLettersResponse) ProtoMessage() {}
+
+func (x *DeadLettersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLettersResponse.ProtoReflect.Descriptor instead.
+func (*DeadLettersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeadLettersResponse) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *DeadLettersResponse) GetDeadLetters() []*DeadLetter {
+	if x != nil {
+		return x.DeadLetters
+	}
+	return nil
+}
+
+// DeadLetter represents a message held on a dead-letter topic
+type DeadLetter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ID to re-drive or purge the message by
+	Id        string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	MessageId string `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	// Consumer group whose copy of the message was dead-lettered, if any
+	Group                  string            `protobuf:"bytes,3,opt,name=group,proto3" json:"group,omitempty"`
+	PublishedAtUnixNano    int64             `protobuf:"varint,4,opt,name=published_at_unix_nano,json=publishedAtUnixNano,proto3" json:"published_at_unix_nano,omitempty"`
+	DeadLetteredAtUnixNano int64             `protobuf:"varint,5,opt,name=dead_lettered_at_unix_nano,json=deadLetteredAtUnixNano,proto3" json:"dead_lettered_at_unix_nano,omitempty"`
+	Retries                int32             `protobuf:"varint,6,opt,name=retries,proto3" json:"retries,omitempty"`
+	Error                  string            `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	Payload                []byte            `protobuf:"bytes,8,opt,name=payload,proto3" json:"payload,omitempty"`
+	Headers                map[string]string `protobuf:"bytes,9,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *DeadLetter) Reset() {
+	*x = DeadLetter{}
+	mi := &file_proto_mq_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeadLetter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLetter) ProtoMessage() {}
+
+func (x *DeadLetter) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLetter.ProtoReflect.Descriptor instead.
+func (*DeadLetter) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeadLetter) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetPublishedAtUnixNano() int64 {
+	if x != nil {
+		return x.PublishedAtUnixNano
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetDeadLetteredAtUnixNano() int64 {
+	if x != nil {
+		return x.DeadLetteredAtUnixNano
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetRetries() int32 {
+	if x != nil {
+		return x.Retries
+	}
+	return 0
+}
+
+func (x *DeadLetter) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *DeadLetter) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *DeadLetter) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+// RedriveDeadLettersRequest represents a request to re-drive a topic's dead
+// letters; with no ids every one is re-driven
+type RedriveDeadLettersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Ids           []string               `protobuf:"bytes,2,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RedriveDeadLettersRequest) Reset() {
+	*x = RedriveDeadLettersRequest{}
+	mi := &file_proto_mq_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedriveDeadLettersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedriveDeadLettersRequest) ProtoMessage() {}
+
+func (x *RedriveDeadLettersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedriveDeadLettersRequest.ProtoReflect.Descriptor instead.
+func (*RedriveDeadLettersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RedriveDeadLettersRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *RedriveDeadLettersRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// PurgeDeadLettersRequest represents a request to discard a topic's dead
+// letters; with no ids every one is discarded
+type PurgeDeadLettersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Ids           []string               `protobuf:"bytes,2,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PurgeDeadLettersRequest) Reset() {
+	*x = PurgeDeadLettersRequest{}
+	mi := &file_proto_mq_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PurgeDeadLettersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeDeadLettersRequest) ProtoMessage() {}
+
+func (x *PurgeDeadLettersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeDeadLettersRequest.ProtoReflect.Descriptor instead.
+func (*PurgeDeadLettersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PurgeDeadLettersRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *PurgeDeadLettersRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// DeadLettersCountResponse reports how many dead letters a request affected
+type DeadLettersCountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeadLettersCountResponse) Reset() {
+	*x = DeadLettersCountResponse{}
+	mi := &file_proto_mq_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeadLettersCountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeadLettersCountResponse) ProtoMessage() {}
+
+func (x *DeadLettersCountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeadLettersCountResponse.ProtoReflect.Descriptor instead.
+func (*DeadLettersCountResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DeadLettersCountResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// TopicConfig is a topic's own configuration; zero fields use the broker's
+type TopicConfig struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Negative leaves the topic's queue unbounded
+	MaxQueueSize int32 `protobuf:"varint,1,opt,name=max_queue_size,json=maxQueueSize,proto3" json:"max_queue_size,omitempty"`
+	// reject, block, drop-oldest or drop-newest
+	OverflowPolicy string `protobuf:"bytes,2,opt,name=overflow_policy,json=overflowPolicy,proto3" json:"overflow_policy,omitempty"`
+	AckTimeoutMs   int64  `protobuf:"varint,3,opt,name=ack_timeout_ms,json=ackTimeoutMs,proto3" json:"ack_timeout_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *TopicConfig) Reset() {
+	*x = TopicConfig{}
+	mi := &file_proto_mq_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicConfig) ProtoMessage() {}
+
+func (x *TopicConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicConfig.ProtoReflect.Descriptor instead.
+func (*TopicConfig) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *TopicConfig) GetMaxQueueSize() int32 {
+	if x != nil {
+		return x.MaxQueueSize
+	}
+	return 0
+}
+
+func (x *TopicConfig) GetOverflowPolicy() string {
+	if x != nil {
+		return x.OverflowPolicy
+	}
+	return ""
+}
+
+func (x *TopicConfig) GetAckTimeoutMs() int64 {
+	if x != nil {
+		return x.AckTimeoutMs
+	}
+	return 0
+}
+
+// CreateTopicRequest represents a request to create or configure a topic
+type CreateTopicRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Config        *TopicConfig           `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTopicRequest) Reset() {
+	*x = CreateTopicRequest{}
+	mi := &file_proto_mq_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTopicRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTopicRequest) ProtoMessage() {}
+
+func (x *CreateTopicRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTopicRequest.ProtoReflect.Descriptor instead.
+func (*CreateTopicRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CreateTopicRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *CreateTopicRequest) GetConfig() *TopicConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+// TopicRequest names the topic of a topic management request
+type TopicRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicRequest) Reset() {
+	*x = TopicRequest{}
+	mi := &file_proto_mq_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicRequest) ProtoMessage() {}
+
+func (x *TopicRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicRequest.ProtoReflect.Descriptor instead.
+func (*TopicRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *TopicRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+// TopicAdminResponse reports the outcome of a topic management request
+type TopicAdminResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Topic string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	// Messages purged, for PurgeTopic
+	Count         int32 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicAdminResponse) Reset() {
+	*x = TopicAdminResponse{}
+	mi := &file_proto_mq_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicAdminResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicAdminResponse) ProtoMessage() {}
+
+func (x *TopicAdminResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mq_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicAdminResponse.ProtoReflect.Descriptor instead.
+func (*TopicAdminResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mq_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *TopicAdminResponse) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *TopicAdminResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+var File_proto_mq_proto protoreflect.FileDescriptor
+
+const file_proto_mq_proto_rawDesc = "" +
+	"\n" +
+	"\x0eproto/mq.proto\x12\x02mq\"\xb7\x01\n" +
+	"\x0ePublishRequest\x12\x14\n" +
 	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x18\n" +
 	"\apayload\x18\x02 \x01(\fR\apayload\x129\n" +
 	"\aheaders\x18\x03 \x03(\v2\x1f.mq.PublishRequest.HeadersEntryR\aheaders\x1a:\n" +
@@ -594,22 +1545,47 @@ const file_proto_mq_proto_rawDesc = "" +
 	"\n" +
 	"message_id\x18\x01 \x01(\tR\tmessageId\x12\x18\n" +
 	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error\"\x97\x01\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"Y\n" +
+	"\x13PublishBatchRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12,\n" +
+	"\bmessages\x18\x02 \x03(\v2\x10.mq.BatchMessageR\bmessages\"\x9d\x01\n" +
+	"\fBatchMessage\x12\x18\n" +
+	"\apayload\x18\x01 \x01(\fR\apayload\x127\n" +
+	"\aheaders\x18\x02 \x03(\v2\x1d.mq.BatchMessage.HeadersEntryR\aheaders\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"E\n" +
+	"\x14PublishBatchResponse\x12-\n" +
+	"\aresults\x18\x01 \x03(\v2\x13.mq.PublishResponseR\aresults\"\xd0\x01\n" +
 	"\x10SubscribeRequest\x12\x14\n" +
 	"\x05topic\x18\x01 \x01(\tR\x05topic\x12%\n" +
 	"\x0econsumer_group\x18\x02 \x01(\tR\rconsumerGroup\x12\x1d\n" +
 	"\n" +
 	"batch_size\x18\x03 \x01(\x05R\tbatchSize\x12'\n" +
-	"\x0ftimeout_seconds\x18\x04 \x01(\x05R\x0etimeoutSeconds\"\xd7\x01\n" +
+	"\x0ftimeout_seconds\x18\x04 \x01(\x05R\x0etimeoutSeconds\x12\x16\n" +
+	"\x06replay\x18\x05 \x01(\bR\x06replay\x12\x1f\n" +
+	"\vfrom_offset\x18\x06 \x01(\x04R\n" +
+	"fromOffset\"\xc5\x02\n" +
 	"\aMessage\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
 	"\x05topic\x18\x02 \x01(\tR\x05topic\x12\x18\n" +
 	"\apayload\x18\x03 \x01(\fR\apayload\x12\x1c\n" +
 	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x122\n" +
-	"\aheaders\x18\x05 \x03(\v2\x18.mq.Message.HeadersEntryR\aheaders\x1a:\n" +
+	"\aheaders\x18\x05 \x03(\v2\x18.mq.Message.HeadersEntryR\aheaders\x12\x1f\n" +
+	"\vretry_count\x18\x06 \x01(\x05R\n" +
+	"retryCount\x123\n" +
+	"\x16published_at_unix_nano\x18\a \x01(\x03R\x13publishedAtUnixNano\x12\x16\n" +
+	"\x06offset\x18\b \x01(\x04R\x06offset\x1a:\n" +
 	"\fHeadersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x0f\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"h\n" +
+	"\n" +
+	"AckRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x02 \x01(\tR\tmessageId\x12%\n" +
+	"\x0econsumer_group\x18\x03 \x01(\tR\rconsumerGroup\"\r\n" +
+	"\vAckResponse\"\x0f\n" +
 	"\rHealthRequest\"z\n" +
 	"\x0eHealthResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1c\n" +
@@ -623,7 +1599,7 @@ const file_proto_mq_proto_rawDesc = "" +
 	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x1aI\n" +
 	"\vTopicsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12$\n" +
-	"\x05value\x18\x02 \x01(\v2\x0e.mq.TopicStatsR\x05value:\x028\x01\"\xf3\x01\n" +
+	"\x05value\x18\x02 \x01(\v2\x0e.mq.TopicStatsR\x05value:\x028\x01\"\x84\x05\n" +
 	"\n" +
 	"TopicStats\x12\x14\n" +
 	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x1d\n" +
@@ -632,12 +1608,76 @@ const file_proto_mq_proto_rawDesc = "" +
 	"\x10subscriber_count\x18\x03 \x01(\x05R\x0fsubscriberCount\x12)\n" +
 	"\x10pending_messages\x18\x04 \x01(\x03R\x0fpendingMessages\x12-\n" +
 	"\x12published_messages\x18\x05 \x01(\x03R\x11publishedMessages\x12+\n" +
-	"\x11consumed_messages\x18\x06 \x01(\x03R\x10consumedMessages2\xd3\x01\n" +
+	"\x11consumed_messages\x18\x06 \x01(\x03R\x10consumedMessages\x12-\n" +
+	"\x12delivered_messages\x18\a \x01(\x03R\x11deliveredMessages\x121\n" +
+	"\x14redelivered_messages\x18\b \x01(\x03R\x13redeliveredMessages\x124\n" +
+	"\x16dead_lettered_messages\x18\t \x01(\x03R\x14deadLetteredMessages\x126\n" +
+	"\x17dropped_oldest_messages\x18\n" +
+	" \x01(\x03R\x15droppedOldestMessages\x126\n" +
+	"\x17dropped_newest_messages\x18\v \x01(\x03R\x15droppedNewestMessages\x12+\n" +
+	"\x11rejected_messages\x18\f \x01(\x03R\x10rejectedMessages\x12\x16\n" +
+	"\x06paused\x18\r \x01(\bR\x06paused\x12!\n" +
+	"\ffirst_offset\x18\x0e \x01(\x04R\vfirstOffset\x12\x1f\n" +
+	"\vnext_offset\x18\x0f \x01(\x04R\n" +
+	"nextOffset\"*\n" +
+	"\x12DeadLettersRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\"^\n" +
+	"\x13DeadLettersResponse\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x121\n" +
+	"\fdead_letters\x18\x02 \x03(\v2\x0e.mq.DeadLetterR\vdeadLetters\"\xff\x02\n" +
+	"\n" +
+	"DeadLetter\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x02 \x01(\tR\tmessageId\x12\x14\n" +
+	"\x05group\x18\x03 \x01(\tR\x05group\x123\n" +
+	"\x16published_at_unix_nano\x18\x04 \x01(\x03R\x13publishedAtUnixNano\x12:\n" +
+	"\x1adead_lettered_at_unix_nano\x18\x05 \x01(\x03R\x16deadLetteredAtUnixNano\x12\x18\n" +
+	"\aretries\x18\x06 \x01(\x05R\aretries\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12\x18\n" +
+	"\apayload\x18\b \x01(\fR\apayload\x125\n" +
+	"\aheaders\x18\t \x03(\v2\x1b.mq.DeadLetter.HeadersEntryR\aheaders\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"C\n" +
+	"\x19RedriveDeadLettersRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\tR\x03ids\"A\n" +
+	"\x17PurgeDeadLettersRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\tR\x03ids\"0\n" +
+	"\x18DeadLettersCountResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\"\x82\x01\n" +
+	"\vTopicConfig\x12$\n" +
+	"\x0emax_queue_size\x18\x01 \x01(\x05R\fmaxQueueSize\x12'\n" +
+	"\x0foverflow_policy\x18\x02 \x01(\tR\x0eoverflowPolicy\x12$\n" +
+	"\x0eack_timeout_ms\x18\x03 \x01(\x03R\fackTimeoutMs\"S\n" +
+	"\x12CreateTopicRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12'\n" +
+	"\x06config\x18\x02 \x01(\v2\x0f.mq.TopicConfigR\x06config\"$\n" +
+	"\fTopicRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\"@\n" +
+	"\x12TopicAdminResponse\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count2\x87\a\n" +
 	"\tMQService\x122\n" +
-	"\aPublish\x12\x12.mq.PublishRequest\x1a\x13.mq.PublishResponse\x120\n" +
-	"\tSubscribe\x12\x14.mq.SubscribeRequest\x1a\v.mq.Message0\x01\x12/\n" +
+	"\aPublish\x12\x12.mq.PublishRequest\x1a\x13.mq.PublishResponse\x12A\n" +
+	"\fPublishBatch\x12\x17.mq.PublishBatchRequest\x1a\x18.mq.PublishBatchResponse\x120\n" +
+	"\tSubscribe\x12\x14.mq.SubscribeRequest\x1a\v.mq.Message0\x01\x12&\n" +
+	"\x03Ack\x12\x0e.mq.AckRequest\x1a\x0f.mq.AckResponse\x12/\n" +
 	"\x06Health\x12\x11.mq.HealthRequest\x1a\x12.mq.HealthResponse\x12/\n" +
-	"\bGetStats\x12\x10.mq.StatsRequest\x1a\x11.mq.StatsResponseB/Z-github.com/harishb93/telemetry-pipeline/protob\x06proto3"
+	"\bGetStats\x12\x10.mq.StatsRequest\x1a\x11.mq.StatsResponse\x12B\n" +
+	"\x0fListDeadLetters\x12\x16.mq.DeadLettersRequest\x1a\x17.mq.DeadLettersResponse\x12Q\n" +
+	"\x12RedriveDeadLetters\x12\x1d.mq.RedriveDeadLettersRequest\x1a\x1c.mq.DeadLettersCountResponse\x12M\n" +
+	"\x10PurgeDeadLetters\x12\x1b.mq.PurgeDeadLettersRequest\x1a\x1c.mq.DeadLettersCountResponse\x12=\n" +
+	"\vCreateTopic\x12\x16.mq.CreateTopicRequest\x1a\x16.mq.TopicAdminResponse\x12@\n" +
+	"\x0eConfigureTopic\x12\x16.mq.CreateTopicRequest\x1a\x16.mq.TopicAdminResponse\x127\n" +
+	"\vDeleteTopic\x12\x10.mq.TopicRequest\x1a\x16.mq.TopicAdminResponse\x126\n" +
+	"\n" +
+	"PurgeTopic\x12\x10.mq.TopicRequest\x1a\x16.mq.TopicAdminResponse\x126\n" +
+	"\n" +
+	"PauseTopic\x12\x10.mq.TopicRequest\x1a\x16.mq.TopicAdminResponse\x127\n" +
+	"\vResumeTopic\x12\x10.mq.TopicRequest\x1a\x16.mq.TopicAdminResponseB/Z-github.com/harishb93/telemetry-pipeline/protob\x06proto3"
 
 var (
 	file_proto_mq_proto_rawDescOnce sync.Once
@@ -651,39 +1691,84 @@ func file_proto_mq_proto_rawDescGZIP() []byte {
 	return file_proto_mq_proto_rawDescData
 }
 
-var file_proto_mq_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_proto_mq_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
 var file_proto_mq_proto_goTypes = []any{
-	(*PublishRequest)(nil),   // 0: mq.PublishRequest
-	(*PublishResponse)(nil),  // 1: mq.PublishResponse
-	(*SubscribeRequest)(nil), // 2: mq.SubscribeRequest
-	(*Message)(nil),          // 3: mq.Message
-	(*HealthRequest)(nil),    // 4: mq.HealthRequest
-	(*HealthResponse)(nil),   // 5: mq.HealthResponse
-	(*StatsRequest)(nil),     // 6: mq.StatsRequest
-	(*StatsResponse)(nil),    // 7: mq.StatsResponse
-	(*TopicStats)(nil),       // 8: mq.TopicStats
-	nil,                      // 9: mq.PublishRequest.HeadersEntry
-	nil,                      // 10: mq.Message.HeadersEntry
-	nil,                      // 11: mq.StatsResponse.TopicsEntry
+	(*PublishRequest)(nil),            // 0: mq.PublishRequest
+	(*PublishResponse)(nil),           // 1: mq.PublishResponse
+	(*PublishBatchRequest)(nil),       // 2: mq.PublishBatchRequest
+	(*BatchMessage)(nil),              // 3: mq.BatchMessage
+	(*PublishBatchResponse)(nil),      // 4: mq.PublishBatchResponse
+	(*SubscribeRequest)(nil),          // 5: mq.SubscribeRequest
+	(*Message)(nil),                   // 6: mq.Message
+	(*AckRequest)(nil),                // 7: mq.AckRequest
+	(*AckResponse)(nil),               // 8: mq.AckResponse
+	(*HealthRequest)(nil),             // 9: mq.HealthRequest
+	(*HealthResponse)(nil),            // 10: mq.HealthResponse
+	(*StatsRequest)(nil),              // 11: mq.StatsRequest
+	(*StatsResponse)(nil),             // 12: mq.StatsResponse
+	(*TopicStats)(nil),                // 13: mq.TopicStats
+	(*DeadLettersRequest)(nil),        // 14: mq.DeadLettersRequest
+	(*DeadLettersResponse)(nil),       // 15: mq.DeadLettersResponse
+	(*DeadLetter)(nil),                // 16: mq.DeadLetter
+	(*RedriveDeadLettersRequest)(nil), // 17: mq.RedriveDeadLettersRequest
+	(*PurgeDeadLettersRequest)(nil),   // 18: mq.PurgeDeadLettersRequest
+	(*DeadLettersCountResponse)(nil),  // 19: mq.DeadLettersCountResponse
+	(*TopicConfig)(nil),               // 20: mq.TopicConfig
+	(*CreateTopicRequest)(nil),        // 21: mq.CreateTopicRequest
+	(*TopicRequest)(nil),              // 22: mq.TopicRequest
+	(*TopicAdminResponse)(nil),        // 23: mq.TopicAdminResponse
+	nil,                               // 24: mq.PublishRequest.HeadersEntry
+	nil,                               // 25: mq.BatchMessage.HeadersEntry
+	nil,                               // 26: mq.Message.HeadersEntry
+	nil,                               // 27: mq.StatsResponse.TopicsEntry
+	nil,                               // 28: mq.DeadLetter.HeadersEntry
 }
 var file_proto_mq_proto_depIdxs = []int32{
-	9,  // 0: mq.PublishRequest.headers:type_name -> mq.PublishRequest.HeadersEntry
-	10, // 1: mq.Message.headers:type_name -> mq.Message.HeadersEntry
-	11, // 2: mq.StatsResponse.topics:type_name -> mq.StatsResponse.TopicsEntry
-	8,  // 3: mq.StatsResponse.TopicsEntry.value:type_name -> mq.TopicStats
-	0,  // 4: mq.MQService.Publish:input_type -> mq.PublishRequest
-	2,  // 5: mq.MQService.Subscribe:input_type -> mq.SubscribeRequest
-	4,  // 6: mq.MQService.Health:input_type -> mq.HealthRequest
-	6,  // 7: mq.MQService.GetStats:input_type -> mq.StatsRequest
-	1,  // 8: mq.MQService.Publish:output_type -> mq.PublishResponse
-	3,  // 9: mq.MQService.Subscribe:output_type -> mq.Message
-	5,  // 10: mq.MQService.Health:output_type -> mq.HealthResponse
-	7,  // 11: mq.MQService.GetStats:output_type -> mq.StatsResponse
-	8,  // [8:12] is the sub-list for method output_type
-	4,  // [4:8] is the sub-list for method input_type
-	4,  // [4:4] is the sub-list for extension type_name
-	4,  // [4:4] is the sub-list for extension extendee
-	0,  // [0:4] is the sub-list for field type_name
+	24, // 0: mq.PublishRequest.headers:type_name -> mq.PublishRequest.HeadersEntry
+	3,  // 1: mq.PublishBatchRequest.messages:type_name -> mq.BatchMessage
+	25, // 2: mq.BatchMessage.headers:type_name -> mq.BatchMessage.HeadersEntry
+	1,  // 3: mq.PublishBatchResponse.results:type_name -> mq.PublishResponse
+	26, // 4: mq.Message.headers:type_name -> mq.Message.HeadersEntry
+	27, // 5: mq.StatsResponse.topics:type_name -> mq.StatsResponse.TopicsEntry
+	16, // 6: mq.DeadLettersResponse.dead_letters:type_name -> mq.DeadLetter
+	28, // 7: mq.DeadLetter.headers:type_name -> mq.DeadLetter.HeadersEntry
+	20, // 8: mq.CreateTopicRequest.config:type_name -> mq.TopicConfig
+	13, // 9: mq.StatsResponse.TopicsEntry.value:type_name -> mq.TopicStats
+	0,  // 10: mq.MQService.Publish:input_type -> mq.PublishRequest
+	2,  // 11: mq.MQService.PublishBatch:input_type -> mq.PublishBatchRequest
+	5,  // 12: mq.MQService.Subscribe:input_type -> mq.SubscribeRequest
+	7,  // 13: mq.MQService.Ack:input_type -> mq.AckRequest
+	9,  // 14: mq.MQService.Health:input_type -> mq.HealthRequest
+	11, // 15: mq.MQService.GetStats:input_type -> mq.StatsRequest
+	14, // 16: mq.MQService.ListDeadLetters:input_type -> mq.DeadLettersRequest
+	17, // 17: mq.MQService.RedriveDeadLetters:input_type -> mq.RedriveDeadLettersRequest
+	18, // 18: mq.MQService.PurgeDeadLetters:input_type -> mq.PurgeDeadLettersRequest
+	21, // 19: mq.MQService.CreateTopic:input_type -> mq.CreateTopicRequest
+	21, // 20: mq.MQService.ConfigureTopic:input_type -> mq.CreateTopicRequest
+	22, // 21: mq.MQService.DeleteTopic:input_type -> mq.TopicRequest
+	22, // 22: mq.MQService.PurgeTopic:input_type -> mq.TopicRequest
+	22, // 23: mq.MQService.PauseTopic:input_type -> mq.TopicRequest
+	22, // 24: mq.MQService.ResumeTopic:input_type -> mq.TopicRequest
+	1,  // 25: mq.MQService.Publish:output_type -> mq.PublishResponse
+	4,  // 26: mq.MQService.PublishBatch:output_type -> mq.PublishBatchResponse
+	6,  // 27: mq.MQService.Subscribe:output_type -> mq.Message
+	8,  // 28: mq.MQService.Ack:output_type -> mq.AckResponse
+	10, // 29: mq.MQService.Health:output_type -> mq.HealthResponse
+	12, // 30: mq.MQService.GetStats:output_type -> mq.StatsResponse
+	15, // 31: mq.MQService.ListDeadLetters:output_type -> mq.DeadLettersResponse
+	19, // 32: mq.MQService.RedriveDeadLetters:output_type -> mq.DeadLettersCountResponse
+	19, // 33: mq.MQService.PurgeDeadLetters:output_type -> mq.DeadLettersCountResponse
+	23, // 34: mq.MQService.CreateTopic:output_type -> mq.TopicAdminResponse
+	23, // 35: mq.MQService.ConfigureTopic:output_type -> mq.TopicAdminResponse
+	23, // 36: mq.MQService.DeleteTopic:output_type -> mq.TopicAdminResponse
+	23, // 37: mq.MQService.PurgeTopic:output_type -> mq.TopicAdminResponse
+	23, // 38: mq.MQService.PauseTopic:output_type -> mq.TopicAdminResponse
+	23, // 39: mq.MQService.ResumeTopic:output_type -> mq.TopicAdminResponse
+	25, // [25:40] is the sub-list for method output_type
+	10, // [10:25] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_proto_mq_proto_init() }
@@ -697,7 +1782,7 @@ func file_proto_mq_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_mq_proto_rawDesc), len(file_proto_mq_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   12,
+			NumMessages:   29,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
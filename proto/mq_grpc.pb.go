@@ -19,10 +19,21 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MQService_Publish_FullMethodName   = "/mq.MQService/Publish"
-	MQService_Subscribe_FullMethodName = "/mq.MQService/Subscribe"
-	MQService_Health_FullMethodName    = "/mq.MQService/Health"
-	MQService_GetStats_FullMethodName  = "/mq.MQService/GetStats"
+	MQService_Publish_FullMethodName            = "/mq.MQService/Publish"
+	MQService_PublishBatch_FullMethodName       = "/mq.MQService/PublishBatch"
+	MQService_Subscribe_FullMethodName          = "/mq.MQService/Subscribe"
+	MQService_Ack_FullMethodName                = "/mq.MQService/Ack"
+	MQService_Health_FullMethodName             = "/mq.MQService/Health"
+	MQService_GetStats_FullMethodName           = "/mq.MQService/GetStats"
+	MQService_ListDeadLetters_FullMethodName    = "/mq.MQService/ListDeadLetters"
+	MQService_RedriveDeadLetters_FullMethodName = "/mq.MQService/RedriveDeadLetters"
+	MQService_PurgeDeadLetters_FullMethodName   = "/mq.MQService/PurgeDeadLetters"
+	MQService_CreateTopic_FullMethodName        = "/mq.MQService/CreateTopic"
+	MQService_ConfigureTopic_FullMethodName     = "/mq.MQService/ConfigureTopic"
+	MQService_DeleteTopic_FullMethodName        = "/mq.MQService/DeleteTopic"
+	MQService_PurgeTopic_FullMethodName         = "/mq.MQService/PurgeTopic"
+	MQService_PauseTopic_FullMethodName         = "/mq.MQService/PauseTopic"
+	MQService_ResumeTopic_FullMethodName        = "/mq.MQService/ResumeTopic"
 )
 
 // MQServiceClient is the client API for MQService service.
@@ -33,12 +44,34 @@ const (
 type MQServiceClient interface {
 	// Publish a message to a topic
 	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// Publish messages to a topic together, with a result per message
+	PublishBatch(ctx context.Context, in *PublishBatchRequest, opts ...grpc.CallOption) (*PublishBatchResponse, error)
 	// Subscribe to a topic (server streaming)
 	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error)
+	// Acknowledge a message streamed by Subscribe
+	Ack(ctx context.Context, in *AckRequest, opts ...grpc.CallOption) (*AckResponse, error)
 	// Health check
 	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
 	// Get statistics
 	GetStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// List the messages held on a topic's dead-letter topic
+	ListDeadLetters(ctx context.Context, in *DeadLettersRequest, opts ...grpc.CallOption) (*DeadLettersResponse, error)
+	// Publish dead-lettered messages to their topic again
+	RedriveDeadLetters(ctx context.Context, in *RedriveDeadLettersRequest, opts ...grpc.CallOption) (*DeadLettersCountResponse, error)
+	// Discard dead-lettered messages
+	PurgeDeadLetters(ctx context.Context, in *PurgeDeadLettersRequest, opts ...grpc.CallOption) (*DeadLettersCountResponse, error)
+	// Create a topic with its own configuration
+	CreateTopic(ctx context.Context, in *CreateTopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error)
+	// Replace a topic's configuration
+	ConfigureTopic(ctx context.Context, in *CreateTopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error)
+	// Delete a topic with its queued messages and log
+	DeleteTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error)
+	// Discard every message queued on a topic
+	PurgeTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error)
+	// Stop delivering a topic's messages, which are queued meanwhile
+	PauseTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error)
+	// Deliver a paused topic's messages again
+	ResumeTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error)
 }
 
 type mQServiceClient struct {
@@ -59,6 +92,16 @@ func (c *mQServiceClient) Publish(ctx context.Context, in *PublishRequest, opts
 	return out, nil
 }
 
+func (c *mQServiceClient) PublishBatch(ctx context.Context, in *PublishBatchRequest, opts ...grpc.CallOption) (*PublishBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishBatchResponse)
+	err := c.cc.Invoke(ctx, MQService_PublishBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *mQServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &MQService_ServiceDesc.Streams[0], MQService_Subscribe_FullMethodName, cOpts...)
@@ -78,6 +121,16 @@ func (c *mQServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, o
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MQService_SubscribeClient = grpc.ServerStreamingClient[Message]
 
+func (c *mQServiceClient) Ack(ctx context.Context, in *AckRequest, opts ...grpc.CallOption) (*AckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AckResponse)
+	err := c.cc.Invoke(ctx, MQService_Ack_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *mQServiceClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(HealthResponse)
@@ -98,6 +151,96 @@ func (c *mQServiceClient) GetStats(ctx context.Context, in *StatsRequest, opts .
 	return out, nil
 }
 
+func (c *mQServiceClient) ListDeadLetters(ctx context.Context, in *DeadLettersRequest, opts ...grpc.CallOption) (*DeadLettersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeadLettersResponse)
+	err := c.cc.Invoke(ctx, MQService_ListDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mQServiceClient) RedriveDeadLetters(ctx context.Context, in *RedriveDeadLettersRequest, opts ...grpc.CallOption) (*DeadLettersCountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeadLettersCountResponse)
+	err := c.cc.Invoke(ctx, MQService_RedriveDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mQServiceClient) PurgeDeadLetters(ctx context.Context, in *PurgeDeadLettersRequest, opts ...grpc.CallOption) (*DeadLettersCountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeadLettersCountResponse)
+	err := c.cc.Invoke(ctx, MQService_PurgeDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mQServiceClient) CreateTopic(ctx context.Context, in *CreateTopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TopicAdminResponse)
+	err := c.cc.Invoke(ctx, MQService_CreateTopic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mQServiceClient) ConfigureTopic(ctx context.Context, in *CreateTopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TopicAdminResponse)
+	err := c.cc.Invoke(ctx, MQService_ConfigureTopic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mQServiceClient) DeleteTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TopicAdminResponse)
+	err := c.cc.Invoke(ctx, MQService_DeleteTopic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mQServiceClient) PurgeTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TopicAdminResponse)
+	err := c.cc.Invoke(ctx, MQService_PurgeTopic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mQServiceClient) PauseTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TopicAdminResponse)
+	err := c.cc.Invoke(ctx, MQService_PauseTopic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mQServiceClient) ResumeTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*TopicAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TopicAdminResponse)
+	err := c.cc.Invoke(ctx, MQService_ResumeTopic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MQServiceServer is the server API for MQService service.
 // All implementations must embed UnimplementedMQServiceServer
 // for forward compatibility.
@@ -106,12 +249,34 @@ func (c *mQServiceClient) GetStats(ctx context.Context, in *StatsRequest, opts .
 type MQServiceServer interface {
 	// Publish a message to a topic
 	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// Publish messages to a topic together, with a result per message
+	PublishBatch(context.Context, *PublishBatchRequest) (*PublishBatchResponse, error)
 	// Subscribe to a topic (server streaming)
 	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Message]) error
+	// Acknowledge a message streamed by Subscribe
+	Ack(context.Context, *AckRequest) (*AckResponse, error)
 	// Health check
 	Health(context.Context, *HealthRequest) (*HealthResponse, error)
 	// Get statistics
 	GetStats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// List the messages held on a topic's dead-letter topic
+	ListDeadLetters(context.Context, *DeadLettersRequest) (*DeadLettersResponse, error)
+	// Publish dead-lettered messages to their topic again
+	RedriveDeadLetters(context.Context, *RedriveDeadLettersRequest) (*DeadLettersCountResponse, error)
+	// Discard dead-lettered messages
+	PurgeDeadLetters(context.Context, *PurgeDeadLettersRequest) (*DeadLettersCountResponse, error)
+	// Create a topic with its own configuration
+	CreateTopic(context.Context, *CreateTopicRequest) (*TopicAdminResponse, error)
+	// Replace a topic's configuration
+	ConfigureTopic(context.Context, *CreateTopicRequest) (*TopicAdminResponse, error)
+	// Delete a topic with its queued messages and log
+	DeleteTopic(context.Context, *TopicRequest) (*TopicAdminResponse, error)
+	// Discard every message queued on a topic
+	PurgeTopic(context.Context, *TopicRequest) (*TopicAdminResponse, error)
+	// Stop delivering a topic's messages, which are queued meanwhile
+	PauseTopic(context.Context, *TopicRequest) (*TopicAdminResponse, error)
+	// Deliver a paused topic's messages again
+	ResumeTopic(context.Context, *TopicRequest) (*TopicAdminResponse, error)
 	mustEmbedUnimplementedMQServiceServer()
 }
 
@@ -125,15 +290,48 @@ type UnimplementedMQServiceServer struct{}
 func (UnimplementedMQServiceServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
 }
+func (UnimplementedMQServiceServer) PublishBatch(context.Context, *PublishBatchRequest) (*PublishBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublishBatch not implemented")
+}
 func (UnimplementedMQServiceServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Message]) error {
 	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
 }
+func (UnimplementedMQServiceServer) Ack(context.Context, *AckRequest) (*AckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ack not implemented")
+}
 func (UnimplementedMQServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
 }
 func (UnimplementedMQServiceServer) GetStats(context.Context, *StatsRequest) (*StatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
 }
+func (UnimplementedMQServiceServer) ListDeadLetters(context.Context, *DeadLettersRequest) (*DeadLettersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedMQServiceServer) RedriveDeadLetters(context.Context, *RedriveDeadLettersRequest) (*DeadLettersCountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RedriveDeadLetters not implemented")
+}
+func (UnimplementedMQServiceServer) PurgeDeadLetters(context.Context, *PurgeDeadLettersRequest) (*DeadLettersCountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeDeadLetters not implemented")
+}
+func (UnimplementedMQServiceServer) CreateTopic(context.Context, *CreateTopicRequest) (*TopicAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTopic not implemented")
+}
+func (UnimplementedMQServiceServer) ConfigureTopic(context.Context, *CreateTopicRequest) (*TopicAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfigureTopic not implemented")
+}
+func (UnimplementedMQServiceServer) DeleteTopic(context.Context, *TopicRequest) (*TopicAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTopic not implemented")
+}
+func (UnimplementedMQServiceServer) PurgeTopic(context.Context, *TopicRequest) (*TopicAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeTopic not implemented")
+}
+func (UnimplementedMQServiceServer) PauseTopic(context.Context, *TopicRequest) (*TopicAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseTopic not implemented")
+}
+func (UnimplementedMQServiceServer) ResumeTopic(context.Context, *TopicRequest) (*TopicAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeTopic not implemented")
+}
 func (UnimplementedMQServiceServer) mustEmbedUnimplementedMQServiceServer() {}
 func (UnimplementedMQServiceServer) testEmbeddedByValue()                   {}
 
@@ -173,6 +371,24 @@ func _MQService_Publish_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MQService_PublishBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).PublishBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_PublishBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).PublishBatch(ctx, req.(*PublishBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _MQService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(SubscribeRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -184,6 +400,24 @@ func _MQService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) err
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MQService_SubscribeServer = grpc.ServerStreamingServer[Message]
 
+func _MQService_Ack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).Ack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_Ack_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).Ack(ctx, req.(*AckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _MQService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(HealthRequest)
 	if err := dec(in); err != nil {
@@ -220,6 +454,168 @@ func _MQService_GetStats_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MQService_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_ListDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).ListDeadLetters(ctx, req.(*DeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MQService_RedriveDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedriveDeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).RedriveDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_RedriveDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).RedriveDeadLetters(ctx, req.(*RedriveDeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MQService_PurgeDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeDeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).PurgeDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_PurgeDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).PurgeDeadLetters(ctx, req.(*PurgeDeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MQService_CreateTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).CreateTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_CreateTopic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).CreateTopic(ctx, req.(*CreateTopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MQService_ConfigureTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).ConfigureTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_ConfigureTopic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).ConfigureTopic(ctx, req.(*CreateTopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MQService_DeleteTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).DeleteTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_DeleteTopic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).DeleteTopic(ctx, req.(*TopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MQService_PurgeTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).PurgeTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_PurgeTopic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).PurgeTopic(ctx, req.(*TopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MQService_PauseTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).PauseTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_PauseTopic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).PauseTopic(ctx, req.(*TopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MQService_ResumeTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MQServiceServer).ResumeTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MQService_ResumeTopic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MQServiceServer).ResumeTopic(ctx, req.(*TopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MQService_ServiceDesc is the grpc.ServiceDesc for MQService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -231,6 +627,14 @@ var MQService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Publish",
 			Handler:    _MQService_Publish_Handler,
 		},
+		{
+			MethodName: "PublishBatch",
+			Handler:    _MQService_PublishBatch_Handler,
+		},
+		{
+			MethodName: "Ack",
+			Handler:    _MQService_Ack_Handler,
+		},
 		{
 			MethodName: "Health",
 			Handler:    _MQService_Health_Handler,
@@ -239,6 +643,42 @@ var MQService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStats",
 			Handler:    _MQService_GetStats_Handler,
 		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _MQService_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "RedriveDeadLetters",
+			Handler:    _MQService_RedriveDeadLetters_Handler,
+		},
+		{
+			MethodName: "PurgeDeadLetters",
+			Handler:    _MQService_PurgeDeadLetters_Handler,
+		},
+		{
+			MethodName: "CreateTopic",
+			Handler:    _MQService_CreateTopic_Handler,
+		},
+		{
+			MethodName: "ConfigureTopic",
+			Handler:    _MQService_ConfigureTopic_Handler,
+		},
+		{
+			MethodName: "DeleteTopic",
+			Handler:    _MQService_DeleteTopic_Handler,
+		},
+		{
+			MethodName: "PurgeTopic",
+			Handler:    _MQService_PurgeTopic_Handler,
+		},
+		{
+			MethodName: "PauseTopic",
+			Handler:    _MQService_PauseTopic_Handler,
+		},
+		{
+			MethodName: "ResumeTopic",
+			Handler:    _MQService_ResumeTopic_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{